@@ -2,9 +2,15 @@ package main
 
 import (
 	"flag"
+	"fmt"
 	"log"
+	"time"
 
+	"github.com/epitech/timemanager/internal/repositories"
 	"github.com/epitech/timemanager/package/database"
+	"github.com/epitech/timemanager/package/timezone"
+	"github.com/epitech/timemanager/services"
+	"github.com/spf13/viper"
 )
 
 func main() {
@@ -14,12 +20,19 @@ func main() {
 		seedDB    bool
 		testData  bool
 		migrateDB bool
+		// cumuls journaliers des KPI
+		rollups     bool
+		rollupsFrom string
+		rollupsTo   string
 	)
 
 	flag.BoolVar(&resetDB, "reset", true, "Reset database by dropping all tables before migration")
 	flag.BoolVar(&seedDB, "seed", true, "Seed database with default data")
 	flag.BoolVar(&testData, "test-data", true, "Seed database with test data (5 users, 1 team, time entries, etc)")
 	flag.BoolVar(&migrateDB, "migrate", true, "Migrate database schema")
+	flag.BoolVar(&rollups, "rebuild-rollups", false, "Only rebuild the KPI daily rollups, leaving the rest of the database untouched")
+	flag.StringVar(&rollupsFrom, "rollups-from", "", "First day (YYYY-MM-DD) of the rollups to rebuild")
+	flag.StringVar(&rollupsTo, "rollups-to", "", "Last day (YYYY-MM-DD) of the rollups to rebuild, today by default")
 	flag.Parse()
 
 	// Se connecter à la base de données
@@ -27,6 +40,15 @@ func main() {
 		log.Fatalf("Failed to connect to database: %v", err)
 	}
 
+	// La reconstruction des cumuls se fait seule, sans reset ni seed
+	if rollups {
+		if err := rebuildRollups(rollupsFrom, rollupsTo); err != nil {
+			log.Fatalf("Rollups rebuild failed: %v", err)
+		}
+		log.Println("Database operations completed")
+		return
+	}
+
 	// Si l'option reset est spécifiée, supprimer toutes les tables
 	if resetDB {
 		if err := dropAllTables(); err != nil {
@@ -79,11 +101,28 @@ func dropAllTables() error {
 
 	// Liste des tables à supprimer dans l'ordre (des enfants aux parents)
 	tablesToDrop := []string{
+		"rollup_coverages",
+		"daily_rollups",
+		"compliance_rules",
+		"overtime_bands",
+		"overtime_policies",
+		"employment_contracts",
+		"leave_balances",
+		"leave_requests",
+		"absence_types",
+		"work_schedule_days",
+		"work_schedules",
+		"time_entry_corrections",
+		"breaks",
+		"break_types",
 		"time_tables",
 		"time_table_entries",
 		"team_users",
 		"teams",
 		"users",
+		"sites",
+		"holidays",
+		"holiday_calendars",
 	}
 
 	for _, tableName := range tablesToDrop {
@@ -98,3 +137,42 @@ func dropAllTables() error {
 
 	return nil
 }
+
+// rebuildRollups recalcule les cumuls journaliers des KPI entre deux jours
+// inclus (YYYY-MM-DD), avec les mêmes règles que le serveur
+func rebuildRollups(from, to string) error {
+	if from == "" {
+		return fmt.Errorf("-rollups-from is required")
+	}
+	if to == "" {
+		to = time.Now().Format("2006-01-02")
+	}
+
+	db, err := database.GetDB()
+	if err != nil {
+		return err
+	}
+	if err := timezone.SetDefault(viper.GetString("DEFAULT_TIME_ZONE")); err != nil {
+		return fmt.Errorf("invalid default time zone: %w", err)
+	}
+
+	repo := repositories.NewRepository(db)
+	kpiService := services.NewKpiService(repo)
+	if kpiService.MidnightRule, err = services.ParseMidnightRule(viper.GetString("KPI_MIDNIGHT_RULE")); err != nil {
+		return fmt.Errorf("invalid KPI midnight rule: %w", err)
+	}
+	scheduleService := services.NewScheduleService(repo)
+	kpiService.Schedules = scheduleService
+	leaveService := services.NewLeaveService(repo)
+	leaveService.Schedules = scheduleService
+	kpiService.Leaves = leaveService
+	kpiService.Contracts = services.NewContractService(repo)
+	kpiService.Overtime = services.NewOvertimeService(repo)
+
+	written, err := services.NewRollupService(repo, kpiService).Rebuild(from, to)
+	if err != nil {
+		return err
+	}
+	log.Printf("%d daily rollups rebuilt from %s to %s", written, from, to)
+	return nil
+}
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"log"
 	"net/http"
 	"os"
@@ -15,8 +16,10 @@ import (
 	"github.com/epitech/timemanager/internal/repositories"
 	"github.com/epitech/timemanager/package/database"
 	"github.com/epitech/timemanager/package/middlewares"
+	"github.com/epitech/timemanager/package/timezone"
 	"github.com/epitech/timemanager/services"
 	"github.com/rs/cors"
+	"github.com/spf13/viper"
 	"github.com/vektah/gqlparser/v2/ast"
 )
 
@@ -53,23 +56,136 @@ func main() {
 	teamRepo := repositories.NewRepository(db)
 	timeTableRepo := repositories.NewRepository(db)
 	kpiRepo := repositories.NewRepository(db)
+	breakRepo := repositories.NewRepository(db)
+	autoCloseRepo := repositories.NewRepository(db)
+	siteRepo := repositories.NewRepository(db)
+	scheduleRepo := repositories.NewRepository(db)
+	leaveRepo := repositories.NewRepository(db)
+	holidayRepo := repositories.NewRepository(db)
+	contractRepo := repositories.NewRepository(db)
+	overtimeRepo := repositories.NewRepository(db)
+	complianceRepo := repositories.NewRepository(db)
+	rollupRepo := repositories.NewRepository(db)
+	accessRepo := repositories.NewRepository(db)
+	latenessRepo := repositories.NewRepository(db)
+	timesheetRepo := repositories.NewRepository(db)
+	payPeriodRepo := repositories.NewRepository(db)
+	payrollRepo := repositories.NewRepository(db)
 	authService := services.NewAuthService(authRepo)
 	adminService := services.NewAdminService(adminRepo)
 	teamService := services.NewTeamService(teamRepo)
 	timeTableService := services.NewTimeTableService(timeTableRepo)
 	kpiService := services.NewKpiService(kpiRepo)
+	// Répartition des postes de nuit entre les jours qu'ils couvrent
+	if kpiService.MidnightRule, err = services.ParseMidnightRule(viper.GetString("KPI_MIDNIGHT_RULE")); err != nil {
+		log.Fatalf("invalid KPI midnight rule: %v", err)
+	}
+	if kpiService.Punctuality, err = services.ParsePunctualityPolicy(viper.GetString("PUNCTUALITY_GRACE_MINUTES"), viper.GetString("PUNCTUALITY_DEFAULT_ARRIVAL")); err != nil {
+		log.Fatalf("invalid punctuality policy: %v", err)
+	}
+	breakService := services.NewBreakService(breakRepo)
+	siteService := services.NewSiteService(siteRepo)
+	scheduleService := services.NewScheduleService(scheduleRepo)
+	kpiService.Schedules = scheduleService
+	leaveService := services.NewLeaveService(leaveRepo)
+	leaveService.Schedules = scheduleService
+	kpiService.Leaves = leaveService
+	holidayService := services.NewHolidayService(holidayRepo)
+	contractService := services.NewContractService(contractRepo)
+	kpiService.Contracts = contractService
+	overtimeService := services.NewOvertimeService(overtimeRepo)
+	kpiService.Overtime = overtimeService
+	complianceService := services.NewComplianceService(complianceRepo)
+	kpiService.Compliance = complianceService
+	// Cumuls journaliers lus par les KPI, tenus à jour à chaque pointage
+	rollupService := services.NewRollupService(rollupRepo, kpiService)
+	accessService := services.NewAccessService(accessRepo)
+	latenessService := services.NewLatenessService(latenessRepo, kpiService)
+	timesheetService := services.NewTimesheetService(timesheetRepo, kpiService)
+	timeTableService.Timesheets = timesheetService
+	// Pointages verrouillés une fois la période de paie clôturée
+	payPeriodService := services.NewPayPeriodService(payPeriodRepo)
+	timeTableService.PayPeriods = payPeriodService
+	// Export de paie des périodes clôturées, heures de nuit comprises
+	payrollService := services.NewPayrollService(payrollRepo, kpiService)
+	if payrollService.NightStart, payrollService.NightEnd, err = services.ParseNightWindow(viper.GetString("PAYROLL_NIGHT_START"), viper.GetString("PAYROLL_NIGHT_END")); err != nil {
+		log.Fatalf("invalid payroll night window: %v", err)
+	}
+	// La paie est figée à la clôture : son export ne change plus ensuite
+	payPeriodService.Payroll = payrollService
+	timeTableService.Rollups = rollupService
+	// Le retard est relevé à nouveau quand une saisie déplace une arrivée, ou
+	// quand un congé ou un planning change l'heure attendue
+	timeTableService.Lateness = latenessService
+	leaveService.Lateness = latenessService
+	scheduleService.Lateness = latenessService
+	// Les changements de planning et de politique recalculent les agrégats
+	leaveService.Rollups = rollupService
+	contractService.Rollups = rollupService
+	scheduleService.Rollups = rollupService
+	holidayService.Rollups = rollupService
+	overtimeService.Rollups = rollupService
+	// Ainsi que les changements d'équipe, de fuseau et d'horaires par défaut
+	teamService.Rollups = rollupService
+	adminService.Rollups = rollupService
+	authService.Rollups = rollupService
+	siteService.Rollups = rollupService
+
+	// Fuseau des utilisateurs sans fuseau propre ni site
+	if err := timezone.SetDefault(viper.GetString("DEFAULT_TIME_ZONE")); err != nil {
+		log.Fatalf("invalid default time zone: %v", err)
+	}
+
+	// Reconstruit les agrégats calculés avec une autre configuration
+	// (règle de minuit, ponctualité, fuseau par défaut)
+	rollupService.RebuildStale()
+
+	// Clôture automatique des pointages de sortie oubliés
+	autoCloseConfig, err := services.NewAutoCloseConfig(
+		viper.GetString("AUTO_CLOSE_POLICY"),
+		viper.GetInt("AUTO_CLOSE_MAX_HOURS"),
+		viper.GetInt("AUTO_CLOSE_INTERVAL_MINUTES"),
+	)
+	if err != nil {
+		log.Fatalf("invalid auto-close configuration: %v", err)
+	}
+	sweeperCtx, stopSweeper := context.WithCancel(context.Background())
+	defer stopSweeper()
+	autoCloseService := services.NewAutoCloseService(autoCloseRepo, autoCloseConfig)
+	autoCloseService.Rollups = rollupService
+	autoCloseService.PayPeriods = payPeriodService
+	go autoCloseService.Run(sweeperCtx)
+
+	// Acquisition mensuelle des congés, depuis le début du contrat
+	go leaveService.RunAccruals(sweeperCtx)
+
 	resolver := &resolvers.Resolver{
-		DB:               db,
-		AuthService:      authService,
-		AdminService:     adminService,
-		TeamService:      teamService,
-		TimeTableService: timeTableService,
-		KpiService:       kpiService,
+		DB:                db,
+		AuthService:       authService,
+		AdminService:      adminService,
+		TeamService:       teamService,
+		TimeTableService:  timeTableService,
+		KpiService:        kpiService,
+		BreakService:      breakService,
+		SiteService:       siteService,
+		ScheduleService:   scheduleService,
+		LeaveService:      leaveService,
+		HolidayService:    holidayService,
+		ContractService:   contractService,
+		OvertimeService:   overtimeService,
+		ComplianceService: complianceService,
+		RollupService:     rollupService,
+		AccessService:     accessService,
+		LatenessService:   latenessService,
+		TimesheetService:  timesheetService,
+		PayPeriodService:  payPeriodService,
+		PayrollService:    payrollService,
 	}
 
 	srv := handler.New(graph.NewExecutableSchema(graph.Config{
 		Resolvers: resolver,
 	}))
+	srv.SetErrorPresenter(resolvers.ErrorPresenter)
 	srv.AddTransport(transport.Options{})
 	srv.AddTransport(transport.GET{})
 	srv.AddTransport(transport.POST{})
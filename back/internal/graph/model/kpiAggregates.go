@@ -0,0 +1,75 @@
+package model
+
+// Totaux des KPI calculés par la base, hors schéma GraphQL
+
+// KpiPeriodTotal est le travail d'une période (jour, semaine, mois ou
+// trimestre) tous utilisateurs confondus, le nombre d'utilisateurs qui y ont
+// travaillé, et ses heures supplémentaires avec le nombre d'utilisateurs qui en
+// ont fait
+type KpiPeriodTotal struct {
+	PeriodStart     string
+	Minutes         int
+	Users           int
+	OvertimeMinutes int
+	OvertimeUsers   int
+}
+
+// KpiUserPeriodTotal est le travail d'un utilisateur sur une période
+type KpiUserPeriodTotal struct {
+	UserID      string
+	PeriodStart string
+	Minutes     int
+}
+
+// KpiWeekdayTotal est le travail d'un jour de la semaine (0 : dimanche) et le
+// nombre de journées travaillées qu'il compte
+type KpiWeekdayTotal struct {
+	Weekday int
+	Minutes int
+	Days    int
+}
+
+// KpiUserTotal est le travail d'un utilisateur sur la période ; Days compte
+// ses journées travaillées, OnTimeDays et LateDays ses arrivées évaluées
+type KpiUserTotal struct {
+	UserID          string
+	Minutes         int
+	Days            int
+	OvertimeMinutes int
+	OnTimeDays      int
+	LateDays        int
+	LateMinutes     int
+	Sessions        int
+	OpenSessions    int
+	Active          bool
+}
+
+// KpiOvertimeWeek regroupe les heures supplémentaires d'un utilisateur sur une
+// semaine coupée par la fenêtre : BeforeMinutes est la part de la semaine avant
+// la fenêtre, dont dépendent les tranches de majoration du reste, Days compte
+// les journées de la fenêtre avec des heures supplémentaires
+type KpiOvertimeWeek struct {
+	UserID        string
+	WeekStart     string
+	BeforeMinutes int
+	Minutes       int
+	ExcessMinutes int
+	Days          int
+}
+
+// KpiPunctualityPeriod regroupe les arrivées évaluées d'une période
+type KpiPunctualityPeriod struct {
+	PeriodStart string
+	OnTime      int
+	Late        int
+	LateMinutes int
+}
+
+// RollupCoverage est une plage de jours (YYYY-MM-DD inclus) dont les cumuls
+// journaliers sont complets ; To vide : sans fin. Rules identifie les règles
+// de calcul avec lesquelles ils ont été reconstruits
+type RollupCoverage struct {
+	From  string
+	To    string
+	Rules string
+}
@@ -10,11 +10,27 @@ import (
 	"time"
 )
 
+type AbsenceType struct {
+	ID                  string   `json:"id"`
+	Name                string   `json:"name"`
+	Paid                bool     `json:"paid"`
+	DeductsBalance      bool     `json:"deductsBalance"`
+	AccrualDaysPerMonth float64  `json:"accrualDaysPerMonth"`
+	MaxBalance          *float64 `json:"maxBalance,omitempty"`
+	IsActive            bool     `json:"isActive"`
+}
+
 type AddUsersToTeamInput struct {
 	UserIDs []string `json:"userIDs"`
 	TeamID  string   `json:"teamID"`
 }
 
+type AdjustLeaveBalanceInput struct {
+	UserID        string  `json:"userID"`
+	AbsenceTypeID string  `json:"absenceTypeID"`
+	Days          float64 `json:"days"`
+}
+
 type AdminKpiDashboard struct {
 	Period       *DateRange            `json:"period"`
 	Summary      *AdminKpiSummary      `json:"summary"`
@@ -24,6 +40,7 @@ type AdminKpiDashboard struct {
 	Compliance   *ComplianceMetrics    `json:"compliance"`
 	Productivity *ProductivityMetrics  `json:"productivity"`
 	Teams        []*TeamDetailedReport `json:"teams"`
+	Comparison   *KpiComparison        `json:"comparison,omitempty"`
 }
 
 type AdminKpiSummary struct {
@@ -35,6 +52,30 @@ type AdminKpiSummary struct {
 	ComplianceRate   float64 `json:"complianceRate"`
 }
 
+type AssignWorkScheduleInput struct {
+	UserID        *string                 `json:"userID,omitempty"`
+	TeamID        *string                 `json:"teamID,omitempty"`
+	EffectiveFrom string                  `json:"effectiveFrom"`
+	EffectiveTo   *string                 `json:"effectiveTo,omitempty"`
+	Days          []*WorkScheduleDayInput `json:"days"`
+}
+
+type Break struct {
+	ID        string     `json:"id"`
+	EntryID   string     `json:"entryID"`
+	BreakType *BreakType `json:"breakType"`
+	StartedAt time.Time  `json:"startedAt"`
+	EndedAt   *time.Time `json:"endedAt,omitempty"`
+	Paid      bool       `json:"paid"`
+}
+
+type BreakType struct {
+	ID       string `json:"id"`
+	Name     string `json:"name"`
+	Paid     bool   `json:"paid"`
+	IsActive bool   `json:"isActive"`
+}
+
 type ComplianceAnomaly struct {
 	Type          string `json:"type"`
 	Count         int32  `json:"count"`
@@ -43,12 +84,60 @@ type ComplianceAnomaly struct {
 }
 
 type ComplianceMetrics struct {
-	MissingEntriesCount    int32                `json:"missingEntriesCount"`
-	IncompleteEntriesCount int32                `json:"incompleteEntriesCount"`
-	AnomaliesCount         int32                `json:"anomaliesCount"`
-	ComplianceRate         float64              `json:"complianceRate"`
-	UsersWithIssues        int32                `json:"usersWithIssues"`
-	Anomalies              []*ComplianceAnomaly `json:"anomalies"`
+	MissingEntriesCount    int32                  `json:"missingEntriesCount"`
+	IncompleteEntriesCount int32                  `json:"incompleteEntriesCount"`
+	AnomaliesCount         int32                  `json:"anomaliesCount"`
+	ComplianceRate         float64                `json:"complianceRate"`
+	UsersWithIssues        int32                  `json:"usersWithIssues"`
+	Anomalies              []*ComplianceAnomaly   `json:"anomalies"`
+	Violations             []*ComplianceViolation `json:"violations"`
+	MissingEntries         []*MissingEntry        `json:"missingEntries"`
+	NoShowsByUser          []*NoShowCount         `json:"noShowsByUser"`
+	NoShowsByTeam          []*NoShowCount         `json:"noShowsByTeam"`
+	AnomaliesByPeriod      []*ComplianceTrend     `json:"anomaliesByPeriod"`
+}
+
+type ComplianceRule struct {
+	Code         ComplianceRuleCode `json:"code"`
+	Enabled      bool               `json:"enabled"`
+	Severity     ComplianceSeverity `json:"severity"`
+	LimitMinutes int32              `json:"limitMinutes"`
+	BreakMinutes *int32             `json:"breakMinutes,omitempty"`
+	Weeks        *int32             `json:"weeks,omitempty"`
+}
+
+type ComplianceRuleInput struct {
+	Enabled      *bool               `json:"enabled,omitempty"`
+	Severity     *ComplianceSeverity `json:"severity,omitempty"`
+	LimitMinutes *int32              `json:"limitMinutes,omitempty"`
+	BreakMinutes *int32              `json:"breakMinutes,omitempty"`
+	Weeks        *int32              `json:"weeks,omitempty"`
+}
+
+type ComplianceTrend struct {
+	Date           string `json:"date"`
+	Anomalies      int32  `json:"anomalies"`
+	MissingEntries int32  `json:"missingEntries"`
+}
+
+type ComplianceViolation struct {
+	Rule          ComplianceRuleCode `json:"rule"`
+	Severity      ComplianceSeverity `json:"severity"`
+	UserID        string             `json:"userID"`
+	UserName      string             `json:"userName"`
+	Date          string             `json:"date"`
+	Detail        string             `json:"detail"`
+	ActualMinutes int32              `json:"actualMinutes"`
+	LimitMinutes  int32              `json:"limitMinutes"`
+}
+
+type ContractInput struct {
+	UserID        string       `json:"userID"`
+	Type          ContractType `json:"type"`
+	WeeklyHours   float64      `json:"weeklyHours"`
+	WorkingDays   []Weekday    `json:"workingDays"`
+	EffectiveFrom string       `json:"effectiveFrom"`
+	EffectiveTo   *string      `json:"effectiveTo,omitempty"`
 }
 
 type CoveragePoint struct {
@@ -56,6 +145,19 @@ type CoveragePoint struct {
 	Count int32     `json:"count"`
 }
 
+type CreateAbsenceTypeInput struct {
+	Name                string   `json:"name"`
+	Paid                bool     `json:"paid"`
+	DeductsBalance      bool     `json:"deductsBalance"`
+	AccrualDaysPerMonth *float64 `json:"accrualDaysPerMonth,omitempty"`
+	MaxBalance          *float64 `json:"maxBalance,omitempty"`
+}
+
+type CreateBreakTypeInput struct {
+	Name string `json:"name"`
+	Paid bool   `json:"paid"`
+}
+
 type CreateMassiveUsersInput struct {
 	Users []*CreateUserInput `json:"users"`
 }
@@ -75,12 +177,30 @@ type CreateTimeEntryInput struct {
 }
 
 type CreateUserInput struct {
-	FirstName string `json:"firstName"`
-	LastName  string `json:"lastName"`
-	Email     string `json:"email"`
-	Phone     string `json:"phone"`
-	Password  string `json:"password"`
-	Role      Role   `json:"role"`
+	FirstName string  `json:"firstName"`
+	LastName  string  `json:"lastName"`
+	Email     string  `json:"email"`
+	Phone     string  `json:"phone"`
+	Password  string  `json:"password"`
+	Role      Role    `json:"role"`
+	TimeZone  *string `json:"timeZone,omitempty"`
+	SiteID    *string `json:"siteID,omitempty"`
+}
+
+type DailyRollup struct {
+	UserID                string     `json:"userID"`
+	UserName              string     `json:"userName"`
+	Day                   string     `json:"day"`
+	WorkedMinutes         int32      `json:"workedMinutes"`
+	UnpaidBreakMinutes    int32      `json:"unpaidBreakMinutes"`
+	OvertimeMinutes       int32      `json:"overtimeMinutes"`
+	OvertimeExcessMinutes int32      `json:"overtimeExcessMinutes"`
+	LateMinutes           *int32     `json:"lateMinutes,omitempty"`
+	FirstArrival          *time.Time `json:"firstArrival,omitempty"`
+	Sessions              int32      `json:"sessions"`
+	OpenSessions          int32      `json:"openSessions"`
+	ActiveSince           *time.Time `json:"activeSince,omitempty"`
+	UpdatedAt             time.Time  `json:"updatedAt"`
 }
 
 type DateRange struct {
@@ -94,26 +214,268 @@ type DayDistribution struct {
 	TotalMinutes int32   `json:"totalMinutes"`
 }
 
+type EmploymentContract struct {
+	ID            string       `json:"id"`
+	UserID        string       `json:"userID"`
+	Type          ContractType `json:"type"`
+	WeeklyHours   float64      `json:"weeklyHours"`
+	WorkingDays   []Weekday    `json:"workingDays"`
+	DailyMinutes  int32        `json:"dailyMinutes"`
+	EffectiveFrom string       `json:"effectiveFrom"`
+	EffectiveTo   *string      `json:"effectiveTo,omitempty"`
+}
+
+type ForecastSlot struct {
+	Time              time.Time `json:"time"`
+	UsualHeadcount    float64   `json:"usualHeadcount"`
+	ExpectedHeadcount float64   `json:"expectedHeadcount"`
+	AbsentUsers       int32     `json:"absentUsers"`
+	Understaffed      bool      `json:"understaffed"`
+}
+
+type Holiday struct {
+	Date string `json:"date"`
+	Name string `json:"name"`
+}
+
+type HolidayCalendar struct {
+	ID       string     `json:"id"`
+	Name     string     `json:"name"`
+	Holidays []*Holiday `json:"holidays"`
+	SiteIDs  []string   `json:"siteIDs"`
+	TeamIDs  []string   `json:"teamIDs"`
+}
+
+type HolidayInput struct {
+	Date string `json:"date"`
+	Name string `json:"name"`
+}
+
+type KpiComparison struct {
+	Mode   KpiComparisonMode `json:"mode"`
+	From   string            `json:"from"`
+	To     string            `json:"to"`
+	Deltas []*KpiDelta       `json:"deltas"`
+}
+
+type KpiComparisonInput struct {
+	Mode KpiComparisonMode `json:"mode"`
+	From *string           `json:"from,omitempty"`
+	To   *string           `json:"to,omitempty"`
+}
+
+type KpiDelta struct {
+	Metric        string   `json:"metric"`
+	Current       float64  `json:"current"`
+	Previous      float64  `json:"previous"`
+	Delta         float64  `json:"delta"`
+	PercentChange *float64 `json:"percentChange,omitempty"`
+}
+
 type KpiPoint struct {
 	Date    string `json:"date"`
 	Minutes int32  `json:"minutes"`
 }
 
+type LatenessEvent struct {
+	ID            string         `json:"id"`
+	EntryID       string         `json:"entryID"`
+	UserID        *User          `json:"userID"`
+	Day           string         `json:"day"`
+	PlannedStart  time.Time      `json:"plannedStart"`
+	Arrival       time.Time      `json:"arrival"`
+	LateMinutes   int32          `json:"lateMinutes"`
+	Justification *string        `json:"justification,omitempty"`
+	Status        LatenessStatus `json:"status"`
+	ReviewerID    *User          `json:"reviewerID,omitempty"`
+	ReviewComment *string        `json:"reviewComment,omitempty"`
+	CreatedAt     time.Time      `json:"createdAt"`
+	ReviewedAt    *time.Time     `json:"reviewedAt,omitempty"`
+}
+
+type LeaveBalance struct {
+	UserID         string       `json:"userID"`
+	AbsenceType    *AbsenceType `json:"absenceType"`
+	Balance        float64      `json:"balance"`
+	PendingDays    float64      `json:"pendingDays"`
+	AccruedThrough *string      `json:"accruedThrough,omitempty"`
+}
+
+type LeaveRequest struct {
+	ID            string       `json:"id"`
+	UserID        *User        `json:"userID"`
+	AbsenceType   *AbsenceType `json:"absenceType"`
+	StartDate     string       `json:"startDate"`
+	EndDate       string       `json:"endDate"`
+	HalfDay       bool         `json:"halfDay"`
+	Days          float64      `json:"days"`
+	Reason        *string      `json:"reason,omitempty"`
+	Status        LeaveStatus  `json:"status"`
+	ReviewerID    *User        `json:"reviewerID,omitempty"`
+	ReviewComment *string      `json:"reviewComment,omitempty"`
+	CreatedAt     time.Time    `json:"createdAt"`
+	ReviewedAt    *time.Time   `json:"reviewedAt,omitempty"`
+}
+
+type MissingEntry struct {
+	UserID          string `json:"userID"`
+	UserName        string `json:"userName"`
+	Date            string `json:"date"`
+	ExpectedMinutes int32  `json:"expectedMinutes"`
+}
+
 type Mutation struct {
 }
 
+type NoShowCount struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+	Days int32  `json:"days"`
+}
+
+type OvertimeBand struct {
+	UpToMinutes *int32 `json:"upToMinutes,omitempty"`
+	RatePercent int32  `json:"ratePercent"`
+}
+
+type OvertimeBandInput struct {
+	UpToMinutes *int32 `json:"upToMinutes,omitempty"`
+	RatePercent int32  `json:"ratePercent"`
+}
+
+type OvertimeBandMinutes struct {
+	RatePercent int32 `json:"ratePercent"`
+	Minutes     int32 `json:"minutes"`
+}
+
 type OvertimeByPeriod struct {
 	PeriodStart  string `json:"periodStart"`
 	TotalMinutes int32  `json:"totalMinutes"`
 	UsersCount   int32  `json:"usersCount"`
 }
 
+type OvertimePolicy struct {
+	ID                     *string         `json:"id,omitempty"`
+	DailyEnabled           bool            `json:"dailyEnabled"`
+	DailyThresholdMinutes  *int32          `json:"dailyThresholdMinutes,omitempty"`
+	WeeklyEnabled          bool            `json:"weeklyEnabled"`
+	WeeklyThresholdMinutes *int32          `json:"weeklyThresholdMinutes,omitempty"`
+	DailyCapMinutes        *int32          `json:"dailyCapMinutes,omitempty"`
+	WeeklyCapMinutes       *int32          `json:"weeklyCapMinutes,omitempty"`
+	Bands                  []*OvertimeBand `json:"bands"`
+	EffectiveFrom          *string         `json:"effectiveFrom,omitempty"`
+}
+
+type OvertimePolicyInput struct {
+	DailyEnabled           bool                 `json:"dailyEnabled"`
+	DailyThresholdMinutes  *int32               `json:"dailyThresholdMinutes,omitempty"`
+	WeeklyEnabled          bool                 `json:"weeklyEnabled"`
+	WeeklyThresholdMinutes *int32               `json:"weeklyThresholdMinutes,omitempty"`
+	DailyCapMinutes        *int32               `json:"dailyCapMinutes,omitempty"`
+	WeeklyCapMinutes       *int32               `json:"weeklyCapMinutes,omitempty"`
+	Bands                  []*OvertimeBandInput `json:"bands"`
+}
+
 type OvertimeReport struct {
-	TotalOvertimeMinutes int32                 `json:"totalOvertimeMinutes"`
-	AvgOvertimePerUser   float64               `json:"avgOvertimePerUser"`
-	UsersWithOvertime    int32                 `json:"usersWithOvertime"`
-	TopOvertimeUsers     []*UserOvertimeDetail `json:"topOvertimeUsers"`
-	OvertimeByWeek       []*OvertimeByPeriod   `json:"overtimeByWeek"`
+	TotalOvertimeMinutes int32                  `json:"totalOvertimeMinutes"`
+	TotalPremiumMinutes  int32                  `json:"totalPremiumMinutes"`
+	TotalExcessMinutes   int32                  `json:"totalExcessMinutes"`
+	Bands                []*OvertimeBandMinutes `json:"bands"`
+	AvgOvertimePerUser   float64                `json:"avgOvertimePerUser"`
+	UsersWithOvertime    int32                  `json:"usersWithOvertime"`
+	TopOvertimeUsers     []*UserOvertimeDetail  `json:"topOvertimeUsers"`
+	OvertimeByWeek       []*OvertimeByPeriod    `json:"overtimeByWeek"`
+}
+
+type PayCode struct {
+	ID          string       `json:"id"`
+	Category    PayCategory  `json:"category"`
+	RatePercent *int32       `json:"ratePercent,omitempty"`
+	AbsenceType *AbsenceType `json:"absenceType,omitempty"`
+	Code        string       `json:"code"`
+	Label       *string      `json:"label,omitempty"`
+}
+
+type PayCodeInput struct {
+	Category      PayCategory `json:"category"`
+	RatePercent   *int32      `json:"ratePercent,omitempty"`
+	AbsenceTypeID *string     `json:"absenceTypeID,omitempty"`
+	Code          string      `json:"code"`
+	Label         *string     `json:"label,omitempty"`
+}
+
+type PayPeriod struct {
+	ID           string          `json:"id"`
+	StartDate    string          `json:"startDate"`
+	EndDate      string          `json:"endDate"`
+	Status       PayPeriodStatus `json:"status"`
+	ClosedByID   *User           `json:"closedByID,omitempty"`
+	ClosedAt     time.Time       `json:"closedAt"`
+	ReopenedByID *User           `json:"reopenedByID,omitempty"`
+	ReopenReason *string         `json:"reopenReason,omitempty"`
+	ReopenedAt   *time.Time      `json:"reopenedAt,omitempty"`
+}
+
+type PayrollColumn struct {
+	Field  PayrollField  `json:"field"`
+	Header *string       `json:"header,omitempty"`
+	Width  *int32        `json:"width,omitempty"`
+	Align  *PayrollAlign `json:"align,omitempty"`
+}
+
+type PayrollColumnInput struct {
+	Field  PayrollField  `json:"field"`
+	Header *string       `json:"header,omitempty"`
+	Width  *int32        `json:"width,omitempty"`
+	Align  *PayrollAlign `json:"align,omitempty"`
+}
+
+type PayrollLayout struct {
+	ID        string           `json:"id"`
+	Name      string           `json:"name"`
+	Format    PayrollFormat    `json:"format"`
+	Delimiter string           `json:"delimiter"`
+	Header    bool             `json:"header"`
+	Columns   []*PayrollColumn `json:"columns"`
+}
+
+type PayrollLayoutInput struct {
+	Name      string                `json:"name"`
+	Format    PayrollFormat         `json:"format"`
+	Delimiter *string               `json:"delimiter,omitempty"`
+	Header    *bool                 `json:"header,omitempty"`
+	Columns   []*PayrollColumnInput `json:"columns"`
+}
+
+type PlannedSchedule struct {
+	UserID         string         `json:"userID"`
+	Date           string         `json:"date"`
+	Source         ScheduleSource `json:"source"`
+	ScheduleID     *string        `json:"scheduleID,omitempty"`
+	Holiday        *string        `json:"holiday,omitempty"`
+	WorkingDay     bool           `json:"workingDay"`
+	Start          *time.Time     `json:"start,omitempty"`
+	End            *time.Time     `json:"end,omitempty"`
+	CoreStart      *time.Time     `json:"coreStart,omitempty"`
+	Remote         bool           `json:"remote"`
+	PlannedMinutes int32          `json:"plannedMinutes"`
+}
+
+type PresenceCell struct {
+	Weekday          Weekday `json:"weekday"`
+	Hour             int32   `json:"hour"`
+	AvgPresent       float64 `json:"avgPresent"`
+	MaxPresent       int32   `json:"maxPresent"`
+	UnderstaffedRate float64 `json:"understaffedRate"`
+}
+
+type PresenceHeatmap struct {
+	From             string          `json:"from"`
+	To               string          `json:"to"`
+	TeamID           string          `json:"teamID"`
+	MinHeadcount     int32           `json:"minHeadcount"`
+	UnderstaffedRate float64         `json:"understaffedRate"`
+	Cells            []*PresenceCell `json:"cells"`
 }
 
 type ProductivityMetrics struct {
@@ -149,6 +511,22 @@ type PunctualityTrend struct {
 type Query struct {
 }
 
+type RequestLeaveInput struct {
+	AbsenceTypeID string  `json:"absenceTypeID"`
+	StartDate     string  `json:"startDate"`
+	EndDate       string  `json:"endDate"`
+	HalfDay       *bool   `json:"halfDay,omitempty"`
+	Reason        *string `json:"reason,omitempty"`
+}
+
+type RequestTimeEntryCorrectionInput struct {
+	EntryID           *string    `json:"entryID,omitempty"`
+	Day               string     `json:"day"`
+	ProposedArrival   time.Time  `json:"proposedArrival"`
+	ProposedDeparture *time.Time `json:"proposedDeparture,omitempty"`
+	Reason            string     `json:"reason"`
+}
+
 type SignUpInput struct {
 	FirstName string `json:"firstName"`
 	LastName  string `json:"lastName"`
@@ -158,15 +536,37 @@ type SignUpInput struct {
 }
 
 type SignedUser struct {
-	ID            string  `json:"id"`
-	FirstName     string  `json:"firstName"`
-	LastName      string  `json:"lastName"`
-	Email         string  `json:"email"`
-	Password      string  `json:"password"`
-	Role          Role    `json:"role"`
-	Phone         string  `json:"phone"`
-	HasStartedDay bool    `json:"hasStartedDay"`
-	StartedAt     *string `json:"startedAt,omitempty"`
+	ID                 string  `json:"id"`
+	FirstName          string  `json:"firstName"`
+	LastName           string  `json:"lastName"`
+	Email              string  `json:"email"`
+	Password           string  `json:"password"`
+	Role               Role    `json:"role"`
+	Phone              string  `json:"phone"`
+	HasStartedDay      bool    `json:"hasStartedDay"`
+	StartedAt          *string `json:"startedAt,omitempty"`
+	WorkedMinutesToday int32   `json:"workedMinutesToday"`
+}
+
+type Site struct {
+	ID       string `json:"id"`
+	Name     string `json:"name"`
+	TimeZone string `json:"timeZone"`
+}
+
+type SiteInput struct {
+	Name     string `json:"name"`
+	TimeZone string `json:"timeZone"`
+}
+
+type StaffingForecast struct {
+	TeamID            string          `json:"teamID"`
+	HistoryFrom       string          `json:"historyFrom"`
+	HistoryTo         string          `json:"historyTo"`
+	WeeklyTrend       float64         `json:"weeklyTrend"`
+	MinHeadcount      int32           `json:"minHeadcount"`
+	UnderstaffedSlots int32           `json:"understaffedSlots"`
+	Slots             []*ForecastSlot `json:"slots"`
 }
 
 type Team struct {
@@ -186,6 +586,7 @@ type TeamDetailedReport struct {
 	ActiveNow            int32                     `json:"activeNow"`
 	TopContributors      []*TeamMemberContribution `json:"topContributors"`
 	WorkloadDistribution []*WorkloadDistribution   `json:"workloadDistribution"`
+	WorkedByPeriod       []*KpiPoint               `json:"workedByPeriod"`
 }
 
 type TeamKpiSummary struct {
@@ -196,6 +597,7 @@ type TeamKpiSummary struct {
 	AvgWorkedMinutesPerUser float64          `json:"avgWorkedMinutesPerUser"`
 	ActiveUsers             int32            `json:"activeUsers"`
 	Coverage                []*CoveragePoint `json:"coverage"`
+	Comparison              *KpiComparison   `json:"comparison,omitempty"`
 }
 
 type TeamMemberContribution struct {
@@ -211,6 +613,21 @@ type TeamUser struct {
 	TeamID *Team `json:"teamID"`
 }
 
+type TimeEntryCorrection struct {
+	ID                string           `json:"id"`
+	EntryID           *string          `json:"entryID,omitempty"`
+	UserID            *User            `json:"userID"`
+	Day               string           `json:"day"`
+	ProposedArrival   time.Time        `json:"proposedArrival"`
+	ProposedDeparture *time.Time       `json:"proposedDeparture,omitempty"`
+	Reason            string           `json:"reason"`
+	Status            CorrectionStatus `json:"status"`
+	ReviewerID        *User            `json:"reviewerID,omitempty"`
+	ReviewComment     *string          `json:"reviewComment,omitempty"`
+	CreatedAt         time.Time        `json:"createdAt"`
+	ReviewedAt        *time.Time       `json:"reviewedAt,omitempty"`
+}
+
 type TimeTable struct {
 	ID            string     `json:"id"`
 	Start         time.Time  `json:"start"`
@@ -221,12 +638,45 @@ type TimeTable struct {
 }
 
 type TimeTableEntry struct {
-	ID        string     `json:"id"`
-	UserID    *User      `json:"userID"`
-	Day       string     `json:"day"`
-	Arrival   time.Time  `json:"arrival"`
-	Departure *time.Time `json:"departure,omitempty"`
-	Status    bool       `json:"status"`
+	ID          string     `json:"id"`
+	UserID      *User      `json:"userID"`
+	Day         string     `json:"day"`
+	Arrival     time.Time  `json:"arrival"`
+	Departure   *time.Time `json:"departure,omitempty"`
+	Status      bool       `json:"status"`
+	Breaks      []*Break   `json:"breaks"`
+	AutoClosed  bool       `json:"autoClosed"`
+	NeedsReview bool       `json:"needsReview"`
+}
+
+type Timesheet struct {
+	ID              *string         `json:"id,omitempty"`
+	UserID          *User           `json:"userID"`
+	WeekStart       string          `json:"weekStart"`
+	WeekEnd         string          `json:"weekEnd"`
+	Status          TimesheetStatus `json:"status"`
+	WorkedMinutes   int32           `json:"workedMinutes"`
+	OvertimeMinutes int32           `json:"overtimeMinutes"`
+	Days            []*DailyRollup  `json:"days"`
+	SubmittedAt     *time.Time      `json:"submittedAt,omitempty"`
+	ReviewerID      *User           `json:"reviewerID,omitempty"`
+	ReviewComment   *string         `json:"reviewComment,omitempty"`
+	ReviewedAt      *time.Time      `json:"reviewedAt,omitempty"`
+}
+
+type UpdateAbsenceTypeInput struct {
+	Name                *string  `json:"name,omitempty"`
+	Paid                *bool    `json:"paid,omitempty"`
+	DeductsBalance      *bool    `json:"deductsBalance,omitempty"`
+	AccrualDaysPerMonth *float64 `json:"accrualDaysPerMonth,omitempty"`
+	MaxBalance          *float64 `json:"maxBalance,omitempty"`
+	IsActive            *bool    `json:"isActive,omitempty"`
+}
+
+type UpdateBreakTypeInput struct {
+	Name     *string `json:"name,omitempty"`
+	Paid     *bool   `json:"paid,omitempty"`
+	IsActive *bool   `json:"isActive,omitempty"`
 }
 
 type UpdateProfileInput struct {
@@ -235,6 +685,7 @@ type UpdateProfileInput struct {
 	Email     *string `json:"email,omitempty"`
 	Phone     *string `json:"phone,omitempty"`
 	Password  *string `json:"password,omitempty"`
+	TimeZone  *string `json:"timeZone,omitempty"`
 }
 
 type UpdateTeamInput struct {
@@ -258,29 +709,40 @@ type UpdateUserInput struct {
 	Phone     *string `json:"phone,omitempty"`
 	Password  *string `json:"password,omitempty"`
 	Role      *Role   `json:"role,omitempty"`
+	TimeZone  *string `json:"timeZone,omitempty"`
+	SiteID    *string `json:"siteID,omitempty"`
 }
 
 type User struct {
-	ID        string `json:"id"`
-	FirstName string `json:"firstName"`
-	LastName  string `json:"lastName"`
-	Email     string `json:"email"`
-	Phone     string `json:"phone"`
-	Password  string `json:"password"`
-	Role      Role   `json:"role"`
+	ID        string  `json:"id"`
+	FirstName string  `json:"firstName"`
+	LastName  string  `json:"lastName"`
+	Email     string  `json:"email"`
+	Phone     string  `json:"phone"`
+	Password  string  `json:"password"`
+	Role      Role    `json:"role"`
+	TimeZone  *string `json:"timeZone,omitempty"`
+	SiteID    *string `json:"siteID,omitempty"`
 }
 
 type UserKpiSummary struct {
-	From              string      `json:"from"`
-	To                string      `json:"to"`
-	UserID            string      `json:"userID"`
-	WorkedMinutes     int32       `json:"workedMinutes"`
-	OvertimeMinutes   int32       `json:"overtimeMinutes"`
-	DaysPresent       int32       `json:"daysPresent"`
-	CurrentStreakDays int32       `json:"currentStreakDays"`
-	PunctualityRate   float64     `json:"punctualityRate"`
-	PresentNow        bool        `json:"presentNow"`
-	DailyWorked       []*KpiPoint `json:"dailyWorked"`
+	From                   string                 `json:"from"`
+	To                     string                 `json:"to"`
+	UserID                 string                 `json:"userID"`
+	WorkedMinutes          int32                  `json:"workedMinutes"`
+	UnpaidBreakMinutes     int32                  `json:"unpaidBreakMinutes"`
+	OvertimeMinutes        int32                  `json:"overtimeMinutes"`
+	OvertimePremiumMinutes int32                  `json:"overtimePremiumMinutes"`
+	OvertimeExcessMinutes  int32                  `json:"overtimeExcessMinutes"`
+	OvertimeBands          []*OvertimeBandMinutes `json:"overtimeBands"`
+	DaysPresent            int32                  `json:"daysPresent"`
+	LeaveDays              float64                `json:"leaveDays"`
+	CurrentStreakDays      int32                  `json:"currentStreakDays"`
+	PunctualityRate        float64                `json:"punctualityRate"`
+	PresentNow             bool                   `json:"presentNow"`
+	AutoClosedEntries      int32                  `json:"autoClosedEntries"`
+	DailyWorked            []*KpiPoint            `json:"dailyWorked"`
+	Comparison             *KpiComparison         `json:"comparison,omitempty"`
 }
 
 type UserLogged struct {
@@ -296,6 +758,7 @@ type UserOvertimeDetail struct {
 	UserID          string `json:"userID"`
 	UserName        string `json:"userName"`
 	OvertimeMinutes int32  `json:"overtimeMinutes"`
+	PremiumMinutes  int32  `json:"premiumMinutes"`
 	DaysWorked      int32  `json:"daysWorked"`
 }
 
@@ -318,6 +781,31 @@ type UserWithAllData struct {
 	TimeTableEntries []*TimeTableEntry `json:"timeTableEntries"`
 }
 
+type WorkSchedule struct {
+	ID            string             `json:"id"`
+	UserID        *string            `json:"userID,omitempty"`
+	TeamID        *string            `json:"teamID,omitempty"`
+	EffectiveFrom string             `json:"effectiveFrom"`
+	EffectiveTo   *string            `json:"effectiveTo,omitempty"`
+	Days          []*WorkScheduleDay `json:"days"`
+}
+
+type WorkScheduleDay struct {
+	Weekday   Weekday `json:"weekday"`
+	Start     string  `json:"start"`
+	End       string  `json:"end"`
+	CoreStart *string `json:"coreStart,omitempty"`
+	Remote    bool    `json:"remote"`
+}
+
+type WorkScheduleDayInput struct {
+	Weekday   Weekday `json:"weekday"`
+	Start     string  `json:"start"`
+	End       string  `json:"end"`
+	CoreStart *string `json:"coreStart,omitempty"`
+	Remote    *bool   `json:"remote,omitempty"`
+}
+
 type WorkloadAnalysis struct {
 	AvgDailyMinutes   float64            `json:"avgDailyMinutes"`
 	AvgWeeklyMinutes  float64            `json:"avgWeeklyMinutes"`
@@ -326,6 +814,7 @@ type WorkloadAnalysis struct {
 	DistributionByDay []*DayDistribution `json:"distributionByDay"`
 	TotalOvertime     int32              `json:"totalOvertime"`
 	UsersWithOvertime int32              `json:"usersWithOvertime"`
+	WorkedByPeriod    []*KpiPoint        `json:"workedByPeriod"`
 }
 
 type WorkloadDistribution struct {
@@ -334,50 +823,54 @@ type WorkloadDistribution struct {
 	Percentage float64 `json:"percentage"`
 }
 
-type Role string
+type ComplianceRuleCode string
 
 const (
-	RoleUser    Role = "USER"
-	RoleAdmin   Role = "ADMIN"
-	RoleManager Role = "MANAGER"
+	ComplianceRuleCodeMinDailyRest   ComplianceRuleCode = "MIN_DAILY_REST"
+	ComplianceRuleCodeMaxWeeklyHours ComplianceRuleCode = "MAX_WEEKLY_HOURS"
+	ComplianceRuleCodeAvgWeeklyHours ComplianceRuleCode = "AVG_WEEKLY_HOURS"
+	ComplianceRuleCodeMandatoryBreak ComplianceRuleCode = "MANDATORY_BREAK"
+	ComplianceRuleCodeMaxDailyHours  ComplianceRuleCode = "MAX_DAILY_HOURS"
 )
 
-var AllRole = []Role{
-	RoleUser,
-	RoleAdmin,
-	RoleManager,
+var AllComplianceRuleCode = []ComplianceRuleCode{
+	ComplianceRuleCodeMinDailyRest,
+	ComplianceRuleCodeMaxWeeklyHours,
+	ComplianceRuleCodeAvgWeeklyHours,
+	ComplianceRuleCodeMandatoryBreak,
+	ComplianceRuleCodeMaxDailyHours,
 }
 
-func (e Role) IsValid() bool {
+func (e ComplianceRuleCode) IsValid() bool {
 	switch e {
-	case RoleUser, RoleAdmin, RoleManager:
+	case ComplianceRuleCodeMinDailyRest, ComplianceRuleCodeMaxWeeklyHours, ComplianceRuleCodeAvgWeeklyHours, ComplianceRuleCodeMandatoryBreak, ComplianceRuleCodeMaxDailyHours:
 		return true
 	}
 	return false
 }
 
-func (e Role) String() string {
+func (e ComplianceRuleCode) String() string {
 	return string(e)
 }
 
-func (e *Role) UnmarshalGQL(v any) error {
+func (e *ComplianceRuleCode) UnmarshalGQL(v any) error {
 	str, ok := v.(string)
 	if !ok {
 		return fmt.Errorf("enums must be strings")
 	}
 
-	*e = Role(str)
+	*e = ComplianceRuleCode(str)
 	if !e.IsValid() {
-		return fmt.Errorf("%s is not a valid Role", str)
+		return fmt.Errorf("%s is not a valid ComplianceRuleCode", str)
 	}
 	return nil
 }
 
-func (e Role) MarshalGQL(w io.Writer) {
+func (e ComplianceRuleCode) MarshalGQL(w io.Writer) {
 	fmt.Fprint(w, strconv.Quote(e.String()))
 }
 
-func (e *Role) UnmarshalJSON(b []byte) error {
+func (e *ComplianceRuleCode) UnmarshalJSON(b []byte) error {
 	s, err := strconv.Unquote(string(b))
 	if err != nil {
 		return err
@@ -385,7 +878,961 @@ func (e *Role) UnmarshalJSON(b []byte) error {
 	return e.UnmarshalGQL(s)
 }
 
-func (e Role) MarshalJSON() ([]byte, error) {
+func (e ComplianceRuleCode) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	e.MarshalGQL(&buf)
+	return buf.Bytes(), nil
+}
+
+type ComplianceSeverity string
+
+const (
+	ComplianceSeverityLow    ComplianceSeverity = "LOW"
+	ComplianceSeverityMedium ComplianceSeverity = "MEDIUM"
+	ComplianceSeverityHigh   ComplianceSeverity = "HIGH"
+)
+
+var AllComplianceSeverity = []ComplianceSeverity{
+	ComplianceSeverityLow,
+	ComplianceSeverityMedium,
+	ComplianceSeverityHigh,
+}
+
+func (e ComplianceSeverity) IsValid() bool {
+	switch e {
+	case ComplianceSeverityLow, ComplianceSeverityMedium, ComplianceSeverityHigh:
+		return true
+	}
+	return false
+}
+
+func (e ComplianceSeverity) String() string {
+	return string(e)
+}
+
+func (e *ComplianceSeverity) UnmarshalGQL(v any) error {
+	str, ok := v.(string)
+	if !ok {
+		return fmt.Errorf("enums must be strings")
+	}
+
+	*e = ComplianceSeverity(str)
+	if !e.IsValid() {
+		return fmt.Errorf("%s is not a valid ComplianceSeverity", str)
+	}
+	return nil
+}
+
+func (e ComplianceSeverity) MarshalGQL(w io.Writer) {
+	fmt.Fprint(w, strconv.Quote(e.String()))
+}
+
+func (e *ComplianceSeverity) UnmarshalJSON(b []byte) error {
+	s, err := strconv.Unquote(string(b))
+	if err != nil {
+		return err
+	}
+	return e.UnmarshalGQL(s)
+}
+
+func (e ComplianceSeverity) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	e.MarshalGQL(&buf)
+	return buf.Bytes(), nil
+}
+
+type ContractType string
+
+const (
+	ContractTypePermanent      ContractType = "PERMANENT"
+	ContractTypeFixedTerm      ContractType = "FIXED_TERM"
+	ContractTypeTemporary      ContractType = "TEMPORARY"
+	ContractTypeInternship     ContractType = "INTERNSHIP"
+	ContractTypeApprenticeship ContractType = "APPRENTICESHIP"
+)
+
+var AllContractType = []ContractType{
+	ContractTypePermanent,
+	ContractTypeFixedTerm,
+	ContractTypeTemporary,
+	ContractTypeInternship,
+	ContractTypeApprenticeship,
+}
+
+func (e ContractType) IsValid() bool {
+	switch e {
+	case ContractTypePermanent, ContractTypeFixedTerm, ContractTypeTemporary, ContractTypeInternship, ContractTypeApprenticeship:
+		return true
+	}
+	return false
+}
+
+func (e ContractType) String() string {
+	return string(e)
+}
+
+func (e *ContractType) UnmarshalGQL(v any) error {
+	str, ok := v.(string)
+	if !ok {
+		return fmt.Errorf("enums must be strings")
+	}
+
+	*e = ContractType(str)
+	if !e.IsValid() {
+		return fmt.Errorf("%s is not a valid ContractType", str)
+	}
+	return nil
+}
+
+func (e ContractType) MarshalGQL(w io.Writer) {
+	fmt.Fprint(w, strconv.Quote(e.String()))
+}
+
+func (e *ContractType) UnmarshalJSON(b []byte) error {
+	s, err := strconv.Unquote(string(b))
+	if err != nil {
+		return err
+	}
+	return e.UnmarshalGQL(s)
+}
+
+func (e ContractType) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	e.MarshalGQL(&buf)
+	return buf.Bytes(), nil
+}
+
+type CorrectionStatus string
+
+const (
+	CorrectionStatusPending  CorrectionStatus = "PENDING"
+	CorrectionStatusApproved CorrectionStatus = "APPROVED"
+	CorrectionStatusRejected CorrectionStatus = "REJECTED"
+)
+
+var AllCorrectionStatus = []CorrectionStatus{
+	CorrectionStatusPending,
+	CorrectionStatusApproved,
+	CorrectionStatusRejected,
+}
+
+func (e CorrectionStatus) IsValid() bool {
+	switch e {
+	case CorrectionStatusPending, CorrectionStatusApproved, CorrectionStatusRejected:
+		return true
+	}
+	return false
+}
+
+func (e CorrectionStatus) String() string {
+	return string(e)
+}
+
+func (e *CorrectionStatus) UnmarshalGQL(v any) error {
+	str, ok := v.(string)
+	if !ok {
+		return fmt.Errorf("enums must be strings")
+	}
+
+	*e = CorrectionStatus(str)
+	if !e.IsValid() {
+		return fmt.Errorf("%s is not a valid CorrectionStatus", str)
+	}
+	return nil
+}
+
+func (e CorrectionStatus) MarshalGQL(w io.Writer) {
+	fmt.Fprint(w, strconv.Quote(e.String()))
+}
+
+func (e *CorrectionStatus) UnmarshalJSON(b []byte) error {
+	s, err := strconv.Unquote(string(b))
+	if err != nil {
+		return err
+	}
+	return e.UnmarshalGQL(s)
+}
+
+func (e CorrectionStatus) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	e.MarshalGQL(&buf)
+	return buf.Bytes(), nil
+}
+
+type KpiComparisonMode string
+
+const (
+	KpiComparisonModePreviousPeriod     KpiComparisonMode = "PREVIOUS_PERIOD"
+	KpiComparisonModeSamePeriodLastYear KpiComparisonMode = "SAME_PERIOD_LAST_YEAR"
+	KpiComparisonModeCustom             KpiComparisonMode = "CUSTOM"
+)
+
+var AllKpiComparisonMode = []KpiComparisonMode{
+	KpiComparisonModePreviousPeriod,
+	KpiComparisonModeSamePeriodLastYear,
+	KpiComparisonModeCustom,
+}
+
+func (e KpiComparisonMode) IsValid() bool {
+	switch e {
+	case KpiComparisonModePreviousPeriod, KpiComparisonModeSamePeriodLastYear, KpiComparisonModeCustom:
+		return true
+	}
+	return false
+}
+
+func (e KpiComparisonMode) String() string {
+	return string(e)
+}
+
+func (e *KpiComparisonMode) UnmarshalGQL(v any) error {
+	str, ok := v.(string)
+	if !ok {
+		return fmt.Errorf("enums must be strings")
+	}
+
+	*e = KpiComparisonMode(str)
+	if !e.IsValid() {
+		return fmt.Errorf("%s is not a valid KpiComparisonMode", str)
+	}
+	return nil
+}
+
+func (e KpiComparisonMode) MarshalGQL(w io.Writer) {
+	fmt.Fprint(w, strconv.Quote(e.String()))
+}
+
+func (e *KpiComparisonMode) UnmarshalJSON(b []byte) error {
+	s, err := strconv.Unquote(string(b))
+	if err != nil {
+		return err
+	}
+	return e.UnmarshalGQL(s)
+}
+
+func (e KpiComparisonMode) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	e.MarshalGQL(&buf)
+	return buf.Bytes(), nil
+}
+
+type KpiGranularity string
+
+const (
+	KpiGranularityHour    KpiGranularity = "HOUR"
+	KpiGranularityDay     KpiGranularity = "DAY"
+	KpiGranularityWeek    KpiGranularity = "WEEK"
+	KpiGranularityMonth   KpiGranularity = "MONTH"
+	KpiGranularityQuarter KpiGranularity = "QUARTER"
+)
+
+var AllKpiGranularity = []KpiGranularity{
+	KpiGranularityHour,
+	KpiGranularityDay,
+	KpiGranularityWeek,
+	KpiGranularityMonth,
+	KpiGranularityQuarter,
+}
+
+func (e KpiGranularity) IsValid() bool {
+	switch e {
+	case KpiGranularityHour, KpiGranularityDay, KpiGranularityWeek, KpiGranularityMonth, KpiGranularityQuarter:
+		return true
+	}
+	return false
+}
+
+func (e KpiGranularity) String() string {
+	return string(e)
+}
+
+func (e *KpiGranularity) UnmarshalGQL(v any) error {
+	str, ok := v.(string)
+	if !ok {
+		return fmt.Errorf("enums must be strings")
+	}
+
+	*e = KpiGranularity(str)
+	if !e.IsValid() {
+		return fmt.Errorf("%s is not a valid KpiGranularity", str)
+	}
+	return nil
+}
+
+func (e KpiGranularity) MarshalGQL(w io.Writer) {
+	fmt.Fprint(w, strconv.Quote(e.String()))
+}
+
+func (e *KpiGranularity) UnmarshalJSON(b []byte) error {
+	s, err := strconv.Unquote(string(b))
+	if err != nil {
+		return err
+	}
+	return e.UnmarshalGQL(s)
+}
+
+func (e KpiGranularity) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	e.MarshalGQL(&buf)
+	return buf.Bytes(), nil
+}
+
+type LatenessStatus string
+
+const (
+	LatenessStatusPending   LatenessStatus = "PENDING"
+	LatenessStatusJustified LatenessStatus = "JUSTIFIED"
+	LatenessStatusExcused   LatenessStatus = "EXCUSED"
+	LatenessStatusUnexcused LatenessStatus = "UNEXCUSED"
+)
+
+var AllLatenessStatus = []LatenessStatus{
+	LatenessStatusPending,
+	LatenessStatusJustified,
+	LatenessStatusExcused,
+	LatenessStatusUnexcused,
+}
+
+func (e LatenessStatus) IsValid() bool {
+	switch e {
+	case LatenessStatusPending, LatenessStatusJustified, LatenessStatusExcused, LatenessStatusUnexcused:
+		return true
+	}
+	return false
+}
+
+func (e LatenessStatus) String() string {
+	return string(e)
+}
+
+func (e *LatenessStatus) UnmarshalGQL(v any) error {
+	str, ok := v.(string)
+	if !ok {
+		return fmt.Errorf("enums must be strings")
+	}
+
+	*e = LatenessStatus(str)
+	if !e.IsValid() {
+		return fmt.Errorf("%s is not a valid LatenessStatus", str)
+	}
+	return nil
+}
+
+func (e LatenessStatus) MarshalGQL(w io.Writer) {
+	fmt.Fprint(w, strconv.Quote(e.String()))
+}
+
+func (e *LatenessStatus) UnmarshalJSON(b []byte) error {
+	s, err := strconv.Unquote(string(b))
+	if err != nil {
+		return err
+	}
+	return e.UnmarshalGQL(s)
+}
+
+func (e LatenessStatus) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	e.MarshalGQL(&buf)
+	return buf.Bytes(), nil
+}
+
+type LeaveStatus string
+
+const (
+	LeaveStatusPending   LeaveStatus = "PENDING"
+	LeaveStatusApproved  LeaveStatus = "APPROVED"
+	LeaveStatusRejected  LeaveStatus = "REJECTED"
+	LeaveStatusCancelled LeaveStatus = "CANCELLED"
+)
+
+var AllLeaveStatus = []LeaveStatus{
+	LeaveStatusPending,
+	LeaveStatusApproved,
+	LeaveStatusRejected,
+	LeaveStatusCancelled,
+}
+
+func (e LeaveStatus) IsValid() bool {
+	switch e {
+	case LeaveStatusPending, LeaveStatusApproved, LeaveStatusRejected, LeaveStatusCancelled:
+		return true
+	}
+	return false
+}
+
+func (e LeaveStatus) String() string {
+	return string(e)
+}
+
+func (e *LeaveStatus) UnmarshalGQL(v any) error {
+	str, ok := v.(string)
+	if !ok {
+		return fmt.Errorf("enums must be strings")
+	}
+
+	*e = LeaveStatus(str)
+	if !e.IsValid() {
+		return fmt.Errorf("%s is not a valid LeaveStatus", str)
+	}
+	return nil
+}
+
+func (e LeaveStatus) MarshalGQL(w io.Writer) {
+	fmt.Fprint(w, strconv.Quote(e.String()))
+}
+
+func (e *LeaveStatus) UnmarshalJSON(b []byte) error {
+	s, err := strconv.Unquote(string(b))
+	if err != nil {
+		return err
+	}
+	return e.UnmarshalGQL(s)
+}
+
+func (e LeaveStatus) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	e.MarshalGQL(&buf)
+	return buf.Bytes(), nil
+}
+
+type PayCategory string
+
+const (
+	PayCategoryNormal         PayCategory = "NORMAL"
+	PayCategoryOvertime       PayCategory = "OVERTIME"
+	PayCategoryOvertimeExcess PayCategory = "OVERTIME_EXCESS"
+	PayCategoryNight          PayCategory = "NIGHT"
+	PayCategorySunday         PayCategory = "SUNDAY"
+	PayCategoryHoliday        PayCategory = "HOLIDAY"
+	PayCategoryAbsence        PayCategory = "ABSENCE"
+)
+
+var AllPayCategory = []PayCategory{
+	PayCategoryNormal,
+	PayCategoryOvertime,
+	PayCategoryOvertimeExcess,
+	PayCategoryNight,
+	PayCategorySunday,
+	PayCategoryHoliday,
+	PayCategoryAbsence,
+}
+
+func (e PayCategory) IsValid() bool {
+	switch e {
+	case PayCategoryNormal, PayCategoryOvertime, PayCategoryOvertimeExcess, PayCategoryNight, PayCategorySunday, PayCategoryHoliday, PayCategoryAbsence:
+		return true
+	}
+	return false
+}
+
+func (e PayCategory) String() string {
+	return string(e)
+}
+
+func (e *PayCategory) UnmarshalGQL(v any) error {
+	str, ok := v.(string)
+	if !ok {
+		return fmt.Errorf("enums must be strings")
+	}
+
+	*e = PayCategory(str)
+	if !e.IsValid() {
+		return fmt.Errorf("%s is not a valid PayCategory", str)
+	}
+	return nil
+}
+
+func (e PayCategory) MarshalGQL(w io.Writer) {
+	fmt.Fprint(w, strconv.Quote(e.String()))
+}
+
+func (e *PayCategory) UnmarshalJSON(b []byte) error {
+	s, err := strconv.Unquote(string(b))
+	if err != nil {
+		return err
+	}
+	return e.UnmarshalGQL(s)
+}
+
+func (e PayCategory) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	e.MarshalGQL(&buf)
+	return buf.Bytes(), nil
+}
+
+type PayPeriodStatus string
+
+const (
+	PayPeriodStatusClosed   PayPeriodStatus = "CLOSED"
+	PayPeriodStatusReopened PayPeriodStatus = "REOPENED"
+)
+
+var AllPayPeriodStatus = []PayPeriodStatus{
+	PayPeriodStatusClosed,
+	PayPeriodStatusReopened,
+}
+
+func (e PayPeriodStatus) IsValid() bool {
+	switch e {
+	case PayPeriodStatusClosed, PayPeriodStatusReopened:
+		return true
+	}
+	return false
+}
+
+func (e PayPeriodStatus) String() string {
+	return string(e)
+}
+
+func (e *PayPeriodStatus) UnmarshalGQL(v any) error {
+	str, ok := v.(string)
+	if !ok {
+		return fmt.Errorf("enums must be strings")
+	}
+
+	*e = PayPeriodStatus(str)
+	if !e.IsValid() {
+		return fmt.Errorf("%s is not a valid PayPeriodStatus", str)
+	}
+	return nil
+}
+
+func (e PayPeriodStatus) MarshalGQL(w io.Writer) {
+	fmt.Fprint(w, strconv.Quote(e.String()))
+}
+
+func (e *PayPeriodStatus) UnmarshalJSON(b []byte) error {
+	s, err := strconv.Unquote(string(b))
+	if err != nil {
+		return err
+	}
+	return e.UnmarshalGQL(s)
+}
+
+func (e PayPeriodStatus) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	e.MarshalGQL(&buf)
+	return buf.Bytes(), nil
+}
+
+type PayrollAlign string
+
+const (
+	PayrollAlignLeft  PayrollAlign = "LEFT"
+	PayrollAlignRight PayrollAlign = "RIGHT"
+)
+
+var AllPayrollAlign = []PayrollAlign{
+	PayrollAlignLeft,
+	PayrollAlignRight,
+}
+
+func (e PayrollAlign) IsValid() bool {
+	switch e {
+	case PayrollAlignLeft, PayrollAlignRight:
+		return true
+	}
+	return false
+}
+
+func (e PayrollAlign) String() string {
+	return string(e)
+}
+
+func (e *PayrollAlign) UnmarshalGQL(v any) error {
+	str, ok := v.(string)
+	if !ok {
+		return fmt.Errorf("enums must be strings")
+	}
+
+	*e = PayrollAlign(str)
+	if !e.IsValid() {
+		return fmt.Errorf("%s is not a valid PayrollAlign", str)
+	}
+	return nil
+}
+
+func (e PayrollAlign) MarshalGQL(w io.Writer) {
+	fmt.Fprint(w, strconv.Quote(e.String()))
+}
+
+func (e *PayrollAlign) UnmarshalJSON(b []byte) error {
+	s, err := strconv.Unquote(string(b))
+	if err != nil {
+		return err
+	}
+	return e.UnmarshalGQL(s)
+}
+
+func (e PayrollAlign) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	e.MarshalGQL(&buf)
+	return buf.Bytes(), nil
+}
+
+type PayrollField string
+
+const (
+	PayrollFieldEmployeeID  PayrollField = "EMPLOYEE_ID"
+	PayrollFieldEmail       PayrollField = "EMAIL"
+	PayrollFieldLastName    PayrollField = "LAST_NAME"
+	PayrollFieldFirstName   PayrollField = "FIRST_NAME"
+	PayrollFieldPeriodStart PayrollField = "PERIOD_START"
+	PayrollFieldPeriodEnd   PayrollField = "PERIOD_END"
+	PayrollFieldPayCode     PayrollField = "PAY_CODE"
+	PayrollFieldLabel       PayrollField = "LABEL"
+	PayrollFieldCategory    PayrollField = "CATEGORY"
+	PayrollFieldQuantity    PayrollField = "QUANTITY"
+	PayrollFieldUnit        PayrollField = "UNIT"
+)
+
+var AllPayrollField = []PayrollField{
+	PayrollFieldEmployeeID,
+	PayrollFieldEmail,
+	PayrollFieldLastName,
+	PayrollFieldFirstName,
+	PayrollFieldPeriodStart,
+	PayrollFieldPeriodEnd,
+	PayrollFieldPayCode,
+	PayrollFieldLabel,
+	PayrollFieldCategory,
+	PayrollFieldQuantity,
+	PayrollFieldUnit,
+}
+
+func (e PayrollField) IsValid() bool {
+	switch e {
+	case PayrollFieldEmployeeID, PayrollFieldEmail, PayrollFieldLastName, PayrollFieldFirstName, PayrollFieldPeriodStart, PayrollFieldPeriodEnd, PayrollFieldPayCode, PayrollFieldLabel, PayrollFieldCategory, PayrollFieldQuantity, PayrollFieldUnit:
+		return true
+	}
+	return false
+}
+
+func (e PayrollField) String() string {
+	return string(e)
+}
+
+func (e *PayrollField) UnmarshalGQL(v any) error {
+	str, ok := v.(string)
+	if !ok {
+		return fmt.Errorf("enums must be strings")
+	}
+
+	*e = PayrollField(str)
+	if !e.IsValid() {
+		return fmt.Errorf("%s is not a valid PayrollField", str)
+	}
+	return nil
+}
+
+func (e PayrollField) MarshalGQL(w io.Writer) {
+	fmt.Fprint(w, strconv.Quote(e.String()))
+}
+
+func (e *PayrollField) UnmarshalJSON(b []byte) error {
+	s, err := strconv.Unquote(string(b))
+	if err != nil {
+		return err
+	}
+	return e.UnmarshalGQL(s)
+}
+
+func (e PayrollField) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	e.MarshalGQL(&buf)
+	return buf.Bytes(), nil
+}
+
+type PayrollFormat string
+
+const (
+	PayrollFormatCSV        PayrollFormat = "CSV"
+	PayrollFormatFixedWidth PayrollFormat = "FIXED_WIDTH"
+)
+
+var AllPayrollFormat = []PayrollFormat{
+	PayrollFormatCSV,
+	PayrollFormatFixedWidth,
+}
+
+func (e PayrollFormat) IsValid() bool {
+	switch e {
+	case PayrollFormatCSV, PayrollFormatFixedWidth:
+		return true
+	}
+	return false
+}
+
+func (e PayrollFormat) String() string {
+	return string(e)
+}
+
+func (e *PayrollFormat) UnmarshalGQL(v any) error {
+	str, ok := v.(string)
+	if !ok {
+		return fmt.Errorf("enums must be strings")
+	}
+
+	*e = PayrollFormat(str)
+	if !e.IsValid() {
+		return fmt.Errorf("%s is not a valid PayrollFormat", str)
+	}
+	return nil
+}
+
+func (e PayrollFormat) MarshalGQL(w io.Writer) {
+	fmt.Fprint(w, strconv.Quote(e.String()))
+}
+
+func (e *PayrollFormat) UnmarshalJSON(b []byte) error {
+	s, err := strconv.Unquote(string(b))
+	if err != nil {
+		return err
+	}
+	return e.UnmarshalGQL(s)
+}
+
+func (e PayrollFormat) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	e.MarshalGQL(&buf)
+	return buf.Bytes(), nil
+}
+
+type Role string
+
+const (
+	RoleUser    Role = "USER"
+	RoleAdmin   Role = "ADMIN"
+	RoleManager Role = "MANAGER"
+)
+
+var AllRole = []Role{
+	RoleUser,
+	RoleAdmin,
+	RoleManager,
+}
+
+func (e Role) IsValid() bool {
+	switch e {
+	case RoleUser, RoleAdmin, RoleManager:
+		return true
+	}
+	return false
+}
+
+func (e Role) String() string {
+	return string(e)
+}
+
+func (e *Role) UnmarshalGQL(v any) error {
+	str, ok := v.(string)
+	if !ok {
+		return fmt.Errorf("enums must be strings")
+	}
+
+	*e = Role(str)
+	if !e.IsValid() {
+		return fmt.Errorf("%s is not a valid Role", str)
+	}
+	return nil
+}
+
+func (e Role) MarshalGQL(w io.Writer) {
+	fmt.Fprint(w, strconv.Quote(e.String()))
+}
+
+func (e *Role) UnmarshalJSON(b []byte) error {
+	s, err := strconv.Unquote(string(b))
+	if err != nil {
+		return err
+	}
+	return e.UnmarshalGQL(s)
+}
+
+func (e Role) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	e.MarshalGQL(&buf)
+	return buf.Bytes(), nil
+}
+
+type ScheduleSource string
+
+const (
+	ScheduleSourceUser    ScheduleSource = "USER"
+	ScheduleSourceTeam    ScheduleSource = "TEAM"
+	ScheduleSourceDefault ScheduleSource = "DEFAULT"
+	ScheduleSourceNone    ScheduleSource = "NONE"
+)
+
+var AllScheduleSource = []ScheduleSource{
+	ScheduleSourceUser,
+	ScheduleSourceTeam,
+	ScheduleSourceDefault,
+	ScheduleSourceNone,
+}
+
+func (e ScheduleSource) IsValid() bool {
+	switch e {
+	case ScheduleSourceUser, ScheduleSourceTeam, ScheduleSourceDefault, ScheduleSourceNone:
+		return true
+	}
+	return false
+}
+
+func (e ScheduleSource) String() string {
+	return string(e)
+}
+
+func (e *ScheduleSource) UnmarshalGQL(v any) error {
+	str, ok := v.(string)
+	if !ok {
+		return fmt.Errorf("enums must be strings")
+	}
+
+	*e = ScheduleSource(str)
+	if !e.IsValid() {
+		return fmt.Errorf("%s is not a valid ScheduleSource", str)
+	}
+	return nil
+}
+
+func (e ScheduleSource) MarshalGQL(w io.Writer) {
+	fmt.Fprint(w, strconv.Quote(e.String()))
+}
+
+func (e *ScheduleSource) UnmarshalJSON(b []byte) error {
+	s, err := strconv.Unquote(string(b))
+	if err != nil {
+		return err
+	}
+	return e.UnmarshalGQL(s)
+}
+
+func (e ScheduleSource) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	e.MarshalGQL(&buf)
+	return buf.Bytes(), nil
+}
+
+type TimesheetStatus string
+
+const (
+	TimesheetStatusDraft     TimesheetStatus = "DRAFT"
+	TimesheetStatusSubmitted TimesheetStatus = "SUBMITTED"
+	TimesheetStatusApproved  TimesheetStatus = "APPROVED"
+	TimesheetStatusRejected  TimesheetStatus = "REJECTED"
+)
+
+var AllTimesheetStatus = []TimesheetStatus{
+	TimesheetStatusDraft,
+	TimesheetStatusSubmitted,
+	TimesheetStatusApproved,
+	TimesheetStatusRejected,
+}
+
+func (e TimesheetStatus) IsValid() bool {
+	switch e {
+	case TimesheetStatusDraft, TimesheetStatusSubmitted, TimesheetStatusApproved, TimesheetStatusRejected:
+		return true
+	}
+	return false
+}
+
+func (e TimesheetStatus) String() string {
+	return string(e)
+}
+
+func (e *TimesheetStatus) UnmarshalGQL(v any) error {
+	str, ok := v.(string)
+	if !ok {
+		return fmt.Errorf("enums must be strings")
+	}
+
+	*e = TimesheetStatus(str)
+	if !e.IsValid() {
+		return fmt.Errorf("%s is not a valid TimesheetStatus", str)
+	}
+	return nil
+}
+
+func (e TimesheetStatus) MarshalGQL(w io.Writer) {
+	fmt.Fprint(w, strconv.Quote(e.String()))
+}
+
+func (e *TimesheetStatus) UnmarshalJSON(b []byte) error {
+	s, err := strconv.Unquote(string(b))
+	if err != nil {
+		return err
+	}
+	return e.UnmarshalGQL(s)
+}
+
+func (e TimesheetStatus) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	e.MarshalGQL(&buf)
+	return buf.Bytes(), nil
+}
+
+type Weekday string
+
+const (
+	WeekdayMonday    Weekday = "MONDAY"
+	WeekdayTuesday   Weekday = "TUESDAY"
+	WeekdayWednesday Weekday = "WEDNESDAY"
+	WeekdayThursday  Weekday = "THURSDAY"
+	WeekdayFriday    Weekday = "FRIDAY"
+	WeekdaySaturday  Weekday = "SATURDAY"
+	WeekdaySunday    Weekday = "SUNDAY"
+)
+
+var AllWeekday = []Weekday{
+	WeekdayMonday,
+	WeekdayTuesday,
+	WeekdayWednesday,
+	WeekdayThursday,
+	WeekdayFriday,
+	WeekdaySaturday,
+	WeekdaySunday,
+}
+
+func (e Weekday) IsValid() bool {
+	switch e {
+	case WeekdayMonday, WeekdayTuesday, WeekdayWednesday, WeekdayThursday, WeekdayFriday, WeekdaySaturday, WeekdaySunday:
+		return true
+	}
+	return false
+}
+
+func (e Weekday) String() string {
+	return string(e)
+}
+
+func (e *Weekday) UnmarshalGQL(v any) error {
+	str, ok := v.(string)
+	if !ok {
+		return fmt.Errorf("enums must be strings")
+	}
+
+	*e = Weekday(str)
+	if !e.IsValid() {
+		return fmt.Errorf("%s is not a valid Weekday", str)
+	}
+	return nil
+}
+
+func (e Weekday) MarshalGQL(w io.Writer) {
+	fmt.Fprint(w, strconv.Quote(e.String()))
+}
+
+func (e *Weekday) UnmarshalJSON(b []byte) error {
+	s, err := strconv.Unquote(string(b))
+	if err != nil {
+		return err
+	}
+	return e.UnmarshalGQL(s)
+}
+
+func (e Weekday) MarshalJSON() ([]byte, error) {
 	var buf bytes.Buffer
 	e.MarshalGQL(&buf)
 	return buf.Bytes(), nil
@@ -0,0 +1,29 @@
+package resolvers
+
+import (
+	"context"
+
+	"github.com/epitech/timemanager/internal/graph/model"
+	"github.com/epitech/timemanager/package/middlewares"
+)
+
+func (r *queryResolver) BreakTypes(ctx context.Context) ([]*model.BreakType, error) {
+	if err := middlewares.VerifyRole(ctx, "ADMIN", "MANAGER", "USER"); err != nil {
+		return nil, err
+	}
+	return r.BreakService.GetBreakTypes()
+}
+
+func (r *mutationResolver) CreateBreakType(ctx context.Context, input model.CreateBreakTypeInput) (*model.BreakType, error) {
+	if err := middlewares.VerifyRole(ctx, "ADMIN"); err != nil {
+		return nil, err
+	}
+	return r.BreakService.CreateBreakType(input)
+}
+
+func (r *mutationResolver) UpdateBreakType(ctx context.Context, id string, input model.UpdateBreakTypeInput) (*model.BreakType, error) {
+	if err := middlewares.VerifyRole(ctx, "ADMIN"); err != nil {
+		return nil, err
+	}
+	return r.BreakService.UpdateBreakType(id, input)
+}
@@ -0,0 +1,22 @@
+package resolvers
+
+import (
+	"context"
+
+	"github.com/epitech/timemanager/internal/graph/model"
+	"github.com/epitech/timemanager/package/middlewares"
+)
+
+func (r *queryResolver) ComplianceRules(ctx context.Context) ([]*model.ComplianceRule, error) {
+	if err := middlewares.VerifyRole(ctx, "ADMIN", "MANAGER"); err != nil {
+		return nil, err
+	}
+	return r.ComplianceService.GetComplianceRules()
+}
+
+func (r *mutationResolver) UpdateComplianceRule(ctx context.Context, code model.ComplianceRuleCode, input model.ComplianceRuleInput) (*model.ComplianceRule, error) {
+	if err := middlewares.VerifyRole(ctx, "ADMIN"); err != nil {
+		return nil, err
+	}
+	return r.ComplianceService.UpdateComplianceRule(code, input)
+}
@@ -0,0 +1,40 @@
+package resolvers
+
+import (
+	"context"
+
+	"github.com/epitech/timemanager/internal/graph/model"
+	"github.com/epitech/timemanager/package/middlewares"
+)
+
+func (r *queryResolver) Contracts(ctx context.Context, userID *string) ([]*model.EmploymentContract, error) {
+	if err := middlewares.VerifyRole(ctx, "ADMIN", "MANAGER", "USER"); err != nil {
+		return nil, err
+	}
+	uid, err := r.scopeUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	return r.ContractService.GetContracts(uid)
+}
+
+func (r *mutationResolver) CreateContract(ctx context.Context, input model.ContractInput) (*model.EmploymentContract, error) {
+	if err := middlewares.VerifyRole(ctx, "ADMIN"); err != nil {
+		return nil, err
+	}
+	return r.ContractService.CreateContract(input)
+}
+
+func (r *mutationResolver) UpdateContract(ctx context.Context, id string, input model.ContractInput) (*model.EmploymentContract, error) {
+	if err := middlewares.VerifyRole(ctx, "ADMIN"); err != nil {
+		return nil, err
+	}
+	return r.ContractService.UpdateContract(id, input)
+}
+
+func (r *mutationResolver) DeleteContract(ctx context.Context, id string) (bool, error) {
+	if err := middlewares.VerifyRole(ctx, "ADMIN"); err != nil {
+		return false, err
+	}
+	return r.ContractService.DeleteContract(id)
+}
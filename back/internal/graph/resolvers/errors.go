@@ -0,0 +1,35 @@
+package resolvers
+
+import (
+	"context"
+	"errors"
+
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/epitech/timemanager/services"
+	"github.com/vektah/gqlparser/v2/gqlerror"
+)
+
+// errorCodes are the errors clients must tell apart, reported in the "code"
+// extension of the GraphQL error.
+var errorCodes = []struct {
+	err  error
+	code string
+}{
+	{services.ErrPayPeriodClosed, "PAY_PERIOD_CLOSED"},
+}
+
+// ErrorPresenter presents the errors as gqlgen does, adding the code of the
+// known ones.
+func ErrorPresenter(ctx context.Context, err error) *gqlerror.Error {
+	gqlErr := graphql.DefaultErrorPresenter(ctx, err)
+	for _, c := range errorCodes {
+		if errors.Is(err, c.err) {
+			if gqlErr.Extensions == nil {
+				gqlErr.Extensions = map[string]interface{}{}
+			}
+			gqlErr.Extensions["code"] = c.code
+			break
+		}
+	}
+	return gqlErr
+}
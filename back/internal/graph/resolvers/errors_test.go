@@ -0,0 +1,21 @@
+package resolvers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/epitech/timemanager/services"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestErrorPresenterAddsTheKnownCodes(t *testing.T) {
+	closed := ErrorPresenter(context.Background(), fmt.Errorf("can't clock in: %w", services.ErrPayPeriodClosed))
+	assert.Equal(t, "PAY_PERIOD_CLOSED", closed.Extensions["code"])
+	assert.Contains(t, closed.Message, services.ErrPayPeriodClosed.Error())
+
+	other := ErrorPresenter(context.Background(), errors.New("user not found"))
+	assert.Equal(t, "user not found", other.Message)
+	assert.NotContains(t, other.Extensions, "code")
+}
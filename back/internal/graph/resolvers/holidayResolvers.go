@@ -0,0 +1,80 @@
+package resolvers
+
+import (
+	"context"
+	"errors"
+
+	"github.com/epitech/timemanager/internal/graph/model"
+	"github.com/epitech/timemanager/package/middlewares"
+)
+
+func (r *queryResolver) HolidayCalendars(ctx context.Context) ([]*model.HolidayCalendar, error) {
+	if err := middlewares.VerifyRole(ctx, "ADMIN", "MANAGER"); err != nil {
+		return nil, err
+	}
+	return r.HolidayService.GetHolidayCalendars()
+}
+
+func (r *queryResolver) Holidays(ctx context.Context, userID *string, from string, to string) ([]*model.Holiday, error) {
+	if err := middlewares.VerifyRole(ctx, "ADMIN", "MANAGER", "USER"); err != nil {
+		return nil, err
+	}
+	callerID, role, err := callerIdentity(ctx)
+	if err != nil {
+		return nil, err
+	}
+	target := callerID
+	if userID != nil && *userID != "" {
+		uid := toUUIDPtr(userID)
+		if uid == nil {
+			return nil, errors.New("invalid userID")
+		}
+		target = *uid
+	}
+	if err := r.AccessService.CanSeeUser(callerID, role, target); err != nil {
+		return nil, err
+	}
+	return r.HolidayService.UserHolidays(target, from, to)
+}
+
+func (r *mutationResolver) CreateHolidayCalendar(ctx context.Context, name string) (*model.HolidayCalendar, error) {
+	if err := middlewares.VerifyRole(ctx, "ADMIN"); err != nil {
+		return nil, err
+	}
+	return r.HolidayService.CreateHolidayCalendar(name)
+}
+
+func (r *mutationResolver) DeleteHolidayCalendar(ctx context.Context, id string) (bool, error) {
+	if err := middlewares.VerifyRole(ctx, "ADMIN"); err != nil {
+		return false, err
+	}
+	return r.HolidayService.DeleteHolidayCalendar(id)
+}
+
+func (r *mutationResolver) AddHolidays(ctx context.Context, calendarID string, holidays []*model.HolidayInput) (*model.HolidayCalendar, error) {
+	if err := middlewares.VerifyRole(ctx, "ADMIN"); err != nil {
+		return nil, err
+	}
+	return r.HolidayService.AddHolidays(calendarID, holidays)
+}
+
+func (r *mutationResolver) RemoveHoliday(ctx context.Context, calendarID string, date string) (*model.HolidayCalendar, error) {
+	if err := middlewares.VerifyRole(ctx, "ADMIN"); err != nil {
+		return nil, err
+	}
+	return r.HolidayService.RemoveHoliday(calendarID, date)
+}
+
+func (r *mutationResolver) ImportHolidayCalendar(ctx context.Context, calendarID string, ics string) (*model.HolidayCalendar, error) {
+	if err := middlewares.VerifyRole(ctx, "ADMIN"); err != nil {
+		return nil, err
+	}
+	return r.HolidayService.ImportICS(calendarID, ics)
+}
+
+func (r *mutationResolver) SetHolidayCalendar(ctx context.Context, calendarID *string, siteID *string, teamID *string) (bool, error) {
+	if err := middlewares.VerifyRole(ctx, "ADMIN"); err != nil {
+		return false, err
+	}
+	return r.HolidayService.SetHolidayCalendar(calendarID, siteID, teamID)
+}
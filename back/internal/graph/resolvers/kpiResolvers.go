@@ -7,36 +7,58 @@ import (
 
 	"github.com/epitech/timemanager/internal/graph/model"
 	"github.com/epitech/timemanager/package/middlewares"
+	"github.com/epitech/timemanager/package/timezone"
+	"github.com/epitech/timemanager/services"
 	"github.com/google/uuid"
 )
 
 const layoutISO = "2006-01-02"
 
-func (r *queryResolver) KpiUserSummary(ctx context.Context, userID *string, from *string, to *string) (*model.UserKpiSummary, error) {
-	if err := middlewares.VerifyRole(ctx, "ADMIN", "MANAGER", "USER"); err != nil {
-		return nil, err
-	}
-	var uid *uuid.UUID
-	if userID != nil && *userID != "" {
-		if parsed, err := uuid.Parse(*userID); err == nil {
-			uid = &parsed
-		}
+// kpiWindow reads the from/to days in the requested time zone (server default
+// when omitted); without a "to" day the window ends now in that zone.
+func kpiWindow(from, to, timeZone *string) (time.Time, time.Time, error) {
+	loc, err := timezone.Load(stringOrEmpty(timeZone))
+	if err != nil {
+		return time.Time{}, time.Time{}, err
 	}
-	var fromT, toT time.Time
+	var fromT time.Time
+	toT := time.Now().In(loc)
 	if from != nil && *from != "" {
-		if t, err := time.Parse(layoutISO, *from); err == nil {
+		if t, err := time.ParseInLocation(layoutISO, *from, loc); err == nil {
 			fromT = t
 		}
 	}
 	if to != nil && *to != "" {
-		if t, err := time.Parse(layoutISO, *to); err == nil {
+		if t, err := time.ParseInLocation(layoutISO, *to, loc); err == nil {
 			toT = t
 		}
 	}
-	return r.KpiService.GetUserKpiSummary(ctx, uid, fromT, toT)
+	return fromT, toT, nil
+}
+
+func stringOrEmpty(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
 }
 
-func (r *queryResolver) KpiTeamSummary(ctx context.Context, teamID string, from *string, to *string) (*model.TeamKpiSummary, error) {
+func (r *queryResolver) KpiUserSummary(ctx context.Context, userID *string, from *string, to *string, timeZone *string, granularity *model.KpiGranularity, compareTo *model.KpiComparisonInput) (*model.UserKpiSummary, error) {
+	if err := middlewares.VerifyRole(ctx, "ADMIN", "MANAGER", "USER"); err != nil {
+		return nil, err
+	}
+	uid, err := r.scopeUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	fromT, toT, err := kpiWindow(from, to, timeZone)
+	if err != nil {
+		return nil, err
+	}
+	return r.KpiService.GetUserKpiSummary(ctx, uid, fromT, toT, granularity, compareTo)
+}
+
+func (r *queryResolver) KpiTeamSummary(ctx context.Context, teamID string, from *string, to *string, timeZone *string, granularity *model.KpiGranularity, compareTo *model.KpiComparisonInput) (*model.TeamKpiSummary, error) {
 	if err := middlewares.VerifyRole(ctx, "ADMIN", "MANAGER"); err != nil {
 		return nil, err
 	}
@@ -47,45 +69,101 @@ func (r *queryResolver) KpiTeamSummary(ctx context.Context, teamID string, from
 	if err != nil {
 		return nil, errors.New("invalid teamID")
 	}
-	var fromT, toT time.Time
-	if from != nil && *from != "" {
-		if t, err := time.Parse(layoutISO, *from); err == nil {
-			fromT = t
-		}
+	if err := r.checkTeam(ctx, tid); err != nil {
+		return nil, err
 	}
-	if to != nil && *to != "" {
-		if t, err := time.Parse(layoutISO, *to); err == nil {
-			toT = t
+	fromT, toT, err := kpiWindow(from, to, timeZone)
+	if err != nil {
+		return nil, err
+	}
+	return r.KpiService.GetTeamKpiSummary(ctx, tid, fromT, toT, granularity, compareTo)
+}
+
+// TeamPresenceHeatmap returns the team's presence per weekday and hour
+func (r *queryResolver) TeamPresenceHeatmap(ctx context.Context, teamID string, from *string, to *string, timeZone *string, minHeadcount *int32, openFrom *int32, openTo *int32) (*model.PresenceHeatmap, error) {
+	if err := middlewares.VerifyRole(ctx, "ADMIN", "MANAGER"); err != nil {
+		return nil, err
+	}
+	tid, err := uuid.Parse(teamID)
+	if err != nil {
+		return nil, errors.New("invalid teamID")
+	}
+	if err := r.checkTeam(ctx, tid); err != nil {
+		return nil, err
+	}
+	fromT, toT, err := kpiWindow(from, to, timeZone)
+	if err != nil {
+		return nil, err
+	}
+	headcount := 1
+	if minHeadcount != nil {
+		headcount = int(*minHeadcount)
+	}
+	var open *services.HourRange
+	if openFrom != nil || openTo != nil {
+		if openFrom == nil || openTo == nil {
+			return nil, errors.New("openFrom and openTo go together")
 		}
+		open = &services.HourRange{From: int(*openFrom), To: int(*openTo)}
 	}
-	return r.KpiService.GetTeamKpiSummary(ctx, tid, fromT, toT)
+	return r.KpiService.GetTeamPresenceHeatmap(ctx, tid, fromT, toT, headcount, open)
 }
 
-func (r *queryResolver) ExportUserKpiCSV(ctx context.Context, userID *string, from *string, to *string) (string, error) {
+// TeamStaffingForecast returns the team's expected headcount per hour
+func (r *queryResolver) TeamStaffingForecast(ctx context.Context, teamID string, weeks *int32, historyWeeks *int32, timeZone *string, minHeadcount *int32) (*model.StaffingForecast, error) {
+	if err := middlewares.VerifyRole(ctx, "ADMIN", "MANAGER"); err != nil {
+		return nil, err
+	}
+	tid, err := uuid.Parse(teamID)
+	if err != nil {
+		return nil, errors.New("invalid teamID")
+	}
+	if err := r.checkTeam(ctx, tid); err != nil {
+		return nil, err
+	}
+	loc, err := timezone.Load(stringOrEmpty(timeZone))
+	if err != nil {
+		return nil, err
+	}
+	forecastWeeks, history, headcount := 0, 0, 1
+	if weeks != nil {
+		forecastWeeks = int(*weeks)
+	}
+	if historyWeeks != nil {
+		history = int(*historyWeeks)
+	}
+	if minHeadcount != nil {
+		headcount = int(*minHeadcount)
+	}
+	return r.KpiService.GetTeamStaffingForecast(ctx, tid, forecastWeeks, history, headcount, loc)
+}
+
+func (r *queryResolver) ExportUserKpiCSV(ctx context.Context, userID *string, from *string, to *string, timeZone *string) (string, error) {
 	if err := middlewares.VerifyRole(ctx, "ADMIN", "MANAGER", "USER"); err != nil {
 		return "", err
 	}
-	var userUUID *uuid.UUID
-	if userID != nil && *userID != "" {
-		parsed, err := uuid.Parse(*userID)
-		if err != nil {
-			return "", errors.New("invalid userID")
-		}
-		userUUID = &parsed
+	userUUID, err := r.scopeUser(ctx, userID)
+	if err != nil {
+		return "", err
+	}
+
+	loc, err := timezone.Load(stringOrEmpty(timeZone))
+	if err != nil {
+		return "", err
 	}
 
 	var fromDate time.Time
 	if from != nil && *from != "" {
-		t, err := time.Parse(layoutISO, *from)
+		t, err := time.ParseInLocation(layoutISO, *from, loc)
 		if err != nil {
 			return "", errors.New("invalid from date, expected YYYY-MM-DD")
 		}
 		fromDate = t
 	}
 
-	var toDate time.Time
+	toDate := time.Now().In(loc)
 	if to != nil && *to != "" {
-		t, err := time.Parse(layoutISO, *to)
+		t, err := time.ParseInLocation(layoutISO, *to, loc)
 		if err != nil {
 			return "", errors.New("invalid to date, expected YYYY-MM-DD")
 		}
@@ -100,106 +178,97 @@ func (r *queryResolver) ExportUserKpiCSV(ctx context.Context, userID *string, fr
 }
 
 // AdminKpiDashboard returns comprehensive KPI dashboard for admins
-func (r *queryResolver) AdminKpiDashboard(ctx context.Context, from *string, to *string) (*model.AdminKpiDashboard, error) {
-	if err := middlewares.VerifyRole(ctx, "ADMIN", "MANAGER"); err != nil {
+func (r *queryResolver) AdminKpiDashboard(ctx context.Context, from *string, to *string, timeZone *string, granularity *model.KpiGranularity, compareTo *model.KpiComparisonInput) (*model.AdminKpiDashboard, error) {
+	if err := middlewares.VerifyRole(ctx, "ADMIN"); err != nil {
 		return nil, err
 	}
 
-	var fromT, toT time.Time
-	if from != nil && *from != "" {
-		if t, err := time.Parse(layoutISO, *from); err == nil {
-			fromT = t
-		}
-	}
-	if to != nil && *to != "" {
-		if t, err := time.Parse(layoutISO, *to); err == nil {
-			toT = t
-		}
+	fromT, toT, err := kpiWindow(from, to, timeZone)
+	if err != nil {
+		return nil, err
 	}
 
-	return r.KpiService.GetAdminKpiDashboard(ctx, fromT, toT)
+	return r.KpiService.GetAdminKpiDashboard(ctx, fromT, toT, granularity, compareTo)
 }
 
 // WorkloadAnalysis returns workload analysis metrics
-func (r *queryResolver) WorkloadAnalysis(ctx context.Context, teamID *string, from *string, to *string) (*model.WorkloadAnalysis, error) {
-	if err := middlewares.VerifyRole(ctx, "ADMIN", "MANAGER"); err != nil {
-		return nil, err
-	}
-
-	dashboard, err := r.AdminKpiDashboard(ctx, from, to)
+func (r *queryResolver) WorkloadAnalysis(ctx context.Context, teamID *string, from *string, to *string, timeZone *string, granularity *model.KpiGranularity) (*model.WorkloadAnalysis, error) {
+	dashboard, err := r.scopedKpiDashboard(ctx, teamID, from, to, timeZone, granularity)
 	if err != nil {
 		return nil, err
 	}
-
 	return dashboard.Workload, nil
 }
 
 // PunctualityMetrics returns punctuality metrics
-func (r *queryResolver) PunctualityMetrics(ctx context.Context, teamID *string, from *string, to *string) (*model.PunctualityMetrics, error) {
-	if err := middlewares.VerifyRole(ctx, "ADMIN", "MANAGER"); err != nil {
-		return nil, err
-	}
-
-	dashboard, err := r.AdminKpiDashboard(ctx, from, to)
+func (r *queryResolver) PunctualityMetrics(ctx context.Context, teamID *string, from *string, to *string, timeZone *string, granularity *model.KpiGranularity) (*model.PunctualityMetrics, error) {
+	dashboard, err := r.scopedKpiDashboard(ctx, teamID, from, to, timeZone, granularity)
 	if err != nil {
 		return nil, err
 	}
-
 	return dashboard.Punctuality, nil
 }
 
 // OvertimeReport returns overtime report
-func (r *queryResolver) OvertimeReport(ctx context.Context, teamID *string, from *string, to *string) (*model.OvertimeReport, error) {
-	if err := middlewares.VerifyRole(ctx, "ADMIN", "MANAGER"); err != nil {
-		return nil, err
-	}
-
-	dashboard, err := r.AdminKpiDashboard(ctx, from, to)
+func (r *queryResolver) OvertimeReport(ctx context.Context, teamID *string, from *string, to *string, timeZone *string, granularity *model.KpiGranularity) (*model.OvertimeReport, error) {
+	dashboard, err := r.scopedKpiDashboard(ctx, teamID, from, to, timeZone, granularity)
 	if err != nil {
 		return nil, err
 	}
-
 	return dashboard.Overtime, nil
 }
 
 // ComplianceMetrics returns compliance metrics
-func (r *queryResolver) ComplianceMetrics(ctx context.Context, teamID *string, from *string, to *string) (*model.ComplianceMetrics, error) {
-	if err := middlewares.VerifyRole(ctx, "ADMIN", "MANAGER"); err != nil {
-		return nil, err
-	}
-
-	dashboard, err := r.AdminKpiDashboard(ctx, from, to)
+func (r *queryResolver) ComplianceMetrics(ctx context.Context, teamID *string, from *string, to *string, timeZone *string, granularity *model.KpiGranularity) (*model.ComplianceMetrics, error) {
+	dashboard, err := r.scopedKpiDashboard(ctx, teamID, from, to, timeZone, granularity)
 	if err != nil {
 		return nil, err
 	}
-
 	return dashboard.Compliance, nil
 }
 
 // ProductivityMetrics returns productivity metrics
-func (r *queryResolver) ProductivityMetrics(ctx context.Context, teamID *string, from *string, to *string) (*model.ProductivityMetrics, error) {
-	if err := middlewares.VerifyRole(ctx, "ADMIN", "MANAGER"); err != nil {
+func (r *queryResolver) ProductivityMetrics(ctx context.Context, teamID *string, from *string, to *string, timeZone *string, granularity *model.KpiGranularity) (*model.ProductivityMetrics, error) {
+	dashboard, err := r.scopedKpiDashboard(ctx, teamID, from, to, timeZone, granularity)
+	if err != nil {
 		return nil, err
 	}
+	return dashboard.Productivity, nil
+}
 
-	dashboard, err := r.AdminKpiDashboard(ctx, from, to)
+// TeamDetailedReports returns detailed reports for all teams, or the team
+// managers ask for
+func (r *queryResolver) TeamDetailedReports(ctx context.Context, teamID *string, from *string, to *string, timeZone *string, granularity *model.KpiGranularity) ([]*model.TeamDetailedReport, error) {
+	if teamID == nil || *teamID == "" {
+		_, role, err := callerIdentity(ctx)
+		if err != nil {
+			return nil, err
+		}
+		if role != string(model.RoleAdmin) {
+			return nil, errors.New("teamID is required")
+		}
+	}
+	dashboard, err := r.scopedKpiDashboard(ctx, teamID, from, to, timeZone, granularity)
 	if err != nil {
 		return nil, err
 	}
-
-	return dashboard.Productivity, nil
+	return dashboard.Teams, nil
 }
 
-// TeamDetailedReports returns detailed reports for all teams
-func (r *queryResolver) TeamDetailedReports(ctx context.Context, from *string, to *string) ([]*model.TeamDetailedReport, error) {
+// scopedKpiDashboard computes the dashboard over the team when the
+// AccessService allows it, over the caller's own data for managers without a
+// team, and over everyone for admins
+func (r *queryResolver) scopedKpiDashboard(ctx context.Context, teamID *string, from *string, to *string, timeZone *string, granularity *model.KpiGranularity) (*model.AdminKpiDashboard, error) {
 	if err := middlewares.VerifyRole(ctx, "ADMIN", "MANAGER"); err != nil {
 		return nil, err
 	}
-
-	dashboard, err := r.AdminKpiDashboard(ctx, from, to)
+	uid, tid, err := r.scopeUserOrTeam(ctx, nil, teamID)
 	if err != nil {
 		return nil, err
 	}
-
-	return dashboard.Teams, nil
+	fromT, toT, err := kpiWindow(from, to, timeZone)
+	if err != nil {
+		return nil, err
+	}
+	return r.KpiService.GetKpiDashboard(ctx, uid, tid, fromT, toT, granularity, nil)
 }
@@ -0,0 +1,41 @@
+package resolvers
+
+import (
+	"context"
+
+	"github.com/epitech/timemanager/internal/graph/model"
+	"github.com/epitech/timemanager/package/middlewares"
+)
+
+func (r *queryResolver) LatenessEvents(ctx context.Context, userID *string, teamID *string, from *string, to *string, status *model.LatenessStatus) ([]*model.LatenessEvent, error) {
+	if err := middlewares.VerifyRole(ctx, "ADMIN", "MANAGER", "USER"); err != nil {
+		return nil, err
+	}
+	uid, tid, err := r.scopeUserOrTeam(ctx, userID, teamID)
+	if err != nil {
+		return nil, err
+	}
+	return r.LatenessService.GetLatenessEvents(uid, tid, from, to, status)
+}
+
+func (r *mutationResolver) JustifyLateness(ctx context.Context, id string, justification string) (*model.LatenessEvent, error) {
+	if err := middlewares.VerifyRole(ctx, "ADMIN", "MANAGER", "USER"); err != nil {
+		return nil, err
+	}
+	callerID, _, err := callerIdentity(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return r.LatenessService.Justify(callerID, id, justification)
+}
+
+func (r *mutationResolver) ReviewLateness(ctx context.Context, id string, excused bool, comment *string) (*model.LatenessEvent, error) {
+	if err := middlewares.VerifyRole(ctx, "ADMIN", "MANAGER"); err != nil {
+		return nil, err
+	}
+	callerID, role, err := callerIdentity(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return r.LatenessService.Review(callerID, role, id, excused, comment)
+}
@@ -0,0 +1,110 @@
+package resolvers
+
+import (
+	"context"
+
+	"github.com/epitech/timemanager/internal/graph/model"
+	"github.com/epitech/timemanager/package/middlewares"
+)
+
+func (r *queryResolver) AbsenceTypes(ctx context.Context) ([]*model.AbsenceType, error) {
+	if err := middlewares.VerifyRole(ctx, "ADMIN", "MANAGER", "USER"); err != nil {
+		return nil, err
+	}
+	return r.LeaveService.GetAbsenceTypes()
+}
+
+func (r *queryResolver) LeaveBalances(ctx context.Context, userID *string) ([]*model.LeaveBalance, error) {
+	if err := middlewares.VerifyRole(ctx, "ADMIN", "MANAGER", "USER"); err != nil {
+		return nil, err
+	}
+	uid, err := r.scopeUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if uid == nil {
+		// admins read their own balances by default too
+		callerID, _, err := callerIdentity(ctx)
+		if err != nil {
+			return nil, err
+		}
+		uid = &callerID
+	}
+	return r.LeaveService.GetBalances(*uid)
+}
+
+func (r *queryResolver) LeaveRequests(ctx context.Context, status *model.LeaveStatus, userID *string, from *string, to *string) ([]*model.LeaveRequest, error) {
+	if err := middlewares.VerifyRole(ctx, "ADMIN", "MANAGER", "USER"); err != nil {
+		return nil, err
+	}
+	callerID, role, err := callerIdentity(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return r.LeaveService.GetLeaveRequests(callerID, role, status, toUUIDPtr(userID), from, to)
+}
+
+func (r *mutationResolver) CreateAbsenceType(ctx context.Context, input model.CreateAbsenceTypeInput) (*model.AbsenceType, error) {
+	if err := middlewares.VerifyRole(ctx, "ADMIN"); err != nil {
+		return nil, err
+	}
+	return r.LeaveService.CreateAbsenceType(input)
+}
+
+func (r *mutationResolver) UpdateAbsenceType(ctx context.Context, id string, input model.UpdateAbsenceTypeInput) (*model.AbsenceType, error) {
+	if err := middlewares.VerifyRole(ctx, "ADMIN"); err != nil {
+		return nil, err
+	}
+	return r.LeaveService.UpdateAbsenceType(id, input)
+}
+
+func (r *mutationResolver) RequestLeave(ctx context.Context, input model.RequestLeaveInput) (*model.LeaveRequest, error) {
+	if err := middlewares.VerifyRole(ctx, "ADMIN", "MANAGER", "USER"); err != nil {
+		return nil, err
+	}
+	callerID, _, err := callerIdentity(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return r.LeaveService.RequestLeave(callerID, input)
+}
+
+func (r *mutationResolver) ApproveLeave(ctx context.Context, id string, comment *string) (*model.LeaveRequest, error) {
+	if err := middlewares.VerifyRole(ctx, "ADMIN", "MANAGER"); err != nil {
+		return nil, err
+	}
+	callerID, role, err := callerIdentity(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return r.LeaveService.ReviewLeave(callerID, role, id, true, comment)
+}
+
+func (r *mutationResolver) RejectLeave(ctx context.Context, id string, comment *string) (*model.LeaveRequest, error) {
+	if err := middlewares.VerifyRole(ctx, "ADMIN", "MANAGER"); err != nil {
+		return nil, err
+	}
+	callerID, role, err := callerIdentity(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return r.LeaveService.ReviewLeave(callerID, role, id, false, comment)
+}
+
+func (r *mutationResolver) CancelLeave(ctx context.Context, id string) (*model.LeaveRequest, error) {
+	if err := middlewares.VerifyRole(ctx, "ADMIN", "MANAGER", "USER"); err != nil {
+		return nil, err
+	}
+	callerID, role, err := callerIdentity(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return r.LeaveService.CancelLeave(callerID, role, id)
+}
+
+func (r *mutationResolver) AdjustLeaveBalance(ctx context.Context, input model.AdjustLeaveBalanceInput) (*model.LeaveBalance, error) {
+	if err := middlewares.VerifyRole(ctx, "ADMIN"); err != nil {
+		return nil, err
+	}
+	return r.LeaveService.AdjustBalance(input)
+}
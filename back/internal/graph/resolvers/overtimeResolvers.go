@@ -0,0 +1,22 @@
+package resolvers
+
+import (
+	"context"
+
+	"github.com/epitech/timemanager/internal/graph/model"
+	"github.com/epitech/timemanager/package/middlewares"
+)
+
+func (r *queryResolver) OvertimePolicy(ctx context.Context) (*model.OvertimePolicy, error) {
+	if err := middlewares.VerifyRole(ctx, "ADMIN", "MANAGER"); err != nil {
+		return nil, err
+	}
+	return r.OvertimeService.GetOvertimePolicy()
+}
+
+func (r *mutationResolver) SetOvertimePolicy(ctx context.Context, input model.OvertimePolicyInput) (*model.OvertimePolicy, error) {
+	if err := middlewares.VerifyRole(ctx, "ADMIN"); err != nil {
+		return nil, err
+	}
+	return r.OvertimeService.SetOvertimePolicy(input)
+}
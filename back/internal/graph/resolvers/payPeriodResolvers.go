@@ -0,0 +1,37 @@
+package resolvers
+
+import (
+	"context"
+
+	"github.com/epitech/timemanager/internal/graph/model"
+	"github.com/epitech/timemanager/package/middlewares"
+)
+
+func (r *queryResolver) PayPeriods(ctx context.Context, from *string, to *string, status *model.PayPeriodStatus) ([]*model.PayPeriod, error) {
+	if err := middlewares.VerifyRole(ctx, "ADMIN"); err != nil {
+		return nil, err
+	}
+	return r.PayPeriodService.GetPayPeriods(status, from, to)
+}
+
+func (r *mutationResolver) ClosePayPeriod(ctx context.Context, startDate string, endDate string) (*model.PayPeriod, error) {
+	if err := middlewares.VerifyRole(ctx, "ADMIN"); err != nil {
+		return nil, err
+	}
+	callerID, _, err := callerIdentity(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return r.PayPeriodService.Close(callerID, startDate, endDate)
+}
+
+func (r *mutationResolver) ReopenPayPeriod(ctx context.Context, id string, reason string) (*model.PayPeriod, error) {
+	if err := middlewares.VerifyRole(ctx, "ADMIN"); err != nil {
+		return nil, err
+	}
+	callerID, _, err := callerIdentity(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return r.PayPeriodService.Reopen(callerID, id, reason)
+}
@@ -0,0 +1,64 @@
+package resolvers
+
+import (
+	"context"
+
+	"github.com/epitech/timemanager/internal/graph/model"
+	"github.com/epitech/timemanager/package/middlewares"
+)
+
+func (r *queryResolver) PayCodes(ctx context.Context) ([]*model.PayCode, error) {
+	if err := middlewares.VerifyRole(ctx, "ADMIN"); err != nil {
+		return nil, err
+	}
+	return r.PayrollService.GetPayCodes()
+}
+
+func (r *queryResolver) PayrollLayouts(ctx context.Context) ([]*model.PayrollLayout, error) {
+	if err := middlewares.VerifyRole(ctx, "ADMIN"); err != nil {
+		return nil, err
+	}
+	return r.PayrollService.GetPayrollLayouts()
+}
+
+func (r *queryResolver) ExportPayroll(ctx context.Context, periodID string, layoutID *string, format *model.PayrollFormat) (string, error) {
+	if err := middlewares.VerifyRole(ctx, "ADMIN"); err != nil {
+		return "", err
+	}
+	return r.PayrollService.ExportPayroll(periodID, layoutID, format)
+}
+
+func (r *mutationResolver) SetPayCode(ctx context.Context, input model.PayCodeInput) (*model.PayCode, error) {
+	if err := middlewares.VerifyRole(ctx, "ADMIN"); err != nil {
+		return nil, err
+	}
+	return r.PayrollService.SetPayCode(input)
+}
+
+func (r *mutationResolver) DeletePayCode(ctx context.Context, id string) (bool, error) {
+	if err := middlewares.VerifyRole(ctx, "ADMIN"); err != nil {
+		return false, err
+	}
+	return r.PayrollService.DeletePayCode(id)
+}
+
+func (r *mutationResolver) CreatePayrollLayout(ctx context.Context, input model.PayrollLayoutInput) (*model.PayrollLayout, error) {
+	if err := middlewares.VerifyRole(ctx, "ADMIN"); err != nil {
+		return nil, err
+	}
+	return r.PayrollService.CreatePayrollLayout(input)
+}
+
+func (r *mutationResolver) UpdatePayrollLayout(ctx context.Context, id string, input model.PayrollLayoutInput) (*model.PayrollLayout, error) {
+	if err := middlewares.VerifyRole(ctx, "ADMIN"); err != nil {
+		return nil, err
+	}
+	return r.PayrollService.UpdatePayrollLayout(id, input)
+}
+
+func (r *mutationResolver) DeletePayrollLayout(ctx context.Context, id string) (bool, error) {
+	if err := middlewares.VerifyRole(ctx, "ADMIN"); err != nil {
+		return false, err
+	}
+	return r.PayrollService.DeletePayrollLayout(id)
+}
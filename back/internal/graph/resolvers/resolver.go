@@ -10,10 +10,24 @@ import (
 // It serves as dependency injection for your app, add any dependencies you require here.
 
 type Resolver struct {
-	DB               *gorm.DB
-	AuthService      *services.AuthService
-	AdminService     *services.AdminService
-	TeamService      *services.TeamService
-	TimeTableService *services.TimeTableService
-	KpiService       *services.KpiService
+	DB                *gorm.DB
+	AuthService       *services.AuthService
+	AdminService      *services.AdminService
+	TeamService       *services.TeamService
+	TimeTableService  *services.TimeTableService
+	KpiService        *services.KpiService
+	BreakService      *services.BreakService
+	SiteService       *services.SiteService
+	ScheduleService   *services.ScheduleService
+	LeaveService      *services.LeaveService
+	HolidayService    *services.HolidayService
+	ContractService   *services.ContractService
+	OvertimeService   *services.OvertimeService
+	ComplianceService *services.ComplianceService
+	RollupService     *services.RollupService
+	AccessService     *services.AccessService
+	LatenessService   *services.LatenessService
+	TimesheetService  *services.TimesheetService
+	PayPeriodService  *services.PayPeriodService
+	PayrollService    *services.PayrollService
 }
@@ -0,0 +1,19 @@
+package resolvers
+
+import (
+	"context"
+
+	"github.com/epitech/timemanager/internal/graph/model"
+	"github.com/epitech/timemanager/package/middlewares"
+)
+
+func (r *queryResolver) DailyRollups(ctx context.Context, userID *string, teamID *string, from string, to string) ([]*model.DailyRollup, error) {
+	if err := middlewares.VerifyRole(ctx, "ADMIN", "MANAGER"); err != nil {
+		return nil, err
+	}
+	uid, tid, err := r.scopeUserOrTeam(ctx, userID, teamID)
+	if err != nil {
+		return nil, err
+	}
+	return r.RollupService.GetDailyRollups(uid, tid, from, to)
+}
@@ -0,0 +1,67 @@
+package resolvers
+
+import (
+	"context"
+	"errors"
+
+	"github.com/epitech/timemanager/internal/graph/model"
+	"github.com/epitech/timemanager/package/middlewares"
+)
+
+func (r *queryResolver) WorkSchedules(ctx context.Context, userID *string, teamID *string) ([]*model.WorkSchedule, error) {
+	if err := middlewares.VerifyRole(ctx, "ADMIN", "MANAGER"); err != nil {
+		return nil, err
+	}
+	uid, tid, err := r.scopeUserOrTeam(ctx, userID, teamID)
+	if err != nil {
+		return nil, err
+	}
+	return r.ScheduleService.GetWorkSchedules(uid, tid)
+}
+
+// PlannedSchedule resolves the planned hours of a user (the caller by default)
+// on a date (today in the user's time zone by default).
+func (r *queryResolver) PlannedSchedule(ctx context.Context, userID *string, date *string) (*model.PlannedSchedule, error) {
+	if err := middlewares.VerifyRole(ctx, "ADMIN", "MANAGER", "USER"); err != nil {
+		return nil, err
+	}
+	callerID, role, err := callerIdentity(ctx)
+	if err != nil {
+		return nil, err
+	}
+	target := callerID
+	if userID != nil && *userID != "" {
+		uid := toUUIDPtr(userID)
+		if uid == nil {
+			return nil, errors.New("invalid userID")
+		}
+		target = *uid
+	}
+	if err := r.AccessService.CanSeeUser(callerID, role, target); err != nil {
+		return nil, err
+	}
+	day := ""
+	if date != nil {
+		day = *date
+	}
+	if day == "" {
+		if day, err = r.ScheduleService.Today(target); err != nil {
+			return nil, err
+		}
+	}
+	return r.ScheduleService.PlannedSchedule(target, day)
+}
+
+func (r *mutationResolver) AssignWorkSchedule(ctx context.Context, input model.AssignWorkScheduleInput) (*model.WorkSchedule, error) {
+	if err := middlewares.VerifyRole(ctx, "ADMIN"); err != nil {
+		return nil, err
+	}
+	return r.ScheduleService.AssignWorkSchedule(input)
+}
+
+func (r *mutationResolver) DeleteWorkSchedule(ctx context.Context, id string) (bool, error) {
+	if err := middlewares.VerifyRole(ctx, "ADMIN"); err != nil {
+		return false, err
+	}
+	return r.ScheduleService.DeleteWorkSchedule(id)
+}
@@ -10,23 +10,33 @@ import (
 
 	"github.com/epitech/timemanager/internal/graph"
 	"github.com/epitech/timemanager/internal/graph/model"
+	"github.com/epitech/timemanager/package/middlewares"
 )
 
 // User mutation resolvers implemented in userResolvers.go
 
 // CreateTimeEntry is the resolver for the createTimeEntry field.
 func (r *mutationResolver) CreateTimeEntry(ctx context.Context, input model.CreateTimeEntryInput) (*model.TimeTableEntry, error) {
-	panic(fmt.Errorf("not implemented: CreateTimeEntry - createTimeEntry"))
+	if err := middlewares.VerifyRole(ctx, "ADMIN"); err != nil {
+		return nil, err
+	}
+	return r.TimeTableService.CreateTimeEntry(input)
 }
 
 // UpdateTimeEntry is the resolver for the updateTimeEntry field.
 func (r *mutationResolver) UpdateTimeEntry(ctx context.Context, id string, input model.UpdateTimeEntryInput) (*model.TimeTableEntry, error) {
-	panic(fmt.Errorf("not implemented: UpdateTimeEntry - updateTimeEntry"))
+	if err := middlewares.VerifyRole(ctx, "ADMIN"); err != nil {
+		return nil, err
+	}
+	return r.TimeTableService.UpdateTimeEntry(id, input)
 }
 
 // DeleteTimeEntry is the resolver for the deleteTimeEntry field.
 func (r *mutationResolver) DeleteTimeEntry(ctx context.Context, id string) (bool, error) {
-	panic(fmt.Errorf("not implemented: DeleteTimeEntry - deleteTimeEntry"))
+	if err := middlewares.VerifyRole(ctx, "ADMIN"); err != nil {
+		return false, err
+	}
+	return r.TimeTableService.DeleteTimeEntry(id)
 }
 
 // Roles is the resolver for the roles field.
@@ -36,7 +46,10 @@ func (r *queryResolver) Roles(ctx context.Context) ([]model.Role, error) {
 
 // TimeTables is the resolver for the timeTables field.
 func (r *queryResolver) TimeTables(ctx context.Context) ([]*model.TimeTable, error) {
-	panic(fmt.Errorf("not implemented: TimeTables - timeTables"))
+	if err := middlewares.VerifyRole(ctx, "ADMIN", "MANAGER"); err != nil {
+		return nil, err
+	}
+	return r.ScheduleService.GetTimeTables()
 }
 
 // Mutation returns graph.MutationResolver implementation.
@@ -0,0 +1,29 @@
+package resolvers
+
+import (
+	"context"
+
+	"github.com/epitech/timemanager/internal/graph/model"
+	"github.com/epitech/timemanager/package/middlewares"
+)
+
+func (r *queryResolver) Sites(ctx context.Context) ([]*model.Site, error) {
+	if err := middlewares.VerifyRole(ctx, "ADMIN", "MANAGER", "USER"); err != nil {
+		return nil, err
+	}
+	return r.SiteService.GetSites()
+}
+
+func (r *mutationResolver) CreateSite(ctx context.Context, input model.SiteInput) (*model.Site, error) {
+	if err := middlewares.VerifyRole(ctx, "ADMIN"); err != nil {
+		return nil, err
+	}
+	return r.SiteService.CreateSite(input)
+}
+
+func (r *mutationResolver) UpdateSite(ctx context.Context, id string, input model.SiteInput) (*model.Site, error) {
+	if err := middlewares.VerifyRole(ctx, "ADMIN"); err != nil {
+		return nil, err
+	}
+	return r.SiteService.UpdateSite(id, input)
+}
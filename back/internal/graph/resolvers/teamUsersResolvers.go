@@ -2,11 +2,24 @@ package resolvers
 
 import (
 	"context"
+	"errors"
 
 	"github.com/epitech/timemanager/internal/graph/model"
 	teamUsersQuery "github.com/epitech/timemanager/internal/repositories/queryRepository/teamUsersQueries"
+	"github.com/epitech/timemanager/package/middlewares"
+	"github.com/google/uuid"
 )
 
 func (r *queryResolver) UsersByTeam(ctx context.Context, teamID string) ([]*model.UserWithAllData, error) {
+	if err := middlewares.VerifyRole(ctx, "ADMIN", "MANAGER"); err != nil {
+		return nil, err
+	}
+	tid, err := uuid.Parse(teamID)
+	if err != nil {
+		return nil, errors.New("invalid teamID")
+	}
+	if err := r.checkTeam(ctx, tid); err != nil {
+		return nil, err
+	}
 	return teamUsersQuery.ListUsersWithAllDataByTeam(teamID)
 }
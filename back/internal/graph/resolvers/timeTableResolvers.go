@@ -2,6 +2,7 @@ package resolvers
 
 import (
 	"context"
+	"errors"
 	"time"
 
 	"github.com/epitech/timemanager/internal/graph/model"
@@ -37,15 +38,140 @@ func (r *queryResolver) TimeTableEntries(ctx context.Context, userID *string, te
 		return nil, err
 	}
 
-	// Pas de filtres -> fallback
+	// Pas de filtres -> fallback, réservé aux admins
 	if userID == nil && teamID == nil && from == nil && to == nil {
-		return r.TimeTableService.GetTimeTableEntries()
+		if err := middlewares.VerifyRole(ctx, "ADMIN"); err == nil {
+			return r.TimeTableService.GetTimeTableEntries()
+		}
 	}
 
-	uid := toUUIDPtr(userID)
-	tid := toUUIDPtr(teamID)
+	uid, tid, err := r.scopeUserOrTeam(ctx, userID, teamID)
+	if err != nil {
+		return nil, err
+	}
 	fromT := toTimePtr(from)
 	toT := toTimePtr(to)
 
 	return r.TimeTableService.GetTimeTableEntriesFiltered(uid, tid, fromT, toT)
 }
+
+// callerIdentity returns the authenticated user's ID and role
+func callerIdentity(ctx context.Context) (uuid.UUID, string, error) {
+	id, err := middlewares.GetUserID(ctx)
+	if err != nil {
+		return uuid.Nil, "", err
+	}
+	uid, err := uuid.Parse(id)
+	if err != nil {
+		return uuid.Nil, "", errors.New("invalid user ID in context")
+	}
+	role, err := middlewares.GetUserRole(ctx)
+	if err != nil {
+		return uuid.Nil, "", err
+	}
+	return uid, role, nil
+}
+
+// parseOptionalID reads an optional ID argument, nil when omitted
+func parseOptionalID(id *string, name string) (*uuid.UUID, error) {
+	if id == nil || *id == "" {
+		return nil, nil
+	}
+	parsed, err := uuid.Parse(*id)
+	if err != nil {
+		return nil, errors.New("invalid " + name)
+	}
+	return &parsed, nil
+}
+
+// scopeUser returns the user whose data the caller asked for, the caller by
+// default, once the AccessService allowed it
+func (r *Resolver) scopeUser(ctx context.Context, userID *string) (*uuid.UUID, error) {
+	uid, err := parseOptionalID(userID, "userID")
+	if err != nil {
+		return nil, err
+	}
+	callerID, role, err := callerIdentity(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return r.AccessService.ScopeUser(callerID, role, uid)
+}
+
+// scopeUserOrTeam does the same for queries filtered by user or team
+func (r *Resolver) scopeUserOrTeam(ctx context.Context, userID, teamID *string) (*uuid.UUID, *uuid.UUID, error) {
+	uid, err := parseOptionalID(userID, "userID")
+	if err != nil {
+		return nil, nil, err
+	}
+	tid, err := parseOptionalID(teamID, "teamID")
+	if err != nil {
+		return nil, nil, err
+	}
+	callerID, role, err := callerIdentity(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	return r.AccessService.ScopeUserOrTeam(callerID, role, uid, tid)
+}
+
+// checkTeam fails unless the caller may read the whole team's data
+func (r *Resolver) checkTeam(ctx context.Context, teamID uuid.UUID) error {
+	callerID, role, err := callerIdentity(ctx)
+	if err != nil {
+		return err
+	}
+	return r.AccessService.CanSeeTeam(callerID, role, teamID)
+}
+
+func (r *queryResolver) TimeEntryCorrections(ctx context.Context, status *model.CorrectionStatus, userID *string) ([]*model.TimeEntryCorrection, error) {
+	if err := middlewares.VerifyRole(ctx, "ADMIN", "MANAGER", "USER"); err != nil {
+		return nil, err
+	}
+	uid, err := parseOptionalID(userID, "userID")
+	if err != nil {
+		return nil, err
+	}
+	callerID, role, err := callerIdentity(ctx)
+	if err != nil {
+		return nil, err
+	}
+	uid, managerID, err := r.AccessService.ScopeManagedUsers(callerID, role, uid)
+	if err != nil {
+		return nil, err
+	}
+	return r.TimeTableService.GetCorrections(status, uid, managerID)
+}
+
+func (r *mutationResolver) RequestTimeEntryCorrection(ctx context.Context, input model.RequestTimeEntryCorrectionInput) (*model.TimeEntryCorrection, error) {
+	if err := middlewares.VerifyRole(ctx, "ADMIN", "MANAGER", "USER"); err != nil {
+		return nil, err
+	}
+	callerID, _, err := callerIdentity(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return r.TimeTableService.RequestCorrection(callerID, input)
+}
+
+func (r *mutationResolver) ApproveTimeEntryCorrection(ctx context.Context, id string, comment *string) (*model.TimeEntryCorrection, error) {
+	if err := middlewares.VerifyRole(ctx, "ADMIN", "MANAGER"); err != nil {
+		return nil, err
+	}
+	callerID, role, err := callerIdentity(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return r.TimeTableService.ReviewCorrection(callerID, role, id, true, comment)
+}
+
+func (r *mutationResolver) RejectTimeEntryCorrection(ctx context.Context, id string, comment *string) (*model.TimeEntryCorrection, error) {
+	if err := middlewares.VerifyRole(ctx, "ADMIN", "MANAGER"); err != nil {
+		return nil, err
+	}
+	callerID, role, err := callerIdentity(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return r.TimeTableService.ReviewCorrection(callerID, role, id, false, comment)
+}
@@ -0,0 +1,71 @@
+package resolvers
+
+import (
+	"context"
+
+	"github.com/epitech/timemanager/internal/graph/model"
+	"github.com/epitech/timemanager/package/middlewares"
+)
+
+func (r *queryResolver) Timesheet(ctx context.Context, userID *string, week string) (*model.Timesheet, error) {
+	if err := middlewares.VerifyRole(ctx, "ADMIN", "MANAGER", "USER"); err != nil {
+		return nil, err
+	}
+	uid, err := r.scopeUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if uid == nil {
+		// an admin without userID reads their own week
+		callerID, _, err := callerIdentity(ctx)
+		if err != nil {
+			return nil, err
+		}
+		uid = &callerID
+	}
+	return r.TimesheetService.GetTimesheet(*uid, week)
+}
+
+func (r *queryResolver) Timesheets(ctx context.Context, userID *string, teamID *string, status *model.TimesheetStatus, from *string, to *string) ([]*model.Timesheet, error) {
+	if err := middlewares.VerifyRole(ctx, "ADMIN", "MANAGER", "USER"); err != nil {
+		return nil, err
+	}
+	uid, tid, err := r.scopeUserOrTeam(ctx, userID, teamID)
+	if err != nil {
+		return nil, err
+	}
+	return r.TimesheetService.GetTimesheets(uid, tid, status, from, to)
+}
+
+func (r *mutationResolver) SubmitTimesheet(ctx context.Context, week string) (*model.Timesheet, error) {
+	if err := middlewares.VerifyRole(ctx, "ADMIN", "MANAGER", "USER"); err != nil {
+		return nil, err
+	}
+	callerID, _, err := callerIdentity(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return r.TimesheetService.Submit(callerID, week)
+}
+
+func (r *mutationResolver) ApproveTimesheet(ctx context.Context, id string, comment *string) (*model.Timesheet, error) {
+	if err := middlewares.VerifyRole(ctx, "ADMIN", "MANAGER"); err != nil {
+		return nil, err
+	}
+	callerID, role, err := callerIdentity(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return r.TimesheetService.Review(callerID, role, id, true, comment)
+}
+
+func (r *mutationResolver) RejectTimesheet(ctx context.Context, id string, comment *string) (*model.Timesheet, error) {
+	if err := middlewares.VerifyRole(ctx, "ADMIN", "MANAGER"); err != nil {
+		return nil, err
+	}
+	callerID, role, err := callerIdentity(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return r.TimesheetService.Review(callerID, role, id, false, comment)
+}
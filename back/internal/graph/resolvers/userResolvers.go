@@ -2,12 +2,15 @@ package resolvers
 
 import (
 	"context"
+	"errors"
+	"log"
 
 	"github.com/epitech/timemanager/internal/graph/model"
 	timetableEntryMutation "github.com/epitech/timemanager/internal/repositories/mutationRepository/timeTableEntryMutations"
 	"github.com/epitech/timemanager/internal/repositories/mutationRepository/userMutations"
 	"github.com/epitech/timemanager/internal/repositories/queryRepository/userQueries"
 	"github.com/epitech/timemanager/package/middlewares"
+	"github.com/google/uuid"
 )
 
 func (r *queryResolver) Users(ctx context.Context) ([]*model.User, error) {
@@ -17,12 +20,55 @@ func (r *queryResolver) Users(ctx context.Context) ([]*model.User, error) {
 	return userQueries.ListUsers()
 }
 
+// UserByEmail returns the user when the caller may read their data
 func (r *queryResolver) UserByEmail(ctx context.Context, email string) (*model.User, error) {
-	return userQueries.GetUserByEmail(email)
+	if err := middlewares.VerifyRole(ctx, "ADMIN", "MANAGER", "USER"); err != nil {
+		return nil, err
+	}
+	callerID, role, err := callerIdentity(ctx)
+	if err != nil {
+		return nil, err
+	}
+	user, err := userQueries.GetUserByEmail(email)
+	if err != nil {
+		return nil, err
+	}
+	uid, err := uuid.Parse(user.ID)
+	if err != nil {
+		return nil, errors.New("invalid user ID")
+	}
+	if err := r.AccessService.CanSeeUser(callerID, role, uid); err != nil {
+		return nil, err
+	}
+	return user, nil
 }
 
+// UsersByGroup lists the users with or without a team. Managers see all the
+// users without one, to add them to their teams, but only the members of the
+// teams they manage
 func (r *queryResolver) UsersByGroup(ctx context.Context, inGroup bool) ([]*model.User, error) {
-	return userQueries.GetUsersByGroup(inGroup)
+	if err := middlewares.VerifyRole(ctx, "ADMIN", "MANAGER"); err != nil {
+		return nil, err
+	}
+	callerID, role, err := callerIdentity(ctx)
+	if err != nil {
+		return nil, err
+	}
+	users, err := userQueries.GetUsersByGroup(inGroup)
+	if err != nil || !inGroup || role == string(model.RoleAdmin) {
+		return users, err
+	}
+	visible := make([]*model.User, 0, len(users))
+	for _, u := range users {
+		uid, err := uuid.Parse(u.ID)
+		if err != nil {
+			continue
+		}
+		if r.AccessService.CanSeeUser(callerID, role, uid) == nil {
+			visible = append(visible, u)
+		}
+	}
+	return visible, nil
 }
 
 func (r *queryResolver) UserWithAllData(ctx context.Context, id string) (*model.UserWithAllData, error) {
@@ -30,6 +76,9 @@ func (r *queryResolver) UserWithAllData(ctx context.Context, id string) (*model.
 }
 
 func (r *queryResolver) UsersWithAllData(ctx context.Context) ([]*model.UserWithAllData, error) {
+	if err := middlewares.VerifyRole(ctx, "ADMIN"); err != nil {
+		return nil, err
+	}
 	return userQueries.ListUsersWithAllData()
 }
 
@@ -46,9 +95,79 @@ func (r *mutationResolver) CreateMassiveUsers(ctx context.Context, input model.C
 }
 
 func (r *mutationResolver) ClockIn(ctx context.Context) (*model.TimeTableEntry, error) {
-	return timetableEntryMutation.ClockIn(ctx, r.DB)
+	// Pas de pointage sur une semaine dont la feuille de temps est soumise ou
+	// validée ; la période de paie est vérifiée dans la transaction du pointage
+	if callerID, _, err := callerIdentity(ctx); err == nil {
+		today, err := r.ScheduleService.Today(callerID)
+		if err != nil {
+			return nil, err
+		}
+		if err := r.TimesheetService.EnsureWeekOpen(callerID.String(), today); err != nil {
+			return nil, err
+		}
+	}
+	entry, err := timetableEntryMutation.ClockIn(ctx, r.DB)
+	if err != nil {
+		return nil, err
+	}
+	// Contrôle du retard par rapport aux heures planifiées, enregistré s'il y a lieu
+	if _, err := r.LatenessService.RecordArrival(entry); err != nil {
+		log.Printf("failed to record lateness: %v", err)
+	}
+	if entry.UserID != nil {
+		r.RollupService.Refresh(entry.UserID.ID, entry.Day)
+	}
+	return entry, nil
 }
 
 func (r *mutationResolver) ClockOut(ctx context.Context) (*model.TimeTableEntry, error) {
-	return timetableEntryMutation.ClockOut(ctx, r.DB)
+	if err := r.ensureSessionUnlocked(ctx); err != nil {
+		return nil, err
+	}
+	entry, err := timetableEntryMutation.ClockOut(ctx, r.DB)
+	if err != nil {
+		return nil, err
+	}
+	if entry.UserID != nil {
+		r.RollupService.Refresh(entry.UserID.ID, entry.Day)
+	}
+	return entry, nil
+}
+
+func (r *mutationResolver) StartBreak(ctx context.Context, breakTypeID string) (*model.Break, error) {
+	if err := r.ensureSessionUnlocked(ctx); err != nil {
+		return nil, err
+	}
+	b, err := timetableEntryMutation.StartBreak(ctx, r.DB, breakTypeID)
+	if err != nil {
+		return nil, err
+	}
+	r.RollupService.RefreshEntry(b.EntryID)
+	return b, nil
+}
+
+func (r *mutationResolver) EndBreak(ctx context.Context) (*model.Break, error) {
+	if err := r.ensureSessionUnlocked(ctx); err != nil {
+		return nil, err
+	}
+	b, err := timetableEntryMutation.EndBreak(ctx, r.DB)
+	if err != nil {
+		return nil, err
+	}
+	r.RollupService.RefreshEntry(b.EntryID)
+	return b, nil
+}
+
+// ensureSessionUnlocked fails when the caller's running session falls in a
+// closed pay period. Without a running session there is nothing to check: the
+// mutation itself reports it.
+func (r *mutationResolver) ensureSessionUnlocked(ctx context.Context) error {
+	session, err := timetableEntryMutation.OpenSession(ctx, r.DB)
+	if errors.Is(err, timetableEntryMutation.ErrNotClockedIn) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return r.PayPeriodService.EnsureOpen(session.Day)
 }
@@ -0,0 +1,53 @@
+package breakMapper
+
+import (
+	"github.com/epitech/timemanager/internal/graph/model"
+	gmodel "github.com/epitech/timemanager/internal/models"
+)
+
+func DBBreakTypeToGraph(bt *gmodel.BreakType) *model.BreakType {
+	if bt == nil {
+		return nil
+	}
+	return &model.BreakType{
+		ID:       bt.ID.String(),
+		Name:     bt.Name,
+		Paid:     bt.Paid,
+		IsActive: bt.IsActive,
+	}
+}
+
+func DBBreakTypesToGraph(types []*gmodel.BreakType) []*model.BreakType {
+	out := make([]*model.BreakType, 0, len(types))
+	for i := range types {
+		out = append(out, DBBreakTypeToGraph(types[i]))
+	}
+	return out
+}
+
+func DBBreakToGraph(b *gmodel.Break) *model.Break {
+	if b == nil {
+		return nil
+	}
+	out := &model.Break{
+		ID:        b.ID.String(),
+		EntryID:   b.TimeTableEntryID.String(),
+		Paid:      b.Paid,
+		StartedAt: b.StartedAt,
+		EndedAt:   b.EndedAt,
+	}
+	if b.BreakType != nil {
+		out.BreakType = DBBreakTypeToGraph(b.BreakType)
+	} else {
+		out.BreakType = &model.BreakType{ID: b.BreakTypeID.String()}
+	}
+	return out
+}
+
+func DBBreaksToGraph(breaks []gmodel.Break) []*model.Break {
+	out := make([]*model.Break, 0, len(breaks))
+	for i := range breaks {
+		out = append(out, DBBreakToGraph(&breaks[i]))
+	}
+	return out
+}
@@ -0,0 +1,51 @@
+package complianceMapper
+
+import (
+	"github.com/epitech/timemanager/internal/graph/model"
+	gmodel "github.com/epitech/timemanager/internal/models"
+)
+
+func DBComplianceRuleToGraph(r *gmodel.ComplianceRule) *model.ComplianceRule {
+	if r == nil {
+		return nil
+	}
+	out := &model.ComplianceRule{
+		Code:         model.ComplianceRuleCode(r.Code),
+		Enabled:      r.Enabled,
+		Severity:     model.ComplianceSeverity(r.Severity),
+		LimitMinutes: int32(r.LimitMinutes),
+	}
+	if r.BreakMinutes > 0 {
+		breakMinutes := int32(r.BreakMinutes)
+		out.BreakMinutes = &breakMinutes
+	}
+	if r.Weeks > 0 {
+		weeks := int32(r.Weeks)
+		out.Weeks = &weeks
+	}
+	return out
+}
+
+func DBComplianceRulesToGraph(rules []*gmodel.ComplianceRule) []*model.ComplianceRule {
+	out := make([]*model.ComplianceRule, 0, len(rules))
+	for _, r := range rules {
+		out = append(out, DBComplianceRuleToGraph(r))
+	}
+	return out
+}
+
+func GraphComplianceRuleToDB(r *model.ComplianceRule) *gmodel.ComplianceRule {
+	out := &gmodel.ComplianceRule{
+		Code:         string(r.Code),
+		Enabled:      r.Enabled,
+		Severity:     string(r.Severity),
+		LimitMinutes: int(r.LimitMinutes),
+	}
+	if r.BreakMinutes != nil {
+		out.BreakMinutes = int(*r.BreakMinutes)
+	}
+	if r.Weeks != nil {
+		out.Weeks = int(*r.Weeks)
+	}
+	return out
+}
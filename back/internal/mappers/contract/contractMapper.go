@@ -0,0 +1,61 @@
+package contractMapper
+
+import (
+	"math/bits"
+
+	"github.com/epitech/timemanager/internal/graph/model"
+	workScheduleMapper "github.com/epitech/timemanager/internal/mappers/workSchedule"
+	gmodel "github.com/epitech/timemanager/internal/models"
+)
+
+func DBContractToGraph(c *gmodel.EmploymentContract) *model.EmploymentContract {
+	if c == nil {
+		return nil
+	}
+	out := &model.EmploymentContract{
+		ID:            c.ID.String(),
+		UserID:        c.UserID.String(),
+		Type:          model.ContractType(c.Type),
+		WeeklyHours:   float64(c.WeeklyMinutes) / 60,
+		WorkingDays:   WorkingDaysToGraph(c.WorkingDays),
+		EffectiveFrom: c.EffectiveFrom,
+	}
+	if n := bits.OnesCount(uint(c.WorkingDays)); n > 0 {
+		out.DailyMinutes = int32(c.WeeklyMinutes / n)
+	}
+	if c.EffectiveTo != "" {
+		to := c.EffectiveTo
+		out.EffectiveTo = &to
+	}
+	return out
+}
+
+func DBContractsToGraph(contracts []*gmodel.EmploymentContract) []*model.EmploymentContract {
+	out := make([]*model.EmploymentContract, 0, len(contracts))
+	for i := range contracts {
+		out = append(out, DBContractToGraph(contracts[i]))
+	}
+	return out
+}
+
+// WorkingDaysToGraph convertit le masque des jours travaillés, lundi en premier
+func WorkingDaysToGraph(mask int) []model.Weekday {
+	days := make([]model.Weekday, 0, 7)
+	for _, d := range model.AllWeekday {
+		if mask&(1<<workScheduleMapper.WeekdayToDB(d)) != 0 {
+			days = append(days, d)
+		}
+	}
+	return days
+}
+
+// WorkingDaysToDB convertit les jours travaillés en masque (bit 0 = dimanche)
+func WorkingDaysToDB(days []model.Weekday) int {
+	mask := 0
+	for _, d := range days {
+		if i := workScheduleMapper.WeekdayToDB(d); i >= 0 {
+			mask |= 1 << i
+		}
+	}
+	return mask
+}
@@ -0,0 +1,55 @@
+package holidayMapper
+
+import (
+	"github.com/epitech/timemanager/internal/graph/model"
+	gmodel "github.com/epitech/timemanager/internal/models"
+)
+
+func DBHolidayToGraph(h *gmodel.Holiday) *model.Holiday {
+	if h == nil {
+		return nil
+	}
+	return &model.Holiday{
+		Date: h.Date,
+		Name: h.Name,
+	}
+}
+
+func DBHolidaysToGraph(holidays []*gmodel.Holiday) []*model.Holiday {
+	out := make([]*model.Holiday, 0, len(holidays))
+	for i := range holidays {
+		out = append(out, DBHolidayToGraph(holidays[i]))
+	}
+	return out
+}
+
+func DBHolidayCalendarToGraph(hc *gmodel.HolidayCalendar) *model.HolidayCalendar {
+	if hc == nil {
+		return nil
+	}
+	out := &model.HolidayCalendar{
+		ID:       hc.ID.String(),
+		Name:     hc.Name,
+		Holidays: make([]*model.Holiday, 0, len(hc.Holidays)),
+		SiteIDs:  make([]string, 0, len(hc.Sites)),
+		TeamIDs:  make([]string, 0, len(hc.Teams)),
+	}
+	for i := range hc.Holidays {
+		out.Holidays = append(out.Holidays, DBHolidayToGraph(&hc.Holidays[i]))
+	}
+	for _, s := range hc.Sites {
+		out.SiteIDs = append(out.SiteIDs, s.ID.String())
+	}
+	for _, t := range hc.Teams {
+		out.TeamIDs = append(out.TeamIDs, t.ID.String())
+	}
+	return out
+}
+
+func DBHolidayCalendarsToGraph(calendars []*gmodel.HolidayCalendar) []*model.HolidayCalendar {
+	out := make([]*model.HolidayCalendar, 0, len(calendars))
+	for i := range calendars {
+		out = append(out, DBHolidayCalendarToGraph(calendars[i]))
+	}
+	return out
+}
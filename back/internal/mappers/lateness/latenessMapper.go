@@ -0,0 +1,51 @@
+package latenessMapper
+
+import (
+	"github.com/epitech/timemanager/internal/graph/model"
+	userMapper "github.com/epitech/timemanager/internal/mappers/user"
+	gmodel "github.com/epitech/timemanager/internal/models"
+)
+
+func DBLatenessEventToGraph(e *gmodel.LatenessEvent) *model.LatenessEvent {
+	if e == nil {
+		return nil
+	}
+	out := &model.LatenessEvent{
+		ID:           e.ID.String(),
+		EntryID:      e.TimeTableEntryID.String(),
+		Day:          e.Day,
+		PlannedStart: e.PlannedStart,
+		Arrival:      e.Arrival,
+		LateMinutes:  int32(e.LateMinutes),
+		Status:       model.LatenessStatus(e.Status),
+		CreatedAt:    e.CreatedAt,
+		ReviewedAt:   e.ReviewedAt,
+	}
+	if e.User != nil {
+		out.UserID = userMapper.DBUserToGraph(e.User)
+	} else {
+		out.UserID = &model.User{ID: e.UserID.String()}
+	}
+	if e.Reviewer != nil {
+		out.ReviewerID = userMapper.DBUserToGraph(e.Reviewer)
+	} else if e.ReviewerID != nil {
+		out.ReviewerID = &model.User{ID: e.ReviewerID.String()}
+	}
+	if e.Justification != "" {
+		justification := e.Justification
+		out.Justification = &justification
+	}
+	if e.ReviewComment != "" {
+		comment := e.ReviewComment
+		out.ReviewComment = &comment
+	}
+	return out
+}
+
+func DBLatenessEventsToGraph(events []*gmodel.LatenessEvent) []*model.LatenessEvent {
+	out := make([]*model.LatenessEvent, 0, len(events))
+	for i := range events {
+		out = append(out, DBLatenessEventToGraph(events[i]))
+	}
+	return out
+}
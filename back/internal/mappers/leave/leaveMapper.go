@@ -0,0 +1,102 @@
+package leaveMapper
+
+import (
+	"github.com/epitech/timemanager/internal/graph/model"
+	userMapper "github.com/epitech/timemanager/internal/mappers/user"
+	gmodel "github.com/epitech/timemanager/internal/models"
+)
+
+func DBAbsenceTypeToGraph(at *gmodel.AbsenceType) *model.AbsenceType {
+	if at == nil {
+		return nil
+	}
+	out := &model.AbsenceType{
+		ID:                  at.ID.String(),
+		Name:                at.Name,
+		Paid:                at.Paid,
+		DeductsBalance:      at.DeductsBalance,
+		AccrualDaysPerMonth: at.AccrualDaysPerMonth,
+		IsActive:            at.IsActive,
+	}
+	if at.MaxBalance > 0 {
+		maxBalance := at.MaxBalance
+		out.MaxBalance = &maxBalance
+	}
+	return out
+}
+
+func DBAbsenceTypesToGraph(types []*gmodel.AbsenceType) []*model.AbsenceType {
+	out := make([]*model.AbsenceType, 0, len(types))
+	for i := range types {
+		out = append(out, DBAbsenceTypeToGraph(types[i]))
+	}
+	return out
+}
+
+func DBLeaveRequestToGraph(lr *gmodel.LeaveRequest) *model.LeaveRequest {
+	if lr == nil {
+		return nil
+	}
+	out := &model.LeaveRequest{
+		ID:         lr.ID.String(),
+		StartDate:  lr.StartDate,
+		EndDate:    lr.EndDate,
+		HalfDay:    lr.HalfDay,
+		Days:       lr.Days,
+		Status:     model.LeaveStatus(lr.Status),
+		CreatedAt:  lr.CreatedAt,
+		ReviewedAt: lr.ReviewedAt,
+	}
+	if lr.AbsenceType != nil {
+		out.AbsenceType = DBAbsenceTypeToGraph(lr.AbsenceType)
+	} else {
+		out.AbsenceType = &model.AbsenceType{ID: lr.AbsenceTypeID.String()}
+	}
+	if lr.User != nil {
+		out.UserID = userMapper.DBUserToGraph(lr.User)
+	} else {
+		out.UserID = &model.User{ID: lr.UserID.String()}
+	}
+	if lr.Reviewer != nil {
+		out.ReviewerID = userMapper.DBUserToGraph(lr.Reviewer)
+	} else if lr.ReviewerID != nil {
+		out.ReviewerID = &model.User{ID: lr.ReviewerID.String()}
+	}
+	if lr.Reason != "" {
+		reason := lr.Reason
+		out.Reason = &reason
+	}
+	if lr.ReviewComment != "" {
+		comment := lr.ReviewComment
+		out.ReviewComment = &comment
+	}
+	return out
+}
+
+func DBLeaveRequestsToGraph(requests []*gmodel.LeaveRequest) []*model.LeaveRequest {
+	out := make([]*model.LeaveRequest, 0, len(requests))
+	for i := range requests {
+		out = append(out, DBLeaveRequestToGraph(requests[i]))
+	}
+	return out
+}
+
+func DBLeaveBalanceToGraph(lb *gmodel.LeaveBalance) *model.LeaveBalance {
+	if lb == nil {
+		return nil
+	}
+	out := &model.LeaveBalance{
+		UserID:  lb.UserID.String(),
+		Balance: lb.Balance,
+	}
+	if lb.AbsenceType != nil {
+		out.AbsenceType = DBAbsenceTypeToGraph(lb.AbsenceType)
+	} else {
+		out.AbsenceType = &model.AbsenceType{ID: lb.AbsenceTypeID.String()}
+	}
+	if lb.AccruedThrough != "" {
+		accrued := lb.AccruedThrough
+		out.AccruedThrough = &accrued
+	}
+	return out
+}
@@ -0,0 +1,79 @@
+package overtimeMapper
+
+import (
+	"sort"
+	"time"
+
+	"github.com/epitech/timemanager/internal/graph/model"
+	gmodel "github.com/epitech/timemanager/internal/models"
+)
+
+func DBOvertimePolicyToGraph(p *gmodel.OvertimePolicy) *model.OvertimePolicy {
+	if p == nil {
+		return nil
+	}
+	id := p.ID.String()
+	effectiveFrom := p.EffectiveFrom.Format(time.RFC3339)
+	bands := make([]gmodel.OvertimeBand, len(p.Bands))
+	copy(bands, p.Bands)
+	sort.Slice(bands, func(i, j int) bool { return bands[i].Position < bands[j].Position })
+	out := &model.OvertimePolicy{
+		ID:                     &id,
+		DailyEnabled:           p.DailyEnabled,
+		DailyThresholdMinutes:  intToGraph(p.DailyThresholdMinutes),
+		WeeklyEnabled:          p.WeeklyEnabled,
+		WeeklyThresholdMinutes: intToGraph(p.WeeklyThresholdMinutes),
+		DailyCapMinutes:        intToGraph(p.DailyCapMinutes),
+		WeeklyCapMinutes:       intToGraph(p.WeeklyCapMinutes),
+		Bands:                  make([]*model.OvertimeBand, 0, len(bands)),
+		EffectiveFrom:          &effectiveFrom,
+	}
+	for _, b := range bands {
+		out.Bands = append(out.Bands, &model.OvertimeBand{
+			UpToMinutes: intToGraph(b.UpToMinutes),
+			RatePercent: int32(b.RatePercent),
+		})
+	}
+	return out
+}
+
+// OvertimePolicyInputToDB construit une nouvelle politique active à partir de la saisie
+func OvertimePolicyInputToDB(input model.OvertimePolicyInput, effectiveFrom time.Time) *gmodel.OvertimePolicy {
+	p := &gmodel.OvertimePolicy{
+		DailyEnabled:           input.DailyEnabled,
+		DailyThresholdMinutes:  intToDB(input.DailyThresholdMinutes),
+		WeeklyEnabled:          input.WeeklyEnabled,
+		WeeklyThresholdMinutes: intToDB(input.WeeklyThresholdMinutes),
+		DailyCapMinutes:        intToDB(input.DailyCapMinutes),
+		WeeklyCapMinutes:       intToDB(input.WeeklyCapMinutes),
+		EffectiveFrom:          effectiveFrom,
+		IsActive:               true,
+	}
+	for i, b := range input.Bands {
+		if b == nil {
+			continue
+		}
+		p.Bands = append(p.Bands, gmodel.OvertimeBand{
+			Position:    i,
+			UpToMinutes: intToDB(b.UpToMinutes),
+			RatePercent: int(b.RatePercent),
+		})
+	}
+	return p
+}
+
+func intToGraph(v *int) *int32 {
+	if v == nil {
+		return nil
+	}
+	out := int32(*v)
+	return &out
+}
+
+func intToDB(v *int32) *int {
+	if v == nil {
+		return nil
+	}
+	out := int(*v)
+	return &out
+}
@@ -0,0 +1,45 @@
+package payPeriodMapper
+
+import (
+	"github.com/epitech/timemanager/internal/graph/model"
+	userMapper "github.com/epitech/timemanager/internal/mappers/user"
+	gmodel "github.com/epitech/timemanager/internal/models"
+	"github.com/google/uuid"
+)
+
+func DBPayPeriodToGraph(pp *gmodel.PayPeriod) *model.PayPeriod {
+	if pp == nil {
+		return nil
+	}
+	out := &model.PayPeriod{
+		ID:         pp.ID.String(),
+		StartDate:  pp.StartDate,
+		EndDate:    pp.EndDate,
+		Status:     model.PayPeriodStatus(pp.Status),
+		ClosedAt:   pp.ClosedAt,
+		ReopenedAt: pp.ReopenedAt,
+	}
+	if pp.ClosedBy != nil {
+		out.ClosedByID = userMapper.DBUserToGraph(pp.ClosedBy)
+	} else if pp.ClosedByID != uuid.Nil {
+		out.ClosedByID = &model.User{ID: pp.ClosedByID.String()}
+	}
+	if pp.ReopenedBy != nil {
+		out.ReopenedByID = userMapper.DBUserToGraph(pp.ReopenedBy)
+	} else if pp.ReopenedByID != nil {
+		out.ReopenedByID = &model.User{ID: pp.ReopenedByID.String()}
+	}
+	if pp.ReopenReason != "" {
+		reason := pp.ReopenReason
+		out.ReopenReason = &reason
+	}
+	return out
+}
+
+func DBPayPeriodsToGraph(periods []*gmodel.PayPeriod) []*model.PayPeriod {
+	out := make([]*model.PayPeriod, 0, len(periods))
+	for i := range periods {
+		out = append(out, DBPayPeriodToGraph(periods[i]))
+	}
+	return out
+}
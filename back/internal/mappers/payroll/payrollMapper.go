@@ -0,0 +1,161 @@
+package payrollMapper
+
+import (
+	"sort"
+
+	"github.com/epitech/timemanager/internal/graph/model"
+	leaveMapper "github.com/epitech/timemanager/internal/mappers/leave"
+	gmodel "github.com/epitech/timemanager/internal/models"
+	"github.com/epitech/timemanager/services"
+	"github.com/google/uuid"
+)
+
+func DBPayCodeToGraph(pc *gmodel.PayCode) *model.PayCode {
+	if pc == nil {
+		return nil
+	}
+	out := &model.PayCode{
+		ID:       pc.ID.String(),
+		Category: model.PayCategory(pc.Category),
+		Code:     pc.Code,
+	}
+	if pc.RatePercent != nil {
+		rate := int32(*pc.RatePercent)
+		out.RatePercent = &rate
+	}
+	if pc.AbsenceType != nil {
+		out.AbsenceType = leaveMapper.DBAbsenceTypeToGraph(pc.AbsenceType)
+	} else if pc.AbsenceTypeID != nil {
+		out.AbsenceType = &model.AbsenceType{ID: pc.AbsenceTypeID.String()}
+	}
+	if pc.Label != "" {
+		label := pc.Label
+		out.Label = &label
+	}
+	return out
+}
+
+func DBPayCodesToGraph(codes []*gmodel.PayCode) []*model.PayCode {
+	out := make([]*model.PayCode, 0, len(codes))
+	for i := range codes {
+		out = append(out, DBPayCodeToGraph(codes[i]))
+	}
+	return out
+}
+
+func DBPayrollLayoutToGraph(pl *gmodel.PayrollLayout) *model.PayrollLayout {
+	if pl == nil {
+		return nil
+	}
+	columns := make([]gmodel.PayrollLayoutColumn, len(pl.Columns))
+	copy(columns, pl.Columns)
+	sort.Slice(columns, func(i, j int) bool { return columns[i].Position < columns[j].Position })
+	out := &model.PayrollLayout{
+		ID:        pl.ID.String(),
+		Name:      pl.Name,
+		Format:    model.PayrollFormat(pl.Format),
+		Delimiter: pl.Delimiter,
+		Header:    pl.Header,
+		Columns:   make([]*model.PayrollColumn, 0, len(columns)),
+	}
+	for _, c := range columns {
+		column := &model.PayrollColumn{Field: model.PayrollField(c.Field)}
+		if c.Header != "" {
+			header := c.Header
+			column.Header = &header
+		}
+		if c.Width > 0 {
+			width := int32(c.Width)
+			column.Width = &width
+		}
+		if c.Align != "" {
+			align := model.PayrollAlign(c.Align)
+			column.Align = &align
+		}
+		out.Columns = append(out.Columns, column)
+	}
+	return out
+}
+
+func DBPayrollLayoutsToGraph(layouts []*gmodel.PayrollLayout) []*model.PayrollLayout {
+	out := make([]*model.PayrollLayout, 0, len(layouts))
+	for i := range layouts {
+		out = append(out, DBPayrollLayoutToGraph(layouts[i]))
+	}
+	return out
+}
+
+// PayrollLayoutInputToDB applique la saisie, déjà validée par le service, à la
+// disposition ; ses colonnes sont remplacées
+func PayrollLayoutInputToDB(pl *gmodel.PayrollLayout, input model.PayrollLayoutInput) {
+	pl.Name = input.Name
+	pl.Format = string(input.Format)
+	pl.Delimiter = ""
+	if input.Delimiter != nil {
+		pl.Delimiter = *input.Delimiter
+	}
+	pl.Header = input.Header == nil || *input.Header
+	pl.Columns = make([]gmodel.PayrollLayoutColumn, 0, len(input.Columns))
+	for i, c := range input.Columns {
+		if c == nil {
+			continue
+		}
+		column := gmodel.PayrollLayoutColumn{Position: i, Field: string(c.Field)}
+		if c.Header != nil {
+			column.Header = *c.Header
+		}
+		if c.Width != nil {
+			column.Width = int(*c.Width)
+		}
+		if c.Align != nil {
+			column.Align = string(*c.Align)
+		}
+		pl.Columns = append(pl.Columns, column)
+	}
+}
+
+// DBPayrollLinesToTotals relit les totaux de paie figés à la clôture
+func DBPayrollLinesToTotals(lines []*gmodel.PayrollLine) []*services.PayrollTotal {
+	out := make([]*services.PayrollTotal, 0, len(lines))
+	for _, l := range lines {
+		total := &services.PayrollTotal{
+			User:        &model.User{ID: l.UserID.String(), FirstName: l.FirstName, LastName: l.LastName, Email: l.Email},
+			Category:    model.PayCategory(l.Category),
+			RatePercent: l.RatePercent,
+			Quantity:    l.Quantity,
+		}
+		if l.AbsenceTypeID != nil {
+			total.AbsenceType = &model.AbsenceType{ID: l.AbsenceTypeID.String(), Name: l.AbsenceTypeName}
+		}
+		out = append(out, total)
+	}
+	return out
+}
+
+// PayrollTotalToDB recopie un total de paie de la période, avec l'identité de
+// l'employé telle qu'à la clôture
+func PayrollTotalToDB(periodID uuid.UUID, t *services.PayrollTotal) (*gmodel.PayrollLine, error) {
+	userID, err := uuid.Parse(t.User.ID)
+	if err != nil {
+		return nil, err
+	}
+	line := &gmodel.PayrollLine{
+		PayPeriodID: periodID,
+		UserID:      userID,
+		FirstName:   t.User.FirstName,
+		LastName:    t.User.LastName,
+		Email:       t.User.Email,
+		Category:    string(t.Category),
+		RatePercent: t.RatePercent,
+		Quantity:    t.Quantity,
+	}
+	if t.AbsenceType != nil {
+		absenceTypeID, err := uuid.Parse(t.AbsenceType.ID)
+		if err != nil {
+			return nil, err
+		}
+		line.AbsenceTypeID = &absenceTypeID
+		line.AbsenceTypeName = t.AbsenceType.Name
+	}
+	return line, nil
+}
@@ -0,0 +1,68 @@
+package rollupMapper
+
+import (
+	"github.com/epitech/timemanager/internal/graph/model"
+	gmodel "github.com/epitech/timemanager/internal/models"
+	"github.com/google/uuid"
+)
+
+func DBDailyRollupToGraph(r *gmodel.DailyRollup) *model.DailyRollup {
+	if r == nil {
+		return nil
+	}
+	out := &model.DailyRollup{
+		UserID:                r.UserID.String(),
+		Day:                   r.Day,
+		WorkedMinutes:         int32(r.WorkedMinutes),
+		UnpaidBreakMinutes:    int32(r.UnpaidBreakMinutes),
+		OvertimeMinutes:       int32(r.OvertimeMinutes),
+		OvertimeExcessMinutes: int32(r.OvertimeExcessMinutes),
+		FirstArrival:          r.FirstArrival,
+		Sessions:              int32(r.Sessions),
+		OpenSessions:          int32(r.OpenSessions),
+		ActiveSince:           r.ActiveSince,
+		UpdatedAt:             r.UpdatedAt,
+	}
+	if r.User != nil {
+		out.UserName = r.User.FirstName + " " + r.User.LastName
+	}
+	if r.LateMinutes != nil {
+		late := int32(*r.LateMinutes)
+		out.LateMinutes = &late
+	}
+	return out
+}
+
+func DBDailyRollupsToGraph(rollups []*gmodel.DailyRollup) []*model.DailyRollup {
+	out := make([]*model.DailyRollup, 0, len(rollups))
+	for _, r := range rollups {
+		out = append(out, DBDailyRollupToGraph(r))
+	}
+	return out
+}
+
+// GraphDailyRollupToDB ignore les cumuls dont l'utilisateur n'est pas un UUID
+func GraphDailyRollupToDB(r *model.DailyRollup) (*gmodel.DailyRollup, bool) {
+	userID, err := uuid.Parse(r.UserID)
+	if err != nil {
+		return nil, false
+	}
+	out := &gmodel.DailyRollup{
+		UserID:                userID,
+		Day:                   r.Day,
+		WorkedMinutes:         int(r.WorkedMinutes),
+		UnpaidBreakMinutes:    int(r.UnpaidBreakMinutes),
+		OvertimeMinutes:       int(r.OvertimeMinutes),
+		OvertimeExcessMinutes: int(r.OvertimeExcessMinutes),
+		FirstArrival:          r.FirstArrival,
+		Sessions:              int(r.Sessions),
+		OpenSessions:          int(r.OpenSessions),
+		ActiveSince:           r.ActiveSince,
+		UpdatedAt:             r.UpdatedAt,
+	}
+	if r.LateMinutes != nil {
+		late := int(*r.LateMinutes)
+		out.LateMinutes = &late
+	}
+	return out, true
+}
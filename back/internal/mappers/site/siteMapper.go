@@ -0,0 +1,25 @@
+package siteMapper
+
+import (
+	"github.com/epitech/timemanager/internal/graph/model"
+	gmodel "github.com/epitech/timemanager/internal/models"
+)
+
+func DBSiteToGraph(s *gmodel.Site) *model.Site {
+	if s == nil {
+		return nil
+	}
+	return &model.Site{
+		ID:       s.ID.String(),
+		Name:     s.Name,
+		TimeZone: s.TimeZone,
+	}
+}
+
+func DBSitesToGraph(sites []*gmodel.Site) []*model.Site {
+	out := make([]*model.Site, 0, len(sites))
+	for i := range sites {
+		out = append(out, DBSiteToGraph(sites[i]))
+	}
+	return out
+}
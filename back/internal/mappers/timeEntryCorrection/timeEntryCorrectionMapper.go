@@ -0,0 +1,50 @@
+package timeEntryCorrectionMapper
+
+import (
+	"github.com/epitech/timemanager/internal/graph/model"
+	userMapper "github.com/epitech/timemanager/internal/mappers/user"
+	gmodel "github.com/epitech/timemanager/internal/models"
+)
+
+func DBCorrectionToGraph(c *gmodel.TimeEntryCorrection) *model.TimeEntryCorrection {
+	if c == nil {
+		return nil
+	}
+	out := &model.TimeEntryCorrection{
+		ID:                c.ID.String(),
+		Day:               c.Day,
+		ProposedArrival:   c.ProposedArrival,
+		ProposedDeparture: c.ProposedDeparture,
+		Reason:            c.Reason,
+		Status:            model.CorrectionStatus(c.Status),
+		CreatedAt:         c.CreatedAt,
+		ReviewedAt:        c.ReviewedAt,
+	}
+	if c.TimeTableEntryID != nil {
+		entryID := c.TimeTableEntryID.String()
+		out.EntryID = &entryID
+	}
+	if c.User != nil {
+		out.UserID = userMapper.DBUserToGraph(c.User)
+	} else {
+		out.UserID = &model.User{ID: c.UserID.String()}
+	}
+	if c.Reviewer != nil {
+		out.ReviewerID = userMapper.DBUserToGraph(c.Reviewer)
+	} else if c.ReviewerID != nil {
+		out.ReviewerID = &model.User{ID: c.ReviewerID.String()}
+	}
+	if c.ReviewComment != "" {
+		comment := c.ReviewComment
+		out.ReviewComment = &comment
+	}
+	return out
+}
+
+func DBCorrectionsToGraph(corrections []*gmodel.TimeEntryCorrection) []*model.TimeEntryCorrection {
+	out := make([]*model.TimeEntryCorrection, 0, len(corrections))
+	for i := range corrections {
+		out = append(out, DBCorrectionToGraph(corrections[i]))
+	}
+	return out
+}
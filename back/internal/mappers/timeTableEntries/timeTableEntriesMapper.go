@@ -5,6 +5,7 @@ import (
 	"time"
 
 	"github.com/epitech/timemanager/internal/graph/model"
+	breakMapper "github.com/epitech/timemanager/internal/mappers/breaks"
 	gmodel "github.com/epitech/timemanager/internal/models"
 	"github.com/google/uuid"
 )
@@ -24,15 +25,22 @@ func DBTimeTableEntryToGraph(e *gmodel.TimeTableEntry) *model.TimeTableEntry {
 		user.FirstName = e.User.FirstName
 		user.LastName = e.User.LastName
 		user.Email = e.User.Email
+		// le fuseau de l'utilisateur situe la journée et le retard de chaque pointage
+		if tz := e.User.EffectiveTimeZone(); tz != "" {
+			user.TimeZone = &tz
+		}
 	}
 
 	return &model.TimeTableEntry{
-		ID:        e.ID.String(),
-		Day:       e.Day,
-		Arrival:   e.Arrival,
-		Departure: &e.Departure,
-		Status:    e.Status,
-		UserID:    user,
+		ID:          e.ID.String(),
+		Day:         e.Day,
+		Arrival:     e.Arrival,
+		Departure:   &e.Departure,
+		Status:      e.Status,
+		UserID:      user,
+		Breaks:      breakMapper.DBBreaksToGraph(e.Breaks),
+		AutoClosed:  e.AutoClosed,
+		NeedsReview: e.NeedsReview,
 	}
 }
 
@@ -57,9 +65,11 @@ func GraphTimeTableEntryToDB(e *model.TimeTableEntry) *gmodel.TimeTableEntry {
 	}
 
 	entry := &gmodel.TimeTableEntry{
-		Day:     e.Day,
-		Arrival: e.Arrival,
-		Status:  e.Status,
+		Day:         e.Day,
+		Arrival:     e.Arrival,
+		Status:      e.Status,
+		AutoClosed:  e.AutoClosed,
+		NeedsReview: e.NeedsReview,
 	}
 
 	// Gérer correctement le champ Departure qui est un pointeur dans le modèle GraphQL
@@ -0,0 +1,47 @@
+package timesheetMapper
+
+import (
+	"github.com/epitech/timemanager/internal/graph/model"
+	userMapper "github.com/epitech/timemanager/internal/mappers/user"
+	gmodel "github.com/epitech/timemanager/internal/models"
+)
+
+// DBTimesheetToGraph ne renseigne que l'état de la feuille ; ses heures sont
+// calculées par le service
+func DBTimesheetToGraph(ts *gmodel.Timesheet) *model.Timesheet {
+	if ts == nil {
+		return nil
+	}
+	id := ts.ID.String()
+	out := &model.Timesheet{
+		ID:          &id,
+		WeekStart:   ts.WeekStart,
+		Status:      model.TimesheetStatus(ts.Status),
+		Days:        []*model.DailyRollup{},
+		SubmittedAt: ts.SubmittedAt,
+		ReviewedAt:  ts.ReviewedAt,
+	}
+	if ts.User != nil {
+		out.UserID = userMapper.DBUserToGraph(ts.User)
+	} else {
+		out.UserID = &model.User{ID: ts.UserID.String()}
+	}
+	if ts.Reviewer != nil {
+		out.ReviewerID = userMapper.DBUserToGraph(ts.Reviewer)
+	} else if ts.ReviewerID != nil {
+		out.ReviewerID = &model.User{ID: ts.ReviewerID.String()}
+	}
+	if ts.ReviewComment != "" {
+		comment := ts.ReviewComment
+		out.ReviewComment = &comment
+	}
+	return out
+}
+
+func DBTimesheetsToGraph(timesheets []*gmodel.Timesheet) []*model.Timesheet {
+	out := make([]*model.Timesheet, 0, len(timesheets))
+	for i := range timesheets {
+		out = append(out, DBTimesheetToGraph(timesheets[i]))
+	}
+	return out
+}
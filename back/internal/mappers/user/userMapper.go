@@ -7,6 +7,7 @@ import (
 	teamMapper "github.com/epitech/timemanager/internal/mappers/team"
 	timeTableEntriesMapper "github.com/epitech/timemanager/internal/mappers/timeTableEntries"
 	gmodel "github.com/epitech/timemanager/internal/models"
+	"github.com/google/uuid"
 )
 
 func DBUserToGraph(u *gmodel.User) *model.User {
@@ -22,23 +23,42 @@ func DBUserToGraph(u *gmodel.User) *model.User {
 		// Do not expose hashed password in GraphQL responses
 		Password: "",
 		Role:     model.Role(u.Role),
+		TimeZone: timeZoneOrNil(u),
+		SiteID:   siteIDOrNil(u),
 	}
 }
 
-func DBUserToSignedGraph(u *gmodel.User, hasStartedDay bool, startedAt *string) *model.SignedUser {
+// timeZoneOrNil expose le fuseau effectif ; nil signifie le fuseau par défaut du serveur
+func timeZoneOrNil(u *gmodel.User) *string {
+	if tz := u.EffectiveTimeZone(); tz != "" {
+		return &tz
+	}
+	return nil
+}
+
+func siteIDOrNil(u *gmodel.User) *string {
+	if u.SiteID == nil {
+		return nil
+	}
+	id := u.SiteID.String()
+	return &id
+}
+
+func DBUserToSignedGraph(u *gmodel.User, hasStartedDay bool, startedAt *string, workedMinutesToday int) *model.SignedUser {
 	if u == nil {
 		return nil
 	}
 
 	return &model.SignedUser{
-		ID:            u.ID.String(),
-		FirstName:     u.FirstName,
-		LastName:      u.LastName,
-		Email:         u.Email,
-		Password:      "",
-		Role:          model.Role(u.Role),
-		HasStartedDay: hasStartedDay,
-		StartedAt:     startedAt,
+		ID:                 u.ID.String(),
+		FirstName:          u.FirstName,
+		LastName:           u.LastName,
+		Email:              u.Email,
+		Password:           "",
+		Role:               model.Role(u.Role),
+		HasStartedDay:      hasStartedDay,
+		StartedAt:          startedAt,
+		WorkedMinutesToday: int32(workedMinutesToday),
 	}
 }
 
@@ -71,7 +91,7 @@ func DBUserToGraphWithAllData(u *gmodel.User) *model.UserWithAllData {
 }
 
 func GraphCreateUserInputToDB(in model.CreateUserInput) *gmodel.User {
-	return &gmodel.User{
+	u := &gmodel.User{
 		FirstName: in.FirstName,
 		LastName:  in.LastName,
 		Email:     in.Email,
@@ -79,4 +99,13 @@ func GraphCreateUserInputToDB(in model.CreateUserInput) *gmodel.User {
 		Password:  in.Password,
 		Role:      gmodel.Role(in.Role),
 	}
+	if in.TimeZone != nil {
+		u.TimeZone = *in.TimeZone
+	}
+	if in.SiteID != nil {
+		if siteID, err := uuid.Parse(*in.SiteID); err == nil {
+			u.SiteID = &siteID
+		}
+	}
+	return u
 }
@@ -0,0 +1,74 @@
+package workScheduleMapper
+
+import (
+	"sort"
+
+	"github.com/epitech/timemanager/internal/graph/model"
+	gmodel "github.com/epitech/timemanager/internal/models"
+)
+
+const layoutClock = "15:04"
+
+func DBWorkScheduleToGraph(ws *gmodel.WorkSchedule) *model.WorkSchedule {
+	if ws == nil {
+		return nil
+	}
+	out := &model.WorkSchedule{
+		ID:            ws.ID.String(),
+		EffectiveFrom: ws.EffectiveFrom,
+		Days:          make([]*model.WorkScheduleDay, 0, len(ws.Days)),
+	}
+	if ws.UserID != nil {
+		id := ws.UserID.String()
+		out.UserID = &id
+	}
+	if ws.TeamID != nil {
+		id := ws.TeamID.String()
+		out.TeamID = &id
+	}
+	if ws.EffectiveTo != "" {
+		to := ws.EffectiveTo
+		out.EffectiveTo = &to
+	}
+	days := make([]gmodel.WorkScheduleDay, len(ws.Days))
+	copy(days, ws.Days)
+	// lundi en premier, comme l'énumération GraphQL
+	sort.Slice(days, func(i, j int) bool { return (days[i].Weekday+6)%7 < (days[j].Weekday+6)%7 })
+	for _, d := range days {
+		day := &model.WorkScheduleDay{
+			Weekday: WeekdayToGraph(d.Weekday),
+			Start:   d.Start.Format(layoutClock),
+			End:     d.Ends.Format(layoutClock),
+			Remote:  d.Remote,
+		}
+		if d.CoreStart != nil {
+			core := d.CoreStart.Format(layoutClock)
+			day.CoreStart = &core
+		}
+		out.Days = append(out.Days, day)
+	}
+	return out
+}
+
+func DBWorkSchedulesToGraph(schedules []*gmodel.WorkSchedule) []*model.WorkSchedule {
+	out := make([]*model.WorkSchedule, 0, len(schedules))
+	for i := range schedules {
+		out = append(out, DBWorkScheduleToGraph(schedules[i]))
+	}
+	return out
+}
+
+// WeekdayToGraph convertit un jour stocké (0 = dimanche) en énumération GraphQL
+func WeekdayToGraph(d int) model.Weekday {
+	return model.AllWeekday[(d+6)%7]
+}
+
+// WeekdayToDB convertit l'énumération GraphQL en jour stocké (0 = dimanche)
+func WeekdayToDB(d model.Weekday) int {
+	for i, w := range model.AllWeekday {
+		if w == d {
+			return (i + 1) % 7
+		}
+	}
+	return -1
+}
@@ -17,6 +17,59 @@ const (
 
 type DAY string
 
+type CorrectionStatus string
+
+const (
+	CorrectionPending  CorrectionStatus = "PENDING"
+	CorrectionApproved CorrectionStatus = "APPROVED"
+	CorrectionRejected CorrectionStatus = "REJECTED"
+)
+
+// LeaveStatus est l'état d'une demande d'absence
+type LeaveStatus string
+
+const (
+	LeavePending   LeaveStatus = "PENDING"
+	LeaveApproved  LeaveStatus = "APPROVED"
+	LeaveRejected  LeaveStatus = "REJECTED"
+	LeaveCancelled LeaveStatus = "CANCELLED"
+)
+
+// LatenessStatus est l'état d'un retard : à justifier, justifié par
+// l'employé, puis excusé ou non par son manager
+type LatenessStatus string
+
+const (
+	LatenessPending   LatenessStatus = "PENDING"
+	LatenessJustified LatenessStatus = "JUSTIFIED"
+	LatenessExcused   LatenessStatus = "EXCUSED"
+	LatenessUnexcused LatenessStatus = "UNEXCUSED"
+)
+
+// TimesheetStatus est l'état d'une feuille de temps : brouillon, soumise par
+// l'employé (semaine verrouillée), puis validée ou refusée (semaine rouverte)
+// par son manager
+type TimesheetStatus string
+
+const (
+	TimesheetDraft     TimesheetStatus = "DRAFT"
+	TimesheetSubmitted TimesheetStatus = "SUBMITTED"
+	TimesheetApproved  TimesheetStatus = "APPROVED"
+	TimesheetRejected  TimesheetStatus = "REJECTED"
+)
+
+// PayPeriodStatus est l'état d'une période de paie : clôturée (pointages
+// verrouillés) ou rouverte par un admin
+type PayPeriodStatus string
+
+const (
+	PayPeriodClosed   PayPeriodStatus = "CLOSED"
+	PayPeriodReopened PayPeriodStatus = "REOPENED"
+)
+
+// ContractType est la nature du contrat de travail (CDI, CDD, intérim...)
+type ContractType string
+
 type User struct {
 	ID               uuid.UUID        `gorm:"primaryKey;type:uuid"`
 	FirstName        string           `gorm:"type:text"`
@@ -27,6 +80,33 @@ type User struct {
 	Role             Role             `gorm:"type:text"`
 	Teams            []*Team          `gorm:"many2many:team_users;"`
 	TimeTableEntries []TimeTableEntry `gorm:"foreignKey:UserID"`
+	// TimeZone : fuseau IANA propre à l'utilisateur, prioritaire sur celui du site
+	TimeZone string     `gorm:"type:text"`
+	SiteID   *uuid.UUID `gorm:"type:uuid;index"`
+	Site     *Site      `gorm:"foreignKey:SiteID;references:ID"`
+	// CreatedAt : date de création du compte, vide pour les comptes créés
+	// avant son ajout
+	CreatedAt *time.Time
+}
+
+// Site regroupe des utilisateurs travaillant dans un même fuseau horaire
+type Site struct {
+	ID                uuid.UUID  `gorm:"primaryKey;type:uuid"`
+	Name              string     `gorm:"type:text;uniqueIndex"`
+	TimeZone          string     `gorm:"type:text"`
+	HolidayCalendarID *uuid.UUID `gorm:"type:uuid;index"`
+}
+
+// EffectiveTimeZone renvoie le fuseau de l'utilisateur, sinon celui de son site
+// (si préchargé), sinon une chaîne vide pour le fuseau par défaut
+func (u *User) EffectiveTimeZone() string {
+	if u.TimeZone != "" {
+		return u.TimeZone
+	}
+	if u.Site != nil {
+		return u.Site.TimeZone
+	}
+	return ""
 }
 
 type Team struct {
@@ -36,6 +116,8 @@ type Team struct {
 	ManagerID   uuid.UUID `gorm:"type:uuid;index"`
 	Manager     *User     `gorm:"foreignKey:ManagerID;references:ID"`
 	Users       []*User   `gorm:"many2many:team_users;"`
+	// HolidayCalendarID : jours fériés des membres, en plus de ceux de leur site
+	HolidayCalendarID *uuid.UUID `gorm:"type:uuid;index"`
 }
 
 type TeamUser struct {
@@ -53,6 +135,51 @@ type TimeTableEntry struct {
 	Arrival   time.Time
 	Departure time.Time
 	Status    bool
+	Breaks    []Break `gorm:"foreignKey:TimeTableEntryID"`
+	// AutoClosed : sortie posée automatiquement par le job de clôture
+	AutoClosed bool `gorm:"default:false"`
+	// NeedsReview : pointage oublié laissé sans sortie, à corriger
+	NeedsReview bool `gorm:"default:false;index"`
+}
+
+// BreakType décrit une catégorie de pause, payée ou non
+type BreakType struct {
+	ID       uuid.UUID `gorm:"primaryKey;type:uuid"`
+	Name     string    `gorm:"type:text;uniqueIndex"`
+	Paid     bool
+	IsActive bool
+}
+
+// Break est une pause prise pendant une session de pointage
+type Break struct {
+	ID               uuid.UUID  `gorm:"primaryKey;type:uuid"`
+	TimeTableEntryID uuid.UUID  `gorm:"type:uuid;index"`
+	BreakTypeID      uuid.UUID  `gorm:"type:uuid;index"`
+	BreakType        *BreakType `gorm:"foreignKey:BreakTypeID;references:ID"`
+	// Paid est figé à la création : modifier le type ne change pas les
+	// heures déjà travaillées
+	Paid      bool
+	StartedAt time.Time
+	EndedAt   *time.Time
+}
+
+// TimeEntryCorrection est une demande de modification d'un pointage,
+// appliquée uniquement après validation du manager
+type TimeEntryCorrection struct {
+	ID                uuid.UUID  `gorm:"primaryKey;type:uuid"`
+	TimeTableEntryID  *uuid.UUID `gorm:"type:uuid;index"`
+	UserID            uuid.UUID  `gorm:"type:uuid;index"`
+	User              *User      `gorm:"foreignKey:UserID;references:ID"`
+	Day               string     `gorm:"type:text"`
+	ProposedArrival   time.Time
+	ProposedDeparture *time.Time
+	Reason            string           `gorm:"type:text"`
+	Status            CorrectionStatus `gorm:"type:text;index"`
+	ReviewerID        *uuid.UUID       `gorm:"type:uuid"`
+	Reviewer          *User            `gorm:"foreignKey:ReviewerID;references:ID"`
+	ReviewComment     string           `gorm:"type:text"`
+	CreatedAt         time.Time
+	ReviewedAt        *time.Time
 }
 
 type TimeTable struct {
@@ -64,6 +191,291 @@ type TimeTable struct {
 	IsActive      bool
 }
 
+// WorkSchedule est un planning hebdomadaire affecté à un utilisateur ou à une
+// équipe, valable du EffectiveFrom au EffectiveTo inclus (vide : sans fin).
+// Les jours de la semaine absents de Days sont des jours de repos.
+type WorkSchedule struct {
+	ID            uuid.UUID         `gorm:"primaryKey;type:uuid"`
+	UserID        *uuid.UUID        `gorm:"type:uuid;index"`
+	TeamID        *uuid.UUID        `gorm:"type:uuid;index"`
+	EffectiveFrom string            `gorm:"type:text;index"`
+	EffectiveTo   string            `gorm:"type:text"`
+	Days          []WorkScheduleDay `gorm:"foreignKey:WorkScheduleID"`
+	CreatedAt     time.Time
+}
+
+// WorkScheduleDay donne les horaires d'un jour de la semaine (0 = dimanche).
+// CoreStart est le début des plages fixes d'un jour en horaires variables,
+// heure d'arrivée attendue au lieu de Start ; un jour Remote est télétravaillé
+type WorkScheduleDay struct {
+	ID             uuid.UUID `gorm:"primaryKey;type:uuid"`
+	WorkScheduleID uuid.UUID `gorm:"type:uuid;index"`
+	Weekday        int
+	Start          time.Time
+	Ends           time.Time
+	CoreStart      *time.Time
+	Remote         bool `gorm:"default:false"`
+}
+
+// AbsenceType décrit une catégorie d'absence (congés payés, maladie, RTT...).
+// Les types décomptés consomment le solde de l'utilisateur, crédité chaque
+// mois de AccrualDaysPerMonth jours dans la limite de MaxBalance (0 : sans limite)
+type AbsenceType struct {
+	ID                  uuid.UUID `gorm:"primaryKey;type:uuid"`
+	Name                string    `gorm:"type:text;uniqueIndex"`
+	Paid                bool
+	DeductsBalance      bool
+	AccrualDaysPerMonth float64
+	MaxBalance          float64
+	IsActive            bool
+}
+
+// LeaveRequest est une demande d'absence du StartDate au EndDate inclus,
+// validée par le manager de l'équipe. Days est le nombre de jours ouvrés décomptés
+type LeaveRequest struct {
+	ID            uuid.UUID    `gorm:"primaryKey;type:uuid"`
+	UserID        uuid.UUID    `gorm:"type:uuid;index"`
+	User          *User        `gorm:"foreignKey:UserID;references:ID"`
+	AbsenceTypeID uuid.UUID    `gorm:"type:uuid;index"`
+	AbsenceType   *AbsenceType `gorm:"foreignKey:AbsenceTypeID;references:ID"`
+	StartDate     string       `gorm:"type:text;index"`
+	EndDate       string       `gorm:"type:text;index"`
+	HalfDay       bool
+	Days          float64
+	Reason        string      `gorm:"type:text"`
+	Status        LeaveStatus `gorm:"type:text;index"`
+	ReviewerID    *uuid.UUID  `gorm:"type:uuid"`
+	Reviewer      *User       `gorm:"foreignKey:ReviewerID;references:ID"`
+	ReviewComment string      `gorm:"type:text"`
+	CreatedAt     time.Time
+	ReviewedAt    *time.Time
+}
+
+// LeaveBalance est le solde en jours d'un utilisateur pour un type d'absence ;
+// AccruedThrough est le dernier mois (YYYY-MM) déjà crédité
+type LeaveBalance struct {
+	ID             uuid.UUID    `gorm:"primaryKey;type:uuid"`
+	UserID         uuid.UUID    `gorm:"type:uuid;uniqueIndex:idx_leave_balance_user_type"`
+	AbsenceTypeID  uuid.UUID    `gorm:"type:uuid;uniqueIndex:idx_leave_balance_user_type"`
+	AbsenceType    *AbsenceType `gorm:"foreignKey:AbsenceTypeID;references:ID"`
+	Balance        float64
+	AccruedThrough string `gorm:"type:text"`
+	UpdatedAt      time.Time
+}
+
+// HolidayCalendar regroupe les jours fériés d'un pays ou d'une région, affecté
+// à des sites ou à des équipes
+type HolidayCalendar struct {
+	ID       uuid.UUID `gorm:"primaryKey;type:uuid"`
+	Name     string    `gorm:"type:text;uniqueIndex"`
+	Holidays []Holiday `gorm:"foreignKey:HolidayCalendarID"`
+	Sites    []Site    `gorm:"foreignKey:HolidayCalendarID"`
+	Teams    []Team    `gorm:"foreignKey:HolidayCalendarID"`
+}
+
+// Holiday est un jour férié (YYYY-MM-DD), unique dans son calendrier
+type Holiday struct {
+	ID                uuid.UUID `gorm:"primaryKey;type:uuid"`
+	HolidayCalendarID uuid.UUID `gorm:"type:uuid;uniqueIndex:idx_holiday_calendar_date"`
+	Date              string    `gorm:"type:text;uniqueIndex:idx_holiday_calendar_date"`
+	Name              string    `gorm:"type:text"`
+}
+
+// EmploymentContract est un contrat de travail valable du EffectiveFrom au
+// EffectiveTo inclus (vide : sans fin). Les heures hebdomadaires sont réparties
+// également entre les jours travaillés, masque de bits dont le bit 0 est le dimanche
+type EmploymentContract struct {
+	ID            uuid.UUID    `gorm:"primaryKey;type:uuid"`
+	UserID        uuid.UUID    `gorm:"type:uuid;index"`
+	Type          ContractType `gorm:"type:text"`
+	WeeklyMinutes int
+	WorkingDays   int
+	EffectiveFrom string `gorm:"type:text;index"`
+	EffectiveTo   string `gorm:"type:text"`
+	CreatedAt     time.Time
+}
+
+// OvertimePolicy regroupe les règles de calcul des heures supplémentaires ; une
+// seule est active, les précédentes sont gardées comme historique. Un seuil vide
+// reprend le temps attendu du contrat ou du planning
+type OvertimePolicy struct {
+	ID                     uuid.UUID `gorm:"primaryKey;type:uuid"`
+	DailyEnabled           bool
+	DailyThresholdMinutes  *int
+	WeeklyEnabled          bool
+	WeeklyThresholdMinutes *int
+	DailyCapMinutes        *int
+	WeeklyCapMinutes       *int
+	Bands                  []OvertimeBand `gorm:"foreignKey:OvertimePolicyID"`
+	EffectiveFrom          time.Time
+	EffectiveTo            *time.Time
+	IsActive               bool `gorm:"index"`
+}
+
+// OvertimeBand est une tranche de majoration des heures supplémentaires de la
+// semaine, jusqu'à UpToMinutes (vide : sans limite)
+type OvertimeBand struct {
+	ID               uuid.UUID `gorm:"primaryKey;type:uuid"`
+	OvertimePolicyID uuid.UUID `gorm:"type:uuid;index"`
+	Position         int
+	UpToMinutes      *int
+	RatePercent      int
+}
+
+// ComplianceRule est une règle légale de temps de travail identifiée par son
+// code ; LimitMinutes est le repos minimal, le travail maximal ou le travail
+// au-delà duquel une pause de BreakMinutes est due, Weeks la période de la moyenne
+type ComplianceRule struct {
+	ID           uuid.UUID `gorm:"primaryKey;type:uuid"`
+	Code         string    `gorm:"type:text;uniqueIndex"`
+	Enabled      bool
+	Severity     string `gorm:"type:text"`
+	LimitMinutes int
+	BreakMinutes int
+	Weeks        int
+	UpdatedAt    time.Time
+}
+
+// DailyRollup est le cumul d'une journée d'un utilisateur pour les KPI, recalculé
+// à chaque pointage ; LateMinutes est vide quand l'arrivée n'est pas évaluée
+// (jour de repos, absence) et ActiveSince est l'arrivée de la session en cours
+type DailyRollup struct {
+	ID                    uuid.UUID `gorm:"primaryKey;type:uuid"`
+	UserID                uuid.UUID `gorm:"type:uuid;uniqueIndex:idx_daily_rollup_user_day"`
+	User                  *User     `gorm:"foreignKey:UserID;references:ID"`
+	Day                   string    `gorm:"type:text;uniqueIndex:idx_daily_rollup_user_day;index"`
+	WorkedMinutes         int
+	UnpaidBreakMinutes    int
+	OvertimeMinutes       int
+	OvertimeExcessMinutes int
+	LateMinutes           *int
+	FirstArrival          *time.Time
+	Sessions              int
+	OpenSessions          int
+	ActiveSince           *time.Time
+	UpdatedAt             time.Time
+}
+
+// RollupCoverage est une plage de jours dont les cumuls journaliers ont été
+// reconstruits puis tenus à jour ; To vide : sans fin. Rules identifie les
+// règles de calcul en vigueur lors de la reconstruction
+type RollupCoverage struct {
+	ID    uuid.UUID `gorm:"primaryKey;type:uuid"`
+	From  string    `gorm:"type:text"`
+	To    string    `gorm:"type:text"`
+	Rules string    `gorm:"type:text"`
+}
+
+// LatenessEvent est un retard relevé au pointage d'arrivée, par rapport à
+// l'heure d'arrivée prévue
+type LatenessEvent struct {
+	ID               uuid.UUID `gorm:"primaryKey;type:uuid"`
+	TimeTableEntryID uuid.UUID `gorm:"type:uuid;uniqueIndex"`
+	UserID           uuid.UUID `gorm:"type:uuid;index"`
+	User             *User     `gorm:"foreignKey:UserID;references:ID"`
+	Day              string    `gorm:"type:text;index"`
+	PlannedStart     time.Time
+	Arrival          time.Time
+	LateMinutes      int
+	Justification    string         `gorm:"type:text"`
+	Status           LatenessStatus `gorm:"type:text;index"`
+	ReviewerID       *uuid.UUID     `gorm:"type:uuid"`
+	Reviewer         *User          `gorm:"foreignKey:ReviewerID;references:ID"`
+	ReviewComment    string         `gorm:"type:text"`
+	CreatedAt        time.Time
+	ReviewedAt       *time.Time
+}
+
+// Timesheet est la feuille de temps d'un utilisateur pour la semaine
+// commençant le lundi WeekStart ; ses heures sont recalculées depuis les
+// pointages, seul son état est enregistré
+type Timesheet struct {
+	ID            uuid.UUID       `gorm:"primaryKey;type:uuid"`
+	UserID        uuid.UUID       `gorm:"type:uuid;uniqueIndex:idx_timesheet_user_week"`
+	User          *User           `gorm:"foreignKey:UserID;references:ID"`
+	WeekStart     string          `gorm:"type:text;uniqueIndex:idx_timesheet_user_week;index"`
+	Status        TimesheetStatus `gorm:"type:text;index"`
+	SubmittedAt   *time.Time
+	ReviewerID    *uuid.UUID `gorm:"type:uuid"`
+	Reviewer      *User      `gorm:"foreignKey:ReviewerID;references:ID"`
+	ReviewComment string     `gorm:"type:text"`
+	ReviewedAt    *time.Time
+	CreatedAt     time.Time
+}
+
+// PayPeriod : période de paie clôturée par un admin ; aucun pointage des jours
+// qu'elle couvre ne peut être créé, modifié ou supprimé tant qu'elle n'est pas
+// rouverte. Une réouverture garde la clôture et son motif pour l'historique
+type PayPeriod struct {
+	ID           uuid.UUID       `gorm:"primaryKey;type:uuid"`
+	StartDate    string          `gorm:"type:text;index"`
+	EndDate      string          `gorm:"type:text;index"`
+	Status       PayPeriodStatus `gorm:"type:text;index"`
+	ClosedByID   uuid.UUID       `gorm:"type:uuid"`
+	ClosedBy     *User           `gorm:"foreignKey:ClosedByID;references:ID"`
+	ClosedAt     time.Time
+	ReopenedByID *uuid.UUID `gorm:"type:uuid"`
+	ReopenedBy   *User      `gorm:"foreignKey:ReopenedByID;references:ID"`
+	ReopenReason string     `gorm:"type:text"`
+	ReopenedAt   *time.Time
+	// PayrollFrozenAt est la date à laquelle ses totaux de paie ont été figés
+	PayrollFrozenAt *time.Time
+}
+
+// PayCode associe une catégorie de paie, et pour les heures supplémentaires
+// le taux de leur tranche ou pour les absences leur type, au code attendu par
+// le logiciel de paie
+type PayCode struct {
+	ID            uuid.UUID `gorm:"primaryKey;type:uuid"`
+	Category      string    `gorm:"type:text;index"`
+	RatePercent   *int
+	AbsenceTypeID *uuid.UUID   `gorm:"type:uuid"`
+	AbsenceType   *AbsenceType `gorm:"foreignKey:AbsenceTypeID;references:ID"`
+	Code          string       `gorm:"type:text"`
+	Label         string       `gorm:"type:text"`
+}
+
+// PayrollLayout est une disposition des colonnes de l'export de paie, en CSV
+// (Delimiter) ou en largeur fixe
+type PayrollLayout struct {
+	ID        uuid.UUID `gorm:"primaryKey;type:uuid"`
+	Name      string    `gorm:"type:text;uniqueIndex"`
+	Format    string    `gorm:"type:text"`
+	Delimiter string    `gorm:"type:text"`
+	Header    bool
+	Columns   []PayrollLayoutColumn `gorm:"foreignKey:PayrollLayoutID"`
+}
+
+// PayrollLayoutColumn est une colonne d'un export de paie ; Width et Align ne
+// servent qu'en largeur fixe
+type PayrollLayoutColumn struct {
+	ID              uuid.UUID `gorm:"primaryKey;type:uuid"`
+	PayrollLayoutID uuid.UUID `gorm:"type:uuid;index"`
+	Position        int
+	Field           string `gorm:"type:text"`
+	Header          string `gorm:"type:text"`
+	Width           int
+	Align           string `gorm:"type:text"`
+}
+
+// PayrollLine est le total d'une catégorie de paie d'un employé, figé à la
+// clôture de la période pour que son export ne change plus. L'identité de
+// l'employé et le nom du type d'absence y sont recopiés
+type PayrollLine struct {
+	ID              uuid.UUID `gorm:"primaryKey;type:uuid"`
+	PayPeriodID     uuid.UUID `gorm:"type:uuid;index"`
+	UserID          uuid.UUID `gorm:"type:uuid"`
+	FirstName       string    `gorm:"type:text"`
+	LastName        string    `gorm:"type:text"`
+	Email           string    `gorm:"type:text"`
+	Category        string    `gorm:"type:text"`
+	RatePercent     int
+	AbsenceTypeID   *uuid.UUID `gorm:"type:uuid"`
+	AbsenceTypeName string     `gorm:"type:text"`
+	// Quantity compte des minutes, ou des jours pour les absences
+	Quantity float64
+}
+
 // Avant les hooks générer les UUIDs s'ils ne sont pas fournis
 func (u *User) BeforeCreate(tx *gorm.DB) (err error) {
 	if u.ID == uuid.Nil {
@@ -72,6 +484,13 @@ func (u *User) BeforeCreate(tx *gorm.DB) (err error) {
 	return
 }
 
+func (s *Site) BeforeCreate(tx *gorm.DB) (err error) {
+	if s.ID == uuid.Nil {
+		s.ID = uuid.New()
+	}
+	return
+}
+
 func (t *Team) BeforeCreate(tx *gorm.DB) (err error) {
 	if t.ID == uuid.Nil {
 		t.ID = uuid.New()
@@ -92,3 +511,164 @@ func (tt *TimeTable) BeforeCreate(tx *gorm.DB) (err error) {
 	}
 	return
 }
+
+func (bt *BreakType) BeforeCreate(tx *gorm.DB) (err error) {
+	if bt.ID == uuid.Nil {
+		bt.ID = uuid.New()
+	}
+	return
+}
+
+func (b *Break) BeforeCreate(tx *gorm.DB) (err error) {
+	if b.ID == uuid.Nil {
+		b.ID = uuid.New()
+	}
+	return
+}
+
+func (c *TimeEntryCorrection) BeforeCreate(tx *gorm.DB) (err error) {
+	if c.ID == uuid.Nil {
+		c.ID = uuid.New()
+	}
+	return
+}
+
+func (ws *WorkSchedule) BeforeCreate(tx *gorm.DB) (err error) {
+	if ws.ID == uuid.Nil {
+		ws.ID = uuid.New()
+	}
+	return
+}
+
+func (wd *WorkScheduleDay) BeforeCreate(tx *gorm.DB) (err error) {
+	if wd.ID == uuid.Nil {
+		wd.ID = uuid.New()
+	}
+	return
+}
+
+func (at *AbsenceType) BeforeCreate(tx *gorm.DB) (err error) {
+	if at.ID == uuid.Nil {
+		at.ID = uuid.New()
+	}
+	return
+}
+
+func (lr *LeaveRequest) BeforeCreate(tx *gorm.DB) (err error) {
+	if lr.ID == uuid.Nil {
+		lr.ID = uuid.New()
+	}
+	return
+}
+
+func (lb *LeaveBalance) BeforeCreate(tx *gorm.DB) (err error) {
+	if lb.ID == uuid.Nil {
+		lb.ID = uuid.New()
+	}
+	return
+}
+
+func (hc *HolidayCalendar) BeforeCreate(tx *gorm.DB) (err error) {
+	if hc.ID == uuid.Nil {
+		hc.ID = uuid.New()
+	}
+	return
+}
+
+func (h *Holiday) BeforeCreate(tx *gorm.DB) (err error) {
+	if h.ID == uuid.Nil {
+		h.ID = uuid.New()
+	}
+	return
+}
+
+func (ec *EmploymentContract) BeforeCreate(tx *gorm.DB) (err error) {
+	if ec.ID == uuid.Nil {
+		ec.ID = uuid.New()
+	}
+	return
+}
+
+func (op *OvertimePolicy) BeforeCreate(tx *gorm.DB) (err error) {
+	if op.ID == uuid.Nil {
+		op.ID = uuid.New()
+	}
+	return
+}
+
+func (ob *OvertimeBand) BeforeCreate(tx *gorm.DB) (err error) {
+	if ob.ID == uuid.Nil {
+		ob.ID = uuid.New()
+	}
+	return
+}
+
+func (cr *ComplianceRule) BeforeCreate(tx *gorm.DB) (err error) {
+	if cr.ID == uuid.Nil {
+		cr.ID = uuid.New()
+	}
+	return
+}
+
+func (dr *DailyRollup) BeforeCreate(tx *gorm.DB) (err error) {
+	if dr.ID == uuid.Nil {
+		dr.ID = uuid.New()
+	}
+	return
+}
+
+func (rc *RollupCoverage) BeforeCreate(tx *gorm.DB) (err error) {
+	if rc.ID == uuid.Nil {
+		rc.ID = uuid.New()
+	}
+	return
+}
+
+func (le *LatenessEvent) BeforeCreate(tx *gorm.DB) (err error) {
+	if le.ID == uuid.Nil {
+		le.ID = uuid.New()
+	}
+	return
+}
+
+func (ts *Timesheet) BeforeCreate(tx *gorm.DB) (err error) {
+	if ts.ID == uuid.Nil {
+		ts.ID = uuid.New()
+	}
+	return
+}
+
+func (pp *PayPeriod) BeforeCreate(tx *gorm.DB) (err error) {
+	if pp.ID == uuid.Nil {
+		pp.ID = uuid.New()
+	}
+	return
+}
+
+func (pc *PayCode) BeforeCreate(tx *gorm.DB) (err error) {
+	if pc.ID == uuid.Nil {
+		pc.ID = uuid.New()
+	}
+	return
+}
+
+func (pl *PayrollLayout) BeforeCreate(tx *gorm.DB) (err error) {
+	if pl.ID == uuid.Nil {
+		pl.ID = uuid.New()
+	}
+	return
+}
+
+func (plc *PayrollLayoutColumn) BeforeCreate(tx *gorm.DB) (err error) {
+	if plc.ID == uuid.Nil {
+		plc.ID = uuid.New()
+	}
+	return
+}
+
+func (pl *PayrollLine) BeforeCreate(tx *gorm.DB) (err error) {
+	if pl.ID == uuid.Nil {
+		pl.ID = uuid.New()
+	}
+	return
+}
@@ -0,0 +1,18 @@
+package repositories
+
+import (
+	dbmodels "github.com/epitech/timemanager/internal/models"
+	"github.com/google/uuid"
+)
+
+// IsManagerOfTeam indique si managerID est le manager de l'équipe teamID
+func (r *Repository) IsManagerOfTeam(managerID uuid.UUID, teamID uuid.UUID) (bool, error) {
+	var count int64
+	err := r.DB.Model(&dbmodels.Team{}).
+		Where("id = ? AND manager_id = ?", teamID, managerID).
+		Count(&count).Error
+	if err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
@@ -9,6 +9,7 @@ import (
 	timeTableMapper "github.com/epitech/timemanager/internal/mappers/timeTable"
 	userMapper "github.com/epitech/timemanager/internal/mappers/user"
 	dbmodels "github.com/epitech/timemanager/internal/models"
+	"github.com/epitech/timemanager/package/timezone"
 	"github.com/google/uuid"
 	"golang.org/x/crypto/bcrypt"
 )
@@ -38,6 +39,9 @@ func (r *Repository) CreateUser(input model.CreateUserInput) (*model.User, error
 		Password:  string(hashedPassword),
 		Role:      dbmodels.Role(input.Role),
 	}
+	if err := r.applyUserLocation(user, input.TimeZone, input.SiteID); err != nil {
+		return nil, err
+	}
 	if err := r.DB.Create(user).Error; err != nil {
 		return nil, err
 	}
@@ -75,6 +79,9 @@ func (r *Repository) UpdateUser(id string, input model.UpdateUserInput) (*model.
 	if input.Phone != nil {
 		existingUser.Phone = *input.Phone
 	}
+	if err := r.applyUserLocation(&existingUser, input.TimeZone, input.SiteID); err != nil {
+		return nil, err
+	}
 
 	if err := r.DB.Save(&existingUser).Error; err != nil {
 		return nil, errors.New("failed to update user")
@@ -82,6 +89,26 @@ func (r *Repository) UpdateUser(id string, input model.UpdateUserInput) (*model.
 	return userMapper.DBUserToGraph(&existingUser), nil
 }
 
+// applyUserLocation renseigne le fuseau et le site de l'utilisateur ; nil laisse
+// la valeur inchangée, une chaîne vide l'efface
+func (r *Repository) applyUserLocation(user *dbmodels.User, timeZone *string, siteID *string) error {
+	if timeZone != nil {
+		if _, err := timezone.Load(*timeZone); err != nil {
+			return err
+		}
+		user.TimeZone = *timeZone
+	}
+	if siteID != nil {
+		id, err := r.siteIDFromInput(*siteID)
+		if err != nil {
+			return err
+		}
+		user.SiteID = id
+		user.Site = nil
+	}
+	return nil
+}
+
 func (r *Repository) DeleteUser(id string) (bool, error) {
 	var existingUser dbmodels.User
 	uID, ok := uuid.Parse(id)
@@ -103,7 +130,7 @@ func (r *Repository) GetUser(id string) (*model.UserWithAllData, error) {
 	if ok != nil {
 		return nil, idParsingError
 	}
-	if err := r.DB.Preload("TimeTableEntries").
+	if err := r.DB.Preload("TimeTableEntries.Breaks.BreakType").
 		Where(whereID, uID).
 		First(&existingUser).Error; err != nil {
 		return nil, userNotFoundError
@@ -111,6 +138,19 @@ func (r *Repository) GetUser(id string) (*model.UserWithAllData, error) {
 	return userMapper.DBUserToGraphWithAllData(&existingUser), nil
 }
 
+// GetUsersWithTeams renvoie tous les utilisateurs avec leurs équipes
+func (r *Repository) GetUsersWithTeams() ([]*model.UserWithAllData, error) {
+	var users []*dbmodels.User
+	if err := r.DB.Preload("Teams").Order("last_name ASC, first_name ASC").Find(&users).Error; err != nil {
+		return nil, errors.New("can't find users")
+	}
+	out := make([]*model.UserWithAllData, 0, len(users))
+	for _, u := range users {
+		out = append(out, userMapper.DBUserToGraphWithAllData(u))
+	}
+	return out, nil
+}
+
 func (r *Repository) SetManagerTeam(userID string, teamID string) (*model.Team, error) {
 	id, ok := uuid.Parse(userID)
 	if ok != nil {
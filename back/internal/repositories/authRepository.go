@@ -7,9 +7,8 @@ import (
 	"github.com/epitech/timemanager/internal/graph/model"
 	userMapper "github.com/epitech/timemanager/internal/mappers/user"
 	models "github.com/epitech/timemanager/internal/models"
-	"github.com/google/uuid"
+	"github.com/epitech/timemanager/package/timezone"
 	"golang.org/x/crypto/bcrypt"
-	"gorm.io/gorm"
 )
 
 const emailCondition = "email = ?"
@@ -51,31 +50,42 @@ func (r *Repository) Login(email, password string) (*model.User, error) {
 
 func (r *Repository) Me(email string) (*model.SignedUser, error) {
 	var user models.User
-	if err := r.DB.Where(emailCondition, email).First(&user).Error; err != nil {
+	if err := r.DB.Preload("Site").Where(emailCondition, email).First(&user).Error; err != nil {
 		return nil, err
 	}
-	today := time.Now().Format("2006-01-02")
-	var entry models.TimeTableEntry
+	// La journée et l'heure d'arrivée s'expriment dans le fuseau de l'utilisateur
+	loc := timezone.Resolve(user.EffectiveTimeZone())
+	today := time.Now().In(loc).Format("2006-01-02")
+	var entries []models.TimeTableEntry
 
-	err := r.DB.
-		Where("user_id = ? AND DATE(day) = ?", user.ID, today).
-		Order("day DESC").
-		First(&entry).Error
+	// Une journée peut contenir plusieurs sessions de pointage, plus un poste
+	// de nuit commencé la veille et toujours ouvert
+	if err := r.DB.
+		Where("user_id = ? AND (day = ? OR status = ?)", user.ID, today, true).
+		Order("arrival ASC").
+		Find(&entries).Error; err != nil {
+		return nil, err
+	}
 
 	hasStartedDay := false
 	var startedAt *string = nil
+	workedMinutesToday := 0
+	now := time.Now()
 
-	if err != nil {
-		if !errors.Is(err, gorm.ErrRecordNotFound) {
-			return nil, err
+	for _, entry := range entries {
+		if entry.Status {
+			hasStartedDay = true
+			workedMinutesToday += int(now.Sub(entry.Arrival).Minutes())
+		} else if !entry.Departure.IsZero() {
+			workedMinutesToday += int(entry.Departure.Sub(entry.Arrival).Minutes())
 		}
-	} else if entry.ID != uuid.Nil && entry.Status {
-		hasStartedDay = true
-		formatted := entry.Arrival.Format("15:04")
+	}
+	if hasStartedDay {
+		formatted := entries[0].Arrival.In(loc).Format("15:04")
 		startedAt = &formatted
 	}
 
-	return userMapper.DBUserToSignedGraph(&user, hasStartedDay, startedAt), nil
+	return userMapper.DBUserToSignedGraph(&user, hasStartedDay, startedAt, workedMinutesToday), nil
 }
 
 func (r *Repository) UpdateProfile(email string, input model.UpdateProfileInput) (*model.User, error) {
@@ -102,6 +112,12 @@ func (r *Repository) UpdateProfile(email string, input model.UpdateProfileInput)
 	if input.Phone != nil {
 		user.Phone = *input.Phone
 	}
+	if input.TimeZone != nil {
+		if _, err := timezone.Load(*input.TimeZone); err != nil {
+			return nil, err
+		}
+		user.TimeZone = *input.TimeZone
+	}
 	if err := r.DB.Save(&user).Error; err != nil {
 		return nil, errors.New("failed to update user profile")
 	}
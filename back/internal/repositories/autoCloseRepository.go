@@ -0,0 +1,74 @@
+package repositories
+
+import (
+	"errors"
+	"time"
+
+	"github.com/epitech/timemanager/internal/graph/model"
+	timeTableMapper "github.com/epitech/timemanager/internal/mappers/timeTable"
+	timeTableEntriesMapper "github.com/epitech/timemanager/internal/mappers/timeTableEntries"
+	dbmodels "github.com/epitech/timemanager/internal/models"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// GetOpenEntriesBefore returns the sessions still open on a day strictly before the given one
+func (r *Repository) GetOpenEntriesBefore(day string) ([]*model.TimeTableEntry, error) {
+	var entries []*dbmodels.TimeTableEntry
+	if err := r.DB.Preload("User.Site").Preload("Breaks.BreakType").
+		Where("status = ? AND day < ?", true, day).
+		Order("day ASC, arrival ASC").
+		Find(&entries).Error; err != nil {
+		return nil, err
+	}
+	return timeTableEntriesMapper.DBTimeTableEntriesToGraph(entries), nil
+}
+
+// GetActiveTimeTable returns the schedule currently in force, or nil if none was set
+func (r *Repository) GetActiveTimeTable() (*model.TimeTable, error) {
+	var tt dbmodels.TimeTable
+	if err := r.DB.Where("is_active = ?", true).Order("effective_from DESC").First(&tt).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return timeTableMapper.DBTimeTableToGraph(&tt), nil
+}
+
+// AutoCloseEntry sets the departure of a forgotten session and marks it as auto-closed
+func (r *Repository) AutoCloseEntry(id string, departure time.Time) error {
+	entryID, err := uuid.Parse(id)
+	if err != nil {
+		return idParsingError
+	}
+	return r.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&dbmodels.Break{}).
+			Where("time_table_entry_id = ? AND ended_at IS NULL", entryID).
+			Update("ended_at", departure).Error; err != nil {
+			return err
+		}
+		return tx.Model(&dbmodels.TimeTableEntry{}).
+			Where("id = ? AND status = ?", entryID, true).
+			Updates(map[string]any{
+				"departure":   departure,
+				"status":      false,
+				"auto_closed": true,
+			}).Error
+	})
+}
+
+// FlagEntryForReview closes a forgotten session without departure so that it
+// stops accruing time and shows up as a missing clock-out until corrected
+func (r *Repository) FlagEntryForReview(id string) error {
+	entryID, err := uuid.Parse(id)
+	if err != nil {
+		return idParsingError
+	}
+	return r.DB.Model(&dbmodels.TimeTableEntry{}).
+		Where("id = ? AND status = ?", entryID, true).
+		Updates(map[string]any{
+			"status":       false,
+			"needs_review": true,
+		}).Error
+}
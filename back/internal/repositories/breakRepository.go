@@ -0,0 +1,60 @@
+package repositories
+
+import (
+	"errors"
+
+	"github.com/epitech/timemanager/internal/graph/model"
+	breakMapper "github.com/epitech/timemanager/internal/mappers/breaks"
+	dbmodels "github.com/epitech/timemanager/internal/models"
+	"github.com/google/uuid"
+)
+
+var breakTypeNotFoundError = errors.New("break type not found")
+
+func (r *Repository) GetBreakTypes() ([]*model.BreakType, error) {
+	var types []*dbmodels.BreakType
+	if err := r.DB.Order("name ASC").Find(&types).Error; err != nil {
+		return nil, errors.New("can't find break types")
+	}
+	return breakMapper.DBBreakTypesToGraph(types), nil
+}
+
+func (r *Repository) CreateBreakType(input model.CreateBreakTypeInput) (*model.BreakType, error) {
+	var existing dbmodels.BreakType
+	if err := r.DB.Where("name = ?", input.Name).First(&existing).Error; err == nil {
+		return nil, errors.New("break type's name is already in use")
+	}
+	breakType := &dbmodels.BreakType{
+		Name:     input.Name,
+		Paid:     input.Paid,
+		IsActive: true,
+	}
+	if err := r.DB.Create(breakType).Error; err != nil {
+		return nil, errors.New("error while creating break type")
+	}
+	return breakMapper.DBBreakTypeToGraph(breakType), nil
+}
+
+func (r *Repository) UpdateBreakType(id string, input model.UpdateBreakTypeInput) (*model.BreakType, error) {
+	typeID, err := uuid.Parse(id)
+	if err != nil {
+		return nil, idParsingError
+	}
+	var breakType dbmodels.BreakType
+	if err := r.DB.Where(whereID, typeID).First(&breakType).Error; err != nil {
+		return nil, breakTypeNotFoundError
+	}
+	if input.Name != nil {
+		breakType.Name = *input.Name
+	}
+	if input.Paid != nil {
+		breakType.Paid = *input.Paid
+	}
+	if input.IsActive != nil {
+		breakType.IsActive = *input.IsActive
+	}
+	if err := r.DB.Save(&breakType).Error; err != nil {
+		return nil, errors.New("error while updating break type")
+	}
+	return breakMapper.DBBreakTypeToGraph(&breakType), nil
+}
@@ -0,0 +1,30 @@
+package repositories
+
+import (
+	"errors"
+
+	"github.com/epitech/timemanager/internal/graph/model"
+	complianceMapper "github.com/epitech/timemanager/internal/mappers/compliance"
+	dbmodels "github.com/epitech/timemanager/internal/models"
+	"gorm.io/gorm/clause"
+)
+
+func (r *Repository) GetComplianceRules() ([]*model.ComplianceRule, error) {
+	var rules []*dbmodels.ComplianceRule
+	if err := r.DB.Order("code ASC").Find(&rules).Error; err != nil {
+		return nil, errors.New("can't find compliance rules")
+	}
+	return complianceMapper.DBComplianceRulesToGraph(rules), nil
+}
+
+// SaveComplianceRule enregistre la règle, créée si son code n'existe pas encore
+func (r *Repository) SaveComplianceRule(rule *model.ComplianceRule) (*model.ComplianceRule, error) {
+	row := complianceMapper.GraphComplianceRuleToDB(rule)
+	if err := r.DB.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "code"}},
+		DoUpdates: clause.AssignmentColumns([]string{"enabled", "severity", "limit_minutes", "break_minutes", "weeks", "updated_at"}),
+	}).Create(row).Error; err != nil {
+		return nil, errors.New("error while saving compliance rule")
+	}
+	return complianceMapper.DBComplianceRuleToGraph(row), nil
+}
@@ -0,0 +1,110 @@
+package repositories
+
+import (
+	"errors"
+	"math"
+
+	"github.com/epitech/timemanager/internal/graph/model"
+	contractMapper "github.com/epitech/timemanager/internal/mappers/contract"
+	dbmodels "github.com/epitech/timemanager/internal/models"
+	"github.com/google/uuid"
+)
+
+var contractNotFoundError = errors.New("contract not found")
+
+func (r *Repository) GetContracts(userID *uuid.UUID) ([]*model.EmploymentContract, error) {
+	var contracts []*dbmodels.EmploymentContract
+	dbq := r.DB.Model(&dbmodels.EmploymentContract{})
+	if userID != nil {
+		dbq = dbq.Where("user_id = ?", *userID)
+	}
+	if err := dbq.Order("effective_from DESC").Find(&contracts).Error; err != nil {
+		return nil, errors.New("can't find contracts")
+	}
+	return contractMapper.DBContractsToGraph(contracts), nil
+}
+
+// GetUsersContracts renvoie les contrats de plusieurs utilisateurs en une requête
+func (r *Repository) GetUsersContracts(userIDs []uuid.UUID) ([]*model.EmploymentContract, error) {
+	if len(userIDs) == 0 {
+		return []*model.EmploymentContract{}, nil
+	}
+	var contracts []*dbmodels.EmploymentContract
+	if err := r.DB.Where("user_id IN ?", userIDs).Order("effective_from DESC").Find(&contracts).Error; err != nil {
+		return nil, errors.New("can't find contracts")
+	}
+	return contractMapper.DBContractsToGraph(contracts), nil
+}
+
+func (r *Repository) GetContract(id string) (*model.EmploymentContract, error) {
+	contractID, err := uuid.Parse(id)
+	if err != nil {
+		return nil, idParsingError
+	}
+	var contract dbmodels.EmploymentContract
+	if err := r.DB.Where(whereID, contractID).First(&contract).Error; err != nil {
+		return nil, contractNotFoundError
+	}
+	return contractMapper.DBContractToGraph(&contract), nil
+}
+
+func (r *Repository) CreateContract(input model.ContractInput) (*model.EmploymentContract, error) {
+	userID, err := uuid.Parse(input.UserID)
+	if err != nil {
+		return nil, idParsingError
+	}
+	if err := r.DB.Where(whereID, userID).First(&dbmodels.User{}).Error; err != nil {
+		return nil, userNotFoundError
+	}
+	contract := &dbmodels.EmploymentContract{UserID: userID}
+	applyContractInput(contract, input)
+	if err := r.DB.Create(contract).Error; err != nil {
+		return nil, errors.New("failed to create contract")
+	}
+	return contractMapper.DBContractToGraph(contract), nil
+}
+
+func (r *Repository) UpdateContract(id string, input model.ContractInput) (*model.EmploymentContract, error) {
+	contractID, err := uuid.Parse(id)
+	if err != nil {
+		return nil, idParsingError
+	}
+	var contract dbmodels.EmploymentContract
+	if err := r.DB.Where(whereID, contractID).First(&contract).Error; err != nil {
+		return nil, contractNotFoundError
+	}
+	if contract.UserID.String() != input.UserID {
+		return nil, errors.New("a contract can't be moved to another user")
+	}
+	applyContractInput(&contract, input)
+	if err := r.DB.Save(&contract).Error; err != nil {
+		return nil, errors.New("failed to update contract")
+	}
+	return contractMapper.DBContractToGraph(&contract), nil
+}
+
+func (r *Repository) DeleteContract(id string) (bool, error) {
+	contractID, err := uuid.Parse(id)
+	if err != nil {
+		return false, idParsingError
+	}
+	result := r.DB.Where(whereID, contractID).Delete(&dbmodels.EmploymentContract{})
+	if result.Error != nil {
+		return false, errors.New("failed to delete contract")
+	}
+	if result.RowsAffected == 0 {
+		return false, contractNotFoundError
+	}
+	return true, nil
+}
+
+func applyContractInput(contract *dbmodels.EmploymentContract, input model.ContractInput) {
+	contract.Type = dbmodels.ContractType(input.Type)
+	contract.WeeklyMinutes = int(math.Round(input.WeeklyHours * 60))
+	contract.WorkingDays = contractMapper.WorkingDaysToDB(input.WorkingDays)
+	contract.EffectiveFrom = input.EffectiveFrom
+	contract.EffectiveTo = ""
+	if input.EffectiveTo != nil {
+		contract.EffectiveTo = *input.EffectiveTo
+	}
+}
@@ -0,0 +1,247 @@
+package repositories
+
+import (
+	"errors"
+
+	"github.com/epitech/timemanager/internal/graph/model"
+	holidayMapper "github.com/epitech/timemanager/internal/mappers/holiday"
+	dbmodels "github.com/epitech/timemanager/internal/models"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var holidayCalendarNotFoundError = errors.New("holiday calendar not found")
+
+func preloadHolidayCalendar(db *gorm.DB) *gorm.DB {
+	return db.Preload("Holidays", func(db *gorm.DB) *gorm.DB {
+		return db.Order("date ASC")
+	}).Preload("Sites").Preload("Teams")
+}
+
+func (r *Repository) GetHolidayCalendars() ([]*model.HolidayCalendar, error) {
+	var calendars []*dbmodels.HolidayCalendar
+	if err := preloadHolidayCalendar(r.DB).Order("name ASC").Find(&calendars).Error; err != nil {
+		return nil, errors.New("can't find holiday calendars")
+	}
+	return holidayMapper.DBHolidayCalendarsToGraph(calendars), nil
+}
+
+func (r *Repository) GetHolidayCalendar(id string) (*model.HolidayCalendar, error) {
+	calendarID, err := uuid.Parse(id)
+	if err != nil {
+		return nil, idParsingError
+	}
+	var calendar dbmodels.HolidayCalendar
+	if err := preloadHolidayCalendar(r.DB).Where(whereID, calendarID).First(&calendar).Error; err != nil {
+		return nil, holidayCalendarNotFoundError
+	}
+	return holidayMapper.DBHolidayCalendarToGraph(&calendar), nil
+}
+
+func (r *Repository) CreateHolidayCalendar(name string) (*model.HolidayCalendar, error) {
+	var existing dbmodels.HolidayCalendar
+	if err := r.DB.Where("name = ?", name).First(&existing).Error; err == nil {
+		return nil, errors.New("holiday calendar's name is already in use")
+	}
+	calendar := &dbmodels.HolidayCalendar{Name: name}
+	if err := r.DB.Create(calendar).Error; err != nil {
+		return nil, errors.New("error while creating holiday calendar")
+	}
+	return holidayMapper.DBHolidayCalendarToGraph(calendar), nil
+}
+
+// DeleteHolidayCalendar supprime le calendrier, ses jours fériés et ses affectations
+func (r *Repository) DeleteHolidayCalendar(id string) (bool, error) {
+	calendarID, err := uuid.Parse(id)
+	if err != nil {
+		return false, idParsingError
+	}
+	if err := r.DB.Where(whereID, calendarID).First(&dbmodels.HolidayCalendar{}).Error; err != nil {
+		return false, holidayCalendarNotFoundError
+	}
+	if err := r.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&dbmodels.Site{}).Where("holiday_calendar_id = ?", calendarID).
+			Update("holiday_calendar_id", nil).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&dbmodels.Team{}).Where("holiday_calendar_id = ?", calendarID).
+			Update("holiday_calendar_id", nil).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("holiday_calendar_id = ?", calendarID).Delete(&dbmodels.Holiday{}).Error; err != nil {
+			return err
+		}
+		return tx.Where(whereID, calendarID).Delete(&dbmodels.HolidayCalendar{}).Error
+	}); err != nil {
+		return false, errors.New("failed to delete holiday calendar")
+	}
+	return true, nil
+}
+
+// SaveHolidays ajoute les jours fériés au calendrier ; le nom d'un jour déjà
+// présent est remplacé
+func (r *Repository) SaveHolidays(calendarID string, holidays []*model.HolidayInput) (*model.HolidayCalendar, error) {
+	id, err := uuid.Parse(calendarID)
+	if err != nil {
+		return nil, idParsingError
+	}
+	if err := r.DB.Where(whereID, id).First(&dbmodels.HolidayCalendar{}).Error; err != nil {
+		return nil, holidayCalendarNotFoundError
+	}
+	rows := make([]dbmodels.Holiday, 0, len(holidays))
+	for _, h := range holidays {
+		rows = append(rows, dbmodels.Holiday{HolidayCalendarID: id, Date: h.Date, Name: h.Name})
+	}
+	if len(rows) > 0 {
+		if err := r.DB.Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "holiday_calendar_id"}, {Name: "date"}},
+			DoUpdates: clause.AssignmentColumns([]string{"name"}),
+		}).Create(&rows).Error; err != nil {
+			return nil, errors.New("error while saving holidays")
+		}
+	}
+	return r.GetHolidayCalendar(calendarID)
+}
+
+func (r *Repository) RemoveHoliday(calendarID string, date string) (*model.HolidayCalendar, error) {
+	id, err := uuid.Parse(calendarID)
+	if err != nil {
+		return nil, idParsingError
+	}
+	result := r.DB.Where("holiday_calendar_id = ? AND date = ?", id, date).Delete(&dbmodels.Holiday{})
+	if result.Error != nil {
+		return nil, errors.New("error while removing holiday")
+	}
+	if result.RowsAffected == 0 {
+		return nil, errors.New("holiday not found")
+	}
+	return r.GetHolidayCalendar(calendarID)
+}
+
+// SetHolidayCalendar affecte le calendrier (ou aucun si calendarID est nil) au
+// site ou à l'équipe
+func (r *Repository) SetHolidayCalendar(calendarID *uuid.UUID, siteID *uuid.UUID, teamID *uuid.UUID) (bool, error) {
+	if calendarID != nil {
+		if err := r.DB.Where(whereID, *calendarID).First(&dbmodels.HolidayCalendar{}).Error; err != nil {
+			return false, holidayCalendarNotFoundError
+		}
+	}
+	if siteID != nil {
+		result := r.DB.Model(&dbmodels.Site{}).Where(whereID, *siteID).Update("holiday_calendar_id", calendarID)
+		if result.Error != nil {
+			return false, errors.New("failed to set the site's holiday calendar")
+		}
+		if result.RowsAffected == 0 {
+			return false, siteNotFoundError
+		}
+	}
+	if teamID != nil {
+		result := r.DB.Model(&dbmodels.Team{}).Where(whereID, *teamID).Update("holiday_calendar_id", calendarID)
+		if result.Error != nil {
+			return false, errors.New("failed to set the team's holiday calendar")
+		}
+		if result.RowsAffected == 0 {
+			return false, teamNotFoundError
+		}
+	}
+	return true, nil
+}
+
+// GetUserHolidays renvoie les jours fériés du site de l'utilisateur et de ses
+// équipes entre from et to inclus (bornes optionnelles si vides)
+func (r *Repository) GetUserHolidays(userID uuid.UUID, from, to string) ([]*model.Holiday, error) {
+	siteCalendars := r.DB.Table("users").
+		Select("sites.holiday_calendar_id").
+		Joins("JOIN sites ON sites.id = users.site_id").
+		Where("users.id = ?", userID)
+	teamCalendars := r.DB.Table("team_users").
+		Select("teams.holiday_calendar_id").
+		Joins("JOIN teams ON teams.id = team_users.team_id").
+		Where("team_users.user_id = ?", userID)
+	dbq := r.DB.Where("holiday_calendar_id IN (?) OR holiday_calendar_id IN (?)", siteCalendars, teamCalendars)
+	if from != "" {
+		dbq = dbq.Where("date >= ?", from)
+	}
+	if to != "" {
+		dbq = dbq.Where("date <= ?", to)
+	}
+	var holidays []*dbmodels.Holiday
+	if err := dbq.Order("date ASC").Find(&holidays).Error; err != nil {
+		return nil, errors.New("can't find holidays")
+	}
+	return holidayMapper.DBHolidaysToGraph(holidays), nil
+}
+
+// GetUsersHolidays renvoie, par utilisateur, les jours fériés de son site et
+// de ses équipes entre from et to, pour tous les utilisateurs à la fois. Un
+// calendrier partagé par le site et une équipe ne compte qu'une fois, et un
+// jour férié de plusieurs calendriers n'est renvoyé qu'une fois
+func (r *Repository) GetUsersHolidays(userIDs []uuid.UUID, from, to string) (map[string][]*model.Holiday, error) {
+	out := make(map[string][]*model.Holiday, len(userIDs))
+	if len(userIDs) == 0 {
+		return out, nil
+	}
+	type userCalendar struct {
+		UserID     uuid.UUID
+		CalendarID uuid.UUID
+	}
+	var siteCalendars, teamCalendars []userCalendar
+	if err := r.DB.Table("users").
+		Select("users.id AS user_id, sites.holiday_calendar_id AS calendar_id").
+		Joins("JOIN sites ON sites.id = users.site_id").
+		Where("users.id IN ? AND sites.holiday_calendar_id IS NOT NULL", userIDs).
+		Scan(&siteCalendars).Error; err != nil {
+		return nil, errors.New("can't find holidays")
+	}
+	if err := r.DB.Table("team_users").
+		Select("team_users.user_id AS user_id, teams.holiday_calendar_id AS calendar_id").
+		Joins("JOIN teams ON teams.id = team_users.team_id").
+		Where("team_users.user_id IN ? AND teams.holiday_calendar_id IS NOT NULL", userIDs).
+		Scan(&teamCalendars).Error; err != nil {
+		return nil, errors.New("can't find holidays")
+	}
+	users := make(map[uuid.UUID][]string)
+	calendarIDs := make([]uuid.UUID, 0)
+	seen := make(map[userCalendar]struct{})
+	for _, uc := range append(siteCalendars, teamCalendars...) {
+		if _, ok := seen[uc]; ok {
+			continue
+		}
+		seen[uc] = struct{}{}
+		if _, ok := users[uc.CalendarID]; !ok {
+			calendarIDs = append(calendarIDs, uc.CalendarID)
+		}
+		users[uc.CalendarID] = append(users[uc.CalendarID], uc.UserID.String())
+	}
+	for _, uid := range userIDs {
+		out[uid.String()] = make([]*model.Holiday, 0)
+	}
+	if len(calendarIDs) == 0 {
+		return out, nil
+	}
+	dbq := r.DB.Where("holiday_calendar_id IN ?", calendarIDs)
+	if from != "" {
+		dbq = dbq.Where("date >= ?", from)
+	}
+	if to != "" {
+		dbq = dbq.Where("date <= ?", to)
+	}
+	var holidays []*dbmodels.Holiday
+	if err := dbq.Order("date ASC").Find(&holidays).Error; err != nil {
+		return nil, errors.New("can't find holidays")
+	}
+	type userDate struct{ userID, date string }
+	days := make(map[userDate]struct{})
+	for _, h := range holidays {
+		holiday := holidayMapper.DBHolidayToGraph(h)
+		for _, userID := range users[h.HolidayCalendarID] {
+			if _, ok := days[userDate{userID, h.Date}]; ok {
+				continue
+			}
+			days[userDate{userID, h.Date}] = struct{}{}
+			out[userID] = append(out[userID], holiday)
+		}
+	}
+	return out, nil
+}
@@ -0,0 +1,47 @@
+package repositories
+
+import (
+	"testing"
+
+	dbmodels "github.com/epitech/timemanager/internal/models"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+func TestGetUsersHolidaysOnceADay(t *testing.T) {
+	withKpiDB(t, func(tx *gorm.DB) {
+		require.NoError(t, tx.AutoMigrate(&dbmodels.HolidayCalendar{}, &dbmodels.Holiday{}))
+		u1, u2, team := seedKpis(t, tx)
+		national := &dbmodels.HolidayCalendar{Name: "France"}
+		local := &dbmodels.HolidayCalendar{Name: "Alsace"}
+		require.NoError(t, tx.Create([]*dbmodels.HolidayCalendar{national, local}).Error)
+		require.NoError(t, tx.Create([]*dbmodels.Holiday{
+			{HolidayCalendarID: national.ID, Date: "2024-01-01", Name: "Jour de l'an"},
+			{HolidayCalendarID: local.ID, Date: "2024-01-01", Name: "Jour de l'an"},
+			{HolidayCalendarID: local.ID, Date: "2024-12-26", Name: "Saint-Étienne"},
+		}).Error)
+		// the site and the team share the national calendar
+		site := &dbmodels.Site{Name: "Paris", HolidayCalendarID: &national.ID}
+		require.NoError(t, tx.Create(site).Error)
+		require.NoError(t, tx.Model(&dbmodels.User{}).Where("id IN ?", []uuid.UUID{u1, u2}).Update("site_id", site.ID).Error)
+		require.NoError(t, tx.Model(&dbmodels.Team{}).Where("id = ?", team).Update("holiday_calendar_id", national.ID).Error)
+
+		r := NewRepository(tx)
+		got, err := r.GetUsersHolidays([]uuid.UUID{u1, u2}, "2024-01-01", "2024-12-31")
+		require.NoError(t, err)
+		assert.Len(t, got[u1.String()], 1)
+		assert.Len(t, got[u2.String()], 1)
+
+		// a day of two calendars is returned once
+		require.NoError(t, tx.Model(&dbmodels.Team{}).Where("id = ?", team).Update("holiday_calendar_id", local.ID).Error)
+		got, err = r.GetUsersHolidays([]uuid.UUID{u1}, "2024-01-01", "2024-12-31")
+		require.NoError(t, err)
+		dates := make([]string, 0)
+		for _, h := range got[u1.String()] {
+			dates = append(dates, h.Date)
+		}
+		assert.Equal(t, []string{"2024-01-01", "2024-12-26"}, dates)
+	})
+}
@@ -0,0 +1,200 @@
+package repositories
+
+import (
+	"errors"
+	"time"
+
+	"github.com/epitech/timemanager/internal/graph/model"
+	dbmodels "github.com/epitech/timemanager/internal/models"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// liveMinutesSQL est le travail d'une journée jusqu'à maintenant : une session
+// en cours a continué depuis le calcul du cumul
+const liveMinutesSQL = `worked_minutes + CASE WHEN active_since IS NOT NULL AND @now > updated_at
+	THEN FLOOR(EXTRACT(EPOCH FROM (@now - updated_at)) / 60)::int ELSE 0 END`
+
+// rollupScope filtre les cumuls entre deux jours inclus, d'un utilisateur ou
+// des membres d'une équipe si précisés
+func (r *Repository) rollupScope(userID *uuid.UUID, teamID *uuid.UUID, from, to string) *gorm.DB {
+	dbq := r.DB.Model(&dbmodels.DailyRollup{}).Where("day >= ? AND day <= ?", from, to)
+	if userID != nil {
+		dbq = dbq.Where("user_id = ?", *userID)
+	}
+	if teamID != nil {
+		sub := r.DB.Table("team_users").Select("user_id").Where("team_id = ?", *teamID)
+		dbq = dbq.Where("user_id IN (?)", sub)
+	}
+	return dbq
+}
+
+// workedRollups renvoie les journées travaillées avec leur travail à jour (live)
+func (r *Repository) workedRollups(userID *uuid.UUID, teamID *uuid.UUID, from, to string, now time.Time) *gorm.DB {
+	sub := r.rollupScope(userID, teamID, from, to).
+		Select("user_id, day, overtime_minutes, "+liveMinutesSQL+" AS live", map[string]any{"now": now})
+	return r.DB.Table("(?) AS w", sub).Where("live > 0")
+}
+
+// truncUnit est l'unité de date_trunc d'une granularité ; les cumuls étant
+// journaliers, une granularité horaire s'arrête au jour
+func truncUnit(granularity model.KpiGranularity) string {
+	switch granularity {
+	case model.KpiGranularityWeek:
+		return "week"
+	case model.KpiGranularityMonth:
+		return "month"
+	case model.KpiGranularityQuarter:
+		return "quarter"
+	}
+	return "day"
+}
+
+// periodStartSQL est le premier jour de la période d'un cumul ; date_trunc fait
+// commencer les semaines le lundi (ISO)
+const periodStartSQL = "TO_CHAR(date_trunc(@unit, day::timestamp), 'YYYY-MM-DD')"
+
+// GetKpiPeriodTotals somme le travail et les heures supplémentaires de chaque
+// période
+func (r *Repository) GetKpiPeriodTotals(userID *uuid.UUID, teamID *uuid.UUID, from, to string, granularity model.KpiGranularity, now time.Time) ([]*model.KpiPeriodTotal, error) {
+	var rows []*model.KpiPeriodTotal
+	if err := r.workedRollups(userID, teamID, from, to, now).
+		Select(periodStartSQL+` AS period_start, SUM(live) AS minutes, COUNT(DISTINCT user_id) AS users,
+			SUM(overtime_minutes) AS overtime_minutes,
+			COUNT(DISTINCT user_id) FILTER (WHERE overtime_minutes > 0) AS overtime_users`,
+			map[string]any{"unit": truncUnit(granularity)}).
+		Group("period_start").Order("period_start").Scan(&rows).Error; err != nil {
+		return nil, errors.New("can't sum worked minutes per period")
+	}
+	return rows, nil
+}
+
+// GetKpiUserPeriodTotals somme le travail de chaque utilisateur par période
+func (r *Repository) GetKpiUserPeriodTotals(userID *uuid.UUID, teamID *uuid.UUID, from, to string, granularity model.KpiGranularity, now time.Time) ([]*model.KpiUserPeriodTotal, error) {
+	var rows []*model.KpiUserPeriodTotal
+	if err := r.workedRollups(userID, teamID, from, to, now).
+		Select("user_id, "+periodStartSQL+" AS period_start, SUM(live) AS minutes",
+			map[string]any{"unit": truncUnit(granularity)}).
+		Group("user_id, period_start").Order("user_id, period_start").Scan(&rows).Error; err != nil {
+		return nil, errors.New("can't sum worked minutes per user and period")
+	}
+	return rows, nil
+}
+
+// GetKpiWeekdayTotals somme le travail par jour de la semaine
+func (r *Repository) GetKpiWeekdayTotals(userID *uuid.UUID, teamID *uuid.UUID, from, to string, now time.Time) ([]*model.KpiWeekdayTotal, error) {
+	var rows []*model.KpiWeekdayTotal
+	if err := r.workedRollups(userID, teamID, from, to, now).
+		Select("EXTRACT(DOW FROM day::date)::int AS weekday, SUM(live) AS minutes, COUNT(DISTINCT day) AS days").
+		Group("weekday").Order("weekday").Scan(&rows).Error; err != nil {
+		return nil, errors.New("can't sum worked minutes per weekday")
+	}
+	return rows, nil
+}
+
+// GetKpiUserTotals somme le travail, les heures supplémentaires, les arrivées
+// évaluées et les sessions de chaque utilisateur
+func (r *Repository) GetKpiUserTotals(userID *uuid.UUID, teamID *uuid.UUID, from, to string, now time.Time) ([]*model.KpiUserTotal, error) {
+	var rows []*model.KpiUserTotal
+	sub := r.rollupScope(userID, teamID, from, to).
+		Select("*, "+liveMinutesSQL+" AS live", map[string]any{"now": now})
+	if err := r.DB.Table("(?) AS w", sub).
+		Select(`user_id,
+			SUM(GREATEST(live, 0)) AS minutes,
+			COUNT(*) FILTER (WHERE live > 0) AS days,
+			SUM(overtime_minutes) AS overtime_minutes,
+			COUNT(*) FILTER (WHERE late_minutes = 0) AS on_time_days,
+			COUNT(*) FILTER (WHERE late_minutes > 0) AS late_days,
+			COALESCE(SUM(late_minutes), 0) AS late_minutes,
+			SUM(sessions) AS sessions,
+			SUM(open_sessions) AS open_sessions,
+			BOOL_OR(active_since IS NOT NULL) AS active`).
+		Group("user_id").Order("user_id").Scan(&rows).Error; err != nil {
+		return nil, errors.New("can't sum worked minutes per user")
+	}
+	return rows, nil
+}
+
+// GetKpiOvertimeWeeks somme par utilisateur et par semaine les heures
+// supplémentaires des jours from à to, déjà réglées par la politique, et celles
+// des jours de la semaine avant from ; weekStart est le lundi de la semaine de
+// from
+func (r *Repository) GetKpiOvertimeWeeks(userID *uuid.UUID, teamID *uuid.UUID, weekStart, from, to string) ([]*model.KpiOvertimeWeek, error) {
+	var rows []*model.KpiOvertimeWeek
+	if err := r.rollupScope(userID, teamID, weekStart, to).
+		Where("(overtime_minutes > 0 OR overtime_excess_minutes > 0)").
+		Select(`user_id, TO_CHAR(date_trunc('week', day::timestamp), 'YYYY-MM-DD') AS week_start,
+			COALESCE(SUM(overtime_minutes) FILTER (WHERE day < @from), 0) AS before_minutes,
+			COALESCE(SUM(overtime_minutes) FILTER (WHERE day >= @from), 0) AS minutes,
+			COALESCE(SUM(overtime_excess_minutes) FILTER (WHERE day >= @from), 0) AS excess_minutes,
+			COUNT(*) FILTER (WHERE day >= @from AND overtime_minutes > 0) AS days`, map[string]any{"from": from}).
+		Group("user_id, week_start").Order("user_id, week_start").Scan(&rows).Error; err != nil {
+		return nil, errors.New("can't sum overtime per week")
+	}
+	return rows, nil
+}
+
+// GetKpiPunctualityPeriods compte les arrivées à l'heure et en retard par période
+func (r *Repository) GetKpiPunctualityPeriods(userID *uuid.UUID, teamID *uuid.UUID, from, to string, granularity model.KpiGranularity) ([]*model.KpiPunctualityPeriod, error) {
+	var rows []*model.KpiPunctualityPeriod
+	if err := r.rollupScope(userID, teamID, from, to).
+		Where("late_minutes IS NOT NULL").
+		Select(periodStartSQL+` AS period_start,
+			COUNT(*) FILTER (WHERE late_minutes = 0) AS on_time,
+			COUNT(*) FILTER (WHERE late_minutes > 0) AS late,
+			SUM(late_minutes) AS late_minutes`, map[string]any{"unit": truncUnit(granularity)}).
+		Group("period_start").Order("period_start").Scan(&rows).Error; err != nil {
+		return nil, errors.New("can't count arrivals per period")
+	}
+	return rows, nil
+}
+
+// GetKpiCoverage compte les utilisateurs présents à chaque heure de la fenêtre,
+// à partir des sessions des jours from à to. start est le début d'une heure
+// locale : les heures suivantes s'en déduisent par pas d'une heure, ce qui garde
+// les journées de changement d'heure à 23 ou 25 heures
+func (r *Repository) GetKpiCoverage(teamID *uuid.UUID, from, to string, start, end, now time.Time) ([]*model.CoveragePoint, error) {
+	type hour struct {
+		Time  time.Time
+		Count int32
+	}
+	var rows []hour
+	dbq := r.DB.Table("generate_series(?::timestamptz, ?::timestamptz, interval '1 hour') AS h(hour)", start, end).
+		Joins(`JOIN time_table_entries e ON e.arrival < h.hour + interval '1 hour'
+			AND h.hour <= CASE WHEN e.departure > e.arrival THEN e.departure WHEN e.status THEN ?::timestamptz END`, now).
+		Where("e.day >= ? AND e.day <= ?", from, to)
+	if teamID != nil {
+		sub := r.DB.Table("team_users").Select("user_id").Where("team_id = ?", *teamID)
+		dbq = dbq.Where("e.user_id IN (?)", sub)
+	}
+	if err := dbq.Select("h.hour AS time, COUNT(DISTINCT e.user_id) AS count").
+		Group("h.hour").Order("h.hour").Scan(&rows).Error; err != nil {
+		return nil, errors.New("can't compute coverage")
+	}
+	out := make([]*model.CoveragePoint, 0, len(rows))
+	for _, h := range rows {
+		out = append(out, &model.CoveragePoint{Time: h.Time, Count: h.Count})
+	}
+	return out, nil
+}
+
+// GetUserStarts renvoie, pour chaque utilisateur, le premier jour où il est
+// attendu au travail : le début de son premier contrat, à défaut le plus tôt
+// de sa date de création et de son premier pointage, vide sans aucun des trois
+func (r *Repository) GetUserStarts() (map[string]string, error) {
+	var rows []struct {
+		ID    uuid.UUID
+		Start string
+	}
+	if err := r.DB.Table("users").Select(`users.id AS id, COALESCE(
+		(SELECT MIN(c.effective_from) FROM employment_contracts c WHERE c.user_id = users.id),
+		LEAST(TO_CHAR(users.created_at, 'YYYY-MM-DD'), (SELECT MIN(e.day) FROM time_table_entries e WHERE e.user_id = users.id)),
+		'') AS start`).Scan(&rows).Error; err != nil {
+		return nil, errors.New("can't find the users' starts")
+	}
+	out := make(map[string]string, len(rows))
+	for _, row := range rows {
+		out[row.ID.String()] = row.Start
+	}
+	return out, nil
+}
@@ -0,0 +1,184 @@
+package repositories
+
+import (
+	"errors"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/epitech/timemanager/internal/graph/model"
+	dbmodels "github.com/epitech/timemanager/internal/models"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// errRollback annule la transaction d'un test une fois ses vérifications faites
+var errRollback = errors.New("rollback")
+
+// withKpiDB exécute f dans une transaction annulée à la fin, sur un schéma
+// vide migré pour les KPI. Les requêtes agrégées ne tournent que sous
+// Postgres : sans TEST_DATABASE_DSN, le test est ignoré
+func withKpiDB(t *testing.T, f func(tx *gorm.DB)) {
+	dsn := os.Getenv("TEST_DATABASE_DSN")
+	if dsn == "" {
+		t.Skip("TEST_DATABASE_DSN not set")
+	}
+	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	require.NoError(t, err)
+	schema := "kpi_test_" + uuid.NewString()[:8]
+	err = db.Transaction(func(tx *gorm.DB) error {
+		require.NoError(t, tx.Exec("CREATE SCHEMA "+schema).Error)
+		require.NoError(t, tx.Exec("SET LOCAL search_path TO "+schema).Error)
+		require.NoError(t, tx.AutoMigrate(&dbmodels.Site{}, &dbmodels.User{}, &dbmodels.Team{}))
+		require.NoError(t, tx.AutoMigrate(&dbmodels.TeamUser{}, &dbmodels.TimeTableEntry{}, &dbmodels.BreakType{}, &dbmodels.Break{}, &dbmodels.DailyRollup{}))
+		f(tx)
+		return errRollback
+	})
+	require.ErrorIs(t, err, errRollback)
+}
+
+// seedKpis crée deux utilisateurs, dont seul le premier est dans l'équipe
+// renvoyée, et leurs cumuls des 8 au 17 janvier 2024
+func seedKpis(t *testing.T, tx *gorm.DB) (u1, u2, team uuid.UUID) {
+	users := []*dbmodels.User{{FirstName: "Ada", Email: "ada@test"}, {FirstName: "Bob", Email: "bob@test"}}
+	require.NoError(t, tx.Create(&users).Error)
+	u1, u2 = users[0].ID, users[1].ID
+	ops := &dbmodels.Team{Name: "Ops", ManagerID: u1}
+	require.NoError(t, tx.Create(ops).Error)
+	require.NoError(t, tx.Create(&dbmodels.TeamUser{UserID: u1, TeamID: ops.ID}).Error)
+
+	late, onTime := 10, 0
+	rollups := []*dbmodels.DailyRollup{
+		{UserID: u1, Day: "2024-01-08", WorkedMinutes: 480, OvertimeMinutes: 60, LateMinutes: &late, Sessions: 1},
+		{UserID: u1, Day: "2024-01-09", WorkedMinutes: 420, OvertimeMinutes: 20, LateMinutes: &onTime, Sessions: 2, OpenSessions: 1},
+		{UserID: u1, Day: "2024-01-17", WorkedMinutes: 300, Sessions: 1},
+		{UserID: u2, Day: "2024-01-09", WorkedMinutes: 240, OvertimeMinutes: 30, OvertimeExcessMinutes: 15, Sessions: 1},
+		{UserID: u2, Day: "2024-01-10", WorkedMinutes: 0},
+	}
+	require.NoError(t, tx.Create(&rollups).Error)
+	return u1, u2, ops.ID
+}
+
+func TestKpiPeriodTotalsStartWeeksOnMonday(t *testing.T) {
+	withKpiDB(t, func(tx *gorm.DB) {
+		seedKpis(t, tx)
+		r := NewRepository(tx)
+		now := time.Date(2024, 1, 18, 12, 0, 0, 0, time.UTC)
+
+		weeks, err := r.GetKpiPeriodTotals(nil, nil, "2024-01-08", "2024-01-17", model.KpiGranularityWeek, now)
+		require.NoError(t, err)
+		assert.Equal(t, []*model.KpiPeriodTotal{
+			{PeriodStart: "2024-01-08", Minutes: 1140, Users: 2, OvertimeMinutes: 110, OvertimeUsers: 2},
+			{PeriodStart: "2024-01-15", Minutes: 300, Users: 1},
+		}, weeks)
+
+		months, err := r.GetKpiPeriodTotals(nil, nil, "2024-01-08", "2024-01-17", model.KpiGranularityMonth, now)
+		require.NoError(t, err)
+		require.Len(t, months, 1)
+		assert.Equal(t, "2024-01-01", months[0].PeriodStart)
+		assert.Equal(t, 1440, months[0].Minutes)
+	})
+}
+
+func TestKpiUserPeriodTotalsPerWeek(t *testing.T) {
+	withKpiDB(t, func(tx *gorm.DB) {
+		u1, u2, _ := seedKpis(t, tx)
+		r := NewRepository(tx)
+
+		rows, err := r.GetKpiUserPeriodTotals(nil, nil, "2024-01-08", "2024-01-17", model.KpiGranularityWeek, time.Now())
+		require.NoError(t, err)
+		byUser := map[string][]model.KpiUserPeriodTotal{}
+		for _, p := range rows {
+			byUser[p.UserID] = append(byUser[p.UserID], *p)
+		}
+		assert.Equal(t, []model.KpiUserPeriodTotal{
+			{UserID: u1.String(), PeriodStart: "2024-01-08", Minutes: 900},
+			{UserID: u1.String(), PeriodStart: "2024-01-15", Minutes: 300},
+		}, byUser[u1.String()])
+		assert.Equal(t, []model.KpiUserPeriodTotal{{UserID: u2.String(), PeriodStart: "2024-01-08", Minutes: 240}}, byUser[u2.String()])
+	})
+}
+
+func TestKpiUserTotalsOfATeam(t *testing.T) {
+	withKpiDB(t, func(tx *gorm.DB) {
+		u1, _, team := seedKpis(t, tx)
+		r := NewRepository(tx)
+
+		users, err := r.GetKpiUserTotals(nil, &team, "2024-01-08", "2024-01-17", time.Now())
+		require.NoError(t, err)
+		assert.Equal(t, []*model.KpiUserTotal{{
+			UserID: u1.String(), Minutes: 1200, Days: 3, OvertimeMinutes: 80,
+			OnTimeDays: 1, LateDays: 1, LateMinutes: 10, Sessions: 4, OpenSessions: 1,
+		}}, users)
+	})
+}
+
+func TestKpiOvertimeWeeksKeepTheDaysBeforeTheWindow(t *testing.T) {
+	withKpiDB(t, func(tx *gorm.DB) {
+		u1, u2, _ := seedKpis(t, tx)
+		r := NewRepository(tx)
+
+		weeks, err := r.GetKpiOvertimeWeeks(nil, nil, "2024-01-08", "2024-01-09", "2024-01-17")
+		require.NoError(t, err)
+		byUser := map[string]*model.KpiOvertimeWeek{}
+		for _, w := range weeks {
+			byUser[w.UserID] = w
+		}
+		assert.Len(t, weeks, 2)
+		assert.Equal(t, &model.KpiOvertimeWeek{UserID: u1.String(), WeekStart: "2024-01-08", BeforeMinutes: 60, Minutes: 20, Days: 1}, byUser[u1.String()])
+		assert.Equal(t, &model.KpiOvertimeWeek{UserID: u2.String(), WeekStart: "2024-01-08", Minutes: 30, ExcessMinutes: 15, Days: 1}, byUser[u2.String()])
+	})
+}
+
+func TestKpiCoverageCountsUsersPerHour(t *testing.T) {
+	withKpiDB(t, func(tx *gorm.DB) {
+		u1, u2, _ := seedKpis(t, tx)
+		r := NewRepository(tx)
+		at := func(h, m int) time.Time { return time.Date(2024, 1, 8, h, m, 0, 0, time.UTC) }
+		entries := []*dbmodels.TimeTableEntry{
+			{UserID: u1, Day: "2024-01-08", Arrival: at(9, 0), Departure: at(11, 30)},
+			{UserID: u2, Day: "2024-01-08", Arrival: at(10, 0), Departure: at(12, 0)},
+			// une session en cours compte jusqu'à maintenant
+			{UserID: u2, Day: "2024-01-08", Arrival: at(13, 0), Status: true},
+		}
+		require.NoError(t, tx.Create(&entries).Error)
+
+		cov, err := r.GetKpiCoverage(nil, "2024-01-08", "2024-01-08", at(9, 0), at(14, 0), at(14, 30))
+		require.NoError(t, err)
+		counts := map[int]int32{}
+		for _, p := range cov {
+			counts[p.Time.UTC().Hour()] = p.Count
+		}
+		assert.Equal(t, map[int]int32{9: 1, 10: 2, 11: 2, 12: 1, 13: 1, 14: 1}, counts)
+	})
+}
+
+func TestGetUserStarts(t *testing.T) {
+	withKpiDB(t, func(tx *gorm.DB) {
+		require.NoError(t, tx.AutoMigrate(&dbmodels.EmploymentContract{}))
+		created := time.Date(2024, 5, 8, 10, 0, 0, 0, time.UTC)
+		users := []*dbmodels.User{
+			{FirstName: "Ada", Email: "ada@test", CreatedAt: &created},
+			{FirstName: "Bob", Email: "bob@test", CreatedAt: &created},
+			{FirstName: "Cid", Email: "cid@test"},
+		}
+		require.NoError(t, tx.Create(&users).Error)
+		require.NoError(t, tx.Model(users[2]).Update("created_at", nil).Error)
+		require.NoError(t, tx.Create(&dbmodels.EmploymentContract{UserID: users[0].ID, EffectiveFrom: "2024-06-01"}).Error)
+		require.NoError(t, tx.Create(&dbmodels.TimeTableEntry{UserID: users[1].ID, Day: "2024-05-02",
+			Arrival: time.Date(2024, 5, 2, 9, 0, 0, 0, time.UTC)}).Error)
+
+		starts, err := NewRepository(tx).GetUserStarts()
+		require.NoError(t, err)
+		// le contrat prime, puis le plus tôt de la création et du premier pointage
+		assert.Equal(t, map[string]string{
+			users[0].ID.String(): "2024-06-01",
+			users[1].ID.String(): "2024-05-02",
+			users[2].ID.String(): "",
+		}, starts)
+	})
+}
@@ -0,0 +1,192 @@
+package repositories
+
+import (
+	"errors"
+	"time"
+
+	"github.com/epitech/timemanager/internal/graph/model"
+	latenessMapper "github.com/epitech/timemanager/internal/mappers/lateness"
+	dbmodels "github.com/epitech/timemanager/internal/models"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+var latenessEventNotFoundError = errors.New("lateness event not found")
+
+// CreateLatenessEvent enregistre le retard d'un pointage d'arrivée, à justifier
+func (r *Repository) CreateLatenessEvent(event *model.LatenessEvent) (*model.LatenessEvent, error) {
+	if event == nil || event.UserID == nil {
+		return nil, errors.New("invalid lateness event")
+	}
+	entryID, err := uuid.Parse(event.EntryID)
+	if err != nil {
+		return nil, idParsingError
+	}
+	userID, err := uuid.Parse(event.UserID.ID)
+	if err != nil {
+		return nil, idParsingError
+	}
+	dbEvent := &dbmodels.LatenessEvent{
+		TimeTableEntryID: entryID,
+		UserID:           userID,
+		Day:              event.Day,
+		PlannedStart:     event.PlannedStart,
+		Arrival:          event.Arrival,
+		LateMinutes:      int(event.LateMinutes),
+		Status:           dbmodels.LatenessPending,
+	}
+	if err := r.DB.Create(dbEvent).Error; err != nil {
+		return nil, errors.New("error while recording the lateness event")
+	}
+	return r.GetLatenessEvent(dbEvent.ID.String())
+}
+
+// SyncLatenessEvent remplace les retards d'une journée par celui de sa
+// première arrivée, ou les supprime si elle est à l'heure. Le retard d'une
+// même arrivée garde sa justification ; sa revue est annulée si sa durée change
+func (r *Repository) SyncLatenessEvent(userID uuid.UUID, day string, event *model.LatenessEvent) error {
+	var entryID uuid.UUID
+	if event != nil {
+		var err error
+		if entryID, err = uuid.Parse(event.EntryID); err != nil {
+			return idParsingError
+		}
+	}
+	err := r.DB.Transaction(func(tx *gorm.DB) error {
+		stale := tx.Where("user_id = ? AND day = ?", userID, day)
+		if event != nil {
+			stale = stale.Where("time_table_entry_id <> ?", entryID)
+		}
+		if err := stale.Delete(&dbmodels.LatenessEvent{}).Error; err != nil {
+			return err
+		}
+		if event == nil {
+			return nil
+		}
+		var current dbmodels.LatenessEvent
+		err := tx.Where("time_table_entry_id = ?", entryID).First(&current).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return tx.Create(&dbmodels.LatenessEvent{
+				TimeTableEntryID: entryID,
+				UserID:           userID,
+				Day:              day,
+				PlannedStart:     event.PlannedStart,
+				Arrival:          event.Arrival,
+				LateMinutes:      int(event.LateMinutes),
+				Status:           dbmodels.LatenessPending,
+			}).Error
+		}
+		if err != nil {
+			return err
+		}
+		updates := map[string]any{
+			"user_id":       userID,
+			"day":           day,
+			"planned_start": event.PlannedStart,
+			"arrival":       event.Arrival,
+			"late_minutes":  int(event.LateMinutes),
+		}
+		if current.LateMinutes != int(event.LateMinutes) {
+			status := dbmodels.LatenessPending
+			if current.Justification != "" {
+				status = dbmodels.LatenessJustified
+			}
+			updates["status"] = status
+			updates["reviewer_id"] = nil
+			updates["review_comment"] = ""
+			updates["reviewed_at"] = nil
+		}
+		return tx.Model(&dbmodels.LatenessEvent{}).Where(whereID, current.ID).Updates(updates).Error
+	})
+	if err != nil {
+		return errors.New("error while updating the lateness event")
+	}
+	return nil
+}
+
+func (r *Repository) GetLatenessEvent(id string) (*model.LatenessEvent, error) {
+	eventID, err := uuid.Parse(id)
+	if err != nil {
+		return nil, idParsingError
+	}
+	var event dbmodels.LatenessEvent
+	if err := r.DB.Preload("User").Preload("Reviewer").Where(whereID, eventID).First(&event).Error; err != nil {
+		return nil, latenessEventNotFoundError
+	}
+	return latenessMapper.DBLatenessEventToGraph(&event), nil
+}
+
+// GetLatenessEvents liste les retards entre deux jours inclus, d'un
+// utilisateur ou des membres d'une équipe si précisés, les plus récents d'abord
+func (r *Repository) GetLatenessEvents(userID *uuid.UUID, teamID *uuid.UUID, from, to *string, status *model.LatenessStatus) ([]*model.LatenessEvent, error) {
+	var events []*dbmodels.LatenessEvent
+	dbq := r.DB.Model(&dbmodels.LatenessEvent{}).Preload("User").Preload("Reviewer")
+	if userID != nil {
+		dbq = dbq.Where("user_id = ?", *userID)
+	}
+	if teamID != nil {
+		sub := r.DB.Table("team_users").Select("user_id").Where("team_id = ?", *teamID)
+		dbq = dbq.Where("user_id IN (?)", sub)
+	}
+	if from != nil && *from != "" {
+		dbq = dbq.Where("day >= ?", *from)
+	}
+	if to != nil && *to != "" {
+		dbq = dbq.Where("day <= ?", *to)
+	}
+	if status != nil {
+		dbq = dbq.Where("status = ?", string(*status))
+	}
+	if err := dbq.Order("arrival DESC").Find(&events).Error; err != nil {
+		return nil, errors.New("can't find lateness events")
+	}
+	return latenessMapper.DBLatenessEventsToGraph(events), nil
+}
+
+// JustifyLatenessEvent enregistre la justification de l'employé
+func (r *Repository) JustifyLatenessEvent(id string, justification string) (*model.LatenessEvent, error) {
+	eventID, err := uuid.Parse(id)
+	if err != nil {
+		return nil, idParsingError
+	}
+	result := r.DB.Model(&dbmodels.LatenessEvent{}).Where(whereID, eventID).Updates(map[string]any{
+		"justification": justification,
+		"status":        dbmodels.LatenessJustified,
+	})
+	if result.Error != nil {
+		return nil, errors.New("failed to justify the lateness event")
+	}
+	if result.RowsAffected == 0 {
+		return nil, latenessEventNotFoundError
+	}
+	return r.GetLatenessEvent(id)
+}
+
+// ReviewLatenessEvent déclare le retard excusé ou non
+func (r *Repository) ReviewLatenessEvent(id string, reviewerID uuid.UUID, excused bool, comment *string) (*model.LatenessEvent, error) {
+	eventID, err := uuid.Parse(id)
+	if err != nil {
+		return nil, idParsingError
+	}
+	status := dbmodels.LatenessUnexcused
+	if excused {
+		status = dbmodels.LatenessExcused
+	}
+	reviewComment := ""
+	if comment != nil {
+		reviewComment = *comment
+	}
+	result := r.DB.Model(&dbmodels.LatenessEvent{}).Where(whereID, eventID).Updates(map[string]any{
+		"status":         status,
+		"reviewer_id":    reviewerID,
+		"review_comment": reviewComment,
+		"reviewed_at":    time.Now(),
+	})
+	if result.Error != nil {
+		return nil, errors.New("failed to review the lateness event")
+	}
+	if result.RowsAffected == 0 {
+		return nil, latenessEventNotFoundError
+	}
+	return r.GetLatenessEvent(id)
+}
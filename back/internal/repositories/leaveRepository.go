@@ -0,0 +1,359 @@
+package repositories
+
+import (
+	"errors"
+	"time"
+
+	"github.com/epitech/timemanager/internal/graph/model"
+	leaveMapper "github.com/epitech/timemanager/internal/mappers/leave"
+	dbmodels "github.com/epitech/timemanager/internal/models"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var absenceTypeNotFoundError = errors.New("absence type not found")
+var leaveRequestNotFoundError = errors.New("leave request not found")
+var insufficientLeaveBalanceError = errors.New("insufficient leave balance")
+
+func (r *Repository) GetAbsenceTypes() ([]*model.AbsenceType, error) {
+	var types []*dbmodels.AbsenceType
+	if err := r.DB.Order("name ASC").Find(&types).Error; err != nil {
+		return nil, errors.New("can't find absence types")
+	}
+	return leaveMapper.DBAbsenceTypesToGraph(types), nil
+}
+
+func (r *Repository) GetAbsenceType(id string) (*model.AbsenceType, error) {
+	typeID, err := uuid.Parse(id)
+	if err != nil {
+		return nil, idParsingError
+	}
+	var absenceType dbmodels.AbsenceType
+	if err := r.DB.Where(whereID, typeID).First(&absenceType).Error; err != nil {
+		return nil, absenceTypeNotFoundError
+	}
+	return leaveMapper.DBAbsenceTypeToGraph(&absenceType), nil
+}
+
+func (r *Repository) CreateAbsenceType(input model.CreateAbsenceTypeInput) (*model.AbsenceType, error) {
+	var existing dbmodels.AbsenceType
+	if err := r.DB.Where("name = ?", input.Name).First(&existing).Error; err == nil {
+		return nil, errors.New("absence type's name is already in use")
+	}
+	absenceType := &dbmodels.AbsenceType{
+		Name:           input.Name,
+		Paid:           input.Paid,
+		DeductsBalance: input.DeductsBalance,
+		IsActive:       true,
+	}
+	if input.AccrualDaysPerMonth != nil {
+		absenceType.AccrualDaysPerMonth = *input.AccrualDaysPerMonth
+	}
+	if input.MaxBalance != nil {
+		absenceType.MaxBalance = *input.MaxBalance
+	}
+	if err := r.DB.Create(absenceType).Error; err != nil {
+		return nil, errors.New("error while creating absence type")
+	}
+	return leaveMapper.DBAbsenceTypeToGraph(absenceType), nil
+}
+
+func (r *Repository) UpdateAbsenceType(id string, input model.UpdateAbsenceTypeInput) (*model.AbsenceType, error) {
+	typeID, err := uuid.Parse(id)
+	if err != nil {
+		return nil, idParsingError
+	}
+	var absenceType dbmodels.AbsenceType
+	if err := r.DB.Where(whereID, typeID).First(&absenceType).Error; err != nil {
+		return nil, absenceTypeNotFoundError
+	}
+	if input.Name != nil {
+		absenceType.Name = *input.Name
+	}
+	if input.Paid != nil {
+		absenceType.Paid = *input.Paid
+	}
+	if input.DeductsBalance != nil {
+		absenceType.DeductsBalance = *input.DeductsBalance
+	}
+	if input.AccrualDaysPerMonth != nil {
+		absenceType.AccrualDaysPerMonth = *input.AccrualDaysPerMonth
+	}
+	if input.MaxBalance != nil {
+		absenceType.MaxBalance = *input.MaxBalance
+	}
+	if input.IsActive != nil {
+		absenceType.IsActive = *input.IsActive
+	}
+	if err := r.DB.Save(&absenceType).Error; err != nil {
+		return nil, errors.New("error while updating absence type")
+	}
+	return leaveMapper.DBAbsenceTypeToGraph(&absenceType), nil
+}
+
+func (r *Repository) GetLeaveBalances(userID uuid.UUID) ([]*model.LeaveBalance, error) {
+	var balances []*dbmodels.LeaveBalance
+	if err := r.DB.Preload("AbsenceType").Where("user_id = ?", userID).Find(&balances).Error; err != nil {
+		return nil, errors.New("can't find leave balances")
+	}
+	out := make([]*model.LeaveBalance, 0, len(balances))
+	for _, b := range balances {
+		out = append(out, leaveMapper.DBLeaveBalanceToGraph(b))
+	}
+	return out, nil
+}
+
+// SaveLeaveAccrual enregistre le solde crédité jusqu'au mois accruedThrough.
+// La mise à jour n'a lieu que si le solde en est toujours au mois previous,
+// pour qu'une même période ne soit jamais créditée deux fois
+func (r *Repository) SaveLeaveAccrual(userID uuid.UUID, absenceTypeID uuid.UUID, previous string, accruedThrough string, balance float64) error {
+	var existing dbmodels.LeaveBalance
+	err := r.DB.Where("user_id = ? AND absence_type_id = ?", userID, absenceTypeID).First(&existing).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		created := &dbmodels.LeaveBalance{
+			UserID:         userID,
+			AbsenceTypeID:  absenceTypeID,
+			Balance:        balance,
+			AccruedThrough: accruedThrough,
+		}
+		return r.DB.Clauses(clause.OnConflict{DoNothing: true}).Create(created).Error
+	}
+	if err != nil {
+		return err
+	}
+	return r.DB.Model(&dbmodels.LeaveBalance{}).
+		Where("id = ? AND accrued_through = ?", existing.ID, previous).
+		Updates(map[string]interface{}{"balance": balance, "accrued_through": accruedThrough}).Error
+}
+
+// GetLeaveAccrualStarts renvoie, pour chaque utilisateur (ou le seul userID),
+// le jour depuis lequel il acquiert des congés : le début de son premier
+// contrat, à défaut son premier pointage, vide sans l'un ni l'autre
+func (r *Repository) GetLeaveAccrualStarts(userID *uuid.UUID) (map[string]string, error) {
+	var rows []struct {
+		ID    uuid.UUID
+		Start string
+	}
+	query := r.DB.Table("users").Select(`users.id AS id, COALESCE(
+		(SELECT MIN(c.effective_from) FROM employment_contracts c WHERE c.user_id = users.id),
+		(SELECT MIN(e.day) FROM time_table_entries e WHERE e.user_id = users.id),
+		'') AS start`)
+	if userID != nil {
+		query = query.Where("users.id = ?", *userID)
+	}
+	if err := query.Scan(&rows).Error; err != nil {
+		return nil, errors.New("can't find leave accrual starts")
+	}
+	out := make(map[string]string, len(rows))
+	for _, row := range rows {
+		out[row.ID.String()] = row.Start
+	}
+	return out, nil
+}
+
+// AdjustLeaveBalance ajoute days (éventuellement négatif) au solde
+func (r *Repository) AdjustLeaveBalance(userID uuid.UUID, absenceTypeID uuid.UUID, days float64) (*model.LeaveBalance, error) {
+	if err := r.DB.Where(whereID, userID).First(&dbmodels.User{}).Error; err != nil {
+		return nil, userNotFoundError
+	}
+	if err := r.DB.Where(whereID, absenceTypeID).First(&dbmodels.AbsenceType{}).Error; err != nil {
+		return nil, absenceTypeNotFoundError
+	}
+	var balance dbmodels.LeaveBalance
+	if err := r.DB.Transaction(func(tx *gorm.DB) error {
+		if err := addToBalance(tx, userID, absenceTypeID, days); err != nil {
+			return err
+		}
+		return tx.Preload("AbsenceType").
+			Where("user_id = ? AND absence_type_id = ?", userID, absenceTypeID).
+			First(&balance).Error
+	}); err != nil {
+		return nil, errors.New("failed to adjust leave balance")
+	}
+	return leaveMapper.DBLeaveBalanceToGraph(&balance), nil
+}
+
+func (r *Repository) CreateLeaveRequest(userID uuid.UUID, input model.RequestLeaveInput, days float64) (*model.LeaveRequest, error) {
+	typeID, err := uuid.Parse(input.AbsenceTypeID)
+	if err != nil {
+		return nil, idParsingError
+	}
+	request := &dbmodels.LeaveRequest{
+		UserID:        userID,
+		AbsenceTypeID: typeID,
+		StartDate:     input.StartDate,
+		EndDate:       input.EndDate,
+		Days:          days,
+		Status:        dbmodels.LeavePending,
+	}
+	if input.HalfDay != nil {
+		request.HalfDay = *input.HalfDay
+	}
+	if input.Reason != nil {
+		request.Reason = *input.Reason
+	}
+	if err := r.DB.Create(request).Error; err != nil {
+		return nil, errors.New("error while creating leave request")
+	}
+	return r.GetLeaveRequest(request.ID.String())
+}
+
+func (r *Repository) GetLeaveRequest(id string) (*model.LeaveRequest, error) {
+	requestID, err := uuid.Parse(id)
+	if err != nil {
+		return nil, idParsingError
+	}
+	var request dbmodels.LeaveRequest
+	if err := r.DB.Preload("User").Preload("Reviewer").Preload("AbsenceType").
+		Where(whereID, requestID).First(&request).Error; err != nil {
+		return nil, leaveRequestNotFoundError
+	}
+	return leaveMapper.DBLeaveRequestToGraph(&request), nil
+}
+
+// GetLeaveRequests liste les demandes chevauchant la période [from, to] (bornes
+// optionnelles), éventuellement restreintes à un utilisateur ou aux membres des
+// équipes d'un manager
+func (r *Repository) GetLeaveRequests(status *model.LeaveStatus, userID *uuid.UUID, managerID *uuid.UUID, from, to *string) ([]*model.LeaveRequest, error) {
+	var requests []*dbmodels.LeaveRequest
+	dbq := r.DB.Model(&dbmodels.LeaveRequest{}).Preload("User").Preload("Reviewer").Preload("AbsenceType")
+	if status != nil {
+		dbq = dbq.Where("status = ?", string(*status))
+	}
+	if userID != nil {
+		dbq = dbq.Where("user_id = ?", *userID)
+	}
+	if managerID != nil {
+		sub := r.DB.Table("team_users").
+			Select("team_users.user_id").
+			Joins("JOIN teams ON teams.id = team_users.team_id").
+			Where("teams.manager_id = ?", *managerID)
+		dbq = dbq.Where("user_id IN (?)", sub)
+	}
+	if from != nil {
+		dbq = dbq.Where("end_date >= ?", *from)
+	}
+	if to != nil {
+		dbq = dbq.Where("start_date <= ?", *to)
+	}
+	if err := dbq.Order("start_date DESC").Find(&requests).Error; err != nil {
+		return nil, errors.New("can't find leave requests")
+	}
+	return leaveMapper.DBLeaveRequestsToGraph(requests), nil
+}
+
+// HasOverlappingLeave indique si l'utilisateur a déjà une demande en attente ou
+// validée chevauchant la période
+func (r *Repository) HasOverlappingLeave(userID uuid.UUID, start, end string) (bool, error) {
+	var count int64
+	err := r.DB.Model(&dbmodels.LeaveRequest{}).
+		Where("user_id = ? AND status IN ?", userID, []dbmodels.LeaveStatus{dbmodels.LeavePending, dbmodels.LeaveApproved}).
+		Where("start_date <= ? AND end_date >= ?", end, start).
+		Count(&count).Error
+	if err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
+// ReviewLeaveRequest valide ou refuse une demande ; une absence validée d'un
+// type décompté est débitée du solde dans la même transaction
+func (r *Repository) ReviewLeaveRequest(id string, reviewerID uuid.UUID, approve bool, comment *string) (*model.LeaveRequest, error) {
+	requestID, err := uuid.Parse(id)
+	if err != nil {
+		return nil, idParsingError
+	}
+
+	err = r.DB.Transaction(func(tx *gorm.DB) error {
+		var request dbmodels.LeaveRequest
+		if err := tx.Preload("AbsenceType").Where(whereID, requestID).First(&request).Error; err != nil {
+			return leaveRequestNotFoundError
+		}
+		if request.Status != dbmodels.LeavePending {
+			return errors.New("leave request has already been reviewed")
+		}
+
+		if approve {
+			if request.AbsenceType != nil && request.AbsenceType.DeductsBalance {
+				if err := debitBalance(tx, &request); err != nil {
+					return err
+				}
+			}
+			request.Status = dbmodels.LeaveApproved
+		} else {
+			request.Status = dbmodels.LeaveRejected
+		}
+
+		now := time.Now()
+		request.ReviewerID = &reviewerID
+		request.ReviewedAt = &now
+		if comment != nil {
+			request.ReviewComment = *comment
+		}
+		return tx.Omit("User", "Reviewer", "AbsenceType").Save(&request).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return r.GetLeaveRequest(id)
+}
+
+// CancelLeaveRequest annule une demande en attente ou validée ; les jours d'une
+// absence validée sont rendus au solde
+func (r *Repository) CancelLeaveRequest(id string) (*model.LeaveRequest, error) {
+	requestID, err := uuid.Parse(id)
+	if err != nil {
+		return nil, idParsingError
+	}
+
+	err = r.DB.Transaction(func(tx *gorm.DB) error {
+		var request dbmodels.LeaveRequest
+		if err := tx.Preload("AbsenceType").Where(whereID, requestID).First(&request).Error; err != nil {
+			return leaveRequestNotFoundError
+		}
+		switch request.Status {
+		case dbmodels.LeavePending:
+		case dbmodels.LeaveApproved:
+			if request.AbsenceType != nil && request.AbsenceType.DeductsBalance {
+				if err := addToBalance(tx, request.UserID, request.AbsenceTypeID, request.Days); err != nil {
+					return err
+				}
+			}
+		default:
+			return errors.New("only pending or approved leave requests can be cancelled")
+		}
+		request.Status = dbmodels.LeaveCancelled
+		return tx.Omit("User", "Reviewer", "AbsenceType").Save(&request).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return r.GetLeaveRequest(id)
+}
+
+func debitBalance(tx *gorm.DB, request *dbmodels.LeaveRequest) error {
+	var balance dbmodels.LeaveBalance
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("user_id = ? AND absence_type_id = ?", request.UserID, request.AbsenceTypeID).
+		First(&balance).Error; err != nil {
+		return insufficientLeaveBalanceError
+	}
+	if balance.Balance < request.Days {
+		return insufficientLeaveBalanceError
+	}
+	return tx.Model(&balance).Update("balance", gorm.Expr("balance - ?", request.Days)).Error
+}
+
+func addToBalance(tx *gorm.DB, userID uuid.UUID, absenceTypeID uuid.UUID, days float64) error {
+	result := tx.Model(&dbmodels.LeaveBalance{}).
+		Where("user_id = ? AND absence_type_id = ?", userID, absenceTypeID).
+		Update("balance", gorm.Expr("balance + ?", days))
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected > 0 {
+		return nil
+	}
+	return tx.Create(&dbmodels.LeaveBalance{UserID: userID, AbsenceTypeID: absenceTypeID, Balance: days}).Error
+}
@@ -0,0 +1,126 @@
+package timetableEntryMutation
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/epitech/timemanager/internal/graph/model"
+	breakMapper "github.com/epitech/timemanager/internal/mappers/breaks"
+	dbmodels "github.com/epitech/timemanager/internal/models"
+	"github.com/epitech/timemanager/package/middlewares"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+const openBreakCondition = "time_table_entry_id = ? AND ended_at IS NULL"
+
+// StartBreak ouvre une pause sur la session de pointage en cours
+func StartBreak(ctx context.Context, db *gorm.DB, breakTypeID string) (*model.Break, error) {
+	userID, err := userIDFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	typeID, err := uuid.Parse(breakTypeID)
+	if err != nil {
+		return nil, errors.New("invalid break type ID")
+	}
+	var breakType dbmodels.BreakType
+	if err := db.Where("id = ? AND is_active = ?", typeID, true).First(&breakType).Error; err != nil {
+		return nil, errors.New("break type not found")
+	}
+
+	entry, err := findOpenSession(db, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	var openBreak dbmodels.Break
+	if err := db.Where(openBreakCondition, entry.ID).First(&openBreak).Error; err == nil {
+		return nil, errors.New("you are already on a break")
+	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, fmt.Errorf("database error: %w", err)
+	}
+
+	newBreak := dbmodels.Break{
+		TimeTableEntryID: entry.ID,
+		BreakTypeID:      breakType.ID,
+		BreakType:        &breakType,
+		Paid:             breakType.Paid,
+		StartedAt:        time.Now(),
+	}
+	if err := db.Omit("BreakType").Create(&newBreak).Error; err != nil {
+		return nil, fmt.Errorf("failed to start break: %w", err)
+	}
+
+	return breakMapper.DBBreakToGraph(&newBreak), nil
+}
+
+// EndBreak termine la pause en cours de l'utilisateur
+func EndBreak(ctx context.Context, db *gorm.DB) (*model.Break, error) {
+	userID, err := userIDFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	entry, err := findOpenSession(db, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	var openBreak dbmodels.Break
+	if err := db.Preload("BreakType").Where(openBreakCondition, entry.ID).First(&openBreak).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("you are not on a break")
+		}
+		return nil, fmt.Errorf("database error: %w", err)
+	}
+
+	now := time.Now()
+	openBreak.EndedAt = &now
+	if err := db.Model(&openBreak).Update("ended_at", now).Error; err != nil {
+		return nil, fmt.Errorf("failed to end break: %w", err)
+	}
+
+	return breakMapper.DBBreakToGraph(&openBreak), nil
+}
+
+// closeOpenBreaks termine les pauses restées ouvertes lors d'un clockOut
+func closeOpenBreaks(db *gorm.DB, entryID uuid.UUID, at time.Time) error {
+	return db.Model(&dbmodels.Break{}).
+		Where(openBreakCondition, entryID).
+		Update("ended_at", at).Error
+}
+
+// ErrNotClockedIn signale qu'aucune session n'est en cours
+var ErrNotClockedIn = errors.New("you are not clocked in")
+
+// findOpenSession retourne la session ouverte de l'utilisateur, quelle que soit
+// sa date : un poste de nuit commencé la veille reste la session en cours
+func findOpenSession(db *gorm.DB, userID uuid.UUID) (*dbmodels.TimeTableEntry, error) {
+	var entry dbmodels.TimeTableEntry
+	result := db.Where(openSessionCondition, userID, true).
+		Order("arrival DESC").
+		First(&entry)
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return nil, ErrNotClockedIn
+		}
+		return nil, fmt.Errorf("database error: %w", result.Error)
+	}
+	return &entry, nil
+}
+
+func userIDFromContext(ctx context.Context) (uuid.UUID, error) {
+	userIDStr, ok := ctx.Value(middlewares.ContextUserIDKey).(string)
+	if !ok || userIDStr == "" {
+		return uuid.Nil, errors.New("unauthorized: user not authenticated")
+	}
+	userID, err := uuid.Parse(userIDStr)
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("invalid user ID format: %w", err)
+	}
+	return userID, nil
+}
@@ -10,12 +10,14 @@ import (
 	"github.com/epitech/timemanager/internal/mappers/timeTableEntries"
 	dbmodels "github.com/epitech/timemanager/internal/models"
 	"github.com/epitech/timemanager/package/middlewares"
+	"github.com/epitech/timemanager/package/payperiod"
+	"github.com/epitech/timemanager/package/timezone"
 	"github.com/google/uuid"
 	"gorm.io/gorm"
 )
 
 const layoutISO = "2006-01-02"
-const userIdAndDayCondition = "user_id = ? AND day = ?"
+const openSessionCondition = "user_id = ? AND status = ?"
 
 func ClockIn(ctx context.Context, db *gorm.DB) (*model.TimeTableEntry, error) {
 	userIDstr, ok := ctx.Value(middlewares.ContextUserIDKey).(string)
@@ -28,49 +30,48 @@ func ClockIn(ctx context.Context, db *gorm.DB) (*model.TimeTableEntry, error) {
 		return nil, fmt.Errorf("invalid user ID in context: %v", err)
 	}
 
-	currentDate := time.Now().Format(layoutISO)
-	now := time.Now()
-
-	var existingEntry dbmodels.TimeTableEntry
-	result := db.Where(userIdAndDayCondition, userID, currentDate).First(&existingEntry)
-
-	if result.Error != nil {
-		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
-			newEntry := dbmodels.TimeTableEntry{
-				UserID:    userID,
-				Day:       currentDate,
-				Arrival:   now,
-				Departure: time.Time{},
-				Status:    true,
-			}
-
-			if err := db.Create(&newEntry).Error; err != nil {
-				return nil, fmt.Errorf("failed to create time entry: %w", err)
-			}
-			checkPlannedHours(db, userID, now)
-
-			return timeTableEntriesMapper.DBTimeTableEntryToGraph(&newEntry), nil
-		}
-		return nil, fmt.Errorf("database error: %w", result.Error)
+	// La journée de pointage est celle du fuseau de l'utilisateur (ou de son site)
+	loc, err := userLocation(db, userID)
+	if err != nil {
+		return nil, err
 	}
-
-	if existingEntry.Status {
-		return nil, errors.New("you are already clocked in")
+	now := time.Now().In(loc)
+	currentDate := now.Format(layoutISO)
+
+	newEntry := dbmodels.TimeTableEntry{
+		UserID:    userID,
+		Day:       currentDate,
+		Arrival:   now,
+		Departure: time.Time{},
+		Status:    true,
 	}
 
-	existingEntry.Arrival = now
-	existingEntry.Status = true
-	existingEntry.Departure = time.Time{}
-
-	if err := db.Save(&existingEntry).Error; err != nil {
-		return nil, fmt.Errorf("failed to update time entry: %w", err)
+	// La période de paie est vérifiée dans la transaction de l'insertion : une
+	// clôture concurrente attend celle-ci, puis compte la session ouverte
+	err = db.Transaction(func(tx *gorm.DB) error {
+		if err := payperiod.EnsureOpen(tx, currentDate); err != nil {
+			return err
+		}
+		// Chaque pointage d'entrée ouvre une nouvelle session : on refuse seulement
+		// s'il existe déjà une session ouverte, quel que soit son jour (poste de nuit).
+		var openEntry dbmodels.TimeTableEntry
+		result := tx.Where(openSessionCondition, userID, true).First(&openEntry)
+		if result.Error == nil {
+			return errors.New("you are already clocked in")
+		}
+		if !errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return fmt.Errorf("database error: %w", result.Error)
+		}
+		if err := tx.Create(&newEntry).Error; err != nil {
+			return fmt.Errorf("failed to create time entry: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
 	}
 
-	// Vérification des heures planifiées (optionnel)
-	checkPlannedHours(db, userID, now)
-
-	return timeTableEntriesMapper.DBTimeTableEntryToGraph(&existingEntry), nil
-
+	return timeTableEntriesMapper.DBTimeTableEntryToGraph(&newEntry), nil
 }
 
 func ClockOut(ctx context.Context, db *gorm.DB) (*model.TimeTableEntry, error) {
@@ -84,45 +85,54 @@ func ClockOut(ctx context.Context, db *gorm.DB) (*model.TimeTableEntry, error) {
 		return nil, fmt.Errorf("invalid user ID format: %w", err)
 	}
 
-	currentDate := time.Now().Format(layoutISO)
 	now := time.Now()
 
-	var existingEntry dbmodels.TimeTableEntry
-	result := db.Where(userIdAndDayCondition, userID, currentDate).First(&existingEntry)
-
-	if result.Error != nil {
-		return nil, errors.New("no clock-in record found for today")
+	// La session ouverte peut dater de la veille (poste de nuit)
+	existingEntry, err := findOpenSession(db, userID)
+	if err != nil {
+		return nil, err
 	}
-	if !existingEntry.Status {
-		return nil, errors.New("you are not clocked in")
+
+	// Une pause oubliée se termine avec la session
+	if err := closeOpenBreaks(db, existingEntry.ID, now); err != nil {
+		return nil, fmt.Errorf("failed to close open break: %w", err)
 	}
 
 	existingEntry.Departure = now
 	existingEntry.Status = false
 
-	if err := db.Save(&existingEntry).Error; err != nil {
+	if err := db.Omit("Breaks").Save(existingEntry).Error; err != nil {
 		return nil, fmt.Errorf("failed to update time entry: %w", err)
 	}
+	if err := db.Preload("Breaks.BreakType").Where("id = ?", existingEntry.ID).First(existingEntry).Error; err != nil {
+		return nil, fmt.Errorf("failed to reload time entry: %w", err)
+	}
 
-	return timeTableEntriesMapper.DBTimeTableEntryToGraph(&existingEntry), nil
+	return timeTableEntriesMapper.DBTimeTableEntryToGraph(existingEntry), nil
 
 }
 
-func checkPlannedHours(db *gorm.DB, userID uuid.UUID, now time.Time) {
-	dayOfWeek := now.Weekday().String()
-	var timeTable dbmodels.TimeTable
-	if err := db.Where(userIdAndDayCondition, userID, dayOfWeek).First(&timeTable).Error; err == nil {
-		plannedStart := timeTable.Start
-
-		nowHour, nowMin := now.Hour(), now.Minute()
-		plannedHour, plannedMin := plannedStart.Hour(), plannedStart.Minute()
-
-		nowMinutes := nowHour*60 + nowMin
-		plannedMinutes := plannedHour*60 + plannedMin
+// OpenSession renvoie la session en cours de l'utilisateur, pour contrôler son
+// jour avant de la modifier
+func OpenSession(ctx context.Context, db *gorm.DB) (*model.TimeTableEntry, error) {
+	userID, err := userIDFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	entry, err := findOpenSession(db, userID)
+	if err != nil {
+		return nil, err
+	}
+	return timeTableEntriesMapper.DBTimeTableEntryToGraph(entry), nil
+}
 
-		if nowMinutes > plannedMinutes+15 { // 15 minutes de tolérance
-			lateMinutes := nowMinutes - plannedMinutes
-			fmt.Printf("User %s is late by %d minutes\n", userID, lateMinutes)
+func userLocation(db *gorm.DB, userID uuid.UUID) (*time.Location, error) {
+	var user dbmodels.User
+	if err := db.Preload("Site").Where("id = ?", userID).First(&user).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("user not found")
 		}
+		return nil, fmt.Errorf("database error: %w", err)
 	}
+	return timezone.Resolve(user.EffectiveTimeZone()), nil
 }
@@ -8,6 +8,7 @@ import (
 	userMapper "github.com/epitech/timemanager/internal/mappers/user"
 	models "github.com/epitech/timemanager/internal/models"
 	"github.com/epitech/timemanager/package/database"
+	"github.com/epitech/timemanager/package/timezone"
 	"golang.org/x/crypto/bcrypt"
 	// "github.com/google/uuid"
 )
@@ -30,6 +31,15 @@ func CreateUserInput(input gmodel.CreateUserInput) (*gmodel.User, error) {
 	}
 	dbUser.Password = string(hashed)
 
+	if _, err := timezone.Load(dbUser.TimeZone); err != nil {
+		return nil, err
+	}
+	if input.SiteID != nil && *input.SiteID != "" {
+		if dbUser.SiteID == nil || database.DB.Where("id = ?", *dbUser.SiteID).First(&models.Site{}).Error != nil {
+			return nil, errors.New("site not found")
+		}
+	}
+
 	// Save to DB
 	if err := database.DB.Create(dbUser).Error; err != nil {
 		return nil, err
@@ -0,0 +1,45 @@
+package repositories
+
+import (
+	"errors"
+	"time"
+
+	"github.com/epitech/timemanager/internal/graph/model"
+	overtimeMapper "github.com/epitech/timemanager/internal/mappers/overtime"
+	dbmodels "github.com/epitech/timemanager/internal/models"
+	"gorm.io/gorm"
+)
+
+// GetOvertimePolicy renvoie la politique d'heures supplémentaires en vigueur,
+// ou nil si aucune n'a été définie
+func (r *Repository) GetOvertimePolicy() (*model.OvertimePolicy, error) {
+	var policy dbmodels.OvertimePolicy
+	if err := r.DB.Preload("Bands").Where("is_active = ?", true).
+		Order("effective_from DESC").First(&policy).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, errors.New("can't find overtime policy")
+	}
+	return overtimeMapper.DBOvertimePolicyToGraph(&policy), nil
+}
+
+// SetOvertimePolicy remplace la politique active ; l'ancienne est gardée comme historique
+func (r *Repository) SetOvertimePolicy(input model.OvertimePolicyInput) (*model.OvertimePolicy, error) {
+	now := time.Now()
+	policy := overtimeMapper.OvertimePolicyInputToDB(input, now)
+	if err := r.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&dbmodels.OvertimePolicy{}).
+			Where("is_active = ?", true).
+			Updates(map[string]any{
+				"is_active":    false,
+				"effective_to": now,
+			}).Error; err != nil {
+			return err
+		}
+		return tx.Create(policy).Error
+	}); err != nil {
+		return nil, errors.New("failed to save overtime policy")
+	}
+	return overtimeMapper.DBOvertimePolicyToGraph(policy), nil
+}
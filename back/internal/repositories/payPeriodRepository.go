@@ -0,0 +1,131 @@
+package repositories
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/epitech/timemanager/internal/graph/model"
+	payPeriodMapper "github.com/epitech/timemanager/internal/mappers/payPeriod"
+	dbmodels "github.com/epitech/timemanager/internal/models"
+	"github.com/epitech/timemanager/package/payperiod"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+var payPeriodNotFoundError = errors.New("pay period not found")
+
+// ClosePayPeriod clôture les jours de startDate à endDate inclus ; ils ne
+// doivent chevaucher aucune autre période clôturée
+func (r *Repository) ClosePayPeriod(startDate, endDate string, closedBy uuid.UUID) (*model.PayPeriod, error) {
+	period := dbmodels.PayPeriod{
+		StartDate:  startDate,
+		EndDate:    endDate,
+		Status:     dbmodels.PayPeriodClosed,
+		ClosedByID: closedBy,
+		ClosedAt:   time.Now(),
+	}
+	err := r.DB.Transaction(func(tx *gorm.DB) error {
+		// attend les écritures de pointages en cours et bloque les suivantes
+		if err := payperiod.LockForClose(tx); err != nil {
+			return err
+		}
+		var overlapping int64
+		if err := tx.Model(&dbmodels.PayPeriod{}).
+			Where("status = ? AND start_date <= ? AND end_date >= ?", dbmodels.PayPeriodClosed, endDate, startDate).
+			Count(&overlapping).Error; err != nil {
+			return err
+		}
+		if overlapping > 0 {
+			return errors.New("this period overlaps a closed pay period")
+		}
+		// compté dans la transaction : une session ouverte entre-temps n'échappe pas au contrôle
+		var running int64
+		if err := tx.Model(&dbmodels.TimeTableEntry{}).
+			Where("status = ? AND day >= ? AND day <= ?", true, startDate, endDate).
+			Count(&running).Error; err != nil {
+			return err
+		}
+		if running > 0 {
+			return fmt.Errorf("%d sessions of this period are still running: clock them out first", running)
+		}
+		if err := tx.Create(&period).Error; err != nil {
+			return errors.New("failed to close pay period")
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return r.GetPayPeriodByID(period.ID.String())
+}
+
+func (r *Repository) GetPayPeriodByID(id string) (*model.PayPeriod, error) {
+	periodID, err := uuid.Parse(id)
+	if err != nil {
+		return nil, idParsingError
+	}
+	var period dbmodels.PayPeriod
+	if err := r.DB.Preload("ClosedBy").Preload("ReopenedBy").Where(whereID, periodID).First(&period).Error; err != nil {
+		return nil, payPeriodNotFoundError
+	}
+	return payPeriodMapper.DBPayPeriodToGraph(&period), nil
+}
+
+// GetPayPeriods liste les périodes chevauchant les jours de from à to inclus,
+// des plus récentes aux plus anciennes
+func (r *Repository) GetPayPeriods(status *model.PayPeriodStatus, from, to *string) ([]*model.PayPeriod, error) {
+	var periods []*dbmodels.PayPeriod
+	dbq := r.DB.Model(&dbmodels.PayPeriod{}).Preload("ClosedBy").Preload("ReopenedBy")
+	if status != nil {
+		dbq = dbq.Where("status = ?", string(*status))
+	}
+	if from != nil && *from != "" {
+		dbq = dbq.Where("end_date >= ?", *from)
+	}
+	if to != nil && *to != "" {
+		dbq = dbq.Where("start_date <= ?", *to)
+	}
+	if err := dbq.Order("start_date DESC, closed_at DESC").Find(&periods).Error; err != nil {
+		return nil, errors.New("can't find pay periods")
+	}
+	return payPeriodMapper.DBPayPeriodsToGraph(periods), nil
+}
+
+// GetClosedPayPeriod renvoie la période clôturée couvrant le jour, nil s'il
+// n'est pas verrouillé
+func (r *Repository) GetClosedPayPeriod(day string) (*model.PayPeriod, error) {
+	var period dbmodels.PayPeriod
+	err := r.DB.Where("status = ? AND start_date <= ? AND end_date >= ?", dbmodels.PayPeriodClosed, day, day).
+		First(&period).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return payPeriodMapper.DBPayPeriodToGraph(&period), nil
+}
+
+// ReopenPayPeriod rouvre une période clôturée en gardant le motif
+func (r *Repository) ReopenPayPeriod(id string, reopenedBy uuid.UUID, reason string) (*model.PayPeriod, error) {
+	periodID, err := uuid.Parse(id)
+	if err != nil {
+		return nil, idParsingError
+	}
+	result := r.DB.Model(&dbmodels.PayPeriod{}).
+		Where("id = ? AND status = ?", periodID, dbmodels.PayPeriodClosed).
+		Updates(map[string]any{
+			"status":         dbmodels.PayPeriodReopened,
+			"reopened_by_id": reopenedBy,
+			"reopen_reason":  reason,
+			"reopened_at":    time.Now(),
+		})
+	if result.Error != nil {
+		return nil, errors.New("failed to reopen pay period")
+	}
+	if result.RowsAffected == 0 {
+		return nil, errors.New("pay period is not closed")
+	}
+	return r.GetPayPeriodByID(id)
+}
@@ -0,0 +1,49 @@
+package repositories
+
+import (
+	"testing"
+	"time"
+
+	"github.com/epitech/timemanager/internal/graph/model"
+	dbmodels "github.com/epitech/timemanager/internal/models"
+	"github.com/epitech/timemanager/package/payperiod"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+func TestClosePayPeriodRefusesRunningSessions(t *testing.T) {
+	withKpiDB(t, func(tx *gorm.DB) {
+		require.NoError(t, tx.AutoMigrate(&dbmodels.PayPeriod{}))
+		u1, _, _ := seedKpis(t, tx)
+		r := NewRepository(tx)
+		running := &dbmodels.TimeTableEntry{UserID: u1, Day: "2024-01-31", Arrival: time.Date(2024, 1, 31, 9, 0, 0, 0, time.UTC), Status: true}
+		require.NoError(t, tx.Create(running).Error)
+
+		_, err := r.ClosePayPeriod("2024-01-01", "2024-01-31", u1)
+		assert.ErrorContains(t, err, "1 sessions of this period are still running")
+		period, err := r.ClosePayPeriod("2024-01-01", "2024-01-30", u1)
+		require.NoError(t, err)
+		assert.Equal(t, "2024-01-30", period.EndDate)
+	})
+}
+
+func TestEntryWritesRecheckTheClosedPeriodInTheirTransaction(t *testing.T) {
+	withKpiDB(t, func(tx *gorm.DB) {
+		require.NoError(t, tx.AutoMigrate(&dbmodels.PayPeriod{}, &dbmodels.LatenessEvent{}))
+		u1, _, _ := seedKpis(t, tx)
+		r := NewRepository(tx)
+		open := &dbmodels.TimeTableEntry{UserID: u1, Day: "2024-02-01", Arrival: time.Date(2024, 2, 1, 9, 0, 0, 0, time.UTC)}
+		require.NoError(t, tx.Create(open).Error)
+		_, err := r.ClosePayPeriod("2024-01-01", "2024-01-31", u1)
+		require.NoError(t, err)
+
+		_, err = r.CreateTimeEntry(model.CreateTimeEntryInput{UserID: u1.String(), Day: "2024-01-15", Arrival: time.Date(2024, 1, 15, 9, 0, 0, 0, time.UTC)})
+		assert.ErrorIs(t, err, payperiod.ErrClosed)
+		closedDay := "2024-01-31"
+		_, err = r.UpdateTimeEntry(open.ID.String(), model.UpdateTimeEntryInput{Day: &closedDay})
+		assert.ErrorIs(t, err, payperiod.ErrClosed, "not into the closed period either")
+		_, err = r.CreateTimeEntry(model.CreateTimeEntryInput{UserID: u1.String(), Day: "2024-02-02", Arrival: time.Date(2024, 2, 2, 9, 0, 0, 0, time.UTC)})
+		assert.NoError(t, err)
+	})
+}
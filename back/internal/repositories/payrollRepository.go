@@ -0,0 +1,242 @@
+package repositories
+
+import (
+	"errors"
+	"time"
+
+	"github.com/epitech/timemanager/internal/graph/model"
+	payrollMapper "github.com/epitech/timemanager/internal/mappers/payroll"
+	dbmodels "github.com/epitech/timemanager/internal/models"
+	"github.com/epitech/timemanager/services"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+var payCodeNotFoundError = errors.New("pay code not found")
+var payrollLayoutNotFoundError = errors.New("payroll layout not found")
+
+func (r *Repository) GetPayCodes() ([]*model.PayCode, error) {
+	var codes []*dbmodels.PayCode
+	if err := r.DB.Preload("AbsenceType").Order("category ASC, rate_percent ASC, code ASC").Find(&codes).Error; err != nil {
+		return nil, errors.New("can't find pay codes")
+	}
+	return payrollMapper.DBPayCodesToGraph(codes), nil
+}
+
+// SavePayCode remplace le code de la catégorie (et du taux ou du type
+// d'absence) s'il existe, sinon le crée
+func (r *Repository) SavePayCode(input model.PayCodeInput) (*model.PayCode, error) {
+	var absenceTypeID *uuid.UUID
+	if input.AbsenceTypeID != nil {
+		id, err := uuid.Parse(*input.AbsenceTypeID)
+		if err != nil {
+			return nil, idParsingError
+		}
+		if err := r.DB.Where(whereID, id).First(&dbmodels.AbsenceType{}).Error; err != nil {
+			return nil, errors.New("absence type not found")
+		}
+		absenceTypeID = &id
+	}
+	var ratePercent *int
+	if input.RatePercent != nil {
+		rate := int(*input.RatePercent)
+		ratePercent = &rate
+	}
+
+	var code dbmodels.PayCode
+	dbq := r.DB.Where("category = ?", string(input.Category))
+	if ratePercent != nil {
+		dbq = dbq.Where("rate_percent = ?", *ratePercent)
+	} else {
+		dbq = dbq.Where("rate_percent IS NULL")
+	}
+	if absenceTypeID != nil {
+		dbq = dbq.Where("absence_type_id = ?", *absenceTypeID)
+	} else {
+		dbq = dbq.Where("absence_type_id IS NULL")
+	}
+	if err := dbq.First(&code).Error; err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+	code.Category = string(input.Category)
+	code.RatePercent = ratePercent
+	code.AbsenceTypeID = absenceTypeID
+	code.Code = input.Code
+	code.Label = ""
+	if input.Label != nil {
+		code.Label = *input.Label
+	}
+	if err := r.DB.Save(&code).Error; err != nil {
+		return nil, errors.New("failed to save pay code")
+	}
+	if err := r.DB.Preload("AbsenceType").Where(whereID, code.ID).First(&code).Error; err != nil {
+		return nil, payCodeNotFoundError
+	}
+	return payrollMapper.DBPayCodeToGraph(&code), nil
+}
+
+func (r *Repository) DeletePayCode(id string) (bool, error) {
+	codeID, err := uuid.Parse(id)
+	if err != nil {
+		return false, idParsingError
+	}
+	result := r.DB.Where(whereID, codeID).Delete(&dbmodels.PayCode{})
+	if result.Error != nil {
+		return false, errors.New("failed to delete pay code")
+	}
+	if result.RowsAffected == 0 {
+		return false, payCodeNotFoundError
+	}
+	return true, nil
+}
+
+func (r *Repository) GetPayrollLayouts() ([]*model.PayrollLayout, error) {
+	var layouts []*dbmodels.PayrollLayout
+	if err := r.DB.Preload("Columns").Order("name ASC").Find(&layouts).Error; err != nil {
+		return nil, errors.New("can't find payroll layouts")
+	}
+	return payrollMapper.DBPayrollLayoutsToGraph(layouts), nil
+}
+
+func (r *Repository) GetPayrollLayout(id string) (*model.PayrollLayout, error) {
+	layoutID, err := uuid.Parse(id)
+	if err != nil {
+		return nil, idParsingError
+	}
+	var layout dbmodels.PayrollLayout
+	if err := r.DB.Preload("Columns").Where(whereID, layoutID).First(&layout).Error; err != nil {
+		return nil, payrollLayoutNotFoundError
+	}
+	return payrollMapper.DBPayrollLayoutToGraph(&layout), nil
+}
+
+func (r *Repository) CreatePayrollLayout(input model.PayrollLayoutInput) (*model.PayrollLayout, error) {
+	layout := &dbmodels.PayrollLayout{}
+	payrollMapper.PayrollLayoutInputToDB(layout, input)
+	err := r.DB.Transaction(func(tx *gorm.DB) error {
+		if err := ensurePayrollLayoutNameFree(tx, input.Name, uuid.Nil); err != nil {
+			return err
+		}
+		if err := tx.Create(layout).Error; err != nil {
+			return errors.New("failed to create payroll layout")
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return payrollMapper.DBPayrollLayoutToGraph(layout), nil
+}
+
+// UpdatePayrollLayout remplace la disposition et toutes ses colonnes
+func (r *Repository) UpdatePayrollLayout(id string, input model.PayrollLayoutInput) (*model.PayrollLayout, error) {
+	layoutID, err := uuid.Parse(id)
+	if err != nil {
+		return nil, idParsingError
+	}
+	var layout dbmodels.PayrollLayout
+	if err := r.DB.Where(whereID, layoutID).First(&layout).Error; err != nil {
+		return nil, payrollLayoutNotFoundError
+	}
+	payrollMapper.PayrollLayoutInputToDB(&layout, input)
+	err = r.DB.Transaction(func(tx *gorm.DB) error {
+		if err := ensurePayrollLayoutNameFree(tx, input.Name, layoutID); err != nil {
+			return err
+		}
+		if err := tx.Where("payroll_layout_id = ?", layoutID).Delete(&dbmodels.PayrollLayoutColumn{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Save(&layout).Error; err != nil {
+			return errors.New("failed to update payroll layout")
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return r.GetPayrollLayout(id)
+}
+
+func (r *Repository) DeletePayrollLayout(id string) (bool, error) {
+	layoutID, err := uuid.Parse(id)
+	if err != nil {
+		return false, idParsingError
+	}
+	if err := r.DB.Where(whereID, layoutID).First(&dbmodels.PayrollLayout{}).Error; err != nil {
+		return false, payrollLayoutNotFoundError
+	}
+	if err := r.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("payroll_layout_id = ?", layoutID).Delete(&dbmodels.PayrollLayoutColumn{}).Error; err != nil {
+			return err
+		}
+		return tx.Where(whereID, layoutID).Delete(&dbmodels.PayrollLayout{}).Error
+	}); err != nil {
+		return false, errors.New("failed to delete payroll layout")
+	}
+	return true, nil
+}
+
+// ensurePayrollLayoutNameFree refuse un nom déjà pris par une autre disposition
+func ensurePayrollLayoutNameFree(tx *gorm.DB, name string, except uuid.UUID) error {
+	var taken int64
+	if err := tx.Model(&dbmodels.PayrollLayout{}).Where("name = ? AND id <> ?", name, except).Count(&taken).Error; err != nil {
+		return err
+	}
+	if taken > 0 {
+		return errors.New("a payroll layout with this name already exists")
+	}
+	return nil
+}
+
+// GetPayrollTotals renvoie les totaux de paie figés à la clôture de la
+// période, et si elle l'a été
+func (r *Repository) GetPayrollTotals(periodID string) ([]*services.PayrollTotal, bool, error) {
+	id, err := uuid.Parse(periodID)
+	if err != nil {
+		return nil, false, idParsingError
+	}
+	var period dbmodels.PayPeriod
+	if err := r.DB.Where(whereID, id).First(&period).Error; err != nil {
+		return nil, false, payPeriodNotFoundError
+	}
+	if period.PayrollFrozenAt == nil {
+		return nil, false, nil
+	}
+	var lines []*dbmodels.PayrollLine
+	if err := r.DB.Where("pay_period_id = ?", id).Find(&lines).Error; err != nil {
+		return nil, false, errors.New("can't find payroll totals")
+	}
+	return payrollMapper.DBPayrollLinesToTotals(lines), true, nil
+}
+
+// SavePayrollTotals fige les totaux de paie de la période, en remplaçant ceux
+// déjà figés
+func (r *Repository) SavePayrollTotals(periodID string, totals []*services.PayrollTotal) error {
+	id, err := uuid.Parse(periodID)
+	if err != nil {
+		return idParsingError
+	}
+	lines := make([]*dbmodels.PayrollLine, 0, len(totals))
+	for _, t := range totals {
+		line, err := payrollMapper.PayrollTotalToDB(id, t)
+		if err != nil {
+			return idParsingError
+		}
+		lines = append(lines, line)
+	}
+	err = r.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("pay_period_id = ?", id).Delete(&dbmodels.PayrollLine{}).Error; err != nil {
+			return err
+		}
+		if len(lines) > 0 {
+			if err := tx.Create(&lines).Error; err != nil {
+				return err
+			}
+		}
+		return tx.Model(&dbmodels.PayPeriod{}).Where(whereID, id).Update("payroll_frozen_at", time.Now()).Error
+	})
+	if err != nil {
+		return errors.New("failed to freeze the payroll")
+	}
+	return nil
+}
@@ -22,7 +22,7 @@ func ListUsers() ([]*gmodel.User, error) {
 	}
 	db := database.DB
 	var users []models.User
-	if err := db.Find(&users).Error; err != nil {
+	if err := db.Preload("Site").Find(&users).Error; err != nil {
 		return nil, err
 	}
 	out := make([]*gmodel.User, 0, len(users))
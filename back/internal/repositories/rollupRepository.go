@@ -0,0 +1,121 @@
+package repositories
+
+import (
+	"errors"
+
+	"github.com/epitech/timemanager/internal/graph/model"
+	rollupMapper "github.com/epitech/timemanager/internal/mappers/rollup"
+	dbmodels "github.com/epitech/timemanager/internal/models"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// GetDailyRollups renvoie les cumuls journaliers entre deux jours (YYYY-MM-DD
+// inclus), d'un utilisateur ou des membres d'une équipe si précisés
+func (r *Repository) GetDailyRollups(userID *uuid.UUID, teamID *uuid.UUID, from, to string) ([]*model.DailyRollup, error) {
+	var rollups []*dbmodels.DailyRollup
+	dbq := r.DB.Model(&dbmodels.DailyRollup{}).Preload("User").
+		Where("day >= ? AND day <= ?", from, to)
+	if userID != nil {
+		dbq = dbq.Where("user_id = ?", *userID)
+	}
+	if teamID != nil {
+		sub := r.DB.Table("team_users").Select("user_id").Where("team_id = ?", *teamID)
+		dbq = dbq.Where("user_id IN (?)", sub)
+	}
+	if err := dbq.Order("day ASC, user_id ASC").Find(&rollups).Error; err != nil {
+		return nil, errors.New("can't find daily rollups")
+	}
+	return rollupMapper.DBDailyRollupsToGraph(rollups), nil
+}
+
+// ReplaceDailyRollups remplace les cumuls entre deux jours inclus, d'un
+// utilisateur ou de tous, par ceux fournis
+func (r *Repository) ReplaceDailyRollups(userID *uuid.UUID, from, to string, rollups []*model.DailyRollup) error {
+	rows := make([]*dbmodels.DailyRollup, 0, len(rollups))
+	for _, ro := range rollups {
+		if row, ok := rollupMapper.GraphDailyRollupToDB(ro); ok {
+			rows = append(rows, row)
+		}
+	}
+	if err := r.DB.Transaction(func(tx *gorm.DB) error {
+		del := tx.Where("day >= ? AND day <= ?", from, to)
+		if userID != nil {
+			del = del.Where("user_id = ?", *userID)
+		}
+		if err := del.Delete(&dbmodels.DailyRollup{}).Error; err != nil {
+			return err
+		}
+		if len(rows) == 0 {
+			return nil
+		}
+		return tx.CreateInBatches(rows, 500).Error
+	}); err != nil {
+		return errors.New("failed to save daily rollups")
+	}
+	return nil
+}
+
+// GetRollupCoverage renvoie les plages de jours dont les cumuls sont complets
+func (r *Repository) GetRollupCoverage() ([]*model.RollupCoverage, error) {
+	var rows []*dbmodels.RollupCoverage
+	if err := r.DB.Order("\"from\" ASC").Find(&rows).Error; err != nil {
+		return nil, errors.New("can't find rollup coverage")
+	}
+	out := make([]*model.RollupCoverage, 0, len(rows))
+	for _, row := range rows {
+		out = append(out, &model.RollupCoverage{From: row.From, To: row.To, Rules: row.Rules})
+	}
+	return out, nil
+}
+
+// UpdateRollupCoverage remplace les plages de jours dont les cumuls sont
+// complets par celles que update tire des plages en cours, dans une seule
+// transaction : la table reste verrouillée en écriture jusqu'au bout, pour
+// qu'une reconstruction et l'abandon d'une plage ne s'écrasent pas
+func (r *Repository) UpdateRollupCoverage(update func(ranges []*model.RollupCoverage) []*model.RollupCoverage) error {
+	if err := r.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec("LOCK TABLE rollup_coverages IN SHARE ROW EXCLUSIVE MODE").Error; err != nil {
+			return err
+		}
+		var current []*dbmodels.RollupCoverage
+		if err := tx.Order("\"from\" ASC").Find(&current).Error; err != nil {
+			return err
+		}
+		ranges := make([]*model.RollupCoverage, 0, len(current))
+		for _, row := range current {
+			ranges = append(ranges, &model.RollupCoverage{From: row.From, To: row.To, Rules: row.Rules})
+		}
+		updated := update(ranges)
+		rows := make([]*dbmodels.RollupCoverage, 0, len(updated))
+		for _, rc := range updated {
+			rows = append(rows, &dbmodels.RollupCoverage{From: rc.From, To: rc.To, Rules: rc.Rules})
+		}
+		if err := tx.Where("1 = 1").Delete(&dbmodels.RollupCoverage{}).Error; err != nil {
+			return err
+		}
+		if len(rows) == 0 {
+			return nil
+		}
+		return tx.Create(&rows).Error
+	}); err != nil {
+		return errors.New("failed to save rollup coverage")
+	}
+	return nil
+}
+
+// GetUserIDs renvoie les membres de l'équipe, ou les utilisateurs du site
+func (r *Repository) GetUserIDs(teamID *uuid.UUID, siteID *uuid.UUID) ([]uuid.UUID, error) {
+	var ids []uuid.UUID
+	var err error
+	switch {
+	case teamID != nil:
+		err = r.DB.Table("team_users").Where("team_id = ?", *teamID).Pluck("user_id", &ids).Error
+	case siteID != nil:
+		err = r.DB.Model(&dbmodels.User{}).Where("site_id = ?", *siteID).Pluck("id", &ids).Error
+	}
+	if err != nil {
+		return nil, errors.New("can't find users")
+	}
+	return ids, nil
+}
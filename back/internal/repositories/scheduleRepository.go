@@ -0,0 +1,211 @@
+package repositories
+
+import (
+	"errors"
+	"time"
+
+	"github.com/epitech/timemanager/internal/graph/model"
+	timeTableMapper "github.com/epitech/timemanager/internal/mappers/timeTable"
+	workScheduleMapper "github.com/epitech/timemanager/internal/mappers/workSchedule"
+	dbmodels "github.com/epitech/timemanager/internal/models"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+var workScheduleNotFoundError = errors.New("work schedule not found")
+
+func (r *Repository) GetTimeTables() ([]*model.TimeTable, error) {
+	var tables []*dbmodels.TimeTable
+	if err := r.DB.Order("effective_from DESC").Find(&tables).Error; err != nil {
+		return nil, errors.New("can't find timetables")
+	}
+	return timeTableMapper.DBTimeTablesToGraph(tables), nil
+}
+
+func (r *Repository) GetWorkSchedules(userID *uuid.UUID, teamID *uuid.UUID) ([]*model.WorkSchedule, error) {
+	var schedules []*dbmodels.WorkSchedule
+	dbq := r.DB.Preload("Days")
+	if userID != nil {
+		dbq = dbq.Where("user_id = ?", *userID)
+	}
+	if teamID != nil {
+		dbq = dbq.Where("team_id = ?", *teamID)
+	}
+	if err := dbq.Order("effective_from DESC").Find(&schedules).Error; err != nil {
+		return nil, errors.New("can't find work schedules")
+	}
+	return workScheduleMapper.DBWorkSchedulesToGraph(schedules), nil
+}
+
+// GetWorkSchedule renvoie un planning et ses jours
+func (r *Repository) GetWorkSchedule(id string) (*model.WorkSchedule, error) {
+	scheduleID, err := uuid.Parse(id)
+	if err != nil {
+		return nil, idParsingError
+	}
+	var schedule dbmodels.WorkSchedule
+	if err := r.DB.Preload("Days").Where(whereID, scheduleID).First(&schedule).Error; err != nil {
+		return nil, workScheduleNotFoundError
+	}
+	return workScheduleMapper.DBWorkScheduleToGraph(&schedule), nil
+}
+
+// GetUserWorkSchedules renvoie les plannings de l'utilisateur et ceux de ses équipes
+func (r *Repository) GetUserWorkSchedules(userID uuid.UUID) ([]*model.WorkSchedule, error) {
+	var schedules []*dbmodels.WorkSchedule
+	teams := r.DB.Table("team_users").Select("team_id").Where("user_id = ?", userID)
+	if err := r.DB.Preload("Days").
+		Where("user_id = ? OR team_id IN (?)", userID, teams).
+		Order("effective_from DESC").
+		Find(&schedules).Error; err != nil {
+		return nil, errors.New("can't find work schedules")
+	}
+	return workScheduleMapper.DBWorkSchedulesToGraph(schedules), nil
+}
+
+// GetUsersWorkSchedules renvoie, par utilisateur, ses plannings et ceux de ses
+// équipes, en deux requêtes pour tous les utilisateurs
+func (r *Repository) GetUsersWorkSchedules(userIDs []uuid.UUID) (map[string][]*model.WorkSchedule, error) {
+	out := make(map[string][]*model.WorkSchedule, len(userIDs))
+	if len(userIDs) == 0 {
+		return out, nil
+	}
+	var memberships []dbmodels.TeamUser
+	if err := r.DB.Where("user_id IN ?", userIDs).Find(&memberships).Error; err != nil {
+		return nil, errors.New("can't find work schedules")
+	}
+	teamIDs := make([]uuid.UUID, 0, len(memberships))
+	members := make(map[uuid.UUID][]string)
+	for _, m := range memberships {
+		if _, ok := members[m.TeamID]; !ok {
+			teamIDs = append(teamIDs, m.TeamID)
+		}
+		members[m.TeamID] = append(members[m.TeamID], m.UserID.String())
+	}
+	var schedules []*dbmodels.WorkSchedule
+	dbq := r.DB.Preload("Days").Where("user_id IN ?", userIDs)
+	if len(teamIDs) > 0 {
+		dbq = dbq.Or("team_id IN ?", teamIDs)
+	}
+	if err := dbq.Order("effective_from DESC").Find(&schedules).Error; err != nil {
+		return nil, errors.New("can't find work schedules")
+	}
+	for _, uid := range userIDs {
+		out[uid.String()] = make([]*model.WorkSchedule, 0)
+	}
+	for _, ws := range workScheduleMapper.DBWorkSchedulesToGraph(schedules) {
+		switch {
+		case ws.UserID != nil:
+			out[*ws.UserID] = append(out[*ws.UserID], ws)
+		case ws.TeamID != nil:
+			teamID, err := uuid.Parse(*ws.TeamID)
+			if err != nil {
+				continue
+			}
+			for _, userID := range members[teamID] {
+				out[userID] = append(out[userID], ws)
+			}
+		}
+	}
+	return out, nil
+}
+
+func (r *Repository) CreateWorkSchedule(input model.AssignWorkScheduleInput) (*model.WorkSchedule, error) {
+	schedule := &dbmodels.WorkSchedule{EffectiveFrom: input.EffectiveFrom}
+	if input.EffectiveTo != nil {
+		schedule.EffectiveTo = *input.EffectiveTo
+	}
+	if input.UserID != nil && *input.UserID != "" {
+		userID, err := uuid.Parse(*input.UserID)
+		if err != nil {
+			return nil, idParsingError
+		}
+		if err := r.DB.Where(whereID, userID).First(&dbmodels.User{}).Error; err != nil {
+			return nil, userNotFoundError
+		}
+		schedule.UserID = &userID
+	}
+	if input.TeamID != nil && *input.TeamID != "" {
+		teamID, err := uuid.Parse(*input.TeamID)
+		if err != nil {
+			return nil, idParsingError
+		}
+		if err := r.DB.Where(whereID, teamID).First(&dbmodels.Team{}).Error; err != nil {
+			return nil, teamNotFoundError
+		}
+		schedule.TeamID = &teamID
+	}
+	for _, d := range input.Days {
+		start, err := time.Parse("15:04", d.Start)
+		if err != nil {
+			return nil, errors.New("invalid start time format, expected : HH:mm")
+		}
+		end, err := time.Parse("15:04", d.End)
+		if err != nil {
+			return nil, errors.New("invalid end time format, expected : HH:mm")
+		}
+		day := dbmodels.WorkScheduleDay{
+			Weekday: workScheduleMapper.WeekdayToDB(d.Weekday),
+			Start:   start,
+			Ends:    end,
+			Remote:  d.Remote != nil && *d.Remote,
+		}
+		if d.CoreStart != nil && *d.CoreStart != "" {
+			core, err := time.Parse("15:04", *d.CoreStart)
+			if err != nil {
+				return nil, errors.New("invalid coreStart time format, expected : HH:mm")
+			}
+			day.CoreStart = &core
+		}
+		schedule.Days = append(schedule.Days, day)
+	}
+	if err := r.DB.Create(schedule).Error; err != nil {
+		return nil, errors.New("failed to create work schedule")
+	}
+	return workScheduleMapper.DBWorkScheduleToGraph(schedule), nil
+}
+
+func (r *Repository) DeleteWorkSchedule(id string) (bool, error) {
+	scheduleID, err := uuid.Parse(id)
+	if err != nil {
+		return false, idParsingError
+	}
+	if err := r.DB.Where(whereID, scheduleID).First(&dbmodels.WorkSchedule{}).Error; err != nil {
+		return false, workScheduleNotFoundError
+	}
+	if err := r.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("work_schedule_id = ?", scheduleID).Delete(&dbmodels.WorkScheduleDay{}).Error; err != nil {
+			return err
+		}
+		return tx.Where(whereID, scheduleID).Delete(&dbmodels.WorkSchedule{}).Error
+	}); err != nil {
+		return false, errors.New("failed to delete work schedule")
+	}
+	return true, nil
+}
+
+// GetUserTimeZone renvoie le fuseau effectif de l'utilisateur (vide : fuseau par défaut)
+func (r *Repository) GetUserTimeZone(userID uuid.UUID) (string, error) {
+	var user dbmodels.User
+	if err := r.DB.Preload("Site").Where(whereID, userID).First(&user).Error; err != nil {
+		return "", userNotFoundError
+	}
+	return user.EffectiveTimeZone(), nil
+}
+
+// GetUsersTimeZones renvoie le fuseau effectif de chaque utilisateur (vide :
+// fuseau par défaut)
+func (r *Repository) GetUsersTimeZones(userIDs []uuid.UUID) (map[string]string, error) {
+	out := make(map[string]string, len(userIDs))
+	if len(userIDs) == 0 {
+		return out, nil
+	}
+	var users []*dbmodels.User
+	if err := r.DB.Preload("Site").Where("id IN ?", userIDs).Find(&users).Error; err != nil {
+		return nil, errors.New("can't find users")
+	}
+	for _, u := range users {
+		out[u.ID.String()] = u.EffectiveTimeZone()
+	}
+	return out, nil
+}
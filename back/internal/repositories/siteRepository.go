@@ -0,0 +1,67 @@
+package repositories
+
+import (
+	"errors"
+
+	"github.com/epitech/timemanager/internal/graph/model"
+	siteMapper "github.com/epitech/timemanager/internal/mappers/site"
+	dbmodels "github.com/epitech/timemanager/internal/models"
+	"github.com/google/uuid"
+)
+
+var siteNotFoundError = errors.New("site not found")
+
+func (r *Repository) GetSites() ([]*model.Site, error) {
+	var sites []*dbmodels.Site
+	if err := r.DB.Order("name ASC").Find(&sites).Error; err != nil {
+		return nil, errors.New("can't find sites")
+	}
+	return siteMapper.DBSitesToGraph(sites), nil
+}
+
+func (r *Repository) CreateSite(input model.SiteInput) (*model.Site, error) {
+	var existing dbmodels.Site
+	if err := r.DB.Where("name = ?", input.Name).First(&existing).Error; err == nil {
+		return nil, errors.New("site's name is already in use")
+	}
+	site := &dbmodels.Site{
+		Name:     input.Name,
+		TimeZone: input.TimeZone,
+	}
+	if err := r.DB.Create(site).Error; err != nil {
+		return nil, errors.New("error while creating site")
+	}
+	return siteMapper.DBSiteToGraph(site), nil
+}
+
+func (r *Repository) UpdateSite(id string, input model.SiteInput) (*model.Site, error) {
+	siteID, err := uuid.Parse(id)
+	if err != nil {
+		return nil, idParsingError
+	}
+	var site dbmodels.Site
+	if err := r.DB.Where(whereID, siteID).First(&site).Error; err != nil {
+		return nil, siteNotFoundError
+	}
+	site.Name = input.Name
+	site.TimeZone = input.TimeZone
+	if err := r.DB.Save(&site).Error; err != nil {
+		return nil, errors.New("error while updating site")
+	}
+	return siteMapper.DBSiteToGraph(&site), nil
+}
+
+// siteIDFromInput vérifie le site demandé ; une chaîne vide détache l'utilisateur
+func (r *Repository) siteIDFromInput(id string) (*uuid.UUID, error) {
+	if id == "" {
+		return nil, nil
+	}
+	siteID, err := uuid.Parse(id)
+	if err != nil {
+		return nil, idParsingError
+	}
+	if err := r.DB.Where(whereID, siteID).First(&dbmodels.Site{}).Error; err != nil {
+		return nil, siteNotFoundError
+	}
+	return &siteID, nil
+}
@@ -0,0 +1,167 @@
+package repositories
+
+import (
+	"errors"
+	"time"
+
+	"github.com/epitech/timemanager/internal/graph/model"
+	timeEntryCorrectionMapper "github.com/epitech/timemanager/internal/mappers/timeEntryCorrection"
+	dbmodels "github.com/epitech/timemanager/internal/models"
+	"github.com/epitech/timemanager/package/payperiod"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+var correctionNotFoundError = errors.New("correction request not found")
+
+func (r *Repository) CreateTimeEntryCorrection(userID uuid.UUID, input model.RequestTimeEntryCorrectionInput) (*model.TimeEntryCorrection, error) {
+	correction := &dbmodels.TimeEntryCorrection{
+		UserID:            userID,
+		Day:               input.Day,
+		ProposedArrival:   input.ProposedArrival,
+		ProposedDeparture: input.ProposedDeparture,
+		Reason:            input.Reason,
+		Status:            dbmodels.CorrectionPending,
+	}
+	if input.EntryID != nil && *input.EntryID != "" {
+		entryID, err := uuid.Parse(*input.EntryID)
+		if err != nil {
+			return nil, idParsingError
+		}
+		correction.TimeTableEntryID = &entryID
+	}
+	if err := r.DB.Create(correction).Error; err != nil {
+		return nil, errors.New("error while creating correction request")
+	}
+	return r.GetTimeEntryCorrection(correction.ID.String())
+}
+
+func (r *Repository) GetTimeEntryCorrection(id string) (*model.TimeEntryCorrection, error) {
+	correctionID, err := uuid.Parse(id)
+	if err != nil {
+		return nil, idParsingError
+	}
+	var correction dbmodels.TimeEntryCorrection
+	if err := r.DB.Preload("User").Preload("Reviewer").Where(whereID, correctionID).First(&correction).Error; err != nil {
+		return nil, correctionNotFoundError
+	}
+	return timeEntryCorrectionMapper.DBCorrectionToGraph(&correction), nil
+}
+
+// GetTimeEntryCorrections liste les demandes, éventuellement restreintes à un
+// utilisateur ou aux membres des équipes d'un manager
+func (r *Repository) GetTimeEntryCorrections(status *model.CorrectionStatus, userID *uuid.UUID, managerID *uuid.UUID) ([]*model.TimeEntryCorrection, error) {
+	var corrections []*dbmodels.TimeEntryCorrection
+	dbq := r.DB.Model(&dbmodels.TimeEntryCorrection{}).Preload("User").Preload("Reviewer")
+	if status != nil {
+		dbq = dbq.Where("status = ?", string(*status))
+	}
+	if userID != nil {
+		dbq = dbq.Where("user_id = ?", *userID)
+	}
+	if managerID != nil {
+		sub := r.DB.Table("team_users").
+			Select("team_users.user_id").
+			Joins("JOIN teams ON teams.id = team_users.team_id").
+			Where("teams.manager_id = ?", *managerID)
+		dbq = dbq.Where("user_id IN (?)", sub)
+	}
+	if err := dbq.Order("created_at DESC").Find(&corrections).Error; err != nil {
+		return nil, errors.New("can't find correction requests")
+	}
+	return timeEntryCorrectionMapper.DBCorrectionsToGraph(corrections), nil
+}
+
+// IsManagerOfUser indique si managerID gère une équipe dont userID est membre
+func (r *Repository) IsManagerOfUser(managerID uuid.UUID, userID uuid.UUID) (bool, error) {
+	var count int64
+	err := r.DB.Table("team_users").
+		Joins("JOIN teams ON teams.id = team_users.team_id").
+		Where("teams.manager_id = ? AND team_users.user_id = ?", managerID, userID).
+		Count(&count).Error
+	if err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
+// ReviewTimeEntryCorrection valide ou refuse une demande ; une demande validée
+// est appliquée à l'entrée (ou crée l'entrée manquante) dans la même transaction
+func (r *Repository) ReviewTimeEntryCorrection(id string, reviewerID uuid.UUID, approve bool, comment *string) (*model.TimeEntryCorrection, error) {
+	correctionID, err := uuid.Parse(id)
+	if err != nil {
+		return nil, idParsingError
+	}
+
+	err = r.DB.Transaction(func(tx *gorm.DB) error {
+		var correction dbmodels.TimeEntryCorrection
+		if err := tx.Where(whereID, correctionID).First(&correction).Error; err != nil {
+			return correctionNotFoundError
+		}
+		if correction.Status != dbmodels.CorrectionPending {
+			return errors.New("correction request has already been reviewed")
+		}
+
+		if approve {
+			if err := applyCorrection(tx, &correction); err != nil {
+				return err
+			}
+			correction.Status = dbmodels.CorrectionApproved
+		} else {
+			correction.Status = dbmodels.CorrectionRejected
+		}
+
+		now := time.Now()
+		correction.ReviewerID = &reviewerID
+		correction.ReviewedAt = &now
+		if comment != nil {
+			correction.ReviewComment = *comment
+		}
+		return tx.Save(&correction).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return r.GetTimeEntryCorrection(id)
+}
+
+func applyCorrection(tx *gorm.DB, correction *dbmodels.TimeEntryCorrection) error {
+	var entry dbmodels.TimeTableEntry
+	days := []string{correction.Day}
+	if correction.TimeTableEntryID != nil {
+		if err := tx.Where(whereID, *correction.TimeTableEntryID).First(&entry).Error; err != nil {
+			return timeEntryNotFoundError
+		}
+		days = append(days, entry.Day)
+	} else {
+		entry.UserID = correction.UserID
+	}
+	// ni le jour corrigé ni celui de l'entrée ne doivent être clôturés
+	if err := payperiod.EnsureOpen(tx, days...); err != nil {
+		return err
+	}
+
+	entry.Day = correction.Day
+	entry.Arrival = correction.ProposedArrival
+	entry.AutoClosed = false
+	entry.NeedsReview = false
+	if correction.ProposedDeparture != nil {
+		entry.Departure = *correction.ProposedDeparture
+		entry.Status = false
+	} else {
+		entry.Departure = time.Time{}
+		entry.Status = true
+	}
+
+	if entry.ID == uuid.Nil {
+		if err := tx.Create(&entry).Error; err != nil {
+			return errors.New("error while creating time entry")
+		}
+		correction.TimeTableEntryID = &entry.ID
+		return nil
+	}
+	if err := tx.Save(&entry).Error; err != nil {
+		return errors.New("error while updating time entry")
+	}
+	return nil
+}
@@ -1,11 +1,13 @@
 package repositories
 
 import (
+	"errors"
 	"time"
 
 	"github.com/epitech/timemanager/internal/graph/model"
 	timeTableEntriesMapper "github.com/epitech/timemanager/internal/mappers/timeTableEntries"
 	dbmodels "github.com/epitech/timemanager/internal/models"
+	"github.com/epitech/timemanager/package/payperiod"
 	"github.com/google/uuid"
 	"gorm.io/gorm"
 )
@@ -13,17 +15,32 @@ import (
 func (r *Repository) GetTimeTableEntries() ([]*model.TimeTableEntry, error) {
 	var entries []*dbmodels.TimeTableEntry
 
-	if err := r.DB.Preload("User").Find(&entries).Error; err != nil {
+	if err := r.DB.Preload("User.Site").Preload("Breaks.BreakType").Find(&entries).Error; err != nil {
 		return nil, err
 	}
 
 	return timeTableEntriesMapper.DBTimeTableEntriesToGraph(entries), nil
 }
 
+// GetUsersTimeTableEntries renvoie les pointages des utilisateurs entre deux
+// jours (YYYY-MM-DD inclus)
+func (r *Repository) GetUsersTimeTableEntries(userIDs []uuid.UUID, from, to string) ([]*model.TimeTableEntry, error) {
+	if len(userIDs) == 0 {
+		return []*model.TimeTableEntry{}, nil
+	}
+	var entries []*dbmodels.TimeTableEntry
+	if err := r.DB.Model(&dbmodels.TimeTableEntry{}).Preload("User.Site").Preload("Breaks.BreakType").
+		Where("user_id IN ? AND day >= ? AND day <= ?", userIDs, from, to).
+		Order("day ASC, arrival ASC").Find(&entries).Error; err != nil {
+		return nil, err
+	}
+	return timeTableEntriesMapper.DBTimeTableEntriesToGraph(entries), nil
+}
+
 // GetTimeTableEntriesFiltered returns entries filtered by optional user, team and date range (on Day string YYYY-MM-DD)
 func (r *Repository) GetTimeTableEntriesFiltered(userID *uuid.UUID, teamID *uuid.UUID, from, to *time.Time) ([]*model.TimeTableEntry, error) {
 	var entries []*dbmodels.TimeTableEntry
-	dbq := r.DB.Model(&dbmodels.TimeTableEntry{}).Preload("User")
+	dbq := r.DB.Model(&dbmodels.TimeTableEntry{}).Preload("User.Site").Preload("Breaks.BreakType")
 
 	if userID != nil {
 		dbq = dbq.Where("user_id = ?", *userID)
@@ -57,3 +74,152 @@ func (r *Repository) GetUserTimeTables(userID uuid.UUID) ([]*dbmodels.TimeTableE
 }
 
 func (r *Repository) DBHandle() *gorm.DB { return r.DB }
+
+var timeEntryNotFoundError = errors.New("time entry not found")
+
+func (r *Repository) GetTimeTableEntryByID(id string) (*model.TimeTableEntry, error) {
+	entryID, err := uuid.Parse(id)
+	if err != nil {
+		return nil, idParsingError
+	}
+	var entry dbmodels.TimeTableEntry
+	if err := r.DB.Preload("User.Site").Preload("Breaks.BreakType").Where(whereID, entryID).First(&entry).Error; err != nil {
+		return nil, timeEntryNotFoundError
+	}
+	return timeTableEntriesMapper.DBTimeTableEntryToGraph(&entry), nil
+}
+
+// HasOpenSession indique si l'utilisateur a une session en cours, autre que
+// l'entrée exceptID
+func (r *Repository) HasOpenSession(userID uuid.UUID, exceptID *string) (bool, error) {
+	dbq := r.DB.Model(&dbmodels.TimeTableEntry{}).Where("user_id = ? AND status = ?", userID, true)
+	if exceptID != nil {
+		entryID, err := uuid.Parse(*exceptID)
+		if err != nil {
+			return false, idParsingError
+		}
+		dbq = dbq.Where("id <> ?", entryID)
+	}
+	var count int64
+	if err := dbq.Count(&count).Error; err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
+// CreateTimeEntry ajoute directement une entrée (saisie manuelle par un admin),
+// sauf sur une période de paie clôturée
+func (r *Repository) CreateTimeEntry(input model.CreateTimeEntryInput) (*model.TimeTableEntry, error) {
+	userID, err := uuid.Parse(input.UserID)
+	if err != nil {
+		return nil, idParsingError
+	}
+	var user dbmodels.User
+	if err := r.DB.Where(whereID, userID).First(&user).Error; err != nil {
+		return nil, userNotFoundError
+	}
+	entry := &dbmodels.TimeTableEntry{
+		UserID:  userID,
+		Day:     input.Day,
+		Arrival: input.Arrival,
+		Status:  input.Status,
+	}
+	if input.Departure != nil {
+		entry.Departure = *input.Departure
+	}
+	err = r.DB.Transaction(func(tx *gorm.DB) error {
+		if err := payperiod.EnsureOpen(tx, entry.Day); err != nil {
+			return err
+		}
+		return tx.Create(entry).Error
+	})
+	if errors.Is(err, payperiod.ErrClosed) {
+		return nil, err
+	}
+	if err != nil {
+		return nil, errors.New("error while creating time entry")
+	}
+	entry.User = &user
+	return timeTableEntriesMapper.DBTimeTableEntryToGraph(entry), nil
+}
+
+// UpdateTimeEntry modifie directement une entrée (saisie manuelle par un
+// admin), sauf si son ancien ou son nouveau jour est sur une période de paie
+// clôturée
+func (r *Repository) UpdateTimeEntry(id string, input model.UpdateTimeEntryInput) (*model.TimeTableEntry, error) {
+	entryID, err := uuid.Parse(id)
+	if err != nil {
+		return nil, idParsingError
+	}
+	var entry dbmodels.TimeTableEntry
+	if err := r.DB.Where(whereID, entryID).First(&entry).Error; err != nil {
+		return nil, timeEntryNotFoundError
+	}
+	previousDay := entry.Day
+	if input.UserID != nil {
+		userID, err := uuid.Parse(*input.UserID)
+		if err != nil {
+			return nil, idParsingError
+		}
+		entry.UserID = userID
+	}
+	if input.Day != nil {
+		entry.Day = *input.Day
+	}
+	if input.Arrival != nil {
+		entry.Arrival = *input.Arrival
+	}
+	if input.Departure != nil {
+		entry.Departure = *input.Departure
+		// une sortie saisie manuellement remplace la clôture automatique
+		entry.AutoClosed = false
+		entry.NeedsReview = false
+	}
+	if input.Status != nil {
+		entry.Status = *input.Status
+	}
+	err = r.DB.Transaction(func(tx *gorm.DB) error {
+		if err := payperiod.EnsureOpen(tx, previousDay, entry.Day); err != nil {
+			return err
+		}
+		return tx.Save(&entry).Error
+	})
+	if errors.Is(err, payperiod.ErrClosed) {
+		return nil, err
+	}
+	if err != nil {
+		return nil, errors.New("error while updating time entry")
+	}
+	return r.GetTimeTableEntryByID(entry.ID.String())
+}
+
+func (r *Repository) DeleteTimeEntry(id string) (bool, error) {
+	entryID, err := uuid.Parse(id)
+	if err != nil {
+		return false, idParsingError
+	}
+	var entry dbmodels.TimeTableEntry
+	if err := r.DB.Where(whereID, entryID).First(&entry).Error; err != nil {
+		return false, timeEntryNotFoundError
+	}
+	err = r.DB.Transaction(func(tx *gorm.DB) error {
+		if err := payperiod.EnsureOpen(tx, entry.Day); err != nil {
+			return err
+		}
+		if err := tx.Where("time_table_entry_id = ?", entryID).Delete(&dbmodels.Break{}).Error; err != nil {
+			return err
+		}
+		// le retard relevé sur l'arrivée disparaît avec elle
+		if err := tx.Where("time_table_entry_id = ?", entryID).Delete(&dbmodels.LatenessEvent{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&entry).Error
+	})
+	if errors.Is(err, payperiod.ErrClosed) {
+		return false, err
+	}
+	if err != nil {
+		return false, errors.New("error while deleting time entry")
+	}
+	return true, nil
+}
@@ -0,0 +1,132 @@
+package repositories
+
+import (
+	"errors"
+	"time"
+
+	"github.com/epitech/timemanager/internal/graph/model"
+	timesheetMapper "github.com/epitech/timemanager/internal/mappers/timesheet"
+	dbmodels "github.com/epitech/timemanager/internal/models"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+var timesheetNotFoundError = errors.New("timesheet not found")
+
+// GetTimesheet renvoie la feuille de la semaine d'un utilisateur, nil si elle
+// n'a jamais été soumise
+func (r *Repository) GetTimesheet(userID uuid.UUID, weekStart string) (*model.Timesheet, error) {
+	var timesheet dbmodels.Timesheet
+	err := r.DB.Preload("User").Preload("Reviewer").
+		Where("user_id = ? AND week_start = ?", userID, weekStart).First(&timesheet).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return timesheetMapper.DBTimesheetToGraph(&timesheet), nil
+}
+
+func (r *Repository) GetTimesheetByID(id string) (*model.Timesheet, error) {
+	timesheetID, err := uuid.Parse(id)
+	if err != nil {
+		return nil, idParsingError
+	}
+	var timesheet dbmodels.Timesheet
+	if err := r.DB.Preload("User").Preload("Reviewer").Where(whereID, timesheetID).First(&timesheet).Error; err != nil {
+		return nil, timesheetNotFoundError
+	}
+	return timesheetMapper.DBTimesheetToGraph(&timesheet), nil
+}
+
+// GetTimesheets liste les feuilles des semaines commençant entre deux jours
+// inclus, d'un utilisateur ou des membres d'une équipe si précisés
+func (r *Repository) GetTimesheets(userID *uuid.UUID, teamID *uuid.UUID, status *model.TimesheetStatus, from, to *string) ([]*model.Timesheet, error) {
+	var timesheets []*dbmodels.Timesheet
+	dbq := r.DB.Model(&dbmodels.Timesheet{}).Preload("User").Preload("Reviewer")
+	if userID != nil {
+		dbq = dbq.Where("user_id = ?", *userID)
+	}
+	if teamID != nil {
+		sub := r.DB.Table("team_users").Select("user_id").Where("team_id = ?", *teamID)
+		dbq = dbq.Where("user_id IN (?)", sub)
+	}
+	if status != nil {
+		dbq = dbq.Where("status = ?", string(*status))
+	}
+	if from != nil && *from != "" {
+		dbq = dbq.Where("week_start >= ?", *from)
+	}
+	if to != nil && *to != "" {
+		dbq = dbq.Where("week_start <= ?", *to)
+	}
+	if err := dbq.Order("week_start DESC").Find(&timesheets).Error; err != nil {
+		return nil, errors.New("can't find timesheets")
+	}
+	return timesheetMapper.DBTimesheetsToGraph(timesheets), nil
+}
+
+// SubmitTimesheet soumet la feuille de la semaine, créée au besoin ; une
+// feuille refusée repart sans l'avis précédent
+func (r *Repository) SubmitTimesheet(userID uuid.UUID, weekStart string) (*model.Timesheet, error) {
+	var id uuid.UUID
+	err := r.DB.Transaction(func(tx *gorm.DB) error {
+		var timesheet dbmodels.Timesheet
+		err := tx.Where("user_id = ? AND week_start = ?", userID, weekStart).First(&timesheet).Error
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+		if err == nil && timesheet.Status != dbmodels.TimesheetDraft && timesheet.Status != dbmodels.TimesheetRejected {
+			return errors.New("timesheet has already been submitted")
+		}
+		now := time.Now()
+		timesheet.UserID = userID
+		timesheet.WeekStart = weekStart
+		timesheet.Status = dbmodels.TimesheetSubmitted
+		timesheet.SubmittedAt = &now
+		timesheet.ReviewerID = nil
+		timesheet.ReviewComment = ""
+		timesheet.ReviewedAt = nil
+		if err := tx.Save(&timesheet).Error; err != nil {
+			return errors.New("failed to submit timesheet")
+		}
+		id = timesheet.ID
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return r.GetTimesheetByID(id.String())
+}
+
+// ReviewTimesheet valide ou refuse une feuille soumise
+func (r *Repository) ReviewTimesheet(id string, reviewerID uuid.UUID, approve bool, comment *string) (*model.Timesheet, error) {
+	timesheetID, err := uuid.Parse(id)
+	if err != nil {
+		return nil, idParsingError
+	}
+	status := dbmodels.TimesheetRejected
+	if approve {
+		status = dbmodels.TimesheetApproved
+	}
+	reviewComment := ""
+	if comment != nil {
+		reviewComment = *comment
+	}
+	result := r.DB.Model(&dbmodels.Timesheet{}).
+		Where("id = ? AND status = ?", timesheetID, dbmodels.TimesheetSubmitted).
+		Updates(map[string]any{
+			"status":         status,
+			"reviewer_id":    reviewerID,
+			"review_comment": reviewComment,
+			"reviewed_at":    time.Now(),
+		})
+	if result.Error != nil {
+		return nil, errors.New("failed to review timesheet")
+	}
+	if result.RowsAffected == 0 {
+		return nil, errors.New("timesheet is not awaiting review")
+	}
+	return r.GetTimesheetByID(id)
+}
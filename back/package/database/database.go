@@ -83,19 +83,53 @@ func MigrateDB() error {
 	}
 
 	// Migration séquentielle pour éviter les problèmes de références
+	if err := DB.AutoMigrate(&dbmodels.HolidayCalendar{}, &dbmodels.Holiday{}); err != nil {
+		return fmt.Errorf("failed to migrate holiday tables: %w", err)
+	}
+	if err := DB.AutoMigrate(&dbmodels.Site{}); err != nil {
+		return fmt.Errorf("failed to migrate Site table: %w", err)
+	}
 	if err := DB.AutoMigrate(&dbmodels.User{}); err != nil {
 		return fmt.Errorf("failed to migrate User table: %w", err)
 	}
 	if err := DB.AutoMigrate(&dbmodels.Team{}); err != nil {
 		return fmt.Errorf("failed to migrate Team table: %w", err)
 	}
+	// Les pauses créées avant l'ajout de la colonne reprennent le statut de leur type
+	backfillBreakPaid := !DB.Migrator().HasColumn(&dbmodels.Break{}, "Paid")
 	if err := DB.AutoMigrate(
 		&dbmodels.TeamUser{},
 		&dbmodels.TimeTableEntry{},
 		&dbmodels.TimeTable{},
+		&dbmodels.BreakType{},
+		&dbmodels.Break{},
+		&dbmodels.TimeEntryCorrection{},
+		&dbmodels.WorkSchedule{},
+		&dbmodels.WorkScheduleDay{},
+		&dbmodels.AbsenceType{},
+		&dbmodels.LeaveRequest{},
+		&dbmodels.LeaveBalance{},
+		&dbmodels.EmploymentContract{},
+		&dbmodels.OvertimePolicy{},
+		&dbmodels.OvertimeBand{},
+		&dbmodels.ComplianceRule{},
+		&dbmodels.DailyRollup{},
+		&dbmodels.RollupCoverage{},
+		&dbmodels.LatenessEvent{},
+		&dbmodels.Timesheet{},
+		&dbmodels.PayPeriod{},
+		&dbmodels.PayCode{},
+		&dbmodels.PayrollLayout{},
+		&dbmodels.PayrollLayoutColumn{},
+		&dbmodels.PayrollLine{},
 	); err != nil {
 		return fmt.Errorf("failed to migrate related tables: %w", err)
 	}
+	if backfillBreakPaid {
+		if err := DB.Exec("UPDATE breaks SET paid = break_types.paid FROM break_types WHERE breaks.break_type_id = break_types.id").Error; err != nil {
+			return fmt.Errorf("failed to backfill paid breaks: %w", err)
+		}
+	}
 
 	log.Println("Database migration completed successfully")
 	return nil
@@ -131,6 +165,44 @@ func SeedDB() error {
 		log.Println("Seeded admin user")
 	}
 
+	// Types de pause par défaut
+	defaultBreakTypes := []dbmodels.BreakType{
+		{Name: "Pause déjeuner", Paid: false, IsActive: true},
+		{Name: "Pause café", Paid: true, IsActive: true},
+	}
+	for _, bt := range defaultBreakTypes {
+		if err := DB.Where("name = ?", bt.Name).FirstOrCreate(&bt).Error; err != nil {
+			return fmt.Errorf("failed to seed break type %q: %w", bt.Name, err)
+		}
+	}
+
+	// Types d'absence par défaut : 25 jours de congés payés par an
+	defaultAbsenceTypes := []dbmodels.AbsenceType{
+		{Name: "Congés payés", Paid: true, DeductsBalance: true, AccrualDaysPerMonth: 2.08, IsActive: true},
+		{Name: "RTT", Paid: true, DeductsBalance: true, AccrualDaysPerMonth: 1, IsActive: true},
+		{Name: "Maladie", Paid: true, DeductsBalance: false, IsActive: true},
+		{Name: "Congé sans solde", Paid: false, DeductsBalance: false, IsActive: true},
+	}
+	for _, at := range defaultAbsenceTypes {
+		if err := DB.Where("name = ?", at.Name).FirstOrCreate(&at).Error; err != nil {
+			return fmt.Errorf("failed to seed absence type %q: %w", at.Name, err)
+		}
+	}
+
+	// Règles légales du temps de travail (code du travail)
+	defaultComplianceRules := []dbmodels.ComplianceRule{
+		{Code: "MIN_DAILY_REST", Enabled: true, Severity: "HIGH", LimitMinutes: 11 * 60},
+		{Code: "MAX_WEEKLY_HOURS", Enabled: true, Severity: "HIGH", LimitMinutes: 48 * 60},
+		{Code: "AVG_WEEKLY_HOURS", Enabled: true, Severity: "MEDIUM", LimitMinutes: 44 * 60, Weeks: 12},
+		{Code: "MANDATORY_BREAK", Enabled: true, Severity: "MEDIUM", LimitMinutes: 6 * 60, BreakMinutes: 20},
+		{Code: "MAX_DAILY_HOURS", Enabled: true, Severity: "HIGH", LimitMinutes: 10 * 60},
+	}
+	for _, cr := range defaultComplianceRules {
+		if err := DB.Where("code = ?", cr.Code).FirstOrCreate(&cr).Error; err != nil {
+			return fmt.Errorf("failed to seed compliance rule %q: %w", cr.Code, err)
+		}
+	}
+
 	// Ajouter d'autres données initiales ici si nécessaire
 
 	log.Println("Database seeding completed successfully")
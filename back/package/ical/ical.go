@@ -0,0 +1,305 @@
+package ical
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const layoutISO = "2006-01-02"
+
+// Nombre d'années développées pour une règle annuelle sans COUNT ni UNTIL
+const maxYearlyOccurrences = 10
+
+// Day est un jour couvert par un événement du calendrier
+type Day struct {
+	Date    string // YYYY-MM-DD
+	Summary string
+}
+
+type event struct {
+	start, end   time.Time
+	endExclusive bool
+	summary      string
+	rrule        string
+	cancelled    bool
+}
+
+// ParseDays lit un fichier iCalendar (RFC 5545) et renvoie les jours couverts
+// par ses événements, un événement sur plusieurs jours donnant un jour chacun.
+// Seules les règles de récurrence annuelles (FREQ=YEARLY), éventuellement
+// précisées par BYMONTH et BYMONTHDAY, sont prises en charge
+func ParseDays(r io.Reader) ([]Day, error) {
+	lines, err := unfold(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var days []Day
+	var cur *event
+	for i, line := range lines {
+		name, params, value := splitProperty(line)
+		switch {
+		case name == "BEGIN" && value == "VEVENT":
+			cur = &event{}
+		case name == "END" && value == "VEVENT":
+			if cur == nil {
+				return nil, fmt.Errorf("line %d: END:VEVENT without BEGIN", i+1)
+			}
+			expanded, err := cur.days()
+			if err != nil {
+				return nil, fmt.Errorf("event %q: %w", cur.summary, err)
+			}
+			days = append(days, expanded...)
+			cur = nil
+		case cur == nil:
+			continue
+		case name == "DTSTART":
+			if cur.start, _, err = parseDate(params, value); err != nil {
+				return nil, fmt.Errorf("line %d: %w", i+1, err)
+			}
+		case name == "DTEND":
+			var allDay bool
+			if cur.end, allDay, err = parseDate(params, value); err != nil {
+				return nil, fmt.Errorf("line %d: %w", i+1, err)
+			}
+			// la fin d'un événement sur des journées entières est exclue
+			cur.endExclusive = allDay || (cur.end.Hour() == 0 && cur.end.Minute() == 0)
+		case name == "SUMMARY":
+			cur.summary = unescape(value)
+		case name == "RRULE":
+			cur.rrule = value
+		case name == "STATUS":
+			cur.cancelled = strings.EqualFold(value, "CANCELLED")
+		}
+	}
+	if len(days) == 0 {
+		return nil, errors.New("no event found in the calendar")
+	}
+	return days, nil
+}
+
+// unfold recolle les lignes repliées (qui commencent par une espace ou une tabulation)
+func unfold(r io.Reader) ([]string, error) {
+	var lines []string
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if (strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t")) && len(lines) > 0 {
+			lines[len(lines)-1] += line[1:]
+			continue
+		}
+		if line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return lines, scanner.Err()
+}
+
+// splitProperty découpe "NAME;PARAM=X:VALUE"
+func splitProperty(line string) (name string, params string, value string) {
+	colon := strings.Index(line, ":")
+	if colon < 0 {
+		return strings.ToUpper(line), "", ""
+	}
+	name, value = line[:colon], line[colon+1:]
+	if semi := strings.Index(name, ";"); semi >= 0 {
+		name, params = name[:semi], name[semi+1:]
+	}
+	return strings.ToUpper(name), strings.ToUpper(params), value
+}
+
+// parseDate lit une date (VALUE=DATE) ou une date-heure, dont seul le jour
+// tel qu'écrit est retenu
+func parseDate(params, value string) (time.Time, bool, error) {
+	allDay := strings.Contains(params, "VALUE=DATE") && !strings.Contains(params, "VALUE=DATE-TIME")
+	if len(value) < 8 {
+		return time.Time{}, false, fmt.Errorf("invalid date %q", value)
+	}
+	day, err := time.Parse("20060102", value[:8])
+	if err != nil {
+		return time.Time{}, false, fmt.Errorf("invalid date %q", value)
+	}
+	if len(value) >= 15 && value[8] == 'T' {
+		clock, err := time.Parse("150405", value[9:15])
+		if err != nil {
+			return time.Time{}, false, fmt.Errorf("invalid time %q", value)
+		}
+		day = day.Add(time.Duration(clock.Hour())*time.Hour + time.Duration(clock.Minute())*time.Minute)
+	} else {
+		allDay = true
+	}
+	return day, allDay, nil
+}
+
+func unescape(v string) string {
+	return strings.NewReplacer(`\n`, " ", `\N`, " ", `\,`, ",", `\;`, ";", `\\`, `\`).Replace(v)
+}
+
+func (e *event) days() ([]Day, error) {
+	if e.cancelled {
+		return nil, nil
+	}
+	if e.start.IsZero() {
+		return nil, errors.New("missing DTSTART")
+	}
+	first := e.start.Truncate(24 * time.Hour)
+	last := first
+	if !e.end.IsZero() {
+		last = e.end.Truncate(24 * time.Hour)
+		if e.endExclusive && last.After(first) {
+			last = last.AddDate(0, 0, -1)
+		}
+	}
+	if last.Before(first) {
+		return nil, errors.New("DTEND is before DTSTART")
+	}
+
+	occurrences := []time.Time{first}
+	if e.rrule != "" {
+		var err error
+		if occurrences, err = yearly(first, e.rrule); err != nil {
+			return nil, err
+		}
+	}
+
+	span := int(last.Sub(first).Hours() / 24)
+	var out []Day
+	for _, o := range occurrences {
+		for d := 0; d <= span; d++ {
+			out = append(out, Day{Date: o.AddDate(0, 0, d).Format(layoutISO), Summary: e.summary})
+		}
+	}
+	return out, nil
+}
+
+// yearly développe une règle FREQ=YEARLY bornée par COUNT ou UNTIL. BYMONTH et
+// BYMONTHDAY donnent les mois et les jours de chaque année, à défaut ceux de
+// DTSTART ; BYMONTHDAY seul vaut pour tous les mois (RFC 5545, 3.3.10)
+func yearly(first time.Time, rrule string) ([]time.Time, error) {
+	count := 0
+	var until time.Time
+	freq := ""
+	var months, monthDays []int
+	for _, part := range strings.Split(rrule, ";") {
+		key, value, _ := strings.Cut(part, "=")
+		switch strings.ToUpper(key) {
+		case "FREQ":
+			freq = strings.ToUpper(value)
+		case "COUNT":
+			n, err := strconv.Atoi(value)
+			if err != nil || n <= 0 {
+				return nil, fmt.Errorf("invalid COUNT %q", value)
+			}
+			count = n
+		case "UNTIL":
+			t, _, err := parseDate("", value)
+			if err != nil {
+				return nil, err
+			}
+			until = t
+		case "INTERVAL":
+			if value != "1" {
+				return nil, fmt.Errorf("unsupported recurrence %q", rrule)
+			}
+		case "BYMONTH":
+			var err error
+			if months, err = ruleNumbers(value, 1, 12, false); err != nil {
+				return nil, fmt.Errorf("invalid BYMONTH %q", value)
+			}
+		case "BYMONTHDAY":
+			var err error
+			if monthDays, err = ruleNumbers(value, 1, 31, true); err != nil {
+				return nil, fmt.Errorf("invalid BYMONTHDAY %q", value)
+			}
+		case "WKST":
+		default:
+			return nil, fmt.Errorf("unsupported recurrence %q", rrule)
+		}
+	}
+	if freq != "YEARLY" {
+		return nil, fmt.Errorf("unsupported recurrence %q", rrule)
+	}
+	if months == nil {
+		if monthDays == nil {
+			months = []int{int(first.Month())}
+		} else {
+			months = []int{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12}
+		}
+	}
+	if monthDays == nil {
+		monthDays = []int{first.Day()}
+	}
+
+	years := maxYearlyOccurrences
+	if count > 0 || !until.IsZero() {
+		// COUNT ou UNTIL bornent la règle ; la borne des années évite une
+		// règle qui ne produit rien (31 février) de tourner sans fin
+		years = 1000
+	}
+	var out []time.Time
+	for y := 0; y < years; y++ {
+		var inYear []time.Time
+		for _, m := range months {
+			for _, d := range monthDays {
+				if o, ok := monthDay(first.Year()+y, time.Month(m), d, first.Location()); ok && !o.Before(first) {
+					inYear = append(inYear, o)
+				}
+			}
+		}
+		sort.Slice(inYear, func(i, j int) bool { return inYear[i].Before(inYear[j]) })
+		for i, o := range inYear {
+			if i > 0 && o.Equal(inYear[i-1]) {
+				continue
+			}
+			if !until.IsZero() && o.After(until) {
+				return out, nil
+			}
+			out = append(out, o)
+			if count > 0 && len(out) == count {
+				return out, nil
+			}
+		}
+	}
+	return out, nil
+}
+
+// ruleNumbers lit une liste "1,15,-1" de valeurs entre min et max, négatives
+// (comptées depuis la fin) si signed
+func ruleNumbers(value string, min, max int, signed bool) ([]int, error) {
+	var out []int
+	for _, v := range strings.Split(value, ",") {
+		n, err := strconv.Atoi(strings.TrimPrefix(v, "+"))
+		if err != nil {
+			return nil, err
+		}
+		abs := n
+		if signed && n < 0 {
+			abs = -n
+		}
+		if abs < min || abs > max {
+			return nil, fmt.Errorf("%d out of range", n)
+		}
+		out = append(out, n)
+	}
+	return out, nil
+}
+
+// monthDay renvoie le jour day (négatif : depuis la fin) du mois, faux si le
+// mois ne l'a pas
+func monthDay(year int, month time.Month, day int, loc *time.Location) (time.Time, bool) {
+	last := time.Date(year, month+1, 0, 0, 0, 0, 0, loc).Day()
+	if day < 0 {
+		day = last + day + 1
+	}
+	if day < 1 || day > last {
+		return time.Time{}, false
+	}
+	return time.Date(year, month, day, 0, 0, 0, 0, loc), true
+}
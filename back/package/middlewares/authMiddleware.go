@@ -95,3 +95,11 @@ func GetUserID(ctx context.Context) (string, error) {
 	}
 	return id, nil
 }
+
+func GetUserRole(ctx context.Context) (string, error) {
+	role, ok := ctx.Value(ContextUserERoleKey).(string)
+	if !ok {
+		return "", errors.New("role not found in context")
+	}
+	return role, nil
+}
@@ -0,0 +1,48 @@
+package payperiod
+
+import (
+	"errors"
+	"fmt"
+
+	dbmodels "github.com/epitech/timemanager/internal/models"
+	"gorm.io/gorm"
+)
+
+// ErrClosed est renvoyée par toute écriture d'un pointage tombant dans une
+// période de paie clôturée
+var ErrClosed = errors.New("pay period is closed: its entries are locked")
+
+// Clé du verrou consultatif de la clôture : la clôture le prend seule, les
+// écritures de pointages le partagent. Une écriture commencée avant la
+// clôture est donc comptée par elle, et une écriture suivante voit la période
+// clôturée, même en READ COMMITTED
+const lockKey int64 = 0x70617950657269 // "payPeri"
+
+// LockForClose prend le verrou exclusif jusqu'à la fin de la transaction ; à
+// appeler avant de compter les sessions de la période à clôturer
+func LockForClose(tx *gorm.DB) error {
+	return tx.Exec("SELECT pg_advisory_xact_lock(?)", lockKey).Error
+}
+
+// EnsureOpen prend le verrou partagé jusqu'à la fin de la transaction, puis
+// échoue avec ErrClosed si un des jours (YYYY-MM-DD) tombe dans une période
+// clôturée ; à appeler dans la transaction de l'écriture, avant celle-ci
+func EnsureOpen(tx *gorm.DB, days ...string) error {
+	if len(days) == 0 {
+		return nil
+	}
+	if err := tx.Exec("SELECT pg_advisory_xact_lock_shared(?)", lockKey).Error; err != nil {
+		return err
+	}
+	for _, day := range days {
+		var periods []dbmodels.PayPeriod
+		if err := tx.Where("status = ? AND start_date <= ? AND end_date >= ?", dbmodels.PayPeriodClosed, day, day).
+			Limit(1).Find(&periods).Error; err != nil {
+			return err
+		}
+		if len(periods) > 0 {
+			return fmt.Errorf("%w (%s to %s)", ErrClosed, periods[0].StartDate, periods[0].EndDate)
+		}
+	}
+	return nil
+}
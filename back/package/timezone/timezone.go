@@ -0,0 +1,55 @@
+package timezone
+
+import (
+	"fmt"
+	"time"
+
+	// la base IANA est embarquée : l'image Docker n'a pas forcément tzdata
+	_ "time/tzdata"
+)
+
+// Fuseau utilisé quand ni l'utilisateur ni son site n'en définit un
+var defaultLocation = time.Local
+
+// SetDefault change le fuseau par défaut ; une valeur vide garde l'heure du serveur
+func SetDefault(name string) error {
+	if name == "" {
+		return nil
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return fmt.Errorf("invalid time zone %q: %w", name, err)
+	}
+	defaultLocation = loc
+	return nil
+}
+
+func Default() *time.Location {
+	return defaultLocation
+}
+
+// Load valide un nom de fuseau IANA (ex : Europe/Paris) ; vide renvoie le fuseau par défaut
+func Load(name string) (*time.Location, error) {
+	if name == "" {
+		return defaultLocation, nil
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return nil, fmt.Errorf("invalid time zone %q: %w", name, err)
+	}
+	return loc, nil
+}
+
+// Resolve renvoie le premier fuseau valide de la liste (utilisateur, puis site),
+// ou le fuseau par défaut
+func Resolve(names ...string) *time.Location {
+	for _, name := range names {
+		if name == "" {
+			continue
+		}
+		if loc, err := time.LoadLocation(name); err == nil {
+			return loc
+		}
+	}
+	return defaultLocation
+}
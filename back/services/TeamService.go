@@ -2,6 +2,7 @@ package services
 
 import (
 	"github.com/epitech/timemanager/internal/graph/model"
+	"github.com/google/uuid"
 )
 
 // TeamRepository is the minimal contract used by TeamService.
@@ -19,6 +20,9 @@ type TeamRepository interface {
 
 type TeamService struct {
 	TeamRepo TeamRepository
+	// Rollups is refreshed for the users joining or leaving a team, whose
+	// schedule and holidays may change; optional.
+	Rollups *RollupService
 }
 
 func (s *TeamService) GetTeamUsers() []*model.TeamUser {
@@ -50,13 +54,43 @@ func (s *TeamService) GetTeams() ([]*model.Team, error) {
 }
 
 func (s *TeamService) AddUsersToTeam(input model.AddUsersToTeamInput) ([]*model.TeamUser, error) {
-	return s.TeamRepo.AddUsersToTeam(input)
+	added, err := s.TeamRepo.AddUsersToTeam(input)
+	if err != nil {
+		return nil, err
+	}
+	s.refreshRollups(input.UserIDs...)
+	return added, nil
 }
 
 func (s *TeamService) RemoveUserFromTeam(userID string, teamID string) (bool, error) {
-	return s.TeamRepo.RemoveUserFromTeam(userID, teamID)
+	ok, err := s.TeamRepo.RemoveUserFromTeam(userID, teamID)
+	if err != nil {
+		return false, err
+	}
+	s.refreshRollups(userID)
+	return ok, nil
 }
 
 func (s *TeamService) AddUserToTeam(id string, teamID string) (*model.TeamUser, error) {
-	return s.TeamRepo.AddUserToTeam(id, teamID)
+	added, err := s.TeamRepo.AddUserToTeam(id, teamID)
+	if err != nil {
+		return nil, err
+	}
+	s.refreshRollups(id)
+	return added, nil
+}
+
+// refreshRollups recomputes in the background every covered rollup of the
+// users.
+func (s *TeamService) refreshRollups(userIDs ...string) {
+	if s.Rollups == nil {
+		return
+	}
+	ids := make([]uuid.UUID, 0, len(userIDs))
+	for _, userID := range userIDs {
+		if uid, err := uuid.Parse(userID); err == nil {
+			ids = append(ids, uid)
+		}
+	}
+	s.Rollups.RefreshUsers(ids, "", "")
 }
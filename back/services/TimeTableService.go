@@ -1,19 +1,44 @@
 package services
 
 import (
+	"errors"
+	"strings"
 	"time"
 
 	"github.com/epitech/timemanager/internal/graph/model"
 	"github.com/google/uuid"
 )
 
+var errDepartureBeforeArrival = errors.New("departure must be after arrival")
+var errForbiddenCorrectionReview = errors.New("forbidden: only the team's manager or an admin can review this request")
+var errCorrectionDayMismatch = errors.New("the correction's day must be the corrected entry's day")
+
 type TimeTableService struct {
 	Repo TimeTableRepository
+	// Rollups is refreshed after every change of an entry; optional.
+	Rollups *RollupService
+	// Lateness rates again the arrivals of the changed days; optional.
+	Lateness *LatenessService
+	// Timesheets locks the entries of the submitted and approved weeks; optional.
+	Timesheets *TimesheetService
+	// PayPeriods locks the entries of the closed pay periods; optional.
+	PayPeriods *PayPeriodService
 }
 
 type TimeTableRepository interface {
 	GetTimeTableEntries() ([]*model.TimeTableEntry, error)
 	GetTimeTableEntriesFiltered(userID *uuid.UUID, teamID *uuid.UUID, from, to *time.Time) ([]*model.TimeTableEntry, error)
+	GetTimeTableEntryByID(id string) (*model.TimeTableEntry, error)
+	// HasOpenSession tells whether the user has a running session other than exceptID.
+	HasOpenSession(userID uuid.UUID, exceptID *string) (bool, error)
+	CreateTimeEntry(input model.CreateTimeEntryInput) (*model.TimeTableEntry, error)
+	UpdateTimeEntry(id string, input model.UpdateTimeEntryInput) (*model.TimeTableEntry, error)
+	DeleteTimeEntry(id string) (bool, error)
+	CreateTimeEntryCorrection(userID uuid.UUID, input model.RequestTimeEntryCorrectionInput) (*model.TimeEntryCorrection, error)
+	GetTimeEntryCorrection(id string) (*model.TimeEntryCorrection, error)
+	GetTimeEntryCorrections(status *model.CorrectionStatus, userID *uuid.UUID, managerID *uuid.UUID) ([]*model.TimeEntryCorrection, error)
+	IsManagerOfUser(managerID uuid.UUID, userID uuid.UUID) (bool, error)
+	ReviewTimeEntryCorrection(id string, reviewerID uuid.UUID, approve bool, comment *string) (*model.TimeEntryCorrection, error)
 }
 
 func NewTimeTableService(repo TimeTableRepository) *TimeTableService {
@@ -27,3 +52,244 @@ func (s *TimeTableService) GetTimeTableEntries() ([]*model.TimeTableEntry, error
 func (s *TimeTableService) GetTimeTableEntriesFiltered(userID *uuid.UUID, teamID *uuid.UUID, from, to *time.Time) ([]*model.TimeTableEntry, error) {
 	return s.Repo.GetTimeTableEntriesFiltered(userID, teamID, from, to)
 }
+
+// CreateTimeEntry adds an entry directly, without going through the correction workflow.
+func (s *TimeTableService) CreateTimeEntry(input model.CreateTimeEntryInput) (*model.TimeTableEntry, error) {
+	if err := validateEntryTimes(input.Arrival, input.Departure); err != nil {
+		return nil, err
+	}
+	if input.Departure != nil && !input.Departure.IsZero() {
+		input.Status = false
+	}
+	if err := s.ensureUnlocked(&model.TimeTableEntry{UserID: &model.User{ID: input.UserID}, Day: input.Day}); err != nil {
+		return nil, err
+	}
+	entry, err := s.Repo.CreateTimeEntry(input)
+	if err != nil {
+		return nil, err
+	}
+	s.refreshDays(entry)
+	return entry, nil
+}
+
+// UpdateTimeEntry edits an entry directly, without going through the correction workflow.
+func (s *TimeTableService) UpdateTimeEntry(id string, input model.UpdateTimeEntryInput) (*model.TimeTableEntry, error) {
+	var current *model.TimeTableEntry
+	if input.Arrival != nil || input.Departure != nil || s.refreshesDays() || s.locksEntries() {
+		var err error
+		if current, err = s.Repo.GetTimeTableEntryByID(id); err != nil {
+			return nil, err
+		}
+	}
+	if s.locksEntries() {
+		moved := *current
+		if input.UserID != nil {
+			moved.UserID = &model.User{ID: *input.UserID}
+		}
+		if input.Day != nil {
+			moved.Day = *input.Day
+		}
+		if err := s.ensureUnlocked(current, &moved); err != nil {
+			return nil, err
+		}
+	}
+	if input.Arrival != nil || input.Departure != nil {
+		arrival := current.Arrival
+		if input.Arrival != nil {
+			arrival = *input.Arrival
+		}
+		departure := current.Departure
+		if input.Departure != nil {
+			departure = input.Departure
+		}
+		if err := validateEntryTimes(arrival, departure); err != nil {
+			return nil, err
+		}
+	}
+	entry, err := s.Repo.UpdateTimeEntry(id, input)
+	if err != nil {
+		return nil, err
+	}
+	// the entry may have moved to another day
+	s.refreshDays(current, entry)
+	return entry, nil
+}
+
+func (s *TimeTableService) DeleteTimeEntry(id string) (bool, error) {
+	var current *model.TimeTableEntry
+	if s.refreshesDays() || s.locksEntries() {
+		var err error
+		if current, err = s.Repo.GetTimeTableEntryByID(id); err != nil {
+			return false, err
+		}
+	}
+	if err := s.ensureUnlocked(current); err != nil {
+		return false, err
+	}
+	deleted, err := s.Repo.DeleteTimeEntry(id)
+	if err != nil {
+		return false, err
+	}
+	s.refreshDays(current)
+	return deleted, nil
+}
+
+// locksEntries tells whether some entries may be locked, so that the current
+// entry must be read before changing it.
+func (s *TimeTableService) locksEntries() bool {
+	return s.Timesheets != nil || s.PayPeriods != nil
+}
+
+// ensureUnlocked fails when one of the entries falls in a closed pay period
+// or in an approved week.
+func (s *TimeTableService) ensureUnlocked(entries ...*model.TimeTableEntry) error {
+	for _, e := range entries {
+		if e == nil {
+			continue
+		}
+		if err := s.PayPeriods.EnsureOpen(e.Day); err != nil {
+			return err
+		}
+		if s.Timesheets == nil || e.UserID == nil {
+			continue
+		}
+		if err := s.Timesheets.EnsureWeekOpen(e.UserID.ID, e.Day); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// refreshesDays tells whether the days of the changed entries are
+// recomputed, so that the current entry must be read before changing it.
+func (s *TimeTableService) refreshesDays() bool {
+	return s.Rollups != nil || s.Lateness != nil
+}
+
+// refreshDays recomputes the rollups and rates again the first arrival of the
+// entries' days.
+func (s *TimeTableService) refreshDays(entries ...*model.TimeTableEntry) {
+	days := make(map[string][]string)
+	for _, e := range entries {
+		if e != nil && e.UserID != nil {
+			days[e.UserID.ID] = append(days[e.UserID.ID], e.Day)
+		}
+	}
+	for userID, list := range days {
+		s.Rollups.Refresh(userID, list...)
+		s.Lateness.Rederive(userID, list...)
+	}
+}
+
+// RequestCorrection records an employee's correction request; nothing is
+// applied to the entry until the team's manager approves it.
+func (s *TimeTableService) RequestCorrection(requesterID uuid.UUID, input model.RequestTimeEntryCorrectionInput) (*model.TimeEntryCorrection, error) {
+	input.Reason = strings.TrimSpace(input.Reason)
+	if input.Reason == "" {
+		return nil, errors.New("a reason is required")
+	}
+	if _, err := time.Parse(layoutISO, input.Day); err != nil {
+		return nil, errors.New("invalid day, expected YYYY-MM-DD")
+	}
+	if err := validateEntryTimes(input.ProposedArrival, input.ProposedDeparture); err != nil {
+		return nil, err
+	}
+	var entry *model.TimeTableEntry
+	if input.EntryID != nil && *input.EntryID != "" {
+		var err error
+		if entry, err = s.Repo.GetTimeTableEntryByID(*input.EntryID); err != nil {
+			return nil, err
+		}
+		if entry.UserID == nil || entry.UserID.ID != requesterID.String() {
+			return nil, errors.New("forbidden: you can only correct your own entries")
+		}
+		if entry.Day != input.Day {
+			return nil, errCorrectionDayMismatch
+		}
+	}
+	if err := s.ensureUnlocked(entry, &model.TimeTableEntry{UserID: &model.User{ID: requesterID.String()}, Day: input.Day}); err != nil {
+		return nil, err
+	}
+	return s.Repo.CreateTimeEntryCorrection(requesterID, input)
+}
+
+// GetCorrections lists the correction requests of a user, or of the members
+// of the teams managerID manages, as scoped by the AccessService; everyone's
+// without either.
+func (s *TimeTableService) GetCorrections(status *model.CorrectionStatus, userID *uuid.UUID, managerID *uuid.UUID) ([]*model.TimeEntryCorrection, error) {
+	return s.Repo.GetTimeEntryCorrections(status, userID, managerID)
+}
+
+// ReviewCorrection approves or rejects a pending request. Only the manager of
+// one of the requester's teams, or an admin, may review; nobody reviews their own.
+func (s *TimeTableService) ReviewCorrection(reviewerID uuid.UUID, reviewerRole string, id string, approve bool, comment *string) (*model.TimeEntryCorrection, error) {
+	correction, err := s.Repo.GetTimeEntryCorrection(id)
+	if err != nil {
+		return nil, err
+	}
+	if correction.Status != model.CorrectionStatusPending {
+		return nil, errors.New("correction request has already been reviewed")
+	}
+	if correction.UserID == nil {
+		return nil, errors.New("correction request has no user")
+	}
+	requesterID, err := uuid.Parse(correction.UserID.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	if reviewerRole != string(model.RoleAdmin) {
+		if requesterID == reviewerID {
+			return nil, errForbiddenCorrectionReview
+		}
+		isManager, err := s.Repo.IsManagerOfUser(reviewerID, requesterID)
+		if err != nil {
+			return nil, err
+		}
+		if !isManager {
+			return nil, errForbiddenCorrectionReview
+		}
+	}
+
+	var entry *model.TimeTableEntry
+	if approve && correction.EntryID != nil {
+		if entry, err = s.Repo.GetTimeTableEntryByID(*correction.EntryID); err != nil {
+			return nil, err
+		}
+		// the entry was moved since the request
+		if entry.Day != correction.Day {
+			return nil, errCorrectionDayMismatch
+		}
+	}
+	if approve {
+		if err := s.ensureUnlocked(entry, &model.TimeTableEntry{UserID: correction.UserID, Day: correction.Day}); err != nil {
+			return nil, err
+		}
+		// without a departure the corrected entry becomes the running session
+		if correction.ProposedDeparture == nil || correction.ProposedDeparture.IsZero() {
+			open, err := s.Repo.HasOpenSession(requesterID, correction.EntryID)
+			if err != nil {
+				return nil, err
+			}
+			if open {
+				return nil, errors.New("the user already has a running session: clock it out before approving a correction without departure")
+			}
+		}
+	}
+	reviewed, err := s.Repo.ReviewTimeEntryCorrection(id, reviewerID, approve, comment)
+	if err != nil {
+		return nil, err
+	}
+	if approve {
+		// the corrected day, and the entry's former one
+		s.refreshDays(entry, &model.TimeTableEntry{UserID: correction.UserID, Day: correction.Day})
+	}
+	return reviewed, nil
+}
+
+func validateEntryTimes(arrival time.Time, departure *time.Time) error {
+	if departure != nil && !departure.IsZero() && departure.Before(arrival) {
+		return errDepartureBeforeArrival
+	}
+	return nil
+}
@@ -0,0 +1,108 @@
+package services
+
+import (
+	"errors"
+
+	"github.com/epitech/timemanager/internal/graph/model"
+	"github.com/google/uuid"
+)
+
+var errForbiddenAccess = errors.New("forbidden: you don't have access")
+
+// AccessRepository is the minimal repository contract used by AccessService.
+type AccessRepository interface {
+	IsManagerOfUser(managerID uuid.UUID, userID uuid.UUID) (bool, error)
+	IsManagerOfTeam(managerID uuid.UUID, teamID uuid.UUID) (bool, error)
+}
+
+// AccessService decides which users' and teams' data a caller may read:
+// users their own, managers also the members of the teams they manage,
+// admins everyone's.
+type AccessService struct {
+	Repo AccessRepository
+}
+
+func NewAccessService(repo AccessRepository) *AccessService {
+	return &AccessService{Repo: repo}
+}
+
+// CanSeeUser fails unless the caller may read the user's data.
+func (s *AccessService) CanSeeUser(callerID uuid.UUID, callerRole string, userID uuid.UUID) error {
+	if userID == callerID || callerRole == string(model.RoleAdmin) {
+		return nil
+	}
+	if callerRole != string(model.RoleManager) {
+		return errForbiddenAccess
+	}
+	isManager, err := s.Repo.IsManagerOfUser(callerID, userID)
+	if err != nil {
+		return err
+	}
+	if !isManager {
+		return errForbiddenAccess
+	}
+	return nil
+}
+
+// CanSeeTeam fails unless the caller may read the whole team's data: admins
+// and the team's manager.
+func (s *AccessService) CanSeeTeam(callerID uuid.UUID, callerRole string, teamID uuid.UUID) error {
+	if callerRole == string(model.RoleAdmin) {
+		return nil
+	}
+	if callerRole != string(model.RoleManager) {
+		return errForbiddenAccess
+	}
+	isManager, err := s.Repo.IsManagerOfTeam(callerID, teamID)
+	if err != nil {
+		return err
+	}
+	if !isManager {
+		return errForbiddenAccess
+	}
+	return nil
+}
+
+// ScopeUser checks the requested user and returns the one to read, the
+// caller by default. Only admins read everyone at once, with a nil user.
+func (s *AccessService) ScopeUser(callerID uuid.UUID, callerRole string, userID *uuid.UUID) (*uuid.UUID, error) {
+	if userID == nil {
+		if callerRole == string(model.RoleAdmin) {
+			return nil, nil
+		}
+		return &callerID, nil
+	}
+	if err := s.CanSeeUser(callerID, callerRole, *userID); err != nil {
+		return nil, err
+	}
+	return userID, nil
+}
+
+// ScopeManagedUsers checks the user filter of a listing and returns it.
+// Without one, managers list the members of the teams they manage, returned
+// as managerID, admins everyone and users their own.
+func (s *AccessService) ScopeManagedUsers(callerID uuid.UUID, callerRole string, userID *uuid.UUID) (*uuid.UUID, *uuid.UUID, error) {
+	if userID == nil && callerRole == string(model.RoleManager) {
+		return nil, &callerID, nil
+	}
+	uid, err := s.ScopeUser(callerID, callerRole, userID)
+	return uid, nil, err
+}
+
+// ScopeUserOrTeam checks the user and team filters of a query and returns the
+// ones to apply. Without either, non-admins only read their own data.
+func (s *AccessService) ScopeUserOrTeam(callerID uuid.UUID, callerRole string, userID, teamID *uuid.UUID) (*uuid.UUID, *uuid.UUID, error) {
+	if teamID == nil {
+		uid, err := s.ScopeUser(callerID, callerRole, userID)
+		return uid, nil, err
+	}
+	if err := s.CanSeeTeam(callerID, callerRole, *teamID); err != nil {
+		return nil, nil, err
+	}
+	if userID != nil {
+		if err := s.CanSeeUser(callerID, callerRole, *userID); err != nil {
+			return nil, nil, err
+		}
+	}
+	return userID, teamID, nil
+}
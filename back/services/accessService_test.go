@@ -0,0 +1,112 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+type mockAccessRepo struct {
+	// managed: manager -> members, teams: manager -> teams
+	managed map[uuid.UUID][]uuid.UUID
+	teams   map[uuid.UUID][]uuid.UUID
+}
+
+func (m *mockAccessRepo) IsManagerOfUser(managerID uuid.UUID, userID uuid.UUID) (bool, error) {
+	for _, id := range m.managed[managerID] {
+		if id == userID {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (m *mockAccessRepo) IsManagerOfTeam(managerID uuid.UUID, teamID uuid.UUID) (bool, error) {
+	for _, id := range m.teams[managerID] {
+		if id == teamID {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func TestAccessScopeUser(t *testing.T) {
+	admin, manager, member, other := uuid.New(), uuid.New(), uuid.New(), uuid.New()
+	svc := NewAccessService(&mockAccessRepo{managed: map[uuid.UUID][]uuid.UUID{manager: {member}}})
+
+	got, err := svc.ScopeUser(admin, "ADMIN", nil)
+	assert.NoError(t, err)
+	assert.Nil(t, got, "admins read everyone")
+	got, err = svc.ScopeUser(member, "USER", nil)
+	assert.NoError(t, err)
+	assert.Equal(t, member, *got, "others default to themselves")
+	got, err = svc.ScopeUser(manager, "MANAGER", nil)
+	assert.NoError(t, err)
+	assert.Equal(t, manager, *got)
+
+	got, err = svc.ScopeUser(manager, "MANAGER", &member)
+	assert.NoError(t, err)
+	assert.Equal(t, member, *got)
+	_, err = svc.ScopeUser(manager, "MANAGER", &other)
+	assert.Error(t, err)
+	_, err = svc.ScopeUser(member, "USER", &other)
+	assert.Error(t, err)
+	_, err = svc.ScopeUser(admin, "ADMIN", &other)
+	assert.NoError(t, err)
+}
+
+func TestAccessScopeManagedUsers(t *testing.T) {
+	manager, member, other := uuid.New(), uuid.New(), uuid.New()
+	svc := NewAccessService(&mockAccessRepo{managed: map[uuid.UUID][]uuid.UUID{manager: {member}}})
+
+	uid, managerID, err := svc.ScopeManagedUsers(manager, "MANAGER", nil)
+	assert.NoError(t, err)
+	assert.Nil(t, uid)
+	assert.Equal(t, manager, *managerID, "managers list their teams' members")
+	uid, managerID, err = svc.ScopeManagedUsers(manager, "MANAGER", &manager)
+	assert.NoError(t, err)
+	assert.Equal(t, manager, *uid, "their own only when they ask for themselves")
+	assert.Nil(t, managerID)
+	_, _, err = svc.ScopeManagedUsers(manager, "MANAGER", &other)
+	assert.Error(t, err)
+
+	uid, managerID, err = svc.ScopeManagedUsers(member, "USER", nil)
+	assert.NoError(t, err)
+	assert.Equal(t, member, *uid)
+	assert.Nil(t, managerID)
+	_, _, err = svc.ScopeManagedUsers(member, "USER", &other)
+	assert.Error(t, err)
+
+	uid, managerID, err = svc.ScopeManagedUsers(other, "ADMIN", nil)
+	assert.NoError(t, err)
+	assert.Nil(t, uid)
+	assert.Nil(t, managerID)
+}
+
+func TestAccessScopeUserOrTeam(t *testing.T) {
+	manager, member, outsider := uuid.New(), uuid.New(), uuid.New()
+	team, otherTeam := uuid.New(), uuid.New()
+	svc := NewAccessService(&mockAccessRepo{
+		managed: map[uuid.UUID][]uuid.UUID{manager: {member}},
+		teams:   map[uuid.UUID][]uuid.UUID{manager: {team}},
+	})
+
+	uid, tid, err := svc.ScopeUserOrTeam(manager, "MANAGER", nil, &team)
+	assert.NoError(t, err)
+	assert.Nil(t, uid)
+	assert.Equal(t, team, *tid)
+	_, _, err = svc.ScopeUserOrTeam(manager, "MANAGER", nil, &otherTeam)
+	assert.Error(t, err, "only the teams they manage")
+	_, _, err = svc.ScopeUserOrTeam(manager, "MANAGER", &outsider, &team)
+	assert.Error(t, err)
+	_, _, err = svc.ScopeUserOrTeam(member, "USER", nil, &team)
+	assert.Error(t, err, "users can't read a whole team")
+	_, _, err = svc.ScopeUserOrTeam(outsider, "ADMIN", nil, &otherTeam)
+	assert.NoError(t, err)
+
+	uid, tid, err = svc.ScopeUserOrTeam(member, "USER", nil, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, member, *uid)
+	assert.Nil(t, tid)
+}
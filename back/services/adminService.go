@@ -2,6 +2,7 @@ package services
 
 import (
 	"github.com/epitech/timemanager/internal/graph/model"
+	"github.com/google/uuid"
 )
 
 type AdminRepo interface {
@@ -16,6 +17,9 @@ type AdminRepo interface {
 
 type AdminService struct {
 	AdminRepo AdminRepo
+	// Rollups is refreshed after a change of a user's time zone or site, and
+	// rebuilt with a new default timetable; optional.
+	Rollups *RollupService
 }
 
 func NewAdminService(repo AdminRepo) *AdminService {
@@ -27,7 +31,16 @@ func (s *AdminService) CreateUser(input model.CreateUserInput) (*model.User, err
 }
 
 func (s *AdminService) UpdateUser(id string, input model.UpdateUserInput) (*model.User, error) {
-	return s.AdminRepo.UpdateUser(id, input)
+	user, err := s.AdminRepo.UpdateUser(id, input)
+	if err != nil {
+		return nil, err
+	}
+	if input.TimeZone != nil || input.SiteID != nil {
+		if uid, err := uuid.Parse(user.ID); err == nil {
+			s.Rollups.RefreshUsers([]uuid.UUID{uid}, "", "")
+		}
+	}
+	return user, nil
 }
 
 func (s *AdminService) DeleteUser(id string) (bool, error) {
@@ -46,6 +59,13 @@ func (s *AdminService) SetRole(userID string, role model.Role) (*model.User, err
 	return s.AdminRepo.SetRole(userID, role)
 }
 
+// SetTimeTable replaces the default timetable, which applies to every day of
+// the users without a schedule: their rollups are all rebuilt.
 func (s *AdminService) SetTimeTable(start, end string) (*model.TimeTable, error) {
-	return s.AdminRepo.SetTimeTable(start, end)
+	timeTable, err := s.AdminRepo.SetTimeTable(start, end)
+	if err != nil {
+		return nil, err
+	}
+	s.Rollups.RebuildCovered()
+	return timeTable, nil
 }
@@ -3,6 +3,7 @@ package services
 import (
 	"github.com/epitech/timemanager/internal/graph/model"
 	"github.com/epitech/timemanager/package/middlewares"
+	"github.com/google/uuid"
 )
 
 type AuthRepository interface {
@@ -16,6 +17,8 @@ type AuthRepository interface {
 type AuthService struct {
 	AuthRepo AuthRepository
 	TokenGen func(email, id, role string) (string, error)
+	// Rollups is refreshed after a change of the user's time zone; optional.
+	Rollups *RollupService
 }
 
 func NewAuthService(repo AuthRepository) *AuthService {
@@ -50,7 +53,16 @@ func (s *AuthService) Me(email string) (*model.SignedUser, error) {
 }
 
 func (s *AuthService) UpdateProfile(email string, input model.UpdateProfileInput) (*model.User, error) {
-	return s.AuthRepo.UpdateProfile(email, input)
+	user, err := s.AuthRepo.UpdateProfile(email, input)
+	if err != nil {
+		return nil, err
+	}
+	if input.TimeZone != nil {
+		if uid, err := uuid.Parse(user.ID); err == nil {
+			s.Rollups.RefreshUsers([]uuid.UUID{uid}, "", "")
+		}
+	}
+	return user, nil
 }
 
 func (s *AuthService) DeleteProfile(email string) (bool, error) {
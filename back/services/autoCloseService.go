@@ -0,0 +1,185 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/epitech/timemanager/internal/graph/model"
+)
+
+// AutoClosePolicy decides how a forgotten clock-out is closed.
+type AutoClosePolicy string
+
+const (
+	// AutoCloseAtScheduledEnd closes the session at the scheduled end of day (TimeTable.Ends).
+	AutoCloseAtScheduledEnd AutoClosePolicy = "schedule"
+	// AutoCloseAfterMaxDuration closes the session MaxDuration after arrival.
+	AutoCloseAfterMaxDuration AutoClosePolicy = "max_duration"
+	// AutoCloseFlagForReview leaves the departure empty and flags the entry for correction.
+	AutoCloseFlagForReview AutoClosePolicy = "flag"
+)
+
+const (
+	defaultAutoCloseMaxDuration = 10 * time.Hour
+	defaultAutoCloseInterval    = 15 * time.Minute
+	// latestUTCOffset is the offset of the first time zone to start a day (UTC+14).
+	latestUTCOffset = 14 * time.Hour
+)
+
+// AutoCloseConfig holds the sweeper settings.
+type AutoCloseConfig struct {
+	Policy      AutoClosePolicy
+	MaxDuration time.Duration
+	Interval    time.Duration
+}
+
+// NewAutoCloseConfig builds a config from raw settings, zero values falling back to defaults.
+func NewAutoCloseConfig(policy string, maxHours int, intervalMinutes int) (AutoCloseConfig, error) {
+	cfg := AutoCloseConfig{
+		Policy:      AutoClosePolicy(strings.ToLower(strings.TrimSpace(policy))),
+		MaxDuration: defaultAutoCloseMaxDuration,
+		Interval:    defaultAutoCloseInterval,
+	}
+	switch cfg.Policy {
+	case "":
+		cfg.Policy = AutoCloseAtScheduledEnd
+	case AutoCloseAtScheduledEnd, AutoCloseAfterMaxDuration, AutoCloseFlagForReview:
+	default:
+		return cfg, fmt.Errorf("unknown auto-close policy %q", policy)
+	}
+	if maxHours > 0 {
+		cfg.MaxDuration = time.Duration(maxHours) * time.Hour
+	}
+	if intervalMinutes > 0 {
+		cfg.Interval = time.Duration(intervalMinutes) * time.Minute
+	}
+	return cfg, nil
+}
+
+// AutoCloseRepository is the minimal repository contract used by AutoCloseService.
+type AutoCloseRepository interface {
+	GetOpenEntriesBefore(day string) ([]*model.TimeTableEntry, error)
+	GetActiveTimeTable() (*model.TimeTable, error)
+	AutoCloseEntry(id string, departure time.Time) error
+	FlagEntryForReview(id string) error
+}
+
+// AutoCloseService closes the sessions left open on past days.
+type AutoCloseService struct {
+	Repo   AutoCloseRepository
+	Config AutoCloseConfig
+	// Rollups is refreshed for every closed session; optional.
+	Rollups *RollupService
+	// PayPeriods keeps the sessions of the closed pay periods untouched; optional.
+	PayPeriods *PayPeriodService
+}
+
+func NewAutoCloseService(repo AutoCloseRepository, cfg AutoCloseConfig) *AutoCloseService {
+	return &AutoCloseService{Repo: repo, Config: cfg}
+}
+
+// Run sweeps once immediately, then on every tick until ctx is cancelled.
+func (s *AutoCloseService) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.Config.Interval)
+	defer ticker.Stop()
+	for {
+		if closed, flagged, err := s.Sweep(time.Now()); err != nil {
+			log.Printf("auto-close sweep failed: %v", err)
+		} else if closed+flagged > 0 {
+			log.Printf("auto-close sweep: %d entries closed, %d flagged for review", closed, flagged)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Sweep applies the configured policy to every session still open from a day
+// past in its user's time zone and older than MaxDuration.
+func (s *AutoCloseService) Sweep(now time.Time) (closed int, flagged int, err error) {
+	// today where the day starts first: every past day of any zone is before it
+	entries, err := s.Repo.GetOpenEntriesBefore(now.UTC().Add(latestUTCOffset).Format(layoutISO))
+	if err != nil {
+		return 0, 0, err
+	}
+	if len(entries) == 0 {
+		return 0, 0, nil
+	}
+
+	var schedule *model.TimeTable
+	if s.Config.Policy == AutoCloseAtScheduledEnd {
+		if schedule, err = s.Repo.GetActiveTimeTable(); err != nil {
+			return 0, 0, err
+		}
+	}
+
+	for _, e := range entries {
+		// still today for the user
+		if e.Day >= now.In(entryLocation(e)).Format(layoutISO) {
+			continue
+		}
+		// A night shift started yesterday is still running, not forgotten
+		if now.Sub(e.Arrival) < s.Config.MaxDuration {
+			continue
+		}
+		// a closed pay period is left as is until an admin reopens it
+		if err := s.PayPeriods.EnsureOpen(e.Day); errors.Is(err, ErrPayPeriodClosed) {
+			continue
+		} else if err != nil {
+			return closed, flagged, err
+		}
+		departure, ok := s.closingTime(e, schedule, now)
+		if !ok {
+			if err := s.Repo.FlagEntryForReview(e.ID); err != nil {
+				return closed, flagged, err
+			}
+			flagged++
+			continue
+		}
+		if err := s.Repo.AutoCloseEntry(e.ID, departure); err != nil {
+			return closed, flagged, err
+		}
+		if e.UserID != nil {
+			s.Rollups.Refresh(e.UserID.ID, e.Day)
+		}
+		closed++
+	}
+	return closed, flagged, nil
+}
+
+// closingTime returns the departure to set on a forgotten session, or false
+// when the entry must be flagged for review instead.
+func (s *AutoCloseService) closingTime(e *model.TimeTableEntry, schedule *model.TimeTable, now time.Time) (time.Time, bool) {
+	switch s.Config.Policy {
+	case AutoCloseFlagForReview:
+		return time.Time{}, false
+	case AutoCloseAtScheduledEnd:
+		if schedule != nil {
+			arr := localArrival(e)
+			end := time.Date(arr.Year(), arr.Month(), arr.Day(), schedule.Ends.Hour(), schedule.Ends.Minute(), 0, 0, arr.Location())
+			if clockMinutes(schedule.Ends) < clockMinutes(schedule.Start) {
+				// night schedule: the shift ends the next morning
+				end = end.AddDate(0, 0, 1)
+			}
+			if end.After(arr) && end.Before(now) {
+				return end, true
+			}
+		}
+		// no usable schedule: fall back to the maximum duration
+	}
+	departure := e.Arrival.Add(s.Config.MaxDuration)
+	if departure.After(now) {
+		return time.Time{}, false
+	}
+	return departure, true
+}
+
+func clockMinutes(t time.Time) int {
+	return t.Hour()*60 + t.Minute()
+}
@@ -0,0 +1,137 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"github.com/epitech/timemanager/internal/graph/model"
+	"github.com/stretchr/testify/assert"
+)
+
+type mockAutoCloseRepo struct {
+	entries  []*model.TimeTableEntry
+	schedule *model.TimeTable
+	closed   map[string]time.Time
+	flagged  []string
+	err      error
+}
+
+func (m *mockAutoCloseRepo) GetOpenEntriesBefore(day string) ([]*model.TimeTableEntry, error) {
+	return m.entries, m.err
+}
+
+func (m *mockAutoCloseRepo) GetActiveTimeTable() (*model.TimeTable, error) {
+	return m.schedule, m.err
+}
+
+func (m *mockAutoCloseRepo) AutoCloseEntry(id string, departure time.Time) error {
+	if m.closed == nil {
+		m.closed = map[string]time.Time{}
+	}
+	m.closed[id] = departure
+	return m.err
+}
+
+func (m *mockAutoCloseRepo) FlagEntryForReview(id string) error {
+	m.flagged = append(m.flagged, id)
+	return m.err
+}
+
+func TestNewAutoCloseConfig(t *testing.T) {
+	cfg, err := NewAutoCloseConfig("", 0, 0)
+	assert.NoError(t, err)
+	assert.Equal(t, AutoCloseAtScheduledEnd, cfg.Policy)
+	assert.Equal(t, defaultAutoCloseMaxDuration, cfg.MaxDuration)
+	assert.Equal(t, defaultAutoCloseInterval, cfg.Interval)
+
+	cfg, err = NewAutoCloseConfig(" MAX_DURATION ", 8, 5)
+	assert.NoError(t, err)
+	assert.Equal(t, AutoCloseAfterMaxDuration, cfg.Policy)
+	assert.Equal(t, 8*time.Hour, cfg.MaxDuration)
+	assert.Equal(t, 5*time.Minute, cfg.Interval)
+
+	_, err = NewAutoCloseConfig("whenever", 0, 0)
+	assert.Error(t, err)
+}
+
+func TestAutoCloseSweepAtScheduledEnd(t *testing.T) {
+	arrival := time.Date(2024, 1, 10, 9, 0, 0, 0, time.UTC)
+	repo := &mockAutoCloseRepo{
+		entries:  []*model.TimeTableEntry{{ID: "e1", Day: "2024-01-10", Arrival: arrival, Status: true}},
+		schedule: &model.TimeTable{Ends: time.Date(0, 1, 1, 18, 0, 0, 0, time.UTC)},
+	}
+	svc := NewAutoCloseService(repo, AutoCloseConfig{Policy: AutoCloseAtScheduledEnd, MaxDuration: 10 * time.Hour})
+	closed, flagged, err := svc.Sweep(time.Date(2024, 1, 11, 8, 0, 0, 0, time.UTC))
+	assert.NoError(t, err)
+	assert.Equal(t, 1, closed)
+	assert.Equal(t, 0, flagged)
+	assert.Equal(t, time.Date(2024, 1, 10, 18, 0, 0, 0, time.UTC), repo.closed["e1"])
+}
+
+func TestAutoCloseSweepFallsBackToMaxDuration(t *testing.T) {
+	// arrived after the scheduled end, or no schedule at all
+	late := time.Date(2024, 1, 10, 19, 0, 0, 0, time.UTC)
+	repo := &mockAutoCloseRepo{
+		entries:  []*model.TimeTableEntry{{ID: "e1", Day: "2024-01-10", Arrival: late, Status: true}},
+		schedule: &model.TimeTable{Ends: time.Date(0, 1, 1, 18, 0, 0, 0, time.UTC)},
+	}
+	svc := NewAutoCloseService(repo, AutoCloseConfig{Policy: AutoCloseAtScheduledEnd, MaxDuration: 4 * time.Hour})
+	closed, _, err := svc.Sweep(time.Date(2024, 1, 11, 12, 0, 0, 0, time.UTC))
+	assert.NoError(t, err)
+	assert.Equal(t, 1, closed)
+	assert.Equal(t, late.Add(4*time.Hour), repo.closed["e1"])
+}
+
+func TestAutoCloseSweepFlagForReview(t *testing.T) {
+	arrival := time.Date(2024, 1, 10, 9, 0, 0, 0, time.UTC)
+	repo := &mockAutoCloseRepo{entries: []*model.TimeTableEntry{{ID: "e1", Arrival: arrival, Status: true}}}
+	svc := NewAutoCloseService(repo, AutoCloseConfig{Policy: AutoCloseFlagForReview, MaxDuration: time.Hour})
+	closed, flagged, err := svc.Sweep(time.Date(2024, 1, 11, 8, 0, 0, 0, time.UTC))
+	assert.NoError(t, err)
+	assert.Equal(t, 0, closed)
+	assert.Equal(t, 1, flagged)
+	assert.Equal(t, []string{"e1"}, repo.flagged)
+}
+
+func TestAutoCloseSweepSkipsRunningNightShift(t *testing.T) {
+	arrival := time.Date(2024, 1, 10, 22, 0, 0, 0, time.UTC)
+	repo := &mockAutoCloseRepo{entries: []*model.TimeTableEntry{{ID: "e1", Day: "2024-01-10", Arrival: arrival, Status: true}}}
+	svc := NewAutoCloseService(repo, AutoCloseConfig{Policy: AutoCloseFlagForReview, MaxDuration: 10 * time.Hour})
+	closed, flagged, err := svc.Sweep(time.Date(2024, 1, 11, 3, 0, 0, 0, time.UTC))
+	assert.NoError(t, err)
+	assert.Equal(t, 0, closed)
+	assert.Equal(t, 0, flagged)
+}
+
+func TestAutoCloseSweepNightScheduleEndsNextDay(t *testing.T) {
+	arrival := time.Date(2024, 1, 10, 22, 0, 0, 0, time.UTC)
+	repo := &mockAutoCloseRepo{
+		entries: []*model.TimeTableEntry{{ID: "e1", Day: "2024-01-10", Arrival: arrival, Status: true}},
+		schedule: &model.TimeTable{
+			Start: time.Date(0, 1, 1, 22, 0, 0, 0, time.UTC),
+			Ends:  time.Date(0, 1, 1, 6, 0, 0, 0, time.UTC),
+		},
+	}
+	svc := NewAutoCloseService(repo, AutoCloseConfig{Policy: AutoCloseAtScheduledEnd, MaxDuration: 10 * time.Hour})
+	closed, _, err := svc.Sweep(time.Date(2024, 1, 11, 12, 0, 0, 0, time.UTC))
+	assert.NoError(t, err)
+	assert.Equal(t, 1, closed)
+	assert.Equal(t, time.Date(2024, 1, 11, 6, 0, 0, 0, time.UTC), repo.closed["e1"])
+}
+
+func TestAutoCloseSweepWaitsForTheUsersDayToEnd(t *testing.T) {
+	losAngeles := "America/Los_Angeles"
+	u := &model.User{ID: "u1", TimeZone: &losAngeles}
+	// 08:00 in Los Angeles, already the 11th in UTC at the sweep
+	arrival := time.Date(2024, 1, 10, 16, 0, 0, 0, time.UTC)
+	repo := &mockAutoCloseRepo{entries: []*model.TimeTableEntry{{ID: "e1", UserID: u, Day: "2024-01-10", Arrival: arrival, Status: true}}}
+	svc := NewAutoCloseService(repo, AutoCloseConfig{Policy: AutoCloseFlagForReview, MaxDuration: 10 * time.Hour})
+
+	_, flagged, err := svc.Sweep(time.Date(2024, 1, 11, 3, 0, 0, 0, time.UTC))
+	assert.NoError(t, err)
+	assert.Equal(t, 0, flagged, "still the 10th in Los Angeles")
+
+	_, flagged, err = svc.Sweep(time.Date(2024, 1, 11, 9, 0, 0, 0, time.UTC))
+	assert.NoError(t, err)
+	assert.Equal(t, 1, flagged)
+}
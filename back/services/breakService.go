@@ -0,0 +1,46 @@
+package services
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/epitech/timemanager/internal/graph/model"
+)
+
+// BreakRepository is the minimal contract used by BreakService.
+type BreakRepository interface {
+	GetBreakTypes() ([]*model.BreakType, error)
+	CreateBreakType(input model.CreateBreakTypeInput) (*model.BreakType, error)
+	UpdateBreakType(id string, input model.UpdateBreakTypeInput) (*model.BreakType, error)
+}
+
+type BreakService struct {
+	Repo BreakRepository
+}
+
+func NewBreakService(repo BreakRepository) *BreakService {
+	return &BreakService{Repo: repo}
+}
+
+func (s *BreakService) GetBreakTypes() ([]*model.BreakType, error) {
+	return s.Repo.GetBreakTypes()
+}
+
+func (s *BreakService) CreateBreakType(input model.CreateBreakTypeInput) (*model.BreakType, error) {
+	input.Name = strings.TrimSpace(input.Name)
+	if input.Name == "" {
+		return nil, errors.New("break type name is required")
+	}
+	return s.Repo.CreateBreakType(input)
+}
+
+func (s *BreakService) UpdateBreakType(id string, input model.UpdateBreakTypeInput) (*model.BreakType, error) {
+	if input.Name != nil {
+		name := strings.TrimSpace(*input.Name)
+		if name == "" {
+			return nil, errors.New("break type name is required")
+		}
+		input.Name = &name
+	}
+	return s.Repo.UpdateBreakType(id, input)
+}
@@ -0,0 +1,65 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/epitech/timemanager/internal/graph/model"
+	"github.com/stretchr/testify/assert"
+)
+
+type mockBreakRepo struct {
+	types   []*model.BreakType
+	created *model.CreateBreakTypeInput
+	updated *model.UpdateBreakTypeInput
+	err     error
+}
+
+func (m *mockBreakRepo) GetBreakTypes() ([]*model.BreakType, error) { return m.types, m.err }
+
+func (m *mockBreakRepo) CreateBreakType(input model.CreateBreakTypeInput) (*model.BreakType, error) {
+	m.created = &input
+	return &model.BreakType{ID: "1", Name: input.Name, Paid: input.Paid, IsActive: true}, m.err
+}
+
+func (m *mockBreakRepo) UpdateBreakType(id string, input model.UpdateBreakTypeInput) (*model.BreakType, error) {
+	m.updated = &input
+	return &model.BreakType{ID: id}, m.err
+}
+
+func TestBreakServiceCreateBreakTypeTrimsName(t *testing.T) {
+	repo := &mockBreakRepo{}
+	svc := NewBreakService(repo)
+	got, err := svc.CreateBreakType(model.CreateBreakTypeInput{Name: "  Lunch ", Paid: false})
+	assert.NoError(t, err)
+	assert.Equal(t, "Lunch", got.Name)
+	assert.False(t, got.Paid)
+}
+
+func TestBreakServiceCreateBreakTypeRequiresName(t *testing.T) {
+	repo := &mockBreakRepo{}
+	svc := NewBreakService(repo)
+	_, err := svc.CreateBreakType(model.CreateBreakTypeInput{Name: "   "})
+	assert.Error(t, err)
+	assert.Nil(t, repo.created)
+}
+
+func TestBreakServiceUpdateBreakTypeRejectsEmptyName(t *testing.T) {
+	repo := &mockBreakRepo{}
+	svc := NewBreakService(repo)
+	empty := ""
+	_, err := svc.UpdateBreakType("1", model.UpdateBreakTypeInput{Name: &empty})
+	assert.Error(t, err)
+	assert.Nil(t, repo.updated)
+
+	paid := true
+	_, err = svc.UpdateBreakType("1", model.UpdateBreakTypeInput{Paid: &paid})
+	assert.NoError(t, err)
+	assert.NotNil(t, repo.updated)
+}
+
+func TestBreakServiceGetBreakTypes(t *testing.T) {
+	repo := &mockBreakRepo{types: []*model.BreakType{{ID: "1"}, {ID: "2"}}}
+	got, err := NewBreakService(repo).GetBreakTypes()
+	assert.NoError(t, err)
+	assert.Len(t, got, 2)
+}
@@ -0,0 +1,318 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/epitech/timemanager/internal/graph/model"
+)
+
+// ComplianceRepository is the minimal repository contract used by ComplianceService.
+type ComplianceRepository interface {
+	GetComplianceRules() ([]*model.ComplianceRule, error)
+	SaveComplianceRule(rule *model.ComplianceRule) (*model.ComplianceRule, error)
+}
+
+type ComplianceService struct {
+	Repo ComplianceRepository
+}
+
+func NewComplianceService(repo ComplianceRepository) *ComplianceService {
+	return &ComplianceService{Repo: repo}
+}
+
+func (s *ComplianceService) GetComplianceRules() ([]*model.ComplianceRule, error) {
+	return s.Repo.GetComplianceRules()
+}
+
+// UpdateComplianceRule changes the given fields of a rule.
+func (s *ComplianceService) UpdateComplianceRule(code model.ComplianceRuleCode, input model.ComplianceRuleInput) (*model.ComplianceRule, error) {
+	if !code.IsValid() {
+		return nil, errors.New("invalid compliance rule")
+	}
+	rules, err := s.Repo.GetComplianceRules()
+	if err != nil {
+		return nil, err
+	}
+	var rule *model.ComplianceRule
+	for _, r := range rules {
+		if r.Code == code {
+			copied := *r
+			rule = &copied
+		}
+	}
+	if rule == nil {
+		return nil, errors.New("compliance rule not found")
+	}
+
+	if input.Enabled != nil {
+		rule.Enabled = *input.Enabled
+	}
+	if input.Severity != nil {
+		if !input.Severity.IsValid() {
+			return nil, errors.New("invalid severity")
+		}
+		rule.Severity = *input.Severity
+	}
+	if input.LimitMinutes != nil {
+		if *input.LimitMinutes <= 0 {
+			return nil, errors.New("limitMinutes must be positive")
+		}
+		rule.LimitMinutes = *input.LimitMinutes
+	}
+	if input.BreakMinutes != nil {
+		if code != model.ComplianceRuleCodeMandatoryBreak {
+			return nil, errors.New("breakMinutes only applies to the mandatory break")
+		}
+		if *input.BreakMinutes <= 0 {
+			return nil, errors.New("breakMinutes must be positive")
+		}
+		rule.BreakMinutes = input.BreakMinutes
+	}
+	if input.Weeks != nil {
+		if code != model.ComplianceRuleCodeAvgWeeklyHours {
+			return nil, errors.New("weeks only applies to the weekly average")
+		}
+		if *input.Weeks < 1 || *input.Weeks > 52 {
+			return nil, errors.New("weeks must be between 1 and 52")
+		}
+		rule.Weeks = input.Weeks
+	}
+	return s.Repo.SaveComplianceRule(rule)
+}
+
+// shift is a user's work of one day, from the first arrival to the last
+// departure, with the longest pause taken in between.
+type shift struct {
+	day     string
+	start   time.Time
+	end     time.Time
+	closed  bool
+	longest time.Duration
+}
+
+// checkCompliance lists each breach of the enabled rules, in date order.
+// The weekly average is only checked on weeks preceded by enough weeks of
+// entries of the same user.
+func checkCompliance(rules []*model.ComplianceRule, entries []*model.TimeTableEntry, now time.Time, midnight MidnightRule) []*model.ComplianceViolation {
+	perUserDay, users := sumByUserDay(entries, now, midnight)
+	shifts := userShifts(entries, now)
+
+	weekly := make(map[string]map[string]int) // user -> monday -> minutes
+	firstMonday := make(map[string]string)    // user -> first week with entries
+	for key, minutes := range perUserDay {
+		dt, err := time.Parse(layoutISO, key.day)
+		if err != nil {
+			continue
+		}
+		monday := mondayOf(dt).Format(layoutISO)
+		if weekly[key.userID] == nil {
+			weekly[key.userID] = make(map[string]int)
+		}
+		weekly[key.userID][monday] += minutes
+		if first, ok := firstMonday[key.userID]; !ok || monday < first {
+			firstMonday[key.userID] = monday
+		}
+	}
+
+	violations := make([]*model.ComplianceViolation, 0)
+	add := func(rule *model.ComplianceRule, userID, date string, actual int, detail string) {
+		name := ""
+		if u := users[userID]; u != nil {
+			name = u.FirstName + " " + u.LastName
+		}
+		violations = append(violations, &model.ComplianceViolation{
+			Rule:          rule.Code,
+			Severity:      rule.Severity,
+			UserID:        userID,
+			UserName:      name,
+			Date:          date,
+			Detail:        detail,
+			ActualMinutes: int32(actual),
+			LimitMinutes:  rule.LimitMinutes,
+		})
+	}
+
+	for _, rule := range rules {
+		if rule == nil || !rule.Enabled {
+			continue
+		}
+		limit := int(rule.LimitMinutes)
+		switch rule.Code {
+		case model.ComplianceRuleCodeMaxDailyHours:
+			for key, minutes := range perUserDay {
+				if minutes > limit {
+					add(rule, key.userID, key.day, minutes, fmt.Sprintf("%s worked, %s allowed", hoursMinutes(minutes), hoursMinutes(limit)))
+				}
+			}
+		case model.ComplianceRuleCodeMandatoryBreak:
+			breakMinutes := 0
+			if rule.BreakMinutes != nil {
+				breakMinutes = int(*rule.BreakMinutes)
+			}
+			for userID, days := range shifts {
+				for _, sh := range days {
+					minutes := perUserDay[userDay{userID: userID, day: sh.day}]
+					if minutes > limit && int(sh.longest.Minutes()) < breakMinutes {
+						add(rule, userID, sh.day, minutes, fmt.Sprintf("%s worked with a longest break of %s, %s due after %s",
+							hoursMinutes(minutes), hoursMinutes(int(sh.longest.Minutes())), hoursMinutes(breakMinutes), hoursMinutes(limit)))
+					}
+				}
+			}
+		case model.ComplianceRuleCodeMinDailyRest:
+			for userID, days := range shifts {
+				for i := 1; i < len(days); i++ {
+					prev, next := days[i-1], days[i]
+					if !prev.closed {
+						continue
+					}
+					rest := int(next.start.Sub(prev.end).Minutes())
+					if rest >= 0 && rest < limit {
+						add(rule, userID, next.day, rest, fmt.Sprintf("%s of rest since %s, %s required",
+							hoursMinutes(rest), prev.end.Format("2006-01-02 15:04"), hoursMinutes(limit)))
+					}
+				}
+			}
+		case model.ComplianceRuleCodeMaxWeeklyHours:
+			for userID, weeks := range weekly {
+				for monday, minutes := range weeks {
+					if minutes > limit {
+						add(rule, userID, monday, minutes, fmt.Sprintf("%s worked in the week, %s allowed", hoursMinutes(minutes), hoursMinutes(limit)))
+					}
+				}
+			}
+		case model.ComplianceRuleCodeAvgWeeklyHours:
+			span := 12
+			if rule.Weeks != nil {
+				span = int(*rule.Weeks)
+			}
+			for userID, weeks := range weekly {
+				for monday := range weeks {
+					end, _ := time.Parse(layoutISO, monday)
+					start := end.AddDate(0, 0, -7*(span-1))
+					if start.Format(layoutISO) < firstMonday[userID] {
+						continue
+					}
+					total := 0
+					for w := 0; w < span; w++ {
+						total += weeks[start.AddDate(0, 0, 7*w).Format(layoutISO)]
+					}
+					if avg := total / span; avg > limit {
+						add(rule, userID, monday, avg, fmt.Sprintf("%s a week on average over %d weeks, %s allowed", hoursMinutes(avg), span, hoursMinutes(limit)))
+					}
+				}
+			}
+		}
+	}
+
+	sort.Slice(violations, func(i, j int) bool {
+		a, b := violations[i], violations[j]
+		if a.Date != b.Date {
+			return a.Date < b.Date
+		}
+		if a.UserID != b.UserID {
+			return a.UserID < b.UserID
+		}
+		return a.Rule < b.Rule
+	})
+	return violations
+}
+
+// complianceHistory holds the sessions the statutory rules read around a
+// window: the rest of its edge weeks, the day before for the daily rest and
+// the weeks the weekly average looks back on.
+type complianceHistory struct {
+	first, last string // the window
+	entries     []*model.TimeTableEntry
+}
+
+// complianceSpan returns the days the enabled rules read to check the window
+// from start to end.
+func complianceSpan(rules []*model.ComplianceRule, start, end time.Time) (time.Time, time.Time) {
+	weeks := 0
+	for _, rule := range rules {
+		if rule == nil || !rule.Enabled {
+			continue
+		}
+		weeks = max(weeks, 1)
+		if rule.Code == model.ComplianceRuleCodeAvgWeeklyHours {
+			span := 12
+			if rule.Weeks != nil {
+				span = int(*rule.Weeks)
+			}
+			weeks = max(weeks, span)
+		}
+	}
+	if weeks == 0 {
+		return start, end
+	}
+	return mondayOf(start).AddDate(0, 0, -7*(weeks-1)-1), mondayOf(end).AddDate(0, 0, 6)
+}
+
+// violationsWithin keeps the breaches on a day of the window, or on a week
+// overlapping it for the weekly rules. Empty bounds keep everything.
+func violationsWithin(violations []*model.ComplianceViolation, first, last string) []*model.ComplianceViolation {
+	if first == "" && last == "" {
+		return violations
+	}
+	out := make([]*model.ComplianceViolation, 0, len(violations))
+	for _, v := range violations {
+		from, to := v.Date, v.Date
+		if v.Rule == model.ComplianceRuleCodeMaxWeeklyHours || v.Rule == model.ComplianceRuleCodeAvgWeeklyHours {
+			if monday, err := time.Parse(layoutISO, v.Date); err == nil {
+				to = monday.AddDate(0, 0, 6).Format(layoutISO)
+			}
+		}
+		if (first == "" || to >= first) && (last == "" || from <= last) {
+			out = append(out, v)
+		}
+	}
+	return out
+}
+
+// userShifts groups the sessions of each user per day, days in order.
+func userShifts(entries []*model.TimeTableEntry, now time.Time) map[string][]*shift {
+	sessions := make(map[userDay][]*model.TimeTableEntry)
+	for _, e := range entries {
+		if e.UserID == nil {
+			continue
+		}
+		key := userDay{userID: e.UserID.ID, day: e.Day}
+		sessions[key] = append(sessions[key], e)
+	}
+
+	out := make(map[string][]*shift)
+	for key, list := range sessions {
+		sort.Slice(list, func(i, j int) bool { return list[i].Arrival.Before(list[j].Arrival) })
+		sh := &shift{day: key.day, start: list[0].Arrival, closed: true}
+		for i, e := range list {
+			if e.Departure == nil || e.Departure.IsZero() {
+				sh.closed = false
+			}
+			end := sessionEnd(e, now)
+			if end.After(sh.end) {
+				sh.end = end
+			}
+			if i > 0 {
+				sh.longest = max(sh.longest, e.Arrival.Sub(sessionEnd(list[i-1], now)))
+			}
+			for _, b := range e.Breaks {
+				if b != nil && b.EndedAt != nil {
+					sh.longest = max(sh.longest, b.EndedAt.Sub(b.StartedAt))
+				}
+			}
+		}
+		out[key.userID] = append(out[key.userID], sh)
+	}
+	for _, days := range out {
+		sort.Slice(days, func(i, j int) bool { return days[i].start.Before(days[j].start) })
+	}
+	return out
+}
+
+// hoursMinutes formats minutes as "9h05".
+func hoursMinutes(minutes int) string {
+	return fmt.Sprintf("%dh%02d", minutes/60, minutes%60)
+}
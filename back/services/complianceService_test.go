@@ -0,0 +1,246 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"github.com/epitech/timemanager/internal/graph/model"
+	"github.com/stretchr/testify/assert"
+)
+
+type mockComplianceRepo struct {
+	rules []*model.ComplianceRule
+	saved *model.ComplianceRule
+}
+
+func (m *mockComplianceRepo) GetComplianceRules() ([]*model.ComplianceRule, error) {
+	return m.rules, nil
+}
+
+func (m *mockComplianceRepo) SaveComplianceRule(rule *model.ComplianceRule) (*model.ComplianceRule, error) {
+	m.saved = rule
+	return rule, nil
+}
+
+func statutoryRules() []*model.ComplianceRule {
+	return []*model.ComplianceRule{
+		{Code: model.ComplianceRuleCodeMinDailyRest, Enabled: true, Severity: model.ComplianceSeverityHigh, LimitMinutes: 11 * 60},
+		{Code: model.ComplianceRuleCodeMaxWeeklyHours, Enabled: true, Severity: model.ComplianceSeverityHigh, LimitMinutes: 48 * 60},
+		{Code: model.ComplianceRuleCodeAvgWeeklyHours, Enabled: true, Severity: model.ComplianceSeverityMedium, LimitMinutes: 44 * 60, Weeks: int32Ptr(2)},
+		{Code: model.ComplianceRuleCodeMandatoryBreak, Enabled: true, Severity: model.ComplianceSeverityMedium, LimitMinutes: 6 * 60, BreakMinutes: int32Ptr(20)},
+		{Code: model.ComplianceRuleCodeMaxDailyHours, Enabled: true, Severity: model.ComplianceSeverityHigh, LimitMinutes: 10 * 60},
+	}
+}
+
+func shiftEntry(u *model.User, day string, from, to string) *model.TimeTableEntry {
+	arr, _ := time.Parse("2006-01-02 15:04", day+" "+from)
+	dep, _ := time.Parse("2006-01-02 15:04", day+" "+to)
+	return &model.TimeTableEntry{UserID: u, Day: day, Arrival: arr, Departure: &dep}
+}
+
+func TestUpdateComplianceRule(t *testing.T) {
+	repo := &mockComplianceRepo{rules: statutoryRules()}
+	svc := NewComplianceService(repo)
+
+	_, err := svc.UpdateComplianceRule(model.ComplianceRuleCodeMaxDailyHours, model.ComplianceRuleInput{BreakMinutes: int32Ptr(30)})
+	assert.Error(t, err, "break on a rule without break")
+	_, err = svc.UpdateComplianceRule(model.ComplianceRuleCodeMaxDailyHours, model.ComplianceRuleInput{LimitMinutes: int32Ptr(0)})
+	assert.Error(t, err)
+	_, err = svc.UpdateComplianceRule("UNKNOWN", model.ComplianceRuleInput{})
+	assert.Error(t, err)
+
+	low := model.ComplianceSeverityLow
+	got, err := svc.UpdateComplianceRule(model.ComplianceRuleCodeMaxDailyHours, model.ComplianceRuleInput{Severity: &low, LimitMinutes: int32Ptr(9 * 60)})
+	assert.NoError(t, err)
+	assert.Equal(t, model.ComplianceSeverityLow, got.Severity)
+	assert.Equal(t, int32(540), got.LimitMinutes)
+	assert.True(t, got.Enabled)
+	assert.Equal(t, model.ComplianceSeverityHigh, repo.rules[4].Severity, "the stored rule is left untouched")
+}
+
+func TestComplianceListsEachViolation(t *testing.T) {
+	night := &model.User{ID: "night", FirstName: "Nina", LastName: "Late"}
+	busy := &model.User{ID: "busy", FirstName: "Bob", LastName: "Busy"}
+	entries := []*model.TimeTableEntry{
+		// 11h without a break, then only 10h of rest
+		shiftEntry(night, "2024-01-08", "08:00", "19:00"),
+		// 6h30 with a 30 minute pause between two sessions
+		shiftEntry(night, "2024-01-09", "05:00", "08:00"),
+		shiftEntry(night, "2024-01-09", "08:30", "12:00"),
+	}
+	// 50h then 40h with lunch breaks: above 48h the first week, 45h on
+	// average over both
+	withLunch := func(e *model.TimeTableEntry) *model.TimeTableEntry {
+		lunch := e.Arrival.Add(4 * time.Hour)
+		lunchEnd := lunch.Add(time.Hour)
+		e.Breaks = []*model.Break{{StartedAt: lunch, EndedAt: &lunchEnd, Paid: true}}
+		return e
+	}
+	for _, day := range []string{"2024-01-15", "2024-01-16", "2024-01-17", "2024-01-18", "2024-01-19"} {
+		next, _ := time.Parse(layoutISO, day)
+		entries = append(entries,
+			withLunch(shiftEntry(busy, day, "08:00", "18:00")),
+			withLunch(shiftEntry(busy, next.AddDate(0, 0, 7).Format(layoutISO), "08:00", "16:00")))
+	}
+
+	svc := NewKpiService(&mockKpiRepo{})
+	svc.Compliance = NewComplianceService(&mockComplianceRepo{rules: statutoryRules()})
+	got := svc.computeComplianceMetrics(entries, nil, nil, model.KpiGranularityWeek)
+
+	type key struct {
+		rule model.ComplianceRuleCode
+		user string
+		date string
+	}
+	found := map[key]*model.ComplianceViolation{}
+	for _, v := range got.Violations {
+		found[key{v.Rule, v.UserID, v.Date}] = v
+	}
+	assert.Len(t, got.Violations, 5)
+	assert.Equal(t, int32(660), found[key{model.ComplianceRuleCodeMaxDailyHours, "night", "2024-01-08"}].ActualMinutes)
+	assert.NotNil(t, found[key{model.ComplianceRuleCodeMandatoryBreak, "night", "2024-01-08"}])
+	rest := found[key{model.ComplianceRuleCodeMinDailyRest, "night", "2024-01-09"}]
+	assert.Equal(t, int32(600), rest.ActualMinutes)
+	assert.Equal(t, "Nina Late", rest.UserName)
+	assert.Equal(t, model.ComplianceSeverityHigh, rest.Severity)
+	assert.Equal(t, int32(3000), found[key{model.ComplianceRuleCodeMaxWeeklyHours, "busy", "2024-01-15"}].ActualMinutes)
+	assert.Equal(t, int32(2700), found[key{model.ComplianceRuleCodeAvgWeeklyHours, "busy", "2024-01-22"}].ActualMinutes)
+
+	affected := map[string]int32{}
+	for _, a := range got.Anomalies {
+		affected[a.Type] = a.AffectedUsers
+	}
+	assert.Equal(t, int32(1), affected["min_daily_rest"])
+	assert.Equal(t, int32(1), affected["max_weekly_hours"])
+	assert.Equal(t, int32(0), affected["missing_clockout"])
+	assert.Equal(t, int32(2), got.UsersWithIssues)
+	assert.Equal(t, int32(5), got.AnomaliesCount)
+}
+
+func TestComplianceReportsALongDayOnce(t *testing.T) {
+	u := &model.User{ID: "long"}
+	entries := []*model.TimeTableEntry{shiftEntry(u, "2024-01-08", "06:00", "19:00")}
+	svc := NewKpiService(&mockKpiRepo{})
+	svc.Compliance = NewComplianceService(&mockComplianceRepo{rules: []*model.ComplianceRule{
+		{Code: model.ComplianceRuleCodeMaxDailyHours, Enabled: true, Severity: model.ComplianceSeverityHigh, LimitMinutes: 10 * 60},
+	}})
+
+	counts := map[string]int32{}
+	got := svc.computeComplianceMetrics(entries, nil, nil, model.KpiGranularityWeek)
+	for _, a := range got.Anomalies {
+		counts[a.Type] = a.Count
+	}
+	assert.Equal(t, int32(1), counts["max_daily_hours"])
+	assert.NotContains(t, counts, "excessive_hours", "the daily maximum already reports the day")
+	assert.Equal(t, int32(1), got.AnomaliesCount)
+
+	// without the statutory rule, the 12h heuristic still applies
+	svc.Compliance = nil
+	counts = map[string]int32{}
+	got = svc.computeComplianceMetrics(entries, nil, nil, model.KpiGranularityWeek)
+	for _, a := range got.Anomalies {
+		counts[a.Type] = a.Count
+	}
+	assert.Equal(t, int32(1), counts["excessive_hours"])
+}
+
+func TestAverageWeeklyHoursWaitsForEachUsersHistory(t *testing.T) {
+	early := &model.User{ID: "early"}
+	late := &model.User{ID: "late"}
+	entries := []*model.TimeTableEntry{shiftEntry(early, "2024-01-01", "09:00", "17:00")}
+	// the late user's first week is long, with no earlier week to average it with
+	for _, day := range []string{"2024-01-15", "2024-01-16", "2024-01-17", "2024-01-18", "2024-01-19"} {
+		entries = append(entries, shiftEntry(late, day, "06:00", "20:00"))
+	}
+	rules := []*model.ComplianceRule{
+		{Code: model.ComplianceRuleCodeAvgWeeklyHours, Enabled: true, Severity: model.ComplianceSeverityMedium, LimitMinutes: 30 * 60, Weeks: int32Ptr(2)},
+	}
+
+	assert.Empty(t, checkCompliance(rules, entries, time.Now(), MidnightStartDay))
+
+	// a second long week is averaged with the first one
+	for _, day := range []string{"2024-01-22", "2024-01-23", "2024-01-24", "2024-01-25", "2024-01-26"} {
+		entries = append(entries, shiftEntry(late, day, "06:00", "20:00"))
+	}
+	got := checkCompliance(rules, entries, time.Now(), MidnightStartDay)
+	assert.Len(t, got, 1)
+	assert.Equal(t, "2024-01-22", got[0].Date)
+}
+
+func TestComplianceReadsTheEdgeWeeksOfAWindowStartingMidWeek(t *testing.T) {
+	u := &model.User{ID: "edge"}
+	rules := []*model.ComplianceRule{
+		{Code: model.ComplianceRuleCodeMinDailyRest, Enabled: true, Severity: model.ComplianceSeverityHigh, LimitMinutes: 11 * 60},
+		{Code: model.ComplianceRuleCodeMaxWeeklyHours, Enabled: true, Severity: model.ComplianceSeverityHigh, LimitMinutes: 48 * 60},
+	}
+	start := time.Date(2024, 1, 10, 0, 0, 0, 0, time.UTC) // a Wednesday
+	end := time.Date(2024, 1, 21, 0, 0, 0, 0, time.UTC)
+	from, to := complianceSpan(rules, start, end)
+	assert.Equal(t, "2024-01-07", from.Format(layoutISO), "the whole first week, and the day before")
+	assert.Equal(t, "2024-01-21", to.Format(layoutISO))
+
+	history := &complianceHistory{first: "2024-01-10", last: "2024-01-21", entries: []*model.TimeTableEntry{
+		shiftEntry(u, "2024-01-08", "11:00", "23:00"),
+		shiftEntry(u, "2024-01-09", "08:00", "22:00"), // short rest, before the window
+	}}
+	entries := []*model.TimeTableEntry{
+		shiftEntry(u, "2024-01-10", "06:00", "16:00"), // short rest across the window start
+		shiftEntry(u, "2024-01-11", "08:00", "18:00"),
+		shiftEntry(u, "2024-01-12", "08:00", "18:00"),
+	}
+	svc := NewKpiService(&mockKpiRepo{})
+	svc.Compliance = NewComplianceService(&mockComplianceRepo{rules: rules})
+
+	got := svc.computeComplianceMetrics(entries, history, nil, model.KpiGranularityWeek)
+	if assert.Len(t, got.Violations, 2) {
+		assert.Equal(t, model.ComplianceRuleCodeMaxWeeklyHours, got.Violations[0].Rule)
+		assert.Equal(t, "2024-01-08", got.Violations[0].Date)
+		assert.Equal(t, int32(56*60), got.Violations[0].ActualMinutes, "the whole week is counted")
+		assert.Equal(t, model.ComplianceRuleCodeMinDailyRest, got.Violations[1].Rule)
+		assert.Equal(t, "2024-01-10", got.Violations[1].Date)
+		assert.Equal(t, int32(8*60), got.Violations[1].ActualMinutes)
+	}
+	assert.Equal(t, 1.0, got.ComplianceRate)
+	assert.Equal(t, int32(2), got.AnomaliesCount, "the sessions before the window are not counted themselves")
+
+	// the window's sessions alone breach nothing
+	assert.Empty(t, svc.computeComplianceMetrics(entries, nil, nil, model.KpiGranularityWeek).Violations)
+}
+
+func TestComplianceAveragesTheWeeksBeforeAShortWindow(t *testing.T) {
+	u := &model.User{ID: "steady"}
+	rules := []*model.ComplianceRule{
+		{Code: model.ComplianceRuleCodeAvgWeeklyHours, Enabled: true, Severity: model.ComplianceSeverityMedium, LimitMinutes: 44 * 60},
+	}
+	start := time.Date(2024, 3, 4, 0, 0, 0, 0, time.UTC)
+	end := time.Date(2024, 3, 31, 0, 0, 0, 0, time.UTC)
+	from, to := complianceSpan(rules, start, end)
+	assert.Equal(t, "2023-12-17", from.Format(layoutISO), "the 11 weeks before the window, and the day before")
+	assert.Equal(t, "2024-03-31", to.Format(layoutISO))
+
+	// 50h a week: the 11 weeks before the window, and the 4 within
+	var before, within []*model.TimeTableEntry
+	for monday := from.AddDate(0, 0, 1); !monday.After(end); monday = monday.AddDate(0, 0, 7) {
+		for d := 0; d < 5; d++ {
+			e := shiftEntry(u, monday.AddDate(0, 0, d).Format(layoutISO), "08:00", "18:00")
+			if monday.Before(start) {
+				before = append(before, e)
+			} else {
+				within = append(within, e)
+			}
+		}
+	}
+	svc := NewKpiService(&mockKpiRepo{})
+	svc.Compliance = NewComplianceService(&mockComplianceRepo{rules: rules})
+
+	assert.Empty(t, svc.computeComplianceMetrics(within, nil, nil, model.KpiGranularityWeek).Violations, "4 weeks are not enough for the average")
+
+	history := &complianceHistory{first: "2024-03-04", last: "2024-03-31", entries: before}
+	got := svc.computeComplianceMetrics(within, history, nil, model.KpiGranularityWeek)
+	dates := make([]string, 0, len(got.Violations))
+	for _, v := range got.Violations {
+		dates = append(dates, v.Date)
+		assert.Equal(t, int32(50*60), v.ActualMinutes)
+	}
+	assert.Equal(t, []string{"2024-03-04", "2024-03-11", "2024-03-18", "2024-03-25"}, dates)
+}
@@ -0,0 +1,258 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/epitech/timemanager/internal/graph/model"
+	"github.com/google/uuid"
+)
+
+// maxWeeklyHours is the legal maximum of a working week.
+const maxWeeklyHours = 48
+
+// ContractRepository is the minimal repository contract used by ContractService.
+type ContractRepository interface {
+	GetContracts(userID *uuid.UUID) ([]*model.EmploymentContract, error)
+	GetUsersContracts(userIDs []uuid.UUID) ([]*model.EmploymentContract, error)
+	GetContract(id string) (*model.EmploymentContract, error)
+	CreateContract(input model.ContractInput) (*model.EmploymentContract, error)
+	UpdateContract(id string, input model.ContractInput) (*model.EmploymentContract, error)
+	DeleteContract(id string) (bool, error)
+}
+
+type ContractService struct {
+	Repo ContractRepository
+	// Rollups is refreshed over the days of a changed contract; optional.
+	Rollups *RollupService
+}
+
+func NewContractService(repo ContractRepository) *ContractService {
+	return &ContractService{Repo: repo}
+}
+
+// GetContracts lists the contracts of a user, or everyone's with a nil user,
+// as scoped by the AccessService.
+func (s *ContractService) GetContracts(userID *uuid.UUID) ([]*model.EmploymentContract, error) {
+	return s.Repo.GetContracts(userID)
+}
+
+func (s *ContractService) CreateContract(input model.ContractInput) (*model.EmploymentContract, error) {
+	if err := s.validateContract("", input); err != nil {
+		return nil, err
+	}
+	contract, err := s.Repo.CreateContract(input)
+	if err != nil {
+		return nil, err
+	}
+	s.refreshRollups(contract)
+	return contract, nil
+}
+
+func (s *ContractService) UpdateContract(id string, input model.ContractInput) (*model.EmploymentContract, error) {
+	previous, err := s.Repo.GetContract(id)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.validateContract(id, input); err != nil {
+		return nil, err
+	}
+	contract, err := s.Repo.UpdateContract(id, input)
+	if err != nil {
+		return nil, err
+	}
+	s.refreshRollups(previous, contract)
+	return contract, nil
+}
+
+func (s *ContractService) DeleteContract(id string) (bool, error) {
+	contract, err := s.Repo.GetContract(id)
+	if err != nil {
+		return false, err
+	}
+	ok, err := s.Repo.DeleteContract(id)
+	if err != nil {
+		return false, err
+	}
+	s.refreshRollups(contract)
+	return ok, nil
+}
+
+// refreshRollups recomputes the rollups of the users over the days the
+// contracts, before and after a change, apply to.
+func (s *ContractService) refreshRollups(contracts ...*model.EmploymentContract) {
+	if s.Rollups == nil {
+		return
+	}
+	for _, c := range contracts {
+		if c == nil {
+			continue
+		}
+		uid, err := uuid.Parse(c.UserID)
+		if err != nil {
+			continue
+		}
+		to := ""
+		if c.EffectiveTo != nil {
+			to = *c.EffectiveTo
+		}
+		s.Rollups.RefreshRange(&uid, c.EffectiveFrom, to)
+	}
+}
+
+// validateContract checks the input and that it overlaps none of the user's
+// other contracts: exactly one contract applies on any day.
+func (s *ContractService) validateContract(id string, input model.ContractInput) error {
+	userID, err := uuid.Parse(input.UserID)
+	if err != nil {
+		return errors.New("invalid userID")
+	}
+	if !input.Type.IsValid() {
+		return errors.New("invalid contract type")
+	}
+	if input.WeeklyHours <= 0 || input.WeeklyHours > maxWeeklyHours {
+		return fmt.Errorf("weeklyHours must be between 0 and %d", maxWeeklyHours)
+	}
+	if len(input.WorkingDays) == 0 {
+		return errors.New("a contract needs at least one working day")
+	}
+	seen := make(map[model.Weekday]struct{}, len(input.WorkingDays))
+	for _, d := range input.WorkingDays {
+		if !d.IsValid() {
+			return errors.New("invalid weekday")
+		}
+		if _, dup := seen[d]; dup {
+			return fmt.Errorf("%s is given twice", d)
+		}
+		seen[d] = struct{}{}
+	}
+	if _, err := time.Parse(layoutISO, input.EffectiveFrom); err != nil {
+		return errors.New("invalid effectiveFrom, expected YYYY-MM-DD")
+	}
+	to := ""
+	if input.EffectiveTo != nil {
+		if _, err := time.Parse(layoutISO, *input.EffectiveTo); err != nil {
+			return errors.New("invalid effectiveTo, expected YYYY-MM-DD")
+		}
+		if *input.EffectiveTo < input.EffectiveFrom {
+			return errors.New("effectiveTo must not be before effectiveFrom")
+		}
+		to = *input.EffectiveTo
+	}
+
+	existing, err := s.Repo.GetContracts(&userID)
+	if err != nil {
+		return err
+	}
+	for _, c := range existing {
+		if c.ID == id {
+			continue
+		}
+		if periodsOverlap(input.EffectiveFrom, to, c.EffectiveFrom, valueOrEmpty(c.EffectiveTo)) {
+			return fmt.Errorf("the contract overlaps the one starting on %s", c.EffectiveFrom)
+		}
+	}
+	return nil
+}
+
+// periodsOverlap compares two date ranges, an empty end being open.
+func periodsOverlap(fromA, toA, fromB, toB string) bool {
+	return (toB == "" || fromA <= toB) && (toA == "" || fromB <= toA)
+}
+
+func valueOrEmpty(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
+
+// ContractCalendar tells which contract applies to a user on a day, caching
+// the contracts of each user; use one per request.
+type ContractCalendar struct {
+	repo      ContractRepository
+	contracts map[string][]*model.EmploymentContract
+}
+
+func (s *ContractService) NewContractCalendar() *ContractCalendar {
+	return &ContractCalendar{repo: s.Repo, contracts: make(map[string][]*model.EmploymentContract)}
+}
+
+// For returns the contract of the user in effect on day (YYYY-MM-DD), or nil.
+func (c *ContractCalendar) For(userID, day string) *model.EmploymentContract {
+	contracts := c.load(userID)
+	var current *model.EmploymentContract
+	for _, ct := range contracts {
+		if ct.EffectiveFrom > day || (ct.EffectiveTo != nil && *ct.EffectiveTo < day) {
+			continue
+		}
+		if current == nil || ct.EffectiveFrom > current.EffectiveFrom {
+			current = ct
+		}
+	}
+	return current
+}
+
+// HasContracts tells whether the user has any contract, in effect or not.
+func (c *ContractCalendar) HasContracts(userID string) bool {
+	return len(c.load(userID)) > 0
+}
+
+// Preload loads the contracts of the users in one query, so that For does not
+// query the repository user by user. Users already loaded are skipped.
+func (c *ContractCalendar) Preload(userIDs []string) error {
+	uids := make([]uuid.UUID, 0, len(userIDs))
+	for _, userID := range userIDs {
+		if _, ok := c.contracts[userID]; ok {
+			continue
+		}
+		uid, err := uuid.Parse(userID)
+		if err != nil {
+			return errors.New("invalid userID")
+		}
+		uids = append(uids, uid)
+	}
+	if len(uids) == 0 {
+		return nil
+	}
+	contracts, err := c.repo.GetUsersContracts(uids)
+	if err != nil {
+		return err
+	}
+	for _, uid := range uids {
+		c.contracts[uid.String()] = []*model.EmploymentContract{}
+	}
+	for _, ct := range contracts {
+		c.contracts[ct.UserID] = append(c.contracts[ct.UserID], ct)
+	}
+	return nil
+}
+
+func (c *ContractCalendar) load(userID string) []*model.EmploymentContract {
+	contracts, ok := c.contracts[userID]
+	if !ok {
+		if uid, err := uuid.Parse(userID); err == nil {
+			// a user whose contracts can't be read is handled as having none
+			contracts, _ = c.repo.GetContracts(&uid)
+		}
+		c.contracts[userID] = contracts
+	}
+	return contracts
+}
+
+// contractDailyMinutes is the work the contract expects on day: its share of
+// the weekly hours on a working day, nothing on a day off.
+func contractDailyMinutes(contract *model.EmploymentContract, day string) int {
+	date, err := time.Parse(layoutISO, day)
+	if err != nil {
+		return 0
+	}
+	weekday := graphWeekday(date.Weekday())
+	for _, d := range contract.WorkingDays {
+		if d == weekday {
+			return int(contract.DailyMinutes)
+		}
+	}
+	return 0
+}
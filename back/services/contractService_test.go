@@ -0,0 +1,154 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"github.com/epitech/timemanager/internal/graph/model"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type mockContractRepo struct {
+	contracts []*model.EmploymentContract
+	created   bool
+	batches   int
+}
+
+func (m *mockContractRepo) GetContracts(userID *uuid.UUID) ([]*model.EmploymentContract, error) {
+	if userID == nil {
+		return m.contracts, nil
+	}
+	var out []*model.EmploymentContract
+	for _, c := range m.contracts {
+		if c.UserID == userID.String() {
+			out = append(out, c)
+		}
+	}
+	return out, nil
+}
+
+func (m *mockContractRepo) GetUsersContracts(userIDs []uuid.UUID) ([]*model.EmploymentContract, error) {
+	m.batches++
+	wanted := make(map[string]struct{}, len(userIDs))
+	for _, uid := range userIDs {
+		wanted[uid.String()] = struct{}{}
+	}
+	var out []*model.EmploymentContract
+	for _, c := range m.contracts {
+		if _, ok := wanted[c.UserID]; ok {
+			out = append(out, c)
+		}
+	}
+	return out, nil
+}
+
+func (m *mockContractRepo) GetContract(id string) (*model.EmploymentContract, error) {
+	for _, c := range m.contracts {
+		if c.ID == id {
+			return c, nil
+		}
+	}
+	return nil, assert.AnError
+}
+
+func (m *mockContractRepo) CreateContract(input model.ContractInput) (*model.EmploymentContract, error) {
+	m.created = true
+	return &model.EmploymentContract{UserID: input.UserID, Type: input.Type, EffectiveFrom: input.EffectiveFrom}, nil
+}
+
+func (m *mockContractRepo) UpdateContract(id string, input model.ContractInput) (*model.EmploymentContract, error) {
+	return &model.EmploymentContract{ID: id, UserID: input.UserID}, nil
+}
+
+func (m *mockContractRepo) DeleteContract(id string) (bool, error) { return true, nil }
+
+var fourDayWeek = []model.Weekday{model.WeekdayMonday, model.WeekdayTuesday, model.WeekdayWednesday, model.WeekdayThursday}
+
+func TestCreateContractValidation(t *testing.T) {
+	uid := uuid.New().String()
+	repo := &mockContractRepo{contracts: []*model.EmploymentContract{
+		{ID: "c1", UserID: uid, EffectiveFrom: "2024-01-01", EffectiveTo: ptrString("2024-06-30")},
+	}}
+	svc := NewContractService(repo)
+	input := func(from string, to *string, hours float64, days ...model.Weekday) model.ContractInput {
+		return model.ContractInput{UserID: uid, Type: model.ContractTypePermanent, WeeklyHours: hours, WorkingDays: days, EffectiveFrom: from, EffectiveTo: to}
+	}
+
+	_, err := svc.CreateContract(input("2024-07-01", nil, 0, fourDayWeek...))
+	assert.Error(t, err, "no hours")
+	_, err = svc.CreateContract(input("2024-07-01", nil, 50, fourDayWeek...))
+	assert.Error(t, err, "above the legal week")
+	_, err = svc.CreateContract(input("2024-07-01", nil, 28))
+	assert.Error(t, err, "no working day")
+	_, err = svc.CreateContract(input("2024-07-01", nil, 28, model.WeekdayMonday, model.WeekdayMonday))
+	assert.Error(t, err, "duplicate day")
+	_, err = svc.CreateContract(input("2024-07-01", ptrString("2024-06-01"), 28, fourDayWeek...))
+	assert.Error(t, err, "ends before it starts")
+	_, err = svc.CreateContract(input("2024-06-01", nil, 28, fourDayWeek...))
+	assert.Error(t, err, "overlaps the first contract")
+	assert.False(t, repo.created)
+
+	_, err = svc.CreateContract(input("2024-07-01", nil, 28, fourDayWeek...))
+	assert.NoError(t, err)
+	assert.True(t, repo.created)
+
+	// a contract may be moved without overlapping itself
+	_, err = svc.UpdateContract("c1", input("2024-02-01", ptrString("2024-06-30"), 35, fourDayWeek...))
+	assert.NoError(t, err)
+}
+
+func TestContractCalendar(t *testing.T) {
+	uid := uuid.New().String()
+	fullTime := &model.EmploymentContract{ID: "full", UserID: uid, DailyMinutes: 420, EffectiveFrom: "2024-01-01", EffectiveTo: ptrString("2024-03-31"),
+		WorkingDays: []model.Weekday{model.WeekdayMonday, model.WeekdayTuesday, model.WeekdayWednesday, model.WeekdayThursday, model.WeekdayFriday}}
+	partTime := &model.EmploymentContract{ID: "part", UserID: uid, DailyMinutes: 420, EffectiveFrom: "2024-04-01", WorkingDays: fourDayWeek}
+	calendar := NewContractService(&mockContractRepo{contracts: []*model.EmploymentContract{fullTime, partTime}}).NewContractCalendar()
+
+	assert.Nil(t, calendar.For(uid, "2023-12-31"))
+	assert.Equal(t, fullTime, calendar.For(uid, "2024-03-31"))
+	assert.Equal(t, partTime, calendar.For(uid, "2024-04-01"))
+	assert.Nil(t, calendar.For(uuid.New().String(), "2024-04-01"))
+
+	assert.Equal(t, 420, contractDailyMinutes(partTime, "2024-04-04"), "Thursday")
+	assert.Equal(t, 0, contractDailyMinutes(partTime, "2024-04-05"), "Friday is off")
+}
+
+func TestContractCalendarPreload(t *testing.T) {
+	withContract, without := uuid.New().String(), uuid.New().String()
+	contract := &model.EmploymentContract{ID: "full", UserID: withContract, DailyMinutes: 420, EffectiveFrom: "2024-01-01"}
+	repo := &mockContractRepo{contracts: []*model.EmploymentContract{contract}}
+	calendar := NewContractService(repo).NewContractCalendar()
+
+	require.NoError(t, calendar.Preload([]string{withContract, without}))
+	assert.Equal(t, contract, calendar.For(withContract, "2024-06-03"))
+	assert.False(t, calendar.HasContracts(without), "a user without contract is cached too")
+	assert.Equal(t, 1, repo.batches)
+	require.NoError(t, calendar.Preload([]string{withContract}))
+	assert.Equal(t, 1, repo.batches, "loaded users are skipped")
+}
+
+func TestKpiUsesContractHours(t *testing.T) {
+	user := &model.User{ID: uuid.New().String(), FirstName: "Part", LastName: "Timer"}
+	contracts := &mockContractRepo{contracts: []*model.EmploymentContract{
+		{ID: "part", UserID: user.ID, WeeklyHours: 28, DailyMinutes: 420, EffectiveFrom: "2024-01-01", WorkingDays: fourDayWeek},
+	}}
+	session := func(day string, hours int) *model.TimeTableEntry {
+		arr, _ := time.Parse(layoutISO, day)
+		arr = arr.Add(9 * time.Hour)
+		dep := arr.Add(time.Duration(hours) * time.Hour)
+		return &model.TimeTableEntry{UserID: user, Day: day, Arrival: arr, Departure: &dep}
+	}
+	svc := NewKpiService(&mockKpiRepo{})
+	svc.Contracts = NewContractService(contracts)
+
+	// two full contract days: exactly as productive as expected
+	got := productivityOf(svc, svc.dailyRollups([]*model.TimeTableEntry{session("2024-01-08", 7), session("2024-01-09", 7)}, time.Now()), model.KpiGranularityDay)
+	assert.InDelta(t, 1.0, got.AvgEfficiencyRate, 1e-9)
+	assert.InDelta(t, 1.0, got.TopPerformers[0].EfficiencyRate, 1e-9)
+
+	// Friday is off: all of it is overtime
+	report := overtimeReportOf(svc, svc.dailyRollups([]*model.TimeTableEntry{session("2024-01-08", 7), session("2024-01-12", 7)}, time.Now()), model.KpiGranularityWeek)
+	assert.Equal(t, int32(420), report.TotalOvertimeMinutes)
+}
@@ -0,0 +1,217 @@
+package services
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/epitech/timemanager/internal/graph/model"
+	"github.com/epitech/timemanager/package/ical"
+	"github.com/google/uuid"
+)
+
+// defaultHolidayName names the imported events that have no summary.
+const defaultHolidayName = "Holiday"
+
+// HolidayRepository is the minimal repository contract used by HolidayService.
+type HolidayRepository interface {
+	GetHolidayCalendars() ([]*model.HolidayCalendar, error)
+	CreateHolidayCalendar(name string) (*model.HolidayCalendar, error)
+	DeleteHolidayCalendar(id string) (bool, error)
+	SaveHolidays(calendarID string, holidays []*model.HolidayInput) (*model.HolidayCalendar, error)
+	RemoveHoliday(calendarID string, date string) (*model.HolidayCalendar, error)
+	SetHolidayCalendar(calendarID *uuid.UUID, siteID *uuid.UUID, teamID *uuid.UUID) (bool, error)
+	// GetUserHolidays returns the holidays of the user's site and teams; empty bounds are open.
+	GetUserHolidays(userID uuid.UUID, from, to string) ([]*model.Holiday, error)
+}
+
+type HolidayService struct {
+	Repo HolidayRepository
+	// Rollups is refreshed around the changed holidays; optional.
+	Rollups *RollupService
+}
+
+func NewHolidayService(repo HolidayRepository) *HolidayService {
+	return &HolidayService{Repo: repo}
+}
+
+func (s *HolidayService) GetHolidayCalendars() ([]*model.HolidayCalendar, error) {
+	return s.Repo.GetHolidayCalendars()
+}
+
+func (s *HolidayService) CreateHolidayCalendar(name string) (*model.HolidayCalendar, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return nil, errors.New("holiday calendar name is required")
+	}
+	return s.Repo.CreateHolidayCalendar(name)
+}
+
+func (s *HolidayService) DeleteHolidayCalendar(id string) (bool, error) {
+	days := s.holidayDays(id)
+	ok, err := s.Repo.DeleteHolidayCalendar(id)
+	if err != nil {
+		return false, err
+	}
+	s.Rollups.RefreshDays(days...)
+	return ok, nil
+}
+
+// holidayDays lists the holidays of a calendar, or of all calendars when id
+// is empty, whose rollups a change affects.
+func (s *HolidayService) holidayDays(id string) []string {
+	if s.Rollups == nil {
+		return nil
+	}
+	calendars, err := s.Repo.GetHolidayCalendars()
+	if err != nil {
+		return nil
+	}
+	days := make([]string, 0)
+	for _, c := range calendars {
+		if id != "" && c.ID != id {
+			continue
+		}
+		for _, h := range c.Holidays {
+			days = append(days, h.Date)
+		}
+	}
+	return days
+}
+
+// AddHolidays adds or renames holidays of a calendar; a date given twice keeps its last name.
+func (s *HolidayService) AddHolidays(calendarID string, holidays []*model.HolidayInput) (*model.HolidayCalendar, error) {
+	if len(holidays) == 0 {
+		return nil, errors.New("no holiday given")
+	}
+	byDate := make(map[string]int, len(holidays))
+	out := make([]*model.HolidayInput, 0, len(holidays))
+	for _, h := range holidays {
+		if h == nil {
+			continue
+		}
+		if _, err := time.Parse(layoutISO, h.Date); err != nil {
+			return nil, errors.New("invalid holiday date, expected YYYY-MM-DD")
+		}
+		name := strings.TrimSpace(h.Name)
+		if name == "" {
+			return nil, errors.New("holiday name is required")
+		}
+		holiday := &model.HolidayInput{Date: h.Date, Name: name}
+		if i, ok := byDate[h.Date]; ok {
+			out[i] = holiday
+			continue
+		}
+		byDate[h.Date] = len(out)
+		out = append(out, holiday)
+	}
+	calendar, err := s.Repo.SaveHolidays(calendarID, out)
+	if err != nil {
+		return nil, err
+	}
+	days := make([]string, 0, len(out))
+	for _, h := range out {
+		days = append(days, h.Date)
+	}
+	s.Rollups.RefreshDays(days...)
+	return calendar, nil
+}
+
+// ImportICS adds the days covered by the events of an iCalendar file.
+func (s *HolidayService) ImportICS(calendarID string, content string) (*model.HolidayCalendar, error) {
+	days, err := ical.ParseDays(strings.NewReader(content))
+	if err != nil {
+		return nil, errors.New("invalid iCalendar file: " + err.Error())
+	}
+	holidays := make([]*model.HolidayInput, 0, len(days))
+	for _, d := range days {
+		name := strings.TrimSpace(d.Summary)
+		if name == "" {
+			name = defaultHolidayName
+		}
+		holidays = append(holidays, &model.HolidayInput{Date: d.Date, Name: name})
+	}
+	return s.AddHolidays(calendarID, holidays)
+}
+
+func (s *HolidayService) RemoveHoliday(calendarID string, date string) (*model.HolidayCalendar, error) {
+	if _, err := time.Parse(layoutISO, date); err != nil {
+		return nil, errors.New("invalid date, expected YYYY-MM-DD")
+	}
+	calendar, err := s.Repo.RemoveHoliday(calendarID, date)
+	if err != nil {
+		return nil, err
+	}
+	s.Rollups.RefreshDays(date)
+	return calendar, nil
+}
+
+// SetHolidayCalendar assigns a calendar to exactly one site or team; a nil
+// calendarID removes the current one.
+func (s *HolidayService) SetHolidayCalendar(calendarID, siteID, teamID *string) (bool, error) {
+	hasSite := siteID != nil && *siteID != ""
+	hasTeam := teamID != nil && *teamID != ""
+	if hasSite == hasTeam {
+		return false, errors.New("a holiday calendar is set on exactly one site or one team")
+	}
+	calendar, err := optionalUUID(calendarID)
+	if err != nil {
+		return false, errors.New("invalid calendarID")
+	}
+	site, err := optionalUUID(siteID)
+	if err != nil {
+		return false, errors.New("invalid siteID")
+	}
+	team, err := optionalUUID(teamID)
+	if err != nil {
+		return false, errors.New("invalid teamID")
+	}
+	// the previous calendar of the site or team is not known here: the days
+	// of every calendar are refreshed
+	days := s.holidayDays("")
+	ok, err := s.Repo.SetHolidayCalendar(calendar, site, team)
+	if err != nil {
+		return false, err
+	}
+	s.Rollups.RefreshDays(days...)
+	return ok, nil
+}
+
+func optionalUUID(s *string) (*uuid.UUID, error) {
+	if s == nil || *s == "" {
+		return nil, nil
+	}
+	id, err := uuid.Parse(*s)
+	if err != nil {
+		return nil, err
+	}
+	return &id, nil
+}
+
+// UserHolidays lists the holidays of a user between from and to inclusive,
+// each date once even when the site and a team both have it.
+func (s *HolidayService) UserHolidays(userID uuid.UUID, from, to string) ([]*model.Holiday, error) {
+	if _, err := time.Parse(layoutISO, from); err != nil {
+		return nil, errors.New("invalid from, expected YYYY-MM-DD")
+	}
+	if _, err := time.Parse(layoutISO, to); err != nil {
+		return nil, errors.New("invalid to, expected YYYY-MM-DD")
+	}
+	if to < from {
+		return nil, errors.New("to must not be before from")
+	}
+	holidays, err := s.Repo.GetUserHolidays(userID, from, to)
+	if err != nil {
+		return nil, err
+	}
+	seen := make(map[string]struct{}, len(holidays))
+	out := make([]*model.Holiday, 0, len(holidays))
+	for _, h := range holidays {
+		if _, ok := seen[h.Date]; ok {
+			continue
+		}
+		seen[h.Date] = struct{}{}
+		out = append(out, h)
+	}
+	return out, nil
+}
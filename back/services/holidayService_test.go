@@ -0,0 +1,235 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"github.com/epitech/timemanager/internal/graph/model"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type mockHolidayRepo struct {
+	saved    []*model.HolidayInput
+	assigned bool
+	holidays []*model.Holiday
+}
+
+func (m *mockHolidayRepo) GetHolidayCalendars() ([]*model.HolidayCalendar, error) { return nil, nil }
+
+func (m *mockHolidayRepo) CreateHolidayCalendar(name string) (*model.HolidayCalendar, error) {
+	return &model.HolidayCalendar{Name: name}, nil
+}
+
+func (m *mockHolidayRepo) DeleteHolidayCalendar(id string) (bool, error) { return true, nil }
+
+func (m *mockHolidayRepo) SaveHolidays(calendarID string, holidays []*model.HolidayInput) (*model.HolidayCalendar, error) {
+	m.saved = holidays
+	return &model.HolidayCalendar{ID: calendarID}, nil
+}
+
+func (m *mockHolidayRepo) RemoveHoliday(calendarID string, date string) (*model.HolidayCalendar, error) {
+	return &model.HolidayCalendar{ID: calendarID}, nil
+}
+
+func (m *mockHolidayRepo) SetHolidayCalendar(calendarID *uuid.UUID, siteID *uuid.UUID, teamID *uuid.UUID) (bool, error) {
+	m.assigned = true
+	return true, nil
+}
+
+func (m *mockHolidayRepo) GetUserHolidays(userID uuid.UUID, from, to string) ([]*model.Holiday, error) {
+	return m.holidays, nil
+}
+
+const testICS = "BEGIN:VCALENDAR\r\n" +
+	"VERSION:2.0\r\n" +
+	"BEGIN:VEVENT\r\n" +
+	"DTSTART;VALUE=DATE:20240101\r\n" +
+	"DTEND;VALUE=DATE:20240102\r\n" +
+	"SUMMARY:Jour de l'an\r\n" +
+	"RRULE:FREQ=YEARLY;COUNT=2\r\n" +
+	"END:VEVENT\r\n" +
+	"BEGIN:VEVENT\r\n" +
+	"DTSTART;VALUE=DATE:20240508\r\n" +
+	"SUMMARY:Victoire\r\n" +
+	"  1945\r\n" +
+	"END:VEVENT\r\n" +
+	"BEGIN:VEVENT\r\n" +
+	"DTSTART;VALUE=DATE:20241224\r\n" +
+	"DTEND;VALUE=DATE:20241227\r\n" +
+	"SUMMARY:Fermeture de Noël\\, usine\r\n" +
+	"END:VEVENT\r\n" +
+	"BEGIN:VEVENT\r\n" +
+	"DTSTART:20240715T000000Z\r\n" +
+	"STATUS:CANCELLED\r\n" +
+	"SUMMARY:Annulé\r\n" +
+	"END:VEVENT\r\n" +
+	"END:VCALENDAR\r\n"
+
+func TestImportICS(t *testing.T) {
+	repo := &mockHolidayRepo{}
+	svc := NewHolidayService(repo)
+
+	_, err := svc.ImportICS("cal", testICS)
+	assert.NoError(t, err)
+	got := map[string]string{}
+	for _, h := range repo.saved {
+		got[h.Date] = h.Name
+	}
+	assert.Equal(t, map[string]string{
+		"2024-01-01": "Jour de l'an",
+		"2025-01-01": "Jour de l'an",
+		"2024-05-08": "Victoire 1945",
+		"2024-12-24": "Fermeture de Noël, usine",
+		"2024-12-25": "Fermeture de Noël, usine",
+		"2024-12-26": "Fermeture de Noël, usine",
+	}, got)
+
+	_, err = svc.ImportICS("cal", "BEGIN:VCALENDAR\nEND:VCALENDAR\n")
+	assert.Error(t, err, "no event")
+	_, err = svc.ImportICS("cal", "BEGIN:VEVENT\nDTSTART;VALUE=DATE:20240101\nRRULE:FREQ=WEEKLY\nEND:VEVENT\n")
+	assert.Error(t, err, "unsupported recurrence")
+}
+
+func TestImportICSExpandsMonthsAndDays(t *testing.T) {
+	repo := &mockHolidayRepo{}
+	svc := NewHolidayService(repo)
+	event := func(start, rrule string) string {
+		return "BEGIN:VEVENT\nDTSTART;VALUE=DATE:" + start + "\nSUMMARY:Fête\nRRULE:" + rrule + "\nEND:VEVENT\n"
+	}
+	dates := func() []string {
+		out := make([]string, 0, len(repo.saved))
+		for _, h := range repo.saved {
+			out = append(out, h.Date)
+		}
+		return out
+	}
+
+	_, err := svc.ImportICS("cal", event("20240501", "FREQ=YEARLY;COUNT=3;BYMONTH=5;BYMONTHDAY=1,8"))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"2024-05-01", "2024-05-08", "2025-05-01"}, dates())
+
+	// the last day of February, whatever the year
+	_, err = svc.ImportICS("cal", event("20240229", "FREQ=YEARLY;COUNT=2;BYMONTH=2;BYMONTHDAY=-1"))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"2024-02-29", "2025-02-28"}, dates())
+
+	_, err = svc.ImportICS("cal", event("20240101", "FREQ=YEARLY;BYMONTH=13"))
+	assert.Error(t, err, "no 13th month")
+	_, err = svc.ImportICS("cal", event("20240101", "FREQ=YEARLY;BYDAY=1MO"))
+	assert.Error(t, err, "unsupported recurrence")
+}
+
+func TestAddHolidaysAndAssignment(t *testing.T) {
+	repo := &mockHolidayRepo{}
+	svc := NewHolidayService(repo)
+
+	_, err := svc.AddHolidays("cal", []*model.HolidayInput{{Date: "01/05/2024", Name: "Fête du travail"}})
+	assert.Error(t, err)
+	_, err = svc.AddHolidays("cal", []*model.HolidayInput{
+		{Date: "2024-05-01", Name: "May Day"},
+		{Date: "2024-05-01", Name: " Fête du travail "},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []*model.HolidayInput{{Date: "2024-05-01", Name: "Fête du travail"}}, repo.saved)
+
+	id := uuid.New().String()
+	_, err = svc.SetHolidayCalendar(&id, &id, &id)
+	assert.Error(t, err, "a site or a team, not both")
+	_, err = svc.SetHolidayCalendar(&id, nil, nil)
+	assert.Error(t, err)
+	assert.False(t, repo.assigned)
+	_, err = svc.SetHolidayCalendar(nil, &id, nil)
+	assert.NoError(t, err, "clearing a site's calendar")
+	assert.True(t, repo.assigned)
+}
+
+func TestHolidaysAreNotWorkingDays(t *testing.T) {
+	uid := uuid.New()
+	repo := &mockScheduleRepo{
+		timeTable: &model.TimeTable{ID: "tt", Start: time.Date(0, 1, 1, 9, 0, 0, 0, time.UTC), Ends: time.Date(0, 1, 1, 17, 0, 0, 0, time.UTC)},
+		timeZone:  "UTC",
+		holidays:  []*model.Holiday{{Date: "2024-05-01", Name: "Fête du travail"}},
+	}
+	schedules := NewScheduleService(repo)
+
+	got, err := schedules.PlannedSchedule(uid, "2024-05-01")
+	assert.NoError(t, err)
+	assert.Equal(t, model.ScheduleSourceDefault, got.Source)
+	assert.Equal(t, "Fête du travail", *got.Holiday)
+	assert.False(t, got.WorkingDay)
+	assert.Equal(t, int32(0), got.PlannedMinutes)
+
+	// a week of leave around the holiday consumes four days
+	sick := &model.AbsenceType{ID: uuid.New().String(), IsActive: true}
+	leaves := &mockLeaveRepo{types: []*model.AbsenceType{sick}}
+	leaveService := NewLeaveService(leaves)
+	leaveService.Schedules = schedules
+	_, err = leaveService.RequestLeave(uid, model.RequestLeaveInput{AbsenceTypeID: sick.ID, StartDate: "2024-04-29", EndDate: "2024-05-03"})
+	assert.NoError(t, err)
+	assert.Equal(t, 4.0, leaves.days)
+}
+
+func TestComplianceSeparatesHolidayWork(t *testing.T) {
+	weekender := &model.User{ID: uuid.New().String()}
+	office := &model.User{ID: uuid.New().String()}
+	ws := &model.WorkSchedule{ID: "ws", UserID: ptrString(weekender.ID), EffectiveFrom: "2024-01-01",
+		Days: []*model.WorkScheduleDay{{Weekday: model.WeekdaySaturday, Start: "09:00", End: "17:00"}}}
+	repo := &mockScheduleRepo{
+		schedules: []*model.WorkSchedule{ws},
+		timeTable: &model.TimeTable{ID: "tt", Start: time.Date(0, 1, 1, 9, 0, 0, 0, time.UTC), Ends: time.Date(0, 1, 1, 17, 0, 0, 0, time.UTC)},
+		timeZone:  "UTC",
+		holidays:  []*model.Holiday{{Date: "2024-05-01", Name: "Fête du travail"}},
+	}
+	session := func(u *model.User, day string) *model.TimeTableEntry {
+		arr, _ := time.Parse(layoutISO, day)
+		arr = arr.Add(9 * time.Hour)
+		dep := arr.Add(8 * time.Hour)
+		return &model.TimeTableEntry{UserID: u, Day: day, Arrival: arr, Departure: &dep}
+	}
+	entries := []*model.TimeTableEntry{
+		session(weekender, "2024-05-04"), // scheduled Saturday
+		session(office, "2024-05-04"),    // unscheduled Saturday
+		session(office, "2024-05-01"),    // public holiday
+	}
+	svc := NewKpiService(&mockKpiRepo{entries: entries})
+	// the mock returns the same schedules to everyone: keep them to the weekender
+	svc.Schedules = NewScheduleService(&userScopedScheduleRepo{mockScheduleRepo: repo, owner: weekender.ID})
+
+	got := svc.computeComplianceMetrics(entries, nil, nil, model.KpiGranularityWeek)
+	counts := map[string]int32{}
+	for _, a := range got.Anomalies {
+		counts[a.Type] = a.Count
+	}
+	assert.Equal(t, int32(1), counts["weekend_work"])
+	assert.Equal(t, int32(1), counts["holiday_work"])
+	assert.Equal(t, int32(2), got.AnomaliesCount)
+
+	// the whole holiday is overtime
+	report := overtimeReportOf(svc, svc.dailyRollups(entries[2:], time.Now()), model.KpiGranularityWeek)
+	assert.Equal(t, int32(480), report.TotalOvertimeMinutes)
+}
+
+// userScopedScheduleRepo only returns the schedules to their owner.
+type userScopedScheduleRepo struct {
+	*mockScheduleRepo
+	owner string
+}
+
+func (m *userScopedScheduleRepo) GetUserWorkSchedules(userID uuid.UUID) ([]*model.WorkSchedule, error) {
+	if userID.String() != m.owner {
+		return nil, nil
+	}
+	return m.mockScheduleRepo.GetUserWorkSchedules(userID)
+}
+
+func (m *userScopedScheduleRepo) GetUsersWorkSchedules(userIDs []uuid.UUID) (map[string][]*model.WorkSchedule, error) {
+	out, err := m.mockScheduleRepo.GetUsersWorkSchedules(userIDs)
+	for userID := range out {
+		if userID != m.owner {
+			out[userID] = nil
+		}
+	}
+	return out, err
+}
@@ -0,0 +1,147 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"math"
+	"time"
+
+	"github.com/epitech/timemanager/internal/graph/model"
+	"github.com/google/uuid"
+)
+
+// comparisonWindow is the window the KPIs of start..end are compared with. The
+// previous period has as many days and ends the day before start; the bounds
+// keep their time of day and zone.
+func comparisonWindow(start, end time.Time, compare *model.KpiComparisonInput) (time.Time, time.Time, error) {
+	switch compare.Mode {
+	case model.KpiComparisonModePreviousPeriod:
+		days := daysBetween(start, end) + 1
+		return start.AddDate(0, 0, -days), end.AddDate(0, 0, -days), nil
+	case model.KpiComparisonModeSamePeriodLastYear:
+		return start.AddDate(-1, 0, 0), end.AddDate(-1, 0, 0), nil
+	case model.KpiComparisonModeCustom:
+		if compare.From == nil || compare.To == nil {
+			return time.Time{}, time.Time{}, errors.New("a custom comparison needs from and to")
+		}
+		from, err := time.ParseInLocation(layoutISO, *compare.From, start.Location())
+		if err != nil {
+			return time.Time{}, time.Time{}, errors.New("invalid comparison from, expected YYYY-MM-DD")
+		}
+		to, err := time.ParseInLocation(layoutISO, *compare.To, start.Location())
+		if err != nil {
+			return time.Time{}, time.Time{}, errors.New("invalid comparison to, expected YYYY-MM-DD")
+		}
+		if to.Before(from) {
+			return time.Time{}, time.Time{}, errors.New("the comparison to must not be before from")
+		}
+		return from, to, nil
+	}
+	return time.Time{}, time.Time{}, errors.New("invalid comparison mode")
+}
+
+// daysBetween counts the calendar days from a to b, in a's zone.
+func daysBetween(a, b time.Time) int {
+	ay, am, ad := a.Date()
+	by, bm, bd := b.In(a.Location()).Date()
+	from := time.Date(ay, am, ad, 0, 0, 0, 0, time.UTC)
+	to := time.Date(by, bm, bd, 0, 0, 0, 0, time.UTC)
+	return int(to.Sub(from).Hours() / 24)
+}
+
+// kpiDelta compares a metric with its value over the comparison period.
+func kpiDelta(metric string, current, previous float64) *model.KpiDelta {
+	d := &model.KpiDelta{
+		Metric:   metric,
+		Current:  current,
+		Previous: previous,
+		Delta:    current - previous,
+	}
+	if previous != 0 {
+		pct := (current - previous) / math.Abs(previous) * 100
+		d.PercentChange = &pct
+	}
+	return d
+}
+
+func (s *KpiService) compareUserKpis(ctx context.Context, userID *uuid.UUID, start, end time.Time, compare *model.KpiComparisonInput, current *model.UserKpiSummary) (*model.KpiComparison, error) {
+	from, to, err := comparisonWindow(start, end, compare)
+	if err != nil {
+		return nil, err
+	}
+	previous, err := s.GetUserKpiSummary(ctx, userID, from, to, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	return &model.KpiComparison{
+		Mode: compare.Mode,
+		From: previous.From,
+		To:   previous.To,
+		Deltas: []*model.KpiDelta{
+			kpiDelta("workedMinutes", float64(current.WorkedMinutes), float64(previous.WorkedMinutes)),
+			kpiDelta("unpaidBreakMinutes", float64(current.UnpaidBreakMinutes), float64(previous.UnpaidBreakMinutes)),
+			kpiDelta("overtimeMinutes", float64(current.OvertimeMinutes), float64(previous.OvertimeMinutes)),
+			kpiDelta("daysPresent", float64(current.DaysPresent), float64(previous.DaysPresent)),
+			kpiDelta("leaveDays", current.LeaveDays, previous.LeaveDays),
+			kpiDelta("punctualityRate", current.PunctualityRate, previous.PunctualityRate),
+			kpiDelta("autoClosedEntries", float64(current.AutoClosedEntries), float64(previous.AutoClosedEntries)),
+		},
+	}, nil
+}
+
+// compareTeamKpis leaves out the users active now, which the past has none of.
+func (s *KpiService) compareTeamKpis(ctx context.Context, teamID uuid.UUID, start, end time.Time, compare *model.KpiComparisonInput, current *model.TeamKpiSummary) (*model.KpiComparison, error) {
+	from, to, err := comparisonWindow(start, end, compare)
+	if err != nil {
+		return nil, err
+	}
+	previous, err := s.GetTeamKpiSummary(ctx, teamID, from, to, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	return &model.KpiComparison{
+		Mode: compare.Mode,
+		From: previous.From,
+		To:   previous.To,
+		Deltas: []*model.KpiDelta{
+			kpiDelta("totalWorkedMinutes", float64(current.TotalWorkedMinutes), float64(previous.TotalWorkedMinutes)),
+			kpiDelta("avgWorkedMinutesPerUser", current.AvgWorkedMinutesPerUser, previous.AvgWorkedMinutesPerUser),
+		},
+	}, nil
+}
+
+// compareAdminKpis only sums the comparison period's rollups for the compared
+// totals: the entries, the compliance checks, the teams and the series of the
+// dashboard are left out.
+func (s *KpiService) compareAdminKpis(ctx context.Context, userID *uuid.UUID, teamID *uuid.UUID, start, end time.Time, compare *model.KpiComparisonInput, cur *model.AdminKpiDashboard) (*model.KpiComparison, error) {
+	from, to, err := comparisonWindow(start, end, compare)
+	if err != nil {
+		return nil, err
+	}
+	from, to = normalizeWindow(from, to)
+	figures, err := s.dashboardFigures(userID, teamID, from, to, time.Now(), model.KpiGranularityDay)
+	if err != nil {
+		return nil, err
+	}
+	summary := s.computeAdminSummary(figures)
+	workload := s.computeWorkloadAnalysis(figures, model.KpiGranularityDay)
+	punctuality := s.computePunctualityMetrics(figures)
+	overtime := s.computeOvertimeReport(figures, nil)
+	productivity := s.computeProductivityMetrics(figures, nil, model.KpiGranularityDay)
+	return &model.KpiComparison{
+		Mode: compare.Mode,
+		From: from.Format(layoutISO),
+		To:   to.Format(layoutISO),
+		Deltas: []*model.KpiDelta{
+			kpiDelta("totalUsers", float64(cur.Summary.TotalUsers), float64(summary.TotalUsers)),
+			kpiDelta("totalWorkedHours", float64(cur.Summary.TotalWorkedHours), float64(summary.TotalWorkedHours)),
+			kpiDelta("avgHoursPerUser", cur.Summary.AvgHoursPerUser, summary.AvgHoursPerUser),
+			kpiDelta("complianceRate", cur.Summary.ComplianceRate, summary.ComplianceRate),
+			kpiDelta("avgDailyMinutes", cur.Workload.AvgDailyMinutes, workload.AvgDailyMinutes),
+			kpiDelta("onTimeRate", cur.Punctuality.OnTimeRate, punctuality.OnTimeRate),
+			kpiDelta("avgLateMinutes", cur.Punctuality.AvgLateMinutes, punctuality.AvgLateMinutes),
+			kpiDelta("totalOvertimeMinutes", float64(cur.Overtime.TotalOvertimeMinutes), float64(overtime.TotalOvertimeMinutes)),
+			kpiDelta("avgEfficiencyRate", cur.Productivity.AvgEfficiencyRate, productivity.AvgEfficiencyRate),
+		},
+	}, nil
+}
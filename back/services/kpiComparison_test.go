@@ -0,0 +1,173 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/epitech/timemanager/internal/graph/model"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// windowedKpiRepo only returns the entries of the requested days.
+type windowedKpiRepo struct {
+	mockKpiRepo
+}
+
+func (m *windowedKpiRepo) GetTimeTableEntriesFiltered(userID *uuid.UUID, teamID *uuid.UUID, from, to *time.Time) ([]*model.TimeTableEntry, error) {
+	out := make([]*model.TimeTableEntry, 0)
+	for _, e := range m.entries {
+		if e.Day >= from.Format(layoutISO) && e.Day <= to.Format(layoutISO) {
+			out = append(out, e)
+		}
+	}
+	return out, nil
+}
+
+func TestComparisonWindow(t *testing.T) {
+	start := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	end := time.Date(2024, 3, 31, 0, 0, 0, 0, time.UTC)
+
+	from, to, err := comparisonWindow(start, end, &model.KpiComparisonInput{Mode: model.KpiComparisonModePreviousPeriod})
+	assert.NoError(t, err)
+	assert.Equal(t, "2024-01-30", from.Format(layoutISO))
+	assert.Equal(t, "2024-02-29", to.Format(layoutISO), "31 days, ending the day before")
+
+	from, to, err = comparisonWindow(start, end, &model.KpiComparisonInput{Mode: model.KpiComparisonModeSamePeriodLastYear})
+	assert.NoError(t, err)
+	assert.Equal(t, "2023-03-01", from.Format(layoutISO))
+	assert.Equal(t, "2023-03-31", to.Format(layoutISO))
+
+	from, to, err = comparisonWindow(start, end, &model.KpiComparisonInput{Mode: model.KpiComparisonModeCustom, From: ptrString("2023-09-01"), To: ptrString("2023-09-30")})
+	assert.NoError(t, err)
+	assert.Equal(t, "2023-09-01", from.Format(layoutISO))
+	assert.Equal(t, "2023-09-30", to.Format(layoutISO))
+
+	_, _, err = comparisonWindow(start, end, &model.KpiComparisonInput{Mode: model.KpiComparisonModeCustom, From: ptrString("2023-09-01")})
+	assert.Error(t, err)
+	_, _, err = comparisonWindow(start, end, &model.KpiComparisonInput{Mode: model.KpiComparisonModeCustom, From: ptrString("2023-09-30"), To: ptrString("2023-09-01")})
+	assert.Error(t, err)
+}
+
+func TestUserKpisComparedWithPreviousPeriod(t *testing.T) {
+	u := &model.User{ID: uuid.New().String()}
+	repo := &windowedKpiRepo{mockKpiRepo{entries: []*model.TimeTableEntry{
+		workSession(u, "2024-01-03", 9, 17), // previous week
+		workSession(u, "2024-01-09", 9, 17),
+		workSession(u, "2024-01-10", 9, 13),
+	}}}
+	svc := NewKpiService(repo)
+	uid := uuid.MustParse(u.ID)
+	from := time.Date(2024, 1, 8, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2024, 1, 14, 0, 0, 0, 0, time.UTC)
+
+	got, err := svc.GetUserKpiSummary(context.Background(), &uid, from, to, nil, &model.KpiComparisonInput{Mode: model.KpiComparisonModePreviousPeriod})
+	assert.NoError(t, err)
+	assert.Equal(t, "2024-01-01", got.Comparison.From)
+	assert.Equal(t, "2024-01-07", got.Comparison.To)
+	deltas := map[string]*model.KpiDelta{}
+	for _, d := range got.Comparison.Deltas {
+		deltas[d.Metric] = d
+	}
+	worked := deltas["workedMinutes"]
+	assert.Equal(t, 720.0, worked.Current)
+	assert.Equal(t, 480.0, worked.Previous)
+	assert.Equal(t, 240.0, worked.Delta)
+	assert.Equal(t, 50.0, *worked.PercentChange)
+	assert.Equal(t, 1.0, deltas["daysPresent"].Delta)
+	assert.Nil(t, deltas["leaveDays"].PercentChange, "no change rate from zero")
+
+	plain, err := svc.GetUserKpiSummary(context.Background(), &uid, from, to, nil, nil)
+	assert.NoError(t, err)
+	assert.Nil(t, plain.Comparison)
+	_, err = svc.GetUserKpiSummary(context.Background(), &uid, from, to, nil, &model.KpiComparisonInput{Mode: model.KpiComparisonModeCustom})
+	assert.Error(t, err)
+}
+
+// countingKpiRepo counts the reads the comparison period must not need.
+type countingKpiRepo struct {
+	mockKpiRepo
+	entryReads, staffReads, teamReads int
+}
+
+func (m *countingKpiRepo) GetTimeTableEntriesFiltered(userID *uuid.UUID, teamID *uuid.UUID, from, to *time.Time) ([]*model.TimeTableEntry, error) {
+	m.entryReads++
+	return m.mockKpiRepo.GetTimeTableEntriesFiltered(userID, teamID, from, to)
+}
+
+func (m *countingKpiRepo) GetUsersTimeTableEntries(userIDs []uuid.UUID, from, to string) ([]*model.TimeTableEntry, error) {
+	m.entryReads++
+	return m.mockKpiRepo.GetUsersTimeTableEntries(userIDs, from, to)
+}
+
+func (m *countingKpiRepo) GetUsersWithTeams() ([]*model.UserWithAllData, error) {
+	m.staffReads++
+	return m.mockKpiRepo.GetUsersWithTeams()
+}
+
+func (m *countingKpiRepo) GetTeams() ([]*model.Team, error) {
+	m.teamReads++
+	return m.mockKpiRepo.GetTeams()
+}
+
+func TestAdminKpisComparedFromTheRollupsOnly(t *testing.T) {
+	uid := uuid.New().String()
+	repo := &countingKpiRepo{mockKpiRepo: mockKpiRepo{
+		users: []*model.UserWithAllData{{ID: uid, FirstName: "Ada"}},
+		rollups: []*model.DailyRollup{
+			{UserID: uid, Day: "2024-01-03", WorkedMinutes: 300, OvertimeMinutes: 30, Sessions: 1},
+			{UserID: uid, Day: "2024-01-09", WorkedMinutes: 480, OvertimeMinutes: 90, Sessions: 1},
+		},
+	}}
+	svc := NewKpiService(repo)
+	repo.rebuilt = []*model.RollupCoverage{{From: "2023-12-01", Rules: svc.rollupRules()}}
+	from := time.Date(2024, 1, 8, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2024, 1, 14, 0, 0, 0, 0, time.UTC)
+
+	got, err := svc.GetAdminKpiDashboard(context.Background(), from, to, nil, &model.KpiComparisonInput{Mode: model.KpiComparisonModePreviousPeriod})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, repo.entryReads, "only the current period's compliance reads entries")
+	assert.Equal(t, 1, repo.staffReads)
+	assert.Equal(t, 1, repo.teamReads)
+	assert.Equal(t, "2024-01-01", got.Comparison.From)
+	assert.Equal(t, "2024-01-07", got.Comparison.To)
+	deltas := map[string]*model.KpiDelta{}
+	for _, d := range got.Comparison.Deltas {
+		deltas[d.Metric] = d
+	}
+	assert.Equal(t, 8.0, deltas["totalWorkedHours"].Current)
+	assert.Equal(t, 5.0, deltas["totalWorkedHours"].Previous)
+	assert.Equal(t, 90.0, deltas["totalOvertimeMinutes"].Current)
+	assert.Equal(t, 30.0, deltas["totalOvertimeMinutes"].Previous)
+}
+
+func TestDashboardComplianceReadsTheStaffPageByPage(t *testing.T) {
+	repo := &countingKpiRepo{}
+	for i := 0; i < compliancePageSize+1; i++ {
+		repo.users = append(repo.users, &model.UserWithAllData{ID: uuid.New().String()})
+	}
+	first := &model.User{ID: repo.users[0].ID}
+	last := &model.User{ID: repo.users[compliancePageSize].ID}
+	open := workSession(last, "2024-01-09", 9, 17)
+	open.Departure = nil
+	repo.entries = []*model.TimeTableEntry{
+		workSession(first, "2024-01-09", 6, 19),
+		workSession(last, "2024-01-08", 6, 19),
+		open,
+	}
+	svc := NewKpiService(repo)
+	start := time.Date(2024, 1, 8, 0, 0, 0, 0, time.UTC)
+	end := time.Date(2024, 1, 14, 0, 0, 0, 0, time.UTC)
+
+	got, err := svc.dashboardCompliance(repo.users, start, end, model.KpiGranularityWeek)
+	require.NoError(t, err)
+	assert.Equal(t, 2, repo.entryReads, "one read per page of users")
+	counts := map[string]int32{}
+	for _, a := range got.Anomalies {
+		counts[a.Type] = a.Count
+	}
+	assert.Equal(t, int32(2), counts["excessive_hours"], "both pages are tallied")
+	assert.Equal(t, int32(1), counts["missing_clockout"])
+}
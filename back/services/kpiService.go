@@ -7,9 +7,11 @@ import (
 	"fmt"
 	"sort"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/epitech/timemanager/internal/graph/model"
+	"github.com/epitech/timemanager/package/timezone"
 	"github.com/google/uuid"
 )
 
@@ -18,71 +20,168 @@ const layoutISO = "2006-01-02"
 // KpiRepository is the minimal repository contract used by KpiService.
 type KpiRepository interface {
 	GetTimeTableEntriesFiltered(userID *uuid.UUID, teamID *uuid.UUID, from, to *time.Time) ([]*model.TimeTableEntry, error)
+	// GetUsersTimeTableEntries returns the entries of the users between two
+	// days (YYYY-MM-DD inclusive).
+	GetUsersTimeTableEntries(userIDs []uuid.UUID, from, to string) ([]*model.TimeTableEntry, error)
 	GetTeams() ([]*model.Team, error)
-	GetTeamByUUID(teamID uuid.UUID) (*model.Team, error)
+	GetUsersWithTeams() ([]*model.UserWithAllData, error)
+	GetDailyRollups(userID *uuid.UUID, teamID *uuid.UUID, from, to string) ([]*model.DailyRollup, error)
+	// sums of the stored daily rollups, done by the database
+	GetKpiPeriodTotals(userID *uuid.UUID, teamID *uuid.UUID, from, to string, granularity model.KpiGranularity, now time.Time) ([]*model.KpiPeriodTotal, error)
+	GetKpiUserPeriodTotals(userID *uuid.UUID, teamID *uuid.UUID, from, to string, granularity model.KpiGranularity, now time.Time) ([]*model.KpiUserPeriodTotal, error)
+	GetKpiWeekdayTotals(userID *uuid.UUID, teamID *uuid.UUID, from, to string, now time.Time) ([]*model.KpiWeekdayTotal, error)
+	GetKpiUserTotals(userID *uuid.UUID, teamID *uuid.UUID, from, to string, now time.Time) ([]*model.KpiUserTotal, error)
+	GetKpiPunctualityPeriods(userID *uuid.UUID, teamID *uuid.UUID, from, to string, granularity model.KpiGranularity) ([]*model.KpiPunctualityPeriod, error)
+	GetKpiOvertimeWeeks(userID *uuid.UUID, teamID *uuid.UUID, weekStart, from, to string) ([]*model.KpiOvertimeWeek, error)
+	// GetKpiCoverage counts the users present in each hour from start to end,
+	// start being the beginning of a local hour.
+	GetKpiCoverage(teamID *uuid.UUID, from, to string, start, end, now time.Time) ([]*model.CoveragePoint, error)
+	// GetRollupCoverage returns the ranges of days whose rollups are complete.
+	GetRollupCoverage() ([]*model.RollupCoverage, error)
+	// GetUserStarts returns the first day each user is due at work, empty
+	// when unknown.
+	GetUserStarts() (map[string]string, error)
 }
 
 type KpiService struct {
-	Repo KpiRepository
+	Repo         KpiRepository
+	MidnightRule MidnightRule
+	// Punctuality rates the arrivals against the planned hours.
+	Punctuality PunctualityPolicy
+	// Schedules provides planned hours; without it the defaults apply
+	// (the policy's default arrival, 7h per day).
+	Schedules *ScheduleService
+	// Leaves provides approved absences, excluded from the expected hours
+	// and from punctuality; without it nobody is ever on leave.
+	Leaves *LeaveService
+	// Contracts set the expected work of each day; users without a contract
+	// are expected to work their planned hours, else 7h.
+	Contracts *ContractService
+	// Overtime holds the admin's overtime rules; without it a day's work
+	// beyond its expected minutes is overtime.
+	Overtime *OvertimeService
+	// Compliance holds the statutory rules checked by the compliance metrics;
+	// without it only the built-in anomalies are reported.
+	Compliance *ComplianceService
 }
 
 func NewKpiService(repo KpiRepository) *KpiService {
-	return &KpiService{Repo: repo}
+	return &KpiService{Repo: repo, MidnightRule: MidnightStartDay, Punctuality: DefaultPunctualityPolicy()}
+}
+
+// MidnightRule decides which day gets the time of a session crossing midnight.
+type MidnightRule string
+
+const (
+	// MidnightStartDay attributes the whole session to the day it started.
+	MidnightStartDay MidnightRule = "start_day"
+	// MidnightSplit splits the session at midnight between the days it covers.
+	MidnightSplit MidnightRule = "split"
+)
+
+// ParseMidnightRule reads a configured rule, empty meaning MidnightStartDay.
+func ParseMidnightRule(v string) (MidnightRule, error) {
+	switch rule := MidnightRule(strings.ToLower(strings.TrimSpace(v))); rule {
+	case "":
+		return MidnightStartDay, nil
+	case MidnightStartDay, MidnightSplit:
+		return rule, nil
+	default:
+		return MidnightStartDay, fmt.Errorf("unknown midnight rule %q", v)
+	}
 }
 
 // Default expected daily work duration in minutes (7h)
 const defaultExpectedDailyMinutes = 7 * 60
 
-// GetUserKpiSummary computes KPIs for a user in a date range; if userID is nil, returns zero-values
-func (s *KpiService) GetUserKpiSummary(ctx context.Context, userID *uuid.UUID, from, to time.Time) (*model.UserKpiSummary, error) {
+// Number of users whose sessions the dashboard's compliance checks read at once
+const compliancePageSize = 200
+
+// GetUserKpiSummary computes KPIs for a user in a date range; if userID is nil, returns zero-values.
+// The worked minutes are listed per day unless another granularity is given;
+// with compare, the headline metrics are compared with another period.
+func (s *KpiService) GetUserKpiSummary(ctx context.Context, userID *uuid.UUID, from, to time.Time, granularity *model.KpiGranularity, compare *model.KpiComparisonInput) (*model.UserKpiSummary, error) {
 	start, end := normalizeWindow(from, to)
+	first, last := start.Format(layoutISO), end.Format(layoutISO)
 
-	entries, err := s.Repo.GetTimeTableEntriesFiltered(uidPtr(userID), nil, &start, &end)
+	// the overtime reads the whole weeks of the window, the other figures
+	// its days only
+	weekStart, weekEnd := weekSpan(start, end)
+	weekEntries, err := s.Repo.GetTimeTableEntriesFiltered(uidPtr(userID), nil, &weekStart, &weekEnd)
 	if err != nil {
 		return nil, err
 	}
+	entries := entriesWithin(weekEntries, first, last)
 
-	todayStr := time.Now().Format(layoutISO)
-	workedMinutes, daily, presentNow, daySet := aggregateDaily(entries, todayStr)
+	todayStr := time.Now().In(end.Location()).Format(layoutISO)
+	workedMinutes, daily, presentNow, daySet := aggregateDaily(entries, todayStr, s.MidnightRule)
 
-	daysPresent := len(daySet)
-	overtimeMinutes := 0
-	expectedTotal := daysPresent * defaultExpectedDailyMinutes
-	if workedMinutes > expectedTotal {
-		overtimeMinutes = workedMinutes - expectedTotal
+	unpaidBreaks := 0
+	autoClosed := 0
+	now := time.Now()
+	for _, e := range entries {
+		unpaidBreaks += unpaidBreakMinutes(e, now)
+		if e.AutoClosed {
+			autoClosed++
+		}
 	}
 
+	planning := s.planning()
+	if userID != nil {
+		planning.preload([]string{userID.String()}, first, last)
+	}
+	daysPresent := len(daySet)
+	engine := s.overtimeEngine(planning)
+	perUserDay, _ := sumByUserDay(weekEntries, now, s.MidnightRule)
+	overtime := engine.total(engine.within(engine.compute(perUserDay), first, last))
+
 	currentStreak := computeStreak(daySet)
 
-	punctualDays, totalScheduledDays := computePunctuality(entries)
+	leaveDays := 0.0
+	if userID != nil && planning.absences != nil {
+		leaveDays = planning.absences.Days(userID.String(), first, last)
+	}
+
+	punctualDays, totalScheduledDays := planning.punctuality(entries)
 	punctualityRate := 0.0
 	if totalScheduledDays > 0 {
 		punctualityRate = float64(punctualDays) / float64(totalScheduledDays)
 	}
 
-	points := buildPoints(daily)
+	points := buildPoints(daily, seriesGranularity(granularity, model.KpiGranularityDay))
 
 	uidOut := ""
 	if userID != nil {
 		uidOut = userID.String()
 	}
 	res := &model.UserKpiSummary{
-		From:              start.Format(layoutISO),
-		To:                end.Format(layoutISO),
-		UserID:            uidOut,
-		WorkedMinutes:     int32(workedMinutes),
-		OvertimeMinutes:   int32(overtimeMinutes),
-		DaysPresent:       int32(daysPresent),
-		CurrentStreakDays: int32(currentStreak),
-		PunctualityRate:   punctualityRate,
-		PresentNow:        presentNow,
-		DailyWorked:       points,
+		From:                   start.Format(layoutISO),
+		To:                     end.Format(layoutISO),
+		UserID:                 uidOut,
+		WorkedMinutes:          int32(workedMinutes),
+		UnpaidBreakMinutes:     int32(unpaidBreaks),
+		OvertimeMinutes:        int32(overtime.minutes),
+		OvertimePremiumMinutes: int32(overtime.premium),
+		OvertimeExcessMinutes:  int32(overtime.excess),
+		OvertimeBands:          engine.bandMinutes(overtime.bands),
+		DaysPresent:            int32(daysPresent),
+		LeaveDays:              leaveDays,
+		CurrentStreakDays:      int32(currentStreak),
+		PunctualityRate:        punctualityRate,
+		PresentNow:             presentNow,
+		AutoClosedEntries:      int32(autoClosed),
+		DailyWorked:            points,
+	}
+	if compare != nil {
+		if res.Comparison, err = s.compareUserKpis(ctx, userID, start, end, compare, res); err != nil {
+			return nil, err
+		}
 	}
 	return res, nil
 }
 
 func (s *KpiService) ExportUserKpiCSV(ctx context.Context, userID *uuid.UUID, from, to time.Time) (string, error) {
-	summary, err := s.GetUserKpiSummary(ctx, userID, from, to)
+	summary, err := s.GetUserKpiSummary(ctx, userID, from, to, nil, nil)
 	if err != nil {
 		return "", err
 	}
@@ -94,11 +193,17 @@ func (s *KpiService) ExportUserKpiCSV(ctx context.Context, userID *uuid.UUID, fr
 		"From",
 		"To",
 		"WorkedMinutes",
+		"UnpaidBreakMinutes",
 		"OvertimeMinutes",
 		"DaysPresent",
+		"LeaveDays",
 		"CurrentStreakDays",
 		"PunctualityRate",
 		"PresentNow",
+		"AutoClosedEntries",
+		"OvertimePremiumMinutes",
+		"OvertimeExcessMinutes",
+		"OvertimeBands",
 	}); err != nil {
 		return "", err
 	}
@@ -108,11 +213,17 @@ func (s *KpiService) ExportUserKpiCSV(ctx context.Context, userID *uuid.UUID, fr
 		summary.From,
 		summary.To,
 		strconv.Itoa(int(summary.WorkedMinutes)),
+		strconv.Itoa(int(summary.UnpaidBreakMinutes)),
 		strconv.Itoa(int(summary.OvertimeMinutes)),
 		strconv.Itoa(int(summary.DaysPresent)),
+		strconv.FormatFloat(summary.LeaveDays, 'f', -1, 64),
 		strconv.Itoa(int(summary.CurrentStreakDays)),
 		fmt.Sprintf("%.2f", summary.PunctualityRate),
 		strconv.FormatBool(summary.PresentNow),
+		strconv.Itoa(int(summary.AutoClosedEntries)),
+		strconv.Itoa(int(summary.OvertimePremiumMinutes)),
+		strconv.Itoa(int(summary.OvertimeExcessMinutes)),
+		formatOvertimeBands(summary.OvertimeBands),
 	}); err != nil {
 		return "", err
 	}
@@ -122,11 +233,26 @@ func (s *KpiService) ExportUserKpiCSV(ctx context.Context, userID *uuid.UUID, fr
 
 }
 
+// formatOvertimeBands renders the bands as "rate%:minutes" separated by ";".
+func formatOvertimeBands(bands []*model.OvertimeBandMinutes) string {
+	parts := make([]string, 0, len(bands))
+	for _, b := range bands {
+		parts = append(parts, fmt.Sprintf("%d%%:%d", b.RatePercent, b.Minutes))
+	}
+	return strings.Join(parts, ";")
+}
+
+// normalizeWindow fills the missing bounds; the window keeps the time zone
+// the bounds were given in, so that "today" is the requested zone's day.
 func normalizeWindow(from, to time.Time) (time.Time, time.Time) {
 	end := to
 	start := from
 	if end.IsZero() {
-		end = time.Now()
+		loc := timezone.Default()
+		if !start.IsZero() {
+			loc = start.Location()
+		}
+		end = time.Now().In(loc)
 	}
 	if start.IsZero() {
 		start = end.AddDate(0, 0, -30)
@@ -137,6 +263,31 @@ func normalizeWindow(from, to time.Time) (time.Time, time.Time) {
 	return start, end
 }
 
+// seriesGranularity is the granularity asked for the KPI series, or the one
+// the series has by default.
+func seriesGranularity(granularity *model.KpiGranularity, own model.KpiGranularity) model.KpiGranularity {
+	if granularity == nil || !granularity.IsValid() {
+		return own
+	}
+	return *granularity
+}
+
+// periodStart returns the first day of the period holding day: the day itself,
+// the Monday of its ISO week, or the first day of its month or quarter. Hours
+// only apply to the coverage, the day series stay daily.
+func periodStart(day time.Time, granularity model.KpiGranularity) time.Time {
+	y, m, d := day.Date()
+	switch granularity {
+	case model.KpiGranularityWeek:
+		return mondayOf(time.Date(y, m, d, 0, 0, 0, 0, day.Location()))
+	case model.KpiGranularityMonth:
+		return time.Date(y, m, 1, 0, 0, 0, 0, day.Location())
+	case model.KpiGranularityQuarter:
+		return time.Date(y, m-(m-1)%3, 1, 0, 0, 0, 0, day.Location())
+	}
+	return time.Date(y, m, d, 0, 0, 0, 0, day.Location())
+}
+
 func uidPtr(u *uuid.UUID) *uuid.UUID {
 	if u == nil {
 		return nil
@@ -145,26 +296,23 @@ func uidPtr(u *uuid.UUID) *uuid.UUID {
 	return &tmp
 }
 
-func aggregateDaily(entries []*model.TimeTableEntry, todayStr string) (worked int, daily map[string]int, presentNow bool, daySet map[string]struct{}) {
+// aggregateDaily sums the worked minutes per day; days present are the days a
+// session started, a night shift counting as one day of presence.
+func aggregateDaily(entries []*model.TimeTableEntry, todayStr string, rule MidnightRule) (worked int, daily map[string]int, presentNow bool, daySet map[string]struct{}) {
 	daily = map[string]int{}
 	daySet = map[string]struct{}{}
+	now := time.Now()
 	for _, e := range entries {
 		day := e.Day
 		daySet[day] = struct{}{}
-		dur := 0
-		if e.Departure != nil && !e.Departure.IsZero() {
-			dur = int(e.Departure.Sub(e.Arrival).Minutes())
-		} else if e.Status {
-			dur = int(time.Since(e.Arrival).Minutes())
-			if day == todayStr {
-				presentNow = true
-			}
+		// a night shift started yesterday is still a current presence
+		if (e.Departure == nil || e.Departure.IsZero()) && e.Status && (day == todayStr || now.Sub(e.Arrival) < 24*time.Hour) {
+			presentNow = true
 		}
-		if dur < 0 {
-			dur = 0
+		for d, dur := range minutesByDay(e, now, rule) {
+			worked += dur
+			daily[d] += dur
 		}
-		worked += dur
-		daily[day] += dur
 	}
 	return
 }
@@ -202,10 +350,180 @@ func computeStreak(daySet map[string]struct{}) int {
 }
 
 func computePunctuality(entries []*model.TimeTableEntry) (punctualDays int, totalScheduled int) {
+	return kpiPlanning{policy: DefaultPunctualityPolicy()}.punctuality(entries)
+}
+
+// kpiPlanning gives the planned hours of each user's day. Without a planner,
+// or for a user with no schedule at all, the defaults apply.
+type kpiPlanning struct {
+	policy    PunctualityPolicy
+	planner   *Planner
+	absences  *AbsenceCalendar
+	contracts *ContractCalendar
+	// starts holds the first day each user is due at work
+	starts map[string]string
+}
+
+func (s *KpiService) planning() kpiPlanning {
+	p := kpiPlanning{policy: s.Punctuality}
+	if s.Schedules != nil {
+		p.planner = s.Schedules.NewPlanner()
+	}
+	if s.Leaves != nil {
+		p.absences = s.Leaves.NewAbsenceCalendar()
+		if p.planner != nil {
+			// the leaves consume the days of the same planning
+			p.absences.planner = p.planner
+		}
+	}
+	if s.Contracts != nil {
+		p.contracts = s.Contracts.NewContractCalendar()
+	}
+	return p
+}
+
+// withStarts bounds the days each user is expected at work by the day they
+// started; without it, by their contracts only.
+func (s *KpiService) withStarts(p kpiPlanning) kpiPlanning {
+	if starts, err := s.Repo.GetUserStarts(); err == nil {
+		p.starts = starts
+	}
+	return p
+}
+
+// preload batch-loads the schedules, holidays and contracts of the users over
+// the whole weeks spanning first to last (YYYY-MM-DD), the weekly figures
+// reading every day of their week. A failed batch falls back to the lookups
+// of each user.
+func (p kpiPlanning) preload(userIDs []string, first, last string) {
+	if len(userIDs) == 0 {
+		return
+	}
+	from, errF := time.Parse(layoutISO, first)
+	to, errT := time.Parse(layoutISO, last)
+	if errF != nil || errT != nil {
+		return
+	}
+	if p.planner != nil {
+		_ = p.planner.Preload(userIDs, mondayOf(from).Format(layoutISO), mondayOf(to).AddDate(0, 0, 6).Format(layoutISO))
+	}
+	if p.contracts != nil {
+		_ = p.contracts.Preload(userIDs)
+	}
+}
+
+// entriesSpan returns the users of the entries and the first and last days
+// they cover.
+func entriesSpan(entries []*model.TimeTableEntry) (userIDs []string, first, last string) {
+	seen := make(map[string]struct{})
 	for _, e := range entries {
-		arrival := e.Arrival
-		threshold := time.Date(arrival.Year(), arrival.Month(), arrival.Day(), 10, 0, 0, 0, arrival.Location())
-		if arrival.Before(threshold) || arrival.Equal(threshold) {
+		if e == nil || e.Day == "" {
+			continue
+		}
+		if first == "" || e.Day < first {
+			first = e.Day
+		}
+		if e.Day > last {
+			last = e.Day
+		}
+		if e.UserID == nil {
+			continue
+		}
+		if _, ok := seen[e.UserID.ID]; !ok {
+			seen[e.UserID.ID] = struct{}{}
+			userIDs = append(userIDs, e.UserID.ID)
+		}
+	}
+	return userIDs, first, last
+}
+
+// entriesWithin keeps the entries of the days from first to last inclusive.
+func entriesWithin(entries []*model.TimeTableEntry, first, last string) []*model.TimeTableEntry {
+	out := make([]*model.TimeTableEntry, 0, len(entries))
+	for _, e := range entries {
+		if e != nil && e.Day >= first && e.Day <= last {
+			out = append(out, e)
+		}
+	}
+	return out
+}
+
+// rollupsWithin keeps the rollups of the days from first to last inclusive.
+func rollupsWithin(rollups []*model.DailyRollup, first, last string) []*model.DailyRollup {
+	out := make([]*model.DailyRollup, 0, len(rollups))
+	for _, r := range rollups {
+		if r != nil && r.Day >= first && r.Day <= last {
+			out = append(out, r)
+		}
+	}
+	return out
+}
+
+// absent is the share of the user's day spent on approved leave.
+func (p kpiPlanning) absent(userID, day string) float64 {
+	if p.absences == nil || userID == "" {
+		return 0
+	}
+	return p.absences.Fraction(userID, day)
+}
+
+// planned returns the user's planned day, or nil when neither a schedule nor
+// a holiday applies.
+func (p kpiPlanning) planned(userID, day string) *model.PlannedSchedule {
+	if p.planner == nil || userID == "" {
+		return nil
+	}
+	planned, err := p.planner.For(userID, day)
+	if err != nil || (planned.Source == model.ScheduleSourceNone && planned.Holiday == nil) {
+		return nil
+	}
+	return planned
+}
+
+// holiday tells whether the day is a public holiday of the user.
+func (p kpiPlanning) holiday(userID, day string) bool {
+	planned := p.planned(userID, day)
+	return planned != nil && planned.Holiday != nil
+}
+
+// scheduledWorkDay tells whether the user is planned to work that day.
+func (p kpiPlanning) scheduledWorkDay(userID, day string) bool {
+	planned := p.planned(userID, day)
+	return planned != nil && planned.WorkingDay
+}
+
+// expectedWorkDay tells whether the user is due at work that day: not on a
+// holiday nor a full day of leave, and on a working day of their contract,
+// else of their schedule, else from Monday to Friday. Users with contracts
+// are not expected outside of them, nor anyone before they started.
+func (p kpiPlanning) expectedWorkDay(userID, day string) bool {
+	if start := p.starts[userID]; start != "" && day < start {
+		return false
+	}
+	if p.holiday(userID, day) || p.absent(userID, day) >= 1 {
+		return false
+	}
+	if p.contracts != nil && userID != "" && p.contracts.HasContracts(userID) {
+		contract := p.contract(userID, day)
+		return contract != nil && contractDailyMinutes(contract, day) > 0
+	}
+	if planned := p.planned(userID, day); planned != nil {
+		return planned.WorkingDay
+	}
+	dt, err := time.Parse(layoutISO, day)
+	if err != nil {
+		return false
+	}
+	return dt.Weekday() != time.Saturday && dt.Weekday() != time.Sunday
+}
+
+func (p kpiPlanning) punctuality(entries []*model.TimeTableEntry) (punctualDays int, totalScheduled int) {
+	for _, e := range firstArrivals(entries) {
+		late, rated := p.lateness(e)
+		if !rated {
+			continue
+		}
+		if late == 0 {
 			punctualDays++
 		}
 		totalScheduled++
@@ -213,11 +531,232 @@ func computePunctuality(entries []*model.TimeTableEntry) (punctualDays int, tota
 	return
 }
 
-func buildPoints(daily map[string]int) []*model.KpiPoint {
-	points := make([]*model.KpiPoint, 0, len(daily))
+// expectedMinutes is the work expected from a user on a day, used as the
+// overtime and efficiency threshold: nothing on a holiday, else what the
+// contract in effect sets, else the planned hours, else the default day.
+// Approved leave removes its share of the day.
+func (p kpiPlanning) expectedMinutes(userID, day string) int {
+	expected := defaultExpectedDailyMinutes
+	planned := p.planned(userID, day)
+	switch {
+	case planned != nil && planned.Holiday != nil:
+		return 0
+	case p.contract(userID, day) != nil:
+		expected = contractDailyMinutes(p.contract(userID, day), day)
+	case planned != nil:
+		expected = int(planned.PlannedMinutes)
+	}
+	return int(float64(expected) * (1 - p.absent(userID, day)))
+}
+
+// contract returns the user's contract in effect on day, or nil.
+func (p kpiPlanning) contract(userID, day string) *model.EmploymentContract {
+	if p.contracts == nil || userID == "" {
+		return nil
+	}
+	return p.contracts.For(userID, day)
+}
+
+// expectedWeek sums the expected minutes of the week starting on monday;
+// without a contract nor a schedule only Monday to Friday are expected.
+func (p kpiPlanning) expectedWeek(userID, monday string) int {
+	start, err := time.Parse(layoutISO, monday)
+	if err != nil {
+		return defaultExpectedWeeklyMinutes
+	}
+	days := 7
+	if p.planner == nil && p.contracts == nil {
+		days = 5
+	}
+	total := 0
+	for i := 0; i < days; i++ {
+		total += p.expectedMinutes(userID, start.AddDate(0, 0, i).Format(layoutISO))
+	}
+	return total
+}
+
+// localArrival returns the arrival in the user's time zone.
+func localArrival(e *model.TimeTableEntry) time.Time {
+	return e.Arrival.In(entryLocation(e))
+}
+
+// entryLocation is the time zone of the entry's user (own zone, else site's),
+// falling back to DEFAULT_TIME_ZONE.
+func entryLocation(e *model.TimeTableEntry) *time.Location {
+	if e.UserID != nil && e.UserID.TimeZone != nil && *e.UserID.TimeZone != "" {
+		return timezone.Resolve(*e.UserID.TimeZone)
+	}
+	return timezone.Default()
+}
+
+// userDay identifies one user's working day; a day may hold several sessions.
+type userDay struct {
+	userID string
+	day    string
+}
+
+// entryMinutes returns the worked duration of a single session, an open
+// session being counted up to now. Unpaid breaks are not worked time.
+func entryMinutes(e *model.TimeTableEntry, now time.Time) int {
+	dur := 0
+	if e.Departure != nil && !e.Departure.IsZero() {
+		dur = int(e.Departure.Sub(e.Arrival).Minutes())
+	} else if e.Status {
+		dur = int(now.Sub(e.Arrival).Minutes())
+	}
+	dur -= unpaidBreakMinutes(e, now)
+	if dur < 0 {
+		dur = 0
+	}
+	return dur
+}
+
+// unpaidBreakMinutes returns the unpaid break time taken during a session; a
+// break still running ends with the session, or now if the session is open.
+func unpaidBreakMinutes(e *model.TimeTableEntry, now time.Time) int {
+	total := 0
+	for _, b := range e.Breaks {
+		if b == nil || b.Paid {
+			continue
+		}
+		bEnd := sessionEnd(e, now)
+		if b.EndedAt != nil && !b.EndedAt.IsZero() {
+			bEnd = *b.EndedAt
+		}
+		if d := int(bEnd.Sub(b.StartedAt).Minutes()); d > 0 {
+			total += d
+		}
+	}
+	return total
+}
+
+// sessionEnd is the departure of a session, or now while it is still open.
+func sessionEnd(e *model.TimeTableEntry, now time.Time) time.Time {
+	if e.Departure != nil && !e.Departure.IsZero() {
+		return *e.Departure
+	}
+	return now
+}
+
+// unpaidBreakOverlap returns the unpaid break time falling within [from, to).
+func unpaidBreakOverlap(e *model.TimeTableEntry, now, from, to time.Time) time.Duration {
+	var total time.Duration
+	for _, b := range e.Breaks {
+		if b == nil || b.Paid {
+			continue
+		}
+		bStart, bEnd := b.StartedAt, sessionEnd(e, now)
+		if b.EndedAt != nil && !b.EndedAt.IsZero() {
+			bEnd = *b.EndedAt
+		}
+		if bStart.Before(from) {
+			bStart = from
+		}
+		if bEnd.After(to) {
+			bEnd = to
+		}
+		if bEnd.After(bStart) {
+			total += bEnd.Sub(bStart)
+		}
+	}
+	return total
+}
+
+// minutesByDay attributes the worked minutes of a session to days according
+// to the midnight rule. With MidnightSplit, a session is cut at each midnight
+// of its user's time zone; the first part stays on the entry's day.
+func minutesByDay(e *model.TimeTableEntry, now time.Time, rule MidnightRule) map[string]int {
+	total := entryMinutes(e, now)
+	end := sessionEnd(e, now)
+	open := e.Departure == nil || e.Departure.IsZero()
+	if rule != MidnightSplit || (open && !e.Status) || !end.After(e.Arrival) {
+		return map[string]int{e.Day: total}
+	}
+	startDay, err := time.Parse(layoutISO, e.Day)
+	if err != nil {
+		return map[string]int{e.Day: total}
+	}
+
+	out := make(map[string]int)
+	var cumulative time.Duration
+	counted := 0
+	segStart := localArrival(e)
+	for i := 0; segStart.Before(end); i++ {
+		y, m, d := segStart.Date()
+		segEnd := time.Date(y, m, d+1, 0, 0, 0, 0, segStart.Location())
+		if segEnd.After(end) {
+			segEnd = end
+		}
+		cumulative += segEnd.Sub(segStart) - unpaidBreakOverlap(e, now, segStart, segEnd)
+		// cumulative rounding keeps the parts adding up to the session total
+		upTo := int(cumulative.Minutes())
+		if upTo > total {
+			upTo = total
+		}
+		if part := upTo - counted; part > 0 {
+			out[startDay.AddDate(0, 0, i).Format(layoutISO)] += part
+		}
+		counted = upTo
+		segStart = segEnd
+	}
+	if len(out) == 0 {
+		out[e.Day] = 0
+	}
+	return out
+}
+
+// sumByUserDay adds up all the sessions of each user's day so that daily
+// thresholds (overtime, excessive hours) apply to the whole day.
+func sumByUserDay(entries []*model.TimeTableEntry, now time.Time, rule MidnightRule) (map[userDay]int, map[string]*model.User) {
+	out := make(map[userDay]int)
+	users := make(map[string]*model.User)
+	for _, e := range entries {
+		if e.UserID == nil {
+			continue
+		}
+		users[e.UserID.ID] = e.UserID
+		for d, dur := range minutesByDay(e, now, rule) {
+			out[userDay{userID: e.UserID.ID, day: d}] += dur
+		}
+	}
+	return out, users
+}
+
+// firstArrivals keeps only the first session of each user's day: coming back
+// from lunch must not count as a late arrival. Entries without user are kept.
+func firstArrivals(entries []*model.TimeTableEntry) []*model.TimeTableEntry {
+	firsts := make(map[userDay]*model.TimeTableEntry)
+	out := make([]*model.TimeTableEntry, 0, len(entries))
+	for _, e := range entries {
+		if e.UserID == nil {
+			out = append(out, e)
+			continue
+		}
+		key := userDay{userID: e.UserID.ID, day: e.Day}
+		if cur, ok := firsts[key]; !ok || e.Arrival.Before(cur.Arrival) {
+			firsts[key] = e
+		}
+	}
+	for _, e := range firsts {
+		out = append(out, e)
+	}
+	sort.SliceStable(out, func(i, j int) bool { return out[i].Arrival.Before(out[j].Arrival) })
+	return out
+}
+
+// buildPoints sums the minutes of each day per period.
+func buildPoints(daily map[string]int, granularity model.KpiGranularity) []*model.KpiPoint {
+	periods := make(map[string]int, len(daily))
 	for d, m := range daily {
-		dt, _ := time.Parse(layoutISO, d)
-		points = append(points, &model.KpiPoint{Date: dt.Format(layoutISO), Minutes: int32(m)})
+		dt, err := time.Parse(layoutISO, d)
+		if err != nil {
+			continue
+		}
+		periods[periodStart(dt, granularity).Format(layoutISO)] += m
+	}
+	points := make([]*model.KpiPoint, 0, len(periods))
+	for d, m := range periods {
+		points = append(points, &model.KpiPoint{Date: d, Minutes: int32(m)})
 	}
 	sort.Slice(points, func(i, j int) bool { return points[i].Date < points[j].Date })
 	return points
@@ -227,23 +766,102 @@ func buildPoints(daily map[string]int) []*model.KpiPoint {
 // - totalWorkedMinutes: sum of durations for all entries in window
 // - avgWorkedMinutesPerUser: total / distinct users appearing in entries
 // - activeUsers: users with an open entry (status true and no departure) at query time
-// - coverage: number of concurrent users per hour slice (ISO timestamps)
-func (s *KpiService) GetTeamKpiSummary(ctx context.Context, teamID uuid.UUID, from, to time.Time) (*model.TeamKpiSummary, error) {
+// - coverage: number of concurrent users per hour slice (ISO timestamps), or
+// of users present in each period of a coarser granularity
+// - comparison: deltas of the totals with another period, when compare is given
+func (s *KpiService) GetTeamKpiSummary(ctx context.Context, teamID uuid.UUID, from, to time.Time, granularity *model.KpiGranularity, compare *model.KpiComparisonInput) (*model.TeamKpiSummary, error) {
 	start, end := normalizeWindow(from, to)
-
-	// fetch entries filtered by team and date range
 	tid := teamID
-	entries, err := s.Repo.GetTimeTableEntriesFiltered(nil, &tid, &start, &end)
+	now := time.Now()
+	coverage := seriesGranularity(granularity, model.KpiGranularityHour)
+
+	var totalWorkedMinutes, distinctUsers, activeUsers int
+	var cov []*model.CoveragePoint
+	var err error
+	if s.storedRollups(start.Format(layoutISO), end.Format(layoutISO)) {
+		// totals are summed by Postgres from the daily rollups
+		var users []*model.KpiUserTotal
+		if users, err = s.Repo.GetKpiUserTotals(nil, &tid, start.Format(layoutISO), end.Format(layoutISO), now); err != nil {
+			return nil, err
+		}
+		for _, u := range users {
+			totalWorkedMinutes += u.Minutes
+			if u.Active {
+				activeUsers++
+			}
+		}
+		distinctUsers = len(users)
+		if cov, err = s.teamCoverage(tid, start, end, now, coverage); err != nil {
+			return nil, err
+		}
+	} else {
+		// until a rebuild covers the window: sum the entries in memory
+		totalWorkedMinutes, distinctUsers, activeUsers, cov, err = s.teamTotalsFromEntries(tid, start, end, now, coverage)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	avg := 0.0
+	if distinctUsers > 0 {
+		avg = float64(totalWorkedMinutes) / float64(distinctUsers)
+	}
+
+	out := &model.TeamKpiSummary{
+		From:                    start.Format(layoutISO),
+		To:                      end.Format(layoutISO),
+		TeamID:                  teamID.String(),
+		TotalWorkedMinutes:      int32(totalWorkedMinutes),
+		AvgWorkedMinutesPerUser: avg,
+		ActiveUsers:             int32(activeUsers),
+		Coverage:                cov,
+	}
+	if compare != nil {
+		if out.Comparison, err = s.compareTeamKpis(ctx, teamID, start, end, compare, out); err != nil {
+			return nil, err
+		}
+	}
+	return out, nil
+}
+
+// teamCoverage has Postgres count the team's users present per hour, from the
+// window's first local hour, or per period of the rollups.
+func (s *KpiService) teamCoverage(teamID uuid.UUID, start, end, now time.Time, granularity model.KpiGranularity) ([]*model.CoveragePoint, error) {
+	loc := start.Location()
+	if granularity == model.KpiGranularityHour {
+		cov, err := s.Repo.GetKpiCoverage(&teamID, start.Format(layoutISO), end.Format(layoutISO), localHour(start, loc), end, now)
+		if err != nil {
+			return nil, err
+		}
+		for _, p := range cov {
+			p.Time = p.Time.In(loc)
+		}
+		return cov, nil
+	}
+	periods, err := s.Repo.GetKpiPeriodTotals(nil, &teamID, start.Format(layoutISO), end.Format(layoutISO), granularity, now)
 	if err != nil {
 		return nil, err
 	}
+	cov := make([]*model.CoveragePoint, 0, len(periods))
+	for _, p := range periods {
+		if t, err := time.ParseInLocation(layoutISO, p.PeriodStart, loc); err == nil {
+			cov = append(cov, &model.CoveragePoint{Time: t, Count: int32(p.Users)})
+		}
+	}
+	return cov, nil
+}
+
+// teamTotalsFromEntries sums the team's entries of the window in memory.
+func (s *KpiService) teamTotalsFromEntries(teamID uuid.UUID, start, end, now time.Time, granularity model.KpiGranularity) (total, users, active int, cov []*model.CoveragePoint, err error) {
+	entries, err := s.Repo.GetTimeTableEntriesFiltered(nil, &teamID, &start, &end)
+	if err != nil {
+		return 0, 0, 0, nil, err
+	}
 
-	totalWorkedMinutes := 0
 	distinctUsers := map[string]struct{}{}
 	activeNow := map[string]struct{}{}
 	coverageCounts := map[string]int{}
-	now := time.Now()
-
+	presence := map[string]map[string]struct{}{}
 	for _, e := range entries {
 		// track distinct users
 		if e.UserID != nil {
@@ -262,34 +880,25 @@ func (s *KpiService) GetTeamKpiSummary(ctx context.Context, teamID uuid.UUID, fr
 			activeNow[e.UserID.ID] = struct{}{}
 		}
 
-		durMin := int(effectiveDep.Sub(arr).Minutes())
-		if durMin < 0 {
-			durMin = 0
-		}
-		totalWorkedMinutes += durMin
+		total += entryMinutes(e, now)
 
-		// coverage per hour buckets
-		addCoverageBuckets(coverageCounts, arr, *effectiveDep, start, end)
-	}
-
-	avg := 0.0
-	if len(distinctUsers) > 0 {
-		avg = float64(totalWorkedMinutes) / float64(len(distinctUsers))
+		// coverage per hour buckets, or users present per period
+		if granularity == model.KpiGranularityHour {
+			addCoverageBuckets(coverageCounts, arr, *effectiveDep, start, end)
+		} else if e.UserID != nil {
+			if day, err := time.ParseInLocation(layoutISO, e.Day, start.Location()); err == nil {
+				key := periodStart(day, granularity).Format(time.RFC3339)
+				if presence[key] == nil {
+					presence[key] = map[string]struct{}{}
+				}
+				presence[key][e.UserID.ID] = struct{}{}
+			}
+		}
 	}
-
-	// build sorted coverage slice
-	cov := buildCoveragePoints(coverageCounts)
-
-	out := &model.TeamKpiSummary{
-		From:                    start.Format(layoutISO),
-		To:                      end.Format(layoutISO),
-		TeamID:                  teamID.String(),
-		TotalWorkedMinutes:      int32(totalWorkedMinutes),
-		AvgWorkedMinutesPerUser: avg,
-		ActiveUsers:             int32(len(activeNow)),
-		Coverage:                cov,
+	for key, users := range presence {
+		coverageCounts[key] = len(users)
 	}
-	return out, nil
+	return total, len(distinctUsers), len(activeNow), buildCoveragePoints(coverageCounts), nil
 }
 
 func effectiveDeparture(dep *time.Time, status bool, now time.Time) *time.Time {
@@ -302,10 +911,14 @@ func effectiveDeparture(dep *time.Time, status bool, now time.Time) *time.Time {
 	return dep
 }
 
+// addCoverageBuckets counts a presence in every hour it overlaps, so not in the
+// hour it ends on the dot. Hours are the window's local hours: stepping by
+// absolute hours from a local hour start keeps DST days at 23 or 25 buckets,
+// each keyed with its own UTC offset.
 func addCoverageBuckets(coverageCounts map[string]int, arr time.Time, effDep time.Time, start, end time.Time) {
-	sh := arr.Truncate(time.Hour)
-	eh := effDep.Truncate(time.Hour)
-	for cur := sh; !cur.After(eh); cur = cur.Add(time.Hour) {
+	loc := start.Location()
+	sh := localHour(arr, loc)
+	for cur := sh; cur.Before(effDep); cur = cur.Add(time.Hour) {
 		if cur.Add(time.Hour).Before(start) {
 			continue
 		}
@@ -317,6 +930,18 @@ func addCoverageBuckets(coverageCounts map[string]int, arr time.Time, effDep tim
 	}
 }
 
+// localHour truncates t to the start of its hour in loc; unlike Truncate, it
+// also handles zones offset by a fraction of an hour.
+func localHour(t time.Time, loc *time.Location) time.Time {
+	t = t.In(loc)
+	h := time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), 0, 0, 0, loc)
+	// the hour repeated when clocks go back resolves to its first occurrence
+	if next := h.Add(time.Hour); !next.After(t) {
+		h = next
+	}
+	return h
+}
+
 func buildCoveragePoints(coverageCounts map[string]int) []*model.CoveragePoint {
 	type kv struct {
 		t string
@@ -336,41 +961,54 @@ func buildCoveragePoints(coverageCounts map[string]int) []*model.CoveragePoint {
 	return cov
 }
 
-// GetAdminKpiDashboard returns comprehensive KPI dashboard for admins
-func (s *KpiService) GetAdminKpiDashboard(ctx context.Context, from, to time.Time) (*model.AdminKpiDashboard, error) {
+// GetAdminKpiDashboard returns comprehensive KPI dashboard for admins. The
+// series keep their own period unless a granularity is given; with compare,
+// the headline metrics are compared with another period.
+func (s *KpiService) GetAdminKpiDashboard(ctx context.Context, from, to time.Time, granularity *model.KpiGranularity, compare *model.KpiComparisonInput) (*model.AdminKpiDashboard, error) {
+	return s.GetKpiDashboard(ctx, nil, nil, from, to, granularity, compare)
+}
+
+// GetKpiDashboard computes the dashboard over a user or the members of a team
+// when given, as scoped by the AccessService, else over everyone.
+func (s *KpiService) GetKpiDashboard(ctx context.Context, userID *uuid.UUID, teamID *uuid.UUID, from, to time.Time, granularity *model.KpiGranularity, compare *model.KpiComparisonInput) (*model.AdminKpiDashboard, error) {
 	start, end := normalizeWindow(from, to)
+	now := time.Now()
+	trend := seriesGranularity(granularity, model.KpiGranularityWeek)
 
-	// Fetch all entries in the period
-	entries, err := s.Repo.GetTimeTableEntriesFiltered(nil, nil, &start, &end)
+	// Only the compliance checks need the sessions, read page by page of
+	// users; the other metrics are sums of the daily rollups
+	figures, err := s.dashboardFigures(userID, teamID, start, end, now, trend)
 	if err != nil {
 		return nil, err
 	}
 
-	// Collect all unique users from entries
-	userSet := make(map[string]struct{})
-	for _, e := range entries {
-		if e.UserID != nil {
-			userSet[e.UserID.ID] = struct{}{}
-		}
-	}
-
 	// Get all teams
 	allTeams, err := s.Repo.GetTeams()
 	if err != nil {
 		allTeams = []*model.Team{}
 	}
+	staff, err := s.Repo.GetUsersWithTeams()
+	if err != nil {
+		return nil, err
+	}
+	staff = staffWithin(staff, userID, teamID)
+	allTeams = teamsWithin(allTeams, staff, userID, teamID)
+	names := userNames(staff)
 
-	summary := s.computeAdminSummary(entries, userSet, start, end)
+	summary := s.computeAdminSummary(figures)
 	summary.TotalTeams = int32(len(allTeams))
 
-	workload := s.computeWorkloadAnalysis(entries, userSet)
-	punctuality := s.computePunctualityMetrics(entries)
-	overtime := s.computeOvertimeReport(entries, userSet)
-	compliance := s.computeComplianceMetrics(entries)
-	productivity := s.computeProductivityMetrics(entries, userSet)
-	teams := s.computeTeamDetailedReportsForAllTeams(ctx, allTeams, start, end)
+	workload := s.computeWorkloadAnalysis(figures, seriesGranularity(granularity, model.KpiGranularityDay))
+	punctuality := s.computePunctualityMetrics(figures)
+	overtime := s.computeOvertimeReport(figures, names)
+	compliance, err := s.dashboardCompliance(staff, start, end, trend)
+	if err != nil {
+		return nil, err
+	}
+	productivity := s.computeProductivityMetrics(figures, names, seriesGranularity(granularity, model.KpiGranularityDay))
+	teams := s.computeTeamDetailedReportsForAllTeams(ctx, allTeams, staff, figures, names)
 
-	return &model.AdminKpiDashboard{
+	dashboard := &model.AdminKpiDashboard{
 		Period: &model.DateRange{
 			From: start.Format(layoutISO),
 			To:   end.Format(layoutISO),
@@ -382,49 +1020,167 @@ func (s *KpiService) GetAdminKpiDashboard(ctx context.Context, from, to time.Tim
 		Compliance:   compliance,
 		Productivity: productivity,
 		Teams:        teams,
-	}, nil
+	}
+	if compare != nil {
+		if dashboard.Comparison, err = s.compareAdminKpis(ctx, userID, teamID, start, end, compare, dashboard); err != nil {
+			return nil, err
+		}
+	}
+	return dashboard, nil
 }
 
-func (s *KpiService) computeAdminSummary(entries []*model.TimeTableEntry, userSet map[string]struct{}, start, end time.Time) *model.AdminKpiSummary {
-	totalMinutes := 0
-	activeUsers := make(map[string]struct{})
-	now := time.Now()
+// staffWithin keeps the user, or the members of the team, when given.
+func staffWithin(staff []*model.UserWithAllData, userID *uuid.UUID, teamID *uuid.UUID) []*model.UserWithAllData {
+	if userID == nil && teamID == nil {
+		return staff
+	}
+	out := make([]*model.UserWithAllData, 0)
+	for _, u := range staff {
+		if u == nil || (userID != nil && u.ID != userID.String()) {
+			continue
+		}
+		if teamID == nil || inTeam(u, teamID.String()) {
+			out = append(out, u)
+		}
+	}
+	return out
+}
 
-	for _, e := range entries {
-		dur := 0
-		if e.Departure != nil && !e.Departure.IsZero() {
-			dur = int(e.Departure.Sub(e.Arrival).Minutes())
-		} else if e.Status {
-			dur = int(now.Sub(e.Arrival).Minutes())
-			if e.UserID != nil {
-				activeUsers[e.UserID.ID] = struct{}{}
+// teamsWithin keeps the team when given, else the teams of the user.
+func teamsWithin(teams []*model.Team, staff []*model.UserWithAllData, userID *uuid.UUID, teamID *uuid.UUID) []*model.Team {
+	if userID == nil && teamID == nil {
+		return teams
+	}
+	out := make([]*model.Team, 0)
+	for _, t := range teams {
+		if t == nil {
+			continue
+		}
+		if teamID != nil {
+			if t.ID == teamID.String() {
+				out = append(out, t)
 			}
+			continue
 		}
-		if dur > 0 {
-			totalMinutes += dur
+		for _, u := range staff {
+			if inTeam(u, t.ID) {
+				out = append(out, t)
+				break
+			}
+		}
+	}
+	return out
+}
+
+// inTeam tells whether the user is a member of the team.
+func inTeam(u *model.UserWithAllData, teamID string) bool {
+	for _, t := range u.Teams {
+		if t != nil && t.ID == teamID {
+			return true
+		}
+	}
+	return false
+}
+
+// dashboardFigures sums the rollups of the window, in Postgres once a rebuild
+// covers its whole weeks. Until then the rollups are computed from the
+// entries of those weeks.
+func (s *KpiService) dashboardFigures(userID *uuid.UUID, teamID *uuid.UUID, start, end, now time.Time, granularity model.KpiGranularity) (*kpiFigures, error) {
+	weekStart, weekEnd := weekSpan(start, end)
+	if s.storedRollups(weekStart.AddDate(0, 0, 1).Format(layoutISO), weekEnd.Format(layoutISO)) {
+		return s.sumKpiFigures(userID, teamID, start, end, now, granularity)
+	}
+	entries, err := s.Repo.GetTimeTableEntriesFiltered(userID, teamID, &weekStart, &weekEnd)
+	if err != nil {
+		return nil, err
+	}
+	return kpiFiguresOf(s.dailyRollups(entries, now), start.Format(layoutISO), end.Format(layoutISO), now, granularity), nil
+}
+
+// dashboardCompliance checks the compliance of the staff and looks for their
+// no-shows page by page of users, so that only one page of sessions is held
+// at a time, with the weeks around the window the statutory rules read.
+func (s *KpiService) dashboardCompliance(staff []*model.UserWithAllData, start, end time.Time, granularity model.KpiGranularity) (*model.ComplianceMetrics, error) {
+	rules := s.complianceRules()
+	tally := newComplianceTally(rules, granularity)
+	from, to := complianceSpan(rules, start, end)
+	first, last := start.Format(layoutISO), end.Format(layoutISO)
+	byTeam := make(map[string]*model.NoShowCount)
+	for i := 0; i < len(staff); i += compliancePageSize {
+		page := staff[i:min(i+compliancePageSize, len(staff))]
+		userIDs := make([]uuid.UUID, 0, len(page))
+		for _, u := range page {
+			if uid, err := uuid.Parse(u.ID); err == nil {
+				userIDs = append(userIDs, uid)
+			}
+		}
+		read, err := s.Repo.GetUsersTimeTableEntries(userIDs, from.Format(layoutISO), to.Format(layoutISO))
+		if err != nil {
+			return nil, err
+		}
+		entries := make([]*model.TimeTableEntry, 0, len(read))
+		history := &complianceHistory{first: first, last: last}
+		for _, e := range read {
+			if e.Day >= first && e.Day <= last {
+				entries = append(entries, e)
+			} else {
+				history.entries = append(history.entries, e)
+			}
+		}
+		missing, noShows := s.computeMissingEntries(page, entries, start, end)
+		for _, n := range noShows {
+			if byTeam[n.ID] == nil {
+				byTeam[n.ID] = &model.NoShowCount{ID: n.ID, Name: n.Name}
+			}
+			byTeam[n.ID].Days += n.Days
+		}
+		s.tallyCompliance(tally, entries, history, missing)
+	}
+	compliance := tally.metrics()
+	compliance.NoShowsByTeam = sortedNoShows(byTeam)
+	return compliance, nil
+}
+
+// userNames maps the users to their full name.
+func userNames(users []*model.UserWithAllData) map[string]string {
+	names := make(map[string]string, len(users))
+	for _, u := range users {
+		if u != nil {
+			names[u.ID] = strings.TrimSpace(u.FirstName + " " + u.LastName)
+		}
+	}
+	return names
+}
+
+func (s *KpiService) computeAdminSummary(figures *kpiFigures) *model.AdminKpiSummary {
+	totalMinutes := 0
+	activeUsers := 0
+	for _, u := range figures.users {
+		if u.Active {
+			activeUsers++
 		}
+		totalMinutes += u.Minutes
 	}
 
 	avgHoursPerUser := 0.0
-	if len(userSet) > 0 {
-		avgHoursPerUser = float64(totalMinutes) / float64(len(userSet)) / 60.0
+	if len(figures.users) > 0 {
+		avgHoursPerUser = float64(totalMinutes) / float64(len(figures.users)) / 60.0
 	}
 
 	// Compliance rate (simplified: entries with departure / total)
-	completeEntries := 0
-	for _, e := range entries {
-		if e.Departure != nil && !e.Departure.IsZero() {
-			completeEntries++
-		}
+	sessions, completeEntries := 0, 0
+	for _, u := range figures.users {
+		sessions += u.Sessions
+		completeEntries += u.Sessions - u.OpenSessions
 	}
 	complianceRate := 0.0
-	if len(entries) > 0 {
-		complianceRate = float64(completeEntries) / float64(len(entries))
+	if sessions > 0 {
+		complianceRate = float64(completeEntries) / float64(sessions)
 	}
 
 	return &model.AdminKpiSummary{
-		TotalUsers:       int32(len(userSet)),
-		ActiveUsers:      int32(len(activeUsers)),
+		TotalUsers:       int32(len(figures.users)),
+		ActiveUsers:      int32(activeUsers),
 		TotalTeams:       0, // Will be filled from teams query
 		TotalWorkedHours: int32(totalMinutes / 60),
 		AvgHoursPerUser:  avgHoursPerUser,
@@ -432,81 +1188,50 @@ func (s *KpiService) computeAdminSummary(entries []*model.TimeTableEntry, userSe
 	}
 }
 
-func (s *KpiService) computeWorkloadAnalysis(entries []*model.TimeTableEntry, userSet map[string]struct{}) *model.WorkloadAnalysis {
-	dailyMinutes := make(map[string]int)
-	dayOfWeekMinutes := make(map[string]int)
-	userOvertime := make(map[string]int)
-
-	for _, e := range entries {
-		dur := 0
-		if e.Departure != nil && !e.Departure.IsZero() {
-			dur = int(e.Departure.Sub(e.Arrival).Minutes())
-		} else if e.Status {
-			dur = int(time.Since(e.Arrival).Minutes())
-		}
-		if dur > 0 {
-			dailyMinutes[e.Day] += dur
-
-			// Track day of week
-			if dt, err := time.Parse(layoutISO, e.Day); err == nil {
-				dayName := dt.Weekday().String()
-				dayOfWeekMinutes[dayName] += dur
-			}
-
-			// Track overtime per user
-			if e.UserID != nil && dur > defaultExpectedDailyMinutes {
-				userOvertime[e.UserID.ID] += (dur - defaultExpectedDailyMinutes)
-			}
-		}
-	}
-
+// computeWorkloadAnalysis sums the work of the days, and per period of the
+// granularity for the series.
+func (s *KpiService) computeWorkloadAnalysis(figures *kpiFigures, granularity model.KpiGranularity) *model.WorkloadAnalysis {
 	// Find peak day
 	peakDay := ""
 	peakMinutes := 0
-	for day, min := range dailyMinutes {
-		if min > peakMinutes {
-			peakMinutes = min
-			peakDay = day
+	totalMinutes := 0
+	daily := make(map[string]int, len(figures.days))
+	for _, d := range figures.days {
+		if d.Minutes > peakMinutes {
+			peakMinutes = d.Minutes
+			peakDay = d.PeriodStart
 		}
+		totalMinutes += d.Minutes
+		daily[d.PeriodStart] += d.Minutes
 	}
 
 	// Average daily and weekly
-	totalMinutes := 0
-	for _, min := range dailyMinutes {
-		totalMinutes += min
-	}
 	avgDailyMinutes := 0.0
-	if len(dailyMinutes) > 0 {
-		avgDailyMinutes = float64(totalMinutes) / float64(len(dailyMinutes))
+	if len(figures.days) > 0 {
+		avgDailyMinutes = float64(totalMinutes) / float64(len(figures.days))
 	}
 	avgWeeklyMinutes := avgDailyMinutes * 5
 
 	// Distribution by day
-	distribution := make([]*model.DayDistribution, 0)
-	for day, min := range dayOfWeekMinutes {
-		days := 0
-		for d := range dailyMinutes {
-			if dt, err := time.Parse(layoutISO, d); err == nil && dt.Weekday().String() == day {
-				days++
-			}
-		}
+	distribution := make([]*model.DayDistribution, 0, len(figures.weekdays))
+	for _, wd := range figures.weekdays {
 		avgMin := 0.0
-		if days > 0 {
-			avgMin = float64(min) / float64(days)
+		if wd.Days > 0 {
+			avgMin = float64(wd.Minutes) / float64(wd.Days)
 		}
 		distribution = append(distribution, &model.DayDistribution{
-			Day:          day,
+			Day:          time.Weekday(wd.Weekday).String(),
 			AvgMinutes:   avgMin,
-			TotalMinutes: int32(min),
+			TotalMinutes: int32(wd.Minutes),
 		})
 	}
 
-	// Total overtime
+	// Total overtime, settled per user on the sum of each day's sessions
 	totalOvertime := 0
 	usersWithOvertime := 0
-	for _, ot := range userOvertime {
-		totalOvertime += ot
-		if ot > 0 {
+	for _, u := range figures.users {
+		totalOvertime += u.OvertimeMinutes
+		if u.OvertimeMinutes > 0 {
 			usersWithOvertime++
 		}
 	}
@@ -519,43 +1244,25 @@ func (s *KpiService) computeWorkloadAnalysis(entries []*model.TimeTableEntry, us
 		DistributionByDay: distribution,
 		TotalOvertime:     int32(totalOvertime),
 		UsersWithOvertime: int32(usersWithOvertime),
+		WorkedByPeriod:    buildPoints(daily, granularity),
 	}
 }
 
-func (s *KpiService) computePunctualityMetrics(entries []*model.TimeTableEntry) *model.PunctualityMetrics {
+func (s *KpiService) computePunctualityMetrics(figures *kpiFigures) *model.PunctualityMetrics {
 	onTimeCount := 0
 	lateCount := 0
 	totalLateMinutes := 0
-	lateUsers := make(map[string]struct{})
-	punctualUsers := make(map[string]struct{})
-	weeklyData := make(map[string]*struct{ onTime, late, lateMin int })
-
-	for _, e := range entries {
-		arrival := e.Arrival
-		threshold := time.Date(arrival.Year(), arrival.Month(), arrival.Day(), 10, 0, 0, 0, arrival.Location())
-
-		// Get week start
-		weekStart := arrival.AddDate(0, 0, -int(arrival.Weekday()))
-		weekKey := weekStart.Format(layoutISO)
-		if weeklyData[weekKey] == nil {
-			weeklyData[weekKey] = &struct{ onTime, late, lateMin int }{}
+	punctualUsers := 0
+	lateUsers := 0
+	for _, u := range figures.users {
+		onTimeCount += u.OnTimeDays
+		lateCount += u.LateDays
+		totalLateMinutes += u.LateMinutes
+		if u.OnTimeDays > 0 {
+			punctualUsers++
 		}
-
-		if arrival.Before(threshold) || arrival.Equal(threshold) {
-			onTimeCount++
-			weeklyData[weekKey].onTime++
-			if e.UserID != nil {
-				punctualUsers[e.UserID.ID] = struct{}{}
-			}
-		} else {
-			lateCount++
-			lateMin := int(arrival.Sub(threshold).Minutes())
-			totalLateMinutes += lateMin
-			weeklyData[weekKey].late++
-			weeklyData[weekKey].lateMin += lateMin
-			if e.UserID != nil {
-				lateUsers[e.UserID.ID] = struct{}{}
-			}
+		if u.LateDays > 0 {
+			lateUsers++
 		}
 	}
 
@@ -573,108 +1280,55 @@ func (s *KpiService) computePunctualityMetrics(entries []*model.TimeTableEntry)
 	}
 
 	// Build trends
-	trends := make([]*model.PunctualityTrend, 0)
-	for week, data := range weeklyData {
-		total := data.onTime + data.late
+	trends := make([]*model.PunctualityTrend, 0, len(figures.punctuality))
+	for _, period := range figures.punctuality {
+		total := period.OnTime + period.Late
 		rate := 0.0
 		avgLate := 0.0
 		if total > 0 {
-			rate = float64(data.onTime) / float64(total)
+			rate = float64(period.OnTime) / float64(total)
 		}
-		if data.late > 0 {
-			avgLate = float64(data.lateMin) / float64(data.late)
+		if period.Late > 0 {
+			avgLate = float64(period.LateMinutes) / float64(period.Late)
 		}
 		trends = append(trends, &model.PunctualityTrend{
-			WeekStart:      week,
+			WeekStart:      period.PeriodStart,
 			OnTimeRate:     rate,
 			AvgLateMinutes: avgLate,
 		})
 	}
-	sort.Slice(trends, func(i, j int) bool { return trends[i].WeekStart < trends[j].WeekStart })
 
 	return &model.PunctualityMetrics{
 		OnTimeRate:         onTimeRate,
 		LateRate:           lateRate,
 		AvgLateMinutes:     avgLateMinutes,
 		TotalLateIncidents: int32(lateCount),
-		PunctualUsers:      int32(len(punctualUsers)),
-		LateUsers:          int32(len(lateUsers)),
+		PunctualUsers:      int32(punctualUsers),
+		LateUsers:          int32(lateUsers),
 		TrendByWeek:        trends,
 	}
 }
 
-func (s *KpiService) computeOvertimeReport(entries []*model.TimeTableEntry, userSet map[string]struct{}) *model.OvertimeReport {
-	userOvertimeMap := make(map[string]*struct {
-		minutes int
-		days    map[string]struct{}
-		name    string
-	})
-
-	weeklyOvertime := make(map[string]*struct{ minutes, users int })
-
-	for _, e := range entries {
-		dur := 0
-		if e.Departure != nil && !e.Departure.IsZero() {
-			dur = int(e.Departure.Sub(e.Arrival).Minutes())
-		} else if e.Status {
-			dur = int(time.Since(e.Arrival).Minutes())
-		}
-
-		if dur > defaultExpectedDailyMinutes && e.UserID != nil {
-			overtime := dur - defaultExpectedDailyMinutes
-			userID := e.UserID.ID
-
-			if userOvertimeMap[userID] == nil {
-				userName := e.UserID.FirstName + " " + e.UserID.LastName
-				userOvertimeMap[userID] = &struct {
-					minutes int
-					days    map[string]struct{}
-					name    string
-				}{days: make(map[string]struct{}), name: userName}
-			}
-			userOvertimeMap[userID].minutes += overtime
-			userOvertimeMap[userID].days[e.Day] = struct{}{}
-
-			// Weekly aggregation
-			if dt, err := time.Parse(layoutISO, e.Day); err == nil {
-				weekStart := dt.AddDate(0, 0, -int(dt.Weekday()))
-				weekKey := weekStart.Format(layoutISO)
-				if weeklyOvertime[weekKey] == nil {
-					weeklyOvertime[weekKey] = &struct{ minutes, users int }{}
-				}
-				weeklyOvertime[weekKey].minutes += overtime
-			}
-		}
-	}
-
-	// Count distinct users with overtime per week
-	userWeekMap := make(map[string]map[string]struct{})
-	for userID, data := range userOvertimeMap {
-		for day := range data.days {
-			if dt, err := time.Parse(layoutISO, day); err == nil {
-				weekStart := dt.AddDate(0, 0, -int(dt.Weekday()))
-				weekKey := weekStart.Format(layoutISO)
-				if userWeekMap[weekKey] == nil {
-					userWeekMap[weekKey] = make(map[string]struct{})
-				}
-				userWeekMap[weekKey][userID] = struct{}{}
-			}
-		}
-	}
-	for week, users := range userWeekMap {
-		if weeklyOvertime[week] != nil {
-			weeklyOvertime[week].users = len(users)
+// computeOvertimeReport reports the overtime of the window from the sums of
+// its rollups per week and per period of the series granularity.
+func (s *KpiService) computeOvertimeReport(figures *kpiFigures, names map[string]string) *model.OvertimeReport {
+	engine := s.overtimeEngine(kpiPlanning{})
+	perUser := engine.fromWeeks(figures.overtime)
+	daysWorked := make(map[string]int)
+	for _, w := range figures.overtime {
+		daysWorked[w.UserID] += w.Days
+	}
+	for userID, ot := range perUser {
+		if ot.minutes == 0 {
+			delete(perUser, userID)
 		}
 	}
-
-	totalOvertime := 0
-	for _, data := range userOvertimeMap {
-		totalOvertime += data.minutes
-	}
+	total := engine.total(perUser)
+	totalOvertime := total.minutes
 
 	avgOvertimePerUser := 0.0
-	if len(userSet) > 0 {
-		avgOvertimePerUser = float64(totalOvertime) / float64(len(userSet))
+	if len(figures.users) > 0 {
+		avgOvertimePerUser = float64(totalOvertime) / float64(len(figures.users))
 	}
 
 	// Top overtime users
@@ -682,15 +1336,17 @@ func (s *KpiService) computeOvertimeReport(entries []*model.TimeTableEntry, user
 		id      string
 		name    string
 		minutes int
+		premium int
 		days    int
 	}
 	topUsers := make([]userOT, 0)
-	for userID, data := range userOvertimeMap {
+	for userID, ot := range perUser {
 		topUsers = append(topUsers, userOT{
 			id:      userID,
-			name:    data.name,
-			minutes: data.minutes,
-			days:    len(data.days),
+			name:    names[userID],
+			minutes: ot.minutes,
+			premium: ot.premium,
+			days:    daysWorked[userID],
 		})
 	}
 	sort.Slice(topUsers, func(i, j int) bool { return topUsers[i].minutes > topUsers[j].minutes })
@@ -704,172 +1360,398 @@ func (s *KpiService) computeOvertimeReport(entries []*model.TimeTableEntry, user
 			UserID:          u.id,
 			UserName:        u.name,
 			OvertimeMinutes: int32(u.minutes),
+			PremiumMinutes:  int32(u.premium),
 			DaysWorked:      int32(u.days),
 		})
 	}
 
-	// Overtime by week
+	// Overtime by period, weeks starting on Monday as in the policy
 	overtimeByWeek := make([]*model.OvertimeByPeriod, 0)
-	for week, data := range weeklyOvertime {
+	for _, p := range figures.periods {
+		if p.OvertimeMinutes <= 0 {
+			continue
+		}
 		overtimeByWeek = append(overtimeByWeek, &model.OvertimeByPeriod{
-			PeriodStart:  week,
-			TotalMinutes: int32(data.minutes),
-			UsersCount:   int32(data.users),
+			PeriodStart:  p.PeriodStart,
+			TotalMinutes: int32(p.OvertimeMinutes),
+			UsersCount:   int32(p.OvertimeUsers),
 		})
 	}
 	sort.Slice(overtimeByWeek, func(i, j int) bool { return overtimeByWeek[i].PeriodStart < overtimeByWeek[j].PeriodStart })
 
 	return &model.OvertimeReport{
 		TotalOvertimeMinutes: int32(totalOvertime),
+		TotalPremiumMinutes:  int32(total.premium),
+		TotalExcessMinutes:   int32(total.excess),
+		Bands:                engine.bandMinutes(total.bands),
 		AvgOvertimePerUser:   avgOvertimePerUser,
-		UsersWithOvertime:    int32(len(userOvertimeMap)),
+		UsersWithOvertime:    int32(len(perUser)),
 		TopOvertimeUsers:     topOvertimeUsers,
 		OvertimeByWeek:       overtimeByWeek,
 	}
 }
 
-func (s *KpiService) computeComplianceMetrics(entries []*model.TimeTableEntry) *model.ComplianceMetrics {
-	missingClockouts := 0
-	excessiveHours := 0
-	weekendWork := 0
-	usersWithIssues := make(map[string]struct{})
+// complianceRules returns the statutory rules, nil when they can't be read.
+func (s *KpiService) complianceRules() []*model.ComplianceRule {
+	if s.Compliance == nil {
+		return nil
+	}
+	rules, err := s.Compliance.GetComplianceRules()
+	if err != nil {
+		return nil
+	}
+	return rules
+}
+
+// computeComplianceMetrics checks the entries against the statutory rules and
+// the usual anomalies, and counts them per period of the granularity on the
+// day they happened. The rules also read the sessions of the history, but
+// only the breaches within its window are reported.
+func (s *KpiService) computeComplianceMetrics(entries []*model.TimeTableEntry, history *complianceHistory, missing []*model.MissingEntry, granularity model.KpiGranularity) *model.ComplianceMetrics {
+	tally := newComplianceTally(s.complianceRules(), granularity)
+	s.tallyCompliance(tally, entries, history, missing)
+	return tally.metrics()
+}
+
+// complianceTally adds up the anomalies, breaches and no-shows of pages of
+// users, each user's sessions being all in one page.
+type complianceTally struct {
+	rules       []*model.ComplianceRule
+	dailyRule   bool
+	granularity model.KpiGranularity
+
+	counts          map[string]int // anomaly type -> count
+	entries         int
+	completeEntries int
+	usersWithIssues map[string]struct{}
+	affected        map[string]map[string]struct{} // anomaly type -> users
+	trends          map[string]*model.ComplianceTrend
+	violations      []*model.ComplianceViolation
+	missing         []*model.MissingEntry
+	noShows         map[string]*model.NoShowCount
+}
+
+func newComplianceTally(rules []*model.ComplianceRule, granularity model.KpiGranularity) *complianceTally {
+	t := &complianceTally{
+		rules:           rules,
+		granularity:     granularity,
+		counts:          make(map[string]int),
+		usersWithIssues: make(map[string]struct{}),
+		affected:        make(map[string]map[string]struct{}),
+		trends:          make(map[string]*model.ComplianceTrend),
+		violations:      make([]*model.ComplianceViolation, 0),
+		missing:         make([]*model.MissingEntry, 0),
+		noShows:         make(map[string]*model.NoShowCount),
+	}
+	for _, rule := range rules {
+		if rule != nil && rule.Enabled && rule.Code == model.ComplianceRuleCodeMaxDailyHours {
+			t.dailyRule = true
+		}
+	}
+	return t
+}
+
+func (t *complianceTally) flag(anomaly, userID string) {
+	if userID == "" {
+		return
+	}
+	if t.affected[anomaly] == nil {
+		t.affected[anomaly] = make(map[string]struct{})
+	}
+	t.affected[anomaly][userID] = struct{}{}
+	t.usersWithIssues[userID] = struct{}{}
+}
+
+// anomaly counts an anomaly of the user on the day.
+func (t *complianceTally) anomaly(anomaly, userID, day string) {
+	t.counts[anomaly]++
+	t.flag(anomaly, userID)
+	t.trend(day).Anomalies++
+}
+
+func (t *complianceTally) trend(day string) *model.ComplianceTrend {
+	dt, err := time.Parse(layoutISO, day)
+	if err != nil {
+		return &model.ComplianceTrend{}
+	}
+	key := periodStart(dt, t.granularity).Format(layoutISO)
+	if t.trends[key] == nil {
+		t.trends[key] = &model.ComplianceTrend{Date: key}
+	}
+	return t.trends[key]
+}
+
+// tallyCompliance adds the anomalies and breaches of the entries, and the
+// no-shows, to the tally.
+func (s *KpiService) tallyCompliance(t *complianceTally, entries []*model.TimeTableEntry, history *complianceHistory, missing []*model.MissingEntry) {
+	planning := s.planning()
+	planning.preload(entriesSpan(entries))
 
 	for _, e := range entries {
-		hasIssue := false
+		userID := ""
+		if e.UserID != nil {
+			userID = e.UserID.ID
+		}
+
+		// Forgotten clock-out closed by the sweeper
+		if e.AutoClosed {
+			t.anomaly("auto_closed", userID, e.Day)
+		}
 
 		// Missing clockout
 		if (e.Departure == nil || e.Departure.IsZero()) && !e.Status {
-			missingClockouts++
-			hasIssue = true
+			t.anomaly("missing_clockout", userID, e.Day)
 		}
 
-		// Excessive hours (>12h)
-		if e.Departure != nil && !e.Departure.IsZero() {
+		// Excessive hours (>12h), unless the statutory daily maximum
+		// already reports the day
+		if !t.dailyRule && e.Departure != nil && !e.Departure.IsZero() {
 			dur := int(e.Departure.Sub(e.Arrival).Minutes())
 			if dur > 720 { // 12 hours
-				excessiveHours++
-				hasIssue = true
+				t.anomaly("excessive_hours", userID, e.Day)
 			}
 		}
 
-		// Weekend work
-		if dt, err := time.Parse(layoutISO, e.Day); err == nil {
-			if dt.Weekday() == time.Saturday || dt.Weekday() == time.Sunday {
-				weekendWork++
-				hasIssue = true
+		// Holiday work, then weekend work outside of the user's schedule
+		if planning.holiday(userID, e.Day) {
+			t.anomaly("holiday_work", userID, e.Day)
+		} else if dt, err := time.Parse(layoutISO, e.Day); err == nil {
+			if (dt.Weekday() == time.Saturday || dt.Weekday() == time.Sunday) && !planning.scheduledWorkDay(userID, e.Day) {
+				t.anomaly("weekend_work", userID, e.Day)
 			}
 		}
 
-		if hasIssue && e.UserID != nil {
-			usersWithIssues[e.UserID.ID] = struct{}{}
+		t.entries++
+		if e.Departure != nil && !e.Departure.IsZero() {
+			t.completeEntries++
 		}
 	}
 
-	completeEntries := 0
-	for _, e := range entries {
-		if e.Departure != nil && !e.Departure.IsZero() {
-			completeEntries++
+	// Statutory rules: every breach is listed
+	if t.rules != nil {
+		checked, first, last := entries, "", ""
+		if history != nil {
+			checked = append(append(make([]*model.TimeTableEntry, 0, len(history.entries)+len(entries)), history.entries...), entries...)
+			first, last = history.first, history.last
+		}
+		for _, v := range violationsWithin(checkCompliance(t.rules, checked, time.Now(), s.MidnightRule), first, last) {
+			t.flag(strings.ToLower(string(v.Rule)), v.UserID)
+			t.trend(v.Date).Anomalies++
+			t.violations = append(t.violations, v)
 		}
 	}
+
+	// No-shows: expected working days without any entry
+	for _, m := range missing {
+		t.flag("no_show", m.UserID)
+		tr := t.trend(m.Date)
+		tr.Anomalies++
+		tr.MissingEntries++
+		if t.noShows[m.UserID] == nil {
+			t.noShows[m.UserID] = &model.NoShowCount{ID: m.UserID, Name: m.UserName}
+		}
+		t.noShows[m.UserID].Days++
+		t.missing = append(t.missing, m)
+	}
+}
+
+// metrics returns the compliance metrics of everything tallied.
+func (t *complianceTally) metrics() *model.ComplianceMetrics {
 	complianceRate := 0.0
-	if len(entries) > 0 {
-		complianceRate = float64(completeEntries) / float64(len(entries))
+	if t.entries > 0 {
+		complianceRate = float64(t.completeEntries) / float64(t.entries)
 	}
 
 	anomalies := []*model.ComplianceAnomaly{
-		{
-			Type:          "missing_clockout",
-			Count:         int32(missingClockouts),
-			Severity:      "medium",
-			AffectedUsers: int32(len(usersWithIssues)),
-		},
-		{
-			Type:          "excessive_hours",
-			Count:         int32(excessiveHours),
-			Severity:      "high",
-			AffectedUsers: int32(len(usersWithIssues)),
-		},
-		{
-			Type:          "weekend_work",
-			Count:         int32(weekendWork),
-			Severity:      "low",
-			AffectedUsers: int32(len(usersWithIssues)),
-		},
+		{Type: "missing_clockout", Count: int32(t.counts["missing_clockout"]), Severity: "medium"},
+	}
+	if !t.dailyRule {
+		anomalies = append(anomalies, &model.ComplianceAnomaly{Type: "excessive_hours", Count: int32(t.counts["excessive_hours"]), Severity: "high"})
+	}
+	anomalies = append(anomalies,
+		&model.ComplianceAnomaly{Type: "weekend_work", Count: int32(t.counts["weekend_work"]), Severity: "low"},
+		&model.ComplianceAnomaly{Type: "holiday_work", Count: int32(t.counts["holiday_work"]), Severity: "medium"},
+		&model.ComplianceAnomaly{Type: "auto_closed", Count: int32(t.counts["auto_closed"]), Severity: "low"},
+	)
+	totalAnomalies := 0
+	for _, count := range t.counts {
+		totalAnomalies += count
+	}
+
+	// Statutory rules: the breaches are counted per rule
+	sort.Slice(t.violations, func(i, j int) bool {
+		a, b := t.violations[i], t.violations[j]
+		if a.Date != b.Date {
+			return a.Date < b.Date
+		}
+		if a.UserID != b.UserID {
+			return a.UserID < b.UserID
+		}
+		return a.Rule < b.Rule
+	})
+	if t.rules != nil {
+		counts := make(map[model.ComplianceRuleCode]int)
+		for _, v := range t.violations {
+			counts[v.Rule]++
+		}
+		for _, rule := range t.rules {
+			if !rule.Enabled {
+				continue
+			}
+			anomalies = append(anomalies, &model.ComplianceAnomaly{
+				Type:     strings.ToLower(string(rule.Code)),
+				Count:    int32(counts[rule.Code]),
+				Severity: strings.ToLower(string(rule.Severity)),
+			})
+		}
+		totalAnomalies += len(t.violations)
 	}
+	anomalies = append(anomalies, &model.ComplianceAnomaly{Type: "no_show", Count: int32(len(t.missing)), Severity: "medium"})
+	totalAnomalies += len(t.missing)
 
-	totalAnomalies := missingClockouts + excessiveHours + weekendWork
+	for _, a := range anomalies {
+		a.AffectedUsers = int32(len(t.affected[a.Type]))
+	}
+	sort.Slice(t.missing, func(i, j int) bool {
+		if t.missing[i].Date != t.missing[j].Date {
+			return t.missing[i].Date < t.missing[j].Date
+		}
+		return t.missing[i].UserName < t.missing[j].UserName
+	})
+	anomaliesByPeriod := make([]*model.ComplianceTrend, 0, len(t.trends))
+	for _, tr := range t.trends {
+		anomaliesByPeriod = append(anomaliesByPeriod, tr)
+	}
+	sort.Slice(anomaliesByPeriod, func(i, j int) bool { return anomaliesByPeriod[i].Date < anomaliesByPeriod[j].Date })
 
 	return &model.ComplianceMetrics{
-		MissingEntriesCount:    0, // Would need additional data to compute
-		IncompleteEntriesCount: int32(missingClockouts),
+		MissingEntriesCount:    int32(len(t.missing)),
+		IncompleteEntriesCount: int32(t.counts["missing_clockout"]),
 		AnomaliesCount:         int32(totalAnomalies),
 		ComplianceRate:         complianceRate,
-		UsersWithIssues:        int32(len(usersWithIssues)),
+		UsersWithIssues:        int32(len(t.usersWithIssues)),
 		Anomalies:              anomalies,
+		Violations:             t.violations,
+		MissingEntries:         t.missing,
+		NoShowsByUser:          sortedNoShows(t.noShows),
+		NoShowsByTeam:          []*model.NoShowCount{},
+		AnomaliesByPeriod:      anomaliesByPeriod,
 	}
 }
 
-func (s *KpiService) computeProductivityMetrics(entries []*model.TimeTableEntry, userSet map[string]struct{}) *model.ProductivityMetrics {
-	totalProductiveMinutes := 0
-	userProductivity := make(map[string]*struct {
-		minutes int
-		name    string
-	})
-	dailyProductivity := make(map[string]*struct{ minutes, users int })
-
+// computeMissingEntries lists, for each user but admins, the days of the
+// window up to yesterday they were due at work without any time entry, and
+// counts them per team.
+func (s *KpiService) computeMissingEntries(staff []*model.UserWithAllData, entries []*model.TimeTableEntry, start, end time.Time) ([]*model.MissingEntry, []*model.NoShowCount) {
+	present := make(map[userDay]struct{}, len(entries))
 	for _, e := range entries {
-		dur := 0
-		if e.Departure != nil && !e.Departure.IsZero() {
-			dur = int(e.Departure.Sub(e.Arrival).Minutes())
-		} else if e.Status {
-			dur = int(time.Since(e.Arrival).Minutes())
-		}
-
-		if dur > 0 {
-			totalProductiveMinutes += dur
-
-			if e.UserID != nil {
-				userID := e.UserID.ID
-				if userProductivity[userID] == nil {
-					userName := e.UserID.FirstName + " " + e.UserID.LastName
-					userProductivity[userID] = &struct {
-						minutes int
-						name    string
-					}{name: userName}
-				}
-				userProductivity[userID].minutes += dur
-			}
-
-			if dailyProductivity[e.Day] == nil {
-				dailyProductivity[e.Day] = &struct{ minutes, users int }{}
-			}
-			dailyProductivity[e.Day].minutes += dur
+		if e.UserID != nil {
+			present[userDay{userID: e.UserID.ID, day: e.Day}] = struct{}{}
 		}
 	}
+	today := time.Now().In(start.Location()).Format(layoutISO)
+	last := end.Format(layoutISO)
 
-	// Count distinct users per day
-	dayUserMap := make(map[string]map[string]struct{})
-	for _, e := range entries {
-		if e.UserID != nil {
-			if dayUserMap[e.Day] == nil {
-				dayUserMap[e.Day] = make(map[string]struct{})
+	planning := s.withStarts(s.planning())
+	staffIDs := make([]string, 0, len(staff))
+	for _, u := range staff {
+		if u != nil && u.Role != model.RoleAdmin {
+			staffIDs = append(staffIDs, u.ID)
+		}
+	}
+	planning.preload(staffIDs, start.Format(layoutISO), last)
+	missing := make([]*model.MissingEntry, 0)
+	byTeam := make(map[string]*model.NoShowCount)
+	for _, u := range staff {
+		if u == nil || u.Role == model.RoleAdmin {
+			continue
+		}
+		for d := start; ; d = d.AddDate(0, 0, 1) {
+			day := d.Format(layoutISO)
+			if day > last || day >= today {
+				break
+			}
+			if _, ok := present[userDay{userID: u.ID, day: day}]; ok || !planning.expectedWorkDay(u.ID, day) {
+				continue
+			}
+			missing = append(missing, &model.MissingEntry{
+				UserID:          u.ID,
+				UserName:        u.FirstName + " " + u.LastName,
+				Date:            day,
+				ExpectedMinutes: int32(planning.expectedMinutes(u.ID, day)),
+			})
+			for _, t := range u.Teams {
+				if byTeam[t.ID] == nil {
+					byTeam[t.ID] = &model.NoShowCount{ID: t.ID, Name: t.Name}
+				}
+				byTeam[t.ID].Days++
 			}
-			dayUserMap[e.Day][e.UserID.ID] = struct{}{}
 		}
 	}
-	for day, users := range dayUserMap {
-		if dailyProductivity[day] != nil {
-			dailyProductivity[day].users = len(users)
+	sort.Slice(missing, func(i, j int) bool {
+		if missing[i].Date != missing[j].Date {
+			return missing[i].Date < missing[j].Date
+		}
+		return missing[i].UserName < missing[j].UserName
+	})
+	return missing, sortedNoShows(byTeam)
+}
+
+// sortedNoShows orders the counts from the most no-show days.
+func sortedNoShows(counts map[string]*model.NoShowCount) []*model.NoShowCount {
+	out := make([]*model.NoShowCount, 0, len(counts))
+	for _, c := range counts {
+		out = append(out, c)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Days != out[j].Days {
+			return out[i].Days > out[j].Days
+		}
+		return out[i].Name < out[j].Name
+	})
+	return out
+}
+
+// computeProductivityMetrics rates the work of the window against the work
+// each user was expected to do on its active days, as set by their contract
+// or schedule; the trend sums the days per period of the granularity.
+func (s *KpiService) computeProductivityMetrics(figures *kpiFigures, names map[string]string, granularity model.KpiGranularity) *model.ProductivityMetrics {
+	totalProductiveMinutes := 0
+	first, last := "", ""
+	for _, d := range figures.days {
+		totalProductiveMinutes += d.Minutes
+		if first == "" || d.PeriodStart < first {
+			first = d.PeriodStart
 		}
+		last = maxString(last, d.PeriodStart)
 	}
 
 	avgHoursPerUser := 0.0
-	if len(userSet) > 0 {
-		avgHoursPerUser = float64(totalProductiveMinutes) / float64(len(userSet)) / 60.0
+	if len(figures.users) > 0 {
+		avgHoursPerUser = float64(totalProductiveMinutes) / float64(len(figures.users)) / 60.0
+	}
+
+	// Efficiency rate: productive minutes over the work each user was expected
+	// to do on the active days
+	planning := s.planning()
+	userIDs := make([]string, 0, len(figures.users))
+	for _, u := range figures.users {
+		userIDs = append(userIDs, u.UserID)
+	}
+	if first != "" {
+		planning.preload(userIDs, first, last)
+	}
+	expectedByDay := make(map[string]int, len(figures.days))
+	expectedByUser := make(map[string]int, len(figures.users))
+	expectedTotal := 0
+	for _, d := range figures.days {
+		for _, userID := range userIDs {
+			expected := planning.expectedMinutes(userID, d.PeriodStart)
+			expectedByDay[d.PeriodStart] += expected
+			expectedByUser[userID] += expected
+			expectedTotal += expected
+		}
 	}
-
-	// Efficiency rate (simplified: ratio of productive hours to expected)
-	expectedTotal := len(userSet) * defaultExpectedDailyMinutes * len(dailyProductivity)
 	avgEfficiencyRate := 0.0
 	if expectedTotal > 0 {
 		avgEfficiencyRate = float64(totalProductiveMinutes) / float64(expectedTotal)
@@ -883,17 +1765,19 @@ func (s *KpiService) computeProductivityMetrics(entries []*model.TimeTableEntry,
 		hours      int
 	}
 	topPerformers := make([]userProd, 0)
-	for userID, data := range userProductivity {
-		expectedUserMinutes := defaultExpectedDailyMinutes * len(dailyProductivity)
+	for _, u := range figures.users {
+		if u.Minutes <= 0 {
+			continue
+		}
 		efficiency := 0.0
-		if expectedUserMinutes > 0 {
-			efficiency = float64(data.minutes) / float64(expectedUserMinutes)
+		if expectedByUser[u.UserID] > 0 {
+			efficiency = float64(u.Minutes) / float64(expectedByUser[u.UserID])
 		}
 		topPerformers = append(topPerformers, userProd{
-			id:         userID,
-			name:       data.name,
+			id:         u.UserID,
+			name:       names[u.UserID],
 			efficiency: efficiency,
-			hours:      data.minutes / 60,
+			hours:      u.Minutes / 60,
 		})
 	}
 	sort.Slice(topPerformers, func(i, j int) bool { return topPerformers[i].efficiency > topPerformers[j].efficiency })
@@ -911,16 +1795,28 @@ func (s *KpiService) computeProductivityMetrics(entries []*model.TimeTableEntry,
 		})
 	}
 
-	// Productivity trend
+	// Productivity trend, the days summed per period
+	periodProductivity := make(map[string]*struct{ minutes, expected int })
+	for _, d := range figures.days {
+		dt, err := time.Parse(layoutISO, d.PeriodStart)
+		if err != nil {
+			continue
+		}
+		key := periodStart(dt, granularity).Format(layoutISO)
+		if periodProductivity[key] == nil {
+			periodProductivity[key] = &struct{ minutes, expected int }{}
+		}
+		periodProductivity[key].minutes += d.Minutes
+		periodProductivity[key].expected += expectedByDay[d.PeriodStart]
+	}
 	productivityTrend := make([]*model.ProductivityTrend, 0)
-	for day, data := range dailyProductivity {
-		expectedDay := data.users * defaultExpectedDailyMinutes
+	for period, data := range periodProductivity {
 		avgEff := 0.0
-		if expectedDay > 0 {
-			avgEff = float64(data.minutes) / float64(expectedDay)
+		if data.expected > 0 {
+			avgEff = float64(data.minutes) / float64(data.expected)
 		}
 		productivityTrend = append(productivityTrend, &model.ProductivityTrend{
-			Date:          day,
+			Date:          period,
 			AvgEfficiency: avgEff,
 			TotalHours:    int32(data.minutes / 60),
 		})
@@ -942,111 +1838,82 @@ func (s *KpiService) computeTeamDetailedReports(ctx context.Context, entries []*
 	return make([]*model.TeamDetailedReport, 0)
 }
 
-func (s *KpiService) computeTeamDetailedReportsForAllTeams(ctx context.Context, teams []*model.Team, start, end time.Time) []*model.TeamDetailedReport {
+// computeTeamDetailedReportsForAllTeams splits the totals of everyone, and
+// their work per period, between the teams of their users, without a query
+// per team.
+func (s *KpiService) computeTeamDetailedReportsForAllTeams(ctx context.Context, teams []*model.Team, staff []*model.UserWithAllData, figures *kpiFigures, names map[string]string) []*model.TeamDetailedReport {
 	reports := make([]*model.TeamDetailedReport, 0)
 
-	for _, team := range teams {
-		if team == nil {
+	userTeams := make(map[string][]string)
+	for _, u := range staff {
+		if u == nil {
 			continue
 		}
-
-		// Parse team ID
-		teamID, err := uuid.Parse(team.ID)
-		if err != nil {
-			continue
+		for _, t := range u.Teams {
+			if t != nil {
+				userTeams[u.ID] = append(userTeams[u.ID], t.ID)
+			}
+		}
+	}
+	byTeam := make(map[string][]*model.KpiUserTotal)
+	for _, u := range figures.users {
+		for _, teamID := range userTeams[u.UserID] {
+			byTeam[teamID] = append(byTeam[teamID], u)
 		}
+	}
+	periodsByTeam := make(map[string]map[string]int)
+	for _, p := range figures.userPeriods {
+		for _, teamID := range userTeams[p.UserID] {
+			if periodsByTeam[teamID] == nil {
+				periodsByTeam[teamID] = make(map[string]int)
+			}
+			periodsByTeam[teamID][p.PeriodStart] += p.Minutes
+		}
+	}
 
-		// Get entries for this team
-		entries, err := s.Repo.GetTimeTableEntriesFiltered(nil, &teamID, &start, &end)
-		if err != nil {
+	for _, team := range teams {
+		if team == nil {
 			continue
 		}
 
-		report := s.computeSingleTeamReport(ctx, team.ID, entries)
+		report := s.computeSingleTeamReport(ctx, team, byTeam[team.ID], periodsByTeam[team.ID], names)
 		reports = append(reports, report)
 	}
 
 	return reports
 }
 
-func (s *KpiService) computeSingleTeamReport(ctx context.Context, teamID string, entries []*model.TimeTableEntry) *model.TeamDetailedReport {
+// computeSingleTeamReport reports on the totals of the team's users; periods
+// maps the start of each period of the series to the team's work in it.
+func (s *KpiService) computeSingleTeamReport(ctx context.Context, team *model.Team, users []*model.KpiUserTotal, periods map[string]int, names map[string]string) *model.TeamDetailedReport {
 	totalMinutes := 0
-	userMinutes := make(map[string]*struct {
-		minutes  int
-		overtime int
-		days     map[string]struct{}
-		name     string
-	})
-	activeNow := make(map[string]struct{})
-	teamName := "Unknown Team"
-
-	// Try to get team name from repository
-	if tid, err := uuid.Parse(teamID); err == nil {
-		if team, err := s.Repo.GetTeamByUUID(tid); err == nil && team != nil {
-			teamName = team.Name
-		}
+	workers := make([]*model.KpiUserTotal, 0, len(users))
+	activeNow := 0
+	teamID := team.ID
+	teamName := team.Name
+	if teamName == "" {
+		teamName = "Unknown Team"
 	}
 
-	for _, e := range entries {
-		dur := 0
-		if e.Departure != nil && !e.Departure.IsZero() {
-			dur = int(e.Departure.Sub(e.Arrival).Minutes())
-		} else if e.Status {
-			dur = int(time.Since(e.Arrival).Minutes())
-			if e.UserID != nil {
-				activeNow[e.UserID.ID] = struct{}{}
-			}
+	for _, u := range users {
+		if u.Active {
+			activeNow++
 		}
-
-		if dur > 0 {
-			totalMinutes += dur
-
-			if e.UserID != nil {
-				userID := e.UserID.ID
-				if userMinutes[userID] == nil {
-					userName := e.UserID.FirstName + " " + e.UserID.LastName
-					userMinutes[userID] = &struct {
-						minutes  int
-						overtime int
-						days     map[string]struct{}
-						name     string
-					}{days: make(map[string]struct{}), name: userName}
-				}
-				userMinutes[userID].minutes += dur
-				userMinutes[userID].days[e.Day] = struct{}{}
-
-				if dur > defaultExpectedDailyMinutes {
-					userMinutes[userID].overtime += (dur - defaultExpectedDailyMinutes)
-				}
-			}
+		if u.Minutes > 0 {
+			totalMinutes += u.Minutes
+			workers = append(workers, u)
 		}
 	}
 
-	memberCount := len(userMinutes)
+	memberCount := len(workers)
 	avgMinutesPerMember := 0.0
 	if memberCount > 0 {
 		avgMinutesPerMember = float64(totalMinutes) / float64(memberCount)
 	}
 
 	// Top contributors
-	type contributor struct {
-		id       string
-		name     string
-		minutes  int
-		days     int
-		overtime int
-	}
-	contributors := make([]contributor, 0)
-	for userID, data := range userMinutes {
-		contributors = append(contributors, contributor{
-			id:       userID,
-			name:     data.name,
-			minutes:  data.minutes,
-			days:     len(data.days),
-			overtime: data.overtime,
-		})
-	}
-	sort.Slice(contributors, func(i, j int) bool { return contributors[i].minutes > contributors[j].minutes })
+	contributors := append([]*model.KpiUserTotal(nil), workers...)
+	sort.Slice(contributors, func(i, j int) bool { return contributors[i].Minutes > contributors[j].Minutes })
 	if len(contributors) > 5 {
 		contributors = contributors[:5]
 	}
@@ -1054,11 +1921,11 @@ func (s *KpiService) computeSingleTeamReport(ctx context.Context, teamID string,
 	topContributors := make([]*model.TeamMemberContribution, 0)
 	for _, c := range contributors {
 		topContributors = append(topContributors, &model.TeamMemberContribution{
-			UserID:          c.id,
-			UserName:        c.name,
-			WorkedMinutes:   int32(c.minutes),
-			DaysPresent:     int32(c.days),
-			OvertimeMinutes: int32(c.overtime),
+			UserID:          c.UserID,
+			UserName:        names[c.UserID],
+			WorkedMinutes:   int32(c.Minutes),
+			DaysPresent:     int32(c.Days),
+			OvertimeMinutes: int32(c.OvertimeMinutes),
 		})
 	}
 
@@ -1068,8 +1935,8 @@ func (s *KpiService) computeSingleTeamReport(ctx context.Context, teamID string,
 		"40-50h": 0,
 		"50+h":   0,
 	}
-	for _, data := range userMinutes {
-		hours := data.minutes / 60
+	for _, u := range workers {
+		hours := u.Minutes / 60
 		if hours < 40 {
 			ranges["0-40h"]++
 		} else if hours < 50 {
@@ -1098,8 +1965,10 @@ func (s *KpiService) computeSingleTeamReport(ctx context.Context, teamID string,
 		MemberCount:          int32(memberCount),
 		TotalWorkedMinutes:   int32(totalMinutes),
 		AvgMinutesPerMember:  avgMinutesPerMember,
-		ActiveNow:            int32(len(activeNow)),
+		ActiveNow:            int32(activeNow),
 		TopContributors:      topContributors,
 		WorkloadDistribution: workloadDist,
+		// the periods already follow the granularity of the series
+		WorkedByPeriod: buildPoints(periods, model.KpiGranularityDay),
 	}
 }
@@ -2,28 +2,115 @@ package services
 
 import (
 	"context"
+	"os"
 	"testing"
 	"time"
 
 	"github.com/epitech/timemanager/internal/graph/model"
+	"github.com/epitech/timemanager/package/timezone"
 	"github.com/google/uuid"
 	"github.com/stretchr/testify/assert"
 )
 
-// mock implementation of KpiRepository
-type mockKpiRepo struct {
-	entries []*model.TimeTableEntry
-	err     error
+// TestMain pins DEFAULT_TIME_ZONE so that the users without a zone are read in
+// UTC whatever the machine's zone.
+func TestMain(m *testing.M) {
+	if err := timezone.SetDefault("UTC"); err != nil {
+		panic(err)
+	}
+	os.Exit(m.Run())
 }
 
-// GetTeamByUUID implements KpiRepository.
-func (m *mockKpiRepo) GetTeamByUUID(teamID uuid.UUID) (*model.Team, error) {
-	panic("unimplemented")
+// mock implementation of KpiRepository
+type mockKpiRepo struct {
+	entries  []*model.TimeTableEntry
+	users    []*model.UserWithAllData
+	rollups  []*model.DailyRollup
+	teams    []*model.Team
+	coverage []*model.CoveragePoint
+	// rebuilt lists the days whose rollups are complete
+	rebuilt []*model.RollupCoverage
+	// starts holds the first day each user is due at work
+	starts map[string]string
+	err    error
 }
 
 // GetTeams implements KpiRepository.
 func (m *mockKpiRepo) GetTeams() ([]*model.Team, error) {
-	panic("unimplemented")
+	return m.teams, nil
+}
+
+func (m *mockKpiRepo) GetUsersWithTeams() ([]*model.UserWithAllData, error) {
+	return m.users, nil
+}
+
+func (m *mockKpiRepo) GetRollupCoverage() ([]*model.RollupCoverage, error) {
+	return m.rebuilt, nil
+}
+
+func (m *mockKpiRepo) GetUserStarts() (map[string]string, error) {
+	return m.starts, nil
+}
+
+func (m *mockKpiRepo) GetDailyRollups(userID *uuid.UUID, teamID *uuid.UUID, from, to string) ([]*model.DailyRollup, error) {
+	return m.rollups, nil
+}
+
+// the sums of the rollups are done in memory, the way Postgres does them
+func (m *mockKpiRepo) GetKpiPeriodTotals(userID *uuid.UUID, teamID *uuid.UUID, from, to string, granularity model.KpiGranularity, now time.Time) ([]*model.KpiPeriodTotal, error) {
+	return periodTotalsOf(m.rollups, now, granularity), m.err
+}
+
+func (m *mockKpiRepo) GetKpiUserPeriodTotals(userID *uuid.UUID, teamID *uuid.UUID, from, to string, granularity model.KpiGranularity, now time.Time) ([]*model.KpiUserPeriodTotal, error) {
+	return userPeriodTotalsOf(m.rollups, now, granularity), m.err
+}
+
+func (m *mockKpiRepo) GetKpiWeekdayTotals(userID *uuid.UUID, teamID *uuid.UUID, from, to string, now time.Time) ([]*model.KpiWeekdayTotal, error) {
+	return kpiFiguresOf(m.rollups, from, to, now, model.KpiGranularityDay).weekdays, m.err
+}
+
+func (m *mockKpiRepo) GetKpiUserTotals(userID *uuid.UUID, teamID *uuid.UUID, from, to string, now time.Time) ([]*model.KpiUserTotal, error) {
+	return kpiFiguresOf(m.rollups, from, to, now, model.KpiGranularityDay).users, m.err
+}
+
+func (m *mockKpiRepo) GetKpiPunctualityPeriods(userID *uuid.UUID, teamID *uuid.UUID, from, to string, granularity model.KpiGranularity) ([]*model.KpiPunctualityPeriod, error) {
+	return kpiFiguresOf(m.rollups, from, to, time.Now(), granularity).punctuality, m.err
+}
+
+// rollupFigures sums all the rollups the way the dashboard does.
+func rollupFigures(rollups []*model.DailyRollup, granularity model.KpiGranularity) *kpiFigures {
+	first, last := "", ""
+	for _, r := range rollups {
+		if first == "" || r.Day < first {
+			first = r.Day
+		}
+		last = maxString(last, r.Day)
+	}
+	return kpiFiguresOf(rollups, first, last, time.Now(), granularity)
+}
+
+func rollupNames(rollups []*model.DailyRollup) map[string]string {
+	names := make(map[string]string)
+	for _, r := range rollups {
+		names[r.UserID] = r.UserName
+	}
+	return names
+}
+
+func overtimeReportOf(svc *KpiService, rollups []*model.DailyRollup, granularity model.KpiGranularity) *model.OvertimeReport {
+	return svc.computeOvertimeReport(rollupFigures(rollups, granularity), rollupNames(rollups))
+}
+
+func productivityOf(svc *KpiService, rollups []*model.DailyRollup, granularity model.KpiGranularity) *model.ProductivityMetrics {
+	return svc.computeProductivityMetrics(rollupFigures(rollups, granularity), rollupNames(rollups), granularity)
+}
+
+func (m *mockKpiRepo) GetKpiOvertimeWeeks(userID *uuid.UUID, teamID *uuid.UUID, weekStart, from, to string) ([]*model.KpiOvertimeWeek, error) {
+	return overtimeWeeksOf(m.rollups, from, to), m.err
+}
+
+func (m *mockKpiRepo) GetKpiCoverage(teamID *uuid.UUID, from, to string, start, end, now time.Time) ([]*model.CoveragePoint, error) {
+	return m.coverage, m.err
 }
 
 var layoutISOs = "2024-01-10"
@@ -33,9 +120,23 @@ func (m *mockKpiRepo) GetTimeTableEntriesFiltered(userID *uuid.UUID, teamID *uui
 	return m.entries, m.err
 }
 
+func (m *mockKpiRepo) GetUsersTimeTableEntries(userIDs []uuid.UUID, from, to string) ([]*model.TimeTableEntry, error) {
+	users := make(map[string]struct{}, len(userIDs))
+	for _, id := range userIDs {
+		users[id.String()] = struct{}{}
+	}
+	out := make([]*model.TimeTableEntry, 0)
+	for _, e := range m.entries {
+		if _, ok := users[e.UserID.ID]; ok && e.Day >= from && e.Day <= to {
+			out = append(out, e)
+		}
+	}
+	return out, m.err
+}
+
 // mustParseDayHour kept simple here; not used in the final test but handy if needed later
 func mustParseDayHour(day string, h, min int) time.Time {
-	loc := time.Local
+	loc := time.UTC
 	t, _ := time.ParseInLocation("2006-01-02 15:04", day+" "+
 		time.Date(2006, 1, 2, h, min, 0, 0, loc).Format("15:04"), loc)
 	return t
@@ -47,10 +148,10 @@ func TestKpiServiceGetUserKpiSummaryBusinessAggregation(t *testing.T) {
 
 	u := &model.User{ID: uuid.New().String()}
 
-	a1 := time.Date(2024, 1, 10, 9, 0, 0, 0, time.Local)
-	d1 := time.Date(2024, 1, 10, 17, 0, 0, 0, time.Local)
-	a2 := time.Date(2024, 1, 11, 10, 30, 0, 0, time.Local)
-	d2 := time.Date(2024, 1, 11, 18, 0, 0, 0, time.Local)
+	a1 := time.Date(2024, 1, 10, 9, 0, 0, 0, time.UTC)
+	d1 := time.Date(2024, 1, 10, 17, 0, 0, 0, time.UTC)
+	a2 := time.Date(2024, 1, 11, 10, 30, 0, 0, time.UTC)
+	d2 := time.Date(2024, 1, 11, 18, 0, 0, 0, time.UTC)
 
 	repo := &mockKpiRepo{entries: []*model.TimeTableEntry{
 		{UserID: u, Day: day1, Arrival: a1, Departure: &d1, Status: false},
@@ -59,10 +160,10 @@ func TestKpiServiceGetUserKpiSummaryBusinessAggregation(t *testing.T) {
 
 	svc := NewKpiService(repo)
 	uid := uuid.New()
-	from := time.Date(2024, 1, 1, 0, 0, 0, 0, time.Local)
-	to := time.Date(2024, 1, 31, 23, 59, 59, 0, time.Local)
+	from := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2024, 1, 31, 23, 59, 59, 0, time.UTC)
 
-	got, err := svc.GetUserKpiSummary(context.Background(), &uid, from, to)
+	got, err := svc.GetUserKpiSummary(context.Background(), &uid, from, to, nil, nil)
 	assert.NoError(t, err)
 	assert.NotNil(t, got)
 
@@ -115,7 +216,7 @@ func TestAggregateDaily(t *testing.T) {
 		{Day: today, Arrival: a1, Departure: &d1, Status: false},
 		{Day: "2024-01-14", Arrival: a2, Departure: nil, Status: true},
 	}
-	worked, daily, presentNow, daySet := aggregateDaily(entries, today)
+	worked, daily, presentNow, daySet := aggregateDaily(entries, today, MidnightStartDay)
 	// first three entries 3*60 = 180; ongoing adds >= minutes since 2024-01-14 09:00 (positive). We only assert lower bound.
 	assert.GreaterOrEqual(t, worked, 180)
 	assert.Equal(t, 2, len(daySet))
@@ -125,7 +226,7 @@ func TestAggregateDaily(t *testing.T) {
 	// If ongoing entry is today, presentNow must be true
 	entries[3].Day = today
 	entries[3].Arrival = time.Date(2024, 1, 15, 8, 0, 0, 0, time.UTC)
-	_, _, presentNow2, _ := aggregateDaily(entries, today)
+	_, _, presentNow2, _ := aggregateDaily(entries, today, MidnightStartDay)
 	assert.True(t, presentNow2)
 }
 
@@ -143,18 +244,19 @@ func TestComputeStreak(t *testing.T) {
 }
 
 func TestComputePunctuality(t *testing.T) {
-	a1 := time.Date(2024, 1, 10, 9, 59, 59, 0, time.Local)
-	a2 := time.Date(2024, 1, 10, 10, 0, 0, 0, time.Local)
-	a3 := time.Date(2024, 1, 10, 10, 1, 0, 0, time.Local)
-	e := []*model.TimeTableEntry{{Arrival: a1}, {Arrival: a2}, {Arrival: a3}}
+	a1 := time.Date(2024, 1, 10, 9, 59, 59, 0, time.UTC)
+	a2 := time.Date(2024, 1, 10, 10, 0, 0, 0, time.UTC)
+	a3 := time.Date(2024, 1, 10, 10, 15, 0, 0, time.UTC)
+	a4 := time.Date(2024, 1, 10, 10, 16, 0, 0, time.UTC)
+	e := []*model.TimeTableEntry{{Arrival: a1}, {Arrival: a2}, {Arrival: a3}, {Arrival: a4}}
 	punctual, total := computePunctuality(e)
-	assert.Equal(t, 2, punctual)
-	assert.Equal(t, 3, total)
+	assert.Equal(t, 3, punctual, "due by 10:00, with 15 minutes of grace")
+	assert.Equal(t, 4, total)
 }
 
 func TestBuildPoints(t *testing.T) {
 	daily := map[string]int{layoutISOs: 60, dater: 30}
-	pts := buildPoints(daily)
+	pts := buildPoints(daily, model.KpiGranularityDay)
 	assert.Equal(t, 2, len(pts))
 	assert.Equal(t, dater, pts[0].Date)
 	assert.Equal(t, int32(30), pts[0].Minutes)
@@ -162,9 +264,29 @@ func TestBuildPoints(t *testing.T) {
 	assert.Equal(t, int32(60), pts[1].Minutes)
 }
 
+func TestPeriodStart(t *testing.T) {
+	sunday := time.Date(2024, 3, 31, 18, 0, 0, 0, time.UTC)
+	assert.Equal(t, "2024-03-31", periodStart(sunday, model.KpiGranularityDay).Format(layoutISO))
+	assert.Equal(t, "2024-03-31", periodStart(sunday, model.KpiGranularityHour).Format(layoutISO))
+	assert.Equal(t, "2024-03-25", periodStart(sunday, model.KpiGranularityWeek).Format(layoutISO), "ISO weeks start on Monday")
+	assert.Equal(t, "2024-03-01", periodStart(sunday, model.KpiGranularityMonth).Format(layoutISO))
+	assert.Equal(t, "2024-01-01", periodStart(sunday, model.KpiGranularityQuarter).Format(layoutISO))
+	assert.Equal(t, "2024-10-01", periodStart(time.Date(2024, 12, 31, 0, 0, 0, 0, time.UTC), model.KpiGranularityQuarter).Format(layoutISO))
+
+	week := model.KpiGranularityWeek
+	assert.Equal(t, model.KpiGranularityWeek, seriesGranularity(&week, model.KpiGranularityDay))
+	assert.Equal(t, model.KpiGranularityDay, seriesGranularity(nil, model.KpiGranularityDay))
+}
+
+func TestBuildPointsPerMonth(t *testing.T) {
+	daily := map[string]int{"2024-01-10": 60, "2024-01-31": 30, "2024-02-01": 45}
+	pts := buildPoints(daily, model.KpiGranularityMonth)
+	assert.Equal(t, []*model.KpiPoint{{Date: "2024-01-01", Minutes: 90}, {Date: "2024-02-01", Minutes: 45}}, pts)
+}
+
 func TestEffectiveDeparture(t *testing.T) {
 	now := time.Now()
-	dep := time.Date(2024, 1, 1, 10, 0, 0, 0, time.Local)
+	dep := time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)
 	// closed entry returns dep
 	out := effectiveDeparture(&dep, false, now)
 	assert.NotNil(t, out)
@@ -196,8 +318,8 @@ func TestKpiServiceGetTeamKpiSummary(t *testing.T) {
 	teamID := uuid.New()
 	u1 := &model.User{ID: uuid.New().String()}
 	u2 := &model.User{ID: uuid.New().String()}
-	a1 := time.Date(2024, 1, 10, 9, 0, 0, 0, time.Local)
-	d1 := time.Date(2024, 1, 10, 10, 0, 0, 0, time.Local)
+	a1 := time.Date(2024, 1, 10, 9, 0, 0, 0, time.UTC)
+	d1 := time.Date(2024, 1, 10, 10, 0, 0, 0, time.UTC)
 	a2 := time.Now().Add(-30 * time.Minute)
 
 	entries := []*model.TimeTableEntry{
@@ -208,7 +330,7 @@ func TestKpiServiceGetTeamKpiSummary(t *testing.T) {
 	svc := NewKpiService(repo)
 	from := time.Now().Add(-time.Hour)
 	to := time.Now().Add(time.Hour)
-	out, err := svc.GetTeamKpiSummary(context.Background(), teamID, from, to)
+	out, err := svc.GetTeamKpiSummary(context.Background(), teamID, from, to, nil, nil)
 	assert.NoError(t, err)
 	assert.NotNil(t, out)
 	// at least 60 minutes worked (the closed one)
@@ -225,13 +347,282 @@ func TestKpiServiceGetUserKpiSummaryRepoError(t *testing.T) {
 	repo := &mockKpiRepo{entries: nil, err: assert.AnError}
 	svc := NewKpiService(repo)
 	uid := uuid.New()
-	_, err := svc.GetUserKpiSummary(context.Background(), &uid, time.Time{}, time.Time{})
+	_, err := svc.GetUserKpiSummary(context.Background(), &uid, time.Time{}, time.Time{}, nil, nil)
 	assert.Error(t, err)
 }
 
 func TestKpiServiceGetTeamKpiSummaryRepoError(t *testing.T) {
 	repo := &mockKpiRepo{err: assert.AnError}
 	svc := NewKpiService(repo)
-	_, err := svc.GetTeamKpiSummary(context.Background(), uuid.New(), time.Time{}, time.Time{})
+	_, err := svc.GetTeamKpiSummary(context.Background(), uuid.New(), time.Time{}, time.Time{}, nil, nil)
+	assert.Error(t, err)
+}
+
+func TestKpiServiceGetUserKpiSummaryMultipleSessionsPerDay(t *testing.T) {
+	day := layoutISOs
+	u := &model.User{ID: uuid.New().String()}
+
+	// morning 09:00-12:00, afternoon 13:30-18:00 on the same day
+	a1 := time.Date(2024, 1, 10, 9, 0, 0, 0, time.UTC)
+	d1 := time.Date(2024, 1, 10, 12, 0, 0, 0, time.UTC)
+	a2 := time.Date(2024, 1, 10, 13, 30, 0, 0, time.UTC)
+	d2 := time.Date(2024, 1, 10, 18, 0, 0, 0, time.UTC)
+
+	repo := &mockKpiRepo{entries: []*model.TimeTableEntry{
+		{UserID: u, Day: day, Arrival: a1, Departure: &d1, Status: false},
+		{UserID: u, Day: day, Arrival: a2, Departure: &d2, Status: false},
+	}}
+	svc := NewKpiService(repo)
+	uid := uuid.New()
+	got, err := svc.GetUserKpiSummary(context.Background(), &uid, time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), time.Date(2024, 1, 31, 0, 0, 0, 0, time.UTC), nil, nil)
+	assert.NoError(t, err)
+
+	// 180 + 270 = 450 minutes over a single day
+	assert.Equal(t, int32(450), got.WorkedMinutes)
+	assert.Equal(t, int32(1), got.DaysPresent)
+	assert.Equal(t, int32(30), got.OvertimeMinutes)
+	// the afternoon session must not count as a late arrival
+	assert.InDelta(t, 1.0, got.PunctualityRate, 1e-9)
+	assert.Len(t, got.DailyWorked, 1)
+	assert.Equal(t, int32(450), got.DailyWorked[0].Minutes)
+}
+
+func TestComputeOvertimeReportSumsSessionsPerDay(t *testing.T) {
+	u := &model.User{ID: uuid.New().String(), FirstName: "Jane", LastName: "Doe"}
+	a1 := time.Date(2024, 1, 10, 8, 0, 0, 0, time.UTC)
+	d1 := time.Date(2024, 1, 10, 12, 0, 0, 0, time.UTC)
+	a2 := time.Date(2024, 1, 10, 13, 0, 0, 0, time.UTC)
+	d2 := time.Date(2024, 1, 10, 17, 0, 0, 0, time.UTC)
+	entries := []*model.TimeTableEntry{
+		{UserID: u, Day: layoutISOs, Arrival: a1, Departure: &d1},
+		{UserID: u, Day: layoutISOs, Arrival: a2, Departure: &d2},
+	}
+	svc := NewKpiService(&mockKpiRepo{})
+	report := overtimeReportOf(svc, svc.dailyRollups(entries, time.Now()), model.KpiGranularityWeek)
+	// two 4h sessions = 8h, 1h over the 7h expected
+	assert.Equal(t, int32(60), report.TotalOvertimeMinutes)
+	assert.Equal(t, int32(1), report.UsersWithOvertime)
+	assert.Equal(t, "Jane Doe", report.TopOvertimeUsers[0].UserName)
+}
+
+func TestKpiServiceSubtractsUnpaidBreaks(t *testing.T) {
+	u := &model.User{ID: uuid.New().String(), FirstName: "Jane", LastName: "Doe"}
+	a := time.Date(2024, 1, 10, 9, 0, 0, 0, time.UTC)
+	d := time.Date(2024, 1, 10, 18, 0, 0, 0, time.UTC)
+	lunchStart := time.Date(2024, 1, 10, 12, 0, 0, 0, time.UTC)
+	lunchEnd := time.Date(2024, 1, 10, 13, 0, 0, 0, time.UTC)
+	coffeeStart := time.Date(2024, 1, 10, 16, 0, 0, 0, time.UTC)
+	coffeeEnd := time.Date(2024, 1, 10, 16, 15, 0, 0, time.UTC)
+
+	entries := []*model.TimeTableEntry{{
+		UserID: u, Day: layoutISOs, Arrival: a, Departure: &d,
+		Breaks: []*model.Break{
+			{StartedAt: lunchStart, EndedAt: &lunchEnd, Paid: false},
+			{StartedAt: coffeeStart, EndedAt: &coffeeEnd, Paid: true},
+		},
+	}}
+	svc := NewKpiService(&mockKpiRepo{entries: entries})
+	uid := uuid.New()
+	from := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2024, 1, 31, 0, 0, 0, 0, time.UTC)
+
+	got, err := svc.GetUserKpiSummary(context.Background(), &uid, from, to, nil, nil)
+	assert.NoError(t, err)
+	// 9h on site minus the 1h unpaid lunch; the paid coffee break counts
+	assert.Equal(t, int32(480), got.WorkedMinutes)
+	assert.Equal(t, int32(60), got.UnpaidBreakMinutes)
+	assert.Equal(t, int32(60), got.OvertimeMinutes)
+
+	report := overtimeReportOf(svc, svc.dailyRollups(entries, time.Now()), model.KpiGranularityWeek)
+	assert.Equal(t, int32(60), report.TotalOvertimeMinutes)
+
+	csvOut, err := svc.ExportUserKpiCSV(context.Background(), &uid, from, to)
+	assert.NoError(t, err)
+	assert.Contains(t, csvOut, "UnpaidBreakMinutes")
+	assert.Contains(t, csvOut, ",480,60,60,")
+}
+
+func TestUnpaidBreakMinutesOpenBreakEndsWithSession(t *testing.T) {
+	a := time.Date(2024, 1, 10, 9, 0, 0, 0, time.UTC)
+	d := time.Date(2024, 1, 10, 12, 0, 0, 0, time.UTC)
+	bs := time.Date(2024, 1, 10, 11, 30, 0, 0, time.UTC)
+	e := &model.TimeTableEntry{Arrival: a, Departure: &d, Breaks: []*model.Break{{StartedAt: bs}}}
+	assert.Equal(t, 30, unpaidBreakMinutes(e, time.Now()))
+	assert.Equal(t, 150, entryMinutes(e, time.Now()))
+}
+
+func TestParseMidnightRule(t *testing.T) {
+	rule, err := ParseMidnightRule("")
+	assert.NoError(t, err)
+	assert.Equal(t, MidnightStartDay, rule)
+
+	rule, err = ParseMidnightRule(" Split ")
+	assert.NoError(t, err)
+	assert.Equal(t, MidnightSplit, rule)
+
+	_, err = ParseMidnightRule("end_day")
 	assert.Error(t, err)
 }
+
+func TestMinutesByDayNightShift(t *testing.T) {
+	// 22:00 -> 06:00 with an unpaid break from 23:30 to 00:30
+	a := time.Date(2024, 1, 10, 22, 0, 0, 0, time.UTC)
+	d := time.Date(2024, 1, 11, 6, 0, 0, 0, time.UTC)
+	bs := time.Date(2024, 1, 10, 23, 30, 0, 0, time.UTC)
+	be := time.Date(2024, 1, 11, 0, 30, 0, 0, time.UTC)
+	e := &model.TimeTableEntry{Day: "2024-01-10", Arrival: a, Departure: &d, Breaks: []*model.Break{{StartedAt: bs, EndedAt: &be}}}
+
+	assert.Equal(t, map[string]int{"2024-01-10": 420}, minutesByDay(e, time.Now(), MidnightStartDay))
+	assert.Equal(t, map[string]int{"2024-01-10": 90, "2024-01-11": 330}, minutesByDay(e, time.Now(), MidnightSplit))
+}
+
+func TestComputeWorkloadAnalysisSplitsNightShift(t *testing.T) {
+	u := &model.User{ID: "u1"}
+	a := time.Date(2024, 1, 10, 20, 0, 0, 0, time.UTC)
+	d := time.Date(2024, 1, 11, 6, 0, 0, 0, time.UTC)
+	entries := []*model.TimeTableEntry{{UserID: u, Day: "2024-01-10", Arrival: a, Departure: &d}}
+
+	startDaySvc := NewKpiService(&mockKpiRepo{})
+	startDay := startDaySvc.computeWorkloadAnalysis(rollupFigures(startDaySvc.dailyRollups(entries, time.Now()), model.KpiGranularityWeek), model.KpiGranularityDay)
+	assert.Equal(t, int32(600), startDay.PeakDayMinutes)
+	assert.Equal(t, "2024-01-10", startDay.PeakDay)
+	assert.Equal(t, int32(180), startDay.TotalOvertime)
+
+	svc := NewKpiService(&mockKpiRepo{})
+	svc.MidnightRule = MidnightSplit
+	split := svc.computeWorkloadAnalysis(rollupFigures(svc.dailyRollups(entries, time.Now()), model.KpiGranularityWeek), model.KpiGranularityDay)
+	assert.Equal(t, int32(360), split.PeakDayMinutes)
+	assert.Equal(t, "2024-01-11", split.PeakDay)
+	assert.Equal(t, int32(0), split.TotalOvertime)
+}
+
+func TestComputePunctualityUsesUserTimeZone(t *testing.T) {
+	// 08:30 UTC is 09:30 in Paris but 17:30 in Tokyo
+	paris, tokyo := "Europe/Paris", "Asia/Tokyo"
+	a := time.Date(2024, 1, 10, 8, 30, 0, 0, time.UTC)
+	e := []*model.TimeTableEntry{
+		{UserID: &model.User{ID: "u1", TimeZone: &paris}, Day: "2024-01-10", Arrival: a},
+		{UserID: &model.User{ID: "u2", TimeZone: &tokyo}, Day: "2024-01-10", Arrival: a},
+	}
+	punctual, total := computePunctuality(e)
+	assert.Equal(t, 1, punctual)
+	assert.Equal(t, 2, total)
+}
+
+func TestAddCoverageBucketsAcrossDSTChange(t *testing.T) {
+	paris, err := time.LoadLocation("Europe/Paris")
+	assert.NoError(t, err)
+	// clocks go back on 2024-10-27: 02:00-03:00 happens twice
+	arr := time.Date(2024, 10, 27, 1, 30, 0, 0, paris)
+	dep := arr.Add(3 * time.Hour)
+	start := time.Date(2024, 10, 27, 0, 0, 0, 0, paris)
+	end := time.Date(2024, 10, 27, 23, 59, 59, 0, paris)
+
+	counts := map[string]int{}
+	addCoverageBuckets(counts, arr, dep, start, end)
+	// 01:00, 02:00 (+02:00), 02:00 (+01:00), 03:00
+	assert.Equal(t, 4, len(counts))
+	assert.Equal(t, 1, counts["2024-10-27T02:00:00+02:00"])
+	assert.Equal(t, 1, counts["2024-10-27T02:00:00+01:00"])
+
+	// a half-hour offset zone still gets whole local hours
+	kolkata, err := time.LoadLocation("Asia/Kolkata")
+	assert.NoError(t, err)
+	counts = map[string]int{}
+	a := time.Date(2024, 1, 10, 9, 10, 0, 0, kolkata)
+	dayStart := time.Date(2024, 1, 10, 0, 0, 0, 0, kolkata)
+	addCoverageBuckets(counts, a, a.Add(30*time.Minute), dayStart, dayStart.AddDate(0, 0, 1))
+	assert.Equal(t, 1, counts["2024-01-10T09:00:00+05:30"])
+}
+
+func TestNormalizeWindowKeepsRequestedZone(t *testing.T) {
+	tokyo, err := time.LoadLocation("Asia/Tokyo")
+	assert.NoError(t, err)
+	from := time.Date(2024, 1, 1, 0, 0, 0, 0, tokyo)
+	start, end := normalizeWindow(from, time.Time{})
+	assert.Equal(t, tokyo, start.Location())
+	assert.Equal(t, tokyo, end.Location())
+}
+
+func TestComputeMissingEntries(t *testing.T) {
+	team := &model.Team{ID: "team", Name: "Support"}
+	alice := &model.UserWithAllData{ID: uuid.New().String(), FirstName: "Alice", LastName: "Martin", Role: model.RoleUser, Teams: []*model.Team{team}}
+	carol := &model.UserWithAllData{ID: uuid.New().String(), FirstName: "Carol", LastName: "Petit", Role: model.RoleManager, Teams: []*model.Team{team}}
+	admin := &model.UserWithAllData{ID: uuid.New().String(), FirstName: "Admin", LastName: "System", Role: model.RoleAdmin}
+
+	svc := NewKpiService(&mockKpiRepo{})
+	svc.Schedules = NewScheduleService(&mockScheduleRepo{
+		timeTable: &model.TimeTable{ID: "tt", Start: time.Date(0, 1, 1, 9, 0, 0, 0, time.UTC), Ends: time.Date(0, 1, 1, 17, 0, 0, 0, time.UTC)},
+		timeZone:  "UTC",
+		holidays:  []*model.Holiday{{Date: "2024-05-01", Name: "Fête du travail"}},
+	})
+	// Carol is hired on Monday for four days a week
+	svc.Contracts = NewContractService(&mockContractRepo{contracts: []*model.EmploymentContract{
+		{ID: "c", UserID: carol.ID, DailyMinutes: 420, EffectiveFrom: "2024-05-06", WorkingDays: fourDayWeek},
+	}})
+	entries := []*model.TimeTableEntry{
+		{UserID: &model.User{ID: alice.ID}, Day: "2024-05-02"},
+		{UserID: &model.User{ID: alice.ID}, Day: "2024-05-03"},
+	}
+	start := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+	end := time.Date(2024, 5, 7, 23, 59, 59, 0, time.UTC)
+
+	missing, byTeam := svc.computeMissingEntries([]*model.UserWithAllData{alice, carol, admin}, entries, start, end)
+	got := map[string][]string{}
+	for _, m := range missing {
+		got[m.UserName] = append(got[m.UserName], m.Date)
+	}
+	// no one is expected on the holiday nor the weekend, nor Carol before her contract
+	assert.Equal(t, map[string][]string{
+		"Alice Martin": {"2024-05-06", "2024-05-07"},
+		"Carol Petit":  {"2024-05-06", "2024-05-07"},
+	}, got)
+	assert.Equal(t, []*model.NoShowCount{{ID: "team", Name: "Support", Days: 4}}, byTeam)
+
+	metrics := svc.computeComplianceMetrics(nil, nil, missing, model.KpiGranularityWeek)
+	assert.Equal(t, int32(4), metrics.MissingEntriesCount)
+	assert.Len(t, metrics.NoShowsByUser, 2)
+	assert.Equal(t, int32(2), metrics.UsersWithIssues)
+	assert.Equal(t, []*model.ComplianceTrend{{Date: "2024-05-06", Anomalies: 4, MissingEntries: 4}}, metrics.AnomaliesByPeriod)
+}
+
+func TestMissingEntriesStartWhenTheUserWasCreated(t *testing.T) {
+	dana := &model.UserWithAllData{ID: uuid.New().String(), FirstName: "Dana", LastName: "Roux", Role: model.RoleUser}
+	eve := &model.UserWithAllData{ID: uuid.New().String(), FirstName: "Eve", LastName: "Blanc", Role: model.RoleUser}
+	// Dana has no contract and was created on Wednesday; Eve's start is unknown
+	svc := NewKpiService(&mockKpiRepo{starts: map[string]string{dana.ID: "2024-05-08", eve.ID: ""}})
+	start := time.Date(2024, 5, 6, 0, 0, 0, 0, time.UTC)
+	end := time.Date(2024, 5, 10, 23, 59, 59, 0, time.UTC)
+
+	missing, _ := svc.computeMissingEntries([]*model.UserWithAllData{dana, eve}, nil, start, end)
+	got := map[string][]string{}
+	for _, m := range missing {
+		got[m.UserName] = append(got[m.UserName], m.Date)
+	}
+	assert.Equal(t, map[string][]string{
+		"Dana Roux": {"2024-05-08", "2024-05-09", "2024-05-10"},
+		"Eve Blanc": {"2024-05-06", "2024-05-07", "2024-05-08", "2024-05-09", "2024-05-10"},
+	}, got)
+}
+
+func TestComplianceMetricsCountsAutoClosedEntries(t *testing.T) {
+	u := &model.User{ID: "u1"}
+	a := time.Date(2024, 1, 10, 9, 0, 0, 0, time.UTC)
+	d := a.Add(10 * time.Hour)
+	entries := []*model.TimeTableEntry{{UserID: u, Day: "2024-01-10", Arrival: a, Departure: &d, AutoClosed: true}}
+	got := NewKpiService(&mockKpiRepo{}).computeComplianceMetrics(entries, nil, nil, model.KpiGranularityWeek)
+	var autoClosed *model.ComplianceAnomaly
+	for _, an := range got.Anomalies {
+		if an.Type == "auto_closed" {
+			autoClosed = an
+		}
+	}
+	if assert.NotNil(t, autoClosed) {
+		assert.Equal(t, int32(1), autoClosed.Count)
+	}
+	assert.Equal(t, int32(1), got.UsersWithIssues)
+	assert.Equal(t, []*model.ComplianceTrend{{Date: "2024-01-08", Anomalies: 1}}, got.AnomaliesByPeriod)
+
+	monthly := NewKpiService(&mockKpiRepo{}).computeComplianceMetrics(entries, nil, nil, model.KpiGranularityMonth)
+	assert.Equal(t, []*model.ComplianceTrend{{Date: "2024-01-01", Anomalies: 1}}, monthly.AnomaliesByPeriod)
+}
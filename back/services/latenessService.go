@@ -0,0 +1,228 @@
+package services
+
+import (
+	"errors"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/epitech/timemanager/internal/graph/model"
+	"github.com/google/uuid"
+)
+
+var errForbiddenLatenessReview = errors.New("forbidden: only the team's manager or an admin can review this lateness")
+
+// LatenessRepository is the minimal repository contract used by LatenessService.
+type LatenessRepository interface {
+	CreateLatenessEvent(event *model.LatenessEvent) (*model.LatenessEvent, error)
+	// SyncLatenessEvent replaces the lateness of a user's day by the given one,
+	// removing it when nil.
+	SyncLatenessEvent(userID uuid.UUID, day string, event *model.LatenessEvent) error
+	GetLatenessEvent(id string) (*model.LatenessEvent, error)
+	GetLatenessEvents(userID *uuid.UUID, teamID *uuid.UUID, from, to *string, status *model.LatenessStatus) ([]*model.LatenessEvent, error)
+	JustifyLatenessEvent(id string, justification string) (*model.LatenessEvent, error)
+	ReviewLatenessEvent(id string, reviewerID uuid.UUID, excused bool, comment *string) (*model.LatenessEvent, error)
+	IsManagerOfUser(managerID uuid.UUID, userID uuid.UUID) (bool, error)
+}
+
+// LatenessService records the late clock-ins, which employees may justify and
+// their managers excuse or not.
+type LatenessService struct {
+	Repo LatenessRepository
+	// Kpi rates the arrivals under the punctuality policy.
+	Kpi *KpiService
+	// background tracks the schedule changes rated again off the request path.
+	background sync.WaitGroup
+}
+
+func NewLatenessService(repo LatenessRepository, kpi *KpiService) *LatenessService {
+	return &LatenessService{Repo: repo, Kpi: kpi}
+}
+
+// RecordArrival rates a clock-in and records it when late; nil when on time.
+func (s *LatenessService) RecordArrival(entry *model.TimeTableEntry) (*model.LatenessEvent, error) {
+	event, err := s.Kpi.CheckArrival(entry)
+	if err != nil || event == nil {
+		return nil, err
+	}
+	return s.Repo.CreateLatenessEvent(event)
+}
+
+// Rederive rates again the first arrival of a user's days after their entries
+// were added, moved or removed, and records, updates or removes their
+// lateness. Failures are only logged, like the rollups'. A nil service does
+// nothing.
+func (s *LatenessService) Rederive(userID string, days ...string) {
+	if s == nil {
+		return
+	}
+	uid, err := uuid.Parse(userID)
+	if err != nil {
+		log.Printf("failed to rate the arrivals of %s: invalid user id", userID)
+		return
+	}
+	for _, day := range days {
+		event, err := s.Kpi.DayLateness(uid, day)
+		if err == nil {
+			err = s.Repo.SyncLatenessEvent(uid, day, event)
+		}
+		if err != nil {
+			log.Printf("failed to rate the arrival of %s on %s: %v", userID, day, err)
+		}
+	}
+}
+
+// RederiveRange rates again the arrivals of a user's days between two days,
+// up to today when to is empty or later, as after their leave was approved or
+// withdrawn. Only the days with an entry or a lateness are synced. Failures
+// are only logged. A nil service does nothing.
+func (s *LatenessService) RederiveRange(userID uuid.UUID, from, to string) {
+	if s == nil {
+		return
+	}
+	if err := s.rederiveRange(userID, from, to, time.Now()); err != nil {
+		log.Printf("failed to rate the arrivals of %s from %s to %s: %v", userID, from, to, err)
+	}
+}
+
+func (s *LatenessService) rederiveRange(userID uuid.UUID, from, to string, now time.Time) error {
+	if today := now.Format(layoutISO); to == "" || to > today {
+		to = today
+	}
+	if from > to {
+		return nil
+	}
+	events, err := s.Kpi.RangeLateness(userID, from, to)
+	if err != nil {
+		return err
+	}
+	// the lateness of a day left without any entry goes too
+	recorded, err := s.Repo.GetLatenessEvents(&userID, nil, &from, &to, nil)
+	if err != nil {
+		return err
+	}
+	for _, e := range recorded {
+		if _, ok := events[e.Day]; !ok && e.Day >= from && e.Day <= to {
+			events[e.Day] = nil
+		}
+	}
+	for day, event := range events {
+		if err := s.Repo.SyncLatenessEvent(userID, day, event); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// RederiveUsers rates again in the background the arrivals of the users
+// between two days, as after a change of their schedule.
+func (s *LatenessService) RederiveUsers(userIDs []uuid.UUID, from, to string) {
+	if s == nil || len(userIDs) == 0 {
+		return
+	}
+	s.inBackground(func() {
+		for _, uid := range userIDs {
+			s.RederiveRange(uid, from, to)
+		}
+	})
+}
+
+// RederiveTeam rates again in the background the arrivals of the team's
+// members between two days, as after a change of its schedule.
+func (s *LatenessService) RederiveTeam(teamID uuid.UUID, from, to string) {
+	if s == nil {
+		return
+	}
+	s.inBackground(func() {
+		members, err := s.Kpi.teamMembers(teamID.String())
+		if err != nil {
+			log.Printf("failed to read the members of team %s whose arrivals to rate: %v", teamID, err)
+			return
+		}
+		for _, member := range members {
+			if uid, err := uuid.Parse(member); err == nil {
+				s.RederiveRange(uid, from, to)
+			}
+		}
+	})
+}
+
+func (s *LatenessService) inBackground(f func()) {
+	s.background.Add(1)
+	go func() {
+		defer s.background.Done()
+		f()
+	}()
+}
+
+// Wait returns once the arrivals rated again in the background are done.
+func (s *LatenessService) Wait() {
+	if s != nil {
+		s.background.Wait()
+	}
+}
+
+// GetLatenessEvents lists the late arrivals of a user or a team, the caller
+// having been checked to see them.
+func (s *LatenessService) GetLatenessEvents(userID *uuid.UUID, teamID *uuid.UUID, from, to *string, status *model.LatenessStatus) ([]*model.LatenessEvent, error) {
+	for _, day := range []*string{from, to} {
+		if day == nil || *day == "" {
+			continue
+		}
+		if _, err := time.Parse(layoutISO, *day); err != nil {
+			return nil, errors.New("invalid date, expected YYYY-MM-DD")
+		}
+	}
+	return s.Repo.GetLatenessEvents(userID, teamID, from, to, status)
+}
+
+// Justify attaches the employee's justification to one of their late
+// arrivals, until their manager reviews it.
+func (s *LatenessService) Justify(callerID uuid.UUID, id string, justification string) (*model.LatenessEvent, error) {
+	justification = strings.TrimSpace(justification)
+	if justification == "" {
+		return nil, errors.New("justification is required")
+	}
+	event, err := s.Repo.GetLatenessEvent(id)
+	if err != nil {
+		return nil, err
+	}
+	if event.UserID == nil || event.UserID.ID != callerID.String() {
+		return nil, errors.New("forbidden: you can only justify your own lateness")
+	}
+	if event.Status == model.LatenessStatusExcused || event.Status == model.LatenessStatusUnexcused {
+		return nil, errors.New("lateness has already been reviewed")
+	}
+	return s.Repo.JustifyLatenessEvent(id, justification)
+}
+
+// Review marks a late arrival excused or unexcused; a decision may be
+// revised. Only the manager of one of the employee's teams, or an admin, may
+// review; nobody reviews their own.
+func (s *LatenessService) Review(reviewerID uuid.UUID, reviewerRole string, id string, excused bool, comment *string) (*model.LatenessEvent, error) {
+	event, err := s.Repo.GetLatenessEvent(id)
+	if err != nil {
+		return nil, err
+	}
+	if event.UserID == nil {
+		return nil, errors.New("lateness has no user")
+	}
+	userID, err := uuid.Parse(event.UserID.ID)
+	if err != nil {
+		return nil, err
+	}
+	if reviewerRole != string(model.RoleAdmin) {
+		if userID == reviewerID {
+			return nil, errForbiddenLatenessReview
+		}
+		isManager, err := s.Repo.IsManagerOfUser(reviewerID, userID)
+		if err != nil {
+			return nil, err
+		}
+		if !isManager {
+			return nil, errForbiddenLatenessReview
+		}
+	}
+	return s.Repo.ReviewLatenessEvent(id, reviewerID, excused, comment)
+}
@@ -0,0 +1,200 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"github.com/epitech/timemanager/internal/graph/model"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type mockLatenessRepo struct {
+	events   map[string]*model.LatenessEvent
+	managers map[uuid.UUID]uuid.UUID // member -> manager
+	synced   map[string]*model.LatenessEvent
+}
+
+func (m *mockLatenessRepo) CreateLatenessEvent(event *model.LatenessEvent) (*model.LatenessEvent, error) {
+	event.ID = uuid.NewString()
+	m.events[event.ID] = event
+	return event, nil
+}
+
+func (m *mockLatenessRepo) SyncLatenessEvent(userID uuid.UUID, day string, event *model.LatenessEvent) error {
+	m.synced[day] = event
+	return nil
+}
+
+func (m *mockLatenessRepo) GetLatenessEvent(id string) (*model.LatenessEvent, error) {
+	if e, ok := m.events[id]; ok {
+		return e, nil
+	}
+	return nil, assert.AnError
+}
+
+func (m *mockLatenessRepo) GetLatenessEvents(userID *uuid.UUID, teamID *uuid.UUID, from, to *string, status *model.LatenessStatus) ([]*model.LatenessEvent, error) {
+	out := make([]*model.LatenessEvent, 0)
+	for _, e := range m.events {
+		out = append(out, e)
+	}
+	return out, nil
+}
+
+func (m *mockLatenessRepo) JustifyLatenessEvent(id string, justification string) (*model.LatenessEvent, error) {
+	e := m.events[id]
+	e.Justification = &justification
+	e.Status = model.LatenessStatusJustified
+	return e, nil
+}
+
+func (m *mockLatenessRepo) ReviewLatenessEvent(id string, reviewerID uuid.UUID, excused bool, comment *string) (*model.LatenessEvent, error) {
+	e := m.events[id]
+	e.Status = model.LatenessStatusUnexcused
+	if excused {
+		e.Status = model.LatenessStatusExcused
+	}
+	e.ReviewerID = &model.User{ID: reviewerID.String()}
+	e.ReviewComment = comment
+	return e, nil
+}
+
+func (m *mockLatenessRepo) IsManagerOfUser(managerID uuid.UUID, userID uuid.UUID) (bool, error) {
+	return m.managers[userID] == managerID, nil
+}
+
+func TestRecordArrival(t *testing.T) {
+	u := &model.User{ID: uuid.New().String()}
+	late := &model.TimeTableEntry{ID: "e1", UserID: u, Day: "2024-01-15", Arrival: time.Date(2024, 1, 15, 10, 30, 0, 0, time.UTC)}
+	kpi := NewKpiService(&mockKpiRepo{entries: []*model.TimeTableEntry{late}})
+	repo := &mockLatenessRepo{events: map[string]*model.LatenessEvent{}}
+	svc := NewLatenessService(repo, kpi)
+
+	event, err := svc.RecordArrival(late)
+	assert.NoError(t, err)
+	assert.Equal(t, int32(30), event.LateMinutes, "due by the default 10:00")
+	assert.Equal(t, model.LatenessStatusPending, event.Status)
+	assert.Len(t, repo.events, 1)
+
+	onTime := &model.TimeTableEntry{ID: "e2", UserID: u, Day: "2024-01-16", Arrival: time.Date(2024, 1, 16, 9, 0, 0, 0, time.UTC)}
+	kpi.Repo = &mockKpiRepo{entries: []*model.TimeTableEntry{onTime}}
+	event, err = svc.RecordArrival(onTime)
+	assert.NoError(t, err)
+	assert.Nil(t, event)
+	assert.Len(t, repo.events, 1)
+}
+
+func TestRederiveRatesTheFirstArrivalOfTheDay(t *testing.T) {
+	u := &model.User{ID: uuid.New().String()}
+	late := &model.TimeTableEntry{ID: "e1", UserID: u, Day: "2024-01-15", Arrival: time.Date(2024, 1, 15, 10, 30, 0, 0, time.UTC)}
+	kpiRepo := &mockKpiRepo{entries: []*model.TimeTableEntry{late}}
+	repo := &mockLatenessRepo{synced: map[string]*model.LatenessEvent{}}
+	svc := NewLatenessService(repo, NewKpiService(kpiRepo))
+
+	svc.Rederive(u.ID, "2024-01-15")
+	if assert.NotNil(t, repo.synced["2024-01-15"]) {
+		assert.Equal(t, "e1", repo.synced["2024-01-15"].EntryID)
+		assert.Equal(t, int32(30), repo.synced["2024-01-15"].LateMinutes)
+	}
+
+	// an earlier arrival entered afterwards makes the day on time
+	kpiRepo.entries = append(kpiRepo.entries, &model.TimeTableEntry{ID: "e0", UserID: u, Day: "2024-01-15", Arrival: time.Date(2024, 1, 15, 9, 0, 0, 0, time.UTC)})
+	svc.Rederive(u.ID, "2024-01-15")
+	assert.Contains(t, repo.synced, "2024-01-15")
+	assert.Nil(t, repo.synced["2024-01-15"])
+
+	var none *LatenessService
+	none.Rederive(u.ID, "2024-01-15")
+}
+
+func TestLeaveChangesRateTheArrivalsAgain(t *testing.T) {
+	u := &model.User{ID: uuid.New().String()}
+	uid := uuid.MustParse(u.ID)
+	leave := &model.LeaveRequest{ID: "l", UserID: u, StartDate: "2024-01-15", EndDate: "2024-01-15", HalfDay: true, Days: 0.5,
+		AbsenceType: &model.AbsenceType{}, Status: model.LeaveStatusPending}
+	leaves := NewLeaveService(&mockLeaveRepo{requests: []*model.LeaveRequest{leave}})
+	kpi := NewKpiService(&mockKpiRepo{entries: []*model.TimeTableEntry{workSession(u, "2024-01-15", 13, 18)}})
+	kpi.Leaves = leaves
+	// a lateness left over on a day whose entries were removed
+	repo := &mockLatenessRepo{
+		events: map[string]*model.LatenessEvent{"old": {ID: "old", UserID: u, Day: "2024-01-16"}},
+		synced: map[string]*model.LatenessEvent{},
+	}
+	leaves.Lateness = NewLatenessService(repo, kpi)
+
+	leaves.Lateness.RederiveRange(uid, "2024-01-15", "2024-01-16")
+	if assert.NotNil(t, repo.synced["2024-01-15"]) {
+		assert.Equal(t, int32(180), repo.synced["2024-01-15"].LateMinutes, "due by the default 10:00")
+	}
+	assert.Contains(t, repo.synced, "2024-01-16")
+	assert.Nil(t, repo.synced["2024-01-16"])
+
+	// a morning off approved afterwards: the afternoon arrival is not rated
+	_, err := leaves.ReviewLeave(uuid.New(), string(model.RoleAdmin), "l", true, nil)
+	require.NoError(t, err)
+	assert.Contains(t, repo.synced, "2024-01-15")
+	assert.Nil(t, repo.synced["2024-01-15"])
+
+	_, err = leaves.CancelLeave(uuid.New(), string(model.RoleAdmin), "l")
+	require.NoError(t, err)
+	assert.NotNil(t, repo.synced["2024-01-15"], "late again once the leave is withdrawn")
+}
+
+func TestScheduleChangesRateTheMembersArrivalsAgain(t *testing.T) {
+	u := &model.User{ID: uuid.New().String()}
+	teamID := uuid.New().String()
+	// the team starts at 13:00 on Mondays
+	scheduleRepo := &mockScheduleRepo{timeZone: "UTC", schedules: []*model.WorkSchedule{{
+		ID: "ws", TeamID: &teamID, EffectiveFrom: "2024-01-15", EffectiveTo: ptrString("2024-01-15"),
+		Days: []*model.WorkScheduleDay{{Weekday: model.WeekdayMonday, Start: "13:00", End: "18:00"}},
+	}}}
+	schedules := NewScheduleService(scheduleRepo)
+	kpi := NewKpiService(&mockKpiRepo{
+		entries: []*model.TimeTableEntry{workSession(u, "2024-01-15", 13, 18)},
+		users:   []*model.UserWithAllData{{ID: u.ID, Teams: []*model.Team{{ID: teamID}}}},
+	})
+	kpi.Schedules = schedules
+	repo := &mockLatenessRepo{events: map[string]*model.LatenessEvent{}, synced: map[string]*model.LatenessEvent{}}
+	schedules.Lateness = NewLatenessService(repo, kpi)
+
+	_, err := schedules.DeleteWorkSchedule("ws")
+	require.NoError(t, err)
+	schedules.Lateness.Wait()
+	if assert.NotNil(t, repo.synced["2024-01-15"], "due by the default 10:00 once the schedule is gone") {
+		assert.Equal(t, int32(180), repo.synced["2024-01-15"].LateMinutes)
+	}
+}
+
+func TestJustifyAndReviewLateness(t *testing.T) {
+	employee, manager, stranger := uuid.New(), uuid.New(), uuid.New()
+	repo := &mockLatenessRepo{
+		events: map[string]*model.LatenessEvent{
+			"l1": {ID: "l1", UserID: &model.User{ID: employee.String()}, Status: model.LatenessStatusPending},
+		},
+		managers: map[uuid.UUID]uuid.UUID{employee: manager},
+	}
+	svc := NewLatenessService(repo, nil)
+
+	_, err := svc.Justify(stranger, "l1", "train strike")
+	assert.Error(t, err, "only the late employee justifies")
+	_, err = svc.Justify(employee, "l1", "  ")
+	assert.Error(t, err)
+	event, err := svc.Justify(employee, "l1", "train strike")
+	assert.NoError(t, err)
+	assert.Equal(t, model.LatenessStatusJustified, event.Status)
+
+	_, err = svc.Review(stranger, "MANAGER", "l1", true, nil)
+	assert.Error(t, err, "not their manager")
+	_, err = svc.Review(employee, "MANAGER", "l1", true, nil)
+	assert.Error(t, err, "nobody reviews their own")
+	event, err = svc.Review(manager, "MANAGER", "l1", true, ptrString("ok"))
+	assert.NoError(t, err)
+	assert.Equal(t, model.LatenessStatusExcused, event.Status)
+
+	_, err = svc.Justify(employee, "l1", "more details")
+	assert.Error(t, err, "already reviewed")
+	event, err = svc.Review(stranger, "ADMIN", "l1", false, nil)
+	assert.NoError(t, err, "a decision may be revised")
+	assert.Equal(t, model.LatenessStatusUnexcused, event.Status)
+}
@@ -0,0 +1,634 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"log"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/epitech/timemanager/internal/graph/model"
+	"github.com/google/uuid"
+)
+
+const layoutMonth = "2006-01"
+
+// leaveAccrualInterval is how often RunAccruals credits the months elapsed;
+// requests and reviews accrue their user's balances first anyway.
+const leaveAccrualInterval = 24 * time.Hour
+
+var errForbiddenLeaveReview = errors.New("forbidden: only the team's manager or an admin can review this request")
+
+// LeaveRepository is the minimal repository contract used by LeaveService.
+type LeaveRepository interface {
+	GetAbsenceTypes() ([]*model.AbsenceType, error)
+	GetAbsenceType(id string) (*model.AbsenceType, error)
+	CreateAbsenceType(input model.CreateAbsenceTypeInput) (*model.AbsenceType, error)
+	UpdateAbsenceType(id string, input model.UpdateAbsenceTypeInput) (*model.AbsenceType, error)
+	GetLeaveBalances(userID uuid.UUID) ([]*model.LeaveBalance, error)
+	// SaveLeaveAccrual stores the balance accrued through a month, unless
+	// another accrual already moved it past previous.
+	SaveLeaveAccrual(userID uuid.UUID, absenceTypeID uuid.UUID, previous string, accruedThrough string, balance float64) error
+	// GetLeaveAccrualStarts returns the day (YYYY-MM-DD) each user, or only
+	// userID, started accruing leave: their first contract, else their first
+	// entry, empty without either.
+	GetLeaveAccrualStarts(userID *uuid.UUID) (map[string]string, error)
+	AdjustLeaveBalance(userID uuid.UUID, absenceTypeID uuid.UUID, days float64) (*model.LeaveBalance, error)
+	CreateLeaveRequest(userID uuid.UUID, input model.RequestLeaveInput, days float64) (*model.LeaveRequest, error)
+	GetLeaveRequest(id string) (*model.LeaveRequest, error)
+	GetLeaveRequests(status *model.LeaveStatus, userID *uuid.UUID, managerID *uuid.UUID, from, to *string) ([]*model.LeaveRequest, error)
+	HasOverlappingLeave(userID uuid.UUID, start, end string) (bool, error)
+	IsManagerOfUser(managerID uuid.UUID, userID uuid.UUID) (bool, error)
+	ReviewLeaveRequest(id string, reviewerID uuid.UUID, approve bool, comment *string) (*model.LeaveRequest, error)
+	CancelLeaveRequest(id string) (*model.LeaveRequest, error)
+}
+
+type LeaveService struct {
+	Repo LeaveRepository
+	// Schedules tells the working days a leave consumes; without it, Monday
+	// to Friday.
+	Schedules *ScheduleService
+	// Rollups is refreshed over the days of an approved or withdrawn leave;
+	// optional.
+	Rollups *RollupService
+	// Lateness rates again the arrivals of those days; optional.
+	Lateness *LatenessService
+}
+
+func NewLeaveService(repo LeaveRepository) *LeaveService {
+	return &LeaveService{Repo: repo}
+}
+
+func (s *LeaveService) GetAbsenceTypes() ([]*model.AbsenceType, error) {
+	return s.Repo.GetAbsenceTypes()
+}
+
+func (s *LeaveService) CreateAbsenceType(input model.CreateAbsenceTypeInput) (*model.AbsenceType, error) {
+	input.Name = strings.TrimSpace(input.Name)
+	if input.Name == "" {
+		return nil, errors.New("absence type name is required")
+	}
+	if err := validateAccrual(input.AccrualDaysPerMonth, input.MaxBalance); err != nil {
+		return nil, err
+	}
+	return s.Repo.CreateAbsenceType(input)
+}
+
+func (s *LeaveService) UpdateAbsenceType(id string, input model.UpdateAbsenceTypeInput) (*model.AbsenceType, error) {
+	if input.Name != nil {
+		name := strings.TrimSpace(*input.Name)
+		if name == "" {
+			return nil, errors.New("absence type name is required")
+		}
+		input.Name = &name
+	}
+	if err := validateAccrual(input.AccrualDaysPerMonth, input.MaxBalance); err != nil {
+		return nil, err
+	}
+	return s.Repo.UpdateAbsenceType(id, input)
+}
+
+func validateAccrual(perMonth *float64, maxBalance *float64) error {
+	if perMonth != nil && *perMonth < 0 {
+		return errors.New("accrualDaysPerMonth must not be negative")
+	}
+	if maxBalance != nil && *maxBalance < 0 {
+		return errors.New("maxBalance must not be negative")
+	}
+	return nil
+}
+
+// GetBalances returns the balance of every active type that consumes one, as
+// last accrued by RunAccruals or a request. The AccessService decides whose
+// balances the caller may read.
+func (s *LeaveService) GetBalances(userID uuid.UUID) ([]*model.LeaveBalance, error) {
+	types, err := s.Repo.GetAbsenceTypes()
+	if err != nil {
+		return nil, err
+	}
+	return s.balances(userID, types)
+}
+
+// accruedBalances accrues then lists the user's balances.
+func (s *LeaveService) accruedBalances(userID uuid.UUID, now time.Time) ([]*model.LeaveBalance, error) {
+	types, err := s.Repo.GetAbsenceTypes()
+	if err != nil {
+		return nil, err
+	}
+	starts, err := s.Repo.GetLeaveAccrualStarts(&userID)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := s.accrue(userID, types, starts[userID.String()], now); err != nil {
+		return nil, err
+	}
+	return s.balances(userID, types)
+}
+
+// balances lists the user's balances, including the days of pending requests
+// so that they are not requested twice.
+func (s *LeaveService) balances(userID uuid.UUID, types []*model.AbsenceType) ([]*model.LeaveBalance, error) {
+	stored, err := s.Repo.GetLeaveBalances(userID)
+	if err != nil {
+		return nil, err
+	}
+	byType := make(map[string]*model.LeaveBalance, len(stored))
+	for _, b := range stored {
+		byType[b.AbsenceType.ID] = b
+	}
+	pendingStatus := model.LeaveStatusPending
+	pending, err := s.Repo.GetLeaveRequests(&pendingStatus, &userID, nil, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	pendingDays := make(map[string]float64)
+	for _, lr := range pending {
+		pendingDays[lr.AbsenceType.ID] += lr.Days
+	}
+
+	out := make([]*model.LeaveBalance, 0, len(types))
+	for _, t := range types {
+		if !t.IsActive || !t.DeductsBalance {
+			continue
+		}
+		b, ok := byType[t.ID]
+		if !ok {
+			b = &model.LeaveBalance{UserID: userID.String()}
+		}
+		b.AbsenceType = t
+		b.PendingDays = pendingDays[t.ID]
+		out = append(out, b)
+	}
+	return out, nil
+}
+
+// RunAccruals accrues every user's balances once immediately, then on every
+// tick until ctx is cancelled.
+func (s *LeaveService) RunAccruals(ctx context.Context) {
+	ticker := time.NewTicker(leaveAccrualInterval)
+	defer ticker.Stop()
+	for {
+		if credited, err := s.AccrueAll(time.Now()); err != nil {
+			log.Printf("leave accrual failed: %v", err)
+		} else if credited > 0 {
+			log.Printf("leave accrual: %d balances credited", credited)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// AccrueAll credits every user the months elapsed since their accrual start
+// or last accrual, up to now's month, and returns the balances credited. A
+// user whose accrual fails is logged and skipped until the next run.
+func (s *LeaveService) AccrueAll(now time.Time) (int, error) {
+	types, err := s.Repo.GetAbsenceTypes()
+	if err != nil {
+		return 0, err
+	}
+	starts, err := s.Repo.GetLeaveAccrualStarts(nil)
+	if err != nil {
+		return 0, err
+	}
+	credited := 0
+	for userID, start := range starts {
+		uid, err := uuid.Parse(userID)
+		if err != nil {
+			continue
+		}
+		n, err := s.accrue(uid, types, start, now)
+		if err != nil {
+			log.Printf("leave accrual of user %s failed: %v", userID, err)
+		}
+		credited += n
+	}
+	return credited, nil
+}
+
+// accrue credits the monthly accrual of each active type up to now's month,
+// from the month of start (YYYY-MM-DD) for a balance never accrued, and
+// returns the balances credited. The repository refuses to credit a balance
+// another accrual moved meanwhile, so that no month is credited twice.
+func (s *LeaveService) accrue(userID uuid.UUID, types []*model.AbsenceType, start string, now time.Time) (int, error) {
+	stored, err := s.Repo.GetLeaveBalances(userID)
+	if err != nil {
+		return 0, err
+	}
+	byType := make(map[string]*model.LeaveBalance, len(stored))
+	for _, b := range stored {
+		byType[b.AbsenceType.ID] = b
+	}
+	month := now.Format(layoutMonth)
+	credited := 0
+	for _, t := range types {
+		if !t.IsActive || !t.DeductsBalance || t.AccrualDaysPerMonth <= 0 {
+			continue
+		}
+		balance, previous := 0.0, ""
+		if b, ok := byType[t.ID]; ok {
+			balance = b.Balance
+			if b.AccruedThrough != nil {
+				previous = *b.AccruedThrough
+			}
+		}
+		since := previous
+		if since == "" {
+			since = monthBefore(start)
+		}
+		maxBalance := 0.0
+		if t.MaxBalance != nil {
+			maxBalance = *t.MaxBalance
+		}
+		total, months := accruedBalance(balance, since, month, t.AccrualDaysPerMonth, maxBalance)
+		if months == 0 {
+			continue
+		}
+		typeID, err := uuid.Parse(t.ID)
+		if err != nil {
+			return credited, err
+		}
+		if err := s.Repo.SaveLeaveAccrual(userID, typeID, previous, month, total); err != nil {
+			return credited, err
+		}
+		credited++
+	}
+	return credited, nil
+}
+
+// monthBefore returns the month (YYYY-MM) before the one of day (YYYY-MM-DD),
+// empty when day is.
+func monthBefore(day string) string {
+	t, err := time.Parse(layoutISO, day)
+	if err != nil {
+		return ""
+	}
+	return time.Date(t.Year(), t.Month()-1, 1, 0, 0, 0, 0, time.UTC).Format(layoutMonth)
+}
+
+// accruedBalance credits perMonth for each month after accruedThrough up to
+// month (YYYY-MM), a balance never accrued starting with month itself. The
+// result is capped at maxBalance when set, without lowering a balance an
+// admin already raised above it.
+func accruedBalance(balance float64, accruedThrough, month string, perMonth, maxBalance float64) (float64, int) {
+	current, err := time.Parse(layoutMonth, month)
+	if err != nil {
+		return balance, 0
+	}
+	months := 1
+	if accruedThrough != "" {
+		last, err := time.Parse(layoutMonth, accruedThrough)
+		if err != nil {
+			return balance, 0
+		}
+		months = (current.Year()-last.Year())*12 + int(current.Month()-last.Month())
+	}
+	if months <= 0 {
+		return balance, 0
+	}
+	credited := balance + perMonth*float64(months)
+	if maxBalance > 0 && credited > maxBalance {
+		credited = math.Max(balance, maxBalance)
+	}
+	return math.Round(credited*100) / 100, months
+}
+
+// AdjustBalance adds days, possibly negative, to a user's balance.
+func (s *LeaveService) AdjustBalance(input model.AdjustLeaveBalanceInput) (*model.LeaveBalance, error) {
+	userID, err := uuid.Parse(input.UserID)
+	if err != nil {
+		return nil, errors.New("invalid userID")
+	}
+	typeID, err := uuid.Parse(input.AbsenceTypeID)
+	if err != nil {
+		return nil, errors.New("invalid absenceTypeID")
+	}
+	if input.Days == 0 {
+		return nil, errors.New("days must not be zero")
+	}
+	return s.Repo.AdjustLeaveBalance(userID, typeID, input.Days)
+}
+
+// RequestLeave records a leave request, counting the working days it covers;
+// types that consume a balance need enough days left once pending requests
+// are taken into account.
+func (s *LeaveService) RequestLeave(requesterID uuid.UUID, input model.RequestLeaveInput) (*model.LeaveRequest, error) {
+	start, err := time.Parse(layoutISO, input.StartDate)
+	if err != nil {
+		return nil, errors.New("invalid startDate, expected YYYY-MM-DD")
+	}
+	end, err := time.Parse(layoutISO, input.EndDate)
+	if err != nil {
+		return nil, errors.New("invalid endDate, expected YYYY-MM-DD")
+	}
+	if end.Before(start) {
+		return nil, errors.New("endDate must not be before startDate")
+	}
+	halfDay := input.HalfDay != nil && *input.HalfDay
+	if halfDay && !end.Equal(start) {
+		return nil, errors.New("a half-day leave covers a single day")
+	}
+	if input.Reason != nil {
+		reason := strings.TrimSpace(*input.Reason)
+		input.Reason = &reason
+	}
+
+	absenceType, err := s.Repo.GetAbsenceType(input.AbsenceTypeID)
+	if err != nil {
+		return nil, err
+	}
+	if !absenceType.IsActive {
+		return nil, errors.New("absence type is not active")
+	}
+	overlaps, err := s.Repo.HasOverlappingLeave(requesterID, input.StartDate, input.EndDate)
+	if err != nil {
+		return nil, err
+	}
+	if overlaps {
+		return nil, errors.New("a leave request already covers part of this period")
+	}
+
+	days, err := s.leaveDays(requesterID.String(), input.StartDate, input.EndDate, halfDay)
+	if err != nil {
+		return nil, err
+	}
+	if days == 0 {
+		return nil, errors.New("no working day in the requested period")
+	}
+
+	if absenceType.DeductsBalance {
+		balances, err := s.accruedBalances(requesterID, time.Now())
+		if err != nil {
+			return nil, err
+		}
+		available := 0.0
+		for _, b := range balances {
+			if b.AbsenceType.ID == absenceType.ID {
+				available = b.Balance - b.PendingDays
+			}
+		}
+		if available < days {
+			return nil, errors.New("insufficient leave balance")
+		}
+	}
+	return s.Repo.CreateLeaveRequest(requesterID, input, days)
+}
+
+// leaveDays counts the working days of the user between start and end
+// inclusive, a half-day counting for 0.5.
+func (s *LeaveService) leaveDays(userID string, start, end string, halfDay bool) (float64, error) {
+	from, err := time.Parse(layoutISO, start)
+	if err != nil {
+		return 0, err
+	}
+	to, err := time.Parse(layoutISO, end)
+	if err != nil {
+		return 0, err
+	}
+	var planner *Planner
+	if s.Schedules != nil {
+		planner = s.Schedules.NewPlanner()
+	}
+	days := 0.0
+	for d := from; !d.After(to); d = d.AddDate(0, 0, 1) {
+		working, err := leaveWorkingDay(planner, userID, d.Format(layoutISO))
+		if err != nil {
+			return 0, err
+		}
+		if working {
+			days++
+		}
+	}
+	if halfDay {
+		days /= 2
+	}
+	return days, nil
+}
+
+// leaveWorkingDay tells whether a leave consumes the day: a working day of the
+// user's schedule, else from Monday to Friday.
+func leaveWorkingDay(planner *Planner, userID, day string) (bool, error) {
+	if planner != nil {
+		planned, err := planner.For(userID, day)
+		if err != nil {
+			return false, err
+		}
+		if planned.Source != model.ScheduleSourceNone || planned.Holiday != nil {
+			return planned.WorkingDay, nil
+		}
+	}
+	return isWeekday(day), nil
+}
+
+// GetLeaveRequests lists the leave requests visible to the caller: admins see
+// everything, managers their teams' members and their own, users their own.
+func (s *LeaveService) GetLeaveRequests(callerID uuid.UUID, callerRole string, status *model.LeaveStatus, userID *uuid.UUID, from, to *string) ([]*model.LeaveRequest, error) {
+	switch callerRole {
+	case string(model.RoleAdmin):
+		return s.Repo.GetLeaveRequests(status, userID, nil, from, to)
+	case string(model.RoleManager):
+		if userID != nil && *userID == callerID {
+			return s.Repo.GetLeaveRequests(status, &callerID, nil, from, to)
+		}
+		return s.Repo.GetLeaveRequests(status, userID, &callerID, from, to)
+	default:
+		return s.Repo.GetLeaveRequests(status, &callerID, nil, from, to)
+	}
+}
+
+// ReviewLeave approves or rejects a pending request. Only the manager of one
+// of the requester's teams, or an admin, may review; nobody reviews their own.
+func (s *LeaveService) ReviewLeave(reviewerID uuid.UUID, reviewerRole string, id string, approve bool, comment *string) (*model.LeaveRequest, error) {
+	request, err := s.Repo.GetLeaveRequest(id)
+	if err != nil {
+		return nil, err
+	}
+	if request.Status != model.LeaveStatusPending {
+		return nil, errors.New("leave request has already been reviewed")
+	}
+	if request.UserID == nil {
+		return nil, errors.New("leave request has no user")
+	}
+	requesterID, err := uuid.Parse(request.UserID.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	if reviewerRole != string(model.RoleAdmin) {
+		if requesterID == reviewerID {
+			return nil, errForbiddenLeaveReview
+		}
+		isManager, err := s.Repo.IsManagerOfUser(reviewerID, requesterID)
+		if err != nil {
+			return nil, err
+		}
+		if !isManager {
+			return nil, errForbiddenLeaveReview
+		}
+	}
+
+	if approve && request.AbsenceType != nil && request.AbsenceType.DeductsBalance {
+		// the months elapsed since the request count towards the balance
+		if _, err := s.accruedBalances(requesterID, time.Now()); err != nil {
+			return nil, err
+		}
+	}
+	reviewed, err := s.Repo.ReviewLeaveRequest(id, reviewerID, approve, comment)
+	if err != nil {
+		return nil, err
+	}
+	if approve {
+		s.Rollups.RefreshRange(&requesterID, request.StartDate, request.EndDate)
+		s.Lateness.RederiveRange(requesterID, request.StartDate, request.EndDate)
+	}
+	return reviewed, nil
+}
+
+// CancelLeave withdraws a request. The requester may cancel it while pending
+// or before it starts; admins at any time. Approved days go back to the balance.
+func (s *LeaveService) CancelLeave(callerID uuid.UUID, callerRole string, id string) (*model.LeaveRequest, error) {
+	request, err := s.Repo.GetLeaveRequest(id)
+	if err != nil {
+		return nil, err
+	}
+	if callerRole != string(model.RoleAdmin) {
+		if request.UserID == nil || request.UserID.ID != callerID.String() {
+			return nil, errors.New("forbidden: you can only cancel your own leave requests")
+		}
+		if request.Status == model.LeaveStatusApproved && request.StartDate <= time.Now().Format(layoutISO) {
+			return nil, errors.New("a leave that has already started can only be cancelled by an admin")
+		}
+	}
+	if request.Status != model.LeaveStatusPending && request.Status != model.LeaveStatusApproved {
+		return nil, errors.New("only pending or approved leave requests can be cancelled")
+	}
+	cancelled, err := s.Repo.CancelLeaveRequest(id)
+	if err != nil {
+		return nil, err
+	}
+	if request.Status == model.LeaveStatusApproved && request.UserID != nil {
+		if uid, err := uuid.Parse(request.UserID.ID); err == nil {
+			s.Rollups.RefreshRange(&uid, request.StartDate, request.EndDate)
+			s.Lateness.RederiveRange(uid, request.StartDate, request.EndDate)
+		}
+	}
+	return cancelled, nil
+}
+
+// AbsenceCalendar tells which days users are on approved leave, caching the
+// leaves of each user; use one per request.
+type AbsenceCalendar struct {
+	repo   LeaveRepository
+	leaves map[string][]*model.LeaveRequest
+	// planner tells the working days a leave consumes, as when it was requested
+	planner *Planner
+}
+
+func (s *LeaveService) NewAbsenceCalendar() *AbsenceCalendar {
+	c := &AbsenceCalendar{repo: s.Repo, leaves: make(map[string][]*model.LeaveRequest)}
+	if s.Schedules != nil {
+		c.planner = s.Schedules.NewPlanner()
+	}
+	return c
+}
+
+// approved returns the approved leaves of a user, loaded once.
+func (c *AbsenceCalendar) approved(userID string) []*model.LeaveRequest {
+	if leaves, ok := c.leaves[userID]; ok {
+		return leaves
+	}
+	var leaves []*model.LeaveRequest
+	if uid, err := uuid.Parse(userID); err == nil {
+		status := model.LeaveStatusApproved
+		// a user whose leaves can't be read counts as never absent
+		leaves, _ = c.repo.GetLeaveRequests(&status, &uid, nil, nil, nil)
+	}
+	c.leaves[userID] = leaves
+	return leaves
+}
+
+// Fraction is the share of the day (YYYY-MM-DD) the user is on approved
+// leave: 1, 0.5 for a half-day, or 0.
+func (c *AbsenceCalendar) Fraction(userID, day string) float64 {
+	for _, lr := range c.approved(userID) {
+		if lr.StartDate <= day && day <= lr.EndDate {
+			if lr.HalfDay {
+				return 0.5
+			}
+			return 1
+		}
+	}
+	return 0
+}
+
+// Days sums the approved leave days of the user between from and to
+// inclusive. A leave crossing the edge of the window counts the share of each
+// of its days inside it, on the working days it consumes.
+func (c *AbsenceCalendar) Days(userID, from, to string) float64 {
+	working := leaveConsumes(c.planner, userID)
+	total := 0.0
+	for _, lr := range c.approved(userID) {
+		total += leaveDaysWithin(lr, from, to, working)
+	}
+	return total
+}
+
+// leaveConsumes tells the days a leave of the user consumes under the
+// planner; a day whose schedule can't be read counts as a weekday.
+func leaveConsumes(planner *Planner, userID string) func(day string) bool {
+	return func(day string) bool {
+		ok, err := leaveWorkingDay(planner, userID, day)
+		return ok || (err != nil && isWeekday(day))
+	}
+}
+
+// leaveDaysWithin is the share of a leave's days between from and to
+// inclusive: all of them when it lies inside, else the sum of its per-day
+// fraction on the days working tells it consumes.
+func leaveDaysWithin(lr *model.LeaveRequest, from, to string, working func(day string) bool) float64 {
+	if lr.EndDate < from || lr.StartDate > to {
+		return 0
+	}
+	if lr.StartDate >= from && lr.EndDate <= to {
+		return lr.Days
+	}
+	start, errS := time.Parse(layoutISO, maxString(lr.StartDate, from))
+	end, errE := time.Parse(layoutISO, minString(lr.EndDate, to))
+	if errS != nil || errE != nil {
+		return 0
+	}
+	fraction := 1.0
+	if lr.HalfDay {
+		fraction = 0.5
+	}
+	days := 0.0
+	for d := start; !d.After(end); d = d.AddDate(0, 0, 1) {
+		if working(d.Format(layoutISO)) {
+			days += fraction
+		}
+	}
+	return days
+}
+
+// isWeekday tells whether the day (YYYY-MM-DD) falls from Monday to Friday.
+func isWeekday(day string) bool {
+	d, err := time.Parse(layoutISO, day)
+	return err == nil && d.Weekday() != time.Saturday && d.Weekday() != time.Sunday
+}
+
+func maxString(a, b string) string {
+	if a > b {
+		return a
+	}
+	return b
+}
+
+func minString(a, b string) string {
+	if a < b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,336 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"github.com/epitech/timemanager/internal/graph/model"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+type mockLeaveRepo struct {
+	types    []*model.AbsenceType
+	balances []*model.LeaveBalance
+	requests []*model.LeaveRequest
+	overlap  bool
+	manager  bool
+	saved    int
+	created  *model.RequestLeaveInput
+	days     float64
+	reviewed bool
+	canceled bool
+	starts   map[string]string
+}
+
+func (m *mockLeaveRepo) GetAbsenceTypes() ([]*model.AbsenceType, error) { return m.types, nil }
+
+func (m *mockLeaveRepo) GetAbsenceType(id string) (*model.AbsenceType, error) {
+	for _, t := range m.types {
+		if t.ID == id {
+			return t, nil
+		}
+	}
+	return nil, assert.AnError
+}
+
+func (m *mockLeaveRepo) CreateAbsenceType(input model.CreateAbsenceTypeInput) (*model.AbsenceType, error) {
+	return &model.AbsenceType{Name: input.Name}, nil
+}
+
+func (m *mockLeaveRepo) UpdateAbsenceType(id string, input model.UpdateAbsenceTypeInput) (*model.AbsenceType, error) {
+	return &model.AbsenceType{ID: id}, nil
+}
+
+func (m *mockLeaveRepo) GetLeaveBalances(userID uuid.UUID) ([]*model.LeaveBalance, error) {
+	return m.balances, nil
+}
+
+func (m *mockLeaveRepo) SaveLeaveAccrual(userID uuid.UUID, absenceTypeID uuid.UUID, previous string, accruedThrough string, balance float64) error {
+	m.saved++
+	for _, b := range m.balances {
+		if b.AbsenceType.ID == absenceTypeID.String() {
+			b.Balance = balance
+			b.AccruedThrough = &accruedThrough
+			return nil
+		}
+	}
+	m.balances = append(m.balances, &model.LeaveBalance{
+		UserID:         userID.String(),
+		AbsenceType:    &model.AbsenceType{ID: absenceTypeID.String()},
+		Balance:        balance,
+		AccruedThrough: &accruedThrough,
+	})
+	return nil
+}
+
+func (m *mockLeaveRepo) GetLeaveAccrualStarts(userID *uuid.UUID) (map[string]string, error) {
+	if userID == nil {
+		return m.starts, nil
+	}
+	return map[string]string{userID.String(): m.starts[userID.String()]}, nil
+}
+
+func (m *mockLeaveRepo) AdjustLeaveBalance(userID uuid.UUID, absenceTypeID uuid.UUID, days float64) (*model.LeaveBalance, error) {
+	return &model.LeaveBalance{Balance: days}, nil
+}
+
+func (m *mockLeaveRepo) CreateLeaveRequest(userID uuid.UUID, input model.RequestLeaveInput, days float64) (*model.LeaveRequest, error) {
+	m.created = &input
+	m.days = days
+	return &model.LeaveRequest{Days: days, Status: model.LeaveStatusPending}, nil
+}
+
+func (m *mockLeaveRepo) GetLeaveRequest(id string) (*model.LeaveRequest, error) {
+	for _, lr := range m.requests {
+		if lr.ID == id {
+			// a copy, as read from the database
+			read := *lr
+			return &read, nil
+		}
+	}
+	return nil, assert.AnError
+}
+
+func (m *mockLeaveRepo) GetLeaveRequests(status *model.LeaveStatus, userID *uuid.UUID, managerID *uuid.UUID, from, to *string) ([]*model.LeaveRequest, error) {
+	var out []*model.LeaveRequest
+	for _, lr := range m.requests {
+		if status != nil && lr.Status != *status {
+			continue
+		}
+		if userID != nil && lr.UserID != nil && lr.UserID.ID != userID.String() {
+			continue
+		}
+		out = append(out, lr)
+	}
+	return out, nil
+}
+
+func (m *mockLeaveRepo) HasOverlappingLeave(userID uuid.UUID, start, end string) (bool, error) {
+	return m.overlap, nil
+}
+
+func (m *mockLeaveRepo) IsManagerOfUser(managerID uuid.UUID, userID uuid.UUID) (bool, error) {
+	return m.manager, nil
+}
+
+func (m *mockLeaveRepo) ReviewLeaveRequest(id string, reviewerID uuid.UUID, approve bool, comment *string) (*model.LeaveRequest, error) {
+	m.reviewed = true
+	status := model.LeaveStatusRejected
+	if approve {
+		status = model.LeaveStatusApproved
+	}
+	m.setStatus(id, status)
+	return &model.LeaveRequest{ID: id, Status: status}, nil
+}
+
+func (m *mockLeaveRepo) CancelLeaveRequest(id string) (*model.LeaveRequest, error) {
+	m.canceled = true
+	m.setStatus(id, model.LeaveStatusCancelled)
+	return &model.LeaveRequest{ID: id, Status: model.LeaveStatusCancelled}, nil
+}
+
+func (m *mockLeaveRepo) setStatus(id string, status model.LeaveStatus) {
+	for _, lr := range m.requests {
+		if lr.ID == id {
+			lr.Status = status
+		}
+	}
+}
+
+func TestAccruedBalance(t *testing.T) {
+	balance, months := accruedBalance(0, "", "2024-03", 2.08, 0)
+	assert.Equal(t, 1, months, "a new balance starts with the current month")
+	assert.Equal(t, 2.08, balance)
+
+	balance, months = accruedBalance(5, "2023-11", "2024-02", 2.08, 0)
+	assert.Equal(t, 3, months)
+	assert.Equal(t, 11.24, balance)
+
+	_, months = accruedBalance(5, "2024-02", "2024-02", 2.08, 0)
+	assert.Equal(t, 0, months, "a month is credited once")
+
+	balance, _ = accruedBalance(24, "2024-01", "2024-03", 2.08, 25)
+	assert.Equal(t, 25.0, balance, "capped")
+	balance, _ = accruedBalance(30, "2024-01", "2024-03", 2.08, 25)
+	assert.Equal(t, 30.0, balance, "an adjusted balance is not lowered by the cap")
+}
+
+func TestRequestLeaveValidation(t *testing.T) {
+	paid := &model.AbsenceType{ID: uuid.New().String(), DeductsBalance: true, IsActive: true}
+	sick := &model.AbsenceType{ID: uuid.New().String(), IsActive: true}
+	month := time.Now().Format(layoutMonth)
+	repo := &mockLeaveRepo{
+		types:    []*model.AbsenceType{paid, sick},
+		balances: []*model.LeaveBalance{{AbsenceType: paid, Balance: 3, AccruedThrough: &month}},
+	}
+	svc := NewLeaveService(repo)
+	uid := uuid.New()
+	half := true
+
+	_, err := svc.RequestLeave(uid, model.RequestLeaveInput{AbsenceTypeID: paid.ID, StartDate: "2024-03-08", EndDate: "2024-03-07"})
+	assert.Error(t, err)
+	_, err = svc.RequestLeave(uid, model.RequestLeaveInput{AbsenceTypeID: paid.ID, StartDate: "2024-03-07", EndDate: "2024-03-08", HalfDay: &half})
+	assert.Error(t, err, "half-day over two days")
+	_, err = svc.RequestLeave(uid, model.RequestLeaveInput{AbsenceTypeID: paid.ID, StartDate: "2024-03-09", EndDate: "2024-03-10"})
+	assert.Error(t, err, "weekend only")
+	_, err = svc.RequestLeave(uid, model.RequestLeaveInput{AbsenceTypeID: paid.ID, StartDate: "2024-03-04", EndDate: "2024-03-08"})
+	assert.Error(t, err, "5 days for a balance of 3")
+	assert.Nil(t, repo.created)
+
+	// Friday to Monday is two working days
+	_, err = svc.RequestLeave(uid, model.RequestLeaveInput{AbsenceTypeID: paid.ID, StartDate: "2024-03-08", EndDate: "2024-03-11"})
+	assert.NoError(t, err)
+	assert.Equal(t, 2.0, repo.days)
+
+	// the pending days are no longer available
+	repo.requests = []*model.LeaveRequest{{AbsenceType: paid, Days: 2, Status: model.LeaveStatusPending}}
+	_, err = svc.RequestLeave(uid, model.RequestLeaveInput{AbsenceTypeID: paid.ID, StartDate: "2024-03-12", EndDate: "2024-03-13"})
+	assert.Error(t, err)
+	_, err = svc.RequestLeave(uid, model.RequestLeaveInput{AbsenceTypeID: paid.ID, StartDate: "2024-03-12", EndDate: "2024-03-12", HalfDay: &half})
+	assert.NoError(t, err)
+	assert.Equal(t, 0.5, repo.days)
+
+	// sick leave consumes no balance, but can't overlap another leave
+	_, err = svc.RequestLeave(uid, model.RequestLeaveInput{AbsenceTypeID: sick.ID, StartDate: "2024-03-04", EndDate: "2024-03-15"})
+	assert.NoError(t, err)
+	assert.Equal(t, 10.0, repo.days)
+	repo.overlap = true
+	_, err = svc.RequestLeave(uid, model.RequestLeaveInput{AbsenceTypeID: sick.ID, StartDate: "2024-03-04", EndDate: "2024-03-04"})
+	assert.Error(t, err)
+}
+
+func TestRequestLeaveCountsScheduledDays(t *testing.T) {
+	uid := uuid.New()
+	sick := &model.AbsenceType{ID: uuid.New().String(), IsActive: true}
+	repo := &mockLeaveRepo{types: []*model.AbsenceType{sick}}
+	svc := NewLeaveService(repo)
+	ws := &model.WorkSchedule{ID: "ws", UserID: ptrString(uid.String()), EffectiveFrom: "2024-01-01",
+		Days: []*model.WorkScheduleDay{
+			{Weekday: model.WeekdaySaturday, Start: "09:00", End: "17:00"},
+			{Weekday: model.WeekdaySunday, Start: "09:00", End: "17:00"},
+		}}
+	svc.Schedules = NewScheduleService(&mockScheduleRepo{schedules: []*model.WorkSchedule{ws}, timeZone: "UTC"})
+
+	// a weekend worker's week of leave only consumes Saturday and Sunday
+	_, err := svc.RequestLeave(uid, model.RequestLeaveInput{AbsenceTypeID: sick.ID, StartDate: "2024-03-04", EndDate: "2024-03-10"})
+	assert.NoError(t, err)
+	assert.Equal(t, 2.0, repo.days)
+}
+
+func TestAbsenceDaysAcrossTheWindowEdge(t *testing.T) {
+	weekender, halfDays := uuid.New(), uuid.New()
+	ws := &model.WorkSchedule{ID: "ws", UserID: ptrString(weekender.String()), EffectiveFrom: "2024-01-01",
+		Days: []*model.WorkScheduleDay{
+			{Weekday: model.WeekdaySaturday, Start: "09:00", End: "17:00"},
+			{Weekday: model.WeekdaySunday, Start: "09:00", End: "17:00"},
+		}}
+	svc := NewLeaveService(&mockLeaveRepo{requests: []*model.LeaveRequest{
+		{UserID: &model.User{ID: weekender.String()}, StartDate: "2024-03-04", EndDate: "2024-03-10", Days: 2, Status: model.LeaveStatusApproved},
+		{UserID: &model.User{ID: halfDays.String()}, StartDate: "2024-03-04", EndDate: "2024-03-06", HalfDay: true, Days: 1.5, Status: model.LeaveStatusApproved},
+	}})
+	svc.Schedules = NewScheduleService(&userScopedScheduleRepo{
+		mockScheduleRepo: &mockScheduleRepo{schedules: []*model.WorkSchedule{ws}, timeZone: "UTC"},
+		owner:            weekender.String(),
+	})
+	calendar := svc.NewAbsenceCalendar()
+
+	// only the scheduled weekend of the leave falls in each window
+	assert.Equal(t, 2.0, calendar.Days(weekender.String(), "2024-03-09", "2024-03-31"))
+	assert.Equal(t, 0.0, calendar.Days(weekender.String(), "2024-03-01", "2024-03-08"))
+	// each day of a half-day leave counts for half
+	assert.Equal(t, 1.0, calendar.Days(halfDays.String(), "2024-03-05", "2024-03-31"))
+}
+
+func TestAccrueAllCreditsEveryMonthSinceTheContractOnce(t *testing.T) {
+	paid := &model.AbsenceType{ID: uuid.New().String(), DeductsBalance: true, AccrualDaysPerMonth: 2, IsActive: true}
+	unpaid := &model.AbsenceType{ID: uuid.New().String(), IsActive: true}
+	uid := uuid.New()
+	repo := &mockLeaveRepo{types: []*model.AbsenceType{paid, unpaid}, starts: map[string]string{uid.String(): "2024-01-15"}}
+	svc := NewLeaveService(repo)
+
+	// reading the balances credits nothing
+	balances, err := svc.GetBalances(uid)
+	assert.NoError(t, err)
+	assert.Len(t, balances, 1, "only types with a balance are listed")
+	assert.Equal(t, 0.0, balances[0].Balance)
+	assert.Equal(t, 0, repo.saved)
+
+	// January to March, the month of the contract start included
+	now := time.Date(2024, 3, 10, 12, 0, 0, 0, time.UTC)
+	credited, err := svc.AccrueAll(now)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, credited)
+	balances, err = svc.GetBalances(uid)
+	assert.NoError(t, err)
+	assert.Equal(t, 6.0, balances[0].Balance)
+	assert.Equal(t, "2024-03", *balances[0].AccruedThrough)
+
+	// a second run in the month credits nothing more
+	credited, err = svc.AccrueAll(now.AddDate(0, 0, 5))
+	assert.NoError(t, err)
+	assert.Equal(t, 0, credited)
+	assert.Equal(t, 1, repo.saved)
+
+	// the next month is credited once, whichever path runs first
+	_, err = svc.accruedBalances(uid, now.AddDate(0, 1, 0))
+	assert.NoError(t, err)
+	_, err = svc.AccrueAll(now.AddDate(0, 1, 0))
+	assert.NoError(t, err)
+	assert.Equal(t, 8.0, repo.balances[0].Balance)
+	assert.Equal(t, 2, repo.saved)
+}
+
+func TestReviewAndCancelLeave(t *testing.T) {
+	requester := uuid.New()
+	managerID := uuid.New()
+	pending := &model.LeaveRequest{ID: "p", UserID: &model.User{ID: requester.String()}, AbsenceType: &model.AbsenceType{}, Status: model.LeaveStatusPending}
+	started := &model.LeaveRequest{ID: "s", UserID: &model.User{ID: requester.String()}, StartDate: "2000-01-03", Status: model.LeaveStatusApproved}
+	repo := &mockLeaveRepo{requests: []*model.LeaveRequest{pending, started}}
+	svc := NewLeaveService(repo)
+
+	_, err := svc.ReviewLeave(requester, string(model.RoleManager), "p", true, nil)
+	assert.Error(t, err, "nobody reviews their own request")
+	_, err = svc.ReviewLeave(managerID, string(model.RoleManager), "p", true, nil)
+	assert.Error(t, err, "not the requester's manager")
+	assert.False(t, repo.reviewed)
+	repo.manager = true
+	_, err = svc.ReviewLeave(managerID, string(model.RoleManager), "p", true, nil)
+	assert.NoError(t, err)
+	assert.True(t, repo.reviewed)
+
+	_, err = svc.CancelLeave(managerID, string(model.RoleManager), "p")
+	assert.Error(t, err, "only the requester cancels")
+	_, err = svc.CancelLeave(requester, string(model.RoleUser), "s")
+	assert.Error(t, err, "the leave has started")
+	assert.False(t, repo.canceled)
+	_, err = svc.CancelLeave(managerID, string(model.RoleAdmin), "s")
+	assert.NoError(t, err)
+	assert.True(t, repo.canceled)
+}
+
+func TestKpiExcludesApprovedLeave(t *testing.T) {
+	u := &model.User{ID: uuid.New().String()}
+	leaves := &mockLeaveRepo{requests: []*model.LeaveRequest{
+		{UserID: u, StartDate: "2024-01-15", EndDate: "2024-01-15", HalfDay: true, Days: 0.5, Status: model.LeaveStatusApproved},
+		{UserID: u, StartDate: "2024-01-16", EndDate: "2024-01-19", Days: 4, Status: model.LeaveStatusApproved},
+	}}
+	// morning off then 5h in the afternoon, arriving well after 10:00
+	mon := time.Date(2024, 1, 15, 13, 0, 0, 0, time.UTC)
+	monDep := mon.Add(5 * time.Hour)
+	entries := []*model.TimeTableEntry{{UserID: u, Day: "2024-01-15", Arrival: mon, Departure: &monDep}}
+	svc := NewKpiService(&mockKpiRepo{entries: entries})
+	svc.Leaves = NewLeaveService(leaves)
+
+	report := overtimeReportOf(svc, svc.dailyRollups(entries, time.Now()), model.KpiGranularityWeek)
+	// 5h worked for half of the 7h day
+	assert.Equal(t, int32(90), report.TotalOvertimeMinutes)
+
+	punctual, total := svc.planning().punctuality(entries)
+	assert.Equal(t, 0, punctual)
+	assert.Equal(t, 0, total, "a day of leave is not rated")
+
+	calendar := svc.Leaves.NewAbsenceCalendar()
+	assert.Equal(t, 4.5, calendar.Days(u.ID, "2024-01-01", "2024-01-31"))
+	assert.Equal(t, 2.5, calendar.Days(u.ID, "2024-01-15", "2024-01-17"))
+}
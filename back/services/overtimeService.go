@@ -0,0 +1,366 @@
+package services
+
+import (
+	"errors"
+	"sort"
+	"time"
+
+	"github.com/epitech/timemanager/internal/graph/model"
+)
+
+// defaultExpectedWeeklyMinutes is the legal week (35h) used as the weekly
+// threshold when neither the policy nor a contract or schedule sets one.
+const defaultExpectedWeeklyMinutes = 5 * defaultExpectedDailyMinutes
+
+// OvertimeRepository is the minimal repository contract used by OvertimeService.
+type OvertimeRepository interface {
+	// GetOvertimePolicy returns the policy in force, or nil when none was set.
+	GetOvertimePolicy() (*model.OvertimePolicy, error)
+	SetOvertimePolicy(input model.OvertimePolicyInput) (*model.OvertimePolicy, error)
+}
+
+type OvertimeService struct {
+	Repo OvertimeRepository
+	// Rollups is rebuilt with a new policy; optional.
+	Rollups *RollupService
+}
+
+func NewOvertimeService(repo OvertimeRepository) *OvertimeService {
+	return &OvertimeService{Repo: repo}
+}
+
+// DefaultOvertimePolicy counts a day's work beyond its expected minutes, with
+// no weekly rule, premium nor cap.
+func DefaultOvertimePolicy() *model.OvertimePolicy {
+	return &model.OvertimePolicy{DailyEnabled: true, Bands: []*model.OvertimeBand{}}
+}
+
+// GetOvertimePolicy returns the policy in force, the default one until an admin sets one.
+func (s *OvertimeService) GetOvertimePolicy() (*model.OvertimePolicy, error) {
+	policy, err := s.Repo.GetOvertimePolicy()
+	if err != nil {
+		return nil, err
+	}
+	if policy == nil {
+		return DefaultOvertimePolicy(), nil
+	}
+	return policy, nil
+}
+
+func (s *OvertimeService) SetOvertimePolicy(input model.OvertimePolicyInput) (*model.OvertimePolicy, error) {
+	for _, v := range []struct {
+		name  string
+		value *int32
+	}{
+		{"dailyThresholdMinutes", input.DailyThresholdMinutes},
+		{"weeklyThresholdMinutes", input.WeeklyThresholdMinutes},
+		{"dailyCapMinutes", input.DailyCapMinutes},
+		{"weeklyCapMinutes", input.WeeklyCapMinutes},
+	} {
+		if v.value != nil && *v.value <= 0 {
+			return nil, errors.New(v.name + " must be positive")
+		}
+	}
+	if !input.DailyEnabled && !input.WeeklyEnabled {
+		return nil, errors.New("enable the daily or the weekly rule")
+	}
+	var previous int32
+	for i, b := range input.Bands {
+		if b == nil {
+			return nil, errors.New("invalid overtime band")
+		}
+		if b.RatePercent < 0 {
+			return nil, errors.New("a band's ratePercent can't be negative")
+		}
+		if b.UpToMinutes == nil {
+			if i != len(input.Bands)-1 {
+				return nil, errors.New("only the last band can be open-ended")
+			}
+			continue
+		}
+		if *b.UpToMinutes <= previous {
+			return nil, errors.New("bands must be given in increasing upToMinutes")
+		}
+		previous = *b.UpToMinutes
+	}
+	policy, err := s.Repo.SetOvertimePolicy(input)
+	if err != nil {
+		return nil, err
+	}
+	s.Rollups.RebuildCovered()
+	return policy, nil
+}
+
+// overtimeEngine applies an overtime policy to the minutes worked per user and
+// day. Weeks start on Monday; a day's overtime is its work beyond the daily
+// threshold, and the rest of the week's work beyond the weekly threshold is
+// overtime too, counted on the day it crosses it.
+type overtimeEngine struct {
+	policy   *model.OvertimePolicy
+	planning kpiPlanning
+}
+
+// userOvertime is the overtime of a user over a period.
+type userOvertime struct {
+	minutes    int            // counted overtime, within the caps
+	premium    int            // extra minutes owed by the premium bands
+	excess     int            // overtime beyond the caps, not counted
+	bands      []int          // counted minutes per band of the policy
+	days       map[string]int // counted minutes per day
+	excessDays map[string]int // minutes beyond the caps per day
+}
+
+func (e overtimeEngine) newUserOvertime() *userOvertime {
+	return &userOvertime{bands: make([]int, len(e.policy.Bands)), days: make(map[string]int), excessDays: make(map[string]int)}
+}
+
+// overtimeEngine loads the policy in force; a policy that can't be read is
+// replaced by the default one.
+func (s *KpiService) overtimeEngine(planning kpiPlanning) overtimeEngine {
+	policy := DefaultOvertimePolicy()
+	if s.Overtime != nil {
+		if p, err := s.Overtime.GetOvertimePolicy(); err == nil {
+			policy = p
+		}
+	}
+	return overtimeEngine{policy: policy, planning: planning}
+}
+
+func (e overtimeEngine) compute(perUserDay map[userDay]int) map[string]*userOvertime {
+	weeks := make(map[string]map[string][]string) // user -> monday -> days
+	for key := range perUserDay {
+		if key.userID == "" {
+			continue
+		}
+		dt, err := time.Parse(layoutISO, key.day)
+		if err != nil {
+			continue
+		}
+		monday := mondayOf(dt).Format(layoutISO)
+		if weeks[key.userID] == nil {
+			weeks[key.userID] = make(map[string][]string)
+		}
+		weeks[key.userID][monday] = append(weeks[key.userID][monday], key.day)
+	}
+
+	out := make(map[string]*userOvertime, len(weeks))
+	for userID, byWeek := range weeks {
+		ot := e.newUserOvertime()
+		for monday, days := range byWeek {
+			sort.Strings(days)
+			worked := make([]int, len(days))
+			for i, day := range days {
+				worked[i] = perUserDay[userDay{userID: userID, day: day}]
+			}
+			e.week(ot, userID, monday, days, worked)
+		}
+		out[userID] = ot
+	}
+	return out
+}
+
+// week adds the overtime of one week, its days in order, to ot.
+func (e overtimeEngine) week(ot *userOvertime, userID, monday string, days []string, worked []int) {
+	overtime := make([]int, len(days))
+	regular := make([]int, len(days))
+	for i, day := range days {
+		daily := 0
+		if e.policy.DailyEnabled {
+			threshold := e.planning.expectedMinutes(userID, day)
+			if e.policy.DailyThresholdMinutes != nil {
+				threshold = int(*e.policy.DailyThresholdMinutes)
+			}
+			daily = max(0, worked[i]-threshold)
+		}
+		regular[i] = worked[i] - daily
+		if e.policy.DailyCapMinutes != nil && daily > int(*e.policy.DailyCapMinutes) {
+			ot.excess += daily - int(*e.policy.DailyCapMinutes)
+			ot.excessDays[day] += daily - int(*e.policy.DailyCapMinutes)
+			daily = int(*e.policy.DailyCapMinutes)
+		}
+		overtime[i] = daily
+	}
+
+	if e.policy.WeeklyEnabled {
+		threshold := e.planning.expectedWeek(userID, monday)
+		if e.policy.WeeklyThresholdMinutes != nil {
+			threshold = int(*e.policy.WeeklyThresholdMinutes)
+		}
+		cumulated := 0
+		for i := range days {
+			before := cumulated
+			cumulated += regular[i]
+			overtime[i] += max(0, cumulated-max(before, threshold))
+		}
+	}
+
+	total := 0
+	for _, m := range overtime {
+		total += m
+	}
+	if e.policy.WeeklyCapMinutes != nil && total > int(*e.policy.WeeklyCapMinutes) {
+		over := total - int(*e.policy.WeeklyCapMinutes)
+		ot.excess += over
+		total -= over
+		// the cap cuts the last overtime of the week
+		for i := len(days) - 1; i >= 0 && over > 0; i-- {
+			cut := min(over, overtime[i])
+			overtime[i] -= cut
+			over -= cut
+			if cut > 0 {
+				ot.excessDays[days[i]] += cut
+			}
+		}
+	}
+
+	for i, day := range days {
+		if overtime[i] > 0 {
+			ot.days[day] += overtime[i]
+		}
+	}
+	ot.minutes += total
+	e.addBands(ot, total)
+}
+
+// addBands spreads the counted overtime of a week over the premium bands.
+func (e overtimeEngine) addBands(ot *userOvertime, total int) {
+	bands := make([]int, len(e.policy.Bands))
+	e.spreadBands(bands, 0, total)
+	e.addWeekBands(ot, bands)
+}
+
+// spreadBands adds to bands the minutes of the week's overtime from its
+// minute lo to its minute hi, the bands applying to the first minutes.
+func (e overtimeEngine) spreadBands(bands []int, lo, hi int) {
+	floor := 0
+	for i, b := range e.policy.Bands {
+		if hi <= floor {
+			break
+		}
+		ceiling := hi
+		if b.UpToMinutes != nil {
+			ceiling = min(hi, int(*b.UpToMinutes))
+		}
+		if ceiling > max(lo, floor) {
+			bands[i] += ceiling - max(lo, floor)
+		}
+		floor = max(floor, ceiling)
+	}
+}
+
+// addWeekBands adds a week's minutes per band and the premium they owe.
+func (e overtimeEngine) addWeekBands(ot *userOvertime, bands []int) {
+	for i, minutes := range bands {
+		ot.bands[i] += minutes
+		ot.premium += minutes * int(e.policy.Bands[i].RatePercent) / 100
+	}
+}
+
+// within keeps the overtime of the days from from to to inclusive (empty:
+// unbounded). The weeks are evaluated whole before, so that a week cut by
+// the window keeps the overtime its days owe; the premium bands of the week
+// go to its days in order.
+func (e overtimeEngine) within(perUser map[string]*userOvertime, from, to string) map[string]*userOvertime {
+	out := make(map[string]*userOvertime, len(perUser))
+	for userID, ot := range perUser {
+		out[userID] = e.attribute(ot.days, ot.excessDays, from, to)
+	}
+	return out
+}
+
+// attribute builds the overtime of the days of the window from the counted
+// and excess minutes per day of whole weeks.
+func (e overtimeEngine) attribute(days, excessDays map[string]int, from, to string) *userOvertime {
+	inWindow := func(day string) bool { return (from == "" || day >= from) && (to == "" || day <= to) }
+	weeks := make(map[string][]string) // monday -> days
+	for day := range days {
+		dt, err := time.Parse(layoutISO, day)
+		if err != nil {
+			continue
+		}
+		monday := mondayOf(dt).Format(layoutISO)
+		weeks[monday] = append(weeks[monday], day)
+	}
+	ot := e.newUserOvertime()
+	for _, weekDays := range weeks {
+		sort.Strings(weekDays)
+		bands := make([]int, len(e.policy.Bands))
+		counted := 0
+		for _, day := range weekDays {
+			minutes := days[day]
+			if minutes > 0 && inWindow(day) {
+				ot.days[day] += minutes
+				ot.minutes += minutes
+				e.spreadBands(bands, counted, counted+minutes)
+			}
+			counted += minutes
+		}
+		e.addWeekBands(ot, bands)
+	}
+	for day, minutes := range excessDays {
+		if minutes > 0 && inWindow(day) {
+			ot.excessDays[day] += minutes
+			ot.excess += minutes
+		}
+	}
+	return ot
+}
+
+// fromWeeks sums the overtime of the weeks, already settled by the policy:
+// the premium bands of a week cut by the window start after the overtime of
+// its days before it.
+func (e overtimeEngine) fromWeeks(weeks []*model.KpiOvertimeWeek) map[string]*userOvertime {
+	out := make(map[string]*userOvertime)
+	for _, w := range weeks {
+		ot := out[w.UserID]
+		if ot == nil {
+			ot = e.newUserOvertime()
+			out[w.UserID] = ot
+		}
+		bands := make([]int, len(e.policy.Bands))
+		e.spreadBands(bands, w.BeforeMinutes, w.BeforeMinutes+w.Minutes)
+		e.addWeekBands(ot, bands)
+		ot.minutes += w.Minutes
+		ot.excess += w.ExcessMinutes
+	}
+	return out
+}
+
+// bandMinutes pairs the minutes per band with the rates of the policy.
+func (e overtimeEngine) bandMinutes(bands []int) []*model.OvertimeBandMinutes {
+	out := make([]*model.OvertimeBandMinutes, 0, len(e.policy.Bands))
+	for i, b := range e.policy.Bands {
+		minutes := 0
+		if i < len(bands) {
+			minutes = bands[i]
+		}
+		out = append(out, &model.OvertimeBandMinutes{RatePercent: b.RatePercent, Minutes: int32(minutes)})
+	}
+	return out
+}
+
+// total sums the overtime of all users.
+func (e overtimeEngine) total(perUser map[string]*userOvertime) *userOvertime {
+	sum := &userOvertime{bands: make([]int, len(e.policy.Bands))}
+	for _, ot := range perUser {
+		sum.minutes += ot.minutes
+		sum.premium += ot.premium
+		sum.excess += ot.excess
+		for i, m := range ot.bands {
+			sum.bands[i] += m
+		}
+	}
+	return sum
+}
+
+// weekSpan widens a window to the whole weeks it touches, plus the day before
+// for the sessions split at midnight: the overtime of a day depends on its
+// whole week.
+func weekSpan(start, end time.Time) (time.Time, time.Time) {
+	return mondayOf(start).AddDate(0, 0, -1), mondayOf(end).AddDate(0, 0, 6)
+}
+
+// mondayOf returns the Monday starting the week of t.
+func mondayOf(t time.Time) time.Time {
+	return t.AddDate(0, 0, -(int(t.Weekday())+6)%7)
+}
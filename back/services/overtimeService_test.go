@@ -0,0 +1,176 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/epitech/timemanager/internal/graph/model"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+type mockOvertimeRepo struct {
+	policy *model.OvertimePolicy
+	saved  bool
+}
+
+func (m *mockOvertimeRepo) GetOvertimePolicy() (*model.OvertimePolicy, error) { return m.policy, nil }
+
+func (m *mockOvertimeRepo) SetOvertimePolicy(input model.OvertimePolicyInput) (*model.OvertimePolicy, error) {
+	m.saved = true
+	return &model.OvertimePolicy{DailyEnabled: input.DailyEnabled, WeeklyEnabled: input.WeeklyEnabled}, nil
+}
+
+func int32Ptr(v int32) *int32 { return &v }
+
+// frenchWeek is the 35h week with +25% for the first 8 hours and +50% beyond.
+func frenchWeek() *model.OvertimePolicy {
+	return &model.OvertimePolicy{
+		WeeklyEnabled:          true,
+		WeeklyThresholdMinutes: int32Ptr(35 * 60),
+		Bands: []*model.OvertimeBand{
+			{UpToMinutes: int32Ptr(8 * 60), RatePercent: 25},
+			{RatePercent: 50},
+		},
+	}
+}
+
+func workWeek(userID string, minutes ...int) map[userDay]int {
+	out := make(map[userDay]int)
+	monday := time.Date(2024, 1, 8, 0, 0, 0, 0, time.UTC)
+	for i, m := range minutes {
+		out[userDay{userID: userID, day: monday.AddDate(0, 0, i).Format(layoutISO)}] = m
+	}
+	return out
+}
+
+func TestSetOvertimePolicyValidation(t *testing.T) {
+	repo := &mockOvertimeRepo{}
+	svc := NewOvertimeService(repo)
+
+	got, err := svc.GetOvertimePolicy()
+	assert.NoError(t, err)
+	assert.True(t, got.DailyEnabled, "default policy until one is set")
+
+	_, err = svc.SetOvertimePolicy(model.OvertimePolicyInput{})
+	assert.Error(t, err, "no rule enabled")
+	_, err = svc.SetOvertimePolicy(model.OvertimePolicyInput{WeeklyEnabled: true, WeeklyThresholdMinutes: int32Ptr(0)})
+	assert.Error(t, err)
+	_, err = svc.SetOvertimePolicy(model.OvertimePolicyInput{WeeklyEnabled: true, Bands: []*model.OvertimeBandInput{
+		{RatePercent: 25}, {UpToMinutes: int32Ptr(480), RatePercent: 50},
+	}})
+	assert.Error(t, err, "open band before the last")
+	_, err = svc.SetOvertimePolicy(model.OvertimePolicyInput{WeeklyEnabled: true, Bands: []*model.OvertimeBandInput{
+		{UpToMinutes: int32Ptr(480), RatePercent: 25}, {UpToMinutes: int32Ptr(240), RatePercent: 50},
+	}})
+	assert.Error(t, err, "decreasing bands")
+	assert.False(t, repo.saved)
+
+	_, err = svc.SetOvertimePolicy(model.OvertimePolicyInput{WeeklyEnabled: true, Bands: []*model.OvertimeBandInput{
+		{UpToMinutes: int32Ptr(480), RatePercent: 25}, {RatePercent: 50},
+	}})
+	assert.NoError(t, err)
+	assert.True(t, repo.saved)
+}
+
+func TestOvertimeEngineWeeklyBands(t *testing.T) {
+	engine := overtimeEngine{policy: frenchWeek()}
+
+	// 5 x 9h = 45h: 10h of overtime, crossing 35h on Thursday
+	ot := engine.compute(workWeek("u1", 540, 540, 540, 540, 540))["u1"]
+	assert.Equal(t, 600, ot.minutes)
+	assert.Equal(t, []int{480, 120}, ot.bands)
+	assert.Equal(t, 480*25/100+120*50/100, ot.premium)
+	assert.Equal(t, map[string]int{"2024-01-11": 60, "2024-01-12": 540}, ot.days)
+
+	// the weekly cap drops the last overtime of the week
+	capped := frenchWeek()
+	capped.WeeklyCapMinutes = int32Ptr(540)
+	ot = overtimeEngine{policy: capped}.compute(workWeek("u1", 540, 540, 540, 540, 540))["u1"]
+	assert.Equal(t, 540, ot.minutes)
+	assert.Equal(t, 60, ot.excess)
+	assert.Equal(t, 480, ot.days["2024-01-12"])
+}
+
+func TestOvertimeEngineDailyAndWeeklyDoNotDoubleCount(t *testing.T) {
+	policy := frenchWeek()
+	policy.DailyEnabled = true
+	policy.DailyThresholdMinutes = int32Ptr(10 * 60)
+	policy.DailyCapMinutes = int32Ptr(30)
+
+	// Monday 11h: 1h of daily overtime, capped at 30min; the other 10h and
+	// the four 8h days make 42h of regular work, 7h beyond the week
+	ot := overtimeEngine{policy: policy}.compute(workWeek("u1", 660, 480, 480, 480, 480))["u1"]
+	assert.Equal(t, 30+420, ot.minutes)
+	assert.Equal(t, 30, ot.excess)
+	assert.Equal(t, 30, ot.days["2024-01-08"])
+}
+
+func TestOvertimeOfAWeekCutByTheWindow(t *testing.T) {
+	engine := overtimeEngine{policy: frenchWeek()}
+	week := engine.compute(workWeek("u1", 540, 540, 540, 540, 540))
+
+	// Friday keeps its 9h of overtime, after the week's first hour of the band at 25%
+	ot := engine.within(week, "2024-01-12", "2024-01-31")["u1"]
+	assert.Equal(t, 540, ot.minutes)
+	assert.Equal(t, []int{420, 120}, ot.bands)
+	assert.Equal(t, 420*25/100+120*50/100, ot.premium)
+	assert.Equal(t, map[string]int{"2024-01-12": 540}, ot.days)
+
+	// the same from the sums of the rollups of the whole week
+	rollups := make([]*model.DailyRollup, 0, 5)
+	for day, minutes := range week["u1"].days {
+		rollups = append(rollups, &model.DailyRollup{UserID: "u1", Day: day, OvertimeMinutes: int32(minutes)})
+	}
+	summed := engine.fromWeeks(overtimeWeeksOf(rollups, "2024-01-12", "2024-01-31"))["u1"]
+	assert.Equal(t, ot.minutes, summed.minutes)
+	assert.Equal(t, ot.bands, summed.bands)
+	assert.Equal(t, ot.premium, summed.premium)
+
+	// a window starting on Thursday used to miss the hours worked before it
+	u := &model.User{ID: uuid.New().String()}
+	var entries []*model.TimeTableEntry
+	for d := 8; d <= 12; d++ {
+		arr := time.Date(2024, 1, d, 8, 0, 0, 0, time.UTC)
+		dep := arr.Add(9 * time.Hour)
+		entries = append(entries, &model.TimeTableEntry{UserID: u, Day: arr.Format(layoutISO), Arrival: arr, Departure: &dep})
+	}
+	svc := NewKpiService(&mockKpiRepo{entries: entries})
+	svc.Overtime = NewOvertimeService(&mockOvertimeRepo{policy: frenchWeek()})
+	uid := uuid.New()
+	got, err := svc.GetUserKpiSummary(context.Background(), &uid,
+		time.Date(2024, 1, 11, 0, 0, 0, 0, time.UTC), time.Date(2024, 1, 12, 0, 0, 0, 0, time.UTC), nil, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, int32(2*540), got.WorkedMinutes)
+	assert.Equal(t, int32(600), got.OvertimeMinutes)
+}
+
+func TestKpiSummaryUsesOvertimePolicy(t *testing.T) {
+	u := &model.User{ID: uuid.New().String()}
+	var entries []*model.TimeTableEntry
+	for d := 8; d <= 12; d++ {
+		arr := time.Date(2024, 1, d, 8, 0, 0, 0, time.UTC)
+		dep := arr.Add(9 * time.Hour)
+		entries = append(entries, &model.TimeTableEntry{UserID: u, Day: arr.Format(layoutISO), Arrival: arr, Departure: &dep})
+	}
+	svc := NewKpiService(&mockKpiRepo{entries: entries})
+	svc.Overtime = NewOvertimeService(&mockOvertimeRepo{policy: frenchWeek()})
+	uid := uuid.New()
+	from := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2024, 1, 31, 0, 0, 0, 0, time.UTC)
+
+	got, err := svc.GetUserKpiSummary(context.Background(), &uid, from, to, nil, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, int32(600), got.OvertimeMinutes)
+	assert.Equal(t, int32(180), got.OvertimePremiumMinutes)
+
+	report := overtimeReportOf(svc, svc.dailyRollups(entries, time.Now()), model.KpiGranularityWeek)
+	assert.Equal(t, int32(600), report.TotalOvertimeMinutes)
+	assert.Equal(t, "2024-01-08", report.OvertimeByWeek[0].PeriodStart)
+	assert.Equal(t, int32(2), report.TopOvertimeUsers[0].DaysWorked)
+
+	csvOut, err := svc.ExportUserKpiCSV(context.Background(), &uid, from, to)
+	assert.NoError(t, err)
+	assert.Contains(t, csvOut, ",180,0,25%:480;50%:120")
+}
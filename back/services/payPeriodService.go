@@ -0,0 +1,121 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/epitech/timemanager/internal/graph/model"
+	"github.com/epitech/timemanager/package/payperiod"
+	"github.com/google/uuid"
+)
+
+// ErrPayPeriodClosed is returned by every change of an entry falling in a
+// closed pay period; the API reports it with its own error code. The
+// repositories check it again within the write's transaction.
+var ErrPayPeriodClosed = payperiod.ErrClosed
+
+// PayPeriodRepository is the minimal repository contract used by PayPeriodService.
+type PayPeriodRepository interface {
+	// ClosePayPeriod fails while a session of the period is still running.
+	ClosePayPeriod(startDate, endDate string, closedBy uuid.UUID) (*model.PayPeriod, error)
+	GetPayPeriodByID(id string) (*model.PayPeriod, error)
+	GetPayPeriods(status *model.PayPeriodStatus, from, to *string) ([]*model.PayPeriod, error)
+	// GetClosedPayPeriod returns the closed period covering day, nil when none.
+	GetClosedPayPeriod(day string) (*model.PayPeriod, error)
+	ReopenPayPeriod(id string, reopenedBy uuid.UUID, reason string) (*model.PayPeriod, error)
+}
+
+// PayPeriodService handles the payroll closing: once an admin closes a pay
+// period no entry of its days can be created, edited or deleted, until an
+// admin reopens it with a reason.
+type PayPeriodService struct {
+	Repo PayPeriodRepository
+	// Payroll freezes the payroll of the periods it closes; optional.
+	Payroll *PayrollService
+}
+
+func NewPayPeriodService(repo PayPeriodRepository) *PayPeriodService {
+	return &PayPeriodService{Repo: repo}
+}
+
+// Close locks the days from startDate to endDate (YYYY-MM-DD, inclusive) and
+// freezes their payroll. Every session of the period must be closed first. A
+// payroll that fails to freeze is only logged: the first export freezes it.
+func (s *PayPeriodService) Close(adminID uuid.UUID, startDate, endDate string) (*model.PayPeriod, error) {
+	start, err := time.Parse(layoutISO, startDate)
+	if err != nil {
+		return nil, errors.New("invalid startDate, expected YYYY-MM-DD")
+	}
+	end, err := time.Parse(layoutISO, endDate)
+	if err != nil {
+		return nil, errors.New("invalid endDate, expected YYYY-MM-DD")
+	}
+	if end.Before(start) {
+		return nil, errors.New("endDate must not be before startDate")
+	}
+	period, err := s.Repo.ClosePayPeriod(startDate, endDate, adminID)
+	if err != nil {
+		return nil, err
+	}
+	if s.Payroll != nil {
+		if err := s.Payroll.Freeze(period); err != nil {
+			log.Printf("failed to freeze the payroll from %s to %s: %v", startDate, endDate, err)
+		}
+	}
+	return period, nil
+}
+
+// Reopen unlocks a closed pay period; the reason is kept with it.
+func (s *PayPeriodService) Reopen(adminID uuid.UUID, id string, reason string) (*model.PayPeriod, error) {
+	reason = strings.TrimSpace(reason)
+	if reason == "" {
+		return nil, errors.New("a reason is required")
+	}
+	period, err := s.Repo.GetPayPeriodByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if period.Status != model.PayPeriodStatusClosed {
+		return nil, errors.New("pay period is not closed")
+	}
+	return s.Repo.ReopenPayPeriod(id, adminID, reason)
+}
+
+// GetPayPeriods lists the pay periods overlapping the days from from to to.
+func (s *PayPeriodService) GetPayPeriods(status *model.PayPeriodStatus, from, to *string) ([]*model.PayPeriod, error) {
+	for _, day := range []*string{from, to} {
+		if day == nil || *day == "" {
+			continue
+		}
+		if _, err := time.Parse(layoutISO, *day); err != nil {
+			return nil, errors.New("invalid date, expected YYYY-MM-DD")
+		}
+	}
+	return s.Repo.GetPayPeriods(status, from, to)
+}
+
+// EnsureOpen fails with ErrPayPeriodClosed when one of the days (YYYY-MM-DD)
+// falls in a closed pay period. A nil service locks nothing.
+func (s *PayPeriodService) EnsureOpen(days ...string) error {
+	if s == nil {
+		return nil
+	}
+	checked := make(map[string]struct{}, len(days))
+	for _, day := range days {
+		if _, ok := checked[day]; ok {
+			continue
+		}
+		checked[day] = struct{}{}
+		period, err := s.Repo.GetClosedPayPeriod(day)
+		if err != nil {
+			return err
+		}
+		if period != nil {
+			return fmt.Errorf("%w (%s to %s)", ErrPayPeriodClosed, period.StartDate, period.EndDate)
+		}
+	}
+	return nil
+}
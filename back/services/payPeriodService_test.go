@@ -0,0 +1,147 @@
+package services
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/epitech/timemanager/internal/graph/model"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+type mockPayPeriodRepo struct {
+	periods []*model.PayPeriod
+	entries []*model.TimeTableEntry
+}
+
+func (m *mockPayPeriodRepo) ClosePayPeriod(startDate, endDate string, closedBy uuid.UUID) (*model.PayPeriod, error) {
+	for _, e := range m.entries {
+		if e.Status && startDate <= e.Day && e.Day <= endDate {
+			return nil, errors.New("a session of this period is still running")
+		}
+	}
+	period := &model.PayPeriod{
+		ID:         uuid.New().String(),
+		StartDate:  startDate,
+		EndDate:    endDate,
+		Status:     model.PayPeriodStatusClosed,
+		ClosedByID: &model.User{ID: closedBy.String()},
+		ClosedAt:   time.Now(),
+	}
+	m.periods = append(m.periods, period)
+	return period, nil
+}
+
+func (m *mockPayPeriodRepo) GetPayPeriodByID(id string) (*model.PayPeriod, error) {
+	for _, p := range m.periods {
+		if p.ID == id {
+			return p, nil
+		}
+	}
+	return nil, assert.AnError
+}
+
+func (m *mockPayPeriodRepo) GetPayPeriods(status *model.PayPeriodStatus, from, to *string) ([]*model.PayPeriod, error) {
+	return m.periods, nil
+}
+
+func (m *mockPayPeriodRepo) GetClosedPayPeriod(day string) (*model.PayPeriod, error) {
+	for _, p := range m.periods {
+		if p.Status == model.PayPeriodStatusClosed && p.StartDate <= day && day <= p.EndDate {
+			return p, nil
+		}
+	}
+	return nil, nil
+}
+
+func (m *mockPayPeriodRepo) ReopenPayPeriod(id string, reopenedBy uuid.UUID, reason string) (*model.PayPeriod, error) {
+	p, err := m.GetPayPeriodByID(id)
+	if err != nil {
+		return nil, err
+	}
+	p.Status = model.PayPeriodStatusReopened
+	p.ReopenedByID = &model.User{ID: reopenedBy.String()}
+	p.ReopenReason = &reason
+	return p, nil
+}
+
+func TestPayPeriodCloseAndReopen(t *testing.T) {
+	admin := uuid.New()
+	running := &model.TimeTableEntry{Day: "2024-01-31", Arrival: time.Date(2024, 1, 31, 9, 0, 0, 0, time.UTC), Status: true}
+	repo := &mockPayPeriodRepo{entries: []*model.TimeTableEntry{running}}
+	svc := NewPayPeriodService(repo)
+
+	_, err := svc.Close(admin, "2024-01-31", "2024-01-01")
+	assert.Error(t, err)
+	_, err = svc.Close(admin, "2024-01", "2024-01-31")
+	assert.Error(t, err)
+	_, err = svc.Close(admin, "2024-01-01", "2024-01-31")
+	assert.Error(t, err, "a session is still running")
+
+	running.Status = false
+	period, err := svc.Close(admin, "2024-01-01", "2024-01-31")
+	assert.NoError(t, err)
+	assert.Equal(t, model.PayPeriodStatusClosed, period.Status)
+	err = svc.EnsureOpen("2024-02-01", "2024-01-15")
+	assert.True(t, errors.Is(err, ErrPayPeriodClosed))
+	assert.NoError(t, svc.EnsureOpen("2023-12-31", "2024-02-01"))
+
+	_, err = svc.Reopen(admin, period.ID, "  ")
+	assert.Error(t, err, "a reason is required")
+	reopened, err := svc.Reopen(admin, period.ID, "late sick note")
+	assert.NoError(t, err)
+	assert.Equal(t, model.PayPeriodStatusReopened, reopened.Status)
+	assert.Equal(t, "late sick note", *reopened.ReopenReason)
+	assert.NoError(t, svc.EnsureOpen("2024-01-15"))
+	_, err = svc.Reopen(admin, period.ID, "again")
+	assert.Error(t, err, "already reopened")
+
+	var none *PayPeriodService
+	assert.NoError(t, none.EnsureOpen("2024-01-15"), "no service locks nothing")
+}
+
+func TestTimeTableServiceLocksClosedPayPeriods(t *testing.T) {
+	employee := uuid.New()
+	periods := &mockPayPeriodRepo{periods: []*model.PayPeriod{
+		{ID: "p1", StartDate: "2024-01-01", EndDate: "2024-01-31", Status: model.PayPeriodStatusClosed},
+	}}
+	current := &model.TimeTableEntry{ID: "e1", UserID: &model.User{ID: employee.String()}, Day: "2024-02-05", Arrival: time.Date(2024, 2, 5, 9, 0, 0, 0, time.UTC)}
+	repo := &mockTTRepo{entry: current}
+	svc := NewTimeTableService(repo)
+	svc.PayPeriods = NewPayPeriodService(periods)
+
+	_, err := svc.CreateTimeEntry(model.CreateTimeEntryInput{UserID: employee.String(), Day: "2024-01-09", Arrival: time.Date(2024, 1, 9, 9, 0, 0, 0, time.UTC)})
+	assert.True(t, errors.Is(err, ErrPayPeriodClosed))
+	assert.Nil(t, repo.created)
+	_, err = svc.UpdateTimeEntry("e1", model.UpdateTimeEntryInput{Day: ptrString("2024-01-31")})
+	assert.True(t, errors.Is(err, ErrPayPeriodClosed), "can't move an entry into a closed period")
+	_, err = svc.UpdateTimeEntry("e1", model.UpdateTimeEntryInput{Day: ptrString("2024-02-06")})
+	assert.NoError(t, err)
+
+	current.Day = "2024-01-12"
+	_, err = svc.DeleteTimeEntry("e1")
+	assert.True(t, errors.Is(err, ErrPayPeriodClosed))
+	_, err = svc.RequestCorrection(employee, model.RequestTimeEntryCorrectionInput{Day: "2024-01-12", ProposedArrival: time.Date(2024, 1, 12, 9, 0, 0, 0, time.UTC), Reason: "forgot"})
+	assert.True(t, errors.Is(err, ErrPayPeriodClosed))
+
+	periods.periods[0].Status = model.PayPeriodStatusReopened
+	_, err = svc.DeleteTimeEntry("e1")
+	assert.NoError(t, err)
+}
+
+func TestAutoCloseSweepSkipsClosedPayPeriods(t *testing.T) {
+	locked := &model.TimeTableEntry{ID: "e1", Day: "2024-01-31", Arrival: time.Date(2024, 1, 31, 9, 0, 0, 0, time.UTC), Status: true}
+	open := &model.TimeTableEntry{ID: "e2", Day: "2024-02-01", Arrival: time.Date(2024, 2, 1, 9, 0, 0, 0, time.UTC), Status: true}
+	repo := &mockAutoCloseRepo{entries: []*model.TimeTableEntry{locked, open}}
+	svc := NewAutoCloseService(repo, AutoCloseConfig{Policy: AutoCloseFlagForReview, MaxDuration: 10 * time.Hour})
+	svc.PayPeriods = NewPayPeriodService(&mockPayPeriodRepo{periods: []*model.PayPeriod{
+		{ID: "p1", StartDate: "2024-01-01", EndDate: "2024-01-31", Status: model.PayPeriodStatusClosed},
+	}})
+
+	closed, flagged, err := svc.Sweep(time.Date(2024, 2, 3, 0, 0, 0, 0, time.UTC))
+	assert.NoError(t, err)
+	assert.Equal(t, 0, closed)
+	assert.Equal(t, 1, flagged)
+	assert.Equal(t, []string{"e2"}, repo.flagged)
+}
@@ -0,0 +1,829 @@
+package services
+
+import (
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/epitech/timemanager/internal/graph/model"
+	"github.com/google/uuid"
+)
+
+const (
+	// defaultNightStart and defaultNightEnd bound the legal night work.
+	defaultNightStart = 21 * time.Hour
+	defaultNightEnd   = 6 * time.Hour
+)
+
+const (
+	payUnitHours = "HOURS"
+	payUnitDays  = "DAYS"
+)
+
+// PayrollRepository is the minimal repository contract used by PayrollService.
+type PayrollRepository interface {
+	GetPayPeriodByID(id string) (*model.PayPeriod, error)
+	GetTimeTableEntriesFiltered(userID *uuid.UUID, teamID *uuid.UUID, from, to *time.Time) ([]*model.TimeTableEntry, error)
+	GetLeaveRequests(status *model.LeaveStatus, userID *uuid.UUID, managerID *uuid.UUID, from, to *string) ([]*model.LeaveRequest, error)
+	GetPayCodes() ([]*model.PayCode, error)
+	// SavePayCode replaces the code of the same category, rate and absence type.
+	SavePayCode(input model.PayCodeInput) (*model.PayCode, error)
+	DeletePayCode(id string) (bool, error)
+	GetPayrollLayouts() ([]*model.PayrollLayout, error)
+	GetPayrollLayout(id string) (*model.PayrollLayout, error)
+	CreatePayrollLayout(input model.PayrollLayoutInput) (*model.PayrollLayout, error)
+	UpdatePayrollLayout(id string, input model.PayrollLayoutInput) (*model.PayrollLayout, error)
+	DeletePayrollLayout(id string) (bool, error)
+	// GetPayrollTotals returns the totals frozen for the period, and whether
+	// they were.
+	GetPayrollTotals(periodID string) ([]*PayrollTotal, bool, error)
+	// SavePayrollTotals freezes the totals of the period, replacing any.
+	SavePayrollTotals(periodID string, totals []*PayrollTotal) error
+}
+
+// PayrollService exports the payroll of the closed pay periods: for each
+// employee, the hours of each pay category (normal, overtime per premium
+// band, overtime beyond the caps, night, Sunday, holiday) and the days of
+// absence per type, under the pay codes set by the admins.
+type PayrollService struct {
+	Repo PayrollRepository
+	// Kpi computes the hours with the KPI rules (midnight rule, overtime
+	// policy, holidays).
+	Kpi *KpiService
+	// NightStart and NightEnd bound the night work, as times of day; the
+	// window runs past midnight when NightEnd is before NightStart.
+	NightStart time.Duration
+	NightEnd   time.Duration
+}
+
+func NewPayrollService(repo PayrollRepository, kpi *KpiService) *PayrollService {
+	return &PayrollService{Repo: repo, Kpi: kpi, NightStart: defaultNightStart, NightEnd: defaultNightEnd}
+}
+
+// ParseNightWindow reads the configured start and end (HH:mm) of the night
+// work, empty values keeping the defaults.
+func ParseNightWindow(start, end string) (time.Duration, time.Duration, error) {
+	bounds := []time.Duration{defaultNightStart, defaultNightEnd}
+	for i, v := range []string{start, end} {
+		if v = strings.TrimSpace(v); v == "" {
+			continue
+		}
+		clock, err := time.Parse(layoutClock, v)
+		if err != nil {
+			return 0, 0, errors.New("invalid night work bound, expected HH:mm")
+		}
+		bounds[i] = time.Duration(clock.Hour())*time.Hour + time.Duration(clock.Minute())*time.Minute
+	}
+	return bounds[0], bounds[1], nil
+}
+
+func (s *PayrollService) GetPayCodes() ([]*model.PayCode, error) {
+	return s.Repo.GetPayCodes()
+}
+
+// SetPayCode maps a pay category to a pay code: the overtime of one premium
+// rate (0 for the overtime outside the bands), or the absences of one type.
+// A code holds hours or days, never both.
+func (s *PayrollService) SetPayCode(input model.PayCodeInput) (*model.PayCode, error) {
+	if !input.Category.IsValid() {
+		return nil, errors.New("invalid pay category")
+	}
+	input.Code = strings.TrimSpace(input.Code)
+	if input.Code == "" {
+		return nil, errors.New("a code is required")
+	}
+	if input.Label != nil {
+		label := strings.TrimSpace(*input.Label)
+		input.Label = &label
+	}
+	if input.AbsenceTypeID != nil && *input.AbsenceTypeID == "" {
+		input.AbsenceTypeID = nil
+	}
+	switch {
+	case input.Category == model.PayCategoryOvertime && input.RatePercent == nil:
+		return nil, errors.New("ratePercent is required for overtime, 0 for the overtime outside the bands")
+	case input.Category == model.PayCategoryOvertime && *input.RatePercent < 0:
+		return nil, errors.New("ratePercent can't be negative")
+	case input.Category != model.PayCategoryOvertime && input.RatePercent != nil:
+		return nil, errors.New("ratePercent only applies to overtime")
+	case input.Category == model.PayCategoryAbsence && input.AbsenceTypeID == nil:
+		return nil, errors.New("absenceTypeID is required for absences")
+	case input.Category != model.PayCategoryAbsence && input.AbsenceTypeID != nil:
+		return nil, errors.New("absenceTypeID only applies to absences")
+	}
+
+	codes, err := s.Repo.GetPayCodes()
+	if err != nil {
+		return nil, err
+	}
+	for _, c := range codes {
+		if c.Code == input.Code && payUnit(c.Category) != payUnit(input.Category) {
+			return nil, fmt.Errorf("code %s already holds %s", input.Code, strings.ToLower(payUnit(c.Category)))
+		}
+	}
+	return s.Repo.SavePayCode(input)
+}
+
+func (s *PayrollService) DeletePayCode(id string) (bool, error) {
+	return s.Repo.DeletePayCode(id)
+}
+
+func (s *PayrollService) GetPayrollLayouts() ([]*model.PayrollLayout, error) {
+	return s.Repo.GetPayrollLayouts()
+}
+
+func (s *PayrollService) CreatePayrollLayout(input model.PayrollLayoutInput) (*model.PayrollLayout, error) {
+	if err := validatePayrollLayout(&input); err != nil {
+		return nil, err
+	}
+	return s.Repo.CreatePayrollLayout(input)
+}
+
+func (s *PayrollService) UpdatePayrollLayout(id string, input model.PayrollLayoutInput) (*model.PayrollLayout, error) {
+	if err := validatePayrollLayout(&input); err != nil {
+		return nil, err
+	}
+	return s.Repo.UpdatePayrollLayout(id, input)
+}
+
+func (s *PayrollService) DeletePayrollLayout(id string) (bool, error) {
+	return s.Repo.DeletePayrollLayout(id)
+}
+
+// validatePayrollLayout checks a layout and fills its defaults: a CSV layout
+// is comma separated unless told otherwise, and every column of a fixed-width
+// layout needs a width.
+func validatePayrollLayout(input *model.PayrollLayoutInput) error {
+	input.Name = strings.TrimSpace(input.Name)
+	if input.Name == "" {
+		return errors.New("a name is required")
+	}
+	if !input.Format.IsValid() {
+		return errors.New("invalid payroll format")
+	}
+	if len(input.Columns) == 0 {
+		return errors.New("a layout needs at least one column")
+	}
+	switch input.Format {
+	case model.PayrollFormatCSV:
+		if input.Delimiter == nil || *input.Delimiter == "" {
+			comma := ","
+			input.Delimiter = &comma
+		}
+		if _, err := csvDelimiter(*input.Delimiter); err != nil {
+			return err
+		}
+	case model.PayrollFormatFixedWidth:
+		input.Delimiter = nil
+	}
+	for _, c := range input.Columns {
+		if c == nil || !c.Field.IsValid() {
+			return errors.New("invalid payroll column")
+		}
+		if c.Align != nil && !c.Align.IsValid() {
+			return errors.New("invalid column alignment")
+		}
+		if c.Width != nil && *c.Width <= 0 {
+			return errors.New("a column's width must be positive")
+		}
+		if input.Format == model.PayrollFormatFixedWidth && c.Width == nil {
+			return fmt.Errorf("column %s needs a width in a fixed-width layout", c.Field)
+		}
+	}
+	return nil
+}
+
+// csvDelimiter returns the single character separating the CSV fields.
+func csvDelimiter(delimiter string) (rune, error) {
+	r, size := utf8.DecodeRuneInString(delimiter)
+	if size == 0 || size != len(delimiter) || r == '"' || r == '\r' || r == '\n' || r == utf8.RuneError {
+		return 0, errors.New("the delimiter must be a single character other than a quote or a line break")
+	}
+	return r, nil
+}
+
+// defaultPayrollLayout is the layout of the exports without a layout of their own.
+func defaultPayrollLayout() *model.PayrollLayout {
+	fields := []model.PayrollField{
+		model.PayrollFieldEmployeeID,
+		model.PayrollFieldLastName,
+		model.PayrollFieldFirstName,
+		model.PayrollFieldPeriodStart,
+		model.PayrollFieldPeriodEnd,
+		model.PayrollFieldPayCode,
+		model.PayrollFieldLabel,
+		model.PayrollFieldQuantity,
+		model.PayrollFieldUnit,
+	}
+	layout := &model.PayrollLayout{Name: "default", Format: model.PayrollFormatCSV, Delimiter: ",", Header: true}
+	for _, f := range fields {
+		layout.Columns = append(layout.Columns, &model.PayrollColumn{Field: f})
+	}
+	return layout
+}
+
+// defaultColumnWidths size the fixed-width columns without a width, when a
+// CSV layout is exported as fixed-width text.
+var defaultColumnWidths = map[model.PayrollField]int{
+	model.PayrollFieldEmployeeID:  36,
+	model.PayrollFieldEmail:       40,
+	model.PayrollFieldLastName:    30,
+	model.PayrollFieldFirstName:   30,
+	model.PayrollFieldPeriodStart: 10,
+	model.PayrollFieldPeriodEnd:   10,
+	model.PayrollFieldPayCode:     20,
+	model.PayrollFieldLabel:       30,
+	model.PayrollFieldCategory:    15,
+	model.PayrollFieldQuantity:    10,
+	model.PayrollFieldUnit:        5,
+}
+
+// payKey identifies what a pay line counts.
+type payKey struct {
+	category    model.PayCategory
+	ratePercent int    // OVERTIME only
+	absenceType string // ABSENCE only: the absence type's ID
+}
+
+// PayrollTotal is the quantity of one pay category of an employee over a
+// period: minutes, or days for the absences. RatePercent only applies to
+// overtime, AbsenceType to absences. The totals are frozen when the period
+// is closed; they are not part of the GraphQL schema.
+type PayrollTotal struct {
+	User        *model.User
+	Category    model.PayCategory
+	RatePercent int
+	AbsenceType *model.AbsenceType
+	Quantity    float64
+}
+
+// payLine is the quantity of one pay code of an employee.
+type payLine struct {
+	user     *model.User
+	category model.PayCategory
+	code     string
+	label    string
+	quantity float64 // hours or days
+	unit     string
+}
+
+// ExportPayroll renders the payroll of a closed pay period with a layout, the
+// default one when layoutID is nil; format overrides the layout's.
+func (s *PayrollService) ExportPayroll(periodID string, layoutID *string, format *model.PayrollFormat) (string, error) {
+	period, err := s.Repo.GetPayPeriodByID(periodID)
+	if err != nil {
+		return "", err
+	}
+	if period.Status != model.PayPeriodStatusClosed {
+		return "", errors.New("close the pay period before exporting its payroll")
+	}
+	layout := defaultPayrollLayout()
+	if layoutID != nil && *layoutID != "" {
+		if layout, err = s.Repo.GetPayrollLayout(*layoutID); err != nil {
+			return "", err
+		}
+	}
+	if format != nil {
+		if !format.IsValid() {
+			return "", errors.New("invalid payroll format")
+		}
+		copied := *layout
+		copied.Format = *format
+		layout = &copied
+	}
+	totals, err := s.frozenTotals(period)
+	if err != nil {
+		return "", err
+	}
+	lines, err := s.payLines(totals)
+	if err != nil {
+		return "", err
+	}
+	return renderPayroll(layout, period, lines)
+}
+
+// Freeze computes and stores the totals of a closed pay period, so that its
+// export no longer changes when leaves, holidays or the overtime policy are
+// edited afterwards.
+func (s *PayrollService) Freeze(period *model.PayPeriod) error {
+	_, err := s.freeze(period, time.Now())
+	return err
+}
+
+func (s *PayrollService) freeze(period *model.PayPeriod, now time.Time) ([]*PayrollTotal, error) {
+	totals, err := s.payTotals(period, now)
+	if err != nil {
+		return nil, err
+	}
+	return totals, s.Repo.SavePayrollTotals(period.ID, totals)
+}
+
+// frozenTotals returns the totals frozen at the period's closing; a period
+// whose totals could not be frozen then is frozen at its first export.
+func (s *PayrollService) frozenTotals(period *model.PayPeriod) ([]*PayrollTotal, error) {
+	totals, frozen, err := s.Repo.GetPayrollTotals(period.ID)
+	if err != nil || frozen {
+		return totals, err
+	}
+	return s.freeze(period, time.Now())
+}
+
+// payTotals computes what the period pays each employee, per pay category:
+// minutes of work, or days of absence. Hours follow the KPI rules, so they
+// match the employee's timesheets: a week's overtime is spread over the
+// premium bands day after day, and night, Sunday and holiday hours are
+// counted on the day the hours are worked. A leave crossing the period's edge
+// counts the share of each of its days inside it the employee's planning
+// makes a working day.
+func (s *PayrollService) payTotals(period *model.PayPeriod, now time.Time) ([]*PayrollTotal, error) {
+	start, err := time.Parse(layoutISO, period.StartDate)
+	if err != nil {
+		return nil, err
+	}
+	end, err := time.Parse(layoutISO, period.EndDate)
+	if err != nil {
+		return nil, err
+	}
+	// whole weeks for the overtime, plus the day before for the night shifts
+	from := mondayOf(start).AddDate(0, 0, -1)
+	to := mondayOf(end).AddDate(0, 0, 6)
+	entries, err := s.Repo.GetTimeTableEntriesFiltered(nil, nil, &from, &to)
+	if err != nil {
+		return nil, err
+	}
+	status := model.LeaveStatusApproved
+	leaves, err := s.Repo.GetLeaveRequests(&status, nil, nil, &period.StartDate, &period.EndDate)
+	if err != nil {
+		return nil, err
+	}
+
+	inPeriod := func(day string) bool { return period.StartDate <= day && day <= period.EndDate }
+	users := make(map[string]*model.User)
+	totals := make(map[string]map[payKey]float64) // user -> minutes, or days of absence
+	add := func(userID string, key payKey, quantity float64) {
+		if quantity <= 0 {
+			return
+		}
+		if totals[userID] == nil {
+			totals[userID] = make(map[payKey]float64)
+		}
+		totals[userID][key] += quantity
+	}
+
+	// normal hours and overtime, from the rollups of whole weeks
+	planning := s.Kpi.planning()
+	userIDs, _, _ := entriesSpan(entries)
+	for _, lr := range leaves {
+		if lr.UserID != nil {
+			userIDs = append(userIDs, lr.UserID.ID)
+		}
+	}
+	planning.preload(userIDs, from.Format(layoutISO), to.Format(layoutISO))
+	engine := s.Kpi.overtimeEngine(planning)
+	weeks := make(map[userDay][]*model.DailyRollup) // user and monday -> days
+	for _, r := range s.Kpi.dailyRollups(entries, now) {
+		dt, err := time.Parse(layoutISO, r.Day)
+		if err != nil {
+			continue
+		}
+		key := userDay{userID: r.UserID, day: mondayOf(dt).Format(layoutISO)}
+		weeks[key] = append(weeks[key], r)
+	}
+	for key, days := range weeks {
+		sort.Slice(days, func(i, j int) bool { return days[i].Day < days[j].Day })
+		counted := 0
+		for _, r := range days {
+			overtime := int(r.OvertimeMinutes)
+			if inPeriod(r.Day) {
+				add(key.userID, payKey{category: model.PayCategoryNormal}, float64(r.WorkedMinutes-r.OvertimeMinutes-r.OvertimeExcessMinutes))
+				add(key.userID, payKey{category: model.PayCategoryOvertimeExcess}, float64(r.OvertimeExcessMinutes))
+				for rate, minutes := range engine.bandSlice(counted, overtime) {
+					add(key.userID, payKey{category: model.PayCategoryOvertime, ratePercent: rate}, float64(minutes))
+				}
+			}
+			counted += overtime
+		}
+	}
+
+	// night, Sunday and holiday hours
+	for _, e := range entries {
+		if e.UserID == nil {
+			continue
+		}
+		users[e.UserID.ID] = e.UserID
+		for day, premiums := range s.premiumMinutes(e, planning, now) {
+			if !inPeriod(day) {
+				continue
+			}
+			for category, minutes := range premiums {
+				add(e.UserID.ID, payKey{category: category}, float64(minutes))
+			}
+		}
+	}
+
+	// days of absence per type
+	absenceTypes := make(map[string]*model.AbsenceType)
+	for _, lr := range leaves {
+		if lr.UserID == nil || lr.AbsenceType == nil {
+			continue
+		}
+		if users[lr.UserID.ID] == nil {
+			users[lr.UserID.ID] = lr.UserID
+		}
+		absenceTypes[lr.AbsenceType.ID] = lr.AbsenceType
+		days := leaveDaysWithin(lr, period.StartDate, period.EndDate, leaveConsumes(planning.planner, lr.UserID.ID))
+		add(lr.UserID.ID, payKey{category: model.PayCategoryAbsence, absenceType: lr.AbsenceType.ID}, days)
+	}
+
+	out := make([]*PayrollTotal, 0)
+	for userID, quantities := range totals {
+		for key, quantity := range quantities {
+			out = append(out, &PayrollTotal{
+				User:        payrollUser(users, userID),
+				Category:    key.category,
+				RatePercent: key.ratePercent,
+				AbsenceType: absenceTypes[key.absenceType],
+				Quantity:    quantity,
+			})
+		}
+	}
+	return out, nil
+}
+
+// payLines maps the totals to the pay codes, per employee in name order.
+func (s *PayrollService) payLines(totals []*PayrollTotal) ([]*payLine, error) {
+	users := make(map[string]*model.User)
+	absenceTypes := make(map[string]*model.AbsenceType)
+	byUser := make(map[string]map[payKey]float64)
+	for _, t := range totals {
+		key := payKey{category: t.Category, ratePercent: t.RatePercent}
+		if t.AbsenceType != nil {
+			key.absenceType = t.AbsenceType.ID
+			absenceTypes[t.AbsenceType.ID] = t.AbsenceType
+		}
+		users[t.User.ID] = t.User
+		if byUser[t.User.ID] == nil {
+			byUser[t.User.ID] = make(map[payKey]float64)
+		}
+		byUser[t.User.ID][key] += t.Quantity
+	}
+
+	codes, err := s.Repo.GetPayCodes()
+	if err != nil {
+		return nil, err
+	}
+	mapped := make(map[payKey]*model.PayCode, len(codes))
+	for _, c := range codes {
+		key := payKey{category: c.Category}
+		if c.RatePercent != nil {
+			key.ratePercent = int(*c.RatePercent)
+		}
+		if c.AbsenceType != nil {
+			key.absenceType = c.AbsenceType.ID
+		}
+		mapped[key] = c
+	}
+
+	userIDs := make([]string, 0, len(byUser))
+	for userID := range byUser {
+		userIDs = append(userIDs, userID)
+	}
+	sort.Slice(userIDs, func(i, j int) bool {
+		a, b := payrollUser(users, userIDs[i]), payrollUser(users, userIDs[j])
+		if a.LastName != b.LastName {
+			return a.LastName < b.LastName
+		}
+		if a.FirstName != b.FirstName {
+			return a.FirstName < b.FirstName
+		}
+		return a.ID < b.ID
+	})
+
+	lines := make([]*payLine, 0)
+	for _, userID := range userIDs {
+		keys := make([]payKey, 0, len(byUser[userID]))
+		for key := range byUser[userID] {
+			keys = append(keys, key)
+		}
+		sort.Slice(keys, func(i, j int) bool { return payKeyLess(keys[i], keys[j]) })
+		// categories mapped to the same code share its line
+		byCode := make(map[string]*payLine)
+		for _, key := range keys {
+			code, label := defaultPayCode(key, absenceTypes[key.absenceType])
+			if c := mapped[key]; c != nil {
+				code = c.Code
+				if c.Label != nil && *c.Label != "" {
+					label = *c.Label
+				}
+			}
+			unit := payUnit(key.category)
+			quantity := byUser[userID][key]
+			if unit == payUnitHours {
+				quantity /= 60
+			}
+			line := byCode[code+"\x00"+unit]
+			if line == nil {
+				line = &payLine{user: payrollUser(users, userID), category: key.category, code: code, label: label, unit: unit}
+				byCode[code+"\x00"+unit] = line
+				lines = append(lines, line)
+			}
+			line.quantity += quantity
+		}
+	}
+	return lines, nil
+}
+
+// payrollUser returns the user met in the entries or leaves, or a user
+// holding only the ID.
+func payrollUser(users map[string]*model.User, userID string) *model.User {
+	if u := users[userID]; u != nil {
+		return u
+	}
+	return &model.User{ID: userID}
+}
+
+// payKeyLess orders the lines of an employee: categories in their schema
+// order, then rates and absence types.
+func payKeyLess(a, b payKey) bool {
+	if a.category != b.category {
+		return categoryIndex(a.category) < categoryIndex(b.category)
+	}
+	if a.ratePercent != b.ratePercent {
+		return a.ratePercent < b.ratePercent
+	}
+	return a.absenceType < b.absenceType
+}
+
+func categoryIndex(category model.PayCategory) int {
+	for i, c := range model.AllPayCategory {
+		if c == category {
+			return i
+		}
+	}
+	return len(model.AllPayCategory)
+}
+
+// payUnit is the unit of a category's quantities.
+func payUnit(category model.PayCategory) string {
+	if category == model.PayCategoryAbsence {
+		return payUnitDays
+	}
+	return payUnitHours
+}
+
+// defaultPayCode names the categories no pay code was set for.
+func defaultPayCode(key payKey, absenceType *model.AbsenceType) (code string, label string) {
+	switch key.category {
+	case model.PayCategoryNormal:
+		return "NORMAL", "Normal hours"
+	case model.PayCategoryOvertime:
+		if key.ratePercent == 0 {
+			return "OVERTIME", "Overtime"
+		}
+		return fmt.Sprintf("OVERTIME_%d", key.ratePercent), fmt.Sprintf("Overtime +%d%%", key.ratePercent)
+	case model.PayCategoryOvertimeExcess:
+		return "OVERTIME_EXCESS", "Overtime beyond the caps"
+	case model.PayCategoryNight:
+		return "NIGHT", "Night hours"
+	case model.PayCategorySunday:
+		return "SUNDAY", "Sunday hours"
+	case model.PayCategoryHoliday:
+		return "HOLIDAY", "Holiday hours"
+	}
+	if absenceType == nil {
+		return "ABSENCE", "Absence"
+	}
+	code = strings.Map(func(r rune) rune {
+		if r < unicode.MaxASCII && (unicode.IsLetter(r) || unicode.IsDigit(r)) {
+			return unicode.ToUpper(r)
+		}
+		return '_'
+	}, absenceType.Name)
+	return "ABSENCE_" + code, absenceType.Name
+}
+
+// bandSlice spreads minutes of overtime over the premium bands, the week
+// having already counted done minutes of overtime before them. The minutes
+// beyond the last band, or without bands, have rate 0.
+func (e overtimeEngine) bandSlice(done, minutes int) map[int]int {
+	out := make(map[int]int)
+	if minutes <= 0 {
+		return out
+	}
+	bands := make([]int, len(e.policy.Bands))
+	e.spreadBands(bands, done, done+minutes)
+	for i, m := range bands {
+		if m > 0 {
+			out[int(e.policy.Bands[i].RatePercent)] += m
+			minutes -= m
+		}
+	}
+	if minutes > 0 {
+		out[0] += minutes
+	}
+	return out
+}
+
+// premiumMinutes returns the night, Sunday and holiday minutes of a session
+// per day they are paid on: the day of the entry, or each calendar day with
+// the midnight split. A holiday falling on a Sunday counts as a holiday.
+func (s *PayrollService) premiumMinutes(e *model.TimeTableEntry, planning kpiPlanning, now time.Time) map[string]map[model.PayCategory]int {
+	out := make(map[string]map[model.PayCategory]int)
+	end := sessionEnd(e, now)
+	if (e.Departure == nil || e.Departure.IsZero()) && !e.Status {
+		// flagged for review: no departure, nothing worked yet
+		return out
+	}
+	startDay, err := time.Parse(layoutISO, e.Day)
+	if err != nil {
+		return out
+	}
+	arrival := localArrival(e)
+	loc := arrival.Location()
+	dayStart := time.Date(arrival.Year(), arrival.Month(), arrival.Day(), 0, 0, 0, 0, loc)
+	for i := 0; dayStart.Before(end); i, dayStart = i+1, dayStart.AddDate(0, 0, 1) {
+		dayEnd := dayStart.AddDate(0, 0, 1)
+		calendarDay := dayStart.Format(layoutISO)
+		// the days of the session follow its day, as in minutesByDay
+		payDay := e.Day
+		if s.Kpi.MidnightRule == MidnightSplit {
+			payDay = startDay.AddDate(0, 0, i).Format(layoutISO)
+		}
+		add := func(category model.PayCategory, minutes int) {
+			if minutes <= 0 {
+				return
+			}
+			if out[payDay] == nil {
+				out[payDay] = make(map[model.PayCategory]int)
+			}
+			out[payDay][category] += minutes
+		}
+
+		worked := workedWithin(e, now, dayStart, dayEnd)
+		if worked == 0 {
+			continue
+		}
+		if planning.holiday(e.UserID.ID, calendarDay) {
+			add(model.PayCategoryHoliday, worked)
+		} else if dayStart.Weekday() == time.Sunday {
+			add(model.PayCategorySunday, worked)
+		}
+
+		nightStart, nightEnd := atClock(dayStart, s.NightStart), atClock(dayStart, s.NightEnd)
+		switch {
+		case s.NightEnd < s.NightStart:
+			add(model.PayCategoryNight, workedWithin(e, now, dayStart, nightEnd)+workedWithin(e, now, nightStart, dayEnd))
+		case s.NightStart < s.NightEnd:
+			add(model.PayCategoryNight, workedWithin(e, now, nightStart, nightEnd))
+		}
+	}
+	return out
+}
+
+// atClock returns the time of day d of the day starting at dayStart.
+func atClock(dayStart time.Time, d time.Duration) time.Time {
+	return time.Date(dayStart.Year(), dayStart.Month(), dayStart.Day(), int(d/time.Hour), int(d%time.Hour/time.Minute), 0, 0, dayStart.Location())
+}
+
+// workedWithin returns the minutes of a session worked within [from, to),
+// unpaid breaks excluded.
+func workedWithin(e *model.TimeTableEntry, now, from, to time.Time) int {
+	start, end := e.Arrival, sessionEnd(e, now)
+	if start.Before(from) {
+		start = from
+	}
+	if end.After(to) {
+		end = to
+	}
+	if !end.After(start) {
+		return 0
+	}
+	return max(0, int((end.Sub(start) - unpaidBreakOverlap(e, now, from, to)).Minutes()))
+}
+
+// renderPayroll writes the lines with the layout's columns, as CSV or as
+// fixed-width text.
+func renderPayroll(layout *model.PayrollLayout, period *model.PayPeriod, lines []*payLine) (string, error) {
+	header := make([]string, len(layout.Columns))
+	for i, c := range layout.Columns {
+		header[i] = string(c.Field)
+		if c.Header != nil && *c.Header != "" {
+			header[i] = *c.Header
+		}
+	}
+	rows := make([][]string, 0, len(lines))
+	for _, line := range lines {
+		row := make([]string, len(layout.Columns))
+		for i, c := range layout.Columns {
+			row[i] = payrollValue(c.Field, line, period)
+		}
+		rows = append(rows, row)
+	}
+
+	var buf bytes.Buffer
+	if layout.Format == model.PayrollFormatFixedWidth {
+		if layout.Header {
+			text, err := fixedWidthRow(layout.Columns, header, true)
+			if err != nil {
+				return "", err
+			}
+			buf.WriteString(text + "\n")
+		}
+		for _, row := range rows {
+			text, err := fixedWidthRow(layout.Columns, row, false)
+			if err != nil {
+				return "", err
+			}
+			buf.WriteString(text + "\n")
+		}
+		return buf.String(), nil
+	}
+
+	writer := csv.NewWriter(&buf)
+	if layout.Delimiter != "" {
+		delimiter, err := csvDelimiter(layout.Delimiter)
+		if err != nil {
+			return "", err
+		}
+		writer.Comma = delimiter
+	}
+	if layout.Header {
+		if err := writer.Write(header); err != nil {
+			return "", err
+		}
+	}
+	if err := writer.WriteAll(rows); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+// payrollValue renders one field of a line.
+func payrollValue(field model.PayrollField, line *payLine, period *model.PayPeriod) string {
+	switch field {
+	case model.PayrollFieldEmployeeID:
+		return line.user.ID
+	case model.PayrollFieldEmail:
+		return line.user.Email
+	case model.PayrollFieldLastName:
+		return line.user.LastName
+	case model.PayrollFieldFirstName:
+		return line.user.FirstName
+	case model.PayrollFieldPeriodStart:
+		return period.StartDate
+	case model.PayrollFieldPeriodEnd:
+		return period.EndDate
+	case model.PayrollFieldPayCode:
+		return line.code
+	case model.PayrollFieldLabel:
+		return line.label
+	case model.PayrollFieldCategory:
+		return string(line.category)
+	case model.PayrollFieldQuantity:
+		return strconv.FormatFloat(line.quantity, 'f', 2, 64)
+	case model.PayrollFieldUnit:
+		return line.unit
+	}
+	return ""
+}
+
+// fixedWidthRow pads each value to its column's width, quantities to the
+// right by default. Names, e-mails and labels too long are cut; any other
+// value too long is an error, as cutting it would corrupt the payroll.
+func fixedWidthRow(columns []*model.PayrollColumn, values []string, header bool) (string, error) {
+	var b strings.Builder
+	for i, c := range columns {
+		width := defaultColumnWidths[c.Field]
+		if c.Width != nil {
+			width = int(*c.Width)
+		}
+		value := values[i]
+		if n := utf8.RuneCountInString(value); n > width {
+			switch {
+			case header, c.Field == model.PayrollFieldLastName, c.Field == model.PayrollFieldFirstName,
+				c.Field == model.PayrollFieldEmail, c.Field == model.PayrollFieldLabel:
+				value = string([]rune(value)[:width])
+			default:
+				return "", fmt.Errorf("%s %q does not fit in %d characters", c.Field, value, width)
+			}
+		}
+		padding := strings.Repeat(" ", width-utf8.RuneCountInString(value))
+		alignRight := c.Field == model.PayrollFieldQuantity
+		if c.Align != nil {
+			alignRight = *c.Align == model.PayrollAlignRight
+		}
+		if alignRight {
+			b.WriteString(padding + value)
+		} else {
+			b.WriteString(value + padding)
+		}
+	}
+	return b.String(), nil
+}
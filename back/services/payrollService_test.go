@@ -0,0 +1,316 @@
+package services
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/epitech/timemanager/internal/graph/model"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+type mockPayrollRepo struct {
+	periods []*model.PayPeriod
+	entries []*model.TimeTableEntry
+	leaves  []*model.LeaveRequest
+	codes   []*model.PayCode
+	layouts []*model.PayrollLayout
+	frozen  map[string][]*PayrollTotal // by period
+}
+
+func (m *mockPayrollRepo) GetPayPeriodByID(id string) (*model.PayPeriod, error) {
+	for _, p := range m.periods {
+		if p.ID == id {
+			return p, nil
+		}
+	}
+	return nil, assert.AnError
+}
+
+func (m *mockPayrollRepo) GetTimeTableEntriesFiltered(userID *uuid.UUID, teamID *uuid.UUID, from, to *time.Time) ([]*model.TimeTableEntry, error) {
+	return m.entries, nil
+}
+
+func (m *mockPayrollRepo) GetLeaveRequests(status *model.LeaveStatus, userID *uuid.UUID, managerID *uuid.UUID, from, to *string) ([]*model.LeaveRequest, error) {
+	return m.leaves, nil
+}
+
+func (m *mockPayrollRepo) GetPayCodes() ([]*model.PayCode, error) { return m.codes, nil }
+
+func (m *mockPayrollRepo) SavePayCode(input model.PayCodeInput) (*model.PayCode, error) {
+	code := &model.PayCode{ID: uuid.New().String(), Category: input.Category, RatePercent: input.RatePercent, Code: input.Code, Label: input.Label}
+	if input.AbsenceTypeID != nil {
+		code.AbsenceType = &model.AbsenceType{ID: *input.AbsenceTypeID}
+	}
+	m.codes = append(m.codes, code)
+	return code, nil
+}
+
+func (m *mockPayrollRepo) DeletePayCode(id string) (bool, error) { return true, nil }
+
+func (m *mockPayrollRepo) GetPayrollLayouts() ([]*model.PayrollLayout, error) { return m.layouts, nil }
+
+func (m *mockPayrollRepo) GetPayrollLayout(id string) (*model.PayrollLayout, error) {
+	for _, l := range m.layouts {
+		if l.ID == id {
+			return l, nil
+		}
+	}
+	return nil, assert.AnError
+}
+
+func (m *mockPayrollRepo) CreatePayrollLayout(input model.PayrollLayoutInput) (*model.PayrollLayout, error) {
+	layout := &model.PayrollLayout{ID: uuid.New().String(), Name: input.Name, Format: input.Format, Header: input.Header == nil || *input.Header}
+	if input.Delimiter != nil {
+		layout.Delimiter = *input.Delimiter
+	}
+	for _, c := range input.Columns {
+		layout.Columns = append(layout.Columns, &model.PayrollColumn{Field: c.Field, Header: c.Header, Width: c.Width, Align: c.Align})
+	}
+	m.layouts = append(m.layouts, layout)
+	return layout, nil
+}
+
+func (m *mockPayrollRepo) UpdatePayrollLayout(id string, input model.PayrollLayoutInput) (*model.PayrollLayout, error) {
+	return m.CreatePayrollLayout(input)
+}
+
+func (m *mockPayrollRepo) DeletePayrollLayout(id string) (bool, error) { return true, nil }
+
+func (m *mockPayrollRepo) GetPayrollTotals(periodID string) ([]*PayrollTotal, bool, error) {
+	totals, ok := m.frozen[periodID]
+	return totals, ok, nil
+}
+
+func (m *mockPayrollRepo) SavePayrollTotals(periodID string, totals []*PayrollTotal) error {
+	m.frozen[periodID] = totals
+	return nil
+}
+
+// payrollFixture is a closed January: Alice works 10h on Monday the 8th, 8h on
+// Tuesday and 20:00-23:00 on Sunday the 14th, Bob takes two days of paid leave.
+func payrollFixture() (*PayrollService, *mockPayrollRepo) {
+	alice := &model.User{ID: "u-alice", FirstName: "Alice", LastName: "Martin", Email: "alice@example.com"}
+	bob := &model.User{ID: "u-bob", FirstName: "Bob", LastName: "Durand", Email: "bob@example.com"}
+	repo := &mockPayrollRepo{
+		frozen: map[string][]*PayrollTotal{},
+		periods: []*model.PayPeriod{
+			{ID: "jan", StartDate: "2024-01-01", EndDate: "2024-01-31", Status: model.PayPeriodStatusClosed},
+			{ID: "feb", StartDate: "2024-02-01", EndDate: "2024-02-29", Status: model.PayPeriodStatusReopened},
+		},
+		entries: []*model.TimeTableEntry{
+			workSession(alice, "2024-01-08", 9, 19),
+			workSession(alice, "2024-01-09", 9, 17),
+			workSession(alice, "2024-01-14", 20, 23),
+		},
+		leaves: []*model.LeaveRequest{
+			{ID: "l1", UserID: bob, AbsenceType: &model.AbsenceType{ID: "paid", Name: "Paid leave"}, StartDate: "2024-01-11", EndDate: "2024-01-12", Days: 2, Status: model.LeaveStatusApproved},
+		},
+	}
+	kpi := NewKpiService(&mockKpiRepo{})
+	kpi.Overtime = NewOvertimeService(&mockOvertimeRepo{policy: &model.OvertimePolicy{
+		DailyEnabled:          true,
+		DailyThresholdMinutes: int32Ptr(7 * 60),
+		DailyCapMinutes:       int32Ptr(150),
+		Bands: []*model.OvertimeBand{
+			{UpToMinutes: int32Ptr(60), RatePercent: 25},
+			{RatePercent: 50},
+		},
+	}})
+	return NewPayrollService(repo, kpi), repo
+}
+
+func TestExportPayrollCSV(t *testing.T) {
+	svc, _ := payrollFixture()
+	_, err := svc.SetPayCode(model.PayCodeInput{Category: model.PayCategoryNormal, Code: " H100 ", Label: ptrString("Base hours")})
+	assert.NoError(t, err)
+	_, err = svc.SetPayCode(model.PayCodeInput{Category: model.PayCategoryOvertime, RatePercent: int32Ptr(25), Code: "HS25"})
+	assert.NoError(t, err)
+	// overtime at 50% and overtime beyond the caps share one code
+	_, err = svc.SetPayCode(model.PayCodeInput{Category: model.PayCategoryOvertime, RatePercent: int32Ptr(50), Code: "HS50", Label: ptrString("Overtime 50%")})
+	assert.NoError(t, err)
+	_, err = svc.SetPayCode(model.PayCodeInput{Category: model.PayCategoryOvertimeExcess, Code: "HS50"})
+	assert.NoError(t, err)
+
+	out, err := svc.ExportPayroll("jan", nil, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, strings.Join([]string{
+		"EMPLOYEE_ID,LAST_NAME,FIRST_NAME,PERIOD_START,PERIOD_END,PAY_CODE,LABEL,QUANTITY,UNIT",
+		"u-bob,Durand,Bob,2024-01-01,2024-01-31,ABSENCE_PAID_LEAVE,Paid leave,2.00,DAYS",
+		"u-alice,Martin,Alice,2024-01-01,2024-01-31,H100,Base hours,17.00,HOURS",
+		"u-alice,Martin,Alice,2024-01-01,2024-01-31,HS25,Overtime +25%,1.00,HOURS",
+		"u-alice,Martin,Alice,2024-01-01,2024-01-31,HS50,Overtime 50%,3.00,HOURS",
+		"u-alice,Martin,Alice,2024-01-01,2024-01-31,NIGHT,Night hours,2.00,HOURS",
+		"u-alice,Martin,Alice,2024-01-01,2024-01-31,SUNDAY,Sunday hours,3.00,HOURS",
+		"",
+	}, "\n"), out)
+
+	_, err = svc.ExportPayroll("feb", nil, nil)
+	assert.Error(t, err, "a reopened period can't be exported")
+	_, err = svc.ExportPayroll("missing", nil, nil)
+	assert.Error(t, err)
+}
+
+func TestExportPayrollFixedWidth(t *testing.T) {
+	svc, _ := payrollFixture()
+	layout, err := svc.CreatePayrollLayout(model.PayrollLayoutInput{
+		Name:   " Sage ",
+		Format: model.PayrollFormatFixedWidth,
+		Header: boolPtr(false),
+		Columns: []*model.PayrollColumnInput{
+			{Field: model.PayrollFieldLastName, Width: int32Ptr(4)},
+			{Field: model.PayrollFieldPayCode, Width: int32Ptr(20)},
+			{Field: model.PayrollFieldQuantity, Width: int32Ptr(8)},
+			{Field: model.PayrollFieldUnit, Width: int32Ptr(6), Align: payrollAlignPtr(model.PayrollAlignRight)},
+		},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "Sage", layout.Name)
+
+	_, err = svc.ExportPayroll(layout.ID, nil, nil)
+	assert.Error(t, err, "a layout isn't a pay period")
+	out, err := svc.ExportPayroll("jan", &layout.ID, nil)
+	assert.NoError(t, err)
+	lines := strings.Split(strings.TrimSuffix(out, "\n"), "\n")
+	assert.Len(t, lines, 7)
+	assert.Equal(t, "DuraABSENCE_PAID_LEAVE      2.00  DAYS", lines[0], "names are cut to the width")
+	assert.Equal(t, "MartNORMAL                 17.00 HOURS", lines[1])
+	assert.Equal(t, "MartOVERTIME_25             1.00 HOURS", lines[2])
+
+	// a pay code that doesn't fit is an error rather than a corrupted line
+	svc.Repo.(*mockPayrollRepo).layouts[0].Columns[1].Width = int32Ptr(10)
+	_, err = svc.ExportPayroll("jan", &layout.ID, nil)
+	assert.Error(t, err)
+
+	// the default layout exported as fixed-width text takes default widths
+	fixed := model.PayrollFormatFixedWidth
+	out, err = svc.ExportPayroll("jan", nil, &fixed)
+	assert.NoError(t, err)
+	lines = strings.Split(strings.TrimSuffix(out, "\n"), "\n")
+	assert.Equal(t, "EMPLOYEE_ID", strings.TrimSpace(lines[0][:36]))
+	for _, l := range lines {
+		assert.Len(t, l, 181)
+	}
+}
+
+func TestPayrollAbsenceFollowsThePlanning(t *testing.T) {
+	svc, repo := payrollFixture()
+	svc.Kpi.Schedules = NewScheduleService(&mockScheduleRepo{
+		timeTable: &model.TimeTable{ID: "tt", Start: time.Date(0, 1, 1, 9, 0, 0, 0, time.UTC), Ends: time.Date(0, 1, 1, 17, 0, 0, 0, time.UTC)},
+		timeZone:  "UTC",
+		holidays:  []*model.Holiday{{Date: "2024-01-31", Name: "Closing day"}},
+	})
+	// half-days from Tuesday the 30th to Friday, February 2nd: only Tuesday
+	// counts in January, the 31st being a holiday
+	repo.leaves[0].StartDate, repo.leaves[0].EndDate = "2024-01-30", "2024-02-02"
+	repo.leaves[0].HalfDay, repo.leaves[0].Days = true, 1.5
+	bob := uuid.NewString()
+	repo.leaves[0].UserID = &model.User{ID: bob, FirstName: "Bob", LastName: "Durand"}
+
+	out, err := svc.ExportPayroll("jan", nil, nil)
+	assert.NoError(t, err)
+	assert.Contains(t, out, bob+",Durand,Bob,2024-01-01,2024-01-31,ABSENCE_PAID_LEAVE,Paid leave,0.50,DAYS\n")
+}
+
+func TestPayrollFrozenAtClose(t *testing.T) {
+	svc, repo := payrollFixture()
+	periods := NewPayPeriodService(&mockPayPeriodRepo{})
+	periods.Payroll = svc
+	closed, err := periods.Close(uuid.New(), "2024-01-01", "2024-01-31")
+	assert.NoError(t, err)
+	assert.NotEmpty(t, repo.frozen[closed.ID], "the payroll is frozen with the period")
+
+	first, err := svc.ExportPayroll("jan", nil, nil)
+	assert.NoError(t, err)
+	// inputs edited after the closing don't change the export
+	repo.leaves[0].EndDate, repo.leaves[0].Days = "2024-01-15", 3
+	svc.Kpi.Overtime = nil
+	again, err := svc.ExportPayroll("jan", nil, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, first, again)
+}
+
+func TestPayrollNightWindowAndSemicolons(t *testing.T) {
+	start, end, err := ParseNightWindow("", "")
+	assert.NoError(t, err)
+	assert.Equal(t, 21*time.Hour, start)
+	assert.Equal(t, 6*time.Hour, end)
+	_, _, err = ParseNightWindow("22h", "")
+	assert.Error(t, err)
+
+	svc, _ := payrollFixture()
+	// night from 22:00 to 07:00 only catches one hour of the Sunday shift
+	svc.NightStart, svc.NightEnd, err = ParseNightWindow("22:00", "07:00")
+	assert.NoError(t, err)
+	layout, err := svc.CreatePayrollLayout(model.PayrollLayoutInput{
+		Name:      "semicolons",
+		Format:    model.PayrollFormatCSV,
+		Delimiter: ptrString(";"),
+		Columns: []*model.PayrollColumnInput{
+			{Field: model.PayrollFieldEmail, Header: ptrString("Mail")},
+			{Field: model.PayrollFieldCategory},
+			{Field: model.PayrollFieldQuantity},
+		},
+	})
+	assert.NoError(t, err)
+	out, err := svc.ExportPayroll("jan", &layout.ID, nil)
+	assert.NoError(t, err)
+	assert.Contains(t, out, "Mail;CATEGORY;QUANTITY\n")
+	assert.Contains(t, out, "alice@example.com;NIGHT;1.00\n")
+}
+
+func TestPayrollValidation(t *testing.T) {
+	svc, _ := payrollFixture()
+	absence := "paid"
+
+	_, err := svc.SetPayCode(model.PayCodeInput{Category: model.PayCategoryNormal, Code: "  "})
+	assert.Error(t, err, "code required")
+	_, err = svc.SetPayCode(model.PayCodeInput{Category: "BONUS", Code: "B"})
+	assert.Error(t, err)
+	_, err = svc.SetPayCode(model.PayCodeInput{Category: model.PayCategoryOvertime, Code: "HS"})
+	assert.Error(t, err, "overtime needs its rate")
+	_, err = svc.SetPayCode(model.PayCodeInput{Category: model.PayCategoryNight, RatePercent: int32Ptr(25), Code: "N"})
+	assert.Error(t, err, "only overtime has a rate")
+	_, err = svc.SetPayCode(model.PayCodeInput{Category: model.PayCategoryAbsence, Code: "CP"})
+	assert.Error(t, err, "absences need their type")
+	_, err = svc.SetPayCode(model.PayCodeInput{Category: model.PayCategoryNormal, AbsenceTypeID: &absence, Code: "H"})
+	assert.Error(t, err)
+	_, err = svc.SetPayCode(model.PayCodeInput{Category: model.PayCategoryAbsence, AbsenceTypeID: &absence, Code: "CP"})
+	assert.NoError(t, err)
+	_, err = svc.SetPayCode(model.PayCodeInput{Category: model.PayCategoryNight, Code: "CP"})
+	assert.Error(t, err, "CP already holds days")
+
+	columns := []*model.PayrollColumnInput{{Field: model.PayrollFieldPayCode}}
+	_, err = svc.CreatePayrollLayout(model.PayrollLayoutInput{Format: model.PayrollFormatCSV, Columns: columns})
+	assert.Error(t, err, "name required")
+	_, err = svc.CreatePayrollLayout(model.PayrollLayoutInput{Name: "l", Format: model.PayrollFormatCSV})
+	assert.Error(t, err, "no column")
+	_, err = svc.CreatePayrollLayout(model.PayrollLayoutInput{Name: "l", Format: model.PayrollFormatCSV, Delimiter: ptrString(`"`), Columns: columns})
+	assert.Error(t, err)
+	_, err = svc.CreatePayrollLayout(model.PayrollLayoutInput{Name: "l", Format: model.PayrollFormatCSV, Delimiter: ptrString(";;"), Columns: columns})
+	assert.Error(t, err)
+	_, err = svc.CreatePayrollLayout(model.PayrollLayoutInput{Name: "l", Format: model.PayrollFormatFixedWidth, Columns: columns})
+	assert.Error(t, err, "fixed width needs widths")
+	_, err = svc.CreatePayrollLayout(model.PayrollLayoutInput{Name: "l", Format: model.PayrollFormatCSV, Columns: []*model.PayrollColumnInput{
+		{Field: model.PayrollFieldPayCode, Width: int32Ptr(-1)},
+	}})
+	assert.Error(t, err)
+	_, err = svc.CreatePayrollLayout(model.PayrollLayoutInput{Name: "l", Format: model.PayrollFormatCSV, Columns: []*model.PayrollColumnInput{
+		{Field: "SALARY"},
+	}})
+	assert.Error(t, err)
+
+	layout, err := svc.CreatePayrollLayout(model.PayrollLayoutInput{Name: "l", Format: model.PayrollFormatFixedWidth, Delimiter: ptrString(";"), Columns: []*model.PayrollColumnInput{
+		{Field: model.PayrollFieldPayCode, Width: int32Ptr(20)},
+	}})
+	assert.NoError(t, err)
+	assert.Equal(t, "", layout.Delimiter, "fixed-width text has no delimiter")
+	layout, err = svc.CreatePayrollLayout(model.PayrollLayoutInput{Name: "c", Format: model.PayrollFormatCSV, Columns: columns})
+	assert.NoError(t, err)
+	assert.Equal(t, ",", layout.Delimiter)
+}
+
+func boolPtr(v bool) *bool { return &v }
+
+func payrollAlignPtr(v model.PayrollAlign) *model.PayrollAlign { return &v }
@@ -0,0 +1,171 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/epitech/timemanager/internal/graph/model"
+	"github.com/google/uuid"
+)
+
+// HourRange is a range of hours of the day, From inclusive to To exclusive.
+type HourRange struct {
+	From, To int
+}
+
+// GetTeamPresenceHeatmap averages the team's concurrent presence per weekday
+// and hour over the window, in the window's zone. Every hour of the window
+// counts, the empty ones too, from the first hour of the first day up to the
+// end of the last day or now if sooner.
+//
+// Only the opening hours can be understaffed: the hours of open on the days a
+// member is due at work, else the hours a member is planned in, per their
+// schedule or from the default arrival for a default day.
+func (s *KpiService) GetTeamPresenceHeatmap(ctx context.Context, teamID uuid.UUID, from, to time.Time, minHeadcount int, open *HourRange) (*model.PresenceHeatmap, error) {
+	if minHeadcount < 0 {
+		return nil, errors.New("minHeadcount must not be negative")
+	}
+	if open != nil && (open.From < 0 || open.To > 24 || open.From >= open.To) {
+		return nil, errors.New("the opening hours must be a range within 0 and 24")
+	}
+	start, end := normalizeWindow(from, to)
+	loc := start.Location()
+	first := time.Date(start.Year(), start.Month(), start.Day(), 0, 0, 0, 0, loc)
+	last := time.Date(end.Year(), end.Month(), end.Day()+1, 0, 0, 0, 0, loc)
+	now := time.Now()
+	if now.Before(last) {
+		last = now
+	}
+
+	entries, err := s.Repo.GetTimeTableEntriesFiltered(nil, &teamID, &start, &end)
+	if err != nil {
+		return nil, err
+	}
+	// hours each member was present in, bucketed like the coverage, so that
+	// a member back from a break within the hour counts once
+	presence := make(map[string]map[string]int)
+	for _, e := range entries {
+		effectiveDep := effectiveDeparture(e.Departure, e.Status, now)
+		if e.UserID == nil || effectiveDep == nil || effectiveDep.Before(e.Arrival) {
+			continue
+		}
+		if presence[e.UserID.ID] == nil {
+			presence[e.UserID.ID] = make(map[string]int)
+		}
+		addCoverageBuckets(presence[e.UserID.ID], e.Arrival, *effectiveDep, first, last)
+	}
+	coverageCounts := map[string]int{}
+	for _, hours := range presence {
+		for key := range hours {
+			coverageCounts[key]++
+		}
+	}
+	members, err := s.teamMembers(teamID.String())
+	if err != nil {
+		return nil, err
+	}
+	opening := s.openingHours(members, first, last, open)
+
+	type cell struct{ hours, present, max, open, under int }
+	var grid [7][24]cell
+	hours, under := 0, 0
+	for cur := first; cur.Before(last); cur = cur.Add(time.Hour) {
+		key := cur.Format(time.RFC3339)
+		present := coverageCounts[key]
+		c := &grid[(int(cur.Weekday())+6)%7][cur.Hour()]
+		c.hours++
+		c.present += present
+		c.max = max(c.max, present)
+		if _, ok := opening[key]; !ok {
+			continue
+		}
+		c.open++
+		hours++
+		if present < minHeadcount {
+			c.under++
+			under++
+		}
+	}
+
+	cells := make([]*model.PresenceCell, 0, 7*24)
+	for d, weekday := range model.AllWeekday {
+		for h := 0; h < 24; h++ {
+			c := grid[d][h]
+			out := &model.PresenceCell{Weekday: weekday, Hour: int32(h), MaxPresent: int32(c.max)}
+			if c.hours > 0 {
+				out.AvgPresent = float64(c.present) / float64(c.hours)
+			}
+			if c.open > 0 {
+				out.UnderstaffedRate = float64(c.under) / float64(c.open)
+			}
+			cells = append(cells, out)
+		}
+	}
+	understaffedRate := 0.0
+	if hours > 0 {
+		understaffedRate = float64(under) / float64(hours)
+	}
+	return &model.PresenceHeatmap{
+		From:             start.Format(layoutISO),
+		To:               end.Format(layoutISO),
+		TeamID:           teamID.String(),
+		MinHeadcount:     int32(minHeadcount),
+		UnderstaffedRate: understaffedRate,
+		Cells:            cells,
+	}, nil
+}
+
+// teamMembers returns the ids of the team's users.
+func (s *KpiService) teamMembers(teamID string) ([]string, error) {
+	staff, err := s.Repo.GetUsersWithTeams()
+	if err != nil {
+		return nil, err
+	}
+	members := make([]string, 0)
+	for _, u := range staff {
+		if u != nil && inTeam(u, teamID) {
+			members = append(members, u.ID)
+		}
+	}
+	return members, nil
+}
+
+// openingHours returns the hours from first to last, bucketed like the
+// coverage, the team is open in: the hours of open on the days a member is due
+// at work, else the hours any member is planned in. A member without a
+// schedule is planned for a default day from the default arrival.
+func (s *KpiService) openingHours(members []string, first, last time.Time, open *HourRange) map[string]struct{} {
+	loc := first.Location()
+	planning := s.withStarts(s.planning())
+	planning.preload(members, first.Format(layoutISO), last.Format(layoutISO))
+	hours := make(map[string]struct{})
+	mark := func(from, to time.Time) {
+		for cur := localHour(from, loc); cur.Before(to) && cur.Before(last); cur = cur.Add(time.Hour) {
+			if !cur.Before(first) {
+				hours[cur.Format(time.RFC3339)] = struct{}{}
+			}
+		}
+	}
+	for day := first; day.Before(last); day = day.AddDate(0, 0, 1) {
+		dayStr := day.Format(layoutISO)
+		for _, userID := range members {
+			if !planning.expectedWorkDay(userID, dayStr) {
+				continue
+			}
+			if open != nil {
+				mark(time.Date(day.Year(), day.Month(), day.Day(), open.From, 0, 0, 0, loc),
+					time.Date(day.Year(), day.Month(), day.Day(), open.To, 0, 0, 0, loc))
+				break
+			}
+			switch planned := planning.planned(userID, dayStr); {
+			case planned != nil && planned.Start != nil && planned.End != nil:
+				mark(*planned.Start, *planned.End)
+			default:
+				start := day.Add(planning.policy.DefaultArrival)
+				mark(start, start.Add(defaultExpectedDailyMinutes*time.Minute))
+			}
+		}
+	}
+	return hours
+}
@@ -0,0 +1,95 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/epitech/timemanager/internal/graph/model"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTeamPresenceHeatmap(t *testing.T) {
+	u1 := &model.User{ID: uuid.New().String()}
+	u2 := &model.User{ID: uuid.New().String()}
+	team := uuid.New()
+	members := []*model.Team{{ID: team.String()}}
+	repo := &mockKpiRepo{
+		entries: []*model.TimeTableEntry{
+			workSession(u1, "2024-01-08", 9, 11.5),
+			workSession(u2, "2024-01-08", 10, 11.5),
+			workSession(u1, "2024-01-15", 10, 10.5),
+		},
+		users: []*model.UserWithAllData{{ID: u1.ID, Teams: members}, {ID: u2.ID, Teams: members}},
+	}
+	svc := NewKpiService(repo)
+	from := time.Date(2024, 1, 8, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2024, 1, 15, 0, 0, 0, 0, time.UTC)
+
+	got, err := svc.GetTeamPresenceHeatmap(context.Background(), team, from, to, 2, nil)
+	assert.NoError(t, err)
+	assert.Len(t, got.Cells, 7*24)
+	cell := func(d model.Weekday, h int) *model.PresenceCell {
+		for _, c := range got.Cells {
+			if c.Weekday == d && int(c.Hour) == h {
+				return c
+			}
+		}
+		return nil
+	}
+	assert.Equal(t, model.WeekdayMonday, got.Cells[0].Weekday)
+	assert.Equal(t, 1.5, cell(model.WeekdayMonday, 10).AvgPresent, "two Mondays: 2 then 1 present")
+	assert.Equal(t, int32(2), cell(model.WeekdayMonday, 10).MaxPresent)
+	assert.Equal(t, 0.5, cell(model.WeekdayMonday, 10).UnderstaffedRate)
+	assert.Equal(t, 0.5, cell(model.WeekdayMonday, 9).AvgPresent)
+	assert.Equal(t, 0.0, cell(model.WeekdayMonday, 9).UnderstaffedRate, "before the default arrival")
+	assert.Equal(t, 0.0, cell(model.WeekdayTuesday, 10).AvgPresent)
+	assert.Equal(t, 1.0, cell(model.WeekdayTuesday, 10).UnderstaffedRate)
+	assert.Equal(t, 0.0, cell(model.WeekdaySaturday, 10).UnderstaffedRate, "nobody is due on the weekend")
+	// 6 weekdays of 7 hours from 10:00, two of them staffed
+	assert.Equal(t, 40.0/42, got.UnderstaffedRate)
+
+	// from 9:00 to noon, only 10:00 and 11:00 on the first Monday are staffed
+	open, err := svc.GetTeamPresenceHeatmap(context.Background(), team, from, to, 2, &HourRange{From: 9, To: 12})
+	assert.NoError(t, err)
+	assert.Equal(t, 16.0/18, open.UnderstaffedRate)
+
+	_, err = svc.GetTeamPresenceHeatmap(context.Background(), team, from, to, -1, nil)
+	assert.Error(t, err)
+	_, err = svc.GetTeamPresenceHeatmap(context.Background(), team, from, to, 1, &HourRange{From: 18, To: 9})
+	assert.Error(t, err)
+}
+
+func TestTeamPresenceHeatmapCountsPeopleNotSessions(t *testing.T) {
+	u1 := &model.User{ID: uuid.New().String()}
+	u2 := &model.User{ID: uuid.New().String()}
+	team := uuid.New()
+	members := []*model.Team{{ID: team.String()}}
+	repo := &mockKpiRepo{
+		entries: []*model.TimeTableEntry{
+			// a lunch break within the noon hour
+			workSession(u1, "2024-01-08", 9, 12),
+			workSession(u1, "2024-01-08", 12.75, 17),
+			workSession(u2, "2024-01-08", 9, 11),
+		},
+		users: []*model.UserWithAllData{{ID: u1.ID, Teams: members}, {ID: u2.ID, Teams: members}},
+	}
+	svc := NewKpiService(repo)
+	day := time.Date(2024, 1, 8, 0, 0, 0, 0, time.UTC)
+
+	got, err := svc.GetTeamPresenceHeatmap(context.Background(), team, day, day, 2, nil)
+	require.NoError(t, err)
+	present := map[int]int32{}
+	for _, c := range got.Cells {
+		if c.Weekday == model.WeekdayMonday {
+			present[int(c.Hour)] = c.MaxPresent
+		}
+	}
+	assert.Equal(t, int32(2), present[10])
+	assert.Equal(t, int32(1), present[11], "not in the hour they left on the dot")
+	assert.Equal(t, int32(1), present[12], "back from the break within the hour: one person")
+	assert.Equal(t, int32(1), present[16])
+	assert.Equal(t, int32(0), present[17])
+}
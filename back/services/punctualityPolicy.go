@@ -0,0 +1,207 @@
+package services
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/epitech/timemanager/internal/graph/model"
+	"github.com/google/uuid"
+)
+
+const (
+	// defaultGracePeriod is how late an arrival may be and still count as on time.
+	defaultGracePeriod = 15 * time.Minute
+	// defaultArrival is when users without a schedule are due, from midnight.
+	defaultArrival = 10 * time.Hour
+)
+
+// PunctualityPolicy rates the first arrival of each working day against the
+// planned start: the start of the core hours on a flex-time day, else the
+// schedule's start, else the default arrival. Rest days, the weekend without a
+// schedule, holidays, remote days and days of approved leave are not rated.
+type PunctualityPolicy struct {
+	GracePeriod time.Duration
+	// DefaultArrival is the time of day users without a schedule are due.
+	DefaultArrival time.Duration
+}
+
+func DefaultPunctualityPolicy() PunctualityPolicy {
+	return PunctualityPolicy{GracePeriod: defaultGracePeriod, DefaultArrival: defaultArrival}
+}
+
+// ParsePunctualityPolicy reads the configured grace period in minutes and
+// default arrival (HH:mm), empty values keeping the defaults.
+func ParsePunctualityPolicy(graceMinutes, defaultArrival string) (PunctualityPolicy, error) {
+	p := DefaultPunctualityPolicy()
+	if v := strings.TrimSpace(graceMinutes); v != "" {
+		minutes, err := strconv.Atoi(v)
+		if err != nil || minutes < 0 {
+			return p, errors.New("the grace period must be a number of minutes")
+		}
+		p.GracePeriod = time.Duration(minutes) * time.Minute
+	}
+	if v := strings.TrimSpace(defaultArrival); v != "" {
+		clock, err := time.Parse(layoutClock, v)
+		if err != nil {
+			return p, errors.New("invalid default arrival, expected HH:mm")
+		}
+		p.DefaultArrival = time.Duration(clock.Hour())*time.Hour + time.Duration(clock.Minute())*time.Minute
+	}
+	return p, nil
+}
+
+// dueAt returns when the arrival was due, false when the day is not rated.
+// planned is nil for users without a schedule, who only work from Monday to
+// Friday; absent is the share of the day on approved leave.
+func (p PunctualityPolicy) dueAt(arrival time.Time, planned *model.PlannedSchedule, absent float64) (time.Time, bool) {
+	if absent > 0 {
+		return time.Time{}, false
+	}
+	if planned != nil {
+		if !planned.WorkingDay || planned.Remote || planned.Start == nil {
+			return time.Time{}, false
+		}
+		if planned.CoreStart != nil {
+			return *planned.CoreStart, true
+		}
+		return *planned.Start, true
+	}
+	if arrival.Weekday() == time.Saturday || arrival.Weekday() == time.Sunday {
+		return time.Time{}, false
+	}
+	day := time.Date(arrival.Year(), arrival.Month(), arrival.Day(), 0, 0, 0, 0, arrival.Location())
+	return day.Add(p.DefaultArrival), true
+}
+
+// lateMinutes is how late an arrival due at due is: 0 within the grace
+// period, else the minutes since due, at least one.
+func (p PunctualityPolicy) lateMinutes(arrival, due time.Time) int {
+	late := arrival.Sub(due)
+	if late <= p.GracePeriod {
+		return 0
+	}
+	return max(1, int(late.Minutes()))
+}
+
+// lateness rates the entry's arrival under the policy; false when its day is
+// not rated.
+func (p kpiPlanning) lateness(e *model.TimeTableEntry) (int, bool) {
+	arrival, due, rated := p.arrivalDue(e)
+	if !rated {
+		return 0, false
+	}
+	return p.policy.lateMinutes(arrival, due), true
+}
+
+// arrivalDue returns the entry's local arrival and when it was due.
+func (p kpiPlanning) arrivalDue(e *model.TimeTableEntry) (time.Time, time.Time, bool) {
+	arrival := localArrival(e)
+	var planned *model.PlannedSchedule
+	absent := 0.0
+	if e.UserID != nil {
+		planned = p.planned(e.UserID.ID, e.Day)
+		absent = p.absent(e.UserID.ID, e.Day)
+	}
+	due, rated := p.policy.dueAt(arrival, planned, absent)
+	return arrival, due, rated
+}
+
+// CheckArrival rates a clock-in under the punctuality policy and returns the
+// lateness it makes, nil when on time. Only the first session of a day is
+// rated; coming back from a break is not a late arrival.
+func (s *KpiService) CheckArrival(entry *model.TimeTableEntry) (*model.LatenessEvent, error) {
+	if entry == nil || entry.UserID == nil {
+		return nil, nil
+	}
+	userID, err := uuid.Parse(entry.UserID.ID)
+	if err != nil {
+		return nil, err
+	}
+	day, err := time.Parse(layoutISO, entry.Day)
+	if err != nil {
+		return nil, err
+	}
+	entries, err := s.Repo.GetTimeTableEntriesFiltered(&userID, nil, &day, &day)
+	if err != nil {
+		return nil, err
+	}
+	for _, e := range entries {
+		if e.ID != entry.ID && e.Day == entry.Day && e.Arrival.Before(entry.Arrival) {
+			return nil, nil
+		}
+	}
+	return s.planning().rateArrival(entry), nil
+}
+
+// DayLateness rates the first arrival of a user's day, once its entries
+// changed; nil when on time or without any entry.
+func (s *KpiService) DayLateness(userID uuid.UUID, day string) (*model.LatenessEvent, error) {
+	d, err := time.Parse(layoutISO, day)
+	if err != nil {
+		return nil, err
+	}
+	entries, err := s.Repo.GetTimeTableEntriesFiltered(&userID, nil, &d, &d)
+	if err != nil {
+		return nil, err
+	}
+	var first *model.TimeTableEntry
+	for _, e := range entries {
+		if e.Day == day && (first == nil || e.Arrival.Before(first.Arrival)) {
+			first = e
+		}
+	}
+	if first == nil {
+		return nil, nil
+	}
+	return s.planning().rateArrival(first), nil
+}
+
+// RangeLateness rates the first arrival of each of a user's days between two
+// days (YYYY-MM-DD inclusive), keyed by day: nil when on time. Days without
+// any entry are left out.
+func (s *KpiService) RangeLateness(userID uuid.UUID, from, to string) (map[string]*model.LatenessEvent, error) {
+	first, err := time.Parse(layoutISO, from)
+	if err != nil {
+		return nil, err
+	}
+	last, err := time.Parse(layoutISO, to)
+	if err != nil {
+		return nil, err
+	}
+	entries, err := s.Repo.GetTimeTableEntriesFiltered(&userID, nil, &first, &last)
+	if err != nil {
+		return nil, err
+	}
+	planning := s.planning()
+	planning.preload([]string{userID.String()}, from, to)
+	out := make(map[string]*model.LatenessEvent)
+	for _, e := range firstArrivals(entries) {
+		if e.Day >= from && e.Day <= to {
+			out[e.Day] = planning.rateArrival(e)
+		}
+	}
+	return out, nil
+}
+
+// rateArrival returns the lateness of a day's first arrival, nil when on time.
+func (p kpiPlanning) rateArrival(entry *model.TimeTableEntry) *model.LatenessEvent {
+	arrival, due, rated := p.arrivalDue(entry)
+	if !rated {
+		return nil
+	}
+	late := p.policy.lateMinutes(arrival, due)
+	if late == 0 {
+		return nil
+	}
+	return &model.LatenessEvent{
+		EntryID:      entry.ID,
+		UserID:       entry.UserID,
+		Day:          entry.Day,
+		PlannedStart: due,
+		Arrival:      entry.Arrival,
+		LateMinutes:  int32(late),
+		Status:       model.LatenessStatusPending,
+	}
+}
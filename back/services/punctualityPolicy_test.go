@@ -0,0 +1,118 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"github.com/epitech/timemanager/internal/graph/model"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParsePunctualityPolicy(t *testing.T) {
+	p, err := ParsePunctualityPolicy("", "")
+	assert.NoError(t, err)
+	assert.Equal(t, DefaultPunctualityPolicy(), p)
+
+	p, err = ParsePunctualityPolicy("0", "08:30")
+	assert.NoError(t, err)
+	assert.Equal(t, time.Duration(0), p.GracePeriod)
+	assert.Equal(t, 8*time.Hour+30*time.Minute, p.DefaultArrival)
+
+	_, err = ParsePunctualityPolicy("-5", "")
+	assert.Error(t, err)
+	_, err = ParsePunctualityPolicy("", "8h")
+	assert.Error(t, err)
+}
+
+func TestPunctualityPolicyExemptionsAndCoreHours(t *testing.T) {
+	uid := uuid.New()
+	ws := &model.WorkSchedule{ID: "ws", UserID: ptrString(uid.String()), EffectiveFrom: "2024-01-01",
+		Days: []*model.WorkScheduleDay{
+			{Weekday: model.WeekdayMonday, Start: "09:00", End: "17:00"},
+			{Weekday: model.WeekdayTuesday, Start: "07:00", End: "19:00", CoreStart: ptrString("10:00")},
+			{Weekday: model.WeekdayWednesday, Start: "09:00", End: "17:00", Remote: true},
+			{Weekday: model.WeekdayThursday, Start: "09:00", End: "17:00"},
+		}}
+	u := &model.User{ID: uid.String()}
+	at := func(day string, h, m int) *model.TimeTableEntry {
+		d, _ := time.Parse(layoutISO, day)
+		return &model.TimeTableEntry{UserID: u, Day: day, Arrival: d.Add(time.Duration(h)*time.Hour + time.Duration(m)*time.Minute)}
+	}
+	svc := NewKpiService(&mockKpiRepo{})
+	svc.Punctuality.GracePeriod = 5 * time.Minute
+	svc.Schedules = NewScheduleService(&mockScheduleRepo{schedules: []*model.WorkSchedule{ws}, timeZone: "UTC"})
+	svc.Leaves = NewLeaveService(&mockLeaveRepo{requests: []*model.LeaveRequest{
+		{StartDate: "2024-01-18", EndDate: "2024-01-18", HalfDay: true, Status: model.LeaveStatusApproved},
+	}})
+	planning := svc.planning()
+
+	late, rated := planning.lateness(at("2024-01-15", 9, 5))
+	assert.True(t, rated)
+	assert.Equal(t, 0, late, "within the grace period")
+	late, _ = planning.lateness(at("2024-01-15", 9, 6))
+	assert.Equal(t, 6, late, "counted from the planned start")
+
+	late, _ = planning.lateness(at("2024-01-16", 9, 50))
+	assert.Equal(t, 0, late, "flex-time: due by the core hours")
+	late, _ = planning.lateness(at("2024-01-16", 10, 20))
+	assert.Equal(t, 20, late)
+
+	_, rated = planning.lateness(at("2024-01-17", 11, 0))
+	assert.False(t, rated, "remote day")
+	_, rated = planning.lateness(at("2024-01-18", 13, 0))
+	assert.False(t, rated, "half a day of leave")
+	_, rated = planning.lateness(at("2024-01-20", 13, 0))
+	assert.False(t, rated, "rest day")
+}
+
+func TestPunctualityPolicyWithoutSchedule(t *testing.T) {
+	u := &model.User{ID: uuid.New().String()}
+	at := func(day string, h int) *model.TimeTableEntry {
+		d, _ := time.Parse(layoutISO, day)
+		return &model.TimeTableEntry{UserID: u, Day: day, Arrival: d.Add(time.Duration(h) * time.Hour)}
+	}
+	planning := NewKpiService(&mockKpiRepo{}).planning()
+
+	late, rated := planning.lateness(at("2024-01-19", 11))
+	assert.True(t, rated)
+	assert.Equal(t, 60, late, "due at the default arrival on a weekday")
+	_, rated = planning.lateness(at("2024-01-20", 11))
+	assert.False(t, rated, "Saturday")
+	_, rated = planning.lateness(at("2024-01-21", 11))
+	assert.False(t, rated, "Sunday")
+}
+
+func TestCheckArrival(t *testing.T) {
+	uid := uuid.New()
+	ws := &model.WorkSchedule{ID: "ws", UserID: ptrString(uid.String()), EffectiveFrom: "2024-01-01",
+		Days: []*model.WorkScheduleDay{{Weekday: model.WeekdayMonday, Start: "09:00", End: "17:00"}}}
+	entry := func(id string, h, m int) *model.TimeTableEntry {
+		return &model.TimeTableEntry{ID: id, UserID: &model.User{ID: uid.String()}, Day: "2024-01-15", Arrival: time.Date(2024, 1, 15, h, m, 0, 0, time.UTC)}
+	}
+	repo := &mockKpiRepo{}
+	svc := NewKpiService(repo)
+	svc.Schedules = NewScheduleService(&mockScheduleRepo{schedules: []*model.WorkSchedule{ws}, timeZone: "UTC"})
+
+	morning := entry("e1", 9, 10)
+	repo.entries = []*model.TimeTableEntry{morning}
+	event, err := svc.CheckArrival(morning)
+	assert.NoError(t, err)
+	assert.Nil(t, event, "within tolerance")
+
+	morning = entry("e1", 9, 40)
+	repo.entries = []*model.TimeTableEntry{morning}
+	event, err = svc.CheckArrival(morning)
+	assert.NoError(t, err)
+	assert.Equal(t, int32(40), event.LateMinutes)
+	assert.Equal(t, "e1", event.EntryID)
+	assert.Equal(t, time.Date(2024, 1, 15, 9, 0, 0, 0, time.UTC), event.PlannedStart)
+	assert.Equal(t, morning.Arrival, event.Arrival)
+
+	// coming back from lunch is not a late arrival
+	afternoon := entry("e2", 13, 30)
+	repo.entries = []*model.TimeTableEntry{morning, afternoon}
+	event, err = svc.CheckArrival(afternoon)
+	assert.NoError(t, err)
+	assert.Nil(t, event)
+}
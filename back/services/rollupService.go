@@ -0,0 +1,746 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/epitech/timemanager/internal/graph/model"
+	"github.com/epitech/timemanager/package/timezone"
+	"github.com/google/uuid"
+)
+
+// RollupRepository is the minimal repository contract used by RollupService.
+type RollupRepository interface {
+	GetTimeTableEntriesFiltered(userID *uuid.UUID, teamID *uuid.UUID, from, to *time.Time) ([]*model.TimeTableEntry, error)
+	GetTimeTableEntryByID(id string) (*model.TimeTableEntry, error)
+	GetDailyRollups(userID *uuid.UUID, teamID *uuid.UUID, from, to string) ([]*model.DailyRollup, error)
+	ReplaceDailyRollups(userID *uuid.UUID, from, to string, rollups []*model.DailyRollup) error
+	GetRollupCoverage() ([]*model.RollupCoverage, error)
+	// UpdateRollupCoverage replaces the coverage with what update makes of
+	// it, in one transaction.
+	UpdateRollupCoverage(update func(ranges []*model.RollupCoverage) []*model.RollupCoverage) error
+	// GetUserIDs returns the members of the team, or the users of the site.
+	GetUserIDs(teamID *uuid.UUID, siteID *uuid.UUID) ([]uuid.UUID, error)
+}
+
+// RollupService keeps the daily rollups read by the KPIs in step with the
+// time entries and with the planning they are computed against: leaves,
+// contracts, schedules, holidays and the overtime policy. Overtime being
+// settled per week, a change recomputes the whole weeks around the changed
+// days. The KPIs only read the rollups of the days a rebuild covered.
+type RollupService struct {
+	Repo RollupRepository
+	// Kpi holds the rules the rollups are computed with: midnight rule,
+	// planning, overtime policy.
+	Kpi *KpiService
+	// background tracks the refreshes and rebuilds run off the request path.
+	background sync.WaitGroup
+}
+
+func NewRollupService(repo RollupRepository, kpi *KpiService) *RollupService {
+	return &RollupService{Repo: repo, Kpi: kpi}
+}
+
+// GetDailyRollups lists the rollups of a user or a team between two days.
+func (s *RollupService) GetDailyRollups(userID *uuid.UUID, teamID *uuid.UUID, from, to string) ([]*model.DailyRollup, error) {
+	if _, err := time.Parse(layoutISO, from); err != nil {
+		return nil, errors.New("invalid from, expected YYYY-MM-DD")
+	}
+	if _, err := time.Parse(layoutISO, to); err != nil {
+		return nil, errors.New("invalid to, expected YYYY-MM-DD")
+	}
+	if to < from {
+		return nil, errors.New("to must not be before from")
+	}
+	return s.Repo.GetDailyRollups(userID, teamID, from, to)
+}
+
+// Refresh recomputes the rollups of a user after a change on the given days.
+// Failures are only logged: the clock event went through and the rollups can
+// be rebuilt. A nil service does nothing.
+func (s *RollupService) Refresh(userID string, days ...string) {
+	if s == nil || len(days) == 0 {
+		return
+	}
+	if err := s.refresh(userID, days); err != nil {
+		log.Printf("failed to refresh the daily rollups of %s: %v", userID, err)
+	}
+}
+
+// RefreshEntry recomputes the rollups of the day of an entry.
+func (s *RollupService) RefreshEntry(entryID string) {
+	if s == nil {
+		return
+	}
+	entry, err := s.Repo.GetTimeTableEntryByID(entryID)
+	if err != nil || entry.UserID == nil {
+		log.Printf("failed to refresh the daily rollups of entry %s: %v", entryID, err)
+		return
+	}
+	s.Refresh(entry.UserID.ID, entry.Day)
+}
+
+func (s *RollupService) refresh(userID string, days []string) error {
+	uid, err := uuid.Parse(userID)
+	if err != nil {
+		return errors.New("invalid user id")
+	}
+	sort.Strings(days)
+	first, err := time.Parse(layoutISO, days[0])
+	if err != nil {
+		return err
+	}
+	last, err := time.Parse(layoutISO, days[len(days)-1])
+	if err != nil {
+		return err
+	}
+	// a session split at midnight also changes the next day, which may
+	// start another week, and gets part of the previous day's work
+	from := mondayOf(first.AddDate(0, 0, -1))
+	to := mondayOf(last.AddDate(0, 0, 1)).AddDate(0, 0, 6)
+	_, err = s.recompute(&uid, from, to, time.Now())
+	return err
+}
+
+// RefreshRange recomputes the rollups of a user, or of everyone when userID
+// is nil, between two days (YYYY-MM-DD inclusive) after a change of their
+// planning; an empty to means up to today. Failures are only logged. A nil
+// service does nothing.
+func (s *RollupService) RefreshRange(userID *uuid.UUID, from, to string) {
+	if s == nil {
+		return
+	}
+	if err := s.refreshRange(userID, from, to, time.Now()); err != nil {
+		log.Printf("failed to refresh the daily rollups from %s to %s: %v", from, to, err)
+	}
+}
+
+func (s *RollupService) refreshRange(userID *uuid.UUID, from, to string, now time.Time) error {
+	// the KPIs only read the covered days
+	ranges, err := s.Repo.GetRollupCoverage()
+	if err != nil {
+		return err
+	}
+	if len(ranges) == 0 {
+		return nil
+	}
+	first := ranges[0].From
+	for _, rc := range ranges {
+		first = minString(first, rc.From)
+	}
+	from = maxString(from, first)
+	today := now.Format(layoutISO)
+	if to == "" || to > today {
+		to = today
+	}
+	if from > to {
+		return nil
+	}
+	start, err := time.Parse(layoutISO, from)
+	if err != nil {
+		return err
+	}
+	end, err := time.Parse(layoutISO, to)
+	if err != nil {
+		return err
+	}
+	// the weeks around, as for the sessions split at midnight
+	start, end = mondayOf(start.AddDate(0, 0, -1)), mondayOf(end.AddDate(0, 0, 1)).AddDate(0, 0, 6)
+	if userID != nil {
+		_, err = s.recompute(userID, start, end, now)
+		return err
+	}
+	_, err = s.rebuildWeeks(start, end, now)
+	return err
+}
+
+// RefreshUsers recomputes in the background the covered rollups of the users
+// between two days, as RefreshRange does, after a change of the planning of
+// several users or of their whole history: a team or a time zone. A nil
+// service does nothing.
+func (s *RollupService) RefreshUsers(userIDs []uuid.UUID, from, to string) {
+	if s == nil || len(userIDs) == 0 {
+		return
+	}
+	s.inBackground(func() { s.refreshUsers(userIDs, from, to) })
+}
+
+// RefreshTeam recomputes in the background the covered rollups of the
+// team's members between two days, as after a change of its schedule.
+func (s *RollupService) RefreshTeam(teamID uuid.UUID, from, to string) {
+	s.refreshMembers(&teamID, nil, from, to)
+}
+
+// RefreshSite recomputes in the background every covered rollup of the
+// site's users, as after a change of its time zone.
+func (s *RollupService) RefreshSite(siteID uuid.UUID) {
+	s.refreshMembers(nil, &siteID, "", "")
+}
+
+func (s *RollupService) refreshMembers(teamID *uuid.UUID, siteID *uuid.UUID, from, to string) {
+	if s == nil {
+		return
+	}
+	s.inBackground(func() {
+		userIDs, err := s.Repo.GetUserIDs(teamID, siteID)
+		if err != nil {
+			log.Printf("failed to read the users whose daily rollups to refresh: %v", err)
+			return
+		}
+		s.refreshUsers(userIDs, from, to)
+	})
+}
+
+func (s *RollupService) refreshUsers(userIDs []uuid.UUID, from, to string) {
+	now := time.Now()
+	for _, userID := range userIDs {
+		if err := s.refreshRange(&userID, from, to, now); err != nil {
+			log.Printf("failed to refresh the daily rollups of %s: %v", userID, err)
+		}
+	}
+}
+
+// inBackground runs f off the request path.
+func (s *RollupService) inBackground(f func()) {
+	s.background.Add(1)
+	go func() {
+		defer s.background.Done()
+		f()
+	}()
+}
+
+// Wait returns once the refreshes and rebuilds started in the background
+// are done.
+func (s *RollupService) Wait() {
+	if s != nil {
+		s.background.Wait()
+	}
+}
+
+// RefreshDays recomputes the rollups of everyone around the given days, such
+// as holidays. Failures are only logged. A nil service does nothing.
+func (s *RollupService) RefreshDays(days ...string) {
+	if s == nil {
+		return
+	}
+	now := time.Now()
+	weeks := make(map[string]struct{})
+	for _, day := range days {
+		d, err := time.Parse(layoutISO, day)
+		if err != nil || day > now.Format(layoutISO) {
+			continue
+		}
+		monday := mondayOf(d).Format(layoutISO)
+		if _, done := weeks[monday]; done {
+			continue
+		}
+		weeks[monday] = struct{}{}
+		if err := s.refreshRange(nil, day, day, now); err != nil {
+			log.Printf("failed to refresh the daily rollups of %s: %v", day, err)
+		}
+	}
+}
+
+// RebuildCovered recomputes in the background the rollups of every covered
+// day, after a change of the rules they are computed with. Until each range
+// is rebuilt the KPIs read the entries. A nil service does nothing.
+func (s *RollupService) RebuildCovered() {
+	if s == nil {
+		return
+	}
+	s.rebuildInBackground(func(*model.RollupCoverage) bool { return true })
+}
+
+// RebuildStale recomputes in the background the covered rollups computed
+// with other rules than the configured ones (midnight rule, punctuality,
+// default time zone), as after a restart with a new configuration.
+func (s *RollupService) RebuildStale() {
+	if s == nil {
+		return
+	}
+	rules := s.Kpi.rollupRules()
+	s.rebuildInBackground(func(rc *model.RollupCoverage) bool { return rc.Rules != rules })
+}
+
+// rebuildInBackground drops the ranges picked from the coverage, so that the
+// KPIs stop reading them, then rebuilds them one after the other.
+func (s *RollupService) rebuildInBackground(pick func(rc *model.RollupCoverage) bool) {
+	var ranges []*model.RollupCoverage
+	if err := s.Repo.UpdateRollupCoverage(func(all []*model.RollupCoverage) []*model.RollupCoverage {
+		ranges = ranges[:0]
+		kept := make([]*model.RollupCoverage, 0, len(all))
+		for _, rc := range all {
+			if pick(rc) {
+				ranges = append(ranges, rc)
+			} else {
+				kept = append(kept, rc)
+			}
+		}
+		return kept
+	}); err != nil {
+		log.Printf("failed to save the rollup coverage: %v", err)
+		return
+	}
+	if len(ranges) == 0 {
+		return
+	}
+	s.inBackground(func() {
+		for _, rc := range ranges {
+			to := rc.To
+			if to == "" {
+				to = time.Now().Format(layoutISO)
+			}
+			if _, err := s.Rebuild(rc.From, to); err != nil {
+				log.Printf("failed to rebuild the daily rollups from %s to %s: %v", rc.From, to, err)
+			}
+		}
+	})
+}
+
+// Rebuild recomputes the rollups of everyone between two days, week by week,
+// returns the number of rollups written and records the weeks as covered:
+// without end when the rebuild reaches today, the later changes refreshing
+// the rollups as they come.
+func (s *RollupService) Rebuild(from, to string) (int, error) {
+	start, err := time.Parse(layoutISO, from)
+	if err != nil {
+		return 0, errors.New("invalid from, expected YYYY-MM-DD")
+	}
+	end, err := time.Parse(layoutISO, to)
+	if err != nil {
+		return 0, errors.New("invalid to, expected YYYY-MM-DD")
+	}
+	now := time.Now()
+	start, end = mondayOf(start), mondayOf(end).AddDate(0, 0, 6)
+	written, err := s.rebuildWeeks(start, end, now)
+	if err != nil {
+		return written, err
+	}
+	covered := &model.RollupCoverage{From: start.Format(layoutISO), To: end.Format(layoutISO), Rules: s.Kpi.rollupRules()}
+	if covered.To >= now.Format(layoutISO) {
+		covered.To = ""
+	}
+	return written, s.Repo.UpdateRollupCoverage(func(ranges []*model.RollupCoverage) []*model.RollupCoverage {
+		return mergeCoverage(ranges, covered)
+	})
+}
+
+// rebuildWeeks recomputes the rollups of everyone, week by week, from a
+// Monday to a Sunday.
+func (s *RollupService) rebuildWeeks(start, end, now time.Time) (int, error) {
+	written := 0
+	for monday := mondayOf(start); !monday.After(end); monday = monday.AddDate(0, 0, 7) {
+		n, err := s.recompute(nil, monday, monday.AddDate(0, 0, 6), now)
+		if err != nil {
+			return written, err
+		}
+		written += n
+	}
+	return written, nil
+}
+
+// mergeCoverage adds a range to the coverage, merged with the ranges of the
+// same rules it overlaps or touches; ranges of other rules are dropped, their
+// rollups being rebuilt with the current ones.
+func mergeCoverage(ranges []*model.RollupCoverage, added *model.RollupCoverage) []*model.RollupCoverage {
+	merged := *added
+	out := make([]*model.RollupCoverage, 0, len(ranges)+1)
+	for _, rc := range ranges {
+		if rc.Rules != added.Rules {
+			continue
+		}
+		if !touches(rc, &merged) {
+			out = append(out, rc)
+			continue
+		}
+		merged.From = minString(merged.From, rc.From)
+		if merged.To != "" && (rc.To == "" || rc.To > merged.To) {
+			merged.To = rc.To
+		}
+	}
+	out = append(out, &merged)
+	sort.Slice(out, func(i, j int) bool { return out[i].From < out[j].From })
+	return out
+}
+
+// touches tells whether two ranges overlap or follow each other.
+func touches(a, b *model.RollupCoverage) bool {
+	return (a.To == "" || dayAfter(a.To) >= b.From) && (b.To == "" || dayAfter(b.To) >= a.From)
+}
+
+func dayAfter(day string) string {
+	d, err := time.Parse(layoutISO, day)
+	if err != nil {
+		return day
+	}
+	return d.AddDate(0, 0, 1).Format(layoutISO)
+}
+
+// covers tells whether the rollups of the days from first to last inclusive
+// were all rebuilt with the given rules.
+func covers(ranges []*model.RollupCoverage, rules, first, last string) bool {
+	for _, rc := range ranges {
+		if rc.Rules == rules && rc.From <= first && (rc.To == "" || rc.To >= last) {
+			return true
+		}
+	}
+	return false
+}
+
+// recompute replaces the rollups of whole weeks, from a Monday to a
+// Sunday, with the ones computed from the entries, and counts them.
+func (s *RollupService) recompute(userID *uuid.UUID, from, to, now time.Time) (int, error) {
+	before := from.AddDate(0, 0, -1)
+	entries, err := s.Repo.GetTimeTableEntriesFiltered(userID, nil, &before, &to)
+	if err != nil {
+		return 0, err
+	}
+	first, last := from.Format(layoutISO), to.Format(layoutISO)
+	rollups := make([]*model.DailyRollup, 0)
+	for _, r := range s.Kpi.dailyRollups(entries, now) {
+		if r.Day >= first && r.Day <= last {
+			rollups = append(rollups, r)
+		}
+	}
+	if err := s.Repo.ReplaceDailyRollups(userID, first, last, rollups); err != nil {
+		return 0, err
+	}
+	return len(rollups), nil
+}
+
+// dailyRollups aggregates the entries per user and day with the KPI rules.
+// The overtime of a day depends on its whole week: give whole weeks of
+// entries, plus the day before for the sessions split at midnight.
+func (s *KpiService) dailyRollups(entries []*model.TimeTableEntry, now time.Time) []*model.DailyRollup {
+	planning := s.planning()
+	planning.preload(entriesSpan(entries))
+	perUserDay, users := sumByUserDay(entries, now, s.MidnightRule)
+	overtime := s.overtimeEngine(planning).compute(perUserDay)
+
+	rows := make(map[userDay]*model.DailyRollup)
+	row := func(userID, day string) *model.DailyRollup {
+		key := userDay{userID: userID, day: day}
+		if rows[key] == nil {
+			name := ""
+			if u := users[userID]; u != nil {
+				name = u.FirstName + " " + u.LastName
+			}
+			rows[key] = &model.DailyRollup{UserID: userID, UserName: name, Day: day, UpdatedAt: now}
+		}
+		return rows[key]
+	}
+
+	for key, minutes := range perUserDay {
+		r := row(key.userID, key.day)
+		r.WorkedMinutes = int32(minutes)
+		if ot := overtime[key.userID]; ot != nil {
+			r.OvertimeMinutes = int32(ot.days[key.day])
+			r.OvertimeExcessMinutes = int32(ot.excessDays[key.day])
+		}
+	}
+	for _, e := range entries {
+		if e.UserID == nil {
+			continue
+		}
+		r := row(e.UserID.ID, e.Day)
+		r.Sessions++
+		r.UnpaidBreakMinutes += int32(unpaidBreakMinutes(e, now))
+		if e.Departure == nil || e.Departure.IsZero() {
+			r.OpenSessions++
+			if e.Status {
+				arrival := e.Arrival
+				r.ActiveSince = &arrival
+			}
+		}
+	}
+	for _, e := range firstArrivals(entries) {
+		if e.UserID == nil {
+			continue
+		}
+		r := row(e.UserID.ID, e.Day)
+		arrival := e.Arrival
+		r.FirstArrival = &arrival
+		if late, rated := planning.lateness(e); rated {
+			lateMinutes := int32(late)
+			r.LateMinutes = &lateMinutes
+		}
+	}
+
+	out := make([]*model.DailyRollup, 0, len(rows))
+	for _, r := range rows {
+		out = append(out, r)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Day != out[j].Day {
+			return out[i].Day < out[j].Day
+		}
+		return out[i].UserID < out[j].UserID
+	})
+	return out
+}
+
+// rollupRules identifies the configured rules the rollups are computed with,
+// besides the planning and the overtime policy whose changes refresh them.
+func (s *KpiService) rollupRules() string {
+	return fmt.Sprintf("midnight=%s;grace=%s;arrival=%s;zone=%s",
+		s.MidnightRule, s.Punctuality.GracePeriod, s.Punctuality.DefaultArrival, timezone.Default())
+}
+
+// storedRollups tells whether the stored rollups of the days from first to
+// last inclusive are complete: a rebuild covered them with the rules in force.
+func (s *KpiService) storedRollups(first, last string) bool {
+	ranges, err := s.Repo.GetRollupCoverage()
+	return err == nil && covers(ranges, s.rollupRules(), first, last)
+}
+
+// kpiFigures are the sums the dashboard metrics are computed from; the
+// periods, per user or not, and the punctuality follow the series granularity, the overtime
+// weeks carry what the premium bands of the weeks cut by the window need.
+type kpiFigures struct {
+	days        []*model.KpiPeriodTotal
+	periods     []*model.KpiPeriodTotal
+	userPeriods []*model.KpiUserPeriodTotal
+	weekdays    []*model.KpiWeekdayTotal
+	users       []*model.KpiUserTotal
+	punctuality []*model.KpiPunctualityPeriod
+	overtime    []*model.KpiOvertimeWeek
+}
+
+// sumKpiFigures has Postgres sum the stored rollups of the window.
+func (s *KpiService) sumKpiFigures(userID *uuid.UUID, teamID *uuid.UUID, start, end, now time.Time, granularity model.KpiGranularity) (*kpiFigures, error) {
+	from, to := start.Format(layoutISO), end.Format(layoutISO)
+	var f kpiFigures
+	var err error
+	if f.days, err = s.Repo.GetKpiPeriodTotals(userID, teamID, from, to, model.KpiGranularityDay, now); err != nil {
+		return nil, err
+	}
+	if f.periods, err = s.Repo.GetKpiPeriodTotals(userID, teamID, from, to, granularity, now); err != nil {
+		return nil, err
+	}
+	if f.userPeriods, err = s.Repo.GetKpiUserPeriodTotals(userID, teamID, from, to, granularity, now); err != nil {
+		return nil, err
+	}
+	if f.overtime, err = s.Repo.GetKpiOvertimeWeeks(userID, teamID, mondayOf(start).Format(layoutISO), from, to); err != nil {
+		return nil, err
+	}
+	if f.weekdays, err = s.Repo.GetKpiWeekdayTotals(userID, teamID, from, to, now); err != nil {
+		return nil, err
+	}
+	if f.users, err = s.Repo.GetKpiUserTotals(userID, teamID, from, to, now); err != nil {
+		return nil, err
+	}
+	if f.punctuality, err = s.Repo.GetKpiPunctualityPeriods(userID, teamID, from, to, granularity); err != nil {
+		return nil, err
+	}
+	return &f, nil
+}
+
+// kpiFiguresOf sums rollups in memory the way sumKpiFigures does in Postgres.
+// Give the rollups of whole weeks: only the overtime reads the days before
+// from, the other sums keep the days from from to to inclusive.
+func kpiFiguresOf(weekRollups []*model.DailyRollup, from, to string, now time.Time, granularity model.KpiGranularity) *kpiFigures {
+	rollups := rollupsWithin(weekRollups, from, to)
+	weekdays := make(map[int]*model.KpiWeekdayTotal)
+	weekdayDays := make(map[int]map[string]struct{})
+	users := make(map[string]*model.KpiUserTotal)
+	periods := make(map[string]*model.KpiPunctualityPeriod)
+	for _, r := range rollups {
+		dt, err := time.Parse(layoutISO, r.Day)
+		if err != nil {
+			continue
+		}
+		live := liveMinutes(r, now)
+		if live > 0 {
+			wd := int(dt.Weekday())
+			if weekdays[wd] == nil {
+				weekdays[wd] = &model.KpiWeekdayTotal{Weekday: wd}
+				weekdayDays[wd] = make(map[string]struct{})
+			}
+			weekdays[wd].Minutes += live
+			weekdayDays[wd][r.Day] = struct{}{}
+		}
+
+		u := users[r.UserID]
+		if u == nil {
+			u = &model.KpiUserTotal{UserID: r.UserID}
+			users[r.UserID] = u
+		}
+		u.Minutes += max(live, 0)
+		if live > 0 {
+			u.Days++
+		}
+		u.OvertimeMinutes += int(r.OvertimeMinutes)
+		u.Sessions += int(r.Sessions)
+		u.OpenSessions += int(r.OpenSessions)
+		u.Active = u.Active || r.ActiveSince != nil
+
+		if r.LateMinutes == nil {
+			continue
+		}
+		key := periodStart(dt, granularity).Format(layoutISO)
+		if periods[key] == nil {
+			periods[key] = &model.KpiPunctualityPeriod{PeriodStart: key}
+		}
+		if *r.LateMinutes == 0 {
+			u.OnTimeDays++
+			periods[key].OnTime++
+		} else {
+			u.LateDays++
+			u.LateMinutes += int(*r.LateMinutes)
+			periods[key].Late++
+			periods[key].LateMinutes += int(*r.LateMinutes)
+		}
+	}
+
+	f := &kpiFigures{
+		days:        periodTotalsOf(rollups, now, model.KpiGranularityDay),
+		periods:     periodTotalsOf(rollups, now, granularity),
+		userPeriods: userPeriodTotalsOf(rollups, now, granularity),
+		overtime:    overtimeWeeksOf(weekRollups, from, to),
+	}
+	for wd, t := range weekdays {
+		t.Days = len(weekdayDays[wd])
+		f.weekdays = append(f.weekdays, t)
+	}
+	sort.Slice(f.weekdays, func(i, j int) bool { return f.weekdays[i].Weekday < f.weekdays[j].Weekday })
+	for _, u := range users {
+		f.users = append(f.users, u)
+	}
+	sort.Slice(f.users, func(i, j int) bool { return f.users[i].UserID < f.users[j].UserID })
+	for _, p := range periods {
+		f.punctuality = append(f.punctuality, p)
+	}
+	sort.Slice(f.punctuality, func(i, j int) bool { return f.punctuality[i].PeriodStart < f.punctuality[j].PeriodStart })
+	return f
+}
+
+// overtimeWeeksOf sums the overtime per user and week, as
+// GetKpiOvertimeWeeks does.
+func overtimeWeeksOf(rollups []*model.DailyRollup, from, to string) []*model.KpiOvertimeWeek {
+	weekStart := from
+	if start, err := time.Parse(layoutISO, from); err == nil {
+		weekStart = mondayOf(start).Format(layoutISO)
+	}
+	weeks := make(map[userDay]*model.KpiOvertimeWeek)
+	for _, r := range rollups {
+		if r.Day < weekStart || r.Day > to || (r.OvertimeMinutes <= 0 && r.OvertimeExcessMinutes <= 0) {
+			continue
+		}
+		dt, err := time.Parse(layoutISO, r.Day)
+		if err != nil {
+			continue
+		}
+		key := userDay{userID: r.UserID, day: mondayOf(dt).Format(layoutISO)}
+		w := weeks[key]
+		if w == nil {
+			w = &model.KpiOvertimeWeek{UserID: r.UserID, WeekStart: key.day}
+			weeks[key] = w
+		}
+		if r.Day < from {
+			w.BeforeMinutes += int(r.OvertimeMinutes)
+			continue
+		}
+		w.Minutes += int(r.OvertimeMinutes)
+		w.ExcessMinutes += int(r.OvertimeExcessMinutes)
+		if r.OvertimeMinutes > 0 {
+			w.Days++
+		}
+	}
+	out := make([]*model.KpiOvertimeWeek, 0, len(weeks))
+	for _, w := range weeks {
+		out = append(out, w)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].UserID != out[j].UserID {
+			return out[i].UserID < out[j].UserID
+		}
+		return out[i].WeekStart < out[j].WeekStart
+	})
+	return out
+}
+
+// periodTotalsOf sums the work and the overtime of each period and counts the
+// users who worked, and did overtime, in it, as GetKpiPeriodTotals does.
+func periodTotalsOf(rollups []*model.DailyRollup, now time.Time, granularity model.KpiGranularity) []*model.KpiPeriodTotal {
+	totals := make(map[string]*model.KpiPeriodTotal)
+	users := make(map[string]map[string]struct{})
+	overtimeUsers := make(map[string]map[string]struct{})
+	for _, r := range rollups {
+		dt, err := time.Parse(layoutISO, r.Day)
+		if err != nil {
+			continue
+		}
+		live := liveMinutes(r, now)
+		if live <= 0 {
+			continue
+		}
+		key := periodStart(dt, granularity).Format(layoutISO)
+		if totals[key] == nil {
+			totals[key] = &model.KpiPeriodTotal{PeriodStart: key}
+			users[key] = make(map[string]struct{})
+		}
+		totals[key].Minutes += live
+		totals[key].OvertimeMinutes += int(r.OvertimeMinutes)
+		users[key][r.UserID] = struct{}{}
+		if r.OvertimeMinutes > 0 {
+			if overtimeUsers[key] == nil {
+				overtimeUsers[key] = make(map[string]struct{})
+			}
+			overtimeUsers[key][r.UserID] = struct{}{}
+		}
+	}
+	out := make([]*model.KpiPeriodTotal, 0, len(totals))
+	for key, t := range totals {
+		t.Users = len(users[key])
+		t.OvertimeUsers = len(overtimeUsers[key])
+		out = append(out, t)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].PeriodStart < out[j].PeriodStart })
+	return out
+}
+
+// userPeriodTotalsOf sums the work of each user per period, as
+// GetKpiUserPeriodTotals does.
+func userPeriodTotalsOf(rollups []*model.DailyRollup, now time.Time, granularity model.KpiGranularity) []*model.KpiUserPeriodTotal {
+	totals := make(map[userDay]*model.KpiUserPeriodTotal)
+	for _, r := range rollups {
+		dt, err := time.Parse(layoutISO, r.Day)
+		if err != nil {
+			continue
+		}
+		live := liveMinutes(r, now)
+		if live <= 0 {
+			continue
+		}
+		key := userDay{userID: r.UserID, day: periodStart(dt, granularity).Format(layoutISO)}
+		if totals[key] == nil {
+			totals[key] = &model.KpiUserPeriodTotal{UserID: r.UserID, PeriodStart: key.day}
+		}
+		totals[key].Minutes += live
+	}
+	out := make([]*model.KpiUserPeriodTotal, 0, len(totals))
+	for _, t := range totals {
+		out = append(out, t)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].UserID != out[j].UserID {
+			return out[i].UserID < out[j].UserID
+		}
+		return out[i].PeriodStart < out[j].PeriodStart
+	})
+	return out
+}
+
+// liveMinutes is the work of a rollup's day up to now: a session still
+// running has gone on since the rollup was computed.
+func liveMinutes(r *model.DailyRollup, now time.Time) int {
+	minutes := int(r.WorkedMinutes)
+	if r.ActiveSince != nil && now.After(r.UpdatedAt) {
+		minutes += int(now.Sub(r.UpdatedAt).Minutes())
+	}
+	return minutes
+}
@@ -0,0 +1,471 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/epitech/timemanager/internal/graph/model"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type mockRollupRepo struct {
+	entries  []*model.TimeTableEntry
+	replaced []rollupWrite
+	coverage []*model.RollupCoverage
+	// members are the users of any team or site
+	members []uuid.UUID
+}
+
+type rollupWrite struct {
+	userID   *uuid.UUID
+	from, to string
+	rollups  []*model.DailyRollup
+}
+
+func (m *mockRollupRepo) GetTimeTableEntriesFiltered(userID *uuid.UUID, teamID *uuid.UUID, from, to *time.Time) ([]*model.TimeTableEntry, error) {
+	out := make([]*model.TimeTableEntry, 0)
+	for _, e := range m.entries {
+		if e.Day >= from.Format(layoutISO) && e.Day <= to.Format(layoutISO) {
+			out = append(out, e)
+		}
+	}
+	return out, nil
+}
+
+func (m *mockRollupRepo) GetTimeTableEntryByID(id string) (*model.TimeTableEntry, error) {
+	for _, e := range m.entries {
+		if e.ID == id {
+			return e, nil
+		}
+	}
+	return nil, errors.New("time entry not found")
+}
+
+func (m *mockRollupRepo) GetDailyRollups(userID *uuid.UUID, teamID *uuid.UUID, from, to string) ([]*model.DailyRollup, error) {
+	return nil, nil
+}
+
+func (m *mockRollupRepo) ReplaceDailyRollups(userID *uuid.UUID, from, to string, rollups []*model.DailyRollup) error {
+	m.replaced = append(m.replaced, rollupWrite{userID: userID, from: from, to: to, rollups: rollups})
+	return nil
+}
+
+func (m *mockRollupRepo) GetRollupCoverage() ([]*model.RollupCoverage, error) {
+	return m.coverage, nil
+}
+
+func (m *mockRollupRepo) UpdateRollupCoverage(update func(ranges []*model.RollupCoverage) []*model.RollupCoverage) error {
+	m.coverage = update(m.coverage)
+	return nil
+}
+
+func (m *mockRollupRepo) GetUserIDs(teamID *uuid.UUID, siteID *uuid.UUID) ([]uuid.UUID, error) {
+	return m.members, nil
+}
+
+// workSession is a session of the user on day, from one hour to another (UTC).
+func workSession(u *model.User, day string, from, to float64) *model.TimeTableEntry {
+	start, _ := time.Parse(layoutISO, day)
+	arrival := start.Add(time.Duration(from * float64(time.Hour)))
+	departure := start.Add(time.Duration(to * float64(time.Hour)))
+	return &model.TimeTableEntry{ID: uuid.New().String(), UserID: u, Day: day, Arrival: arrival, Departure: &departure}
+}
+
+func TestDailyRollupsAggregateADay(t *testing.T) {
+	u := &model.User{ID: uuid.New().String(), FirstName: "Ada", LastName: "Lovelace"}
+	morning := workSession(u, "2024-01-10", 10.5, 12)
+	breakEnd := morning.Arrival.Add(45 * time.Minute)
+	morning.Breaks = []*model.Break{{StartedAt: morning.Arrival.Add(30 * time.Minute), EndedAt: &breakEnd}}
+	afternoon := workSession(u, "2024-01-10", 13, 19)
+	now := time.Date(2024, 1, 11, 9, 30, 0, 0, time.UTC)
+	open := &model.TimeTableEntry{UserID: u, Day: "2024-01-11", Arrival: time.Date(2024, 1, 11, 9, 0, 0, 0, time.UTC), Status: true}
+
+	svc := NewKpiService(&mockKpiRepo{})
+	got := svc.dailyRollups([]*model.TimeTableEntry{afternoon, morning, open}, now)
+	assert.Len(t, got, 2)
+
+	day := got[0]
+	assert.Equal(t, "2024-01-10", day.Day)
+	assert.Equal(t, "Ada Lovelace", day.UserName)
+	assert.Equal(t, int32(75+360), day.WorkedMinutes)
+	assert.Equal(t, int32(15), day.UnpaidBreakMinutes)
+	assert.Equal(t, int32(435-defaultExpectedDailyMinutes), day.OvertimeMinutes)
+	assert.Equal(t, int32(30), *day.LateMinutes, "arrived 10:30, due by 10:00")
+	assert.Equal(t, morning.Arrival, *day.FirstArrival)
+	assert.Equal(t, int32(2), day.Sessions)
+	assert.Equal(t, int32(0), day.OpenSessions)
+	assert.Nil(t, day.ActiveSince)
+
+	running := got[1]
+	assert.Equal(t, int32(30), running.WorkedMinutes)
+	assert.Equal(t, int32(0), *running.LateMinutes)
+	assert.Equal(t, int32(1), running.OpenSessions)
+	assert.Equal(t, open.Arrival, *running.ActiveSince)
+	// the running session goes on after the rollup was computed
+	assert.Equal(t, 45, liveMinutes(running, now.Add(15*time.Minute)))
+	assert.Equal(t, 435, liveMinutes(day, now.Add(15*time.Minute)))
+}
+
+func TestDailyRollupsKeepWeeklyCapExcessOnItsDays(t *testing.T) {
+	u := &model.User{ID: uuid.New().String()}
+	entries := []*model.TimeTableEntry{
+		workSession(u, "2024-01-08", 8, 17), // 9h
+		workSession(u, "2024-01-09", 8, 17),
+		workSession(u, "2024-01-10", 8, 17),
+	}
+	svc := NewKpiService(&mockKpiRepo{})
+	svc.Overtime = NewOvertimeService(&mockOvertimeRepo{policy: &model.OvertimePolicy{DailyEnabled: true, WeeklyCapMinutes: int32Ptr(300)}})
+
+	got := svc.dailyRollups(entries, time.Now())
+	overtime := map[string]int32{}
+	excess := map[string]int32{}
+	for _, r := range got {
+		overtime[r.Day] = r.OvertimeMinutes
+		excess[r.Day] = r.OvertimeExcessMinutes
+	}
+	assert.Equal(t, map[string]int32{"2024-01-08": 120, "2024-01-09": 120, "2024-01-10": 60}, overtime)
+	assert.Equal(t, map[string]int32{"2024-01-08": 0, "2024-01-09": 0, "2024-01-10": 60}, excess)
+
+	// the report built on the rollups matches the one of the engine
+	report := overtimeReportOf(svc, got, model.KpiGranularityWeek)
+	assert.Equal(t, int32(300), report.TotalOvertimeMinutes)
+	assert.Equal(t, int32(60), report.TotalExcessMinutes)
+}
+
+func TestRollupRefreshRecomputesTheWeek(t *testing.T) {
+	u := &model.User{ID: uuid.New().String()}
+	repo := &mockRollupRepo{entries: []*model.TimeTableEntry{
+		workSession(u, "2024-01-07", 9, 17), // Sunday of the week before
+		workSession(u, "2024-01-08", 9, 17),
+		workSession(u, "2024-01-10", 9, 17),
+	}}
+	svc := NewRollupService(repo, NewKpiService(&mockKpiRepo{}))
+
+	svc.Refresh(u.ID, "2024-01-10")
+	assert.Len(t, repo.replaced, 1)
+	write := repo.replaced[0]
+	assert.Equal(t, u.ID, write.userID.String())
+	assert.Equal(t, "2024-01-08", write.from)
+	assert.Equal(t, "2024-01-14", write.to)
+	days := []string{}
+	for _, r := range write.rollups {
+		days = append(days, r.Day)
+	}
+	assert.Equal(t, []string{"2024-01-08", "2024-01-10"}, days)
+
+	// a Monday's session may carry over from Sunday night: both weeks are redone
+	svc.RefreshEntry(repo.entries[1].ID)
+	assert.Equal(t, "2024-01-01", repo.replaced[1].from)
+	assert.Equal(t, "2024-01-14", repo.replaced[1].to)
+
+	// a nil service is a no-op, so hooks don't need to check it
+	var none *RollupService
+	none.Refresh(u.ID, "2024-01-10")
+	none.RefreshEntry("x")
+}
+
+func TestRollupRebuildGoesWeekByWeek(t *testing.T) {
+	u := &model.User{ID: uuid.New().String()}
+	v := &model.User{ID: uuid.New().String()}
+	repo := &mockRollupRepo{entries: []*model.TimeTableEntry{
+		workSession(u, "2024-01-03", 9, 17),
+		workSession(v, "2024-01-03", 9, 17),
+		workSession(u, "2024-01-16", 9, 17),
+	}}
+	svc := NewRollupService(repo, NewKpiService(&mockKpiRepo{}))
+
+	written, err := svc.Rebuild("2024-01-03", "2024-01-16")
+	assert.NoError(t, err)
+	assert.Equal(t, 3, written)
+	assert.Len(t, repo.replaced, 3)
+	for _, w := range repo.replaced {
+		assert.Nil(t, w.userID, "everyone's rollups are rebuilt")
+	}
+	assert.Equal(t, "2024-01-01", repo.replaced[0].from)
+	assert.Equal(t, "2024-01-21", repo.replaced[2].to)
+
+	_, err = svc.Rebuild("2024-13-01", "2024-01-16")
+	assert.Error(t, err)
+	_, err = svc.GetDailyRollups(nil, nil, "2024-01-16", "2024-01-03")
+	assert.Error(t, err)
+}
+
+func TestAdminDashboardReadsRollups(t *testing.T) {
+	alice := &model.UserWithAllData{ID: uuid.New().String(), FirstName: "Alice", Teams: []*model.Team{{ID: "t1", Name: "Ops"}}}
+	bob := &model.UserWithAllData{ID: uuid.New().String(), FirstName: "Bob", Teams: []*model.Team{{ID: "t2", Name: "Dev"}}}
+	late := int32(12)
+	onTime := int32(0)
+	repo := &mockKpiRepo{
+		users: []*model.UserWithAllData{alice, bob},
+		teams: []*model.Team{{ID: "t1", Name: "Ops"}, {ID: "t2", Name: "Dev"}},
+		rollups: []*model.DailyRollup{
+			{UserID: alice.ID, UserName: "Alice", Day: "2024-01-08", WorkedMinutes: 480, OvertimeMinutes: 60, LateMinutes: &late, Sessions: 2},
+			{UserID: alice.ID, UserName: "Alice", Day: "2024-01-09", WorkedMinutes: 420, LateMinutes: &onTime, Sessions: 1},
+			{UserID: bob.ID, UserName: "Bob", Day: "2024-01-09", WorkedMinutes: 300, Sessions: 1, OpenSessions: 1},
+		},
+	}
+	svc := NewKpiService(repo)
+	repo.rebuilt = []*model.RollupCoverage{{From: "2024-01-01", Rules: svc.rollupRules()}}
+	from := time.Date(2024, 1, 8, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2024, 1, 9, 0, 0, 0, 0, time.UTC)
+
+	got, err := svc.GetAdminKpiDashboard(context.Background(), from, to, nil, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, int32(20), got.Summary.TotalWorkedHours)
+	assert.Equal(t, int32(2), got.Summary.TotalUsers)
+	assert.Equal(t, 0.75, got.Summary.ComplianceRate)
+	assert.Equal(t, int32(60), got.Overtime.TotalOvertimeMinutes)
+	assert.Equal(t, int32(1), got.Punctuality.TotalLateIncidents)
+	assert.Equal(t, 12.0, got.Punctuality.AvgLateMinutes)
+
+	teams := map[string]*model.TeamDetailedReport{}
+	for _, r := range got.Teams {
+		teams[r.TeamName] = r
+	}
+	assert.Equal(t, int32(900), teams["Ops"].TotalWorkedMinutes)
+	assert.Equal(t, int32(60), teams["Ops"].TopContributors[0].OvertimeMinutes)
+	assert.Equal(t, int32(2), teams["Ops"].TopContributors[0].DaysPresent)
+	assert.Equal(t, int32(300), teams["Dev"].TotalWorkedMinutes)
+}
+
+func TestDashboardFallsBackToEntriesOutsideCoverage(t *testing.T) {
+	alice := &model.User{ID: uuid.New().String(), FirstName: "Alice"}
+	// only Tuesday was rebuilt: Monday's session is missing from the table
+	repo := &mockKpiRepo{
+		entries: []*model.TimeTableEntry{
+			workSession(alice, "2024-01-08", 9, 17),
+			workSession(alice, "2024-01-09", 9, 12),
+		},
+		rollups: []*model.DailyRollup{
+			{UserID: alice.ID, UserName: "Alice", Day: "2024-01-09", WorkedMinutes: 180, Sessions: 1},
+		},
+	}
+	svc := NewKpiService(repo)
+	from := time.Date(2024, 1, 8, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2024, 1, 9, 0, 0, 0, 0, time.UTC)
+	worked := func() map[string]int {
+		figures, err := svc.dashboardFigures(nil, nil, from, to, time.Now(), model.KpiGranularityWeek)
+		require.NoError(t, err)
+		out := map[string]int{}
+		for _, d := range figures.days {
+			out[d.PeriodStart] = d.Minutes
+		}
+		return out
+	}
+
+	repo.rebuilt = []*model.RollupCoverage{{From: "2024-01-09", Rules: svc.rollupRules()}}
+	assert.Equal(t, map[string]int{"2024-01-08": 480, "2024-01-09": 180}, worked())
+
+	// rollups computed with other rules are not read either
+	repo.rebuilt = []*model.RollupCoverage{{From: "2024-01-01", Rules: "midnight=other"}}
+	assert.Equal(t, map[string]int{"2024-01-08": 480, "2024-01-09": 180}, worked())
+
+	repo.rebuilt = []*model.RollupCoverage{{From: "2024-01-01", Rules: svc.rollupRules()}}
+	assert.Equal(t, map[string]int{"2024-01-09": 180}, worked())
+}
+
+func TestRebuildMarksCoverage(t *testing.T) {
+	repo := &mockRollupRepo{}
+	kpi := NewKpiService(&mockKpiRepo{})
+	svc := NewRollupService(repo, kpi)
+	rules := kpi.rollupRules()
+
+	_, err := svc.Rebuild("2024-01-03", "2024-01-10")
+	require.NoError(t, err)
+	require.Len(t, repo.coverage, 1)
+	assert.Equal(t, "2024-01-01", repo.coverage[0].From)
+	assert.Equal(t, "2024-01-14", repo.coverage[0].To)
+	assert.Equal(t, rules, repo.coverage[0].Rules)
+
+	// a touching range is merged, a distant one kept apart
+	_, err = svc.Rebuild("2024-01-15", "2024-01-21")
+	require.NoError(t, err)
+	_, err = svc.Rebuild("2024-03-04", "2024-03-10")
+	require.NoError(t, err)
+	require.Len(t, repo.coverage, 2)
+	assert.Equal(t, "2024-01-21", repo.coverage[0].To)
+	assert.Equal(t, "2024-03-04", repo.coverage[1].From)
+}
+
+func TestTeamSummaryAndMetricsFromSums(t *testing.T) {
+	late := int32(20)
+	onTime := int32(0)
+	since := time.Date(2024, 1, 9, 14, 0, 0, 0, time.UTC)
+	hour := time.Date(2024, 1, 9, 9, 0, 0, 0, time.UTC)
+	repo := &mockKpiRepo{
+		rollups: []*model.DailyRollup{
+			{UserID: "u1", Day: "2024-01-08", WorkedMinutes: 480, OvertimeMinutes: 30, LateMinutes: &late},
+			{UserID: "u1", Day: "2024-01-09", WorkedMinutes: 240, LateMinutes: &onTime},
+			{UserID: "u2", Day: "2024-01-09", WorkedMinutes: 120, LateMinutes: &onTime, ActiveSince: &since, UpdatedAt: since},
+			{UserID: "u2", Day: "2024-01-14", WorkedMinutes: 0},
+		},
+		coverage: []*model.CoveragePoint{{Time: hour, Count: 2}},
+	}
+	svc := NewKpiService(repo)
+	repo.rebuilt = []*model.RollupCoverage{{From: "2024-01-01", Rules: svc.rollupRules()}}
+
+	got, err := svc.GetTeamKpiSummary(context.Background(), uuid.New(), time.Date(2024, 1, 8, 0, 0, 0, 0, time.UTC), time.Date(2024, 1, 14, 0, 0, 0, 0, time.UTC), nil, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, int32(1), got.ActiveUsers)
+	assert.Len(t, got.Coverage, 1, "the coverage is counted by the database")
+	assert.Equal(t, int32(2), got.Coverage[0].Count)
+	assert.Greater(t, got.TotalWorkedMinutes, int32(840), "the running session goes on")
+
+	now := since.Add(time.Hour)
+	figures := kpiFiguresOf(repo.rollups, "2024-01-01", "2024-01-31", now, model.KpiGranularityWeek)
+	workload := svc.computeWorkloadAnalysis(figures, model.KpiGranularityDay)
+	assert.Equal(t, "2024-01-08", workload.PeakDay)
+	assert.Equal(t, 900.0/2, workload.AvgDailyMinutes, "days without work don't count")
+	assert.Equal(t, int32(30), workload.TotalOvertime)
+	assert.Equal(t, int32(1), workload.UsersWithOvertime)
+	assert.Len(t, workload.DistributionByDay, 2)
+	assert.Equal(t, "Monday", workload.DistributionByDay[0].Day)
+
+	punctuality := svc.computePunctualityMetrics(figures)
+	assert.Equal(t, int32(1), punctuality.TotalLateIncidents)
+	assert.Equal(t, int32(2), punctuality.PunctualUsers)
+	assert.Equal(t, int32(1), punctuality.LateUsers)
+	assert.Len(t, punctuality.TrendByWeek, 1)
+	assert.Equal(t, "2024-01-08", punctuality.TrendByWeek[0].WeekStart)
+	assert.InDelta(t, 2.0/3, punctuality.TrendByWeek[0].OnTimeRate, 1e-9)
+}
+
+func TestTeamSummaryReadsEntriesUntilRebuilt(t *testing.T) {
+	alice := &model.User{ID: uuid.New().String(), FirstName: "Alice"}
+	repo := &mockKpiRepo{
+		entries: []*model.TimeTableEntry{
+			workSession(alice, "2024-01-08", 9, 17),
+			workSession(alice, "2024-01-09", 9, 12),
+		},
+		// a refresh wrote Tuesday before any rebuild
+		rollups: []*model.DailyRollup{{UserID: alice.ID, Day: "2024-01-09", WorkedMinutes: 180}},
+	}
+	svc := NewKpiService(repo)
+	repo.rebuilt = []*model.RollupCoverage{{From: "2024-01-09", Rules: svc.rollupRules()}}
+
+	got, err := svc.GetTeamKpiSummary(context.Background(), uuid.New(), time.Date(2024, 1, 8, 0, 0, 0, 0, time.UTC), time.Date(2024, 1, 9, 0, 0, 0, 0, time.UTC), nil, nil)
+	require.NoError(t, err)
+	assert.Equal(t, int32(660), got.TotalWorkedMinutes)
+}
+
+func TestDashboardSeriesFollowTheGranularity(t *testing.T) {
+	u := &model.UserWithAllData{ID: uuid.New().String(), FirstName: "Ada", Teams: []*model.Team{{ID: "t1", Name: "Ops"}}}
+	late := int32(10)
+	onTime := int32(0)
+	rollups := []*model.DailyRollup{
+		{UserID: u.ID, UserName: "Ada", Day: "2024-03-29", WorkedMinutes: 540, OvertimeMinutes: 120, LateMinutes: &late},
+		{UserID: u.ID, UserName: "Ada", Day: "2024-04-02", WorkedMinutes: 480, OvertimeMinutes: 60, LateMinutes: &onTime},
+		{UserID: u.ID, UserName: "Ada", Day: "2024-04-30", WorkedMinutes: 420, LateMinutes: &onTime},
+	}
+	repo := &mockKpiRepo{users: []*model.UserWithAllData{u}, teams: []*model.Team{{ID: "t1", Name: "Ops"}}, rollups: rollups}
+	svc := NewKpiService(repo)
+	repo.rebuilt = []*model.RollupCoverage{{From: "2024-01-01", Rules: svc.rollupRules()}}
+	from := time.Date(2024, 3, 25, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2024, 4, 30, 0, 0, 0, 0, time.UTC)
+
+	weekly, err := svc.GetAdminKpiDashboard(context.Background(), from, to, nil, nil)
+	assert.NoError(t, err)
+	assert.Len(t, weekly.Punctuality.TrendByWeek, 3)
+	assert.Equal(t, "2024-03-25", weekly.Punctuality.TrendByWeek[0].WeekStart)
+	assert.Len(t, weekly.Overtime.OvertimeByWeek, 2)
+	assert.Len(t, weekly.Productivity.ProductivityTrend, 3, "daily by default")
+	assert.Len(t, weekly.Workload.WorkedByPeriod, 3, "daily by default")
+	require.Len(t, weekly.Teams, 1)
+	assert.Equal(t, []*model.KpiPoint{
+		{Date: "2024-03-25", Minutes: 540}, {Date: "2024-04-01", Minutes: 480}, {Date: "2024-04-29", Minutes: 420},
+	}, weekly.Teams[0].WorkedByPeriod)
+
+	month := model.KpiGranularityMonth
+	monthly, err := svc.GetAdminKpiDashboard(context.Background(), from, to, &month, nil)
+	assert.NoError(t, err)
+	trend := monthly.Punctuality.TrendByWeek
+	assert.Len(t, trend, 2)
+	assert.Equal(t, "2024-03-01", trend[0].WeekStart)
+	assert.Equal(t, "2024-04-01", trend[1].WeekStart)
+	assert.Equal(t, 1.0, trend[1].OnTimeRate)
+	overtime := monthly.Overtime.OvertimeByWeek
+	assert.Len(t, overtime, 2)
+	assert.Equal(t, "2024-04-01", overtime[1].PeriodStart)
+	productivity := monthly.Productivity.ProductivityTrend
+	assert.Len(t, productivity, 2)
+	assert.Equal(t, int32(15), productivity[1].TotalHours)
+	assert.Equal(t, []*model.KpiPoint{{Date: "2024-03-01", Minutes: 540}, {Date: "2024-04-01", Minutes: 900}}, monthly.Workload.WorkedByPeriod)
+	assert.Equal(t, []*model.KpiPoint{{Date: "2024-03-01", Minutes: 540}, {Date: "2024-04-01", Minutes: 900}}, monthly.Teams[0].WorkedByPeriod)
+
+	quarter := model.KpiGranularityQuarter
+	team, err := svc.GetTeamKpiSummary(context.Background(), uuid.New(), from, to, &quarter, nil)
+	assert.NoError(t, err)
+	assert.Len(t, team.Coverage, 2)
+	assert.Equal(t, time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), team.Coverage[0].Time)
+	assert.Equal(t, int32(1), team.Coverage[1].Count)
+}
+
+func TestKpiDashboardScopedToATeam(t *testing.T) {
+	ops, dev := uuid.New(), uuid.New()
+	alice := &model.UserWithAllData{ID: uuid.New().String(), FirstName: "Alice", Teams: []*model.Team{{ID: ops.String()}}}
+	bob := &model.UserWithAllData{ID: uuid.New().String(), FirstName: "Bob", Teams: []*model.Team{{ID: dev.String()}}}
+	repo := &mockKpiRepo{
+		users: []*model.UserWithAllData{alice, bob},
+		teams: []*model.Team{{ID: ops.String(), Name: "Ops"}, {ID: dev.String(), Name: "Dev"}},
+	}
+	svc := NewKpiService(repo)
+	from := time.Date(2024, 1, 8, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2024, 1, 9, 0, 0, 0, 0, time.UTC)
+
+	got, err := svc.GetKpiDashboard(context.Background(), nil, &ops, from, to, nil, nil)
+	require.NoError(t, err)
+	assert.Equal(t, int32(1), got.Summary.TotalTeams)
+	require.Len(t, got.Teams, 1)
+	assert.Equal(t, "Ops", got.Teams[0].TeamName)
+	for _, m := range got.Compliance.MissingEntries {
+		assert.Equal(t, alice.ID, m.UserID, "only the team's members are expected")
+	}
+	assert.NotEmpty(t, got.Compliance.MissingEntries)
+
+	uid := uuid.MustParse(bob.ID)
+	own, err := svc.GetKpiDashboard(context.Background(), &uid, nil, from, to, nil, nil)
+	require.NoError(t, err)
+	require.Len(t, own.Teams, 1)
+	assert.Equal(t, "Dev", own.Teams[0].TeamName)
+}
+
+func TestTeamScheduleRefreshesItsMembersInTheBackground(t *testing.T) {
+	member := &model.User{ID: uuid.New().String()}
+	memberID := uuid.MustParse(member.ID)
+	repo := &mockRollupRepo{
+		entries: []*model.TimeTableEntry{workSession(member, "2024-01-10", 9, 17)},
+		members: []uuid.UUID{memberID},
+	}
+	rollups := NewRollupService(repo, NewKpiService(&mockKpiRepo{}))
+	repo.coverage = []*model.RollupCoverage{{From: "2024-01-01", Rules: rollups.Kpi.rollupRules()}}
+	teamID := uuid.New().String()
+	schedules := NewScheduleService(&mockScheduleRepo{schedules: []*model.WorkSchedule{
+		{ID: "ws", TeamID: &teamID, EffectiveFrom: "2024-01-10", EffectiveTo: ptrString("2024-01-12")},
+	}})
+	schedules.Rollups = rollups
+
+	_, err := schedules.DeleteWorkSchedule("ws")
+	require.NoError(t, err)
+	rollups.Wait()
+	require.Len(t, repo.replaced, 1, "only the members are refreshed, not everyone")
+	require.NotNil(t, repo.replaced[0].userID)
+	assert.Equal(t, memberID, *repo.replaced[0].userID)
+	assert.Equal(t, "2024-01-08", repo.replaced[0].from)
+	assert.Equal(t, "2024-01-14", repo.replaced[0].to)
+}
+
+func TestRebuildCoveredDropsThenRebuildsTheCoverage(t *testing.T) {
+	repo := &mockRollupRepo{}
+	rollups := NewRollupService(repo, NewKpiService(&mockKpiRepo{}))
+	repo.coverage = []*model.RollupCoverage{{From: "2024-01-01", To: "2024-01-14", Rules: "midnight=other"}}
+
+	rollups.RebuildCovered()
+	rollups.Wait()
+	require.Len(t, repo.coverage, 1)
+	assert.Equal(t, model.RollupCoverage{From: "2024-01-01", To: "2024-01-14", Rules: rollups.Kpi.rollupRules()}, *repo.coverage[0])
+	assert.Len(t, repo.replaced, 2, "one rebuild per week")
+}
@@ -0,0 +1,418 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/epitech/timemanager/internal/graph/model"
+	"github.com/epitech/timemanager/package/timezone"
+	"github.com/google/uuid"
+)
+
+const layoutClock = "15:04"
+
+// ScheduleRepository is the minimal repository contract used by ScheduleService.
+type ScheduleRepository interface {
+	GetTimeTables() ([]*model.TimeTable, error)
+	GetActiveTimeTable() (*model.TimeTable, error)
+	GetWorkSchedules(userID *uuid.UUID, teamID *uuid.UUID) ([]*model.WorkSchedule, error)
+	GetWorkSchedule(id string) (*model.WorkSchedule, error)
+	// GetUserWorkSchedules returns the schedules of the user and of all their teams.
+	GetUserWorkSchedules(userID uuid.UUID) ([]*model.WorkSchedule, error)
+	CreateWorkSchedule(input model.AssignWorkScheduleInput) (*model.WorkSchedule, error)
+	DeleteWorkSchedule(id string) (bool, error)
+	GetUserTimeZone(userID uuid.UUID) (string, error)
+	GetUserHolidays(userID uuid.UUID, from, to string) ([]*model.Holiday, error)
+	// The batch lookups below key their results by user ID.
+	GetUsersWorkSchedules(userIDs []uuid.UUID) (map[string][]*model.WorkSchedule, error)
+	GetUsersTimeZones(userIDs []uuid.UUID) (map[string]string, error)
+	GetUsersHolidays(userIDs []uuid.UUID, from, to string) (map[string][]*model.Holiday, error)
+}
+
+type ScheduleService struct {
+	Repo ScheduleRepository
+	// Rollups is refreshed over the days of a changed schedule; optional.
+	Rollups *RollupService
+	// Lateness rates again the arrivals of those days; optional.
+	Lateness *LatenessService
+}
+
+func NewScheduleService(repo ScheduleRepository) *ScheduleService {
+	return &ScheduleService{Repo: repo}
+}
+
+func (s *ScheduleService) GetTimeTables() ([]*model.TimeTable, error) {
+	return s.Repo.GetTimeTables()
+}
+
+func (s *ScheduleService) GetWorkSchedules(userID *uuid.UUID, teamID *uuid.UUID) ([]*model.WorkSchedule, error) {
+	return s.Repo.GetWorkSchedules(userID, teamID)
+}
+
+// AssignWorkSchedule validates and stores a weekly schedule for a user or a team.
+func (s *ScheduleService) AssignWorkSchedule(input model.AssignWorkScheduleInput) (*model.WorkSchedule, error) {
+	hasUser := input.UserID != nil && *input.UserID != ""
+	hasTeam := input.TeamID != nil && *input.TeamID != ""
+	if hasUser == hasTeam {
+		return nil, errors.New("a schedule is assigned to exactly one user or one team")
+	}
+	if _, err := time.Parse(layoutISO, input.EffectiveFrom); err != nil {
+		return nil, errors.New("invalid effectiveFrom, expected YYYY-MM-DD")
+	}
+	if input.EffectiveTo != nil {
+		if _, err := time.Parse(layoutISO, *input.EffectiveTo); err != nil {
+			return nil, errors.New("invalid effectiveTo, expected YYYY-MM-DD")
+		}
+		if *input.EffectiveTo < input.EffectiveFrom {
+			return nil, errors.New("effectiveTo must not be before effectiveFrom")
+		}
+	}
+	seen := make(map[model.Weekday]struct{}, len(input.Days))
+	for _, d := range input.Days {
+		if d == nil || !d.Weekday.IsValid() {
+			return nil, errors.New("invalid weekday")
+		}
+		if _, dup := seen[d.Weekday]; dup {
+			return nil, fmt.Errorf("%s is given twice", d.Weekday)
+		}
+		seen[d.Weekday] = struct{}{}
+		start, err := time.Parse(layoutClock, d.Start)
+		if err != nil {
+			return nil, errors.New("invalid start time format, expected : HH:mm")
+		}
+		end, err := time.Parse(layoutClock, d.End)
+		if err != nil {
+			return nil, errors.New("invalid end time format, expected : HH:mm")
+		}
+		if start.Equal(end) {
+			return nil, fmt.Errorf("%s starts and ends at the same time", d.Weekday)
+		}
+		if d.CoreStart != nil && *d.CoreStart != "" {
+			core, err := time.Parse(layoutClock, *d.CoreStart)
+			if err != nil {
+				return nil, errors.New("invalid coreStart time format, expected : HH:mm")
+			}
+			if clockOffset(start, core) > clockOffset(start, end) {
+				return nil, fmt.Errorf("%s's core hours start after the end of the day", d.Weekday)
+			}
+		}
+	}
+	ws, err := s.Repo.CreateWorkSchedule(input)
+	if err != nil {
+		return nil, err
+	}
+	s.refreshRollups(ws)
+	return ws, nil
+}
+
+func (s *ScheduleService) DeleteWorkSchedule(id string) (bool, error) {
+	ws, err := s.Repo.GetWorkSchedule(id)
+	if err != nil {
+		return false, err
+	}
+	ok, err := s.Repo.DeleteWorkSchedule(id)
+	if err != nil {
+		return false, err
+	}
+	s.refreshRollups(ws)
+	return ok, nil
+}
+
+// refreshRollups recomputes in the background the rollups, and rates again
+// the arrivals, of the days the schedule applies to: its user's, or its team
+// members'.
+func (s *ScheduleService) refreshRollups(ws *model.WorkSchedule) {
+	if (s.Rollups == nil && s.Lateness == nil) || ws == nil {
+		return
+	}
+	to := ""
+	if ws.EffectiveTo != nil {
+		to = *ws.EffectiveTo
+	}
+	if ws.UserID != nil {
+		if uid, err := uuid.Parse(*ws.UserID); err == nil {
+			s.Rollups.RefreshUsers([]uuid.UUID{uid}, ws.EffectiveFrom, to)
+			s.Lateness.RederiveUsers([]uuid.UUID{uid}, ws.EffectiveFrom, to)
+		}
+		return
+	}
+	if ws.TeamID != nil {
+		if teamID, err := uuid.Parse(*ws.TeamID); err == nil {
+			s.Rollups.RefreshTeam(teamID, ws.EffectiveFrom, to)
+			s.Lateness.RederiveTeam(teamID, ws.EffectiveFrom, to)
+		}
+	}
+}
+
+// PlannedSchedule resolves the planned hours of a user on a day (YYYY-MM-DD).
+func (s *ScheduleService) PlannedSchedule(userID uuid.UUID, day string) (*model.PlannedSchedule, error) {
+	if _, err := time.Parse(layoutISO, day); err != nil {
+		return nil, errors.New("invalid date, expected YYYY-MM-DD")
+	}
+	return s.NewPlanner().For(userID.String(), day)
+}
+
+// Today returns the current day in the user's time zone.
+func (s *ScheduleService) Today(userID uuid.UUID) (string, error) {
+	tz, err := s.Repo.GetUserTimeZone(userID)
+	if err != nil {
+		return "", err
+	}
+	return time.Now().In(timezone.Resolve(tz)).Format(layoutISO), nil
+}
+
+// Planner resolves planned schedules, caching the lookups of each user; use one
+// per request, as schedules may change between requests. Holidays are cached
+// per user and year, so a lookup never reads more than the year of the day.
+type Planner struct {
+	repo       ScheduleRepository
+	schedules  map[string][]*model.WorkSchedule
+	holidays   map[holidayYear]map[string]string
+	locations  map[string]*time.Location
+	timeTable  *model.TimeTable
+	ttResolved bool
+}
+
+func (s *ScheduleService) NewPlanner() *Planner {
+	return &Planner{
+		repo:      s.Repo,
+		schedules: make(map[string][]*model.WorkSchedule),
+		holidays:  make(map[holidayYear]map[string]string),
+		locations: make(map[string]*time.Location),
+	}
+}
+
+// holidayYear keys the holiday cache of a planner.
+type holidayYear struct {
+	userID string
+	year   string
+}
+
+// Preload loads in one batch the schedules, time zones and holidays of the
+// users over the years spanned by from and to (YYYY-MM-DD), so that For does
+// not query the repository user by user. Users already loaded are skipped.
+func (p *Planner) Preload(userIDs []string, from, to string) error {
+	if len(from) < 4 || len(to) < 4 || to < from {
+		return errors.New("invalid preload window")
+	}
+	firstYear, lastYear := from[:4], to[:4]
+	years := make([]string, 0, 1)
+	for y := firstYear; y <= lastYear; y = nextYear(y) {
+		years = append(years, y)
+	}
+	uids := make([]uuid.UUID, 0, len(userIDs))
+	for _, userID := range userIDs {
+		if _, ok := p.schedules[userID]; ok {
+			continue
+		}
+		uid, err := uuid.Parse(userID)
+		if err != nil {
+			return errors.New("invalid userID")
+		}
+		uids = append(uids, uid)
+	}
+	if len(uids) == 0 {
+		return nil
+	}
+	schedules, err := p.repo.GetUsersWorkSchedules(uids)
+	if err != nil {
+		return err
+	}
+	zones, err := p.repo.GetUsersTimeZones(uids)
+	if err != nil {
+		return err
+	}
+	holidays, err := p.repo.GetUsersHolidays(uids, firstYear+"-01-01", lastYear+"-12-31")
+	if err != nil {
+		return err
+	}
+	for _, uid := range uids {
+		userID := uid.String()
+		p.schedules[userID] = schedules[userID]
+		p.locations[userID] = timezone.Resolve(zones[userID])
+		for _, y := range years {
+			p.holidays[holidayYear{userID, y}] = make(map[string]string)
+		}
+		for _, h := range holidays[userID] {
+			if len(h.Date) < 4 {
+				continue
+			}
+			if byDay, ok := p.holidays[holidayYear{userID, h.Date[:4]}]; ok {
+				byDay[h.Date] = h.Name
+			}
+		}
+	}
+	return nil
+}
+
+// nextYear returns the year after y (YYYY).
+func nextYear(y string) string {
+	year, err := time.Parse("2006", y)
+	if err != nil {
+		return "9999"
+	}
+	return year.AddDate(1, 0, 0).Format("2006")
+}
+
+// For returns the planned schedule of a user on a day. User schedules take
+// precedence over team schedules, then the global timetable applies on
+// weekdays; the most recent schedule in effect wins. Nobody works on the
+// public holidays of their site or teams.
+func (p *Planner) For(userID string, day string) (*model.PlannedSchedule, error) {
+	uid, err := uuid.Parse(userID)
+	if err != nil {
+		return nil, errors.New("invalid userID")
+	}
+	schedules, ok := p.schedules[userID]
+	if !ok {
+		if schedules, err = p.repo.GetUserWorkSchedules(uid); err != nil {
+			return nil, err
+		}
+		p.schedules[userID] = schedules
+	}
+	if len(day) < 4 {
+		return nil, errors.New("invalid date, expected YYYY-MM-DD")
+	}
+	key := holidayYear{userID, day[:4]}
+	holidays, ok := p.holidays[key]
+	if !ok {
+		list, err := p.repo.GetUserHolidays(uid, key.year+"-01-01", key.year+"-12-31")
+		if err != nil {
+			return nil, err
+		}
+		holidays = make(map[string]string, len(list))
+		for _, h := range list {
+			holidays[h.Date] = h.Name
+		}
+		p.holidays[key] = holidays
+	}
+	loc, ok := p.locations[userID]
+	if !ok {
+		tz, err := p.repo.GetUserTimeZone(uid)
+		if err != nil {
+			return nil, err
+		}
+		loc = timezone.Resolve(tz)
+		p.locations[userID] = loc
+	}
+	if !p.ttResolved {
+		if p.timeTable, err = p.repo.GetActiveTimeTable(); err != nil {
+			return nil, err
+		}
+		p.ttResolved = true
+	}
+	planned := resolvePlanned(userID, day, schedules, p.timeTable, loc)
+	if name, ok := holidays[day]; ok {
+		planned.Holiday = &name
+		planned.WorkingDay = false
+		planned.Start = nil
+		planned.End = nil
+		planned.PlannedMinutes = 0
+	}
+	return planned, nil
+}
+
+// resolvePlanned picks the schedule in effect on day and computes its hours.
+func resolvePlanned(userID, day string, schedules []*model.WorkSchedule, fallback *model.TimeTable, loc *time.Location) *model.PlannedSchedule {
+	out := &model.PlannedSchedule{UserID: userID, Date: day, Source: model.ScheduleSourceNone}
+	date, err := time.ParseInLocation(layoutISO, day, loc)
+	if err != nil {
+		return out
+	}
+	weekday := graphWeekday(date.Weekday())
+
+	if ws := latestInEffect(schedules, day, true); ws != nil {
+		out.Source = model.ScheduleSourceUser
+		applyScheduleDay(out, ws, weekday, date)
+		return out
+	}
+	if ws := latestInEffect(schedules, day, false); ws != nil {
+		out.Source = model.ScheduleSourceTeam
+		applyScheduleDay(out, ws, weekday, date)
+		return out
+	}
+	if fallback != nil {
+		out.Source = model.ScheduleSourceDefault
+		out.ScheduleID = &fallback.ID
+		if date.Weekday() != time.Saturday && date.Weekday() != time.Sunday {
+			setPlannedHours(out, date, fallback.Start, fallback.Ends)
+		}
+	}
+	return out
+}
+
+func latestInEffect(schedules []*model.WorkSchedule, day string, userLevel bool) *model.WorkSchedule {
+	var candidates []*model.WorkSchedule
+	for _, ws := range schedules {
+		if (ws.UserID != nil) != userLevel {
+			continue
+		}
+		if ws.EffectiveFrom > day || (ws.EffectiveTo != nil && *ws.EffectiveTo < day) {
+			continue
+		}
+		candidates = append(candidates, ws)
+	}
+	if len(candidates) == 0 {
+		return nil
+	}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].EffectiveFrom > candidates[j].EffectiveFrom
+	})
+	return candidates[0]
+}
+
+func applyScheduleDay(out *model.PlannedSchedule, ws *model.WorkSchedule, weekday model.Weekday, date time.Time) {
+	out.ScheduleID = &ws.ID
+	for _, d := range ws.Days {
+		if d == nil || d.Weekday != weekday {
+			continue
+		}
+		start, errS := time.Parse(layoutClock, d.Start)
+		end, errE := time.Parse(layoutClock, d.End)
+		if errS == nil && errE == nil {
+			setPlannedHours(out, date, start, end)
+		}
+		if d.CoreStart != nil && out.Start != nil {
+			if core, err := time.Parse(layoutClock, *d.CoreStart); err == nil {
+				coreStart := time.Date(date.Year(), date.Month(), date.Day(), core.Hour(), core.Minute(), 0, 0, date.Location())
+				if coreStart.Before(*out.Start) {
+					coreStart = coreStart.AddDate(0, 0, 1)
+				}
+				out.CoreStart = &coreStart
+			}
+		}
+		out.Remote = d.Remote
+		return
+	}
+}
+
+// setPlannedHours places the clock times on date; an end before the start is
+// on the next day (night shift).
+func setPlannedHours(out *model.PlannedSchedule, date time.Time, startClock, endClock time.Time) {
+	start := time.Date(date.Year(), date.Month(), date.Day(), startClock.Hour(), startClock.Minute(), 0, 0, date.Location())
+	end := time.Date(date.Year(), date.Month(), date.Day(), endClock.Hour(), endClock.Minute(), 0, 0, date.Location())
+	if !end.After(start) {
+		end = end.AddDate(0, 0, 1)
+	}
+	out.WorkingDay = true
+	out.Start = &start
+	out.End = &end
+	out.PlannedMinutes = int32(end.Sub(start).Minutes())
+}
+
+// clockOffset is the time from one clock time to the next occurrence of the
+// other, the next day when it is earlier.
+func clockOffset(from, to time.Time) time.Duration {
+	d := to.Sub(from)
+	if d < 0 {
+		d += 24 * time.Hour
+	}
+	return d
+}
+
+// graphWeekday converts a Go weekday (Sunday first) to the GraphQL enum (Monday first).
+func graphWeekday(d time.Weekday) model.Weekday {
+	return model.AllWeekday[(int(d)+6)%7]
+}
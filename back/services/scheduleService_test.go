@@ -0,0 +1,264 @@
+package services
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/epitech/timemanager/internal/graph/model"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type mockScheduleRepo struct {
+	schedules []*model.WorkSchedule
+	timeTable *model.TimeTable
+	timeZone  string
+	holidays  []*model.Holiday
+	created   *model.AssignWorkScheduleInput
+	lookups   int
+	batches   int
+	bounds    [2]string
+	err       error
+}
+
+func (m *mockScheduleRepo) GetTimeTables() ([]*model.TimeTable, error) { return nil, m.err }
+
+func (m *mockScheduleRepo) GetActiveTimeTable() (*model.TimeTable, error) { return m.timeTable, m.err }
+
+func (m *mockScheduleRepo) GetWorkSchedules(userID *uuid.UUID, teamID *uuid.UUID) ([]*model.WorkSchedule, error) {
+	return m.schedules, m.err
+}
+
+func (m *mockScheduleRepo) GetUserWorkSchedules(userID uuid.UUID) ([]*model.WorkSchedule, error) {
+	m.lookups++
+	return m.schedules, m.err
+}
+
+func (m *mockScheduleRepo) CreateWorkSchedule(input model.AssignWorkScheduleInput) (*model.WorkSchedule, error) {
+	m.created = &input
+	return &model.WorkSchedule{ID: "ws"}, m.err
+}
+
+func (m *mockScheduleRepo) DeleteWorkSchedule(id string) (bool, error) {
+	kept := make([]*model.WorkSchedule, 0, len(m.schedules))
+	for _, ws := range m.schedules {
+		if ws.ID != id {
+			kept = append(kept, ws)
+		}
+	}
+	m.schedules = kept
+	return true, m.err
+}
+
+func (m *mockScheduleRepo) GetWorkSchedule(id string) (*model.WorkSchedule, error) {
+	for _, ws := range m.schedules {
+		if ws.ID == id {
+			return ws, m.err
+		}
+	}
+	return nil, errors.New("work schedule not found")
+}
+
+func (m *mockScheduleRepo) GetUserTimeZone(userID uuid.UUID) (string, error) {
+	return m.timeZone, m.err
+}
+
+func (m *mockScheduleRepo) GetUserHolidays(userID uuid.UUID, from, to string) ([]*model.Holiday, error) {
+	m.bounds = [2]string{from, to}
+	return m.holidaysWithin(from, to), m.err
+}
+
+func (m *mockScheduleRepo) GetUsersWorkSchedules(userIDs []uuid.UUID) (map[string][]*model.WorkSchedule, error) {
+	m.batches++
+	out := make(map[string][]*model.WorkSchedule, len(userIDs))
+	for _, uid := range userIDs {
+		out[uid.String()] = m.schedules
+	}
+	return out, m.err
+}
+
+func (m *mockScheduleRepo) GetUsersTimeZones(userIDs []uuid.UUID) (map[string]string, error) {
+	out := make(map[string]string, len(userIDs))
+	for _, uid := range userIDs {
+		out[uid.String()] = m.timeZone
+	}
+	return out, m.err
+}
+
+func (m *mockScheduleRepo) GetUsersHolidays(userIDs []uuid.UUID, from, to string) (map[string][]*model.Holiday, error) {
+	m.bounds = [2]string{from, to}
+	out := make(map[string][]*model.Holiday, len(userIDs))
+	for _, uid := range userIDs {
+		out[uid.String()] = m.holidaysWithin(from, to)
+	}
+	return out, m.err
+}
+
+func (m *mockScheduleRepo) holidaysWithin(from, to string) []*model.Holiday {
+	var out []*model.Holiday
+	for _, h := range m.holidays {
+		if (from == "" || h.Date >= from) && (to == "" || h.Date <= to) {
+			out = append(out, h)
+		}
+	}
+	return out
+}
+
+func TestAssignWorkScheduleValidation(t *testing.T) {
+	repo := &mockScheduleRepo{}
+	svc := NewScheduleService(repo)
+	uid := uuid.New().String()
+	day := []*model.WorkScheduleDayInput{{Weekday: model.WeekdayMonday, Start: "09:00", End: "17:00"}}
+
+	_, err := svc.AssignWorkSchedule(model.AssignWorkScheduleInput{EffectiveFrom: "2024-01-01", Days: day})
+	assert.Error(t, err, "needs a user or a team")
+	_, err = svc.AssignWorkSchedule(model.AssignWorkScheduleInput{UserID: &uid, TeamID: &uid, EffectiveFrom: "2024-01-01", Days: day})
+	assert.Error(t, err, "not both")
+	_, err = svc.AssignWorkSchedule(model.AssignWorkScheduleInput{UserID: &uid, EffectiveFrom: "2024-02-01", EffectiveTo: ptrString("2024-01-01"), Days: day})
+	assert.Error(t, err)
+	twice := append(day, &model.WorkScheduleDayInput{Weekday: model.WeekdayMonday, Start: "10:00", End: "18:00"})
+	_, err = svc.AssignWorkSchedule(model.AssignWorkScheduleInput{UserID: &uid, EffectiveFrom: "2024-01-01", Days: twice})
+	assert.Error(t, err)
+	_, err = svc.AssignWorkSchedule(model.AssignWorkScheduleInput{UserID: &uid, EffectiveFrom: "2024-01-01",
+		Days: []*model.WorkScheduleDayInput{{Weekday: model.WeekdayMonday, Start: "9h", End: "17:00"}}})
+	assert.Error(t, err)
+	_, err = svc.AssignWorkSchedule(model.AssignWorkScheduleInput{UserID: &uid, EffectiveFrom: "2024-01-01",
+		Days: []*model.WorkScheduleDayInput{{Weekday: model.WeekdayMonday, Start: "09:00", End: "17:00", CoreStart: ptrString("18:00")}}})
+	assert.Error(t, err, "core hours after the end of the day")
+	assert.Nil(t, repo.created)
+
+	_, err = svc.AssignWorkSchedule(model.AssignWorkScheduleInput{UserID: &uid, EffectiveFrom: "2024-01-01",
+		Days: []*model.WorkScheduleDayInput{{Weekday: model.WeekdayMonday, Start: "22:00", End: "06:00", CoreStart: ptrString("00:30")}}})
+	assert.NoError(t, err, "night shift core hours after midnight")
+
+	_, err = svc.AssignWorkSchedule(model.AssignWorkScheduleInput{UserID: &uid, EffectiveFrom: "2024-01-01", Days: day})
+	assert.NoError(t, err)
+	assert.NotNil(t, repo.created)
+}
+
+func TestPlannedScheduleUserOverridesTeam(t *testing.T) {
+	uid := uuid.New()
+	team := &model.WorkSchedule{ID: "team", TeamID: ptrString("t1"), EffectiveFrom: "2024-01-01",
+		Days: []*model.WorkScheduleDay{{Weekday: model.WeekdayWednesday, Start: "08:00", End: "16:00"}}}
+	user := &model.WorkSchedule{ID: "user", UserID: ptrString(uid.String()), EffectiveFrom: "2024-02-01",
+		Days: []*model.WorkScheduleDay{{Weekday: model.WeekdayWednesday, Start: "10:00", End: "14:00"}}}
+	svc := NewScheduleService(&mockScheduleRepo{schedules: []*model.WorkSchedule{team, user}, timeZone: "Europe/Paris"})
+
+	// before the user schedule takes effect, the team's applies
+	got, err := svc.PlannedSchedule(uid, "2024-01-10")
+	assert.NoError(t, err)
+	assert.Equal(t, model.ScheduleSourceTeam, got.Source)
+	assert.Equal(t, int32(480), got.PlannedMinutes)
+	paris, _ := time.LoadLocation("Europe/Paris")
+	assert.Equal(t, time.Date(2024, 1, 10, 8, 0, 0, 0, paris), *got.Start)
+
+	got, err = svc.PlannedSchedule(uid, "2024-02-07")
+	assert.NoError(t, err)
+	assert.Equal(t, model.ScheduleSourceUser, got.Source)
+	assert.Equal(t, "user", *got.ScheduleID)
+	assert.Equal(t, int32(240), got.PlannedMinutes)
+
+	// Thursday is not in the user schedule: rest day
+	got, err = svc.PlannedSchedule(uid, "2024-02-08")
+	assert.NoError(t, err)
+	assert.Equal(t, model.ScheduleSourceUser, got.Source)
+	assert.False(t, got.WorkingDay)
+	assert.Equal(t, int32(0), got.PlannedMinutes)
+}
+
+func TestPlannedScheduleNightShiftAndDefault(t *testing.T) {
+	uid := uuid.New()
+	night := &model.WorkSchedule{ID: "n", UserID: ptrString(uid.String()), EffectiveFrom: "2024-01-01", EffectiveTo: ptrString("2024-01-31"),
+		Days: []*model.WorkScheduleDay{{Weekday: model.WeekdayMonday, Start: "22:00", End: "06:00"}}}
+	repo := &mockScheduleRepo{
+		schedules: []*model.WorkSchedule{night},
+		timeTable: &model.TimeTable{ID: "tt", Start: time.Date(0, 1, 1, 9, 0, 0, 0, time.UTC), Ends: time.Date(0, 1, 1, 17, 0, 0, 0, time.UTC)},
+		timeZone:  "UTC",
+	}
+	svc := NewScheduleService(repo)
+
+	got, err := svc.PlannedSchedule(uid, "2024-01-15")
+	assert.NoError(t, err)
+	assert.Equal(t, int32(480), got.PlannedMinutes)
+	assert.Equal(t, time.Date(2024, 1, 16, 6, 0, 0, 0, time.UTC), *got.End)
+
+	// after the schedule ends, the global timetable applies on weekdays only
+	got, err = svc.PlannedSchedule(uid, "2024-02-05")
+	assert.NoError(t, err)
+	assert.Equal(t, model.ScheduleSourceDefault, got.Source)
+	assert.True(t, got.WorkingDay)
+	got, err = svc.PlannedSchedule(uid, "2024-02-04")
+	assert.NoError(t, err)
+	assert.False(t, got.WorkingDay)
+
+	_, err = svc.PlannedSchedule(uid, "05/02/2024")
+	assert.Error(t, err)
+}
+
+func TestKpiUsesPlannedHours(t *testing.T) {
+	uid := uuid.New()
+	ws := &model.WorkSchedule{ID: "ws", UserID: ptrString(uid.String()), EffectiveFrom: "2024-01-01",
+		Days: []*model.WorkScheduleDay{
+			{Weekday: model.WeekdayMonday, Start: "06:00", End: "10:00"},
+			{Weekday: model.WeekdayTuesday, Start: "13:00", End: "21:00"},
+		}}
+	repo := &mockScheduleRepo{schedules: []*model.WorkSchedule{ws}, timeZone: "UTC"}
+	u := &model.User{ID: uid.String()}
+	mon := time.Date(2024, 1, 15, 6, 0, 0, 0, time.UTC)
+	monDep := mon.Add(6 * time.Hour)
+	tue := time.Date(2024, 1, 16, 13, 5, 0, 0, time.UTC)
+	tueDep := tue.Add(6 * time.Hour)
+	entries := []*model.TimeTableEntry{
+		{UserID: u, Day: "2024-01-15", Arrival: mon, Departure: &monDep},
+		{UserID: u, Day: "2024-01-16", Arrival: tue, Departure: &tueDep},
+	}
+	svc := NewKpiService(&mockKpiRepo{entries: entries})
+	svc.Schedules = NewScheduleService(repo)
+
+	report := overtimeReportOf(svc, svc.dailyRollups(entries, time.Now()), model.KpiGranularityWeek)
+	// Monday: 6h worked for 4h planned; Tuesday is under its 8h
+	assert.Equal(t, int32(120), report.TotalOvertimeMinutes)
+
+	// arriving 13:05 for a 13:00 start is on time; 10:00 no longer matters
+	repo.lookups = 0
+	punctual, total := svc.planning().punctuality(entries)
+	assert.Equal(t, 2, punctual)
+	assert.Equal(t, 2, total)
+	assert.Equal(t, 1, repo.lookups, "schedules are fetched once per user")
+}
+
+func TestPlannerPreloadsUsersInOneBatch(t *testing.T) {
+	days := []*model.WorkScheduleDay{{Weekday: model.WeekdayMonday, Start: "09:00", End: "17:00"}}
+	repo := &mockScheduleRepo{
+		schedules: []*model.WorkSchedule{{ID: "ws", EffectiveFrom: "2024-01-01", Days: days}},
+		timeZone:  "UTC",
+		holidays: []*model.Holiday{
+			{Date: "2023-12-25", Name: "Christmas"},
+			{Date: "2024-01-01", Name: "New Year"},
+		},
+	}
+	planner := NewScheduleService(repo).NewPlanner()
+	users := []string{uuid.NewString(), uuid.NewString(), uuid.NewString()}
+
+	require.NoError(t, planner.Preload(users, "2024-01-01", "2024-01-31"))
+	assert.Equal(t, [2]string{"2024-01-01", "2024-12-31"}, repo.bounds, "holidays are read for the years of the window only")
+	for _, userID := range users {
+		planned, err := planner.For(userID, "2024-01-08")
+		require.NoError(t, err)
+		assert.Equal(t, int32(480), planned.PlannedMinutes)
+		holiday, err := planner.For(userID, "2024-01-01")
+		require.NoError(t, err)
+		require.NotNil(t, holiday.Holiday)
+		assert.False(t, holiday.WorkingDay)
+	}
+	assert.Equal(t, 1, repo.batches)
+	assert.Equal(t, 0, repo.lookups, "preloaded users are not looked up one by one")
+
+	// a day outside the preloaded years reads its own year only
+	planned, err := planner.For(users[0], "2023-12-25")
+	require.NoError(t, err)
+	require.NotNil(t, planned.Holiday)
+	assert.Equal(t, [2]string{"2023-01-01", "2023-12-31"}, repo.bounds)
+}
@@ -0,0 +1,79 @@
+package services
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/epitech/timemanager/internal/graph/model"
+	"github.com/epitech/timemanager/package/timezone"
+	"github.com/google/uuid"
+)
+
+// SiteRepository is the minimal contract used by SiteService.
+type SiteRepository interface {
+	GetSites() ([]*model.Site, error)
+	CreateSite(input model.SiteInput) (*model.Site, error)
+	UpdateSite(id string, input model.SiteInput) (*model.Site, error)
+}
+
+type SiteService struct {
+	Repo SiteRepository
+	// Rollups is refreshed for the site's users when its time zone changes;
+	// optional.
+	Rollups *RollupService
+}
+
+func NewSiteService(repo SiteRepository) *SiteService {
+	return &SiteService{Repo: repo}
+}
+
+func (s *SiteService) GetSites() ([]*model.Site, error) {
+	return s.Repo.GetSites()
+}
+
+func (s *SiteService) CreateSite(input model.SiteInput) (*model.Site, error) {
+	if err := validateSiteInput(&input); err != nil {
+		return nil, err
+	}
+	return s.Repo.CreateSite(input)
+}
+
+func (s *SiteService) UpdateSite(id string, input model.SiteInput) (*model.Site, error) {
+	if err := validateSiteInput(&input); err != nil {
+		return nil, err
+	}
+	previous := ""
+	if s.Rollups != nil {
+		if sites, err := s.Repo.GetSites(); err == nil {
+			for _, site := range sites {
+				if site.ID == id {
+					previous = site.TimeZone
+				}
+			}
+		}
+	}
+	site, err := s.Repo.UpdateSite(id, input)
+	if err != nil {
+		return nil, err
+	}
+	if site.TimeZone != previous {
+		if siteID, err := uuid.Parse(site.ID); err == nil {
+			s.Rollups.RefreshSite(siteID)
+		}
+	}
+	return site, nil
+}
+
+// validateSiteInput trims the input and requires a valid IANA time zone.
+func validateSiteInput(input *model.SiteInput) error {
+	input.Name = strings.TrimSpace(input.Name)
+	input.TimeZone = strings.TrimSpace(input.TimeZone)
+	if input.Name == "" {
+		return errors.New("site name is required")
+	}
+	if input.TimeZone == "" {
+		return errors.New("site time zone is required")
+	}
+	_, err := timezone.Load(input.TimeZone)
+	return err
+}
@@ -0,0 +1,46 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/epitech/timemanager/internal/graph/model"
+	"github.com/stretchr/testify/assert"
+)
+
+type mockSiteRepo struct {
+	created *model.SiteInput
+	updated *model.SiteInput
+	err     error
+}
+
+func (m *mockSiteRepo) GetSites() ([]*model.Site, error) { return nil, m.err }
+
+func (m *mockSiteRepo) CreateSite(input model.SiteInput) (*model.Site, error) {
+	m.created = &input
+	return &model.Site{ID: "1", Name: input.Name, TimeZone: input.TimeZone}, m.err
+}
+
+func (m *mockSiteRepo) UpdateSite(id string, input model.SiteInput) (*model.Site, error) {
+	m.updated = &input
+	return &model.Site{ID: id, Name: input.Name, TimeZone: input.TimeZone}, m.err
+}
+
+func TestSiteServiceCreateSiteTrimsInput(t *testing.T) {
+	repo := &mockSiteRepo{}
+	got, err := NewSiteService(repo).CreateSite(model.SiteInput{Name: " Montréal ", TimeZone: " America/Montreal "})
+	assert.NoError(t, err)
+	assert.Equal(t, "Montréal", got.Name)
+	assert.Equal(t, "America/Montreal", got.TimeZone)
+}
+
+func TestSiteServiceRejectsUnknownTimeZone(t *testing.T) {
+	repo := &mockSiteRepo{}
+	svc := NewSiteService(repo)
+	_, err := svc.CreateSite(model.SiteInput{Name: "Paris", TimeZone: "Europe/Atlantis"})
+	assert.Error(t, err)
+	assert.Nil(t, repo.created)
+
+	_, err = svc.UpdateSite("1", model.SiteInput{Name: "Paris", TimeZone: ""})
+	assert.Error(t, err)
+	assert.Nil(t, repo.updated)
+}
@@ -0,0 +1,184 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/epitech/timemanager/internal/graph/model"
+	"github.com/google/uuid"
+)
+
+const (
+	defaultForecastWeeks        = 4
+	maxForecastWeeks            = 12
+	defaultForecastHistoryWeeks = 8
+	maxForecastHistoryWeeks     = 52
+)
+
+// GetTeamStaffingForecast predicts the team's headcount for each hour of the
+// next weeks, from today in loc. Each member's share of the past weeks they
+// were present in on a weekday and hour is summed, scaled by the trend of the
+// weekly presence, and the members on approved leave or on a holiday that day
+// are taken out as far as they are away; a rising trend never expects more
+// members than those not away. Zero weeks or historyWeeks take the defaults.
+func (s *KpiService) GetTeamStaffingForecast(ctx context.Context, teamID uuid.UUID, weeks, historyWeeks, minHeadcount int, loc *time.Location) (*model.StaffingForecast, error) {
+	if weeks == 0 {
+		weeks = defaultForecastWeeks
+	}
+	if historyWeeks == 0 {
+		historyWeeks = defaultForecastHistoryWeeks
+	}
+	if weeks < 1 || weeks > maxForecastWeeks {
+		return nil, fmt.Errorf("weeks must be between 1 and %d", maxForecastWeeks)
+	}
+	if historyWeeks < 2 || historyWeeks > maxForecastHistoryWeeks {
+		return nil, fmt.Errorf("historyWeeks must be between 2 and %d", maxForecastHistoryWeeks)
+	}
+	if minHeadcount < 0 {
+		return nil, errors.New("minHeadcount must not be negative")
+	}
+
+	now := time.Now().In(loc)
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, loc)
+	historyFrom := today.AddDate(0, 0, -7*historyWeeks)
+	historyTo := today.AddDate(0, 0, -1)
+	entries, err := s.Repo.GetTimeTableEntriesFiltered(nil, &teamID, &historyFrom, &historyTo)
+	if err != nil {
+		return nil, err
+	}
+
+	// hours each member was present in, bucketed like the coverage
+	presence := make(map[string]map[string]int)
+	for _, e := range entries {
+		effectiveDep := effectiveDeparture(e.Departure, e.Status, now)
+		if e.UserID == nil || effectiveDep == nil || effectiveDep.Before(e.Arrival) {
+			continue
+		}
+		if presence[e.UserID.ID] == nil {
+			presence[e.UserID.ID] = make(map[string]int)
+		}
+		addCoverageBuckets(presence[e.UserID.ID], e.Arrival, *effectiveDep, historyFrom, today)
+	}
+
+	// share of the history weeks each member was present each weekday and hour
+	shares := make(map[string]*[7][24]float64, len(presence))
+	weekly := make([]float64, historyWeeks)
+	for userID, hours := range presence {
+		var grid [7][24]float64
+		for key := range hours {
+			t, err := time.Parse(time.RFC3339, key)
+			if err != nil {
+				continue
+			}
+			t = t.In(loc)
+			if t.Before(historyFrom) || !t.Before(today) {
+				continue
+			}
+			grid[(int(t.Weekday())+6)%7][t.Hour()] += 1 / float64(historyWeeks)
+			weekly[daysBetween(historyFrom, t)/7]++
+		}
+		shares[userID] = &grid
+	}
+	trend := weeklyTrend(weekly)
+
+	// the headcount can't exceed the members at hand, those without a
+	// history included
+	memberIDs, err := s.teamMembers(teamID.String())
+	if err != nil {
+		return nil, err
+	}
+	known := make(map[string]struct{}, len(memberIDs))
+	for _, userID := range memberIDs {
+		known[userID] = struct{}{}
+	}
+	for userID := range shares {
+		if _, ok := known[userID]; !ok {
+			memberIDs = append(memberIDs, userID)
+		}
+	}
+	planning := s.planning()
+	planning.preload(memberIDs, today.Format(layoutISO), today.AddDate(0, 0, 7*weeks-1).Format(layoutISO))
+	slots := make([]*model.ForecastSlot, 0)
+	understaffed := 0
+	for d := 0; d < 7*weeks; d++ {
+		day := today.AddDate(0, 0, d)
+		dayStr := day.Format(layoutISO)
+		// the trend is counted from the middle of the history
+		factor := max(0, 1+trend*(float64(historyWeeks)/2+float64(d/7)+0.5))
+		away := make(map[string]float64, len(memberIDs))
+		available := 0.0
+		for _, userID := range memberIDs {
+			if planning.holiday(userID, dayStr) {
+				away[userID] = 1
+			} else {
+				away[userID] = min(planning.absent(userID, dayStr), 1)
+			}
+			available += 1 - away[userID]
+		}
+		weekday := (int(day.Weekday()) + 6) % 7
+		for h := 0; h < 24; h++ {
+			usual, expected := 0.0, 0.0
+			absent := 0
+			for userID, grid := range shares {
+				share := grid[weekday][h]
+				if share == 0 {
+					continue
+				}
+				usual += share
+				if away[userID] > 0 {
+					absent++
+				}
+				expected += share * (1 - away[userID])
+			}
+			if usual == 0 {
+				continue
+			}
+			slot := &model.ForecastSlot{
+				Time:              time.Date(day.Year(), day.Month(), day.Day(), h, 0, 0, 0, loc),
+				UsualHeadcount:    usual,
+				ExpectedHeadcount: min(expected*factor, available),
+				AbsentUsers:       int32(absent),
+			}
+			if slot.ExpectedHeadcount < float64(minHeadcount) {
+				slot.Understaffed = true
+				understaffed++
+			}
+			slots = append(slots, slot)
+		}
+	}
+
+	return &model.StaffingForecast{
+		TeamID:            teamID.String(),
+		HistoryFrom:       historyFrom.Format(layoutISO),
+		HistoryTo:         historyTo.Format(layoutISO),
+		WeeklyTrend:       trend,
+		MinHeadcount:      int32(minHeadcount),
+		UnderstaffedSlots: int32(understaffed),
+		Slots:             slots,
+	}, nil
+}
+
+// weeklyTrend fits a line to the weekly totals by least squares and returns
+// its slope as a share of the average week.
+func weeklyTrend(weekly []float64) float64 {
+	n := float64(len(weekly))
+	if n < 2 {
+		return 0
+	}
+	meanX, meanY := (n-1)/2, 0.0
+	for _, y := range weekly {
+		meanY += y / n
+	}
+	if meanY == 0 {
+		return 0
+	}
+	num, den := 0.0, 0.0
+	for x, y := range weekly {
+		dx := float64(x) - meanX
+		num += dx * (y - meanY)
+		den += dx * dx
+	}
+	return num / den / meanY
+}
@@ -0,0 +1,95 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/epitech/timemanager/internal/graph/model"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWeeklyTrend(t *testing.T) {
+	assert.Equal(t, 0.0, weeklyTrend([]float64{5, 5, 5, 5}))
+	assert.Equal(t, 0.0, weeklyTrend([]float64{0, 0}))
+	assert.InDelta(t, 1.0/2.5, weeklyTrend([]float64{1, 2, 3, 4}), 1e-9)
+	assert.Less(t, weeklyTrend([]float64{4, 3, 2, 1}), 0.0)
+}
+
+func TestTeamStaffingForecast(t *testing.T) {
+	today := time.Now().UTC().Truncate(24 * time.Hour)
+	steady := &model.User{ID: uuid.New().String()}
+	newcomer := &model.User{ID: uuid.New().String()}
+	entries := make([]*model.TimeTableEntry, 0)
+	for k := 1; k <= 56; k++ {
+		day := today.AddDate(0, 0, -k).Format(layoutISO)
+		entries = append(entries, workSession(steady, day, 9, 9.5))
+		if k <= 28 {
+			entries = append(entries, workSession(newcomer, day, 9, 9.5))
+		}
+	}
+	tomorrow := today.AddDate(0, 0, 1).Format(layoutISO)
+	team := uuid.New()
+	// a third member, never clocked in yet, leaves room for the trend
+	members := []*model.Team{{ID: team.String()}}
+	svc := NewKpiService(&mockKpiRepo{entries: entries, users: []*model.UserWithAllData{
+		{ID: steady.ID, Teams: members}, {ID: newcomer.ID, Teams: members}, {ID: uuid.New().String(), Teams: members},
+	}})
+	// the mock gives the leave to everyone
+	svc.Leaves = NewLeaveService(&mockLeaveRepo{requests: []*model.LeaveRequest{
+		{StartDate: tomorrow, EndDate: tomorrow, Days: 1, Status: model.LeaveStatusApproved},
+	}})
+
+	got, err := svc.GetTeamStaffingForecast(context.Background(), team, 4, 8, 1, time.UTC)
+	assert.NoError(t, err)
+	assert.Equal(t, today.AddDate(0, 0, -56).Format(layoutISO), got.HistoryFrom)
+	// weekly presence hours: 7 for four weeks, then 14
+	trend := (56.0 / 42) / 10.5
+	assert.InDelta(t, trend, got.WeeklyTrend, 1e-9)
+	assert.Len(t, got.Slots, 28, "only 9h is ever staffed")
+
+	first := got.Slots[0]
+	assert.Equal(t, today.Add(9*time.Hour), first.Time)
+	assert.InDelta(t, 1.5, first.UsualHeadcount, 1e-9)
+	assert.InDelta(t, 1.5*(1+trend*4.5), first.ExpectedHeadcount, 1e-9)
+	assert.False(t, first.Understaffed)
+
+	away := got.Slots[1]
+	assert.Equal(t, 0.0, away.ExpectedHeadcount)
+	assert.Equal(t, int32(2), away.AbsentUsers)
+	assert.True(t, away.Understaffed)
+	assert.Equal(t, int32(1), got.UnderstaffedSlots)
+	// later weeks carry the trend further
+	assert.Greater(t, got.Slots[27].ExpectedHeadcount, first.ExpectedHeadcount)
+
+	_, err = svc.GetTeamStaffingForecast(context.Background(), uuid.New(), 13, 8, 1, time.UTC)
+	assert.Error(t, err)
+	_, err = svc.GetTeamStaffingForecast(context.Background(), uuid.New(), 4, 1, 1, time.UTC)
+	assert.Error(t, err)
+}
+
+func TestStaffingForecastNeverExpectsMoreThanTheTeam(t *testing.T) {
+	today := time.Now().UTC().Truncate(24 * time.Hour)
+	steady := &model.User{ID: uuid.New().String()}
+	newcomer := &model.User{ID: uuid.New().String()}
+	entries := make([]*model.TimeTableEntry, 0)
+	for k := 1; k <= 56; k++ {
+		day := today.AddDate(0, 0, -k).Format(layoutISO)
+		entries = append(entries, workSession(steady, day, 9, 9.5))
+		if k <= 14 {
+			entries = append(entries, workSession(newcomer, day, 9, 9.5))
+		}
+	}
+	svc := NewKpiService(&mockKpiRepo{entries: entries})
+
+	got, err := svc.GetTeamStaffingForecast(context.Background(), uuid.New(), 4, 8, 1, time.UTC)
+	assert.NoError(t, err)
+	assert.Greater(t, got.WeeklyTrend, 0.0)
+	assert.Len(t, got.Slots, 28)
+	for _, slot := range got.Slots {
+		assert.LessOrEqual(t, slot.ExpectedHeadcount, 2.0, "the team has two members")
+	}
+	// the last weeks would carry the trend past the team
+	assert.Equal(t, 2.0, got.Slots[27].ExpectedHeadcount)
+}
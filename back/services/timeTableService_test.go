@@ -13,12 +13,57 @@ type mockTTRepo struct {
 	entries  []*model.TimeTableEntry
 	filtered []*model.TimeTableEntry
 	err      error
+
+	entry       *model.TimeTableEntry
+	correction  *model.TimeEntryCorrection
+	isManager   bool
+	created     *model.CreateTimeEntryInput
+	requested   *model.RequestTimeEntryCorrectionInput
+	reviewed    bool
+	approved    bool
+	listUserID  *uuid.UUID
+	listManager *uuid.UUID
+	openSession bool
 }
 
 func (m *mockTTRepo) GetTimeTableEntries() ([]*model.TimeTableEntry, error) { return m.entries, m.err }
 func (m *mockTTRepo) GetTimeTableEntriesFiltered(userID *uuid.UUID, teamID *uuid.UUID, from, to *time.Time) ([]*model.TimeTableEntry, error) {
 	return m.filtered, m.err
 }
+func (m *mockTTRepo) GetTimeTableEntryByID(id string) (*model.TimeTableEntry, error) {
+	return m.entry, m.err
+}
+func (m *mockTTRepo) HasOpenSession(userID uuid.UUID, exceptID *string) (bool, error) {
+	return m.openSession, m.err
+}
+func (m *mockTTRepo) CreateTimeEntry(input model.CreateTimeEntryInput) (*model.TimeTableEntry, error) {
+	m.created = &input
+	return &model.TimeTableEntry{ID: "new", Status: input.Status}, m.err
+}
+func (m *mockTTRepo) UpdateTimeEntry(id string, input model.UpdateTimeEntryInput) (*model.TimeTableEntry, error) {
+	return &model.TimeTableEntry{ID: id}, m.err
+}
+func (m *mockTTRepo) DeleteTimeEntry(id string) (bool, error) { return m.err == nil, m.err }
+func (m *mockTTRepo) CreateTimeEntryCorrection(userID uuid.UUID, input model.RequestTimeEntryCorrectionInput) (*model.TimeEntryCorrection, error) {
+	m.requested = &input
+	return &model.TimeEntryCorrection{ID: "c1", UserID: &model.User{ID: userID.String()}, Status: model.CorrectionStatusPending}, m.err
+}
+func (m *mockTTRepo) GetTimeEntryCorrection(id string) (*model.TimeEntryCorrection, error) {
+	return m.correction, m.err
+}
+func (m *mockTTRepo) GetTimeEntryCorrections(status *model.CorrectionStatus, userID *uuid.UUID, managerID *uuid.UUID) ([]*model.TimeEntryCorrection, error) {
+	m.listUserID = userID
+	m.listManager = managerID
+	return nil, m.err
+}
+func (m *mockTTRepo) IsManagerOfUser(managerID uuid.UUID, userID uuid.UUID) (bool, error) {
+	return m.isManager, m.err
+}
+func (m *mockTTRepo) ReviewTimeEntryCorrection(id string, reviewerID uuid.UUID, approve bool, comment *string) (*model.TimeEntryCorrection, error) {
+	m.reviewed = true
+	m.approved = approve
+	return m.correction, m.err
+}
 
 func TestTimeTableServiceGetAll(t *testing.T) {
 	repo := &mockTTRepo{entries: []*model.TimeTableEntry{{ID: "1"}}}
@@ -38,3 +83,149 @@ func TestTimeTableServiceFiltered(t *testing.T) {
 	assert.NoError(t, err)
 	assert.Len(t, got, 1)
 }
+
+func TestTimeTableServiceCreateTimeEntryRejectsDepartureBeforeArrival(t *testing.T) {
+	repo := &mockTTRepo{}
+	svc := NewTimeTableService(repo)
+	arrival := time.Date(2024, 1, 10, 9, 0, 0, 0, time.UTC)
+	departure := arrival.Add(-time.Hour)
+	_, err := svc.CreateTimeEntry(model.CreateTimeEntryInput{UserID: uuid.NewString(), Day: "2024-01-10", Arrival: arrival, Departure: &departure})
+	assert.Error(t, err)
+	assert.Nil(t, repo.created)
+}
+
+func TestTimeTableServiceCreateTimeEntryClosedWhenDepartureGiven(t *testing.T) {
+	repo := &mockTTRepo{}
+	svc := NewTimeTableService(repo)
+	arrival := time.Date(2024, 1, 10, 9, 0, 0, 0, time.UTC)
+	departure := arrival.Add(8 * time.Hour)
+	got, err := svc.CreateTimeEntry(model.CreateTimeEntryInput{UserID: uuid.NewString(), Day: "2024-01-10", Arrival: arrival, Departure: &departure, Status: true})
+	assert.NoError(t, err)
+	assert.False(t, got.Status)
+}
+
+func TestTimeTableServiceDeleteTimeEntryRatesTheNextArrival(t *testing.T) {
+	u := &model.User{ID: uuid.New().String()}
+	deleted := &model.TimeTableEntry{ID: "e1", UserID: u, Day: "2024-01-15", Arrival: time.Date(2024, 1, 15, 9, 0, 0, 0, time.UTC)}
+	next := &model.TimeTableEntry{ID: "e2", UserID: u, Day: "2024-01-15", Arrival: time.Date(2024, 1, 15, 10, 20, 0, 0, time.UTC)}
+	lateness := &mockLatenessRepo{synced: map[string]*model.LatenessEvent{}}
+	svc := NewTimeTableService(&mockTTRepo{entry: deleted})
+	svc.Lateness = NewLatenessService(lateness, NewKpiService(&mockKpiRepo{entries: []*model.TimeTableEntry{next}}))
+
+	ok, err := svc.DeleteTimeEntry("e1")
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	if assert.NotNil(t, lateness.synced["2024-01-15"], "the remaining session is now the day's arrival") {
+		assert.Equal(t, "e2", lateness.synced["2024-01-15"].EntryID)
+		assert.Equal(t, int32(20), lateness.synced["2024-01-15"].LateMinutes)
+	}
+}
+
+func TestTimeTableServiceRequestCorrection(t *testing.T) {
+	requester := uuid.New()
+	arrival := time.Date(2024, 1, 10, 9, 0, 0, 0, time.UTC)
+	departure := arrival.Add(8 * time.Hour)
+	input := model.RequestTimeEntryCorrectionInput{Day: "2024-01-10", ProposedArrival: arrival, ProposedDeparture: &departure, Reason: " forgot my badge "}
+
+	// reason is mandatory
+	repo := &mockTTRepo{}
+	svc := NewTimeTableService(repo)
+	_, err := svc.RequestCorrection(requester, model.RequestTimeEntryCorrectionInput{Day: "2024-01-10", ProposedArrival: arrival, Reason: "  "})
+	assert.Error(t, err)
+
+	// cannot target someone else's entry
+	entryID := "e1"
+	input.EntryID = &entryID
+	repo.entry = &model.TimeTableEntry{ID: entryID, UserID: &model.User{ID: uuid.NewString()}}
+	_, err = svc.RequestCorrection(requester, input)
+	assert.Error(t, err)
+	assert.Nil(t, repo.requested)
+
+	// the day must be the entry's
+	repo.entry = &model.TimeTableEntry{ID: entryID, UserID: &model.User{ID: requester.String()}, Day: "2024-01-09"}
+	_, err = svc.RequestCorrection(requester, input)
+	assert.Error(t, err)
+	assert.Nil(t, repo.requested)
+
+	// own entry is accepted and left pending
+	repo.entry.Day = "2024-01-10"
+	got, err := svc.RequestCorrection(requester, input)
+	assert.NoError(t, err)
+	assert.Equal(t, model.CorrectionStatusPending, got.Status)
+	assert.Equal(t, "forgot my badge", repo.requested.Reason)
+}
+
+func TestTimeTableServiceReviewCorrection(t *testing.T) {
+	requester := uuid.New()
+	manager := uuid.New()
+	pending := &model.TimeEntryCorrection{ID: "c1", UserID: &model.User{ID: requester.String()}, Status: model.CorrectionStatusPending}
+
+	// a manager who does not manage the requester is refused
+	repo := &mockTTRepo{correction: pending, isManager: false}
+	svc := NewTimeTableService(repo)
+	_, err := svc.ReviewCorrection(manager, "MANAGER", "c1", true, nil)
+	assert.Error(t, err)
+	assert.False(t, repo.reviewed)
+
+	// nobody reviews their own request
+	repo.isManager = true
+	_, err = svc.ReviewCorrection(requester, "MANAGER", "c1", true, nil)
+	assert.Error(t, err)
+	assert.False(t, repo.reviewed)
+
+	// the team's manager may approve
+	_, err = svc.ReviewCorrection(manager, "MANAGER", "c1", true, nil)
+	assert.NoError(t, err)
+	assert.True(t, repo.reviewed)
+	assert.True(t, repo.approved)
+
+	// an admin may reject without being the manager
+	repo = &mockTTRepo{correction: pending}
+	svc = NewTimeTableService(repo)
+	_, err = svc.ReviewCorrection(uuid.New(), "ADMIN", "c1", false, nil)
+	assert.NoError(t, err)
+	assert.False(t, repo.approved)
+
+	// already reviewed requests are final
+	repo = &mockTTRepo{correction: &model.TimeEntryCorrection{ID: "c1", UserID: pending.UserID, Status: model.CorrectionStatusApproved}}
+	svc = NewTimeTableService(repo)
+	_, err = svc.ReviewCorrection(uuid.New(), "ADMIN", "c1", true, nil)
+	assert.Error(t, err)
+	assert.False(t, repo.reviewed)
+}
+
+func TestTimeTableServiceReviewCorrectionKeepsOneOpenSession(t *testing.T) {
+	requester := uuid.New()
+	entryID := "e1"
+	departure := time.Date(2024, 1, 10, 17, 0, 0, 0, time.UTC)
+	pending := &model.TimeEntryCorrection{ID: "c1", EntryID: &entryID, UserID: &model.User{ID: requester.String()}, Day: "2024-01-10", ProposedArrival: time.Date(2024, 1, 10, 9, 0, 0, 0, time.UTC), Status: model.CorrectionStatusPending}
+	repo := &mockTTRepo{correction: pending, openSession: true, entry: &model.TimeTableEntry{ID: entryID, UserID: pending.UserID, Day: "2024-01-10"}}
+	svc := NewTimeTableService(repo)
+
+	// no departure while another session runs: two open sessions
+	_, err := svc.ReviewCorrection(uuid.New(), "ADMIN", "c1", true, nil)
+	assert.Error(t, err)
+	assert.False(t, repo.reviewed)
+
+	// the entry was moved to another day since the request
+	pending.ProposedDeparture = &departure
+	repo.entry.Day = "2024-01-11"
+	_, err = svc.ReviewCorrection(uuid.New(), "ADMIN", "c1", true, nil)
+	assert.Error(t, err)
+	assert.False(t, repo.reviewed)
+
+	repo.entry.Day = "2024-01-10"
+	_, err = svc.ReviewCorrection(uuid.New(), "ADMIN", "c1", true, nil)
+	assert.NoError(t, err)
+	assert.True(t, repo.approved)
+}
+
+func TestTimeTableServiceGetCorrectionsAppliesTheScope(t *testing.T) {
+	manager := uuid.New()
+	repo := &mockTTRepo{}
+	svc := NewTimeTableService(repo)
+
+	_, _ = svc.GetCorrections(nil, nil, &manager)
+	assert.Nil(t, repo.listUserID)
+	assert.Equal(t, manager, *repo.listManager)
+}
@@ -0,0 +1,347 @@
+package services
+
+import (
+	"errors"
+	"sort"
+	"time"
+
+	"github.com/epitech/timemanager/internal/graph/model"
+	"github.com/google/uuid"
+)
+
+var errWeekLocked = errors.New("this week's timesheet is submitted or approved: its entries are locked")
+var errForbiddenTimesheetReview = errors.New("forbidden: only the team's manager or an admin can review this timesheet")
+
+// TimesheetRepository is the minimal repository contract used by TimesheetService.
+type TimesheetRepository interface {
+	// GetTimesheet returns the user's timesheet of the week, nil when never submitted.
+	GetTimesheet(userID uuid.UUID, weekStart string) (*model.Timesheet, error)
+	GetTimesheetByID(id string) (*model.Timesheet, error)
+	GetTimesheets(userID *uuid.UUID, teamID *uuid.UUID, status *model.TimesheetStatus, from, to *string) ([]*model.Timesheet, error)
+	SubmitTimesheet(userID uuid.UUID, weekStart string) (*model.Timesheet, error)
+	ReviewTimesheet(id string, reviewerID uuid.UUID, approve bool, comment *string) (*model.Timesheet, error)
+	GetTimeTableEntriesFiltered(userID *uuid.UUID, teamID *uuid.UUID, from, to *time.Time) ([]*model.TimeTableEntry, error)
+	IsManagerOfUser(managerID uuid.UUID, userID uuid.UUID) (bool, error)
+}
+
+// TimesheetService handles the weekly timesheets: employees submit the hours
+// clocked in a week (Monday to Sunday), their manager approves or rejects
+// them. A submitted or approved week accepts no more clock-in nor edit; a
+// rejected one reopens.
+type TimesheetService struct {
+	Repo TimesheetRepository
+	// Kpi computes the week's hours with the KPI rules (midnight rule,
+	// overtime policy).
+	Kpi *KpiService
+}
+
+func NewTimesheetService(repo TimesheetRepository, kpi *KpiService) *TimesheetService {
+	return &TimesheetService{Repo: repo, Kpi: kpi}
+}
+
+// weekOf returns the Monday of the week holding day (YYYY-MM-DD).
+func weekOf(day string) (time.Time, error) {
+	t, err := time.Parse(layoutISO, day)
+	if err != nil {
+		return time.Time{}, errors.New("invalid date, expected YYYY-MM-DD")
+	}
+	return mondayOf(t), nil
+}
+
+// GetTimesheet returns the user's timesheet of the week holding day, a draft
+// until it is first submitted.
+func (s *TimesheetService) GetTimesheet(userID uuid.UUID, day string) (*model.Timesheet, error) {
+	monday, err := weekOf(day)
+	if err != nil {
+		return nil, err
+	}
+	timesheet, err := s.Repo.GetTimesheet(userID, monday.Format(layoutISO))
+	if err != nil {
+		return nil, err
+	}
+	if timesheet == nil {
+		timesheet = &model.Timesheet{
+			UserID:    &model.User{ID: userID.String()},
+			WeekStart: monday.Format(layoutISO),
+			Status:    model.TimesheetStatusDraft,
+		}
+	}
+	return timesheet, s.fillHours(timesheet)
+}
+
+// GetTimesheets lists the timesheets of weeks starting between from and to,
+// of a user or a team, the caller having been checked to see them. The weeks
+// the user or a team member never submitted, up to the current one, are
+// listed as drafts so that a manager sees who is missing.
+func (s *TimesheetService) GetTimesheets(userID *uuid.UUID, teamID *uuid.UUID, status *model.TimesheetStatus, from, to *string) ([]*model.Timesheet, error) {
+	for _, day := range []*string{from, to} {
+		if day == nil || *day == "" {
+			continue
+		}
+		if _, err := time.Parse(layoutISO, *day); err != nil {
+			return nil, errors.New("invalid date, expected YYYY-MM-DD")
+		}
+	}
+	timesheets, err := s.Repo.GetTimesheets(userID, teamID, status, from, to)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	if (userID != nil || teamID != nil) && (status == nil || *status == model.TimesheetStatusDraft) {
+		drafts, err := s.missingWeeks(userID, teamID, timesheets, from, to, now)
+		if err != nil {
+			return nil, err
+		}
+		timesheets = append(timesheets, drafts...)
+		sort.SliceStable(timesheets, func(i, j int) bool { return timesheets[i].WeekStart > timesheets[j].WeekStart })
+	}
+	return timesheets, s.fillAllHours(userID, teamID, timesheets, now)
+}
+
+// missingWeeks returns the drafts of the weeks starting between from and to,
+// by default the current week, that the user or the team's members have no
+// timesheet for. Weeks to come are left out.
+func (s *TimesheetService) missingWeeks(userID *uuid.UUID, teamID *uuid.UUID, stored []*model.Timesheet, from, to *string, now time.Time) ([]*model.Timesheet, error) {
+	current := mondayOf(now)
+	first, last := current, current
+	if from != nil && *from != "" {
+		day, _ := time.Parse(layoutISO, *from)
+		if first = mondayOf(day); first.Before(day) {
+			first = first.AddDate(0, 0, 7)
+		}
+	}
+	if to != nil && *to != "" {
+		day, _ := time.Parse(layoutISO, *to)
+		if last = mondayOf(day); last.After(current) {
+			last = current
+		}
+	}
+	if first.After(last) {
+		return nil, nil
+	}
+	owners, err := s.owners(userID, teamID)
+	if err != nil {
+		return nil, err
+	}
+	known := make(map[string]struct{}, len(stored))
+	for _, ts := range stored {
+		if ts.UserID != nil {
+			known[ts.UserID.ID+"/"+ts.WeekStart] = struct{}{}
+		}
+	}
+	drafts := make([]*model.Timesheet, 0)
+	for week := first; !week.After(last); week = week.AddDate(0, 0, 7) {
+		for _, u := range owners {
+			if _, ok := known[u.ID+"/"+week.Format(layoutISO)]; ok {
+				continue
+			}
+			drafts = append(drafts, &model.Timesheet{UserID: u, WeekStart: week.Format(layoutISO), Status: model.TimesheetStatusDraft})
+		}
+	}
+	return drafts, nil
+}
+
+// owners returns the user, or the members of the team, a listing covers.
+func (s *TimesheetService) owners(userID *uuid.UUID, teamID *uuid.UUID) ([]*model.User, error) {
+	staff, err := s.Kpi.Repo.GetUsersWithTeams()
+	if err != nil {
+		return nil, err
+	}
+	owners := make([]*model.User, 0)
+	for _, u := range staff {
+		if u == nil || (userID != nil && u.ID != userID.String()) || (teamID != nil && !inTeam(u, teamID.String())) {
+			continue
+		}
+		owners = append(owners, &model.User{ID: u.ID, FirstName: u.FirstName, LastName: u.LastName, Email: u.Email, Phone: u.Phone, Role: u.Role})
+	}
+	return owners, nil
+}
+
+// fillHours computes the timesheet's days and totals from the week's entries.
+func (s *TimesheetService) fillHours(ts *model.Timesheet) error {
+	monday, err := time.Parse(layoutISO, ts.WeekStart)
+	if err != nil {
+		return err
+	}
+	sunday := monday.AddDate(0, 0, 6)
+	userID, err := uuid.Parse(ts.UserID.ID)
+	if err != nil {
+		return err
+	}
+	entries, err := s.Repo.GetTimeTableEntriesFiltered(&userID, nil, &monday, &sunday)
+	if err != nil {
+		return err
+	}
+	s.fillDays(ts, entries, time.Now())
+	return nil
+}
+
+// fillAllHours computes the hours of the listed timesheets from the entries
+// of their weeks, loaded at once and split by user and week.
+func (s *TimesheetService) fillAllHours(userID *uuid.UUID, teamID *uuid.UUID, timesheets []*model.Timesheet, now time.Time) error {
+	if len(timesheets) == 0 {
+		return nil
+	}
+	first, last := timesheets[0].WeekStart, timesheets[0].WeekStart
+	for _, ts := range timesheets {
+		first, last = minString(first, ts.WeekStart), maxString(last, ts.WeekStart)
+	}
+	monday, err := time.Parse(layoutISO, first)
+	if err != nil {
+		return err
+	}
+	lastMonday, err := time.Parse(layoutISO, last)
+	if err != nil {
+		return err
+	}
+	sunday := lastMonday.AddDate(0, 0, 6)
+	entries, err := s.Repo.GetTimeTableEntriesFiltered(userID, teamID, &monday, &sunday)
+	if err != nil {
+		return err
+	}
+	byWeek := make(map[string][]*model.TimeTableEntry)
+	for _, e := range entries {
+		week, err := weekOf(e.Day)
+		if err != nil || e.UserID == nil {
+			continue
+		}
+		key := e.UserID.ID + "/" + week.Format(layoutISO)
+		byWeek[key] = append(byWeek[key], e)
+	}
+	for _, ts := range timesheets {
+		s.fillDays(ts, byWeek[ts.UserID.ID+"/"+ts.WeekStart], now)
+	}
+	return nil
+}
+
+// fillDays computes the timesheet's days and totals from the entries of its week.
+func (s *TimesheetService) fillDays(ts *model.Timesheet, entries []*model.TimeTableEntry, now time.Time) {
+	monday, _ := time.Parse(layoutISO, ts.WeekStart)
+	ts.WeekEnd = monday.AddDate(0, 0, 6).Format(layoutISO)
+	ts.Days = make([]*model.DailyRollup, 0, 7)
+	ts.WorkedMinutes, ts.OvertimeMinutes = 0, 0
+	for _, r := range s.Kpi.dailyRollups(entries, now) {
+		// a night shift split at midnight may spill over the next week
+		if r.Day < ts.WeekStart || r.Day > ts.WeekEnd {
+			continue
+		}
+		ts.Days = append(ts.Days, r)
+		ts.WorkedMinutes += r.WorkedMinutes
+		ts.OvertimeMinutes += r.OvertimeMinutes
+	}
+}
+
+// openSessions counts the sessions of the timesheet's week still running.
+func openSessions(ts *model.Timesheet) int32 {
+	var open int32
+	for _, d := range ts.Days {
+		open += d.OpenSessions
+	}
+	return open
+}
+
+// Submit sends the caller's timesheet of the week holding day to their
+// manager. The week must be over in the caller's zone and every session be
+// closed; a rejected timesheet may be submitted again.
+func (s *TimesheetService) Submit(userID uuid.UUID, day string) (*model.Timesheet, error) {
+	timesheet, err := s.GetTimesheet(userID, day)
+	if err != nil {
+		return nil, err
+	}
+	if timesheet.WeekEnd >= s.today(userID) {
+		return nil, errors.New("this week is not over yet")
+	}
+	if timesheet.Status != model.TimesheetStatusDraft && timesheet.Status != model.TimesheetStatusRejected {
+		return nil, errors.New("timesheet has already been submitted")
+	}
+	if openSessions(timesheet) > 0 {
+		return nil, errors.New("clock out before submitting the timesheet")
+	}
+	submitted, err := s.Repo.SubmitTimesheet(userID, timesheet.WeekStart)
+	if err != nil {
+		return nil, err
+	}
+	return submitted, s.fillHours(submitted)
+}
+
+// today returns the user's current day, in their zone when the schedules are
+// known.
+func (s *TimesheetService) today(userID uuid.UUID) string {
+	if s.Kpi != nil && s.Kpi.Schedules != nil {
+		if day, err := s.Kpi.Schedules.Today(userID); err == nil {
+			return day
+		}
+	}
+	return time.Now().Format(layoutISO)
+}
+
+// Review approves or rejects a submitted timesheet. Only the manager of one
+// of the employee's teams, or an admin, may review; nobody reviews their own.
+func (s *TimesheetService) Review(reviewerID uuid.UUID, reviewerRole string, id string, approve bool, comment *string) (*model.Timesheet, error) {
+	timesheet, err := s.Repo.GetTimesheetByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if timesheet.Status != model.TimesheetStatusSubmitted {
+		return nil, errors.New("timesheet is not awaiting review")
+	}
+	userID, err := uuid.Parse(timesheet.UserID.ID)
+	if err != nil {
+		return nil, err
+	}
+	if reviewerRole != string(model.RoleAdmin) {
+		if userID == reviewerID {
+			return nil, errForbiddenTimesheetReview
+		}
+		isManager, err := s.Repo.IsManagerOfUser(reviewerID, userID)
+		if err != nil {
+			return nil, err
+		}
+		if !isManager {
+			return nil, errForbiddenTimesheetReview
+		}
+	}
+	if approve {
+		// the employee may have clocked in again since submitting
+		if err := s.fillHours(timesheet); err != nil {
+			return nil, err
+		}
+		if openSessions(timesheet) > 0 {
+			return nil, errors.New("a session of this week is still running")
+		}
+	}
+	reviewed, err := s.Repo.ReviewTimesheet(id, reviewerID, approve, comment)
+	if err != nil {
+		return nil, err
+	}
+	return reviewed, s.fillHours(reviewed)
+}
+
+// EnsureWeekOpen fails when one of the user's days (YYYY-MM-DD) falls in a
+// week whose timesheet is submitted or approved.
+func (s *TimesheetService) EnsureWeekOpen(userID string, days ...string) error {
+	uid, err := uuid.Parse(userID)
+	if err != nil {
+		return errors.New("invalid userID")
+	}
+	checked := make(map[string]struct{}, len(days))
+	for _, day := range days {
+		monday, err := weekOf(day)
+		if err != nil {
+			return err
+		}
+		week := monday.Format(layoutISO)
+		if _, ok := checked[week]; ok {
+			continue
+		}
+		checked[week] = struct{}{}
+		timesheet, err := s.Repo.GetTimesheet(uid, week)
+		if err != nil {
+			return err
+		}
+		if timesheet != nil && (timesheet.Status == model.TimesheetStatusSubmitted || timesheet.Status == model.TimesheetStatusApproved) {
+			return errWeekLocked
+		}
+	}
+	return nil
+}
@@ -0,0 +1,206 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"github.com/epitech/timemanager/internal/graph/model"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+type mockTimesheetRepo struct {
+	timesheets map[string]*model.Timesheet // by timesheetKey
+	entries    []*model.TimeTableEntry
+	isManager  bool
+}
+
+func timesheetKey(userID uuid.UUID, weekStart string) string {
+	return userID.String() + "/" + weekStart
+}
+
+func (m *mockTimesheetRepo) GetTimesheet(userID uuid.UUID, weekStart string) (*model.Timesheet, error) {
+	return m.timesheets[timesheetKey(userID, weekStart)], nil
+}
+
+func (m *mockTimesheetRepo) GetTimesheetByID(id string) (*model.Timesheet, error) {
+	for _, ts := range m.timesheets {
+		if ts.ID != nil && *ts.ID == id {
+			return ts, nil
+		}
+	}
+	return nil, assert.AnError
+}
+
+func (m *mockTimesheetRepo) GetTimesheets(userID *uuid.UUID, teamID *uuid.UUID, status *model.TimesheetStatus, from, to *string) ([]*model.Timesheet, error) {
+	out := make([]*model.Timesheet, 0)
+	for _, ts := range m.timesheets {
+		if (userID != nil && ts.UserID.ID != userID.String()) || (status != nil && ts.Status != *status) {
+			continue
+		}
+		out = append(out, ts)
+	}
+	return out, nil
+}
+
+func (m *mockTimesheetRepo) SubmitTimesheet(userID uuid.UUID, weekStart string) (*model.Timesheet, error) {
+	id := "ts-" + weekStart
+	ts := &model.Timesheet{ID: &id, UserID: &model.User{ID: userID.String()}, WeekStart: weekStart, Status: model.TimesheetStatusSubmitted}
+	m.timesheets[timesheetKey(userID, weekStart)] = ts
+	return ts, nil
+}
+
+func (m *mockTimesheetRepo) ReviewTimesheet(id string, reviewerID uuid.UUID, approve bool, comment *string) (*model.Timesheet, error) {
+	ts, _ := m.GetTimesheetByID(id)
+	ts.Status = model.TimesheetStatusRejected
+	if approve {
+		ts.Status = model.TimesheetStatusApproved
+	}
+	return ts, nil
+}
+
+func (m *mockTimesheetRepo) GetTimeTableEntriesFiltered(userID *uuid.UUID, teamID *uuid.UUID, from, to *time.Time) ([]*model.TimeTableEntry, error) {
+	return m.entries, nil
+}
+
+func (m *mockTimesheetRepo) IsManagerOfUser(managerID uuid.UUID, userID uuid.UUID) (bool, error) {
+	return m.isManager, nil
+}
+
+func TestTimesheetWorkflow(t *testing.T) {
+	employee, manager := uuid.New(), uuid.New()
+	u := &model.User{ID: employee.String()}
+	repo := &mockTimesheetRepo{
+		timesheets: map[string]*model.Timesheet{},
+		entries: []*model.TimeTableEntry{
+			workSession(u, "2024-01-08", 9, 17),
+			workSession(u, "2024-01-10", 9, 13),
+			workSession(u, "2024-01-15", 9, 17), // next week
+		},
+	}
+	svc := NewTimesheetService(repo, NewKpiService(&mockKpiRepo{}))
+
+	draft, err := svc.GetTimesheet(employee, "2024-01-11")
+	assert.NoError(t, err)
+	assert.Nil(t, draft.ID)
+	assert.Equal(t, model.TimesheetStatusDraft, draft.Status)
+	assert.Equal(t, "2024-01-08", draft.WeekStart)
+	assert.Equal(t, "2024-01-14", draft.WeekEnd)
+	assert.Equal(t, int32(720), draft.WorkedMinutes)
+	assert.Len(t, draft.Days, 2)
+
+	submitted, err := svc.Submit(employee, "2024-01-08")
+	assert.NoError(t, err)
+	assert.Equal(t, model.TimesheetStatusSubmitted, submitted.Status)
+	assert.Equal(t, int32(720), submitted.WorkedMinutes)
+	_, err = svc.Submit(employee, "2024-01-09")
+	assert.Error(t, err, "already submitted")
+	assert.Error(t, svc.EnsureWeekOpen(employee.String(), "2024-01-10"), "submitted week is locked")
+
+	_, err = svc.Review(manager, "MANAGER", *submitted.ID, true, nil)
+	assert.Error(t, err, "not the employee's manager")
+	repo.isManager = true
+	_, err = svc.Review(employee, "MANAGER", *submitted.ID, true, nil)
+	assert.Error(t, err, "nobody reviews their own")
+	rejected, err := svc.Review(manager, "MANAGER", *submitted.ID, false, ptrString("missing Friday"))
+	assert.NoError(t, err)
+	assert.Equal(t, model.TimesheetStatusRejected, rejected.Status)
+	assert.NoError(t, svc.EnsureWeekOpen(employee.String(), "2024-01-10"))
+
+	resubmitted, err := svc.Submit(employee, "2024-01-08")
+	assert.NoError(t, err)
+	approved, err := svc.Review(manager, "MANAGER", *resubmitted.ID, true, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, model.TimesheetStatusApproved, approved.Status)
+	assert.Error(t, svc.EnsureWeekOpen(employee.String(), "2024-01-14"), "approved week is locked")
+	assert.NoError(t, svc.EnsureWeekOpen(employee.String(), "2024-01-15"))
+	assert.NoError(t, svc.EnsureWeekOpen(uuid.NewString(), "2024-01-10"), "only the employee's week is locked")
+}
+
+func TestTimesheetsListTheTeamsMissingWeeks(t *testing.T) {
+	team := uuid.New()
+	ada, bob, outsider := uuid.New(), uuid.New(), uuid.New()
+	member := func(id uuid.UUID, name string, teams ...*model.Team) *model.UserWithAllData {
+		return &model.UserWithAllData{ID: id.String(), FirstName: name, Teams: teams}
+	}
+	ops := &model.Team{ID: team.String()}
+	submittedID := "ts1"
+	repo := &mockTimesheetRepo{
+		timesheets: map[string]*model.Timesheet{
+			timesheetKey(ada, "2024-01-08"): {ID: &submittedID, UserID: &model.User{ID: ada.String()}, WeekStart: "2024-01-08", Status: model.TimesheetStatusSubmitted},
+		},
+		entries: []*model.TimeTableEntry{
+			workSession(&model.User{ID: ada.String()}, "2024-01-08", 9, 17),
+			workSession(&model.User{ID: bob.String()}, "2024-01-09", 9, 13),
+			workSession(&model.User{ID: bob.String()}, "2024-01-16", 9, 12),
+		},
+	}
+	staff := []*model.UserWithAllData{member(ada, "Ada", ops), member(bob, "Bob", ops), member(outsider, "Eve")}
+	svc := NewTimesheetService(repo, NewKpiService(&mockKpiRepo{users: staff}))
+
+	list, err := svc.GetTimesheets(nil, &team, nil, ptrString("2024-01-08"), ptrString("2024-01-15"))
+	assert.NoError(t, err)
+	got := make(map[string]*model.Timesheet)
+	for _, ts := range list {
+		got[ts.UserID.ID+"/"+ts.WeekStart] = ts
+	}
+	assert.Len(t, got, 4, "both members, both weeks, without the outsider")
+	assert.Equal(t, model.TimesheetStatusSubmitted, got[timesheetKey(ada, "2024-01-08")].Status)
+	assert.Equal(t, int32(480), got[timesheetKey(ada, "2024-01-08")].WorkedMinutes)
+	missing := got[timesheetKey(bob, "2024-01-08")]
+	if assert.NotNil(t, missing) {
+		assert.Equal(t, model.TimesheetStatusDraft, missing.Status)
+		assert.Equal(t, "Bob", missing.UserID.FirstName)
+		assert.Equal(t, int32(240), missing.WorkedMinutes)
+	}
+	assert.Equal(t, int32(180), got[timesheetKey(bob, "2024-01-15")].WorkedMinutes)
+	assert.Equal(t, "2024-01-15", list[0].WeekStart, "latest first")
+
+	submitted := model.TimesheetStatusSubmitted
+	list, err = svc.GetTimesheets(nil, &team, &submitted, ptrString("2024-01-08"), ptrString("2024-01-15"))
+	assert.NoError(t, err)
+	assert.Len(t, list, 1)
+}
+
+func TestTimesheetSubmitChecks(t *testing.T) {
+	employee := uuid.New()
+	u := &model.User{ID: employee.String()}
+	running := &model.TimeTableEntry{UserID: u, Day: "2024-01-08", Arrival: time.Date(2024, 1, 8, 9, 0, 0, 0, time.UTC), Status: true}
+	repo := &mockTimesheetRepo{timesheets: map[string]*model.Timesheet{}, entries: []*model.TimeTableEntry{running}}
+	svc := NewTimesheetService(repo, NewKpiService(&mockKpiRepo{}))
+
+	_, err := svc.Submit(employee, "2024-01-08")
+	assert.Error(t, err, "a session is still running")
+	_, err = svc.Submit(employee, time.Now().AddDate(0, 0, 8).Format(layoutISO))
+	assert.Error(t, err, "future week")
+	_, err = svc.Submit(employee, time.Now().Format(layoutISO))
+	assert.Error(t, err, "the current week is not over")
+	_, err = svc.Submit(employee, "08/01/2024")
+	assert.Error(t, err)
+}
+
+func TestTimeTableServiceLocksApprovedWeeks(t *testing.T) {
+	employee := uuid.New()
+	approvedID := "ts1"
+	timesheets := &mockTimesheetRepo{timesheets: map[string]*model.Timesheet{
+		timesheetKey(employee, "2024-01-08"): {ID: &approvedID, UserID: &model.User{ID: employee.String()}, WeekStart: "2024-01-08", Status: model.TimesheetStatusApproved},
+	}}
+	current := &model.TimeTableEntry{ID: "e1", UserID: &model.User{ID: employee.String()}, Day: "2024-01-16", Arrival: time.Date(2024, 1, 16, 9, 0, 0, 0, time.UTC)}
+	repo := &mockTTRepo{entry: current}
+	svc := NewTimeTableService(repo)
+	svc.Timesheets = NewTimesheetService(timesheets, nil)
+
+	_, err := svc.CreateTimeEntry(model.CreateTimeEntryInput{UserID: employee.String(), Day: "2024-01-09", Arrival: time.Date(2024, 1, 9, 9, 0, 0, 0, time.UTC)})
+	assert.Error(t, err)
+	assert.Nil(t, repo.created)
+	_, err = svc.UpdateTimeEntry("e1", model.UpdateTimeEntryInput{Day: ptrString("2024-01-10")})
+	assert.Error(t, err, "can't move an entry into an approved week")
+	_, err = svc.UpdateTimeEntry("e1", model.UpdateTimeEntryInput{Day: ptrString("2024-01-17")})
+	assert.NoError(t, err)
+
+	current.Day = "2024-01-12"
+	_, err = svc.DeleteTimeEntry("e1")
+	assert.Error(t, err)
+	_, err = svc.RequestCorrection(employee, model.RequestTimeEntryCorrectionInput{Day: "2024-01-12", ProposedArrival: time.Date(2024, 1, 12, 9, 0, 0, 0, time.UTC), Reason: "forgot"})
+	assert.Error(t, err)
+}